@@ -0,0 +1,452 @@
+// This package is a typed Go client for the sslcerttop REST API
+//
+// It mirrors the routes described by the OpenAPI document served at /api/v1/openapi.json
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Domain is a tracked domain as returned by the API
+type Domain struct {
+	ID          uint       `json:"id"`
+	Domain      string     `json:"domain"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiryDate  *time.Time `json:"expiry_date"`
+	DaysLeft    *int       `json:"days_left"`
+	LastChecked *time.Time `json:"last_checked"`
+	LastError   *string    `json:"last_error"`
+	IsActive    bool       `json:"is_active"`
+	Status      string     `json:"status"`
+	// StatusReason explains the status, e.g. how many days are left or why the last check failed
+	StatusReason  string   `json:"status_reason"`
+	Tags          []string `json:"tags"`
+	CheckSchedule string   `json:"check_schedule,omitempty"`
+	TeamID        *uint    `json:"team_id,omitempty"`
+	// RegistrationExpiry is when the domain's registration expires, nil until the server looked it up
+	RegistrationExpiry *time.Time `json:"registration_expiry,omitempty"`
+	// DNSError describes how the domain's DNS records differ from those expected, nil when they matched
+	DNSError *string `json:"dns_error,omitempty"`
+	// Warning describes a problem with a certificate that is otherwise fine, nil when there is none
+	Warning *string `json:"warning,omitempty"`
+	// AutoRenewDays is how many days before expiry the certificate renews automatically, zero when it doesn't
+	AutoRenewDays int `json:"auto_renew_days,omitempty"`
+	// AutoRenewVia names what renews the certificate automatically, e.g. cert-manager
+	AutoRenewVia string `json:"auto_renew_via,omitempty"`
+	// CertFingerprint is the SHA-256 fingerprint of the domain's certificate, shared by domains serving the same one
+	CertFingerprint string `json:"cert_fingerprint,omitempty"`
+	// SANs are the DNS names the certificate is valid for
+	SANs []string `json:"sans,omitempty"`
+	// ExpectedSANs are the DNS names the certificate has to cover, checks fail when it doesn't
+	ExpectedSANs []string `json:"expected_sans,omitempty"`
+	// IssuedAt is when the certificate became valid
+	IssuedAt *time.Time `json:"issued_at,omitempty"`
+	// KeyType describes the certificate's public key, e.g. RSA 2048 or ECDSA P-256
+	KeyType string `json:"key_type,omitempty"`
+	// Signatures are the signature algorithms of the certificate and its intermediates, e.g. SHA256-RSA
+	Signatures []string `json:"signatures,omitempty"`
+}
+
+// CheckRecord is one historical certificate check
+type CheckRecord struct {
+	CheckedAt  time.Time  `json:"checked_at"`
+	ExpiryDate *time.Time `json:"expiry_date"`
+	Error      *string    `json:"error"`
+}
+
+// CheckEvent is a completed certificate check as streamed by the API
+type CheckEvent struct {
+	// Domain is the domain as of the check
+	Domain    Domain    `json:"domain"`
+	CheckedAt time.Time `json:"checked_at"`
+	Error     *string   `json:"error"`
+}
+
+// Notification is an expiry notification as returned by the API
+type Notification struct {
+	ID             uint       `json:"id"`
+	DomainID       uint       `json:"domain_id"`
+	Domain         string     `json:"domain"`
+	ExpiryDate     *time.Time `json:"expiry_date"`
+	DaysBefore     int        `json:"days_before"`
+	Channel        string     `json:"channel"`
+	Status         string     `json:"status"`
+	CreatedAt      time.Time  `json:"created_at"`
+	SentAt         *time.Time `json:"sent_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at"`
+	LastError      *string    `json:"last_error"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at"`
+	LastStatusCode *int       `json:"last_status_code"`
+}
+
+// Attempt is a notification delivery attempt as returned by the API
+type Attempt struct {
+	AttemptedAt time.Time `json:"attempted_at"`
+	StatusCode  *int      `json:"status_code"`
+	Error       *string   `json:"error"`
+}
+
+// Ack is a domain acknowledgement as returned by the API
+type Ack struct {
+	DomainID   uint       `json:"domain_id"`
+	Domain     string     `json:"domain"`
+	Note       string     `json:"note"`
+	ExpiryDate *time.Time `json:"expiry_date"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Active     bool       `json:"active"`
+}
+
+// Session is a session token as returned by the API
+type Session struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UserID    uint      `json:"user_id"`
+}
+
+// APIError is returned when the server answers with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("api error (%d): %s", e.StatusCode, e.Message)
+}
+
+// Client talks to a single sslcerttop API server
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	apiKey     string
+}
+
+// NewClient creates a client for the server at baseURL, e.g. http://localhost:8080.
+//
+// A nil httpClient uses http.DefaultClient
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/") + "/api/v1",
+		httpClient: httpClient,
+	}
+}
+
+// NewUnixClient creates a client for the server listening on the unix socket at path, e.g. a daemon on this machine
+func NewUnixClient(path string) *Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", path)
+		},
+	}
+	return NewClient("http://localhost", &http.Client{Transport: transport})
+}
+
+// SetAPIKey sends key, or a session token, in the Authorization header of every request
+func (c *Client) SetAPIKey(key string) {
+	c.apiKey = key
+}
+
+// ListDomains lists every tracked domain
+func (c *Client) ListDomains(ctx context.Context) ([]Domain, error) {
+	var domains []Domain
+	err := c.do(ctx, http.MethodGet, "/domains", nil, &domains)
+	return domains, err
+}
+
+// AddDomain tracks a new domain and returns it after its initial check
+func (c *Client) AddDomain(ctx context.Context, name string) (*Domain, error) {
+	var d Domain
+	body := struct {
+		Domain string `json:"domain"`
+	}{Domain: name}
+	if err := c.do(ctx, http.MethodPost, "/domains", body, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// GetDomain looks up a single domain
+func (c *Client) GetDomain(ctx context.Context, id uint) (*Domain, error) {
+	var d Domain
+	if err := c.do(ctx, http.MethodGet, domainPath(id), nil, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// DeleteDomain stops tracking a domain
+func (c *Client) DeleteDomain(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodDelete, domainPath(id), nil, nil)
+}
+
+// CheckDomain checks a domain's certificate now and returns the updated domain
+func (c *Client) CheckDomain(ctx context.Context, id uint) (*Domain, error) {
+	var d Domain
+	if err := c.do(ctx, http.MethodPost, domainPath(id)+"/check", nil, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// CheckAllDomains checks every tracked domain and returns them updated
+func (c *Client) CheckAllDomains(ctx context.Context) ([]Domain, error) {
+	var domains []Domain
+	err := c.do(ctx, http.MethodPost, "/checks", nil, &domains)
+	return domains, err
+}
+
+// CheckFailedDomains checks again only the domains whose last check failed and returns every domain updated
+func (c *Client) CheckFailedDomains(ctx context.Context) ([]Domain, error) {
+	var domains []Domain
+	err := c.do(ctx, http.MethodPost, "/checks?failed=true", nil, &domains)
+	return domains, err
+}
+
+// GetDomainHistory lists past checks of a domain, newest first.
+//
+// A limit of zero uses the server default
+func (c *Client) GetDomainHistory(ctx context.Context, id uint, limit int) ([]CheckRecord, error) {
+	path := domainPath(id) + "/history"
+	if limit > 0 {
+		path += "?" + url.Values{"limit": {strconv.Itoa(limit)}}.Encode()
+	}
+	var records []CheckRecord
+	err := c.do(ctx, http.MethodGet, path, nil, &records)
+	return records, err
+}
+
+// GetCertificateChain fetches the certificate chain a domain serves, PEM encoded with the leaf first
+func (c *Client) GetCertificateChain(ctx context.Context, id uint) (string, error) {
+	resp, err := c.send(ctx, http.MethodGet, domainPath(id)+"/chain", nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	pem, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	return string(pem), nil
+}
+
+// ListNotifications lists notifications for every tracked domain, newest first
+func (c *Client) ListNotifications(ctx context.Context) ([]Notification, error) {
+	var notifications []Notification
+	err := c.do(ctx, http.MethodGet, "/notifications", nil, &notifications)
+	return notifications, err
+}
+
+// ListNotificationAttempts lists the delivery attempts of a notification, oldest first
+func (c *Client) ListNotificationAttempts(ctx context.Context, id uint) ([]Attempt, error) {
+	var attempts []Attempt
+	err := c.do(ctx, http.MethodGet, notificationPath(id)+"/attempts", nil, &attempts)
+	return attempts, err
+}
+
+// ResendNotification queues a notification for delivery again
+func (c *Client) ResendNotification(ctx context.Context, id uint) (*Notification, error) {
+	var n Notification
+	if err := c.do(ctx, http.MethodPost, notificationPath(id)+"/resend", nil, &n); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// AcknowledgeNotification marks a notification as acknowledged
+func (c *Client) AcknowledgeNotification(ctx context.Context, id uint) (*Notification, error) {
+	var n Notification
+	if err := c.do(ctx, http.MethodPost, notificationPath(id)+"/acknowledge", nil, &n); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// ListAcks lists the acknowledgements of every tracked domain, including ones that have ended
+func (c *Client) ListAcks(ctx context.Context) ([]Ack, error) {
+	var acks []Ack
+	err := c.do(ctx, http.MethodGet, "/acks", nil, &acks)
+	return acks, err
+}
+
+// GetDomainAck fetches a domain's acknowledgement
+func (c *Client) GetDomainAck(ctx context.Context, id uint) (*Ack, error) {
+	var a Ack
+	if err := c.do(ctx, http.MethodGet, domainPath(id)+"/ack", nil, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// AcknowledgeDomain silences a domain's notifications until its certificate changes or, when set, expiresAt
+func (c *Client) AcknowledgeDomain(ctx context.Context, id uint, note string, expiresAt *time.Time) (*Ack, error) {
+	var a Ack
+	body := struct {
+		Note      string     `json:"note"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}{Note: note, ExpiresAt: expiresAt}
+	if err := c.do(ctx, http.MethodPut, domainPath(id)+"/ack", body, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// RemoveDomainAck lets a domain notify again
+func (c *Client) RemoveDomainAck(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodDelete, domainPath(id)+"/ack", nil, nil)
+}
+
+// Login signs in with an account's email and password and sends the session token with every later request
+func (c *Client) Login(ctx context.Context, email, password string) (*Session, error) {
+	var session Session
+	body := struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}{Email: email, Password: password}
+	if err := c.do(ctx, http.MethodPost, "/auth/login", body, &session); err != nil {
+		return nil, err
+	}
+	c.apiKey = session.Token
+	return &session, nil
+}
+
+// RefreshSession swaps the current session token for a new one before it expires
+func (c *Client) RefreshSession(ctx context.Context) (*Session, error) {
+	var session Session
+	if err := c.do(ctx, http.MethodPost, "/auth/refresh", nil, &session); err != nil {
+		return nil, err
+	}
+	c.apiKey = session.Token
+	return &session, nil
+}
+
+// Logout ends the current session
+func (c *Client) Logout(ctx context.Context) error {
+	if err := c.do(ctx, http.MethodPost, "/auth/logout", nil, nil); err != nil {
+		return err
+	}
+	c.apiKey = ""
+	return nil
+}
+
+// WatchChecks calls checked for every check the server completes on the caller's domains, until ctx is cancelled
+// or the server ends the stream
+func (c *Client) WatchChecks(ctx context.Context, checked func(CheckEvent)) error {
+	resp, err := c.send(ctx, http.MethodGet, "/events", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Events are an event line and a data line, ended by an empty line. Heartbeats are comments
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var event string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			event = ""
+		} else if name, ok := strings.CutPrefix(line, "event: "); ok {
+			event = name
+		} else if data, ok := strings.CutPrefix(line, "data: "); ok && event == "check" {
+			var e CheckEvent
+			if err := json.Unmarshal([]byte(data), &e); err != nil {
+				return fmt.Errorf("failed to decode event: %w", err)
+			}
+			checked(e)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("event stream failed: %w", err)
+	}
+	return errors.New("event stream ended")
+}
+
+func notificationPath(id uint) string {
+	return "/notifications/" + strconv.FormatUint(uint64(id), 10)
+}
+
+func domainPath(id uint) string {
+	return "/domains/" + strconv.FormatUint(uint64(id), 10)
+}
+
+// do sends a request with an optional JSON body and decodes the JSON response into out if non-nil
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	resp, err := c.send(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// send performs a request, turning non-2xx responses into an APIError.
+//
+// The caller must close the body of the returned response
+func (c *Client) send(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var reqBody io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reqBody = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		var errBody struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&errBody) == nil && errBody.Error != "" {
+			apiErr.Message = errBody.Error
+		}
+		return nil, apiErr
+	}
+	return resp, nil
+}
@@ -0,0 +1,189 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/api"
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/notification"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/samokw/ssl_tracker/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClient starts an API server over a fresh database holding one domain.
+func newTestClient(t *testing.T) (*Client, *domain.Repository, uint) {
+	t.Helper()
+
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	sslService := ssl.NewCertService()
+	t.Cleanup(sslService.Stop)
+
+	repo := domain.NewRepository(db)
+	d := domain.Domain{
+		UserID:     types.UserID(1),
+		DomainName: domain.NewDomainName("example.com"),
+		CreatedAt:  domain.NewCreatedAt(time.Now()),
+		IsActive:   true,
+	}
+	require.NoError(t, repo.CreateDomain(&d))
+
+	ts := httptest.NewServer(api.NewServer(domain.NewService(repo, sslService), notification.NewService(notification.NewRepository(db))))
+	t.Cleanup(ts.Close)
+
+	return NewClient(ts.URL+"/", ts.Client()), repo, d.DomainID.Uint()
+}
+
+// TestClient_Domains - lists, gets and deletes domains through the API.
+func TestClient_Domains(t *testing.T) {
+	c, _, id := newTestClient(t)
+	ctx := context.Background()
+
+	domains, err := c.ListDomains(ctx)
+	require.NoError(t, err)
+	require.Len(t, domains, 1)
+	assert.Equal(t, "example.com", domains[0].Domain)
+
+	d, err := c.GetDomain(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, id, d.ID)
+
+	require.NoError(t, c.DeleteDomain(ctx, id))
+
+	_, err = c.GetDomain(ctx, id)
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Contains(t, apiErr.Message, "not found")
+}
+
+// TestClient_Acks - acknowledges a domain, reads the ack back and removes it.
+func TestClient_Acks(t *testing.T) {
+	c, _, id := newTestClient(t)
+	ctx := context.Background()
+
+	until := time.Now().Add(72 * time.Hour)
+	a, err := c.AcknowledgeDomain(ctx, id, "renewal scheduled for Friday", &until)
+	require.NoError(t, err)
+	assert.True(t, a.Active)
+
+	got, err := c.GetDomainAck(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "renewal scheduled for Friday", got.Note)
+	acks, err := c.ListAcks(ctx)
+	require.NoError(t, err)
+	assert.Len(t, acks, 1)
+
+	require.NoError(t, c.RemoveDomainAck(ctx, id))
+	_, err = c.GetDomainAck(ctx, id)
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+}
+
+// TestClient_GetDomainHistory - decodes check history and passes the limit.
+func TestClient_GetDomainHistory(t *testing.T) {
+	c, repo, id := newTestClient(t)
+	ctx := context.Background()
+
+	expiry := time.Now().Add(30 * 24 * time.Hour)
+	require.NoError(t, repo.UpdateSSLInfo(types.NewDomainID(id), &expiry, nil))
+	require.NoError(t, repo.UpdateSSLInfo(types.NewDomainID(id), &expiry, nil))
+
+	records, err := c.GetDomainHistory(ctx, id, 0)
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+
+	records, err = c.GetDomainHistory(ctx, id, 1)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.NotNil(t, records[0].ExpiryDate)
+	assert.WithinDuration(t, expiry, *records[0].ExpiryDate, time.Second)
+}
+
+// TestClient_AddDomainInvalid - validation errors surface as APIError.
+func TestClient_AddDomainInvalid(t *testing.T) {
+	c, _, _ := newTestClient(t)
+
+	_, err := c.AddDomain(context.Background(), "not a domain")
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+}
+
+// TestClient_SetAPIKey - the key is sent as a bearer token.
+func TestClient_SetAPIKey(t *testing.T) {
+	var got string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Authorization")
+		w.Write([]byte("[]"))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, nil)
+	c.SetAPIKey("sct_example")
+	_, err := c.ListDomains(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer sct_example", got)
+}
+
+// TestClient_UnixSocket - a local client needs no key until anyone registered and follows the checks completed.
+func TestClient_UnixSocket(t *testing.T) {
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	sslService := ssl.NewCertService()
+	t.Cleanup(sslService.Stop)
+
+	repo := domain.NewRepository(db)
+	// Invalid hostname fails the check in the worker pool without touching the network
+	d := domain.Domain{UserID: types.UserID(1), DomainName: domain.NewDomainName("invalid..domain"), CreatedAt: domain.NewCreatedAt(time.Now()), IsActive: true}
+	require.NoError(t, repo.CreateDomain(&d))
+	domainService := domain.NewService(repo, sslService)
+	s := api.NewServer(domainService, notification.NewService(notification.NewRepository(db)))
+	s.EnableSessions(user.NewService(user.NewRepository(db)), time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "test.db.sock")
+	served := make(chan error, 1)
+	go func() { served <- s.ServeUnix(ctx, path) }()
+
+	c := NewUnixClient(path)
+	var domains []Domain
+	require.Eventually(t, func() bool {
+		domains, err = c.ListDomains(ctx)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Len(t, domains, 1)
+
+	checks := make(chan CheckEvent, 16)
+	go c.WatchChecks(ctx, func(e CheckEvent) { checks <- e })
+	// The stream may not be subscribed yet, check until it reports one
+	require.Eventually(t, func() bool {
+		require.NoError(t, domainService.CheckDomainsSSLSync([]domain.Domain{d}))
+		select {
+		case e := <-checks:
+			assert.Equal(t, "invalid..domain", e.Domain.Domain)
+			assert.NotNil(t, e.Error)
+			return true
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-served)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/notification"
+)
+
+// runAck acknowledges domains, silencing their notifications until the certificate changes or the ack expires
+func runAck(cfg *config.Config, args []string) error {
+	usage := "Usage: sslcerttop ack add [--for 72h] <domain> [note] | list | remove <domain> [--output table|json|csv]"
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		return errors.New("missing ack command")
+	}
+
+	fs := flag.NewFlagSet("ack "+args[0], flag.ExitOnError)
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	var duration *time.Duration
+	if args[0] == "add" {
+		duration = fs.Duration("for", 0, "end the ack after this long, by default it lasts until the certificate changes")
+	}
+	rest, err := parseInterleaved(fs, args[1:])
+	if err != nil {
+		return err
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "add":
+		if len(rest) < 1 {
+			fmt.Fprintln(os.Stderr, usage)
+			return errors.New("missing domain")
+		}
+		if *duration < 0 {
+			return errors.New("--for must not be negative")
+		}
+		d, err := svc.domainService.FindDomainByName(userID, rest[0])
+		if err != nil {
+			return err
+		}
+		var expiresAt *time.Time
+		if *duration > 0 {
+			t := time.Now().Add(*duration)
+			expiresAt = &t
+		}
+
+		a, err := svc.notificationService.AcknowledgeDomain(*d, strings.Join(rest[1:], " "), expiresAt)
+		if err != nil {
+			return err
+		}
+
+		out := newAckRecords()
+		out.single = true
+		addAckRecord(out, *a, true)
+		return out.write(os.Stdout, output.format)
+
+	case "list":
+		acks, err := svc.notificationService.GetUsersAcks(userID)
+		if err != nil {
+			return err
+		}
+
+		out := newAckRecords()
+		now := time.Now()
+		for _, a := range acks {
+			d, err := svc.domainService.GetDomain(a.DomainID)
+			if err != nil {
+				return err
+			}
+			addAckRecord(out, a, a.Active(d.ExpiryTime(), now))
+		}
+		return out.write(os.Stdout, output.format)
+
+	case "remove":
+		if len(rest) != 1 {
+			fmt.Fprintln(os.Stderr, usage)
+			return errors.New("missing domain")
+		}
+		d, err := svc.domainService.FindDomainByName(userID, rest[0])
+		if err != nil {
+			return err
+		}
+		if err := svc.notificationService.RemoveAck(d.DomainID); err != nil {
+			return err
+		}
+
+		out := newRecords("domain", "status")
+		out.single = true
+		out.add(d.DomainName.String(), "removed")
+		return out.write(os.Stdout, output.format)
+
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return fmt.Errorf("unknown ack command %q", args[0])
+	}
+}
+
+func newAckRecords() *records {
+	return newRecords("domain", "note", "expires_at", "created_at", "active")
+}
+
+func addAckRecord(out *records, a notification.DomainAck, active bool) {
+	out.add(a.DomainName, a.Note, a.ExpiresAt, a.CreatedAt, active)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/samokw/ssl_tracker/internal/apikey"
+	"github.com/samokw/ssl_tracker/internal/config"
+)
+
+// runAPIKey creates, lists and revokes the keys used by API clients
+func runAPIKey(cfg *config.Config, args []string) error {
+	usage := "Usage: sslcerttop apikey create [--scope read|read-write] <name> | list | revoke <id> [--output table|json|csv]"
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		return errors.New("missing apikey command")
+	}
+
+	fs := flag.NewFlagSet("apikey "+args[0], flag.ExitOnError)
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	var scope *string
+	if args[0] == "create" {
+		scope = fs.String("scope", apikey.ScopeRead.String(), "what the key may do: read or read-write")
+	}
+	rest, err := parseInterleaved(fs, args[1:])
+	if err != nil {
+		return err
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "create":
+		if len(rest) != 1 {
+			fmt.Fprintln(os.Stderr, usage)
+			return errors.New("missing key name")
+		}
+		parsed, err := apikey.ParseScope(*scope)
+		if err != nil {
+			return err
+		}
+
+		key, created, err := svc.apiKeyService.CreateKey(userID, rest[0], parsed)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "Store the key now, it won't be shown again")
+
+		out := newRecords("id", "name", "scope", "key")
+		out.single = true
+		out.add(created.KeyID, created.Name, created.Scope.String(), key)
+		return out.write(os.Stdout, output.format)
+
+	case "list":
+		keys, err := svc.apiKeyService.GetUsersKeys(userID)
+		if err != nil {
+			return err
+		}
+
+		out := newRecords("id", "name", "prefix", "scope", "created_at", "last_used_at", "status")
+		for _, k := range keys {
+			out.add(k.KeyID, k.Name, k.Prefix, k.Scope.String(), k.CreatedAt, k.LastUsedAt, keyStatus(k))
+		}
+		return out.write(os.Stdout, output.format)
+
+	case "revoke":
+		if len(rest) != 1 {
+			fmt.Fprintln(os.Stderr, usage)
+			return errors.New("missing key ID")
+		}
+		id, err := strconv.ParseUint(rest[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid key ID %q", rest[0])
+		}
+		if err := svc.apiKeyService.RevokeKey(userID, uint(id)); err != nil {
+			return err
+		}
+
+		out := newRecords("id", "status")
+		out.single = true
+		out.add(uint(id), "revoked")
+		return out.write(os.Stdout, output.format)
+
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return fmt.Errorf("unknown apikey command %q", args[0])
+	}
+}
+
+// keyStatus reports whether a key can still be used
+func keyStatus(k apikey.APIKey) string {
+	if k.IsRevoked() {
+		return "revoked"
+	}
+	return "active"
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/domain"
+)
+
+// runCadence lists how often each domain's certificate has been renewed, when it is expected to be renewed next
+// and whether that has slipped
+func runCadence(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("cadence", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop cadence [domain] [--slipped] [--output table|json|csv]")
+	}
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	slippedOnly := fs.Bool("slipped", false, "only list domains whose expected renewal has slipped")
+	rest, err := parseInterleaved(fs, args)
+	if err != nil {
+		return err
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+	var domains []domain.Domain
+	if len(rest) > 0 {
+		d, err := svc.domainService.FindDomainByName(userID, rest[0])
+		if err != nil {
+			return err
+		}
+		domains = append(domains, *d)
+	} else if domains, err = svc.domainService.GetUsersDomains(userID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	out := newRecords("domain", "issuer", "renewals", "every_days", "lead_days", "last_renewed", "next_renewal", "status")
+	for _, d := range domains {
+		cadence, err := svc.domainService.GetRenewalCadence(d)
+		if err != nil {
+			return err
+		}
+		status := "on_track"
+		switch {
+		case cadence.NextRenewal == nil:
+			status = "unknown"
+		case cadence.Slipped(now):
+			status = "slipped"
+		}
+		if *slippedOnly && status != "slipped" {
+			continue
+		}
+
+		var lastRenewed *time.Time
+		if n := len(cadence.Renewals); n > 0 {
+			lastRenewed = &cadence.Renewals[n-1]
+		}
+		out.add(d.DomainName.String(), d.Issuer, len(cadence.Renewals), days(cadence.Interval), days(cadence.Lead),
+			lastRenewed, cadence.NextRenewal, status)
+	}
+	return out.write(os.Stdout, output.format)
+}
+
+// days is a duration in whole days, rounded, nil when it is zero
+func days(d time.Duration) *int {
+	if d == 0 {
+		return nil
+	}
+	n := int((d + 12*time.Hour) / (24 * time.Hour))
+	return &n
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/samokw/ssl_tracker/internal/certstore"
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// runCert tracks certificates that can't be fetched over the network, S/MIME, code signing and client
+// certificates, from a PEM, DER or PKCS#12 copy
+func runCert(cfg *config.Config, args []string) error {
+	usage := "Usage: sslcerttop cert add <file|->... [--team <team>] [--tags tag,tag] [--dry-run] [--output table|json|csv]"
+	if len(args) < 1 || args[0] != "add" {
+		fmt.Fprintln(os.Stderr, usage)
+		return errors.New("missing cert command")
+	}
+
+	fs := flag.NewFlagSet("cert add", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), usage) }
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	teamName := fs.String("team", "", "share the certificates with this team instead of keeping them private")
+	tagList := fs.String("tags", "", "comma separated tags to add besides the certificate's usage")
+	dryRun := fs.Bool("dry-run", false, "list the certificates found without tracking them")
+	rest, err := parseInterleaved(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if len(rest) < 1 {
+		fs.Usage()
+		return errors.New("missing certificate file")
+	}
+
+	var certs []*x509.Certificate
+	for _, path := range rest {
+		var data []byte
+		if path == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(path)
+		}
+		if err != nil {
+			return err
+		}
+		found, err := ssl.ParseCertificates(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		certs = append(certs, endEntities(found)...)
+	}
+
+	out := newRecords("name", "usage", "expires", "issuer", "status")
+	if *dryRun {
+		for _, cert := range certs {
+			out.add(certstore.Name(cert), certstore.Usage(cert), cert.NotAfter, ssl.IssuerName(cert), scanFound)
+		}
+		return out.write(os.Stdout, output.format)
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+	var teamID types.TeamID
+	if *teamName != "" {
+		t, err := svc.teamService.FindTeam(userID, *teamName)
+		if err != nil {
+			return fmt.Errorf("team %q: %w", *teamName, err)
+		}
+		teamID = t.TeamID
+	}
+
+	for _, cert := range certs {
+		name := certstore.Name(cert)
+		d, status, err := storeCertificate(svc, userID, teamID, cert, domain.ParseTags(*tagList))
+		if err != nil {
+			out.add(name, certstore.Usage(cert), cert.NotAfter, ssl.IssuerName(cert), err.Error())
+			continue
+		}
+		out.add(name, certstore.Usage(cert), d.ExpiryTime(), d.Issuer, status)
+	}
+	return out.write(os.Stdout, output.format)
+}
+
+// endEntities leaves out the CA certificates bundled with the ones to track, unless there is nothing else
+func endEntities(certs []*x509.Certificate) []*x509.Certificate {
+	var leaves []*x509.Certificate
+	for _, cert := range certs {
+		if !cert.IsCA {
+			leaves = append(leaves, cert)
+		}
+	}
+	if len(leaves) == 0 {
+		return certs[:1]
+	}
+	return leaves
+}
+
+// storeCertificate tracks a certificate from its copy, tagged with its usage and tags
+func storeCertificate(svc *services, userID types.UserID, teamID types.TeamID, cert *x509.Certificate, tags []string) (*domain.Domain, string, error) {
+	name := certstore.Name(cert)
+	d, err := svc.domainService.AddTeamDomainUnchecked(userID, teamID, name)
+	if errors.Is(err, domain.ErrDuplicate) {
+		d, err = svc.domainService.FindDomainByName(userID, name)
+		return d, scanTracked, err
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Checks can only find the certificate once its copy is saved
+	if err := svc.certRepo.Save(d.DomainID, cert); err != nil {
+		return nil, "", err
+	}
+	if err := svc.domainService.CheckDomainSSL(d.DomainID); err != nil {
+		return nil, "", err
+	}
+	if usage := certstore.Usage(cert); usage != "" {
+		tags = append(tags, usage)
+	}
+	if len(tags) > 0 {
+		if err := svc.domainService.SetTags(d.DomainID, tags); err != nil {
+			return nil, "", err
+		}
+	}
+	d, err = svc.domainService.GetDomain(d.DomainID)
+	return d, scanAdded, err
+}
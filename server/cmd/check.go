@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/zabbix"
+)
+
+// Exit codes of the check command, matching the Nagios plugin convention
+const (
+	checkOK       = 0
+	checkWarning  = 1
+	checkCritical = 2
+)
+
+var checkStatusNames = map[int]string{
+	checkOK:       "OK",
+	checkWarning:  "WARNING",
+	checkCritical: "CRITICAL",
+}
+
+// checkTarget fetches the certificate checkOne grades, tests replace it to stay off the network
+var checkTarget = ssl.CheckTarget
+
+// runCheck checks certificates ad hoc without touching the database and exits by the worst result
+func runCheck(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop check <domain>... [--warn days] [--crit days] [--timeout duration] [--roots system|mozilla|file] [--output text|table|json|csv|checkmk|zabbix] [--zabbix-server host[:port] [--zabbix-host name]]")
+		fs.PrintDefaults()
+	}
+	warn := fs.Int("warn", cfg.Thresholds.Warning, "exit 1 when a certificate expires within this many days")
+	crit := fs.Int("crit", cfg.Thresholds.Critical, "exit 2 when a certificate expires within this many days")
+	timeout := fs.Duration("timeout", cfg.CheckTimeout, "time allowed for each check")
+	output := addOutputFlagWithDefault(fs, outputText, outputCheckmk, outputZabbix)
+	zabbixServer := fs.String("zabbix-server", "", "send the results to this Zabbix server or proxy instead of printing them")
+	zabbixHost := fs.String("zabbix-host", "", "host the results belong to in Zabbix (default: this machine's hostname, or - in a zabbix_sender file)")
+	roots := fs.String("roots", cfg.Trust.Roots, "root store to verify against: system, mozilla, or the path of a PEM bundle")
+
+	domains, err := parseInterleaved(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(domains) == 0 {
+		fs.Usage()
+		return errors.New("missing domain")
+	}
+	if *crit > *warn {
+		return fmt.Errorf("--crit (%d) must not be greater than --warn (%d)", *crit, *warn)
+	}
+	if *roots != cfg.Trust.Roots {
+		store, err := ssl.LoadRootStore(*roots)
+		if err != nil {
+			return fmt.Errorf("invalid --roots: %w", err)
+		}
+		// The other store no longer compares against the one configured, so only the chosen one is reported on
+		ssl.SetRootStores(store, nil)
+	}
+
+	// The result line already carries any error, keep stderr quiet for pipelines
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	out := newRecords("domain", "status", "days_left", "expires", "error", "warning")
+	var results []checkResult
+	worst := checkOK
+	for _, name := range domains {
+		result := checkOne(name, *warn, *crit, *timeout)
+		worst = max(worst, result.code)
+		results = append(results, result)
+		switch output.format {
+		case outputText:
+			printCheckLine(result)
+		case outputCheckmk:
+			printCheckmkLine(result, *warn, *crit)
+		case outputZabbix:
+		default:
+			out.add(result.domain, checkStatusNames[result.code], result.daysLeft, result.expires, result.err, result.warning)
+		}
+	}
+
+	if *zabbixServer != "" {
+		// Zabbix triggers judge the values, so only failing to deliver them is an error
+		host := *zabbixHost
+		if host == "" {
+			if host, err = os.Hostname(); err != nil {
+				return err
+			}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		resp, err := zabbix.Send(ctx, *zabbixServer, zabbixItems(host, results))
+		if err != nil {
+			return err
+		}
+		if resp.Failed > 0 {
+			return fmt.Errorf("Zabbix rejected %d of %d values, check that the items from the sslcerttop template exist on host %q", resp.Failed, resp.Total, host)
+		}
+		return nil
+	}
+
+	switch output.format {
+	case outputText, outputCheckmk:
+	case outputZabbix:
+		host := *zabbixHost
+		if host == "" {
+			host = "-"
+		}
+		for _, item := range zabbixItems(host, results) {
+			fmt.Fprintln(os.Stdout, zabbix.SenderLine(item))
+		}
+	default:
+		if err := out.write(os.Stdout, output.format); err != nil {
+			return err
+		}
+	}
+	if worst != checkOK {
+		return exitCodeError(worst)
+	}
+	return nil
+}
+
+// checkResult is the outcome of checking one domain
+type checkResult struct {
+	domain   string
+	code     int
+	daysLeft *int
+	expires  *time.Time
+	err      *string
+	// warning is a problem with a certificate that is otherwise fine, which makes it a warning at least
+	warning *string
+}
+
+// checkOne checks a single domain, returning its result and exit code
+func checkOne(name string, warn, crit int, timeout time.Duration) checkResult {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cert, err := checkTarget(ctx, name)
+	if err != nil {
+		msg := err.Error()
+		return checkResult{domain: name, code: checkCritical, err: &msg}
+	}
+
+	var warning *string
+	if cert.Warning != "" {
+		warning = &cert.Warning
+	}
+	if cert.ExpiryDate.Time().IsZero() {
+		// Plain SSH host keys don't expire
+		return checkResult{domain: name, code: checkOK}
+	}
+	daysLeft := int(cert.TimeLeft)
+	expires := cert.ExpiryDate.Time()
+	code := checkOK
+	switch {
+	case expires.Before(time.Now()) || daysLeft <= crit:
+		code = checkCritical
+	case daysLeft <= warn || warning != nil:
+		code = checkWarning
+	}
+	return checkResult{domain: name, code: code, daysLeft: &daysLeft, expires: &expires, warning: warning}
+}
+
+// printCheckLine writes one logfmt line per domain so results are easy to grep and parse
+func printCheckLine(r checkResult) {
+	details := ""
+	switch {
+	case r.err != nil:
+		details = "error=" + strconv.Quote(*r.err)
+	case r.daysLeft == nil:
+		details = "expires=never"
+	default:
+		details = fmt.Sprintf("days_left=%d expires=%s", *r.daysLeft, r.expires.UTC().Format(time.RFC3339))
+	}
+	if r.warning != nil {
+		details += " warning=" + strconv.Quote(*r.warning)
+	}
+	fmt.Fprintf(os.Stdout, "status=%s domain=%s %s\n", checkStatusNames[r.code], r.domain, details)
+}
+
+// printCheckmkLine writes a checkmk local check line, e.g.
+// 1 "SSL example.com" days_left=20;30;7 expires 2026-01-09 (20 days left)
+func printCheckmkLine(r checkResult, warn, crit int) {
+	service := strconv.Quote("SSL " + r.domain)
+	if r.err != nil {
+		// Local check output is one line per service
+		summary := strings.Join(strings.Fields(*r.err), " ")
+		fmt.Fprintf(os.Stdout, "%d %s - %s\n", r.code, service, summary)
+		return
+	}
+	if r.daysLeft == nil {
+		fmt.Fprintf(os.Stdout, "%d %s - does not expire\n", r.code, service)
+		return
+	}
+	summary := fmt.Sprintf("expires %s (%d days left)", r.expires.UTC().Format(time.DateOnly), *r.daysLeft)
+	if r.warning != nil {
+		summary += ", " + *r.warning
+	}
+	fmt.Fprintf(os.Stdout, "%d %s days_left=%d;%d;%d %s\n", r.code, service, *r.daysLeft, warn, crit, summary)
+}
+
+// zabbixItems turns results into values for the trapper items of host: a discovery of the domains, then the
+// status, days left, expiry as a Unix time and last error of each
+func zabbixItems(host string, results []checkResult) []zabbix.Item {
+	discovery := make([]map[string]string, len(results))
+	for i, r := range results {
+		discovery[i] = map[string]string{"{#DOMAIN}": r.domain}
+	}
+	data, _ := json.Marshal(discovery)
+
+	items := []zabbix.Item{{Host: host, Key: "sslcerttop.discovery", Value: string(data)}}
+	for _, r := range results {
+		items = append(items, zabbix.Item{Host: host, Key: zabbix.Key("sslcerttop.status", r.domain), Value: strconv.Itoa(r.code)})
+		if r.daysLeft != nil {
+			items = append(items,
+				zabbix.Item{Host: host, Key: zabbix.Key("sslcerttop.days_left", r.domain), Value: strconv.Itoa(*r.daysLeft)},
+				zabbix.Item{Host: host, Key: zabbix.Key("sslcerttop.expires", r.domain), Value: strconv.FormatInt(r.expires.Unix(), 10)})
+		}
+		errMsg := ""
+		if r.err != nil {
+			errMsg = *r.err
+		}
+		items = append(items, zabbix.Item{Host: host, Key: zabbix.Key("sslcerttop.error", r.domain), Value: errMsg})
+	}
+	return items
+}
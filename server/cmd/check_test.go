@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureStdout runs f and returns what it wrote to standard output.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	f()
+	require.NoError(t, w.Close())
+	out, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(out)
+}
+
+// fakeCheckTarget makes checkOne see certificates expiring in the days of daysLeft, keyed by the target's first
+// label, and any other target as unreachable.
+func fakeCheckTarget(t *testing.T, daysLeft map[string]int) {
+	t.Helper()
+
+	logger, check := slog.Default(), checkTarget
+	t.Cleanup(func() {
+		slog.SetDefault(logger)
+		checkTarget = check
+	})
+	checkTarget = func(_ context.Context, name string) (*ssl.SSLCertificate, error) {
+		days, ok := daysLeft[strings.Split(name, ".")[0]]
+		if !ok {
+			return nil, errors.New("dial tcp: connection refused")
+		}
+		return &ssl.SSLCertificate{
+			ExpiryDate: types.NewExpiryDate(time.Now().Add(time.Duration(days)*24*time.Hour + time.Hour)),
+			TimeLeft:   ssl.TimeLeft(days),
+		}, nil
+	}
+}
+
+// TestRunCheck - the exit code and result line follow the days left against --warn and --crit, an unreachable
+// domain is critical.
+func TestRunCheck(t *testing.T) {
+	fakeCheckTarget(t, map[string]int{"ok": 60, "warning": 20, "critical": 3, "expired": -2})
+
+	tests := []struct {
+		domain   string
+		exitCode int
+		line     string
+	}{
+		{"ok.example.com", checkOK, "status=OK domain=ok.example.com days_left=60 expires="},
+		{"warning.example.com", checkWarning, "status=WARNING domain=warning.example.com days_left=20 expires="},
+		{"critical.example.com", checkCritical, "status=CRITICAL domain=critical.example.com days_left=3 expires="},
+		{"expired.example.com", checkCritical, "status=CRITICAL domain=expired.example.com days_left=-2 expires="},
+		{"unreachable.example.com", checkCritical, `status=CRITICAL domain=unreachable.example.com error="dial tcp: connection refused"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			var err error
+			out := captureStdout(t, func() {
+				err = runCheck(&config.Config{CheckTimeout: time.Second}, []string{tt.domain, "--warn", "30", "--crit", "7"})
+			})
+
+			if tt.exitCode == checkOK {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, exitCodeError(tt.exitCode), err)
+			}
+			assert.True(t, strings.HasPrefix(out, tt.line), "got %q", out)
+			assert.Equal(t, 1, strings.Count(out, "\n"), "One line per domain")
+		})
+	}
+}
+
+// TestRunCheck_Worst - checking several domains exits by the worst of them.
+func TestRunCheck_Worst(t *testing.T) {
+	fakeCheckTarget(t, map[string]int{"fine": 60, "soon": 20})
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runCheck(&config.Config{CheckTimeout: time.Second}, []string{"fine.example.com", "soon.example.com", "--warn", "30", "--crit", "7"})
+	})
+	assert.Equal(t, exitCodeError(checkWarning), err)
+	assert.Equal(t, 2, strings.Count(out, "\n"))
+}
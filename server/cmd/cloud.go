@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/cloud"
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/samokw/ssl_tracker/internal/user"
+)
+
+// runCloud imports certificates held by cloud providers and lists the ones imported
+func runCloud(cfg *config.Config, args []string) error {
+	usage := "Usage: sslcerttop cloud sync [--team <team>] | list [--output table|json|csv]"
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		return errors.New("missing cloud command")
+	}
+
+	fs := flag.NewFlagSet("cloud "+args[0], flag.ExitOnError)
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	var teamName *string
+	if args[0] == "sync" {
+		teamName = fs.String("team", "", "share newly imported certificates with this team")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "sync":
+		providers, err := registerCloudProviders(cfg)
+		if err != nil {
+			return err
+		}
+		if len(providers) == 0 {
+			return errors.New("no cloud provider is configured, see cloud in the config file")
+		}
+		var teamID types.TeamID
+		if *teamName != "" {
+			t, err := svc.teamService.FindTeam(userID, *teamName)
+			if err != nil {
+				return fmt.Errorf("team %q: %w", *teamName, err)
+			}
+			teamID = t.TeamID
+		}
+
+		importer := cloud.NewImporter(svc.domainService, svc.cloudRepo)
+		out := newRecords("provider", "account", "region", "domain", "expires", "renewal", "status", "id")
+		var errs []error
+		for _, p := range providers {
+			results, err := importer.Sync(context.Background(), userID, teamID, p)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+			}
+			for _, r := range results {
+				out.add(r.Provider, r.Account, r.Region, r.Domain, r.NotAfter, r.RenewalEligibility, r.Status, r.ID)
+			}
+		}
+		if err := out.write(os.Stdout, output.format); err != nil {
+			return err
+		}
+		return errors.Join(errs...)
+
+	case "list":
+		entries, err := svc.cloudRepo.GetEntriesByUserID(userID)
+		if err != nil {
+			return err
+		}
+		out := newRecords("provider", "account", "region", "domain", "expires", "type", "status", "renewal", "in_use", "synced_at", "id")
+		for _, e := range entries {
+			out.add(e.Provider, e.Account, e.Region, e.Domain, e.NotAfter, e.Type, e.Status, e.RenewalEligibility, e.InUse, e.SyncedAt, e.ID)
+		}
+		return out.write(os.Stdout, output.format)
+
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return fmt.Errorf("unknown cloud command %q", args[0])
+	}
+}
+
+// registerCloudProviders sets up the configured providers so checks of their certificates reach them
+func registerCloudProviders(cfg *config.Config) ([]cloud.PrefixedProvider, error) {
+	var providers []cloud.PrefixedProvider
+	if a := cfg.Cloud.AWS; a.Enabled() {
+		// Without accounts the keys come from the environment
+		accounts := []cloud.AWSAccount{{}}
+		if len(a.Accounts) > 0 {
+			accounts = nil
+			for _, acc := range a.Accounts {
+				accounts = append(accounts, cloud.AWSAccount{
+					Name:            acc.Name,
+					AccessKeyID:     acc.AccessKeyID,
+					SecretAccessKey: acc.SecretAccessKey,
+					SessionToken:    acc.SessionToken,
+					RoleARN:         acc.RoleARN,
+				})
+			}
+		}
+		p, err := cloud.NewAWSProvider(accounts, a.Regions, a.IAM, nil)
+		if err != nil {
+			return nil, fmt.Errorf("cloud.aws: %w", err)
+		}
+		providers = append(providers, p)
+	}
+	if g := cfg.Cloud.GCP; g.Enabled() {
+		client, err := cloud.GCPClient(g.CredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("cloud.gcp: %w", err)
+		}
+		p, err := cloud.NewGCPProvider(g.Projects, client)
+		if err != nil {
+			return nil, fmt.Errorf("cloud.gcp: %w", err)
+		}
+		providers = append(providers, p)
+	}
+	if a := cfg.Cloud.Azure; a.Enabled() {
+		client, err := cloud.AzureClient(a.TenantID, a.ClientID, a.ClientSecret)
+		if err != nil {
+			return nil, fmt.Errorf("cloud.azure: %w", err)
+		}
+		p, err := cloud.NewAzureProvider(a.Vaults, client)
+		if err != nil {
+			return nil, fmt.Errorf("cloud.azure: %w", err)
+		}
+		providers = append(providers, p)
+	}
+	for _, p := range providers {
+		cloud.Register(p)
+	}
+	return providers, nil
+}
+
+// syncCloud imports the providers' certificates again every interval for each user who has imported before
+func syncCloud(ctx context.Context, svc *services, providers []cloud.PrefixedProvider, interval time.Duration) error {
+	importer := cloud.NewImporter(svc.domainService, svc.cloudRepo)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		users, err := svc.cloudRepo.GetUserIDs()
+		if err != nil {
+			slog.Error("Failed to look up who imports cloud certificates", "error", err)
+		} else if len(users) == 0 {
+			// Before anyone registers, certificates are imported for the default user
+			if required, err := svc.userService.RequiresLogin(); err == nil && !required {
+				users = []types.UserID{user.DefaultUserID}
+			}
+		}
+		for _, userID := range users {
+			for _, p := range providers {
+				results, err := importer.Sync(ctx, userID, 0, p)
+				if err != nil {
+					slog.Error("Cloud sync incomplete", "provider", p.Name(), "user_id", userID.Uint(), "error", err)
+				}
+				added := 0
+				for _, r := range results {
+					if r.Status == cloud.SyncAdded {
+						added++
+					}
+				}
+				slog.Info("Synced cloud certificates", "provider", p.Name(), "user_id", userID.Uint(), "certificates", len(results), "added", added)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/domain"
+)
+
+// runCompliance reports the certificates breaking the compliance policies, and those that will once a policy
+// takes effect, exiting 1 when any breaks one in effect
+func runCompliance(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("compliance", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop compliance [domain...] [--all] [--output table|json|csv]")
+		fmt.Fprintln(fs.Output(), "violating: the certificate breaks a policy in effect. due: it breaks one that takes effect in days_left days")
+		fs.PrintDefaults()
+	}
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	all := fs.Bool("all", false, "list compliant domains and those not checked yet as well")
+	names, err := parseInterleaved(fs, args)
+	if err != nil {
+		return err
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+	var domains []domain.Domain
+	if len(names) == 0 {
+		if domains, err = svc.domainService.GetUsersDomains(userID); err != nil {
+			return err
+		}
+	}
+	for _, name := range names {
+		d, err := svc.domainService.FindDomainByName(userID, name)
+		if err != nil {
+			return err
+		}
+		domains = append(domains, *d)
+	}
+
+	now := time.Now()
+	out := newRecords("domain", "policy", "status", "from", "days_left", "reason")
+	violating := false
+	for _, d := range domains {
+		c := svc.domainService.Compliance(d)
+		if len(c.Violations) == 0 && *all {
+			out.add(d.DomainName.String(), "", string(c.Level), nil, nil, "")
+		}
+		for _, v := range c.Violations {
+			status, from, daysLeft := domain.NonCompliant, (*time.Time)(nil), (*int)(nil)
+			if !v.From.IsZero() {
+				from = &v.From
+			}
+			if now.Before(v.From) {
+				days := int(v.From.Sub(now).Hours() / 24)
+				status, daysLeft = domain.ComplianceDue, &days
+			} else {
+				violating = true
+			}
+			out.add(d.DomainName.String(), v.Policy, string(status), from, daysLeft, v.Reason)
+		}
+	}
+	if err := out.write(os.Stdout, output.format); err != nil {
+		return err
+	}
+	if violating {
+		return exitCodeError(1)
+	}
+	return nil
+}
@@ -0,0 +1,480 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/api"
+	"github.com/samokw/ssl_tracker/internal/buildinfo"
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/cron"
+	"github.com/samokw/ssl_tracker/internal/daemon"
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/dnscheck"
+	"github.com/samokw/ssl_tracker/internal/eventlog"
+	"github.com/samokw/ssl_tracker/internal/grpcapi"
+	"github.com/samokw/ssl_tracker/internal/hooks"
+	"github.com/samokw/ssl_tracker/internal/mqtt"
+	"github.com/samokw/ssl_tracker/internal/notification"
+	"github.com/samokw/ssl_tracker/internal/scheduler"
+	"github.com/samokw/ssl_tracker/internal/whois"
+)
+
+// tagSchedules collects repeated --tag-schedule tag=expr flags
+type tagSchedules map[string]*cron.Schedule
+
+func (t tagSchedules) String() string {
+	var parts []string
+	for tag, sched := range t {
+		parts = append(parts, tag+"="+sched.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+func (t tagSchedules) Set(value string) error {
+	tag, expr, ok := strings.Cut(value, "=")
+	if !ok || strings.TrimSpace(tag) == "" {
+		return fmt.Errorf("expected tag=cron expression, got %q", value)
+	}
+	sched, err := cron.Parse(expr)
+	if err != nil {
+		return err
+	}
+	t[strings.ToLower(strings.TrimSpace(tag))] = sched
+	return nil
+}
+
+// runDaemon runs the scheduler without the TUI until interrupted
+func runDaemon(cfg *config.Config, args []string) error {
+	byTag := tagSchedules{}
+
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	interval := fs.Duration("interval", 24*time.Hour, "how often each domain is checked unless it has its own interval or schedule")
+	schedule := fs.String("schedule", "", "cron expression for checks of domains without their own schedule, replaces --interval")
+	fs.Var(byTag, "tag-schedule", "cron schedule for domains with a tag, as tag=expression (repeatable)")
+	tick := fs.Duration("tick", time.Minute, "how often to look for domains that are due")
+	jitter := fs.Float64("jitter", 0.1, "move each interval-based check by up to this fraction of its interval either way, spreading checks out, 0 turns it off")
+	listen := fs.String("listen", "", "also serve the REST API and health endpoints on this address, e.g. :8080")
+	grpcListen := fs.String("grpc-listen", "", "also serve the gRPC API on this address, e.g. :9090")
+	addDBFlag(fs, cfg)
+	addPoolFlags(fs, cfg)
+	pidPath := fs.String("pid-file", "", "PID file location (default: sslcerttop.pid in the config directory)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *jitter < 0 || *jitter >= 1 {
+		return fmt.Errorf("--jitter must be at least 0 and less than 1, got %g", *jitter)
+	}
+
+	var globalSchedule *cron.Schedule
+	if *schedule != "" {
+		var err error
+		if globalSchedule, err = cron.Parse(*schedule); err != nil {
+			return err
+		}
+	}
+
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	})))
+
+	if *pidPath == "" {
+		configDir, err := database.GetConfigDir()
+		if err != nil {
+			return fmt.Errorf("failed to get config directory: %w", err)
+		}
+		*pidPath = filepath.Join(configDir, "sslcerttop.pid")
+	}
+
+	pidFile, err := daemon.AcquirePIDFile(*pidPath)
+	if err != nil {
+		return err
+	}
+	defer pidFile.Release()
+
+	lock, err := lockDatabase(cfg, "sslcerttop daemon")
+	if err != nil {
+		return err
+	}
+	svc, err := openServices(cfg, lock)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	dispatcher, providers, err := newDispatcher(cfg, svc.notificationRepo)
+	if err != nil {
+		return err
+	}
+	digester, err := newDigester(cfg, svc.notificationRepo)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	slog.Info("Daemon started", "version", buildinfo.Get().Short(), "pid", os.Getpid(), "database", svc.dbPath, "pid_file", pidFile.Path())
+	sched := scheduler.NewScheduler(svc.domainService, dispatcher, *tick, *interval)
+	sched.SetSchedules(globalSchedule, byTag)
+	sched.SetJitter(*jitter)
+	sched.SetAlerter(notification.NewAlerter(svc.notificationRepo, cfg.Notifications.IncidentTags, providers...))
+	sched.SetDigester(digester)
+	sched.SetRenewer(newRenewer(cfg, svc.renewalRepo))
+
+	run := []func(context.Context) error{sched.Run}
+	if retention := cfg.Retention.CheckHistory(); retention > 0 {
+		run = append(run, func(ctx context.Context) error {
+			return pruneHistory(ctx, svc.domainService, retention, pruneInterval)
+		})
+	}
+	if interval := cfg.Cloud.SyncInterval; interval > 0 {
+		providers, err := registerCloudProviders(cfg)
+		if err != nil {
+			return err
+		}
+		if len(providers) > 0 {
+			run = append(run, func(ctx context.Context) error {
+				return syncCloud(ctx, svc, providers, interval)
+			})
+		}
+	}
+	if cfg.MQTT.Enabled() {
+		publisher, err := newMQTTPublisher(cfg.MQTT)
+		if err != nil {
+			return err
+		}
+		defer publisher.Close()
+		run = append(run, func(ctx context.Context) error {
+			return publisher.Run(ctx, svc.domainService)
+		})
+	}
+	if e := cfg.Events; e.Output != "" {
+		events, err := eventlog.Open(eventlog.Options{
+			Output:        e.Output,
+			SyslogAddress: e.SyslogAddress,
+			SyslogTag:     e.SyslogTag,
+			ChangesOnly:   e.ChangesOnly,
+		})
+		if err != nil {
+			return err
+		}
+		defer events.Close()
+		run = append(run, func(ctx context.Context) error {
+			return events.Run(ctx, svc.domainService)
+		})
+	}
+	if len(cfg.Hooks) > 0 {
+		runner := hooks.NewRunner(statusHooks(cfg.Hooks))
+		run = append(run, func(ctx context.Context) error {
+			return runner.Run(ctx, svc.domainService)
+		})
+	}
+	if cfg.Whois.Interval > 0 {
+		tracker, err := newRegistrationTracker(cfg, svc, dispatcher)
+		if err != nil {
+			return err
+		}
+		run = append(run, tracker.Run)
+	}
+	if cfg.DNS.Interval > 0 {
+		resolver, err := dnscheck.NewResolver(cfg.DNS.Resolver)
+		if err != nil {
+			return err
+		}
+		run = append(run, dnscheck.NewTracker(svc.domainService, resolver, cfg.DNS.Interval).Run)
+	}
+	if *listen != "" {
+		server, err := newAPIServer(ctx, cfg, svc)
+		if err != nil {
+			return err
+		}
+		server.AddReadinessCheck("scheduler", sched.Healthy)
+		run = append(run, func(ctx context.Context) error {
+			return server.ListenAndServe(ctx, *listen)
+		})
+	}
+	if svc.lock != nil {
+		// The TUI attaches here instead of opening the database the daemon holds
+		socket := api.NewServer(svc.domainService, svc.notificationService)
+		socket.EnableSessions(svc.userService, cfg.API.SessionLifetime)
+		run = append(run, func(ctx context.Context) error {
+			return socket.ServeUnix(ctx, database.SocketPath(svc.dbPath))
+		})
+	}
+	if *grpcListen != "" {
+		grpcServer := grpcapi.NewServer(svc.domainService)
+		grpcServer.RequireAPIKeys(svc.apiKeyService)
+		run = append(run, func(ctx context.Context) error {
+			return grpcServer.ListenAndServe(ctx, *grpcListen)
+		})
+	}
+	return runAll(ctx, run...)
+}
+
+// newRegistrationTracker looks up domain registrations, notifying through the configured channels within the rate
+// limits of the dispatcher's
+func newRegistrationTracker(cfg *config.Config, svc *services, dispatcher *notification.Dispatcher) (*whois.Tracker, error) {
+	client, err := whois.NewClient(cfg.Whois.RDAPServer, nil)
+	if err != nil {
+		return nil, err
+	}
+	senders, err := notificationSenders(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for i, s := range senders {
+		senders[i] = dispatcher.RateLimited(s)
+	}
+	tracker := whois.NewTracker(svc.domainService, client, svc.whoisRepo, cfg.Whois.Interval)
+	tracker.SetNotifications(cfg.Whois.Notify, senders...)
+	return tracker, nil
+}
+
+// statusHooks are the hooks configured to run on status changes
+func statusHooks(configs []config.HookConfig) []hooks.Hook {
+	result := make([]hooks.Hook, len(configs))
+	for i, h := range configs {
+		result[i] = hooks.Hook{Command: h.Command, From: h.From, To: h.To, Timeout: h.Timeout}
+	}
+	return result
+}
+
+// newMQTTPublisher connects to the broker check results are published to
+func newMQTTPublisher(cfg config.MQTTConfig) (*mqtt.Publisher, error) {
+	opts := mqtt.Options{
+		Broker:   cfg.Broker,
+		ClientID: cfg.ClientID,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		Topic:    cfg.Topic,
+		QoS:      byte(cfg.QoS),
+		Retain:   cfg.Retain,
+	}
+	if t := cfg.TLS; t != (config.MQTTTLSConfig{}) {
+		tlsConfig, err := mqtt.TLSConfig(t.CAFile, t.CertFile, t.KeyFile, t.InsecureSkipVerify)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mqtt.tls settings: %w", err)
+		}
+		opts.TLS = tlsConfig
+	}
+	return mqtt.NewPublisher(opts)
+}
+
+// notificationSenders creates a sender for every notification channel the config sets up
+func notificationSenders(cfg *config.Config) ([]notification.Sender, error) {
+	var senders []notification.Sender
+	if cfg.Notifications.Email.Host != "" {
+		sender, err := newEmailSender(cfg)
+		if err != nil {
+			return nil, err
+		}
+		senders = append(senders, sender)
+	}
+	if webhookURL := cfg.Notifications.Slack.WebhookURL; webhookURL != "" {
+		sender, err := notification.NewSlackSender(webhookURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid slack notification settings: %w", err)
+		}
+		senders = append(senders, sender)
+	}
+	if webhookURL := cfg.Notifications.Discord.WebhookURL; webhookURL != "" {
+		sender, err := notification.NewDiscordSender(webhookURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid discord notification settings: %w", err)
+		}
+		senders = append(senders, sender)
+	}
+	if webhookURL := cfg.Notifications.Teams.WebhookURL; webhookURL != "" {
+		sender, err := notification.NewTeamsSender(webhookURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid teams notification settings: %w", err)
+		}
+		senders = append(senders, sender)
+	}
+	if len(cfg.Notifications.Webhooks) > 0 {
+		endpoints := make([]notification.WebhookEndpoint, len(cfg.Notifications.Webhooks))
+		for i, w := range cfg.Notifications.Webhooks {
+			endpoints[i] = notification.WebhookEndpoint{URL: w.URL, Secret: w.Secret}
+		}
+		sender, err := notification.NewWebhookSender(endpoints, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook notification settings: %w", err)
+		}
+		senders = append(senders, sender)
+	}
+	return append(senders, pluginSenders(cfg)...), nil
+}
+
+// newEmailSender mails through the configured SMTP server
+func newEmailSender(cfg *config.Config) (*notification.EmailSender, error) {
+	email := cfg.Notifications.Email
+	sender, err := notification.NewEmailSender(notification.EmailConfig{
+		Host:     email.Host,
+		Port:     email.Port,
+		Username: email.Username,
+		Password: email.Password,
+		Security: notification.EmailSecurity(email.Security),
+		From:     email.From,
+		To:       email.To,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid email notification settings: %w", err)
+	}
+	return sender, nil
+}
+
+// newDigester mails digests on the configured schedule, nil when digests are off
+func newDigester(cfg *config.Config, notificationRepo *notification.Repository) (*notification.Digester, error) {
+	digest := cfg.Notifications.Digest
+	if digest.Schedule == "" {
+		return nil, nil
+	}
+	schedule, err := cron.Parse(digest.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digest schedule: %w", err)
+	}
+	sender, err := newEmailSender(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return notification.NewDigester(notificationRepo, sender, schedule, digest.Days), nil
+}
+
+// newDispatcher delivers through every channel the config sets up, returning the on-call providers among them
+func newDispatcher(cfg *config.Config, notificationRepo *notification.Repository) (*notification.Dispatcher, []notification.IncidentProvider, error) {
+	senders, err := notificationSenders(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	providers, err := incidentProviders(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, p := range providers {
+		senders = append(senders, notification.NewIncidentSender(p, notificationRepo))
+	}
+
+	dispatcher := notification.NewDispatcher(notificationRepo, cfg.Thresholds.Notify, senders...)
+	dispatcher.SetReminderInterval(cfg.Notifications.ReminderInterval)
+	dispatcher.SetRetryPolicy(notification.RetryPolicy{
+		MaxAttempts: cfg.Notifications.Retry.MaxAttempts,
+		Backoff:     cfg.Notifications.Retry.Backoff,
+	})
+	limiter, err := newRateLimiter(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	dispatcher.SetRateLimiter(limiter)
+	if err := applyDeliveryPolicies(dispatcher, cfg); err != nil {
+		return nil, nil, err
+	}
+	return dispatcher, providers, nil
+}
+
+// newRateLimiter limits the channels configured with a rate limit
+func newRateLimiter(cfg *config.Config) (*notification.RateLimiter, error) {
+	limits := map[notification.NotificationType]notification.RateLimit{}
+	for channel, limit := range cfg.Notifications.RateLimits {
+		if limit == "" {
+			continue
+		}
+		l, err := notification.ParseRateLimit(limit)
+		if err != nil {
+			return nil, fmt.Errorf("notifications.rate_limits.%s: %w", channel, err)
+		}
+		limits[notification.NewNotificationType(channel)] = l
+	}
+	return notification.NewRateLimiter(limits), nil
+}
+
+// applyDeliveryPolicies sets up the quiet hours, message templates and escalations the config asks for
+func applyDeliveryPolicies(dispatcher *notification.Dispatcher, cfg *config.Config) error {
+	if q := cfg.Notifications.QuietHours; q.Start != "" {
+		quietHours, err := notification.NewQuietHours(q.Start, q.End, q.CriticalDays)
+		if err != nil {
+			return err
+		}
+		dispatcher.SetQuietHours(quietHours)
+	}
+
+	templatesDir := cfg.Notifications.TemplatesDir
+	if templatesDir == "" {
+		configPath, err := config.DefaultPath()
+		if err != nil {
+			return fmt.Errorf("failed to get config path: %w", err)
+		}
+		templatesDir = filepath.Join(filepath.Dir(configPath), "templates")
+	}
+	templates, err := notification.LoadTemplates(templatesDir, cfg.Notifications.DashboardURL)
+	if err != nil {
+		return err
+	}
+	dispatcher.SetTemplates(templates)
+
+	escalations := make([]notification.Escalation, 0, len(cfg.Notifications.Escalations))
+	for _, e := range cfg.Notifications.Escalations {
+		escalation := notification.Escalation{
+			Threshold: e.Threshold,
+			After:     e.After,
+			Channel:   notification.NewNotificationType(e.Channel),
+		}
+		if err := escalation.Validate(); err != nil {
+			return err
+		}
+		escalations = append(escalations, escalation)
+	}
+	dispatcher.SetEscalations(escalations...)
+	return nil
+}
+
+// incidentProviders creates a provider for every on-call service the config sets up
+func incidentProviders(cfg *config.Config) ([]notification.IncidentProvider, error) {
+	var providers []notification.IncidentProvider
+	if key := cfg.Notifications.PagerDuty.RoutingKey; key != "" {
+		provider, err := notification.NewPagerDutyProvider(key, nil)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+	if opsgenie := cfg.Notifications.Opsgenie; opsgenie.APIKey != "" {
+		provider, err := notification.NewOpsgenieProvider(opsgenie.APIKey, opsgenie.APIURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid opsgenie settings: %w", err)
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+// runAll runs every function until the context is cancelled or the first of them returns, stopping the rest
+func runAll(ctx context.Context, fns ...func(context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errCh := make(chan error, len(fns))
+	for _, fn := range fns {
+		go func() {
+			err := fn(ctx)
+			cancel()
+			errCh <- err
+		}()
+	}
+
+	var errs []error
+	for range fns {
+		errs = append(errs, <-errCh)
+	}
+	return errors.Join(errs...)
+}
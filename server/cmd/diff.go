@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+)
+
+// runDiff compares the certificate a domain serves with a local copy, exiting 1 when they differ, to verify a
+// deploy shipped the renewed certificate
+func runDiff(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop diff <domain> --file <cert.pem> [--output table|json|csv]")
+	}
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	file := fs.String("file", "", "PEM, DER or PKCS#12 file holding the certificate expected, a chain's leaf is used")
+	rest, err := parseInterleaved(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 || *file == "" {
+		fs.Usage()
+		return errors.New("missing domain or --file")
+	}
+
+	local, err := ssl.LoadCertificateFile(*file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *file, err)
+	}
+	served, err := servedChain(cfg, rest[0])
+	if err != nil {
+		return err
+	}
+
+	out := newRecords("field", "served", "file", "match")
+	differ := false
+	for _, f := range ssl.CompareCertificates(served[0], endEntities(local)[0]) {
+		match := "yes"
+		if !f.Matches() {
+			match, differ = "no", true
+		}
+		out.add(f.Field, f.Served, f.Local, match)
+	}
+	if err := out.write(os.Stdout, output.format); err != nil {
+		return err
+	}
+	if differ {
+		return exitCodeError(1)
+	}
+	return nil
+}
+
+// servedChain fetches the chain a tracked domain serves, or a hostname or file that isn't tracked
+func servedChain(cfg *config.Config, name string) ([]*x509.Certificate, error) {
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return nil, err
+	}
+	d, err := svc.domainService.FindDomainByName(userID, name)
+	if err == nil {
+		return svc.domainService.GetCertificateChain(d.DomainID)
+	}
+	if !errors.Is(err, domain.ErrNotFound) {
+		return nil, err
+	}
+
+	if ssl.IsFileTarget(name) {
+		return ssl.LoadCertificateFile(ssl.FilePath(name))
+	}
+	hostname, err := ssl.NewHostname(name)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return ssl.FetchCertificateChain(ctx, hostname)
+}
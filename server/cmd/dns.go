@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/dnscheck"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+)
+
+// runDNS shows or sets the DNS records a domain is expected to have, checking them first with --check
+func runDNS(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("dns", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop dns [domain [TYPE=value... | none]] [--check] [--output table|json|csv]")
+		fmt.Fprintln(fs.Output(), "TYPE is A, AAAA, CNAME or CAA, whose value is the issuer domain of the CA allowed to issue, e.g. CAA=letsencrypt.org")
+	}
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	check := fs.Bool("check", false, "look the records up now instead of showing what the daemon last found, exiting 1 on a mismatch")
+	rest, err := parseInterleaved(fs, args)
+	if err != nil {
+		return err
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+	var domains []domain.Domain
+	if len(rest) == 0 {
+		all, err := svc.domainService.GetUsersDomains(userID)
+		if err != nil {
+			return err
+		}
+		for _, d := range all {
+			if len(d.DNSExpectations) > 0 {
+				domains = append(domains, d)
+			}
+		}
+	} else {
+		d, err := svc.domainService.FindDomainByName(userID, rest[0])
+		if err != nil {
+			return err
+		}
+		if len(rest) > 1 {
+			if _, err := dnscheck.Hostname(d.DomainName.String()); err != nil {
+				return fmt.Errorf("%s: %w", d.DomainName, err)
+			}
+			var expectations []domain.DNSExpectation
+			if rest[1] != "none" {
+				if expectations, err = domain.NormalizeDNSExpectations(rest[1:]); err != nil {
+					return err
+				}
+			}
+			if err := svc.domainService.SetDNSExpectations(d.DomainID, expectations); err != nil {
+				return err
+			}
+			if d, err = svc.domainService.GetDomain(d.DomainID); err != nil {
+				return err
+			}
+		}
+		domains = append(domains, *d)
+	}
+
+	if *check {
+		resolver, err := dnscheck.NewResolver(cfg.DNS.Resolver)
+		if err != nil {
+			return err
+		}
+		if err := dnscheck.NewTracker(svc.domainService, resolver, cfg.DNS.Interval).Check(context.Background(), domains); err != nil {
+			return err
+		}
+		for i, d := range domains {
+			updated, err := svc.domainService.GetDomain(d.DomainID)
+			if err != nil {
+				return err
+			}
+			domains[i] = *updated
+		}
+	}
+
+	out := newRecords("domain", "expect", "dns", "mismatch", "checked_at")
+	mismatched := false
+	for _, d := range domains {
+		status, mismatch := "unchecked", ""
+		switch {
+		case d.DNSError != nil:
+			status, mismatch = "mismatch", *d.DNSError
+			mismatched = true
+		case d.DNSChecked != nil:
+			status = "ok"
+		}
+		out.add(d.DomainName.String(), domain.FormatDNSExpectations(d.DNSExpectations), status, mismatch, d.DNSChecked)
+	}
+	if err := out.write(os.Stdout, output.format); err != nil {
+		return err
+	}
+	if *check && mismatched {
+		return exitCodeError(1)
+	}
+	return nil
+}
+
+// registerRootStores verifies certificate chains against trust.roots, reporting those trust.compare disagrees on
+func registerRootStores(cfg *config.Config) error {
+	roots, err := ssl.LoadRootStore(cfg.Trust.Roots)
+	if err != nil {
+		return fmt.Errorf("invalid trust.roots: %w", err)
+	}
+	var compare *ssl.RootStore
+	if cfg.Trust.Compare != "" {
+		store, err := ssl.LoadRootStore(cfg.Trust.Compare)
+		if err != nil {
+			return fmt.Errorf("invalid trust.compare: %w", err)
+		}
+		compare = &store
+	}
+	ssl.SetRootStores(roots, compare)
+	return nil
+}
+
+// registerIssuerVerifier checks the CAA records of every hostname against the CA of the certificate it serves.
+//
+// It is skipped on systems whose nameservers can't be read unless dns.resolver names one
+func registerIssuerVerifier(cfg *config.Config) error {
+	if !cfg.DNS.VerifyIssuer {
+		return nil
+	}
+	resolver, err := dnscheck.NewResolver(cfg.DNS.Resolver)
+	if err != nil {
+		if cfg.DNS.Resolver == "" {
+			return nil
+		}
+		return fmt.Errorf("invalid dns.resolver: %w", err)
+	}
+	ssl.SetIssuerVerifier(resolver.VerifyIssuer)
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/domain"
+)
+
+// runDuplicates lists certificates served by several tracked domains, and domains whose certificate is also
+// valid for other tracked domains, to consolidate renewals
+func runDuplicates(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("duplicates", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop duplicates [--output table|json|csv]")
+		fmt.Fprintln(fs.Output(), "shared: the domains serve the same certificate. covers: the certificate of domain is also valid for others, which serve their own")
+	}
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	if _, err := parseInterleaved(fs, args); err != nil {
+		return err
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+	domains, err := svc.domainService.GetUsersDomains(userID)
+	if err != nil {
+		return err
+	}
+
+	out := newRecords("kind", "domain", "others", "certificate", "expiry_date")
+	for _, g := range domain.SharedCertificates(domains) {
+		out.add("shared", g.Domains[0].DomainName.String(), domainNames(g.Domains[1:]), shortFingerprint(g.Fingerprint), g.Domains[0].ExpiryTime())
+	}
+	for _, c := range domain.CoveredBySANs(domains) {
+		out.add("covers", c.Domain.DomainName.String(), domainNames(c.Covered), shortFingerprint(c.Domain.CertFingerprint), c.Domain.ExpiryTime())
+	}
+	return out.write(os.Stdout, output.format)
+}
+
+// domainNames are the names of domains, in order
+func domainNames(domains []domain.Domain) []string {
+	names := make([]string, len(domains))
+	for i, d := range domains {
+		names[i] = d.DomainName.String()
+	}
+	return names
+}
+
+// shortFingerprint is the start of a certificate fingerprint, enough to tell certificates apart at a glance
+func shortFingerprint(fingerprint string) string {
+	if len(fingerprint) > 16 {
+		return fingerprint[:16]
+	}
+	return fingerprint
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+)
+
+// chainFormats are the encodings export-cert writes, by the file extensions that imply them
+var chainFormats = map[string]string{
+	".pem": "pem", ".crt": "pem",
+	".der": "der", ".cer": "der",
+	".p7b": "p7b", ".p7c": "p7b",
+}
+
+// runExportCert writes the certificate chain a domain currently serves to a file, or to stdout
+func runExportCert(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("export-cert", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop export-cert <domain> [--out chain.pem] [--format pem|der|p7b] [--output table|json|csv]")
+		fmt.Fprintln(fs.Output(), "pem and p7b hold the leaf and intermediates, der the leaf only. The format defaults to the one --out's extension implies, else pem")
+	}
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	outPath := fs.String("out", "", "file to write, stdout when empty")
+	format := fs.String("format", "", "pem, der or p7b")
+	rest, err := parseInterleaved(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		fs.Usage()
+		return errors.New("missing domain")
+	}
+	if *format == "" {
+		*format = chainFormats[strings.ToLower(filepath.Ext(*outPath))]
+		if *format == "" {
+			*format = "pem"
+		}
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+	d, err := svc.domainService.FindDomainByName(userID, rest[0])
+	if err != nil {
+		return err
+	}
+	chain, err := svc.domainService.GetCertificateChain(d.DomainID)
+	if err != nil {
+		return err
+	}
+	data, err := encodeChain(chain, *format)
+	if err != nil {
+		return err
+	}
+
+	if *outPath == "" || *outPath == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(*outPath, data, 0o644); err != nil {
+		return err
+	}
+	written := len(chain)
+	if *format == "der" {
+		written = 1
+	}
+	out := newRecords("domain", "file", "format", "certificates", "expiry_date")
+	out.single = true
+	out.add(d.DomainName.String(), *outPath, *format, written, chain[0].NotAfter)
+	return out.write(os.Stdout, output.format)
+}
+
+// encodeChain encodes a chain, leaf first, in one of the formats of chainFormats
+func encodeChain(chain []*x509.Certificate, format string) ([]byte, error) {
+	switch format {
+	case "pem":
+		return []byte(ssl.EncodeChainPEM(chain)), nil
+	case "der":
+		return chain[0].Raw, nil
+	case "p7b":
+		return ssl.EncodeChainPKCS7(chain)
+	default:
+		return nil, fmt.Errorf("unknown format %q, expected pem, der or p7b", format)
+	}
+}
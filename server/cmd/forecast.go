@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/domain"
+)
+
+// runForecast counts the certificates expiring in each of the coming months by tag or issuer, to plan renewal
+// work and budget for paid certificates
+func runForecast(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("forecast", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop forecast [--months 12] [--by tag|issuer|none] [--output table|json|csv]")
+		fmt.Fprintln(fs.Output(), "expiring: certificates served now. projected: the certificates expected to replace them, renewed with the same lifetime")
+		fs.PrintDefaults()
+	}
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	months := fs.Int("months", 12, "how many months to forecast, starting with this one")
+	by := fs.String("by", domain.ForecastByTag, "break the forecast down by tag, issuer or none")
+	if _, err := parseInterleaved(fs, args); err != nil {
+		return err
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+	domains, err := svc.domainService.GetUsersDomains(userID)
+	if err != nil {
+		return err
+	}
+	forecast, err := domain.ExpiryForecast(domains, time.Now(), *months, *by)
+	if err != nil {
+		return err
+	}
+
+	out := newRecords("month", "group", "expiring", "projected", "total")
+	for _, f := range forecast {
+		out.add(f.Month.Format("2006-01"), f.Group, f.Expiring, f.Projected, f.Total())
+	}
+	return out.write(os.Stdout, output.format)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+)
+
+// runHostKey shows the host key recorded for an SSH target, or accepts the key it presents now after it changed
+func runHostKey(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("hostkey", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop hostkey <ssh://host[:port]> [accept] [--output table|json|csv]")
+	}
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	rest, err := parseInterleaved(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) < 1 {
+		fs.Usage()
+		return errors.New("missing SSH target")
+	}
+	if !ssl.IsSSHTarget(rest[0]) {
+		return fmt.Errorf("%s is not an SSH target, expected %shost[:port]", rest[0], ssl.SSHPrefix)
+	}
+	accept := false
+	if len(rest) > 1 {
+		if rest[1] != "accept" {
+			fs.Usage()
+			return fmt.Errorf("unknown action %q", rest[1])
+		}
+		accept = true
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+	d, err := svc.domainService.FindDomainByName(userID, rest[0])
+	if err != nil {
+		return err
+	}
+
+	if accept {
+		if err := svc.domainService.AcceptHostKey(d.DomainID); err != nil {
+			return err
+		}
+		if d, err = svc.domainService.GetDomain(d.DomainID); err != nil {
+			return err
+		}
+		if d.LastError != nil {
+			return fmt.Errorf("failed to read the new host key: %s", d.LastError)
+		}
+	}
+	return writeDomainSetting(d, "fingerprint", d.Fingerprint, output.format)
+}
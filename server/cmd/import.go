@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/inventory"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/samokw/ssl_tracker/internal/webconfig"
+)
+
+// importTarget is a hostname or certificate file found in a web server configuration or an inventory
+type importTarget struct {
+	source string
+	target string
+	tags   []string
+	// err is why a certificate path couldn't be read
+	err error
+}
+
+// runImport tracks the HTTPS hostnames and certificate files of nginx, Apache, HAProxy and Caddy configurations,
+// and the hostnames of Terraform output and JSON inventories
+func runImport(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop import <config file|->... [--format nginx|apache|haproxy|caddy|terraform|inventory] [--team <team>] [--dry-run] [--output table|json|csv]")
+	}
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	formatName := fs.String("format", "", "configuration format, detected from the file when empty")
+	teamName := fs.String("team", "", "share the imported domains with this team instead of keeping them private")
+	dryRun := fs.Bool("dry-run", false, "list the hostnames and certificate files found without tracking them")
+	rest, err := parseInterleaved(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) < 1 {
+		fs.Usage()
+		return errors.New("missing config file")
+	}
+	// Terraform output and inventories are both read by the inventory package, which tells them apart itself
+	jsonFormat := *formatName == "terraform" || *formatName == "inventory"
+	var format webconfig.Format
+	if *formatName != "" && !jsonFormat {
+		if format, err = webconfig.ParseFormat(*formatName); err != nil {
+			return err
+		}
+	}
+
+	var targets []importTarget
+	seen := map[string]bool{}
+	add := func(t importTarget) {
+		if !seen[t.target] {
+			seen[t.target] = true
+			targets = append(targets, t)
+		}
+	}
+	for _, path := range rest {
+		var data []byte
+		if path == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(path)
+		}
+		if err != nil {
+			return err
+		}
+		if path == "-" || jsonFormat || (format == "" && inventory.IsJSON(data)) {
+			entries, err := inventory.Parse(data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			for _, e := range entries {
+				add(importTarget{source: path + ":" + e.Source, target: e.Hostname, tags: e.Tags})
+			}
+			continue
+		}
+
+		sites, err := webconfig.ParseFile(path, format)
+		if err != nil {
+			return err
+		}
+		for _, site := range sites {
+			for _, host := range site.Hostnames {
+				add(importTarget{source: site.Source, target: host})
+			}
+			for _, certPath := range site.CertFiles {
+				// HAProxy's crt may name a directory of certificates
+				files, err := ssl.ScanCertificateFiles(certPath)
+				if err != nil {
+					add(importTarget{source: site.Source, target: ssl.FilePrefix + certPath, err: err})
+					continue
+				}
+				for _, f := range files {
+					add(importTarget{source: site.Source, target: ssl.FilePrefix + f})
+				}
+			}
+		}
+	}
+
+	out := newRecords("source", "target", "expires", "issuer", "status")
+	if *dryRun {
+		for _, t := range targets {
+			switch {
+			case t.err != nil:
+				out.add(t.source, t.target, nil, nil, t.err.Error())
+			case ssl.IsFileTarget(t.target):
+				cert, err := ssl.CheckCertificateFile(ssl.FilePath(t.target))
+				if err != nil {
+					out.add(t.source, t.target, nil, nil, err.Error())
+					continue
+				}
+				out.add(t.source, t.target, cert.ExpiryDate.Time(), cert.Issuer, scanFound)
+			default:
+				out.add(t.source, t.target, nil, nil, scanFound)
+			}
+		}
+		return out.write(os.Stdout, output.format)
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+	var teamID types.TeamID
+	if *teamName != "" {
+		t, err := svc.teamService.FindTeam(userID, *teamName)
+		if err != nil {
+			return fmt.Errorf("team %q: %w", *teamName, err)
+		}
+		teamID = t.TeamID
+	}
+
+	failed := false
+	for _, t := range targets {
+		if t.err != nil {
+			failed = true
+			out.add(t.source, t.target, nil, nil, t.err.Error())
+			continue
+		}
+		d, status, err := trackTarget(svc, userID, teamID, t.target)
+		if err == nil && status == scanAdded && len(t.tags) > 0 {
+			err = svc.domainService.SetTags(d.DomainID, t.tags)
+		}
+		if err != nil {
+			failed = true
+			out.add(t.source, t.target, nil, nil, err.Error())
+			continue
+		}
+		out.add(t.source, t.target, d.ExpiryTime(), d.Issuer, status)
+	}
+	if err := out.write(os.Stdout, output.format); err != nil {
+		return err
+	}
+	// Scripted imports find out something wasn't tracked, the rest of it still is
+	if failed {
+		return exitCodeError(1)
+	}
+	return nil
+}
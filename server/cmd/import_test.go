@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRunImport_Failed - an import that couldn't track a certificate file exits 1 after reporting it.
+func TestRunImport_Failed(t *testing.T) {
+	dir := t.TempDir()
+	nginx := filepath.Join(dir, "nginx.conf")
+	require.NoError(t, os.WriteFile(nginx, []byte(`server {
+	listen 443 ssl;
+	ssl_certificate `+filepath.Join(dir, "missing.pem")+`;
+}
+`), 0o600))
+	cfg := &config.Config{Workers: 1, QueueSize: 1, Database: config.DatabaseConfig{Path: filepath.Join(dir, "sslcerttop.db")}}
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runImport(cfg, []string{nginx, "--output", "csv"})
+	})
+	assert.Equal(t, exitCodeError(1), err)
+	assert.Contains(t, out, "missing.pem")
+}
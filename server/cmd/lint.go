@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+)
+
+// runLint checks a certificate bundle before it is deployed, exiting 1 on warnings and 2 on errors like check
+func runLint(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop lint <bundle.pem> [--host name,name] [--key key.pem] [--warn days] [--output table|json|csv]")
+		fs.PrintDefaults()
+	}
+	output := addOutputFlag(fs)
+	hosts := fs.String("host", "", "comma separated hostnames the leaf certificate has to be valid for")
+	keyPath := fs.String("key", "", "PEM private key that has to belong to the leaf certificate, which may be the bundle itself")
+	warn := fs.Int("warn", cfg.Thresholds.Warning, "warn when the leaf certificate expires within this many days")
+	rest, err := parseInterleaved(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		fs.Usage()
+		return errors.New("missing certificate bundle")
+	}
+
+	data, err := os.ReadFile(rest[0])
+	if err != nil {
+		return err
+	}
+	certs, err := ssl.ParseCertificates(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", rest[0], err)
+	}
+
+	opts := ssl.LintOptions{WarnDays: *warn, Now: time.Now()}
+	for _, host := range strings.Split(*hosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			opts.Hostnames = append(opts.Hostnames, host)
+		}
+	}
+	if *keyPath != "" {
+		keyData, err := os.ReadFile(*keyPath)
+		if err != nil {
+			return err
+		}
+		if opts.Key, err = ssl.ParsePrivateKey(keyData); err != nil {
+			return fmt.Errorf("%s: %w", *keyPath, err)
+		}
+	}
+
+	out := newRecords("severity", "check", "message")
+	worst := checkOK
+	for _, f := range ssl.LintBundle(certs, opts) {
+		switch f.Severity {
+		case ssl.LintWarning:
+			worst = max(worst, checkWarning)
+		case ssl.LintError:
+			worst = checkCritical
+		}
+		out.add(string(f.Severity), f.Check, f.Message)
+	}
+	if err := out.write(os.Stdout, output.format); err != nil {
+		return err
+	}
+	if worst != checkOK {
+		return exitCodeError(worst)
+	}
+	return nil
+}
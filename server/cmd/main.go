@@ -1,20 +1,124 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"os"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samokw/ssl_tracker/client"
+	"github.com/samokw/ssl_tracker/internal/config"
 	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/demo"
 	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/remote"
 	"github.com/samokw/ssl_tracker/internal/ssl"
 	"github.com/samokw/ssl_tracker/internal/tui"
+	"github.com/samokw/ssl_tracker/internal/user"
 )
 
+// commands maps subcommand names to their entry points
+var commands = map[string]func(cfg *config.Config, args []string) error{
+	"ack":             runAck,
+	"apikey":          runAPIKey,
+	"autorenew":       runAutoRenew,
+	"cadence":         runCadence,
+	"cert":            runCert,
+	"check":           runCheck,
+	"cloud":           runCloud,
+	"compliance":      runCompliance,
+	"daemon":          runDaemon,
+	"diff":            runDiff,
+	"dns":             runDNS,
+	"duplicates":      runDuplicates,
+	"export-cert":     runExportCert,
+	"forecast":        runForecast,
+	"hostkey":         runHostKey,
+	"import":          runImport,
+	"install-service": runInstallService,
+	"lint":            runLint,
+	"maintenance":     runMaintenance,
+	"notify":          runNotify,
+	"prune":           runPrune,
+	"recheck":         runRecheck,
+	"renewals":        runRenewals,
+	"rule":            runRule,
+	"sans":            runSANs,
+	"scan":            runScan,
+	"serve":           runServe,
+	"schedule":        runSchedule,
+	"statuspage":      runStatusPage,
+	"sweep":           runSweep,
+	"tag":             runTag,
+	"team":            runTeam,
+	"version":         runVersion,
+	"whois":           runWhois,
+}
+
+// exitCodeError makes a command exit with a specific status without printing an error
+type exitCodeError int
+
+func (e exitCodeError) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
 // Creating a basic program that will check the exipry of a predefined sercer
 func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	ssl.SetPKCS12Passwords(cfg.Files.PKCS12Passwords)
+	if _, err := registerCloudProviders(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := registerIssuerVerifier(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := registerRootStores(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := registerPlugins(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(os.Args) > 1 {
+		if run, ok := commands[os.Args[1]]; ok {
+			// Commands only report problems, the daemon raises this itself
+			slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+				Level: slog.LevelWarn,
+			})))
+			if err := run(cfg, os.Args[2:]); err != nil {
+				var code exitCodeError
+				if errors.As(err, &code) {
+					os.Exit(int(code))
+				}
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fs := flag.NewFlagSet("sslcerttop", flag.ExitOnError)
+	server := fs.String("server", "", "URL of a remote sslcerttop server to use instead of the local database")
+	apiKey := fs.String("api-key", os.Getenv("SSLCERTTOP_API_KEY"), "API key for --server (default: $SSLCERTTOP_API_KEY)")
+	demoMode := fs.Bool("demo", false, "show made up domains checked by a fake checker, without network access or touching the database")
+	addDBFlag(fs, cfg)
+	addPoolFlags(fs, cfg)
+	fs.Parse(os.Args[1:])
+
 	// Disable logging for TUI mode to prevent console output interference
 	logger := slog.New(slog.NewJSONHandler(io.Discard, &slog.HandlerOptions{
 		Level:     slog.LevelError, // Only log errors, and discard them
@@ -22,25 +126,70 @@ func main() {
 	}))
 	slog.SetDefault(logger)
 
-	// Initialize database
-	dbPath, err := database.GetDefaultDBPath()
+	tui.SetTheme(themeFromConfig(cfg.Theme))
+	tui.SetRegistrationThresholds(cfg.Whois.Warning, cfg.Whois.Critical)
+	policies, err := compliancePolicies(cfg)
 	if err != nil {
-		fmt.Printf("Error getting database path: %v\n", err)
+		fmt.Printf("Error initializing: %v\n", err)
 		os.Exit(1)
 	}
+	tui.SetCompliancePolicies(policies)
 
-	db, err := database.InitSQLite(dbPath)
-	if err != nil {
-		fmt.Printf("Error initializing database: %v\n", err)
-		os.Exit(1)
+	// A daemon holding the database is attached to rather than turned down
+	var daemonClient *client.Client
+	if !*demoMode && *server == "" {
+		daemonClient = attachDaemon(cfg)
 	}
-	defer db.Close()
 
-	domainRepo := domain.NewRepository(db)
-	sslService := ssl.NewCertService()
-	domainService := domain.NewService(domainRepo, sslService)
+	var app *tui.App
+	switch {
+	case *demoMode:
+		domains, notifications, sslService, err := demo.Open(demo.Sites, cfg.Workers, cfg.CheckTimeout)
+		if err != nil {
+			fmt.Printf("Error initializing: %v\n", err)
+			os.Exit(1)
+		}
+		defer sslService.Stop()
+
+		domains.SetStatusThresholds(domain.StatusThresholds{Warning: cfg.Thresholds.Warning, Critical: cfg.Thresholds.Critical})
+		app = tui.NewApp(domains, notifications)
+	case *server != "":
+		c := client.NewClient(*server, nil)
+		c.SetAPIKey(*apiKey)
+		domains := remote.NewDomainService(c)
+		app = tui.NewApp(domains, remote.NewNotificationService(c))
+		app.SetCheckFeed(domains)
+	case daemonClient != nil:
+		domains := remote.NewDomainService(daemonClient)
+		app = tui.NewApp(domains, remote.NewNotificationService(daemonClient))
+		app.SetCheckFeed(domains)
+	default:
+		lock, err := lockDatabase(cfg, "sslcerttop")
+		if err != nil {
+			fmt.Printf("Error initializing: %v\n", err)
+			os.Exit(1)
+		}
+		svc, err := openServices(cfg, lock)
+		if err != nil {
+			fmt.Printf("Error initializing: %v\n", err)
+			os.Exit(1)
+		}
+		defer svc.Close()
 
-	app := tui.NewApp(domainService)
+		app = tui.NewApp(svc.domainService, svc.notificationService)
+		if dispatcher, _, err := newDispatcher(cfg, svc.notificationRepo); err == nil {
+			app.SetChannelTester(dispatcher)
+			app.SetSimulator(dispatcher)
+		}
+		app.SetSettings(svc.userService)
+		app.SetPool(svc.domainService)
+		app.SetActivity(svc.activityService)
+		app.SetHandshakes(svc.domainService)
+		if err := setUpAccounts(app, svc); err != nil {
+			fmt.Printf("Error initializing: %v\n", err)
+			os.Exit(1)
+		}
+	}
 	program := tea.NewProgram(app, tea.WithAltScreen())
 
 	if _, err := program.Run(); err != nil {
@@ -48,3 +197,87 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// setUpAccounts lets the TUI sign in and switch users, resuming the active user's session saved by the previous run
+// if it is still valid
+func setUpAccounts(app *tui.App, svc *services) error {
+	required, err := svc.userService.RequiresLogin()
+	if err != nil {
+		return err
+	}
+	sessionFile, err := user.DefaultSessionFile()
+	if err != nil {
+		return err
+	}
+	app.SetUsers(svc.userService, sessionFile, required)
+
+	token, err := sessionFile.Load()
+	if err != nil || token == "" {
+		return err
+	}
+	u, err := svc.userService.ResumeSession(token)
+	if errors.Is(err, user.ErrInvalidSession) {
+		return sessionFile.Remove(token)
+	}
+	if err != nil {
+		return err
+	}
+	app.SetUser(u, token)
+	return nil
+}
+
+// attachDaemon connects to the daemon serving the configured SQLite database on its socket, nil when none is
+// running so the TUI opens the database itself. The session the TUI saved picks the user, as it would locally
+func attachDaemon(cfg *config.Config) *client.Client {
+	if cfg.Database.Driver == config.DriverMySQL {
+		return nil
+	}
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		var err error
+		if dbPath, err = defaultDBPath(); err != nil {
+			return nil
+		}
+	}
+	socket := database.SocketPath(dbPath)
+	// A socket left behind by a daemon that crashed refuses connections
+	conn, err := net.DialTimeout("unix", socket, time.Second)
+	if err != nil {
+		return nil
+	}
+	conn.Close()
+
+	c := client.NewUnixClient(socket)
+	if sessionFile, err := user.DefaultSessionFile(); err == nil {
+		if token, err := sessionFile.Load(); err == nil {
+			c.SetAPIKey(token)
+		}
+	}
+	return c
+}
+
+// themeFromConfig applies the configured colours on top of the default theme
+func themeFromConfig(c config.ThemeConfig) tui.Theme {
+	t := tui.DefaultTheme
+	overrides := []struct {
+		value string
+		color *lipgloss.Color
+	}{
+		{c.Accent, &t.Accent},
+		{c.Highlight, &t.Highlight},
+		{c.Text, &t.Text},
+		{c.Subtle, &t.Subtle},
+		{c.Muted, &t.Muted},
+		{c.Error, &t.Error},
+		{c.Warning, &t.Warning},
+		{c.Border, &t.Border},
+		{c.SelectedText, &t.SelectedText},
+		{c.SelectedBackground, &t.SelectedBackground},
+	}
+	for _, o := range overrides {
+		if o.value != "" {
+			*o.color = lipgloss.Color(o.value)
+		}
+	}
+	return t
+}
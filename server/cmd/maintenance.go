@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/notification"
+)
+
+// startLayouts are the forms --start accepts, in local time unless they carry an offset
+var startLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04"}
+
+// runMaintenance adds, lists and removes maintenance windows, during which failing checks don't notify
+func runMaintenance(cfg *config.Config, args []string) error {
+	usage := `Usage: sslcerttop maintenance add (--domain <domain> | --tag <tag>) (--start "2026-01-02 22:00" | --cron "0 2 * * sun") --for 2h [note] | list | remove <id> [--output table|json|csv]`
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		return errors.New("missing maintenance command")
+	}
+
+	fs := flag.NewFlagSet("maintenance "+args[0], flag.ExitOnError)
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	var domainName, tag, start, schedule *string
+	var duration *time.Duration
+	if args[0] == "add" {
+		domainName = fs.String("domain", "", "domain the window applies to")
+		tag = fs.String("tag", "", "apply the window to every domain with this tag instead")
+		start = fs.String("start", "", `when a one-off window begins, e.g. "2026-01-02 22:00" in local time`)
+		schedule = fs.String("cron", "", "cron expression for when a recurring window begins")
+		duration = fs.Duration("for", 0, "how long the window lasts")
+	}
+	rest, err := parseInterleaved(fs, args[1:])
+	if err != nil {
+		return err
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "add":
+		w := notification.MaintenanceWindow{
+			Tag:      *tag,
+			Schedule: strings.TrimSpace(*schedule),
+			Duration: *duration,
+			Note:     strings.Join(rest, " "),
+		}
+		if *domainName != "" {
+			d, err := svc.domainService.FindDomainByName(userID, *domainName)
+			if err != nil {
+				return err
+			}
+			w.DomainID, w.DomainName = d.DomainID, d.DomainName.String()
+		}
+		if *start != "" {
+			t, err := parseStart(*start)
+			if err != nil {
+				return err
+			}
+			w.Start = &t
+		}
+		if err := svc.notificationService.AddMaintenanceWindow(userID, &w); err != nil {
+			return err
+		}
+
+		out := newMaintenanceRecords()
+		out.single = true
+		addMaintenanceRecord(out, w, time.Now())
+		return out.write(os.Stdout, output.format)
+
+	case "list":
+		windows, err := svc.notificationService.GetUsersMaintenanceWindows(userID)
+		if err != nil {
+			return err
+		}
+
+		out := newMaintenanceRecords()
+		now := time.Now()
+		for _, w := range windows {
+			addMaintenanceRecord(out, w, now)
+		}
+		return out.write(os.Stdout, output.format)
+
+	case "remove":
+		if len(rest) != 1 {
+			fmt.Fprintln(os.Stderr, usage)
+			return errors.New("missing maintenance window ID")
+		}
+		id, err := strconv.ParseUint(rest[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid maintenance window ID %q", rest[0])
+		}
+		if err := svc.notificationService.RemoveMaintenanceWindow(userID, uint(id)); err != nil {
+			return err
+		}
+
+		out := newRecords("id", "status")
+		out.single = true
+		out.add(uint(id), "removed")
+		return out.write(os.Stdout, output.format)
+
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return fmt.Errorf("unknown maintenance command %q", args[0])
+	}
+}
+
+// parseStart reads the start of a one-off window in one of startLayouts
+func parseStart(s string) (time.Time, error) {
+	for _, layout := range startLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf(`invalid --start %q, expected e.g. "2026-01-02 22:00"`, s)
+}
+
+func newMaintenanceRecords() *records {
+	return newRecords("id", "domain", "tag", "start", "cron", "duration", "note", "status")
+}
+
+func addMaintenanceRecord(out *records, w notification.MaintenanceWindow, now time.Time) {
+	status := "scheduled"
+	switch {
+	case w.Active(now):
+		status = "active"
+	case w.Ended(now):
+		status = "ended"
+	}
+	out.add(w.WindowID, w.DomainName, w.Tag, w.Start, w.Schedule, w.Duration.String(), w.Note, status)
+}
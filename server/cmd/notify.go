@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/notification"
+)
+
+// runNotify works with the notification channels themselves
+func runNotify(cfg *config.Config, args []string) error {
+	usage := "Usage: sslcerttop notify test [--channel <channel>] [--output table|json|csv] | digest [--html] [--days 30] [--send] | simulate --at 2025-10-01 [--output table|json|csv]"
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		return errors.New("missing notify command")
+	}
+	switch args[0] {
+	case "test":
+		return runNotifyTest(cfg, args[1:])
+	case "digest":
+		return runNotifyDigest(cfg, args[1:])
+	case "simulate":
+		return runNotifySimulate(cfg, args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return fmt.Errorf("unknown notify command %q", args[0])
+	}
+}
+
+// runNotifyTest sends a sample notification through every configured channel
+func runNotifyTest(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("notify test", flag.ExitOnError)
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	channel := fs.String("channel", "", "only test this channel: "+channelNames())
+	timeout := fs.Duration("timeout", 30*time.Second, "give up on a channel after this long")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	dispatcher, _, err := newDispatcher(cfg, svc.notificationRepo)
+	if err != nil {
+		return err
+	}
+	channels := dispatcher.Channels()
+	if *channel != "" {
+		channels = []notification.NotificationType{notification.NewNotificationType(*channel)}
+	}
+	if len(channels) == 0 {
+		return errors.New("no notification channels are configured")
+	}
+
+	out := newRecords("channel", "result", "error")
+	failed := false
+	for _, c := range channels {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		err := dispatcher.SendTest(ctx, c)
+		cancel()
+		if err != nil {
+			failed = true
+			out.add(c.String(), "failed", err.Error())
+			continue
+		}
+		out.add(c.String(), "sent", "")
+	}
+	if err := out.write(os.Stdout, output.format); err != nil {
+		return err
+	}
+	if failed {
+		return exitCodeError(1)
+	}
+	return nil
+}
+
+// runNotifyDigest prints the digest as it would be mailed now, or mails it
+func runNotifyDigest(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("notify digest", flag.ExitOnError)
+	addDBFlag(fs, cfg)
+	html := fs.Bool("html", false, "print the HTML part instead of the plain text")
+	days := fs.Int("days", cfg.Notifications.Digest.Days, "list certificates expiring within this many days")
+	send := fs.Bool("send", false, "mail the digest now and count it as the last one")
+	timeout := fs.Duration("timeout", 30*time.Second, "give up sending after this long")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	if *send {
+		if cfg.Notifications.Email.Host == "" {
+			return errors.New("digests are mailed, set up notifications.email first")
+		}
+		sender, err := newEmailSender(cfg)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		return notification.NewDigester(svc.notificationRepo, sender, nil, *days).Send(ctx, time.Now())
+	}
+
+	digest, err := notification.NewDigester(svc.notificationRepo, nil, nil, *days).Build(time.Now())
+	if err != nil {
+		return err
+	}
+	render := digest.Text
+	if *html {
+		render = digest.HTML
+	}
+	out, err := render()
+	if err != nil {
+		return err
+	}
+	fmt.Print(out)
+	return nil
+}
+
+// runNotifySimulate lists the notifications the current domains would queue at a date, without queueing any
+func runNotifySimulate(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("notify simulate", flag.ExitOnError)
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	at := fs.String("at", "", `date to simulate, e.g. "2025-10-01" or "2025-10-01 09:00" in local time, now when empty`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	when := time.Now()
+	if *at != "" {
+		t, err := parseSimulatedAt(*at)
+		if err != nil {
+			return err
+		}
+		when = t
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	dispatcher, _, err := newDispatcher(cfg, svc.notificationRepo)
+	if err != nil {
+		return err
+	}
+	if !dispatcher.HasSenders() {
+		return errors.New("no notification channels are configured")
+	}
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+	domains, err := svc.domainService.GetUsersDomains(userID)
+	if err != nil {
+		return err
+	}
+
+	alerts, err := dispatcher.Simulate(domains, when)
+	if err != nil {
+		return err
+	}
+	out := newRecords("domain", "channel", "alert", "rule", "result")
+	for _, a := range alerts {
+		result := "would notify"
+		if a.Covered {
+			result = "already queued"
+		}
+		out.add(a.Domain.DomainName.String(), a.Channel.String(), a.Describe(), a.Rule, result)
+	}
+	return out.write(os.Stdout, output.format)
+}
+
+// parseSimulatedAt reads a date, or a date and time, in local time
+func parseSimulatedAt(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := parseStart(s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf(`invalid --at %q, expected e.g. "2025-10-01"`, s)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// outputFormat selects how command results are printed
+type outputFormat string
+
+const (
+	outputTable outputFormat = "table"
+	outputJSON  outputFormat = "json"
+	outputCSV   outputFormat = "csv"
+	// outputText is a command's own line format, for commands that have one
+	outputText outputFormat = "text"
+	// outputCheckmk is a checkmk local check, one service per line
+	outputCheckmk outputFormat = "checkmk"
+	// outputZabbix is a zabbix_sender input file
+	outputZabbix outputFormat = "zabbix"
+)
+
+// outputFlag is the --output flag, limited to the formats a command supports
+type outputFlag struct {
+	format  outputFormat
+	choices []outputFormat
+}
+
+func (o *outputFlag) String() string {
+	return string(o.format)
+}
+
+func (o *outputFlag) Set(value string) error {
+	for _, choice := range o.choices {
+		if outputFormat(value) == choice {
+			o.format = choice
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown output format %q, expected %s", value, o.names())
+}
+
+func (o *outputFlag) names() string {
+	names := make([]string, len(o.choices))
+	for i, choice := range o.choices {
+		names[i] = string(choice)
+	}
+	return strings.Join(names, ", ")
+}
+
+// addOutputFlag registers --output on a command, defaulting to a table
+func addOutputFlag(fs *flag.FlagSet) *outputFlag {
+	return addOutputFlagWithDefault(fs, outputTable)
+}
+
+// addOutputFlagWithDefault registers --output on a command that defaults to one of its own formats,
+// extra lists any further formats of its own
+func addOutputFlagWithDefault(fs *flag.FlagSet, def outputFormat, extra ...outputFormat) *outputFlag {
+	choices := []outputFormat{outputTable, outputJSON, outputCSV}
+	if def != outputTable {
+		choices = append([]outputFormat{def}, choices...)
+	}
+	choices = append(choices, extra...)
+	o := &outputFlag{format: def, choices: choices}
+	fs.Var(o, "output", "output format: "+o.names())
+	return o
+}
+
+// records are command results with stable field names shared by every output format
+type records struct {
+	fields []string
+	rows   [][]any
+	// single prints a lone JSON object instead of an array
+	single bool
+}
+
+func newRecords(fields ...string) *records {
+	return &records{fields: fields}
+}
+
+// add appends a row whose values line up with the fields
+func (r *records) add(values ...any) {
+	r.rows = append(r.rows, values)
+}
+
+// write prints the records in the given format
+func (r *records) write(w io.Writer, format outputFormat) error {
+	switch format {
+	case outputJSON:
+		objects := make([]map[string]any, len(r.rows))
+		for i, row := range r.rows {
+			obj := make(map[string]any, len(r.fields))
+			for j, field := range r.fields {
+				obj[field] = jsonValue(row[j])
+			}
+			objects[i] = obj
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if r.single && len(objects) == 1 {
+			return enc.Encode(objects[0])
+		}
+		return enc.Encode(objects)
+
+	case outputCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(r.fields); err != nil {
+			return err
+		}
+		for _, row := range r.rows {
+			line := make([]string, len(row))
+			for i, v := range row {
+				line[i] = textValue(v, "")
+			}
+			if err := cw.Write(line); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(r.fields, "\t")))
+		for _, row := range r.rows {
+			line := make([]string, len(row))
+			for i, v := range row {
+				line[i] = textValue(v, "-")
+			}
+			fmt.Fprintln(tw, strings.Join(line, "\t"))
+		}
+		return tw.Flush()
+	}
+}
+
+// jsonValue normalises values so times are RFC 3339 and missing values are null
+func jsonValue(v any) any {
+	switch v := v.(type) {
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		return v.UTC().Format(time.RFC3339)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	case *string:
+		if v == nil {
+			return nil
+		}
+		return *v
+	case *int:
+		if v == nil {
+			return nil
+		}
+		return *v
+	case []string:
+		if v == nil {
+			return []string{}
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// textValue formats a value for table and CSV output, using missing for absent values
+func textValue(v any, missing string) string {
+	switch v := jsonValue(v).(type) {
+	case nil:
+		return missing
+	case string:
+		if v == "" {
+			return missing
+		}
+		return v
+	case []string:
+		if len(v) == 0 {
+			return missing
+		}
+		return strings.Join(v, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
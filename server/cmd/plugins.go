@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/notification"
+	"github.com/samokw/ssl_tracker/internal/plugin"
+)
+
+// registerPlugins has the configured plugins check the names starting with their prefix, and adds the
+// notification channels of those that notify so rules can use them
+func registerPlugins(cfg *config.Config) error {
+	for _, pc := range cfg.Plugins {
+		p := newPlugin(pc)
+		if pc.Prefix != "" {
+			p.RegisterChecker(pc.Prefix)
+		}
+		if pc.Notify {
+			if err := p.RegisterChannel(); err != nil {
+				return fmt.Errorf("plugin %s: %w", p.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// pluginSenders deliver notifications through the plugins that notify
+func pluginSenders(cfg *config.Config) []notification.Sender {
+	var senders []notification.Sender
+	for _, pc := range cfg.Plugins {
+		if pc.Notify {
+			senders = append(senders, plugin.NewSender(newPlugin(pc)))
+		}
+	}
+	return senders
+}
+
+func newPlugin(pc config.PluginConfig) plugin.Plugin {
+	return plugin.Plugin{Name: pc.Name, Command: pc.Command, Args: pc.Args, Timeout: pc.Timeout}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/domain"
+)
+
+// pruneInterval is how often the daemon applies the retention policy
+const pruneInterval = 24 * time.Hour
+
+// runPrune deletes check history older than the retention period
+func runPrune(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop prune [--days n] [--dry-run]")
+		fs.PrintDefaults()
+	}
+	days := fs.Int("days", cfg.Retention.CheckHistoryDays, "keep this many days of check history")
+	dryRun := fs.Bool("dry-run", false, "only report how many entries would be deleted")
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	if _, err := parseInterleaved(fs, args); err != nil {
+		return err
+	}
+	if *days <= 0 {
+		return errors.New("--days must be at least 1, a retention of 0 keeps all history")
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	deleted, err := svc.domainService.PruneCheckHistory(time.Duration(*days)*24*time.Hour, *dryRun)
+	if err != nil {
+		return fmt.Errorf("failed to prune check history: %w", err)
+	}
+
+	out := newRecords("table", "retention_days", "deleted", "dry_run")
+	out.single = true
+	out.add("check_history", *days, deleted, *dryRun)
+	return out.write(os.Stdout, output.format)
+}
+
+// pruneHistory applies the retention policy now and then every interval until the context is cancelled
+func pruneHistory(ctx context.Context, domainService *domain.Service, retention, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := domainService.PruneCheckHistory(retention, false)
+		if err != nil {
+			slog.Error("Failed to prune check history", "error", err)
+		} else if deleted > 0 {
+			slog.Info("Pruned check history", "deleted", deleted, "retention", retention.String())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
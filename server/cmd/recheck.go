@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/domain"
+)
+
+// runRecheck checks the tracked domains now and stores the results, only those whose last check failed with
+// --failed
+func runRecheck(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("recheck", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop recheck [--failed] [--output table|json|csv]")
+		fs.PrintDefaults()
+	}
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	addPoolFlags(fs, cfg)
+	failedOnly := fs.Bool("failed", false, "only check the domains whose last check failed, e.g. after a sweep with transient errors")
+	if _, err := parseInterleaved(fs, args); err != nil {
+		return err
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+	domains, err := svc.domainService.GetUsersDomains(userID)
+	if err != nil {
+		return err
+	}
+	if *failedOnly {
+		domains = domain.FailedDomains(domains)
+	}
+	if err := svc.domainService.CheckDomainsSSLSync(domains); err != nil {
+		return err
+	}
+
+	out := newRecords("domain", "status", "reason", "days_left", "expiry_date")
+	for _, d := range domains {
+		checked, err := svc.domainService.GetDomain(d.DomainID)
+		if err != nil {
+			return err
+		}
+		status := svc.domainService.Status(*checked)
+		out.add(checked.DomainName.String(), string(status.Level), status.Reason, status.DaysLeft, checked.ExpiryTime())
+	}
+	return out.write(os.Stdout, output.format)
+}
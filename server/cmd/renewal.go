@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/renewal"
+)
+
+// runRenewals lists the renewals the daemon started and whether they replaced the certificate
+func runRenewals(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("renewals", flag.ExitOnError)
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	limit := fs.Int("limit", 50, "how many of the most recent attempts to list")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *limit < 1 {
+		return fmt.Errorf("--limit must be at least 1, got %d", *limit)
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+	attempts, err := svc.renewalRepo.GetAttemptsByUserID(userID, *limit)
+	if err != nil {
+		return err
+	}
+
+	out := newRecords("domain", "triggered_at", "actions", "expiry_before", "outcome", "verified_at", "error")
+	for _, a := range attempts {
+		out.add(a.DomainName, a.TriggeredAt, a.Actions, a.ExpiryBefore, a.Outcome(), a.VerifiedAt, a.Error)
+	}
+	return out.write(os.Stdout, output.format)
+}
+
+// runAutoRenew shows or sets how many days before expiry a domain's certificate renews automatically, so it is
+// alerted on when the certificate wasn't renewed by then
+func runAutoRenew(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("autorenew", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop autorenew [domain [days [via] | none]] [--output table|json|csv]")
+		fmt.Fprintln(fs.Output(), "e.g. sslcerttop autorenew example.com 30 cert-manager")
+	}
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	rest, err := parseInterleaved(fs, args)
+	if err != nil {
+		return err
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+	var domains []domain.Domain
+	if len(rest) == 0 {
+		all, err := svc.domainService.GetUsersDomains(userID)
+		if err != nil {
+			return err
+		}
+		for _, d := range all {
+			if d.AutoRenewDays > 0 {
+				domains = append(domains, d)
+			}
+		}
+	} else {
+		d, err := svc.domainService.FindDomainByName(userID, rest[0])
+		if err != nil {
+			return err
+		}
+		if len(rest) > 1 {
+			days := 0
+			if rest[1] != "none" {
+				if days, err = strconv.Atoi(rest[1]); err != nil || days < 1 {
+					return fmt.Errorf("days must be a positive number or none, got %q", rest[1])
+				}
+			}
+			if err := svc.domainService.SetAutoRenewal(d.DomainID, days, strings.Join(rest[2:], " ")); err != nil {
+				return err
+			}
+			if d, err = svc.domainService.GetDomain(d.DomainID); err != nil {
+				return err
+			}
+		}
+		domains = append(domains, *d)
+	}
+
+	now := time.Now()
+	out := newRecords("domain", "days_before", "via", "expiry_date", "due_at", "renewal")
+	for _, d := range domains {
+		status := "none"
+		switch {
+		case d.AutoRenewDays == 0:
+		case d.RenewalOverdue(now):
+			status = "overdue"
+		case d.RenewalDue() == nil:
+			status = "unknown"
+		case now.After(*d.RenewalDue()):
+			status = "due"
+		default:
+			status = "scheduled"
+		}
+		out.add(d.DomainName.String(), d.AutoRenewDays, d.AutoRenewVia, d.ExpiryTime(), d.RenewalDue(), status)
+	}
+	return out.write(os.Stdout, output.format)
+}
+
+// newRenewer runs the configured renewal command and webhook, nil when neither is set
+func newRenewer(cfg *config.Config, repo *renewal.Repository) *renewal.Renewer {
+	r := cfg.Renewal
+	if !r.Enabled() {
+		return nil
+	}
+	var actions []renewal.Action
+	if r.Command != "" {
+		actions = append(actions, renewal.NewCommandAction(r.Command))
+	}
+	if r.WebhookURL != "" {
+		actions = append(actions, renewal.NewWebhookAction(r.WebhookURL, nil))
+	}
+	renewer := renewal.NewRenewer(repo, r.Threshold, r.Timeout, r.Retry, actions...)
+	renewer.SetTags(r.Tags)
+	return renewer
+}
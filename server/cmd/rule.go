@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/notification"
+)
+
+// runRule adds, lists, switches and removes notification rules
+func runRule(cfg *config.Config, args []string) error {
+	usage := "Usage: sslcerttop rule add --channel <channel> [--days 30,7] [--on-error] [--tags prod,web] <name> | list | enable <id> | disable <id> | remove <id> [--output table|json|csv]"
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		return errors.New("missing rule command")
+	}
+
+	fs := flag.NewFlagSet("rule "+args[0], flag.ExitOnError)
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	var channel, days, tags *string
+	var onError *bool
+	if args[0] == "add" {
+		channel = fs.String("channel", "", "channel to notify: "+channelNames())
+		days = fs.String("days", "", "comma separated days before expiry to notify at, 0 meaning expired")
+		onError = fs.Bool("on-error", false, "also notify when checks start failing")
+		tags = fs.String("tags", "", "only apply to domains with any of these comma separated tags")
+	}
+	rest, err := parseInterleaved(fs, args[1:])
+	if err != nil {
+		return err
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "add":
+		if len(rest) < 1 {
+			fmt.Fprintln(os.Stderr, usage)
+			return errors.New("missing rule name")
+		}
+		thresholds, err := notification.ParseThresholds(*days)
+		if err != nil {
+			return err
+		}
+		rule := notification.Rule{
+			Name:       strings.Join(rest, " "),
+			Channel:    notification.NewNotificationType(*channel),
+			Thresholds: thresholds,
+			OnError:    *onError,
+			Tags:       domain.ParseTags(*tags),
+			Enabled:    true,
+		}
+		if err := svc.notificationService.AddRule(userID, &rule); err != nil {
+			return err
+		}
+
+		out := newRuleRecords()
+		out.single = true
+		addRuleRecord(out, rule)
+		return out.write(os.Stdout, output.format)
+
+	case "list":
+		rules, err := svc.notificationService.GetUsersRules(userID)
+		if err != nil {
+			return err
+		}
+
+		out := newRuleRecords()
+		for _, r := range rules {
+			addRuleRecord(out, r)
+		}
+		return out.write(os.Stdout, output.format)
+
+	case "enable", "disable", "remove":
+		if len(rest) != 1 {
+			fmt.Fprintln(os.Stderr, usage)
+			return errors.New("missing rule ID")
+		}
+		id, err := strconv.ParseUint(rest[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid rule ID %q", rest[0])
+		}
+
+		status := "removed"
+		if args[0] == "remove" {
+			err = svc.notificationService.RemoveRule(userID, uint(id))
+		} else {
+			status = args[0] + "d"
+			err = svc.notificationService.SetRuleEnabled(userID, uint(id), args[0] == "enable")
+		}
+		if err != nil {
+			return err
+		}
+
+		out := newRecords("id", "status")
+		out.single = true
+		out.add(uint(id), status)
+		return out.write(os.Stdout, output.format)
+
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return fmt.Errorf("unknown rule command %q", args[0])
+	}
+}
+
+func newRuleRecords() *records {
+	return newRecords("id", "name", "channel", "days", "on_error", "tags", "enabled")
+}
+
+func addRuleRecord(out *records, r notification.Rule) {
+	out.add(r.RuleID, r.Name, r.Channel.String(), notification.FormatThresholds(r.Thresholds), r.OnError, strings.Join(r.Tags, ","), r.Enabled)
+}
+
+// channelNames lists the channels rules can use for help text
+func channelNames() string {
+	names := make([]string, len(notification.NotificationTypes))
+	for i, t := range notification.NotificationTypes {
+		names[i] = t.String()
+	}
+	return strings.Join(names, ", ")
+}
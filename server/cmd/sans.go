@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/domain"
+)
+
+// runSANs shows or sets the DNS names a domain's certificate has to cover, checking the certificate first with
+// --check
+func runSANs(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("sans", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop sans [domain [name... | none]] [--check] [--output table|json|csv]")
+		fmt.Fprintln(fs.Output(), "e.g. sslcerttop sans example.com example.com www.example.com api.example.com")
+	}
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	check := fs.Bool("check", false, "check the certificates now instead of showing what the daemon last found, exiting 1 when one is missing a name")
+	rest, err := parseInterleaved(fs, args)
+	if err != nil {
+		return err
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+	var domains []domain.Domain
+	if len(rest) == 0 {
+		all, err := svc.domainService.GetUsersDomains(userID)
+		if err != nil {
+			return err
+		}
+		for _, d := range all {
+			if len(d.ExpectedSANs) > 0 {
+				domains = append(domains, d)
+			}
+		}
+	} else {
+		d, err := svc.domainService.FindDomainByName(userID, rest[0])
+		if err != nil {
+			return err
+		}
+		if len(rest) > 1 {
+			var names []string
+			if rest[1] != "none" {
+				names = rest[1:]
+			}
+			if err := svc.domainService.SetExpectedSANs(d.DomainID, names); err != nil {
+				return err
+			}
+			if d, err = svc.domainService.GetDomain(d.DomainID); err != nil {
+				return err
+			}
+		}
+		domains = append(domains, *d)
+	}
+
+	if *check {
+		for i, d := range domains {
+			if err := svc.domainService.CheckDomainSSL(d.DomainID); err != nil {
+				return err
+			}
+			updated, err := svc.domainService.GetDomain(d.DomainID)
+			if err != nil {
+				return err
+			}
+			domains[i] = *updated
+		}
+	}
+
+	out := newRecords("domain", "expect", "status", "missing", "checked_at")
+	mismatched := false
+	for _, d := range domains {
+		status, missing := "unchecked", []string(nil)
+		var checkedAt *time.Time
+		switch {
+		case d.LastError != nil:
+			status = "failing"
+			if names, ok := strings.CutPrefix(d.LastError.String(), domain.ErrMissingSANs.Error()+": "); ok {
+				status, missing = "missing", strings.Split(names, ", ")
+				mismatched = true
+			}
+		case d.LastChecked != nil:
+			status = "ok"
+		}
+		if d.LastChecked != nil {
+			t := d.LastChecked.Time()
+			checkedAt = &t
+		}
+		out.add(d.DomainName.String(), d.ExpectedSANs, status, missing, checkedAt)
+	}
+	if err := out.write(os.Stdout, output.format); err != nil {
+		return err
+	}
+	if *check && mismatched {
+		return exitCodeError(1)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// Statuses reported for each file found by scan
+const (
+	scanAdded   = "added"
+	scanTracked = "already tracked"
+	scanFound   = "found"
+)
+
+// runScan finds certificate files in files or directories and tracks them alongside network checks
+func runScan(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop scan <file|directory>... [--team <team>] [--dry-run] [--output table|json|csv]")
+	}
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	addPoolFlags(fs, cfg)
+	teamName := fs.String("team", "", "share the files with this team instead of keeping them private")
+	dryRun := fs.Bool("dry-run", false, "list the certificates found without tracking them")
+	rest, err := parseInterleaved(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) < 1 {
+		fs.Usage()
+		return errors.New("missing file or directory")
+	}
+
+	var paths []string
+	for _, root := range rest {
+		found, err := ssl.ScanCertificateFiles(root)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, found...)
+	}
+
+	out := newRecords("file", "expires", "issuer", "status")
+	if *dryRun {
+		for _, path := range paths {
+			cert, err := ssl.CheckCertificateFile(path)
+			if err != nil {
+				out.add(path, nil, nil, err.Error())
+				continue
+			}
+			out.add(path, cert.ExpiryDate.Time(), cert.Issuer, scanFound)
+		}
+		return out.write(os.Stdout, output.format)
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+	var teamID types.TeamID
+	if *teamName != "" {
+		t, err := svc.teamService.FindTeam(userID, *teamName)
+		if err != nil {
+			return fmt.Errorf("team %q: %w", *teamName, err)
+		}
+		teamID = t.TeamID
+	}
+
+	for _, path := range paths {
+		d, status, err := trackTarget(svc, userID, teamID, ssl.FilePrefix+path)
+		if err != nil {
+			out.add(path, nil, nil, err.Error())
+			continue
+		}
+		out.add(path, d.ExpiryTime(), d.Issuer, status)
+	}
+	return out.write(os.Stdout, output.format)
+}
+
+// trackTarget adds a hostname or file unless it is tracked already, returning it with the status to report:
+// whether it was added, or the error of its last check
+func trackTarget(svc *services, userID types.UserID, teamID types.TeamID, target string) (*domain.Domain, string, error) {
+	status := scanAdded
+	d, err := svc.domainService.AddTeamDomain(userID, teamID, target)
+	if errors.Is(err, domain.ErrDuplicate) {
+		status = scanTracked
+		d, err = svc.domainService.FindDomainByName(userID, target)
+	} else if err == nil {
+		// Wait for the initial check, then reload to pick up its result
+		if err = svc.domainService.CheckDomainSSL(d.DomainID); err == nil {
+			d, err = svc.domainService.GetDomain(d.DomainID)
+		}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if d.LastError != nil {
+		status = d.LastError.String()
+	}
+	return d, status, nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/domain"
+)
+
+// runSchedule shows or sets the cron schedule of a domain
+func runSchedule(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("schedule", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop schedule <domain> [cron expression | none] [--output table|json|csv]")
+	}
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	rest, err := parseInterleaved(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) < 1 {
+		fs.Usage()
+		return errors.New("missing domain")
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+	d, err := svc.domainService.FindDomainByName(userID, rest[0])
+	if err != nil {
+		return err
+	}
+
+	if len(rest) > 1 {
+		expr := strings.Join(rest[1:], " ")
+		if expr == "none" {
+			expr = ""
+		}
+		if err := svc.domainService.SetCheckSchedule(d.DomainID, expr); err != nil {
+			return err
+		}
+		if d, err = svc.domainService.GetDomain(d.DomainID); err != nil {
+			return err
+		}
+	}
+
+	return writeDomainSetting(d, "schedule", d.CheckSchedule, output.format)
+}
+
+// runTag shows or replaces the tags of a domain
+func runTag(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("tag", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop tag <domain> [tag,tag,... | none] [--output table|json|csv]")
+	}
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	rest, err := parseInterleaved(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) < 1 {
+		fs.Usage()
+		return errors.New("missing domain")
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+	d, err := svc.domainService.FindDomainByName(userID, rest[0])
+	if err != nil {
+		return err
+	}
+
+	if len(rest) > 1 {
+		tags := strings.Join(rest[1:], ",")
+		if tags == "none" {
+			tags = ""
+		}
+		if err := svc.domainService.SetTags(d.DomainID, strings.Split(tags, ",")); err != nil {
+			return err
+		}
+		if d, err = svc.domainService.GetDomain(d.DomainID); err != nil {
+			return err
+		}
+	}
+
+	return writeDomainSetting(d, "tags", d.Tags, output.format)
+}
+
+// writeDomainSetting prints a domain with the one setting a command manages
+func writeDomainSetting(d *domain.Domain, field string, value any, format outputFormat) error {
+	out := newRecords("domain", field)
+	out.single = true
+	out.add(d.DomainName.String(), value)
+	return out.write(os.Stdout, format)
+}
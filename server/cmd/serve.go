@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/samokw/ssl_tracker/internal/api"
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/sso"
+)
+
+// runServe serves the REST API until interrupted
+func runServe(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := fs.String("listen", ":8080", "address to listen on")
+	addDBFlag(fs, cfg)
+	addPoolFlags(fs, cfg)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	})))
+
+	lock, err := lockDatabase(cfg, "sslcerttop serve")
+	if err != nil {
+		return err
+	}
+	svc, err := openServices(cfg, lock)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server, err := newAPIServer(ctx, cfg, svc)
+	if err != nil {
+		return err
+	}
+	return server.ListenAndServe(ctx, *listen)
+}
+
+// newAPIServer creates an API server that requires API keys or session tokens and whose readiness covers the database and the worker pool
+func newAPIServer(ctx context.Context, cfg *config.Config, svc *services) (*api.Server, error) {
+	server := api.NewServer(svc.domainService, svc.notificationService)
+	server.RequireAPIKeys(svc.apiKeyService)
+	server.EnableSessions(svc.userService, cfg.API.SessionLifetime)
+	if o := cfg.API.OIDC; o.Issuer != "" {
+		provider, err := sso.NewProvider(ctx, sso.Config{
+			Issuer:       o.Issuer,
+			ClientID:     o.ClientID,
+			ClientSecret: o.ClientSecret,
+			RedirectURL:  o.RedirectURL,
+			Scopes:       o.Scopes,
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		server.EnableOIDC(provider)
+		slog.Info("Single sign-on enabled", "issuer", o.Issuer)
+	}
+	server.AddReadinessCheck("database", svc.db.PingContext)
+	server.AddReadinessCheck("worker_pool", func(ctx context.Context) error {
+		if svc.sslService.Stopped() {
+			return errors.New("worker pool is stopped")
+		}
+		return nil
+	})
+	return server, nil
+}
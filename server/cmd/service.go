@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"slices"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/daemon"
+	"github.com/samokw/ssl_tracker/internal/database"
+)
+
+// runInstallService writes a systemd unit, or a launchd agent on macOS, running the daemon with the arguments
+// after --
+func runInstallService(cfg *config.Config, args []string) error {
+	var daemonArgs []string
+	if i := slices.Index(args, "--"); i >= 0 {
+		args, daemonArgs = args[:i], args[i+1:]
+	}
+
+	fs := flag.NewFlagSet("install-service", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop install-service [--system [--run-as user]] [--name name] [--path file] [--db file] [--print] [--force] [--output table|json|csv] [-- daemon flags]")
+		fs.PrintDefaults()
+	}
+	system := fs.Bool("system", false, "install a system-wide systemd unit instead of a user unit, needs root")
+	runAs := fs.String("run-as", "", "user the system-wide unit runs as (default: the user running sudo, or the current user)")
+	name := fs.String("name", "sslcerttop", "unit name, or launchd label")
+	path := fs.String("path", "", "where to write the service file (default: where the service manager looks for it)")
+	printOnly := fs.Bool("print", false, "print the service file instead of writing it")
+	force := fs.Bool("force", false, "replace an existing service file")
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	if _, err := parseInterleaved(fs, args); err != nil {
+		return err
+	}
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		return fmt.Errorf("install-service writes systemd units and launchd agents, on %s run `sslcerttop daemon` from your service manager", runtime.GOOS)
+	}
+	if *system && runtime.GOOS != "linux" {
+		return errors.New("--system is only supported with systemd")
+	}
+	if *runAs != "" && !*system {
+		return errors.New("--run-as needs --system, user units run as their user")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the sslcerttop binary: %w", err)
+	}
+	if executable, err = filepath.EvalSymlinks(executable); err != nil {
+		return fmt.Errorf("failed to find the sslcerttop binary: %w", err)
+	}
+
+	svc := daemon.Service{Name: *name, Executable: executable, Args: daemonArgs, Environment: map[string]string{}}
+	if path := os.Getenv("SSLCERTTOP_CONFIG"); path != "" {
+		if svc.Environment["SSLCERTTOP_CONFIG"], err = filepath.Abs(path); err != nil {
+			return err
+		}
+	}
+	if cfg.Database.Path != "" {
+		if svc.Environment["SSLCERTTOP_DB"], err = filepath.Abs(cfg.Database.Path); err != nil {
+			return err
+		}
+	}
+	if *system {
+		if *runAs == "" {
+			*runAs = os.Getenv("SUDO_USER")
+		}
+		if *runAs == "" {
+			current, err := user.Current()
+			if err != nil {
+				return fmt.Errorf("failed to look up the current user: %w", err)
+			}
+			*runAs = current.Username
+		}
+		svc.User = *runAs
+	}
+	if svc.WritablePaths, err = serviceWritablePaths(cfg, svc.User); err != nil {
+		return err
+	}
+
+	// next starts the daemon once the file is written, hint is any advice besides
+	var content, next, hint string
+	switch runtime.GOOS {
+	case "linux":
+		content = daemon.SystemdUnit(svc)
+		unit := *name + ".service"
+		if *path == "" {
+			if *system {
+				*path = filepath.Join("/etc/systemd/system", unit)
+			} else {
+				configDir, err := os.UserConfigDir()
+				if err != nil {
+					return err
+				}
+				*path = filepath.Join(configDir, "systemd", "user", unit)
+			}
+		}
+		if *system {
+			next = "systemctl daemon-reload && systemctl enable --now " + unit
+		} else {
+			next = "systemctl --user daemon-reload && systemctl --user enable --now " + unit
+			hint = "To keep it running after you log out: loginctl enable-linger"
+		}
+	case "darwin":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		svc.LogPath = filepath.Join(home, "Library", "Logs", *name+".log")
+		content = daemon.LaunchdPlist(svc)
+		if *path == "" {
+			*path = filepath.Join(home, "Library", "LaunchAgents", *name+".plist")
+		}
+		next = "launchctl load -w " + *path
+	}
+
+	if *printOnly {
+		fmt.Print(content)
+		return nil
+	}
+	if _, err := os.Stat(*path); err == nil && !*force {
+		return fmt.Errorf("%s already exists, use --force to replace it", *path)
+	}
+	if err := os.MkdirAll(filepath.Dir(*path), 0755); err != nil {
+		return fmt.Errorf("failed to create service directory: %w", err)
+	}
+	if err := os.WriteFile(*path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write service file: %w", err)
+	}
+	if hint != "" {
+		fmt.Fprintln(os.Stderr, hint)
+	}
+	out := newRecords("file", "start_command")
+	out.single = true
+	out.add(*path, next)
+	return out.write(os.Stdout, output.format)
+}
+
+// serviceWritablePaths are the directories the daemon writes its database and PID file to, for runAs or, when
+// empty, the current user
+func serviceWritablePaths(cfg *config.Config, runAs string) ([]string, error) {
+	var configDir, dataDir string
+	current, err := user.Current()
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the current user: %w", err)
+	}
+	if runAs == "" || runAs == current.Username {
+		if configDir, err = database.GetConfigDir(); err != nil {
+			return nil, err
+		}
+		if dataDir, err = database.GetDataDir(); err != nil {
+			return nil, err
+		}
+	} else {
+		// Our XDG variables are not the other user's, the service sees the defaults
+		u, err := user.Lookup(runAs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up user %s: %w", runAs, err)
+		}
+		configDir = filepath.Join(u.HomeDir, ".config", "sslcerttop")
+		dataDir = filepath.Join(u.HomeDir, ".local", "share", "sslcerttop")
+	}
+
+	paths := []string{configDir, dataDir}
+	if cfg.Database.Path != "" {
+		dbDir, err := filepath.Abs(filepath.Dir(cfg.Database.Path))
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(paths, dbDir) {
+			paths = append(paths, dbDir)
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/activity"
+	"github.com/samokw/ssl_tracker/internal/apikey"
+	"github.com/samokw/ssl_tracker/internal/certstore"
+	"github.com/samokw/ssl_tracker/internal/cloud"
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/notification"
+	"github.com/samokw/ssl_tracker/internal/renewal"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/team"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/samokw/ssl_tracker/internal/user"
+	"github.com/samokw/ssl_tracker/internal/whois"
+)
+
+// services bundles the database and the services built on top of it
+type services struct {
+	db                  *sql.DB
+	dbPath              string
+	sslService          *ssl.CertService
+	domainRepo          *domain.Repository
+	domainService       *domain.Service
+	notificationRepo    *notification.Repository
+	notificationService *notification.Service
+	apiKeyService       *apikey.Service
+	userService         *user.Service
+	teamService         *team.Service
+	renewalRepo         *renewal.Repository
+	cloudRepo           *cloud.Repository
+	certRepo            *certstore.Repository
+	whoisRepo           *whois.Repository
+	activityService     *activity.Service
+	// lock claims a SQLite database for a long running instance, nil for other commands
+	lock *database.Lock
+}
+
+// openServices opens the configured database and wires up the services. A long running instance passes the lock it
+// claimed the database with from lockDatabase, which is released by Close or right away if opening fails
+func openServices(cfg *config.Config, lock *database.Lock) (_ *services, err error) {
+	if lock != nil {
+		defer func() {
+			if err != nil {
+				lock.Release()
+			}
+		}()
+	}
+	db, dbPath, err := openDatabase(cfg.Database, lock != nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+
+	domainRepo := domain.NewRepository(db)
+	sslService := ssl.NewCertServiceWithPool(cfg.Workers, cfg.CheckTimeout)
+	if err := sslService.ResizePool(ssl.PoolSize{Workers: cfg.Workers, QueueSize: cfg.QueueSize}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	notificationRepo := notification.NewRepository(db)
+	userService := user.NewService(user.NewRepository(db))
+	userService.SetDefaultSettings(user.Settings{
+		WarningDays:  cfg.Thresholds.Warning,
+		CriticalDays: cfg.Thresholds.Critical,
+	})
+	teamService := team.NewService(team.NewRepository(db))
+	domainService := domain.NewService(domainRepo, sslService)
+	domainService.SetTeams(teamService)
+	activityRepo := activity.NewRepository(db)
+	domainService.SetActivityLog(activityRepo)
+	domainService.SetStatusThresholds(domain.StatusThresholds{Warning: cfg.Thresholds.Warning, Critical: cfg.Thresholds.Critical})
+	policies, err := compliancePolicies(cfg)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	domainService.SetCompliancePolicies(policies)
+	providers, err := incidentProviders(cfg)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	domainService.SetIncidents(notification.NewAlerter(notificationRepo, nil, providers...))
+	certRepo := certstore.NewRepository(db)
+	certstore.Register(certRepo)
+
+	return &services{
+		db:                  db,
+		dbPath:              dbPath,
+		sslService:          sslService,
+		domainRepo:          domainRepo,
+		domainService:       domainService,
+		notificationRepo:    notificationRepo,
+		notificationService: notification.NewService(notificationRepo),
+		apiKeyService:       apikey.NewService(apikey.NewRepository(db)),
+		userService:         userService,
+		teamService:         teamService,
+		renewalRepo:         renewal.NewRepository(db),
+		cloudRepo:           cloud.NewRepository(db),
+		certRepo:            certRepo,
+		whoisRepo:           whois.NewRepository(db),
+		activityService:     activity.NewService(activityRepo, teamService),
+		lock:                lock,
+	}, nil
+}
+
+// compliancePolicies are the compliance policies of the config, checked for ones that could never be broken
+func compliancePolicies(cfg *config.Config) ([]domain.Policy, error) {
+	policies := make([]domain.Policy, 0, len(cfg.Compliance))
+	for _, c := range cfg.Compliance {
+		p := domain.Policy{
+			Name:                c.Name,
+			MaxLifetimeDays:     c.MaxLifetimeDays,
+			MinRSABits:          c.MinRSABits,
+			ForbiddenSignatures: c.ForbidSignatures,
+		}
+		if c.From != "" {
+			from, err := time.ParseInLocation(config.PolicyDateFormat, c.From, time.Local)
+			if err != nil {
+				return nil, fmt.Errorf("compliance policy %s: %w", c.Name, err)
+			}
+			p.From = from
+		}
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
+
+// openDatabase connects to the configured backend, returning the database and a description safe to log. A SQLite
+// database is only migrated under its lock: locked tells the caller holds it, otherwise it is taken for the
+// migrations, and a database another instance holds is opened as that instance migrated it
+func openDatabase(cfg config.DatabaseConfig, locked bool) (*sql.DB, string, error) {
+	if cfg.Driver == config.DriverMySQL {
+		db, err := database.InitMySQL(cfg.DSN)
+		return db, database.RedactDSN(cfg.DSN), err
+	}
+
+	dbPath, err := sqlitePath(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	if !locked {
+		lock, err := database.AcquireLock(dbPath, "sslcerttop migrations")
+		if errors.Is(err, database.ErrLocked) {
+			db, err := database.OpenSQLite(dbPath)
+			return db, dbPath, err
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		defer lock.Release()
+	}
+	db, err := database.InitSQLite(dbPath)
+	return db, dbPath, err
+}
+
+// sqlitePath is where the configured SQLite database is
+func sqlitePath(cfg config.DatabaseConfig) (string, error) {
+	if cfg.Path != "" {
+		return cfg.Path, nil
+	}
+	return defaultDBPath()
+}
+
+// defaultDBPath returns the database location in the data directory, first moving a database left in the old location
+func defaultDBPath() (string, error) {
+	dbPath, err := database.GetDefaultDBPath()
+	if err != nil {
+		return "", fmt.Errorf("failed to get database path: %w", err)
+	}
+	legacyPath, err := database.GetLegacyDBPath()
+	if err != nil {
+		return "", fmt.Errorf("failed to get database path: %w", err)
+	}
+	if _, err := database.MigrateLegacyDB(legacyPath, dbPath); err != nil {
+		return "", err
+	}
+	return dbPath, nil
+}
+
+// addDBFlag registers --db on a command, overriding the configured database path
+func addDBFlag(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.Database.Path, "db", cfg.Database.Path, "SQLite database file (default: $SSLCERTTOP_DB or sslcerttop.db in the data directory)")
+}
+
+// addPoolFlags registers --workers and --queue-size on a command, overriding the configured worker pool size
+func addPoolFlags(fs *flag.FlagSet, cfg *config.Config) {
+	fs.IntVar(&cfg.Workers, "workers", cfg.Workers, "certificate checks run concurrently (default: $SSLCERTTOP_WORKERS or workers)")
+	fs.IntVar(&cfg.QueueSize, "queue-size", cfg.QueueSize, "checks that can wait for a worker (default: $SSLCERTTOP_QUEUE_SIZE or queue_size)")
+}
+
+// currentUser returns who commands act as, the user signed in to the TUI once anyone registered
+func (s *services) currentUser() (types.UserID, error) {
+	required, err := s.userService.RequiresLogin()
+	if err != nil {
+		return 0, err
+	}
+	if !required {
+		return user.DefaultUserID, nil
+	}
+
+	sessionFile, err := user.DefaultSessionFile()
+	if err != nil {
+		return 0, err
+	}
+	token, err := sessionFile.Load()
+	if err != nil {
+		return 0, err
+	}
+	if token == "" {
+		return 0, errors.New("not signed in, sign in with sslcerttop first")
+	}
+	u, err := s.userService.ResumeSession(token)
+	if err != nil {
+		return 0, fmt.Errorf("%w, sign in with sslcerttop again", err)
+	}
+	return u.UserID, nil
+}
+
+// lockDatabase claims the SQLite database for openServices, so instances that keep writing to it, like the daemon
+// and the TUI, refuse to start next to each other rather than clash. It runs before the database is opened, whose
+// migrations only run under the lock: other commands skip them while an instance holds it. MySQL handles
+// concurrent writers itself, so there is no lock for it
+func lockDatabase(cfg *config.Config, command string) (*database.Lock, error) {
+	if cfg.Database.Driver == config.DriverMySQL {
+		return nil, nil
+	}
+	dbPath, err := sqlitePath(cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+	lock, err := database.AcquireLock(dbPath, command)
+	if errors.Is(err, database.ErrLocked) {
+		return nil, fmt.Errorf("%w, stop it or use another --db", err)
+	}
+	return lock, err
+}
+
+// Close stops the worker pool, closes the database and lets go of its lock
+func (s *services) Close() {
+	s.sslService.Stop()
+	s.db.Close()
+	if s.lock != nil {
+		s.lock.Release()
+	}
+}
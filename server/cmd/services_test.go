@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLockDatabase - a second long running instance is turned away before it opens, let alone migrates, the
+// database.
+func TestLockDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "data", "sslcerttop.db")
+	cfg := &config.Config{Workers: 1, QueueSize: 1, Database: config.DatabaseConfig{Path: dbPath}}
+
+	lock, err := lockDatabase(cfg, "sslcerttop daemon")
+	require.NoError(t, err)
+	_, err = os.Stat(dbPath)
+	require.ErrorIs(t, err, os.ErrNotExist, "The lock is taken before the database is opened")
+
+	_, err = lockDatabase(cfg, "sslcerttop")
+	require.ErrorIs(t, err, database.ErrLocked)
+	assert.Contains(t, err.Error(), "sslcerttop daemon")
+	_, err = os.Stat(dbPath)
+	assert.ErrorIs(t, err, os.ErrNotExist, "The second instance never touched the database")
+
+	svc, err := openServices(cfg, lock)
+	require.NoError(t, err)
+	_, err = lockDatabase(cfg, "sslcerttop")
+	assert.ErrorIs(t, err, database.ErrLocked, "The services hold the lock they were opened with")
+
+	svc.Close()
+	lock, err = lockDatabase(cfg, "sslcerttop")
+	require.NoError(t, err, "Closing the services releases the lock")
+	lock.Release()
+}
+
+// TestOpenServices_Migrations - one-shot commands leave the migrations to the instance holding the database, and
+// run them once nothing does.
+func TestOpenServices_Migrations(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "sslcerttop.db")
+	cfg := &config.Config{Workers: 1, QueueSize: 1, Database: config.DatabaseConfig{Path: dbPath}}
+	db, err := database.InitSQLite(dbPath)
+	require.NoError(t, err)
+	_, err = db.Exec(`DROP TABLE digests`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	tableExists := func(svc *services) bool {
+		var count int
+		require.NoError(t, svc.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'digests'`).Scan(&count))
+		return count > 0
+	}
+
+	lock, err := lockDatabase(cfg, "sslcerttop daemon")
+	require.NoError(t, err)
+	svc, err := openServices(cfg, nil)
+	require.NoError(t, err)
+	assert.False(t, tableExists(svc), "Not migrated under the daemon")
+	svc.Close()
+	require.NoError(t, lock.Release())
+
+	svc, err = openServices(cfg, nil)
+	require.NoError(t, err)
+	defer svc.Close()
+	assert.True(t, tableExists(svc), "Migrated once the database is free")
+	lock, err = lockDatabase(cfg, "sslcerttop daemon")
+	require.NoError(t, err, "The lock taken for the migrations is let go")
+	lock.Release()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/statuspage"
+)
+
+// runStatusPage renders a static status page of the tracked domains for publishing
+func runStatusPage(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("statuspage", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop statuspage --out <directory> [--title <title>] [--tags tag,tag,...] [--show-errors] [--output table|json|csv]")
+		fs.PrintDefaults()
+	}
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	outDir := fs.String("out", "", "directory to write index.html and status.json to")
+	title := fs.String("title", "", "page title (default: Certificate status)")
+	tags := fs.String("tags", "", "only publish domains with one of these comma separated tags")
+	showErrors := fs.Bool("show-errors", false, "publish why failing checks failed, which can reveal internal addresses")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *outDir == "" {
+		fs.Usage()
+		return errors.New("missing --out directory")
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+	domains, err := svc.domainService.GetUsersDomains(userID)
+	if err != nil {
+		return err
+	}
+
+	page := statuspage.Build(domains, statuspage.Options{
+		Title:      *title,
+		Tags:       domain.ParseTags(*tags),
+		ShowErrors: *showErrors,
+	}, time.Now())
+	if err := statuspage.Write(*outDir, page); err != nil {
+		return err
+	}
+	out := newRecords("file", "domains")
+	for _, name := range []string{"index.html", "status.json"} {
+		out.add(filepath.Join(*outDir, name), page.Total())
+	}
+	return out.write(os.Stdout, output.format)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRunStatusPage_Output - the files written are reported in the chosen format.
+func TestRunStatusPage_Output(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{Workers: 1, QueueSize: 1, Database: config.DatabaseConfig{Path: filepath.Join(dir, "sslcerttop.db")}}
+	public := filepath.Join(dir, "public")
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runStatusPage(cfg, []string{"--out", public, "--output", "json"})
+	})
+	require.NoError(t, err)
+
+	var files []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(out), &files))
+	require.Len(t, files, 2)
+	assert.Equal(t, filepath.Join(public, "index.html"), files[0]["file"])
+	assert.Equal(t, filepath.Join(public, "status.json"), files[1]["file"])
+	assert.FileExists(t, filepath.Join(public, "status.json"))
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/notification"
+	"github.com/samokw/ssl_tracker/internal/scheduler"
+)
+
+// runSweep checks the given domains, or every tracked one, once, sends the notifications that are due and exits,
+// for containers run by a scheduler such as a Kubernetes CronJob
+func runSweep(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("sweep", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop sweep [domain...] [--ephemeral] [--exit-status] [--output json|table|csv]")
+		fmt.Fprintln(fs.Output(), "Domains can also be listed in $SSLCERTTOP_DOMAINS, separated by commas or spaces")
+		fs.PrintDefaults()
+	}
+	output := addOutputFlagWithDefault(fs, outputJSON)
+	addDBFlag(fs, cfg)
+	addPoolFlags(fs, cfg)
+	ephemeral := fs.Bool("ephemeral", false, "keep no state, using a temporary database removed on exit")
+	exitStatus := fs.Bool("exit-status", false, "exit 1 when a certificate expires soon and 2 when one is critical, expired or failing, like check")
+	names, err := parseInterleaved(fs, args)
+	if err != nil {
+		return err
+	}
+	names = append(names, strings.FieldsFunc(os.Getenv("SSLCERTTOP_DOMAINS"), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\t'
+	})...)
+
+	if *ephemeral {
+		if cfg.Database.Driver == config.DriverMySQL {
+			return errors.New("--ephemeral only works with SQLite")
+		}
+		dir, err := os.MkdirTemp("", "sslcerttop-")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary database directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
+		cfg.Database.Path = filepath.Join(dir, "sslcerttop.db")
+	}
+
+	lock, err := lockDatabase(cfg, "sslcerttop sweep")
+	if err != nil {
+		return err
+	}
+	svc, err := openServices(cfg, lock)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+	var domains []domain.Domain
+	if len(names) == 0 {
+		if domains, err = svc.domainService.GetUsersDomains(userID); err != nil {
+			return err
+		}
+	}
+	// Names that can't be tracked, e.g. because they no longer resolve, are reported like failed checks
+	rejected := map[string]error{}
+	for _, name := range names {
+		d, err := svc.domainService.FindDomainByName(userID, name)
+		if errors.Is(err, domain.ErrNotFound) {
+			d, err = svc.domainService.AddTeamDomainUnchecked(userID, 0, name)
+		}
+		if errors.Is(err, domain.ErrInvalidInput) {
+			rejected[name] = err
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		domains = append(domains, *d)
+	}
+	if len(domains) == 0 && len(rejected) == 0 {
+		fs.Usage()
+		return errors.New("no domains to check, list them as arguments or in $SSLCERTTOP_DOMAINS")
+	}
+
+	dispatcher, providers, err := newDispatcher(cfg, svc.notificationRepo)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Every domain is checked, so the tick and default interval only plan checks no one waits for
+	sched := scheduler.NewScheduler(svc.domainService, dispatcher, time.Minute, 24*time.Hour)
+	sched.SetAlerter(notification.NewAlerter(svc.notificationRepo, cfg.Notifications.IncidentTags, providers...))
+	var sweepErr error
+	if len(domains) > 0 {
+		sweepErr = sched.SweepDomains(ctx, domains)
+	}
+	var storeErr *domain.StoreError
+	if sweepErr != nil && !errors.As(sweepErr, &storeErr) {
+		return sweepErr
+	}
+
+	out := newRecords("domain", "status", "reason", "days_left", "expiry_date", "error")
+	worst := checkOK
+	for _, name := range names {
+		if err, ok := rejected[name]; ok {
+			msg := err.Error()
+			out.add(name, string(domain.StatusError), "could not be tracked", nil, nil, &msg)
+			worst = checkCritical
+		}
+	}
+	for _, d := range domains {
+		checked, err := svc.domainService.GetDomain(d.DomainID)
+		if err != nil {
+			return err
+		}
+		status := svc.domainService.Status(*checked)
+		var lastError *string
+		if checked.LastError != nil {
+			msg := checked.LastError.String()
+			lastError = &msg
+		}
+		out.add(checked.DomainName.String(), string(status.Level), status.Reason, status.DaysLeft, checked.ExpiryTime(), lastError)
+
+		switch status.Level {
+		case domain.StatusExpiringSoon:
+			worst = max(worst, checkWarning)
+		case domain.StatusCritical, domain.StatusExpired, domain.StatusError:
+			worst = max(worst, checkCritical)
+		}
+	}
+	if err := out.write(os.Stdout, output.format); err != nil {
+		return err
+	}
+	// Results that couldn't be stored are missing from the output, which is worse than any status
+	if sweepErr != nil {
+		return sweepErr
+	}
+	if *exitStatus && worst != checkOK {
+		return exitCodeError(worst)
+	}
+	return nil
+}
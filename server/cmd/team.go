@@ -0,0 +1,218 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/team"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// runTeam manages teams, their members and the domains shared with them
+func runTeam(cfg *config.Config, args []string) error {
+	usage := "Usage: sslcerttop team create <name> | list | delete <team> | members <team> | add [--role owner|member] <team> <email> | remove <team> <email> | role <team> <email> <role> | share <team> <domain> | unshare <domain> [--output table|json|csv]"
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		return errors.New("missing team command")
+	}
+
+	fs := flag.NewFlagSet("team "+args[0], flag.ExitOnError)
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	var role *string
+	if args[0] == "add" {
+		role = fs.String("role", team.RoleMember.String(), "role of the new member: owner or member")
+	}
+	rest, err := parseInterleaved(fs, args[1:])
+	if err != nil {
+		return err
+	}
+
+	// wantArgs checks the number of arguments after the subcommand
+	wantArgs := func(n int, missing string) error {
+		if len(rest) != n {
+			fmt.Fprintln(os.Stderr, usage)
+			return errors.New(missing)
+		}
+		return nil
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+
+	// member resolves a team by name and a user by email
+	member := func() (*team.Team, types.UserID, error) {
+		t, err := svc.teamService.FindTeam(userID, rest[0])
+		if err != nil {
+			return nil, 0, fmt.Errorf("team %q: %w", rest[0], err)
+		}
+		u, err := svc.userService.GetUserByEmail(rest[1])
+		if err != nil {
+			return nil, 0, err
+		}
+		return t, u.UserID, nil
+	}
+
+	switch args[0] {
+	case "create":
+		if err := wantArgs(1, "missing team name"); err != nil {
+			return err
+		}
+		t, err := svc.teamService.CreateTeam(userID, rest[0])
+		if err != nil {
+			return err
+		}
+		return writeTeams([]team.Team{*t}, true, output.format)
+
+	case "list":
+		teams, err := svc.teamService.GetUsersTeams(userID)
+		if err != nil {
+			return err
+		}
+		return writeTeams(teams, false, output.format)
+
+	case "delete":
+		if err := wantArgs(1, "missing team name"); err != nil {
+			return err
+		}
+		t, err := svc.teamService.FindTeam(userID, rest[0])
+		if err != nil {
+			return fmt.Errorf("team %q: %w", rest[0], err)
+		}
+		if err := svc.teamService.DeleteTeam(userID, t.TeamID); err != nil {
+			return err
+		}
+
+		out := newRecords("team", "status")
+		out.single = true
+		out.add(t.Name, "deleted")
+		return out.write(os.Stdout, output.format)
+
+	case "members":
+		if err := wantArgs(1, "missing team name"); err != nil {
+			return err
+		}
+		t, err := svc.teamService.FindTeam(userID, rest[0])
+		if err != nil {
+			return fmt.Errorf("team %q: %w", rest[0], err)
+		}
+		members, err := svc.teamService.GetMembers(userID, t.TeamID)
+		if err != nil {
+			return err
+		}
+
+		out := newRecords("email", "role", "joined_at")
+		for _, m := range members {
+			out.add(m.Email, m.Role.String(), m.JoinedAt)
+		}
+		return out.write(os.Stdout, output.format)
+
+	case "add", "role":
+		want := 2
+		if args[0] == "role" {
+			want = 3
+		}
+		if err := wantArgs(want, "missing team, email or role"); err != nil {
+			return err
+		}
+		t, memberID, err := member()
+		if err != nil {
+			return err
+		}
+		if args[0] == "add" {
+			parsed, err := team.ParseRole(*role)
+			if err != nil {
+				return err
+			}
+			err = svc.teamService.AddMember(userID, t.TeamID, memberID, parsed)
+			if err != nil {
+				return err
+			}
+			return writeMember(t, rest[1], parsed, output.format)
+		}
+		parsed, err := team.ParseRole(rest[2])
+		if err != nil {
+			return err
+		}
+		if err := svc.teamService.SetRole(userID, t.TeamID, memberID, parsed); err != nil {
+			return err
+		}
+		return writeMember(t, rest[1], parsed, output.format)
+
+	case "remove":
+		if err := wantArgs(2, "missing team or email"); err != nil {
+			return err
+		}
+		t, memberID, err := member()
+		if err != nil {
+			return err
+		}
+		if err := svc.teamService.RemoveMember(userID, t.TeamID, memberID); err != nil {
+			return err
+		}
+
+		out := newRecords("team", "email", "status")
+		out.single = true
+		out.add(t.Name, rest[1], "removed")
+		return out.write(os.Stdout, output.format)
+
+	case "share", "unshare":
+		var teamID types.TeamID
+		teamName := ""
+		if args[0] == "share" {
+			if err := wantArgs(2, "missing team or domain"); err != nil {
+				return err
+			}
+			t, err := svc.teamService.FindTeam(userID, rest[0])
+			if err != nil {
+				return fmt.Errorf("team %q: %w", rest[0], err)
+			}
+			teamID, teamName = t.TeamID, t.Name
+			rest = rest[1:]
+		} else if err := wantArgs(1, "missing domain"); err != nil {
+			return err
+		}
+
+		d, err := svc.domainService.FindDomainByName(userID, rest[0])
+		if err != nil {
+			return err
+		}
+		if err := svc.domainService.ShareDomain(userID, d.DomainID, teamID); err != nil {
+			return err
+		}
+		return writeDomainSetting(d, "team", teamName, output.format)
+
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return fmt.Errorf("unknown team command %q", args[0])
+	}
+}
+
+// writeTeams prints teams, as a single record when one was just created
+func writeTeams(teams []team.Team, single bool, format outputFormat) error {
+	out := newRecords("id", "name", "created_at")
+	out.single = single
+	for _, t := range teams {
+		out.add(t.TeamID.Uint(), t.Name, t.CreatedAt)
+	}
+	return out.write(os.Stdout, format)
+}
+
+// writeMember prints a member's role after it was added or changed
+func writeMember(t *team.Team, email string, role team.Role, format outputFormat) error {
+	out := newRecords("team", "email", "role")
+	out.single = true
+	out.add(t.Name, email, role.String())
+	return out.write(os.Stdout, format)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/samokw/ssl_tracker/internal/buildinfo"
+	"github.com/samokw/ssl_tracker/internal/config"
+)
+
+// runVersion prints the version, commit and build date of the binary
+func runVersion(_ *config.Config, args []string) error {
+	fs := flag.NewFlagSet("version", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop version [--json] [--output text|table|json|csv]")
+	}
+	output := addOutputFlagWithDefault(fs, outputText)
+	asJSON := fs.Bool("json", false, "shorthand for --output json")
+	if _, err := parseInterleaved(fs, args); err != nil {
+		return err
+	}
+	if *asJSON {
+		output.format = outputJSON
+	}
+
+	info := buildinfo.Get()
+	if output.format == outputText {
+		fmt.Println(info)
+		return nil
+	}
+	out := newRecords("version", "commit", "date", "go_version")
+	out.single = true
+	out.add(info.Version, info.Commit, info.Date, info.GoVersion)
+	return out.write(os.Stdout, output.format)
+}
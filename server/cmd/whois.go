@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/config"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/whois"
+)
+
+// runWhois lists when the registrations of the user's domains expire, looking them up first with --lookup
+func runWhois(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("whois", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: sslcerttop whois [domain]... [--lookup] [--output table|json|csv]")
+	}
+	output := addOutputFlag(fs)
+	addDBFlag(fs, cfg)
+	lookup := fs.Bool("lookup", false, "look the registrations up now instead of showing what the daemon last found")
+	rest, err := parseInterleaved(fs, args)
+	if err != nil {
+		return err
+	}
+
+	svc, err := openServices(cfg, nil)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	userID, err := svc.currentUser()
+	if err != nil {
+		return err
+	}
+	var domains []domain.Domain
+	if len(rest) == 0 {
+		if domains, err = svc.domainService.GetUsersDomains(userID); err != nil {
+			return err
+		}
+	}
+	for _, name := range rest {
+		d, err := svc.domainService.FindDomainByName(userID, name)
+		if err != nil {
+			return err
+		}
+		domains = append(domains, *d)
+	}
+
+	var lookupErr error
+	if *lookup {
+		client, err := whois.NewClient(cfg.Whois.RDAPServer, nil)
+		if err != nil {
+			return err
+		}
+		tracker := whois.NewTracker(svc.domainService, client, svc.whoisRepo, cfg.Whois.Interval)
+		lookupErr = tracker.LookUp(context.Background(), domains)
+		for i, d := range domains {
+			updated, err := svc.domainService.GetDomain(d.DomainID)
+			if err != nil {
+				return err
+			}
+			domains[i] = *updated
+		}
+	}
+
+	now := time.Now()
+	out := newRecords("domain", "registered_domain", "registration_expiry", "days_left", "status", "checked_at")
+	for _, d := range domains {
+		registered, err := whois.RegisteredDomain(d.DomainName.String())
+		if errors.Is(err, whois.ErrNotRegistrable) {
+			continue
+		}
+		var daysLeft *int
+		if d.RegistrationExpiry != nil {
+			days := int(d.RegistrationExpiry.Sub(now).Hours() / 24)
+			daysLeft = &days
+		}
+		out.add(d.DomainName.String(), registered, d.RegistrationExpiry, daysLeft,
+			d.RegistrationStatus(cfg.Whois.Warning, cfg.Whois.Critical, now), d.RegistrationChecked)
+	}
+	if err := out.write(os.Stdout, output.format); err != nil {
+		return err
+	}
+	return lookupErr
+}
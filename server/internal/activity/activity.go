@@ -0,0 +1,178 @@
+// Package activity keeps a feed of what happened to the domains a user can see: domains added and deleted,
+// certificates renewed, status changes and the notifications sent about them
+package activity
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/notification"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// KindNotified is a notification sent about a domain, the other kinds are those domain records
+const KindNotified = "notified"
+
+// Event is something that happened to a domain
+type Event struct {
+	At       time.Time
+	Kind     string
+	DomainID types.DomainID
+	Domain   string
+	// Detail says more about the event, e.g. the statuses of a status change, empty when there is nothing to add
+	Detail string
+}
+
+// Repository stores the events domain records, the notifications sent are read from the notifications themselves
+type Repository struct {
+	db     *sql.DB
+	writer *database.Writer
+}
+
+var _ domain.ActivityLog = (*Repository)(nil)
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db:     db,
+		writer: database.NewWriter(db),
+	}
+}
+
+// Record adds an event about d to the feed of whoever added it and of the team it is shared with
+func (r *Repository) Record(d domain.Domain, kind, detail string, at time.Time) error {
+	var teamID sql.NullInt64
+	if d.TeamID != 0 {
+		teamID = sql.NullInt64{Int64: int64(d.TeamID.Uint()), Valid: true}
+	}
+	query := `INSERT INTO activity (user_id, team_id, domain_id, domain_name, kind, detail, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err := r.writer.Exec(query, d.UserID.Uint(), teamID, d.DomainID.Uint(), d.DomainName.String(), kind, detail, at)
+	return err
+}
+
+// List returns the latest events about the domains of a user and of their teams, newest first
+func (r *Repository) List(userID types.UserID, teamIDs []types.TeamID, limit int) ([]Event, error) {
+	recorded, err := r.listRecorded(userID, teamIDs, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list activity: %w", err)
+	}
+	notified, err := r.listNotified(userID, teamIDs, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications sent: %w", err)
+	}
+	events := append(recorded, notified...)
+	slices.SortStableFunc(events, func(a, b Event) int { return b.At.Compare(a.At) })
+	return events[:min(len(events), limit)], nil
+}
+
+// ownedBy is the condition matching the rows of a user and of their teams, with its arguments
+func ownedBy(userColumn, teamColumn string, userID types.UserID, teamIDs []types.TeamID) (string, []any) {
+	args := []any{userID.Uint()}
+	if len(teamIDs) == 0 {
+		return userColumn + ` = ?`, args
+	}
+	for _, id := range teamIDs {
+		args = append(args, id.Uint())
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(teamIDs)), ", ")
+	return fmt.Sprintf(`(%s = ? OR %s IN (%s))`, userColumn, teamColumn, placeholders), args
+}
+
+func (r *Repository) listRecorded(userID types.UserID, teamIDs []types.TeamID, limit int) ([]Event, error) {
+	where, args := ownedBy("user_id", "team_id", userID, teamIDs)
+	query := `SELECT created_at, kind, domain_id, domain_name, detail FROM activity WHERE ` + where +
+		` ORDER BY created_at DESC, id DESC LIMIT ?`
+	rows, err := r.db.Query(query, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var e Event
+		var domainID uint
+		if err := rows.Scan(&e.At, &e.Kind, &domainID, &e.Domain, &e.Detail); err != nil {
+			return nil, err
+		}
+		e.DomainID = types.DomainID(domainID)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (r *Repository) listNotified(userID types.UserID, teamIDs []types.TeamID, limit int) ([]Event, error) {
+	where, args := ownedBy("d.user_id", "d.team_id", userID, teamIDs)
+	query := `SELECT n.sent_at, d.id, d.domain_name, n.notification_type, n.days_before, n.escalated_from
+              FROM notifications n JOIN domains d ON d.id = n.domain_id
+              WHERE n.sent_at IS NOT NULL AND ` + where + ` ORDER BY n.sent_at DESC, n.id DESC LIMIT ?`
+	rows, err := r.db.Query(query, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		e := Event{Kind: KindNotified}
+		var domainID uint
+		var channel string
+		var daysBefore int
+		var escalatedFrom sql.NullInt64
+		if err := rows.Scan(&e.At, &domainID, &e.Domain, &channel, &daysBefore, &escalatedFrom); err != nil {
+			return nil, err
+		}
+		e.DomainID = types.DomainID(domainID)
+		e.Detail = notifiedDetail(channel, daysBefore, escalatedFrom.Valid)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// notifiedDetail describes a notification sent over channel at a threshold
+func notifiedDetail(channel string, daysBefore int, escalation bool) string {
+	var about string
+	switch daysBefore {
+	case notification.ErrorThreshold:
+		about = "failing check"
+	case notification.RenewalOverdueThreshold:
+		about = "overdue renewal"
+	case 0:
+		about = "expired"
+	default:
+		about = fmt.Sprintf("%d days before expiry", daysBefore)
+	}
+	if escalation {
+		return fmt.Sprintf("escalated over %s, %s", channel, about)
+	}
+	return fmt.Sprintf("%s, %s", channel, about)
+}
+
+// Service is the feed of each user
+type Service struct {
+	repo  *Repository
+	teams domain.Teams
+}
+
+// NewService lists the events of the domains a user added, and with teams those of the domains shared with their
+// teams too
+func NewService(repo *Repository, teams domain.Teams) *Service {
+	return &Service{repo: repo, teams: teams}
+}
+
+// Feed returns the latest limit events about the domains a user can see, newest first
+func (s *Service) Feed(userID types.UserID, limit int) ([]Event, error) {
+	var teamIDs []types.TeamID
+	if s.teams != nil {
+		ids, err := s.teams.GetTeamIDs(userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get teams: %w", err)
+		}
+		teamIDs = ids
+	}
+	return s.repo.List(userID, teamIDs, limit)
+}
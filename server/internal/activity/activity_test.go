@@ -0,0 +1,78 @@
+package activity
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// offlineChecker accepts every name without resolving it
+type offlineChecker ssl.CheckerFunc
+
+func (c offlineChecker) CheckSSLCertificate(ctx context.Context, name string) (*ssl.SSLCertificate, error) {
+	return c(ctx, name)
+}
+
+func (offlineChecker) ValidateTarget(string) error { return nil }
+
+// TestService_Feed - additions, renewals, status changes, deletions and notifications sent show up newest first.
+func TestService_Feed(t *testing.T) {
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	repo := NewRepository(db)
+	domains := domain.NewService(domain.NewRepository(db), nil)
+	domains.SetActivityLog(repo)
+	expiry := time.Now().AddDate(0, 2, 0)
+	var checkErr error
+	domains.SetChecker(offlineChecker(func(context.Context, string) (*ssl.SSLCertificate, error) {
+		if checkErr != nil {
+			return nil, checkErr
+		}
+		return &ssl.SSLCertificate{ExpiryDate: types.NewExpiryDate(expiry)}, nil
+	}))
+
+	d, err := domains.AddDomain(types.UserID(1), "example.com")
+	require.NoError(t, err)
+	expiry = expiry.AddDate(0, 3, 0)
+	require.NoError(t, domains.CheckDomainSSL(d.DomainID))
+	checkErr = errors.New("connection refused")
+	require.NoError(t, domains.CheckDomainSSL(d.DomainID))
+
+	other, err := domains.AddDomain(types.UserID(1), "other.example.com")
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO notifications (domain_id, days_before, notification_type, status, created_at, sent_at)
+		VALUES (?, 7, 'slack', 'sent', ?, ?)`, d.DomainID.Uint(), time.Now(), time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, domains.RemoveDomain(other.DomainID))
+
+	feed, err := NewService(repo, nil).Feed(types.UserID(1), 10)
+	require.NoError(t, err)
+	kinds := make([]string, len(feed))
+	for i, e := range feed {
+		kinds[i] = e.Kind
+	}
+	assert.Equal(t, []string{domain.ActivityDeleted, domain.ActivityAdded, domain.ActivityStatus, domain.ActivityRenewed,
+		domain.ActivityAdded, KindNotified}, kinds, "The first check of a domain isn't a change")
+	assert.Equal(t, "other.example.com", feed[0].Domain, "Deleted domains stay in the feed")
+	assert.Equal(t, "valid → error, connection refused", feed[2].Detail)
+	assert.Equal(t, "slack, 7 days before expiry", feed[5].Detail)
+
+	feed, err = NewService(repo, nil).Feed(types.UserID(1), 2)
+	require.NoError(t, err)
+	assert.Len(t, feed, 2)
+
+	feed, err = NewService(repo, nil).Feed(types.UserID(2), 10)
+	require.NoError(t, err)
+	assert.Empty(t, feed, "Other users' domains aren't in the feed")
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/notification"
+)
+
+// AckResponse is the JSON representation of a domain acknowledgement
+type AckResponse struct {
+	DomainID   uint       `json:"domain_id"`
+	Domain     string     `json:"domain"`
+	Note       string     `json:"note"`
+	ExpiryDate *time.Time `json:"expiry_date"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	// Active is false once the ack has expired or the domain serves a different certificate
+	Active bool `json:"active"`
+}
+
+// AckRequest is the body of a request to acknowledge a domain
+type AckRequest struct {
+	Note string `json:"note"`
+	// ExpiresAt ends the ack early, without it the ack lasts until the certificate changes
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func newAckResponse(a notification.DomainAck, expiry *time.Time, loc *time.Location) AckResponse {
+	return AckResponse{
+		DomainID:   a.DomainID.Uint(),
+		Domain:     a.DomainName,
+		Note:       a.Note,
+		ExpiryDate: inZonePtr(a.ExpiryDate, loc),
+		ExpiresAt:  inZonePtr(a.ExpiresAt, loc),
+		CreatedAt:  inZone(a.CreatedAt, loc),
+		Active:     a.Active(expiry, time.Now()),
+	}
+}
+
+func (s *Server) handleListAcks(w http.ResponseWriter, r *http.Request) {
+	acks, err := s.notificationService.GetUsersAcks(userFromRequest(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	loc := s.location(r)
+	resp := make([]AckResponse, len(acks))
+	for i, a := range acks {
+		d, err := s.domainService.GetDomain(a.DomainID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		resp[i] = newAckResponse(a, d.ExpiryTime(), loc)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleGetAck(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.domainFromPath(w, r)
+	if !ok {
+		return
+	}
+	a, err := s.notificationService.GetDomainAck(d.DomainID)
+	if err != nil {
+		writeError(w, ackErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newAckResponse(*a, d.ExpiryTime(), s.location(r)))
+}
+
+func (s *Server) handleSetAck(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.domainFromPath(w, r)
+	if !ok {
+		return
+	}
+	var req AckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	a, err := s.notificationService.AcknowledgeDomain(*d, req.Note, req.ExpiresAt)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newAckResponse(*a, d.ExpiryTime(), s.location(r)))
+}
+
+func (s *Server) handleDeleteAck(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.domainFromPath(w, r)
+	if !ok {
+		return
+	}
+	if err := s.notificationService.RemoveAck(d.DomainID); err != nil {
+		writeError(w, ackErrorStatus(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ackErrorStatus answers domains without an acknowledgement with not found
+func ackErrorStatus(err error) int {
+	if errors.Is(err, notification.ErrNoAck) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAcks - acknowledge a domain, list and fetch the ack, then remove it.
+func TestAcks(t *testing.T) {
+	s, _, _, id := newTestServerDB(t)
+	path := fmt.Sprintf("/api/v1/domains/%d/ack", id.Uint())
+
+	rec := doRequest(t, s, http.MethodGet, path, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = doRequest(t, s, http.MethodPut, path, AckRequest{Note: strings.Repeat("x", 1025)})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	until := time.Now().Add(72 * time.Hour)
+	rec = doRequest(t, s, http.MethodPut, path, AckRequest{Note: "renewal scheduled for Friday", ExpiresAt: &until})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var ack AckResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &ack))
+	assert.Equal(t, "example.com", ack.Domain)
+	assert.Equal(t, "renewal scheduled for Friday", ack.Note)
+	assert.True(t, ack.Active)
+
+	rec = doRequest(t, s, http.MethodGet, "/api/v1/acks", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list []AckResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list, 1)
+	assert.Equal(t, id.Uint(), list[0].DomainID)
+
+	rec = doRequest(t, s, http.MethodDelete, path, nil)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = doRequest(t, s, http.MethodDelete, path, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = doRequest(t, s, http.MethodPut, "/api/v1/domains/999/ack", AckRequest{Note: "missing"})
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/apikey"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/samokw/ssl_tracker/internal/user"
+)
+
+type contextKey int
+
+const userContextKey contextKey = iota
+
+// sessionTokenPrefix tells session tokens apart from API keys
+const sessionTokenPrefix = "scs_"
+
+// publicPaths are served without an API key so probes and tooling can reach them
+var publicPaths = map[string]bool{
+	"/healthz":             true,
+	"/readyz":              true,
+	"/metrics":             true,
+	"/api/v1/openapi.json": true,
+	"/api/v1/version":      true,
+	"/api/v1/auth/login":   true,
+	// The provider redirects browsers here without credentials
+	"/api/v1/auth/oidc/login":    true,
+	"/api/v1/auth/oidc/callback": true,
+}
+
+// RequireAPIKeys makes every non-public route require a key in the Authorization header.
+//
+// Read-only keys may only use GET requests
+func (s *Server) RequireAPIKeys(keyService *apikey.Service) {
+	s.keyService = keyService
+}
+
+// EnableSessions lets accounts sign in with their email and password for tokens lasting lifetime,
+// which are accepted wherever an API key is and can use every route
+func (s *Server) EnableSessions(userService *user.Service, lifetime time.Duration) {
+	s.userService = userService
+	s.sessionLifetime = lifetime
+}
+
+// authRequired reports whether requests need credentials at all
+func (s *Server) authRequired() bool {
+	return s.keyService != nil || s.userService != nil
+}
+
+// authenticate resolves the caller's key, session or ID token, writing an error response and returning nil if the request may not proceed
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request) *http.Request {
+	if !s.authRequired() || publicPaths[r.URL.Path] {
+		return r
+	}
+
+	token, ok := bearerToken(r)
+	if !ok && s.local && s.userService != nil {
+		required, err := s.userService.RequiresLogin()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return nil
+		}
+		if !required {
+			return r
+		}
+	}
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="sslcerttop"`)
+		writeError(w, http.StatusUnauthorized, errors.New("missing API key or session token"))
+		return nil
+	}
+
+	if strings.HasPrefix(token, sessionTokenPrefix) && s.userService != nil {
+		u, err := s.userService.ResumeSession(token)
+		if err != nil {
+			writeAuthError(w, err)
+			return nil
+		}
+		return r.WithContext(context.WithValue(r.Context(), userContextKey, u.UserID))
+	}
+
+	if isIDToken(token) && s.oidcProvider != nil && s.userService != nil {
+		userID, err := s.authenticateIDToken(r, token)
+		if err != nil {
+			writeAuthError(w, err)
+			return nil
+		}
+		return r.WithContext(context.WithValue(r.Context(), userContextKey, userID))
+	}
+
+	if s.keyService == nil {
+		writeAuthError(w, apikey.ErrInvalidKey)
+		return nil
+	}
+	key, err := s.keyService.Authenticate(token)
+	if err != nil {
+		writeAuthError(w, err)
+		return nil
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && !key.Scope.CanWrite() {
+		writeError(w, http.StatusForbidden, errors.New("API key is read-only"))
+		return nil
+	}
+	return r.WithContext(context.WithValue(r.Context(), userContextKey, key.UserID))
+}
+
+// bearerToken returns the token from the Authorization header.
+//
+// The event stream also takes it from ?access_token= since browsers can't set headers on an EventSource
+func bearerToken(r *http.Request) (string, bool) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token), true
+	}
+	if r.URL.Path == "/api/v1/events" {
+		if token := r.URL.Query().Get("access_token"); token != "" {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+// writeAuthError answers a request whose credentials were rejected
+func writeAuthError(w http.ResponseWriter, err error) {
+	if errors.Is(err, apikey.ErrInvalidKey) || errors.Is(err, user.ErrInvalidSession) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="sslcerttop", error="invalid_token"`)
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err)
+}
+
+// userFromRequest returns the user a request acts on behalf of
+func userFromRequest(r *http.Request) types.UserID {
+	if userID, ok := r.Context().Value(userContextKey).(types.UserID); ok {
+		return userID
+	}
+	return types.UserID(1) // Use default user when keys aren't required
+}
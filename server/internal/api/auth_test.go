@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/apikey"
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/samokw/ssl_tracker/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestKeyService creates a key service with one read-only and one read-write key.
+func newTestKeyService(t *testing.T) (*apikey.Service, string, string) {
+	t.Helper()
+
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "keys.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	keys := apikey.NewService(apikey.NewRepository(db))
+	readKey, _, err := keys.CreateKey(types.UserID(1), "reader", apikey.ScopeRead)
+	require.NoError(t, err)
+	writeKey, _, err := keys.CreateKey(types.UserID(1), "writer", apikey.ScopeReadWrite)
+	require.NoError(t, err)
+	return keys, readKey, writeKey
+}
+
+func doAuthRequest(s *Server, method, path, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestRequireAPIKeys - missing and unknown keys are rejected, public paths stay open.
+func TestRequireAPIKeys(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	keys, readKey, _ := newTestKeyService(t)
+	s.RequireAPIKeys(keys)
+
+	rec := doAuthRequest(s, http.MethodGet, "/api/v1/domains", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+
+	rec = doAuthRequest(s, http.MethodGet, "/api/v1/domains", "sct_wrong")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = doAuthRequest(s, http.MethodGet, "/api/v1/domains", readKey)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	for _, path := range []string{"/healthz", "/readyz", "/api/v1/openapi.json"} {
+		rec = doAuthRequest(s, http.MethodGet, path, "")
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+	}
+}
+
+// TestRequireAPIKeys_Scope - read-only keys can't change anything.
+func TestRequireAPIKeys_Scope(t *testing.T) {
+	s, _, id := newTestServer(t)
+	keys, readKey, writeKey := newTestKeyService(t)
+	s.RequireAPIKeys(keys)
+
+	rec := doAuthRequest(s, http.MethodDelete, "/api/v1/domains/"+idString(id), readKey)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = doAuthRequest(s, http.MethodDelete, "/api/v1/domains/"+idString(id), writeKey)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+// newTestUserService creates a user service with one account.
+func newTestUserService(t *testing.T) *user.Service {
+	t.Helper()
+
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "users.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	users := user.NewService(user.NewRepository(db))
+	_, err = users.Register("alice@example.com", "correct horse")
+	require.NoError(t, err)
+	return users
+}
+
+// TestSessions - signing in issues a token that works until it is refreshed or logged out.
+func TestSessions(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	keys, _, writeKey := newTestKeyService(t)
+	s.RequireAPIKeys(keys)
+	s.EnableSessions(newTestUserService(t), time.Hour)
+
+	rec := doRequest(t, s, http.MethodPost, "/api/v1/auth/login", LoginRequest{Email: "alice@example.com", Password: "wrong"})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = doRequest(t, s, http.MethodPost, "/api/v1/auth/login", LoginRequest{Email: "alice@example.com", Password: "correct horse"})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var session SessionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &session))
+	assert.WithinDuration(t, time.Now().Add(time.Hour), session.ExpiresAt, time.Minute)
+
+	rec = doAuthRequest(s, http.MethodDelete, "/api/v1/domains/999", session.Token)
+	assert.Equal(t, http.StatusNotFound, rec.Code, "sessions may write")
+
+	rec = doAuthRequest(s, http.MethodPost, "/api/v1/auth/refresh", writeKey)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doAuthRequest(s, http.MethodPost, "/api/v1/auth/refresh", session.Token)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var refreshed SessionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &refreshed))
+	assert.NotEqual(t, session.Token, refreshed.Token)
+
+	rec = doAuthRequest(s, http.MethodGet, "/api/v1/domains", session.Token)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "refreshing ends the old token")
+
+	rec = doAuthRequest(s, http.MethodPost, "/api/v1/auth/logout", refreshed.Token)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = doAuthRequest(s, http.MethodGet, "/api/v1/domains", refreshed.Token)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+// TestSessions_EventStreamToken - the event stream accepts the token as a query parameter.
+func TestSessions_EventStreamToken(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	s.EnableSessions(newTestUserService(t), time.Hour)
+
+	rec := doAuthRequest(s, http.MethodGet, "/api/v1/events?access_token=scs_unknown", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = doAuthRequest(s, http.MethodGet, "/api/v1/domains?access_token=scs_unknown", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "missing")
+}
@@ -0,0 +1,305 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// DomainResponse is the JSON representation of a tracked domain
+type DomainResponse struct {
+	ID          uint       `json:"id"`
+	Domain      string     `json:"domain"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiryDate  *time.Time `json:"expiry_date"`
+	DaysLeft    *int       `json:"days_left"`
+	LastChecked *time.Time `json:"last_checked"`
+	LastError   *string    `json:"last_error"`
+	IsActive    bool       `json:"is_active"`
+	Status      string     `json:"status"`
+	// StatusReason explains the status, e.g. how many days are left or why the last check failed
+	StatusReason  string   `json:"status_reason"`
+	Tags          []string `json:"tags"`
+	CheckSchedule string   `json:"check_schedule,omitempty"`
+	// TeamID is the team the domain is shared with, absent for private domains
+	TeamID *uint `json:"team_id,omitempty"`
+	// Fingerprint is the host key of SSH targets
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// RegistrationExpiry is when the domain's registration expires, absent until the daemon looked it up
+	RegistrationExpiry *time.Time `json:"registration_expiry,omitempty"`
+	// DNSError describes how the domain's DNS records differ from those expected, absent when they matched
+	DNSError *string `json:"dns_error,omitempty"`
+	// Warning describes a problem with a certificate that is otherwise fine, e.g. a CA the CAA records don't authorize
+	Warning *string `json:"warning,omitempty"`
+	// AutoRenewDays is how many days before expiry the certificate renews automatically, absent when it doesn't
+	AutoRenewDays int `json:"auto_renew_days,omitempty"`
+	// AutoRenewVia names what renews the certificate automatically, e.g. cert-manager
+	AutoRenewVia string `json:"auto_renew_via,omitempty"`
+	// CertFingerprint is the SHA-256 fingerprint of the domain's certificate, shared by domains serving the same one
+	CertFingerprint string `json:"cert_fingerprint,omitempty"`
+	// SANs are the DNS names the certificate is valid for
+	SANs []string `json:"sans,omitempty"`
+	// ExpectedSANs are the DNS names the certificate has to cover, checks fail when it doesn't
+	ExpectedSANs []string `json:"expected_sans,omitempty"`
+	// IssuedAt is when the certificate became valid
+	IssuedAt *time.Time `json:"issued_at,omitempty"`
+	// KeyType describes the certificate's public key, e.g. RSA 2048 or ECDSA P-256
+	KeyType string `json:"key_type,omitempty"`
+	// Signatures are the signature algorithms of the certificate and its intermediates, e.g. SHA256-RSA
+	Signatures []string `json:"signatures,omitempty"`
+}
+
+// CheckRecordResponse is the JSON representation of one historical check
+type CheckRecordResponse struct {
+	CheckedAt  time.Time  `json:"checked_at"`
+	ExpiryDate *time.Time `json:"expiry_date"`
+	Error      *string    `json:"error"`
+	// Fingerprint is the host key an SSH check saw
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// AddDomainRequest is the body of a request to track a new domain
+type AddDomainRequest struct {
+	Domain string `json:"domain"`
+	// TeamID shares the domain with one of the caller's teams, zero keeps it private
+	TeamID uint `json:"team_id,omitempty"`
+}
+
+// newDomainResponse represents a domain in its status, as the domain service works it out
+func newDomainResponse(d domain.Domain, status domain.DomainStatus, loc *time.Location) DomainResponse {
+	resp := DomainResponse{
+		ID:              d.DomainID.Uint(),
+		Domain:          d.DomainName.String(),
+		CreatedAt:       inZone(d.CreatedAt.Time(), loc),
+		IsActive:        d.IsActive,
+		Status:          string(status.Level),
+		StatusReason:    status.Reason,
+		Tags:            d.Tags,
+		CheckSchedule:   d.CheckSchedule,
+		Fingerprint:     d.Fingerprint,
+		DNSError:        d.DNSError,
+		Warning:         d.Warning,
+		AutoRenewDays:   d.AutoRenewDays,
+		AutoRenewVia:    d.AutoRenewVia,
+		CertFingerprint: d.CertFingerprint,
+		SANs:            d.SANs,
+		ExpectedSANs:    d.ExpectedSANs,
+		KeyType:         d.KeyType,
+		Signatures:      d.Signatures,
+	}
+	if d.ExpiryDate != nil {
+		expiry := inZone(d.ExpiryDate.Time(), loc)
+		resp.ExpiryDate = &expiry
+		resp.DaysLeft = status.DaysLeft
+	}
+	if d.LastChecked != nil {
+		lastChecked := inZone(d.LastChecked.Time(), loc)
+		resp.LastChecked = &lastChecked
+	}
+	if d.IssuedAt != nil {
+		issuedAt := inZone(*d.IssuedAt, loc)
+		resp.IssuedAt = &issuedAt
+	}
+	if d.RegistrationExpiry != nil {
+		registrationExpiry := inZone(*d.RegistrationExpiry, loc)
+		resp.RegistrationExpiry = &registrationExpiry
+	}
+	if d.LastError != nil {
+		lastError := d.LastError.String()
+		resp.LastError = &lastError
+	}
+	if d.TeamID != 0 {
+		teamID := d.TeamID.Uint()
+		resp.TeamID = &teamID
+	}
+	return resp
+}
+
+// visible reports whether a user may see a domain, their own or one shared with their team
+func (s *Server) visible(userID types.UserID, d *domain.Domain) bool {
+	ok, err := s.domainService.CanAccess(userID, *d)
+	return err == nil && ok
+}
+
+// domainFromPath loads the domain named by the {id} path value, writing an error response if it can't
+func (s *Server) domainFromPath(w http.ResponseWriter, r *http.Request) (*domain.Domain, bool) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil || id == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("invalid domain ID"))
+		return nil, false
+	}
+
+	d, err := s.domainService.GetDomain(types.NewDomainID(uint(id)))
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		writeError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if err != nil || !s.visible(userFromRequest(r), d) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("domain with ID %d not found", id))
+		return nil, false
+	}
+	return d, true
+}
+
+// domainErrorStatus maps an error from the domain layer to the status code it is answered with
+func domainErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrDuplicate):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrInvalidInput):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (s *Server) handleListDomains(w http.ResponseWriter, r *http.Request) {
+	domains, err := s.domainService.GetUsersDomains(userFromRequest(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	loc := s.location(r)
+	resp := make([]DomainResponse, len(domains))
+	for i, d := range domains {
+		resp[i] = newDomainResponse(d, s.domainService.Status(d), loc)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleAddDomain(w http.ResponseWriter, r *http.Request) {
+	var req AddDomainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if ssl.IsFileTarget(req.Domain) {
+		// Files are read from the server's disk, so only someone on that machine may point at them
+		writeError(w, http.StatusBadRequest, errors.New("certificate files can only be added on the server"))
+		return
+	}
+
+	// The first check runs in the background, the domain is returned unchecked
+	d, err := s.domainService.AddTeamDomain(userFromRequest(r), types.TeamID(req.TeamID), req.Domain)
+	if err != nil {
+		writeError(w, domainErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newDomainResponse(*d, s.domainService.Status(*d), s.location(r)))
+}
+
+func (s *Server) handleGetDomain(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.domainFromPath(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, newDomainResponse(*d, s.domainService.Status(*d), s.location(r)))
+}
+
+func (s *Server) handleDeleteDomain(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.domainFromPath(w, r)
+	if !ok {
+		return
+	}
+	if err := s.domainService.RemoveDomain(d.DomainID); err != nil {
+		writeError(w, domainErrorStatus(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleCheckDomain(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.domainFromPath(w, r)
+	if !ok {
+		return
+	}
+	if err := s.domainService.CheckDomainSSL(d.DomainID); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	checked, err := s.domainService.GetDomain(d.DomainID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newDomainResponse(*checked, s.domainService.Status(*checked), s.location(r)))
+}
+
+func (s *Server) handleCheckAll(w http.ResponseWriter, r *http.Request) {
+	userID := userFromRequest(r)
+	check := s.domainService.CheckAllDomainsSSLSync
+	if r.URL.Query().Get("failed") == "true" {
+		check = s.domainService.CheckFailedDomainsSSLSync
+	}
+	if err := check(userID); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.handleListDomains(w, r)
+}
+
+func (s *Server) handleDomainHistory(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.domainFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, errors.New("limit must be a positive integer"))
+			return
+		}
+		limit = min(parsed, 1000)
+	}
+
+	records, err := s.domainService.GetCheckHistory(d.DomainID, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	loc := s.location(r)
+	resp := make([]CheckRecordResponse, len(records))
+	for i, rec := range records {
+		resp[i] = CheckRecordResponse{CheckedAt: inZone(rec.CheckedAt, loc), Fingerprint: rec.Fingerprint}
+		if rec.ExpiryDate != nil {
+			expiry := inZone(rec.ExpiryDate.Time(), loc)
+			resp[i].ExpiryDate = &expiry
+		}
+		if rec.Error != nil {
+			checkErr := rec.Error.String()
+			resp[i].Error = &checkErr
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleDomainChain(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.domainFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	chain, err := s.domainService.GetCertificateChain(d.DomainID)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-pem-file")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(ssl.EncodeChainPEM(chain)))
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// eventHeartbeat is how often an idle stream sends a comment to keep proxies from closing it
+const eventHeartbeat = 15 * time.Second
+
+// CheckEvent is sent on the event stream for every completed certificate check
+type CheckEvent struct {
+	Domain    DomainResponse `json:"domain"`
+	CheckedAt time.Time      `json:"checked_at"`
+	Error     *string        `json:"error"`
+}
+
+// StatusEvent is sent on the event stream when a check moves a domain to a different status
+type StatusEvent struct {
+	DomainID uint   `json:"domain_id"`
+	Domain   string `json:"domain"`
+	Previous string `json:"previous"`
+	Current  string `json:"current"`
+}
+
+// handleEvents streams check results and status changes as server-sent events
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	userID := userFromRequest(r)
+
+	// Subscribe before reading the current state so no result falls in between
+	results, unsubscribe := s.domainService.SubscribeResults(64)
+	defer unsubscribe()
+
+	domains, err := s.domainService.GetUsersDomains(userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	loc := s.location(r)
+	statuses := make(map[types.DomainID]string, len(domains))
+	for _, d := range domains {
+		statuses[d.DomainID] = d.Status()
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		slog.Error("Event stream not supported", "error", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case result, ok := <-results:
+			if !ok {
+				return
+			}
+			d, err := s.domainService.GetDomain(types.DomainID(result.Task.DomainID))
+			if err != nil || !s.visible(userID, d) {
+				continue
+			}
+
+			event := CheckEvent{Domain: newDomainResponse(*d, s.domainService.Status(*d), loc), CheckedAt: inZone(result.CheckedAt, loc)}
+			if result.Error != nil {
+				checkErr := result.Error.Error()
+				event.Error = &checkErr
+			}
+			if err := writeEvent(w, "check", event); err != nil {
+				return
+			}
+
+			previous, known := statuses[d.DomainID]
+			statuses[d.DomainID] = event.Domain.Status
+			if known && previous != event.Domain.Status {
+				change := StatusEvent{
+					DomainID: event.Domain.ID,
+					Domain:   event.Domain.Domain,
+					Previous: previous,
+					Current:  event.Domain.Status,
+				}
+				if err := writeEvent(w, "status", change); err != nil {
+					return
+				}
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes a single named server-sent event with a JSON payload
+func writeEvent(w http.ResponseWriter, name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+	return err
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sseEvent struct {
+	name string
+	data string
+}
+
+// readEvents parses server-sent events from body until it closes.
+func readEvents(body *bufio.Scanner, events chan<- sseEvent) {
+	defer close(events)
+	var ev sseEvent
+	for body.Scan() {
+		line := body.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			ev.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			ev.data = strings.TrimPrefix(line, "data: ")
+		case line == "" && ev.name != "":
+			events <- ev
+			ev = sseEvent{}
+		}
+	}
+}
+
+// TestEvents_CheckAndStatusChange - a failing check streams a check event then a status change.
+func TestEvents_CheckAndStatusChange(t *testing.T) {
+	s, repo, _ := newTestServer(t)
+
+	// Invalid hostname fails the check in the worker pool without touching the network
+	d := domain.Domain{
+		UserID:     types.UserID(1),
+		DomainName: domain.NewDomainName("invalid..domain"),
+		CreatedAt:  domain.NewCreatedAt(time.Now()),
+		IsActive:   true,
+	}
+	require.NoError(t, repo.CreateDomain(&d))
+	expiry := time.Now().Add(90 * 24 * time.Hour)
+	require.NoError(t, repo.UpdateSSLInfo(d.DomainID, &expiry, nil))
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/v1/events", nil)
+	require.NoError(t, err)
+	resp, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := make(chan sseEvent)
+	go readEvents(bufio.NewScanner(resp.Body), events)
+
+	require.NoError(t, s.domainService.CheckDomainsSSLSync([]domain.Domain{d}))
+
+	var check CheckEvent
+	var change StatusEvent
+	for check.Domain.ID == 0 || change.DomainID == 0 {
+		select {
+		case ev := <-events:
+			switch ev.name {
+			case "check":
+				require.NoError(t, json.Unmarshal([]byte(ev.data), &check))
+			case "status":
+				require.NoError(t, json.Unmarshal([]byte(ev.data), &change))
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for events")
+		}
+	}
+
+	assert.Equal(t, d.DomainID.Uint(), check.Domain.ID)
+	assert.Equal(t, "error", check.Domain.Status)
+	require.NotNil(t, check.Error)
+	assert.Equal(t, "valid", change.Previous)
+	assert.Equal(t, "error", change.Current)
+}
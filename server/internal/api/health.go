@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/buildinfo"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+)
+
+// readinessTimeout bounds how long all readiness checks may take together
+const readinessTimeout = 5 * time.Second
+
+// HealthCheck reports whether a dependency is ready, returning an error if it isn't
+type HealthCheck func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check HealthCheck
+}
+
+// HealthResponse is the body of the health and readiness endpoints
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+	// Workers is what the worker pool is doing, left out by readiness and servers without a pool
+	Workers *ssl.PoolStats `json:"workers,omitempty"`
+}
+
+// AddReadinessCheck registers a check that must pass for /readyz to report ready
+func (s *Server) AddReadinessCheck(name string, check HealthCheck) {
+	s.readiness = append(s.readiness, namedCheck{name: name, check: check})
+}
+
+// handleHealthz reports that the process is alive and serving requests, and how busy its worker pool is
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	resp := HealthResponse{Status: "ok"}
+	if stats, err := s.domainService.PoolStats(); err == nil {
+		resp.Workers = &stats
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleVersion reports the version the server was built as, so clients can tell what they talk to
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, buildinfo.Get())
+}
+
+// handleReadyz runs every readiness check, answering 503 if any of them fail
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	resp := HealthResponse{Status: "ok", Checks: make(map[string]string, len(s.readiness))}
+	status := http.StatusOK
+	for _, c := range s.readiness {
+		if err := c.check(ctx); err != nil {
+			resp.Checks[c.name] = err.Error()
+			resp.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		resp.Checks[c.name] = "ok"
+	}
+	writeJSON(w, status, resp)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/samokw/ssl_tracker/internal/buildinfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHealthz - liveness doesn't depend on any check.
+func TestHealthz(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	s.AddReadinessCheck("broken", func(ctx context.Context) error { return errors.New("down") })
+
+	rec := doRequest(t, s, http.MethodGet, "/healthz", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+// TestReadyz - every check is reported and any failure answers 503.
+func TestReadyz(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	s.AddReadinessCheck("database", func(ctx context.Context) error { return nil })
+
+	rec := doRequest(t, s, http.MethodGet, "/readyz", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp HealthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "ok", resp.Status)
+	assert.Equal(t, "ok", resp.Checks["database"])
+
+	s.AddReadinessCheck("scheduler", func(ctx context.Context) error { return errors.New("scheduler is not running") })
+
+	rec = doRequest(t, s, http.MethodGet, "/readyz", nil)
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "unavailable", resp.Status)
+	assert.Equal(t, "ok", resp.Checks["database"])
+	assert.Equal(t, "scheduler is not running", resp.Checks["scheduler"])
+}
+
+// TestVersion - the server reports its build.
+func TestVersion(t *testing.T) {
+	s, _, _ := newTestServer(t)
+
+	rec := doRequest(t, s, http.MethodGet, "/api/v1/version", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var info buildinfo.Info
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+	assert.Equal(t, "dev", info.Version, "Tests aren't built with a version")
+	assert.NotEmpty(t, info.GoVersion)
+}
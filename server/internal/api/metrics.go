@@ -0,0 +1,40 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// metric is a sample in the Prometheus text format
+type metric struct {
+	name, kind, help string
+	value            float64
+}
+
+// handleMetrics exposes the worker pool's statistics for Prometheus to scrape
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.domainService.PoolStats()
+	if err != nil {
+		writeError(w, http.StatusNotImplemented, err)
+		return
+	}
+
+	metrics := []metric{
+		{"sslcerttop_worker_pool_workers", "gauge", "Certificate checks the worker pool runs at once", float64(stats.Workers)},
+		{"sslcerttop_worker_pool_busy_workers", "gauge", "Workers running a check", float64(stats.Busy)},
+		{"sslcerttop_worker_pool_queued_checks", "gauge", "Checks waiting for a worker", float64(stats.Queued)},
+		{"sslcerttop_worker_pool_in_flight_checks", "gauge", "Checks taken by a worker whose results weren't handed over yet", float64(stats.InFlight)},
+		{"sslcerttop_worker_pool_checks_completed_total", "counter", "Checks finished", float64(stats.Completed)},
+		{"sslcerttop_worker_pool_checks_failed_total", "counter", "Checks that failed", float64(stats.Failed)},
+		{"sslcerttop_worker_pool_check_latency_avg_seconds", "gauge", "How long a finished check took on average", stats.AvgLatency.Seconds()},
+	}
+	var b strings.Builder
+	for _, m := range metrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", m.name, m.help, m.name, m.kind, m.name, m.value)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(b.String()))
+}
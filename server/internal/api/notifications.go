@@ -0,0 +1,138 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/notification"
+)
+
+// NotificationResponse is the JSON representation of a notification
+type NotificationResponse struct {
+	ID             uint       `json:"id"`
+	DomainID       uint       `json:"domain_id"`
+	Domain         string     `json:"domain"`
+	ExpiryDate     *time.Time `json:"expiry_date"`
+	DaysBefore     int        `json:"days_before"`
+	Channel        string     `json:"channel"`
+	Status         string     `json:"status"`
+	CreatedAt      time.Time  `json:"created_at"`
+	SentAt         *time.Time `json:"sent_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at"`
+	LastError      *string    `json:"last_error"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at"`
+	LastStatusCode *int       `json:"last_status_code"`
+}
+
+// AttemptResponse is the JSON representation of a delivery attempt
+type AttemptResponse struct {
+	AttemptedAt time.Time `json:"attempted_at"`
+	StatusCode  *int      `json:"status_code"`
+	Error       *string   `json:"error"`
+}
+
+func newNotificationResponse(n notification.Notification, loc *time.Location) NotificationResponse {
+	return NotificationResponse{
+		ID:             n.NotificationID,
+		DomainID:       n.DomainID.Uint(),
+		Domain:         n.DomainName,
+		ExpiryDate:     inZonePtr(n.ExpiryDate, loc),
+		DaysBefore:     n.DaysBefore,
+		Channel:        n.NotificationType.String(),
+		Status:         n.Status.String(),
+		CreatedAt:      inZone(n.CreatedAt, loc),
+		SentAt:         inZonePtr(n.SentAt, loc),
+		AcknowledgedAt: inZonePtr(n.AcknowledgedAt, loc),
+		LastError:      n.LastError,
+		Attempts:       n.Attempts,
+		NextAttemptAt:  inZonePtr(n.NextAttemptAt, loc),
+		LastStatusCode: n.LastStatusCode,
+	}
+}
+
+// notificationFromPath loads the notification named by the {id} path value, writing an error response if it can't
+func (s *Server) notificationFromPath(w http.ResponseWriter, r *http.Request) (*notification.Notification, bool) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil || id == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("invalid notification ID"))
+		return nil, false
+	}
+
+	notFound := fmt.Errorf("notification with ID %d not found", id)
+	n, err := s.notificationService.GetNotification(uint(id))
+	if err != nil {
+		writeError(w, http.StatusNotFound, notFound)
+		return nil, false
+	}
+	d, err := s.domainService.GetDomain(n.DomainID)
+	if err != nil || !s.visible(userFromRequest(r), d) {
+		writeError(w, http.StatusNotFound, notFound)
+		return nil, false
+	}
+	return n, true
+}
+
+func (s *Server) handleListNotifications(w http.ResponseWriter, r *http.Request) {
+	notifications, err := s.notificationService.GetUsersNotifications(userFromRequest(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	loc := s.location(r)
+	resp := make([]NotificationResponse, len(notifications))
+	for i, n := range notifications {
+		resp[i] = newNotificationResponse(n, loc)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleListAttempts(w http.ResponseWriter, r *http.Request) {
+	n, ok := s.notificationFromPath(w, r)
+	if !ok {
+		return
+	}
+	attempts, err := s.notificationService.GetAttempts(n.NotificationID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	loc := s.location(r)
+	resp := make([]AttemptResponse, len(attempts))
+	for i, a := range attempts {
+		resp[i] = AttemptResponse{AttemptedAt: inZone(a.AttemptedAt, loc), StatusCode: a.StatusCode, Error: a.Error}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleResendNotification(w http.ResponseWriter, r *http.Request) {
+	s.updateNotification(w, r, s.notificationService.Resend)
+}
+
+func (s *Server) handleAcknowledgeNotification(w http.ResponseWriter, r *http.Request) {
+	s.updateNotification(w, r, s.notificationService.Acknowledge)
+}
+
+// updateNotification applies an update to the notification in the path and responds with its new state
+func (s *Server) updateNotification(w http.ResponseWriter, r *http.Request, update func(id uint) error) {
+	n, ok := s.notificationFromPath(w, r)
+	if !ok {
+		return
+	}
+	if err := update(n.NotificationID); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	updated, err := s.notificationService.GetNotification(n.NotificationID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newNotificationResponse(*updated, s.location(r)))
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/notification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNotifications - list, acknowledge and re-send a notification and list its delivery attempts.
+func TestNotifications(t *testing.T) {
+	s, db, _, id := newTestServerDB(t)
+
+	n := notification.Notification{DomainID: id, DaysBefore: 7, NotificationType: notification.NotificationTypeSlack}
+	require.NoError(t, notification.NewRepository(db).CreateNotification(&n))
+
+	rec := doRequest(t, s, http.MethodGet, "/api/v1/notifications", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list []NotificationResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list, 1)
+	assert.Equal(t, "example.com", list[0].Domain)
+	assert.Equal(t, "slack", list[0].Channel)
+	assert.Equal(t, "pending", list[0].Status)
+
+	path := fmt.Sprintf("/api/v1/notifications/%d", n.NotificationID)
+
+	rec = doRequest(t, s, http.MethodPost, path+"/acknowledge", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var updated NotificationResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &updated))
+	assert.Equal(t, "acknowledged", updated.Status)
+	assert.NotNil(t, updated.AcknowledgedAt)
+
+	rec = doRequest(t, s, http.MethodPost, path+"/resend", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &updated))
+	assert.Equal(t, "pending", updated.Status)
+
+	code := 503
+	errMsg := "unavailable"
+	attempt := notification.DeliveryAttempt{NotificationID: n.NotificationID, AttemptedAt: time.Now(), StatusCode: &code, Error: &errMsg}
+	require.NoError(t, notification.NewRepository(db).RecordAttempt(&attempt, notification.StatusRetrying, nil))
+
+	rec = doRequest(t, s, http.MethodGet, path+"/attempts", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var attempts []AttemptResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &attempts))
+	require.Len(t, attempts, 1)
+	assert.Equal(t, &code, attempts[0].StatusCode)
+	assert.Equal(t, &errMsg, attempts[0].Error)
+
+	rec = doRequest(t, s, http.MethodGet, "/api/v1/notifications", nil)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	assert.Equal(t, "retrying", list[0].Status)
+	assert.Equal(t, 1, list[0].Attempts)
+	assert.Equal(t, &code, list[0].LastStatusCode)
+
+	rec = doRequest(t, s, http.MethodPost, "/api/v1/notifications/999/resend", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = doRequest(t, s, http.MethodPost, "/api/v1/notifications/abc/acknowledge", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
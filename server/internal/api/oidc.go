@@ -0,0 +1,130 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/sso"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/samokw/ssl_tracker/internal/user"
+	"golang.org/x/oauth2"
+)
+
+// oidcCookie carries the state, nonce and PKCE verifier of a sign in from the redirect to the callback
+const oidcCookie = "sslcerttop_oidc"
+
+// oidcSignInTimeout is how long a user has to sign in at the provider
+const oidcSignInTimeout = 10 * time.Minute
+
+// EnableOIDC lets users sign in through an OpenID Connect provider, linking each subject to a local user
+// the first time it signs in. ID tokens from the provider are accepted like session tokens.
+//
+// Signing in hands out session tokens, so EnableSessions has to be called as well
+func (s *Server) EnableOIDC(provider *sso.Provider) {
+	s.oidcProvider = provider
+}
+
+// oidcEnabled writes an error response and returns false when the server doesn't sign in through OIDC
+func (s *Server) oidcEnabled(w http.ResponseWriter) bool {
+	if s.oidcProvider == nil || s.userService == nil {
+		writeError(w, http.StatusNotFound, errors.New("single sign-on is not enabled on this server"))
+		return false
+	}
+	return true
+}
+
+// isIDToken reports whether a bearer token is a JWT rather than one of our own tokens
+func isIDToken(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// authenticateIDToken resolves the user an ID token from the provider belongs to
+func (s *Server) authenticateIDToken(r *http.Request, token string) (types.UserID, error) {
+	identity, err := s.oidcProvider.Verify(r.Context(), token)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", user.ErrInvalidSession, err)
+	}
+	u, err := s.userService.ResolveIdentity(identity.Issuer, identity.Subject, identity.Email, identity.EmailVerified)
+	if err != nil {
+		if errors.Is(err, user.ErrInvalidCredentials) || errors.Is(err, user.ErrEmailTaken) || errors.Is(err, user.ErrNoEmail) {
+			return 0, fmt.Errorf("%w: %v", user.ErrInvalidSession, err)
+		}
+		return 0, err
+	}
+	return u.UserID, nil
+}
+
+func (s *Server) handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if !s.oidcEnabled(w) {
+		return
+	}
+	state, nonce, verifier := oauth2.GenerateVerifier(), oauth2.GenerateVerifier(), oauth2.GenerateVerifier()
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookie,
+		Value:    strings.Join([]string{state, nonce, verifier}, "."),
+		Path:     "/api/v1/auth/oidc",
+		MaxAge:   int(oidcSignInTimeout.Seconds()),
+		Secure:   r.TLS != nil,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, s.oidcProvider.AuthCodeURL(state, nonce, verifier), http.StatusFound)
+}
+
+func (s *Server) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if !s.oidcEnabled(w) {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcCookie, Path: "/api/v1/auth/oidc", MaxAge: -1})
+
+	query := r.URL.Query()
+	if reason := query.Get("error"); reason != "" {
+		if description := query.Get("error_description"); description != "" {
+			reason += ": " + description
+		}
+		writeError(w, http.StatusUnauthorized, fmt.Errorf("sign in failed: %s", reason))
+		return
+	}
+
+	cookie, err := r.Cookie(oidcCookie)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("sign in expired or was started elsewhere, try again"))
+		return
+	}
+	parts := strings.Split(cookie.Value, ".")
+	if len(parts) != 3 || subtle.ConstantTimeCompare([]byte(parts[0]), []byte(query.Get("state"))) != 1 {
+		writeError(w, http.StatusBadRequest, errors.New("sign in expired or was started elsewhere, try again"))
+		return
+	}
+
+	identity, err := s.oidcProvider.Exchange(r.Context(), query.Get("code"), parts[1], parts[2])
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	u, err := s.userService.ResolveIdentity(identity.Issuer, identity.Subject, identity.Email, identity.EmailVerified)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrInvalidCredentials):
+			writeError(w, http.StatusForbidden, errors.New("account is deactivated"))
+		case errors.Is(err, user.ErrNoEmail):
+			writeError(w, http.StatusForbidden, err)
+		case errors.Is(err, user.ErrEmailTaken):
+			writeError(w, http.StatusConflict, errors.New("an account with this email exists but the provider didn't verify the email"))
+		default:
+			writeError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	token, session, err := s.userService.StartSession(u.UserID, s.sessionLifetime)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newSessionResponse(token, session))
+}
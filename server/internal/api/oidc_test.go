@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/coreos/go-oidc/v3/oidc/oidctest"
+	"github.com/samokw/ssl_tracker/internal/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestOIDCServer creates an API server signing in through a fake provider, returning a function that signs ID tokens.
+func newTestOIDCServer(t *testing.T) (*Server, func(subject string) string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	discovery := &oidctest.Server{PublicKeys: []oidctest.PublicKey{{PublicKey: key.Public(), KeyID: "test", Algorithm: oidc.RS256}}}
+	issuer := httptest.NewServer(discovery)
+	t.Cleanup(issuer.Close)
+	discovery.SetIssuer(issuer.URL)
+
+	provider, err := sso.NewProvider(context.Background(), sso.Config{
+		Issuer:      issuer.URL,
+		ClientID:    "sslcerttop",
+		RedirectURL: "http://localhost/api/v1/auth/oidc/callback",
+	}, issuer.Client())
+	require.NoError(t, err)
+
+	s, _, _ := newTestServer(t)
+	s.EnableSessions(newTestUserService(t), time.Hour)
+	s.EnableOIDC(provider)
+
+	sign := func(subject string) string {
+		claims := fmt.Sprintf(`{"iss": %q, "aud": "sslcerttop", "sub": %q, "exp": %d, "email": %q, "email_verified": true}`,
+			issuer.URL, subject, time.Now().Add(time.Hour).Unix(), subject+"@example.com")
+		return oidctest.SignIDToken(key, "test", oidc.RS256, claims)
+	}
+	return s, sign
+}
+
+// TestOIDCLogin - signing in redirects to the provider and remembers the state in a cookie.
+func TestOIDCLogin(t *testing.T) {
+	s, _ := newTestOIDCServer(t)
+
+	rec := doAuthRequest(s, http.MethodGet, "/api/v1/auth/oidc/login", "")
+	require.Equal(t, http.StatusFound, rec.Code)
+	location, err := url.Parse(rec.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "sslcerttop", location.Query().Get("client_id"))
+
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.True(t, strings.HasPrefix(cookies[0].Value, location.Query().Get("state")+"."))
+}
+
+// TestOIDCCallback_State - callbacks without the matching state are refused.
+func TestOIDCCallback_State(t *testing.T) {
+	s, _ := newTestOIDCServer(t)
+
+	rec := doAuthRequest(s, http.MethodGet, "/api/v1/auth/oidc/callback?code=x&state=y", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/callback?code=x&state=forged", nil)
+	req.AddCookie(&http.Cookie{Name: oidcCookie, Value: "state.nonce.verifier"})
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doAuthRequest(s, http.MethodGet, "/api/v1/auth/oidc/callback?error=access_denied", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+// TestOIDCBearer - ID tokens from the provider authenticate API requests as the linked user.
+func TestOIDCBearer(t *testing.T) {
+	s, sign := newTestOIDCServer(t)
+
+	rec := doAuthRequest(s, http.MethodGet, "/api/v1/domains", sign("dave"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "[]\n", rec.Body.String(), "a new user starts without domains")
+
+	rec = doAuthRequest(s, http.MethodGet, "/api/v1/domains", "a.b.c")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+// TestOIDCDisabled - the sign in routes are missing without a provider.
+func TestOIDCDisabled(t *testing.T) {
+	s, _, _ := newTestServer(t)
+
+	rec := doAuthRequest(s, http.MethodGet, "/api/v1/auth/oidc/login", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,174 @@
+// This package exposes domain management over a JSON REST API
+//
+// It reuses the domain service so the API behaves exactly like the TUI
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/apikey"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/notification"
+	"github.com/samokw/ssl_tracker/internal/sso"
+	"github.com/samokw/ssl_tracker/internal/user"
+)
+
+// Server serves the REST API
+type Server struct {
+	domainService       *domain.Service
+	notificationService *notification.Service
+	mux                 *http.ServeMux
+	keyService          *apikey.Service
+	userService         *user.Service
+	sessionLifetime     time.Duration
+	oidcProvider        *sso.Provider
+	readiness           []namedCheck
+	// local is set while serving a unix socket only its owner can connect to
+	local bool
+}
+
+// NewServer creates an API server with all routes registered
+func NewServer(domainService *domain.Service, notificationService *notification.Service) *Server {
+	s := &Server{
+		domainService:       domainService,
+		notificationService: notificationService,
+		mux:                 http.NewServeMux(),
+	}
+	s.routes()
+	return s
+}
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("GET /api/v1/domains", s.handleListDomains)
+	s.mux.HandleFunc("POST /api/v1/domains", s.handleAddDomain)
+	s.mux.HandleFunc("GET /api/v1/domains/{id}", s.handleGetDomain)
+	s.mux.HandleFunc("DELETE /api/v1/domains/{id}", s.handleDeleteDomain)
+	s.mux.HandleFunc("POST /api/v1/domains/{id}/check", s.handleCheckDomain)
+	s.mux.HandleFunc("GET /api/v1/domains/{id}/history", s.handleDomainHistory)
+	s.mux.HandleFunc("GET /api/v1/domains/{id}/chain", s.handleDomainChain)
+	s.mux.HandleFunc("POST /api/v1/checks", s.handleCheckAll)
+	s.mux.HandleFunc("GET /api/v1/domains/{id}/ack", s.handleGetAck)
+	s.mux.HandleFunc("PUT /api/v1/domains/{id}/ack", s.handleSetAck)
+	s.mux.HandleFunc("DELETE /api/v1/domains/{id}/ack", s.handleDeleteAck)
+	s.mux.HandleFunc("GET /api/v1/acks", s.handleListAcks)
+	s.mux.HandleFunc("GET /api/v1/notifications", s.handleListNotifications)
+	s.mux.HandleFunc("GET /api/v1/notifications/{id}/attempts", s.handleListAttempts)
+	s.mux.HandleFunc("POST /api/v1/notifications/{id}/resend", s.handleResendNotification)
+	s.mux.HandleFunc("POST /api/v1/notifications/{id}/acknowledge", s.handleAcknowledgeNotification)
+	s.mux.HandleFunc("GET /api/v1/events", s.handleEvents)
+	s.mux.HandleFunc("POST /api/v1/auth/login", s.handleLogin)
+	s.mux.HandleFunc("POST /api/v1/auth/refresh", s.handleRefreshSession)
+	s.mux.HandleFunc("POST /api/v1/auth/logout", s.handleLogout)
+	s.mux.HandleFunc("GET /api/v1/auth/oidc/login", s.handleOIDCLogin)
+	s.mux.HandleFunc("GET /api/v1/auth/oidc/callback", s.handleOIDCCallback)
+	s.mux.HandleFunc("GET /api/v1/openapi.json", s.handleOpenAPI)
+	s.mux.HandleFunc("GET /api/v1/workers", s.handleGetWorkers)
+	s.mux.HandleFunc("PUT /api/v1/workers", s.handleSetWorkers)
+	s.mux.HandleFunc("GET /api/v1/workers/stats", s.handleWorkerStats)
+	s.mux.HandleFunc("GET /api/v1/version", s.handleVersion)
+	s.mux.HandleFunc("GET /healthz", s.handleHealthz)
+	s.mux.HandleFunc("GET /readyz", s.handleReadyz)
+	s.mux.HandleFunc("GET /metrics", s.handleMetrics)
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	if r = s.authenticate(w, r); r == nil {
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+	slog.Debug("API request", "method", r.Method, "path", r.URL.Path, "duration", time.Since(start).String())
+}
+
+// ListenAndServe serves the API on addr until the context is cancelled
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	slog.Info("API server listening", "addr", addr)
+	return s.serve(ctx, listener)
+}
+
+// ServeUnix serves the API on a unix socket at path until the context is cancelled, for instances on this machine
+// to attach to. A socket left behind by a crash is replaced, so the caller has to own path, e.g. by holding the
+// database lock.
+//
+// Only the socket's owner can connect, who could open the database as well, so requests without credentials act
+// for the default user until anyone registered, as commands on the database do. Session tokens act for their user.
+// That trust extends to every listener of the server, so one serving a socket shouldn't serve anything else
+func (s *Server) ServeUnix(ctx context.Context, path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to restrict socket: %w", err)
+	}
+	s.local = true
+	slog.Info("API server listening", "socket", path)
+	return s.serve(ctx, listener)
+}
+
+// serve answers requests on listener until the context is cancelled
+func (s *Server) serve(ctx context.Context, listener net.Listener) error {
+	httpServer := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+		// Cancelling ctx also ends long-lived event streams so shutdown isn't held up by them
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+		if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		slog.Info("API server stopped")
+		return nil
+	}
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Failed to encode API response", "error", err)
+	}
+}
+
+// errorResponse is the body of every failed request
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeError writes an error response with the given status code
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/notification"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer creates an API server over a fresh database holding one domain.
+func newTestServer(t *testing.T) (*Server, *domain.Repository, types.DomainID) {
+	t.Helper()
+
+	s, _, repo, id := newTestServerDB(t)
+	return s, repo, id
+}
+
+// newTestServerDB is newTestServer that also returns the database for tests that seed other tables.
+func newTestServerDB(t *testing.T) (*Server, *sql.DB, *domain.Repository, types.DomainID) {
+	t.Helper()
+
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	sslService := ssl.NewCertService()
+	t.Cleanup(sslService.Stop)
+
+	repo := domain.NewRepository(db)
+	d := domain.Domain{
+		UserID:     types.UserID(1),
+		DomainName: domain.NewDomainName("example.com"),
+		CreatedAt:  domain.NewCreatedAt(time.Now()),
+		IsActive:   true,
+	}
+	require.NoError(t, repo.CreateDomain(&d))
+
+	s := NewServer(domain.NewService(repo, sslService), notification.NewService(notification.NewRepository(db)))
+	return s, db, repo, d.DomainID
+}
+
+func doRequest(t *testing.T, s *Server, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestListDomains - returns the tracked domains as JSON.
+func TestListDomains(t *testing.T) {
+	s, _, _ := newTestServer(t)
+
+	rec := doRequest(t, s, http.MethodGet, "/api/v1/domains", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var domains []DomainResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &domains))
+	require.Len(t, domains, 1)
+	assert.Equal(t, "example.com", domains[0].Domain)
+	assert.Nil(t, domains[0].ExpiryDate)
+}
+
+// TestGetDomain - unknown and malformed IDs are rejected.
+func TestGetDomain(t *testing.T) {
+	s, _, id := newTestServer(t)
+
+	rec := doRequest(t, s, http.MethodGet, "/api/v1/domains/"+idString(id), nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = doRequest(t, s, http.MethodGet, "/api/v1/domains/999", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = doRequest(t, s, http.MethodGet, "/api/v1/domains/abc", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// TestAddDomain_Invalid - validation errors become 400s.
+func TestAddDomain_Invalid(t *testing.T) {
+	s, _, _ := newTestServer(t)
+
+	rec := doRequest(t, s, http.MethodPost, "/api/v1/domains", AddDomainRequest{Domain: "not a domain"})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var resp errorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp.Error)
+}
+
+// TestDomainErrorStatus - domain layer errors map to their status codes.
+func TestDomainErrorStatus(t *testing.T) {
+	assert.Equal(t, http.StatusNotFound, domainErrorStatus(fmt.Errorf("domain with ID 1 %w", domain.ErrNotFound)))
+	assert.Equal(t, http.StatusConflict, domainErrorStatus(fmt.Errorf("domain a.com %w", domain.ErrDuplicate)))
+	assert.Equal(t, http.StatusBadRequest, domainErrorStatus(fmt.Errorf("%w: bad", domain.ErrInvalidInput)))
+	assert.Equal(t, http.StatusInternalServerError, domainErrorStatus(errors.New("disk full")))
+}
+
+// TestDeleteDomain - deleting removes the domain.
+func TestDeleteDomain(t *testing.T) {
+	s, _, id := newTestServer(t)
+
+	rec := doRequest(t, s, http.MethodDelete, "/api/v1/domains/"+idString(id), nil)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = doRequest(t, s, http.MethodGet, "/api/v1/domains/"+idString(id), nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+// TestDomainHistory - recorded checks are returned newest first.
+func TestDomainHistory(t *testing.T) {
+	s, repo, id := newTestServer(t)
+
+	expiry := time.Now().Add(60 * 24 * time.Hour)
+	checkErr := "connection refused"
+	require.NoError(t, repo.UpdateSSLInfo(id, nil, &checkErr))
+	require.NoError(t, repo.UpdateSSLInfo(id, &expiry, nil))
+
+	rec := doRequest(t, s, http.MethodGet, "/api/v1/domains/"+idString(id)+"/history", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var history []CheckRecordResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &history))
+	require.Len(t, history, 2)
+	assert.NotNil(t, history[0].ExpiryDate)
+	assert.Nil(t, history[0].Error)
+	assert.Equal(t, checkErr, *history[1].Error)
+
+	rec = doRequest(t, s, http.MethodGet, "/api/v1/domains/"+idString(id)+"/history?limit=0", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func idString(id types.DomainID) string {
+	return fmt.Sprint(id.Uint())
+}
+
+// TestOpenAPISpec - the served document is valid JSON and describes every route.
+func TestOpenAPISpec(t *testing.T) {
+	s, _, _ := newTestServer(t)
+
+	rec := doRequest(t, s, http.MethodGet, "/api/v1/openapi.json", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &spec))
+	assert.Equal(t, "3.0.3", spec.OpenAPI)
+
+	routes := map[string][]string{
+		"/domains":                        {"get", "post"},
+		"/domains/{id}":                   {"get", "delete"},
+		"/domains/{id}/check":             {"post"},
+		"/domains/{id}/history":           {"get"},
+		"/domains/{id}/chain":             {"get"},
+		"/domains/{id}/ack":               {"get", "put", "delete"},
+		"/acks":                           {"get"},
+		"/notifications":                  {"get"},
+		"/notifications/{id}/attempts":    {"get"},
+		"/notifications/{id}/resend":      {"post"},
+		"/notifications/{id}/acknowledge": {"post"},
+		"/checks":                         {"post"},
+		"/events":                         {"get"},
+		"/auth/login":                     {"post"},
+		"/auth/refresh":                   {"post"},
+		"/auth/logout":                    {"post"},
+		"/auth/oidc/login":                {"get"},
+		"/auth/oidc/callback":             {"get"},
+		"/workers/stats":                  {"get"},
+	}
+	for path, methods := range routes {
+		require.Contains(t, spec.Paths, path)
+		for _, method := range methods {
+			assert.Contains(t, spec.Paths[path], method, "%s %s", method, path)
+		}
+	}
+}
+
+// TestListDomains_Timezone - times are given in the timezone from the caller's settings.
+func TestListDomains_Timezone(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	users := newTestUserService(t)
+	s.EnableSessions(users, time.Hour)
+
+	u, err := users.Authenticate("alice@example.com", "correct horse")
+	require.NoError(t, err)
+	settings, err := users.GetSettings(u.UserID)
+	require.NoError(t, err)
+	settings.Timezone = "Asia/Tokyo"
+	require.NoError(t, users.SaveSettings(settings))
+
+	rec := doRequest(t, s, http.MethodPost, "/api/v1/auth/login", LoginRequest{Email: "alice@example.com", Password: "correct horse"})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var session SessionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &session))
+
+	rec = doAuthRequest(s, http.MethodGet, "/api/v1/domains", session.Token)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var domains []struct {
+		CreatedAt string `json:"created_at"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &domains))
+	require.Len(t, domains, 1)
+	assert.Contains(t, domains[0].CreatedAt, "+09:00")
+}
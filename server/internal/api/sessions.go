@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/user"
+)
+
+// LoginRequest is the body of a request to sign in
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// SessionResponse is a freshly issued session token
+type SessionResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UserID    uint      `json:"user_id"`
+}
+
+func newSessionResponse(token string, session *user.Session) SessionResponse {
+	return SessionResponse{
+		Token:     token,
+		ExpiresAt: session.ExpiresAt,
+		UserID:    session.UserID.Uint(),
+	}
+}
+
+// sessionsEnabled writes an error response and returns false when the server doesn't issue sessions
+func (s *Server) sessionsEnabled(w http.ResponseWriter) bool {
+	if s.userService == nil {
+		writeError(w, http.StatusNotFound, errors.New("sessions are not enabled on this server"))
+		return false
+	}
+	return true
+}
+
+// sessionToken returns the session token a request was made with, if it wasn't made with an API key
+func sessionToken(r *http.Request) (string, bool) {
+	token, ok := bearerToken(r)
+	if !ok || !strings.HasPrefix(token, sessionTokenPrefix) {
+		return "", false
+	}
+	return token, true
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if !s.sessionsEnabled(w) {
+		return
+	}
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	u, err := s.userService.Authenticate(req.Email, req.Password)
+	if err != nil {
+		if errors.Is(err, user.ErrInvalidCredentials) {
+			writeError(w, http.StatusUnauthorized, err)
+		} else {
+			writeError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	token, session, err := s.userService.StartSession(u.UserID, s.sessionLifetime)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newSessionResponse(token, session))
+}
+
+func (s *Server) handleRefreshSession(w http.ResponseWriter, r *http.Request) {
+	if !s.sessionsEnabled(w) {
+		return
+	}
+	token, ok := sessionToken(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, errors.New("only session tokens can be refreshed"))
+		return
+	}
+
+	newToken, session, err := s.userService.RefreshSession(token, s.sessionLifetime)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newSessionResponse(newToken, session))
+}
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if !s.sessionsEnabled(w) {
+		return
+	}
+	token, ok := sessionToken(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, errors.New("only session tokens can be logged out"))
+		return
+	}
+
+	if err := s.userService.Logout(token); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// location is the timezone the caller's settings show times in, nil leaves times in the server's
+func (s *Server) location(r *http.Request) *time.Location {
+	if s.userService == nil {
+		return nil
+	}
+	settings, err := s.userService.GetSettings(userFromRequest(r))
+	if err != nil {
+		slog.Warn("Failed to load settings for API response", "error", err)
+		return nil
+	}
+	if settings.Timezone == "" {
+		return nil
+	}
+	loc, err := settings.Location()
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+// inZone shows t in loc, unchanged when loc is nil
+func inZone(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		return t
+	}
+	return t.In(loc)
+}
+
+// inZonePtr is inZone for optional times
+func inZonePtr(t *time.Time, loc *time.Location) *time.Time {
+	if t == nil {
+		return nil
+	}
+	zoned := inZone(*t, loc)
+	return &zoned
+}
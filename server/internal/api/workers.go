@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// handleGetWorkers returns the size of the worker pool checking certificates
+func (s *Server) handleGetWorkers(w http.ResponseWriter, r *http.Request) {
+	size, err := s.domainService.PoolSize()
+	if err != nil {
+		writeError(w, http.StatusNotImplemented, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, size)
+}
+
+// handleWorkerStats returns what the worker pool is doing and has done
+func (s *Server) handleWorkerStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.domainService.PoolStats()
+	if err != nil {
+		writeError(w, http.StatusNotImplemented, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// handleSetWorkers resizes the worker pool, fields left out of the request keep their value
+func (s *Server) handleSetWorkers(w http.ResponseWriter, r *http.Request) {
+	size, err := s.domainService.PoolSize()
+	if err != nil {
+		writeError(w, http.StatusNotImplemented, err)
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&size); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if err := s.domainService.ResizePool(size); err != nil {
+		writeError(w, domainErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, size)
+}
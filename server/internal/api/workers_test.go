@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWorkers - the pool is resized field by field, and an empty pool is refused.
+func TestWorkers(t *testing.T) {
+	s, _, _ := newTestServer(t)
+
+	rec := doRequest(t, s, http.MethodGet, "/api/v1/workers", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var size ssl.PoolSize
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &size))
+	assert.Equal(t, ssl.PoolSize{Workers: ssl.DefaultWorkers, QueueSize: ssl.DefaultQueueSize}, size)
+
+	rec = doRequest(t, s, http.MethodPut, "/api/v1/workers", map[string]int{"workers": 4})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &size))
+	assert.Equal(t, ssl.PoolSize{Workers: 4, QueueSize: ssl.DefaultQueueSize}, size)
+
+	rec = doRequest(t, s, http.MethodPut, "/api/v1/workers", map[string]int{"queue_size": 0})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	size, err := s.domainService.PoolSize()
+	require.NoError(t, err)
+	assert.Equal(t, 4, size.Workers)
+	assert.Equal(t, ssl.DefaultQueueSize, size.QueueSize)
+}
+
+// TestWorkerStats - the pool's statistics are served as JSON, to Prometheus and with liveness.
+func TestWorkerStats(t *testing.T) {
+	s, _, _ := newTestServer(t)
+
+	rec := doRequest(t, s, http.MethodGet, "/api/v1/workers/stats", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var stats ssl.PoolStats
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	assert.Equal(t, ssl.PoolStats{Workers: ssl.DefaultWorkers}, stats)
+
+	rec = doRequest(t, s, http.MethodGet, "/metrics", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, rec.Body.String(), "# TYPE sslcerttop_worker_pool_checks_completed_total counter\n")
+	assert.Contains(t, rec.Body.String(), fmt.Sprintf("\nsslcerttop_worker_pool_workers %d\n", ssl.DefaultWorkers))
+
+	rec = doRequest(t, s, http.MethodGet, "/healthz", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var health HealthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &health))
+	require.NotNil(t, health.Workers)
+	assert.Equal(t, ssl.DefaultWorkers, health.Workers.Workers)
+}
@@ -0,0 +1,78 @@
+// This package issues and verifies the API keys that authenticate REST and gRPC clients
+//
+// Only a SHA-256 hash of each key is stored, the key itself is shown once when it is created
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// keyPrefix marks a string as an sslcerttop API key
+const keyPrefix = "sct_"
+
+// Scope limits what a key may do
+type Scope string
+
+const (
+	ScopeRead      Scope = "read"
+	ScopeReadWrite Scope = "read-write"
+)
+
+// ParseScope validates a scope name
+func ParseScope(scope string) (Scope, error) {
+	switch Scope(scope) {
+	case ScopeRead, ScopeReadWrite:
+		return Scope(scope), nil
+	default:
+		return "", fmt.Errorf("unknown scope %q, expected %q or %q", scope, ScopeRead, ScopeReadWrite)
+	}
+}
+
+func (s Scope) String() string {
+	return string(s)
+}
+
+// CanWrite reports whether the scope allows changes
+func (s Scope) CanWrite() bool {
+	return s == ScopeReadWrite
+}
+
+type APIKey struct {
+	KeyID      uint         `db:"id"`
+	UserID     types.UserID `db:"user_id"`
+	Name       string       `db:"name"`
+	Prefix     string       `db:"prefix"`
+	Scope      Scope        `db:"scope"`
+	CreatedAt  time.Time    `db:"created_at"`
+	LastUsedAt *time.Time   `db:"last_used_at"`
+	RevokedAt  *time.Time   `db:"revoked_at"`
+}
+
+// IsRevoked reports whether the key can no longer be used
+func (k APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+// generateKey creates a new random key, returning it with the prefix shown in listings
+func generateKey() (string, string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	key := keyPrefix + strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf))
+	return key, key[:len(keyPrefix)+6], nil
+}
+
+// hashKey returns the stored form of a key
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,133 @@
+package apikey
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+type Repository struct {
+	db     *sql.DB
+	writer *database.Writer
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db:     db,
+		writer: database.NewWriter(db),
+	}
+}
+
+const selectAPIKeys = `SELECT id, user_id, name, prefix, scope, created_at, last_used_at, revoked_at FROM api_keys`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func (r *Repository) scanAPIKey(row scanner) (APIKey, error) {
+	var id, userID uint
+	var name, prefix, scope string
+	var createdAt time.Time
+	var lastUsedAt, revokedAt sql.NullTime
+
+	if err := row.Scan(&id, &userID, &name, &prefix, &scope, &createdAt, &lastUsedAt, &revokedAt); err != nil {
+		return APIKey{}, err
+	}
+
+	k := APIKey{
+		KeyID:     id,
+		UserID:    types.UserID(userID),
+		Name:      name,
+		Prefix:    prefix,
+		Scope:     Scope(scope),
+		CreatedAt: createdAt,
+	}
+	if lastUsedAt.Valid {
+		k.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		k.RevokedAt = &revokedAt.Time
+	}
+	return k, nil
+}
+
+// CreateAPIKey stores a key by its hash
+func (r *Repository) CreateAPIKey(k *APIKey, keyHash string) error {
+	if err := types.ValidateUserID(k.UserID); err != nil {
+		return fmt.Errorf("invalid user ID: %w", err)
+	}
+	if k.CreatedAt.IsZero() {
+		k.CreatedAt = time.Now()
+	}
+
+	query := `INSERT INTO api_keys (user_id, name, prefix, key_hash, scope, created_at) VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := r.writer.Exec(query, k.UserID.Uint(), k.Name, k.Prefix, keyHash, k.Scope.String(), k.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	k.KeyID = uint(id)
+	return nil
+}
+
+// GetAPIKeysByUserID lists every key of a user, including revoked ones, oldest first
+func (r *Repository) GetAPIKeysByUserID(userID types.UserID) ([]APIKey, error) {
+	rows, err := r.db.Query(selectAPIKeys+` WHERE user_id = ? ORDER BY created_at, id`, userID.Uint())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		k, err := r.scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// GetAPIKeyByHash looks up a key by the hash of its secret.
+//
+// Returns nil without an error when no key matches
+func (r *Repository) GetAPIKeyByHash(keyHash string) (*APIKey, error) {
+	k, err := r.scanAPIKey(r.db.QueryRow(selectAPIKeys+` WHERE key_hash = ?`, keyHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &k, nil
+}
+
+// TouchAPIKey records that a key was just used
+func (r *Repository) TouchAPIKey(id uint) error {
+	_, err := r.writer.Exec(`UPDATE api_keys SET last_used_at = ? WHERE id = ?`, time.Now(), id)
+	return err
+}
+
+// RevokeAPIKey stops a user's key from authenticating, leaving already revoked keys untouched
+func (r *Repository) RevokeAPIKey(userID types.UserID, id uint) error {
+	result, err := r.writer.Exec(`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ? AND user_id = ?`,
+		time.Now(), id, userID.Uint())
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("API key with ID %d not found", id)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package apikey
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// ErrInvalidKey is returned when a key is missing, unknown or revoked
+var ErrInvalidKey = errors.New("invalid API key")
+
+type Service struct {
+	apiKeyRepo *Repository
+}
+
+func NewService(apiKeyRepo *Repository) *Service {
+	return &Service{
+		apiKeyRepo: apiKeyRepo,
+	}
+}
+
+// CreateKey issues a new key for a user.
+//
+// Returns the key itself, which can't be recovered later, along with its stored record
+func (s *Service) CreateKey(userID types.UserID, name string, scope Scope) (string, *APIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, fmt.Errorf("key name cannot be empty")
+	}
+	if _, err := ParseScope(scope.String()); err != nil {
+		return "", nil, err
+	}
+
+	key, prefix, err := generateKey()
+	if err != nil {
+		return "", nil, err
+	}
+	k := APIKey{
+		UserID: userID,
+		Name:   name,
+		Prefix: prefix,
+		Scope:  scope,
+	}
+	if err := s.apiKeyRepo.CreateAPIKey(&k, hashKey(key)); err != nil {
+		return "", nil, fmt.Errorf("failed to store API key: %w", err)
+	}
+	return key, &k, nil
+}
+
+// GetUsersKeys lists every key of a user
+func (s *Service) GetUsersKeys(userID types.UserID) ([]APIKey, error) {
+	return s.apiKeyRepo.GetAPIKeysByUserID(userID)
+}
+
+// RevokeKey stops a user's key from authenticating
+func (s *Service) RevokeKey(userID types.UserID, id uint) error {
+	return s.apiKeyRepo.RevokeAPIKey(userID, id)
+}
+
+// Authenticate resolves a presented key to its record, returning ErrInvalidKey if it can't be used
+func (s *Service) Authenticate(key string) (*APIKey, error) {
+	if !strings.HasPrefix(key, keyPrefix) {
+		return nil, ErrInvalidKey
+	}
+	k, err := s.apiKeyRepo.GetAPIKeyByHash(hashKey(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+	if k == nil || k.IsRevoked() {
+		return nil, ErrInvalidKey
+	}
+
+	if err := s.apiKeyRepo.TouchAPIKey(k.KeyID); err != nil {
+		slog.Warn("Failed to record API key use", "key", k.Prefix, "error", err)
+	}
+	return k, nil
+}
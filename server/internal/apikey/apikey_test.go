@@ -0,0 +1,104 @@
+package apikey
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestService creates a key service backed by a fresh SQLite database.
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return NewService(NewRepository(db))
+}
+
+// TestParseScope - only known scopes are accepted.
+func TestParseScope(t *testing.T) {
+	scope, err := ParseScope("read")
+	require.NoError(t, err)
+	assert.False(t, scope.CanWrite())
+
+	scope, err = ParseScope("read-write")
+	require.NoError(t, err)
+	assert.True(t, scope.CanWrite())
+
+	_, err = ParseScope("admin")
+	assert.Error(t, err)
+}
+
+// TestService_CreateAndAuthenticate - a new key authenticates and only its hash is stored.
+func TestService_CreateAndAuthenticate(t *testing.T) {
+	s := newTestService(t)
+
+	key, created, err := s.CreateKey(types.UserID(1), "ci", ScopeRead)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, keyPrefix))
+	assert.True(t, strings.HasPrefix(key, created.Prefix))
+	assert.NotEqual(t, key, created.Prefix)
+
+	var stored string
+	require.NoError(t, s.apiKeyRepo.db.QueryRow(`SELECT key_hash FROM api_keys WHERE id = ?`, created.KeyID).Scan(&stored))
+	assert.Equal(t, hashKey(key), stored)
+	assert.NotContains(t, stored, key)
+
+	k, err := s.Authenticate(key)
+	require.NoError(t, err)
+	assert.Equal(t, created.KeyID, k.KeyID)
+	assert.Equal(t, types.UserID(1), k.UserID)
+	assert.Equal(t, ScopeRead, k.Scope)
+
+	keys, err := s.GetUsersKeys(types.UserID(1))
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.NotNil(t, keys[0].LastUsedAt, "Authenticating should record the use")
+}
+
+// TestService_AuthenticateInvalid - unknown, malformed and revoked keys are rejected.
+func TestService_AuthenticateInvalid(t *testing.T) {
+	s := newTestService(t)
+
+	_, err := s.Authenticate("")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	_, err = s.Authenticate("sct_doesnotexist")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	key, created, err := s.CreateKey(types.UserID(1), "old", ScopeReadWrite)
+	require.NoError(t, err)
+	require.NoError(t, s.RevokeKey(types.UserID(1), created.KeyID))
+
+	_, err = s.Authenticate(key)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+// TestService_CreateKeyValidation - names and scopes are validated.
+func TestService_CreateKeyValidation(t *testing.T) {
+	s := newTestService(t)
+
+	_, _, err := s.CreateKey(types.UserID(1), "  ", ScopeRead)
+	assert.Error(t, err)
+	_, _, err = s.CreateKey(types.UserID(1), "ci", Scope("admin"))
+	assert.Error(t, err)
+}
+
+// TestService_RevokeKey - only the owner can revoke a key.
+func TestService_RevokeKey(t *testing.T) {
+	s := newTestService(t)
+
+	_, created, err := s.CreateKey(types.UserID(1), "ci", ScopeRead)
+	require.NoError(t, err)
+
+	assert.Error(t, s.RevokeKey(types.UserID(2), created.KeyID))
+	assert.Error(t, s.RevokeKey(types.UserID(1), 999))
+	require.NoError(t, s.RevokeKey(types.UserID(1), created.KeyID))
+	require.NoError(t, s.RevokeKey(types.UserID(1), created.KeyID), "Revoking twice is fine")
+}
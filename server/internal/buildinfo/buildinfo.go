@@ -0,0 +1,69 @@
+// Package buildinfo holds the version sslcerttop was built as, set at build time with
+//
+//	go build -ldflags "-X github.com/samokw/ssl_tracker/internal/buildinfo.version=v1.2.0 \
+//	  -X github.com/samokw/ssl_tracker/internal/buildinfo.commit=$(git rev-parse HEAD) \
+//	  -X github.com/samokw/ssl_tracker/internal/buildinfo.date=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd
+//
+// Builds without them fall back to what the Go toolchain recorded about the module and its VCS checkout
+package buildinfo
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Set with -ldflags -X, see the package documentation
+var (
+	version string
+	commit  string
+	date    string
+)
+
+// Info describes a build
+type Info struct {
+	Version string `json:"version"`
+	// Commit is the VCS revision built, empty when unknown
+	Commit string `json:"commit,omitempty"`
+	// Date is when the binary was built, or the commit was made when only the toolchain recorded it
+	Date      string `json:"date,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get describes the running binary
+func Get() Info {
+	info := Info{Version: version, Commit: commit, Date: date, GoVersion: runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.Date == "":
+				info.Date = s.Value
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	return info
+}
+
+// Short is the version with the abbreviated commit, e.g. v1.2.0 (3f9c2a1)
+func (i Info) Short() string {
+	if i.Commit == "" {
+		return i.Version
+	}
+	return fmt.Sprintf("%s (%.7s)", i.Version, i.Commit)
+}
+
+func (i Info) String() string {
+	s := "sslcerttop " + i.Short()
+	if i.Date != "" {
+		s += ", built " + i.Date
+	}
+	return s + " with " + i.GoVersion
+}
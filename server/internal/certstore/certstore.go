@@ -0,0 +1,145 @@
+// Package certstore keeps certificates that can't be fetched over the network, such as S/MIME, code signing and
+// client certificates, so they can be tracked from a copy entered by hand
+package certstore
+
+import (
+	"context"
+	"crypto/x509"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// Prefix marks a tracked name as a stored certificate, e.g. cert://alice@example.com/3f9a1c2b7d4e5f60
+const Prefix = "cert://"
+
+// IsStored reports whether a tracked name refers to a stored certificate
+func IsStored(name string) bool {
+	return strings.HasPrefix(name, Prefix)
+}
+
+// Usages a stored certificate is tagged with
+const (
+	UsageSMIME       = "smime"
+	UsageCodeSigning = "code-signing"
+	UsageClient      = "client-auth"
+	UsageServer      = "server"
+)
+
+// ErrNotStored occurs when no copy of a tracked certificate is stored
+var ErrNotStored = errors.New("certificate is not stored, add it again")
+
+// fingerprintLength is how many hex digits of the SHA-256 fingerprint a name carries
+const fingerprintLength = 16
+
+// Name is what a certificate is tracked as: its first email address or common name, then a short fingerprint
+// keeping certificates for the same subject apart
+func Name(cert *x509.Certificate) string {
+	label := cert.Subject.CommonName
+	if len(cert.EmailAddresses) > 0 {
+		label = cert.EmailAddresses[0]
+	}
+	label = strings.Join(strings.FieldsFunc(label, func(r rune) bool { return r == '/' || r == ' ' || r == '\t' }), "-")
+	if label == "" {
+		label = "certificate"
+	}
+	return Prefix + label + "/" + Fingerprint(cert)[:fingerprintLength]
+}
+
+// Fingerprint is the hex SHA-256 of the certificate
+func Fingerprint(cert *x509.Certificate) string {
+	return ssl.CertFingerprint(cert)
+}
+
+// Usage names what a certificate is for from its extended key usages, empty when it doesn't say
+func Usage(cert *x509.Certificate) string {
+	has := func(usage x509.ExtKeyUsage) bool {
+		for _, u := range cert.ExtKeyUsage {
+			if u == usage {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case has(x509.ExtKeyUsageCodeSigning):
+		return UsageCodeSigning
+	case has(x509.ExtKeyUsageEmailProtection):
+		return UsageSMIME
+	case has(x509.ExtKeyUsageServerAuth):
+		return UsageServer
+	case has(x509.ExtKeyUsageClientAuth):
+		return UsageClient
+	}
+	return ""
+}
+
+// Repository keeps the certificate of each domain tracking one, removed along with the domain
+type Repository struct {
+	db     *sql.DB
+	writer *database.Writer
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db:     db,
+		writer: database.NewWriter(db),
+	}
+}
+
+// Save stores the certificate tracked as domainID, only the certificate itself and never a key bundled with it
+func (r *Repository) Save(domainID types.DomainID, cert *x509.Certificate) error {
+	return r.writer.Transaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM stored_certificates WHERE domain_id = ?`, domainID.Uint()); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO stored_certificates (domain_id, pem, added_at) VALUES (?, ?, ?)`,
+			domainID.Uint(), ssl.EncodeChainPEM([]*x509.Certificate{cert}), time.Now())
+		return err
+	})
+}
+
+// Lookup returns the stored certificate a tracked name refers to. The name ends in the certificate's
+// fingerprint, so any domain tracking it holds the same certificate
+func (r *Repository) Lookup(name string) (*x509.Certificate, error) {
+	var pem string
+	err := r.db.QueryRow(`SELECT s.pem FROM stored_certificates s JOIN domains d ON d.id = s.domain_id
+	                      WHERE d.domain_name = ? LIMIT 1`, name).Scan(&pem)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotStored
+	}
+	if err != nil {
+		return nil, err
+	}
+	certs, err := ssl.ParseChainPEM([]byte(pem))
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, ErrNotStored
+	}
+	return certs[0], nil
+}
+
+// Register makes checks of stored certificates read them from the repository
+func Register(r *Repository) {
+	ssl.RegisterTargetChecker(Prefix, func(_ context.Context, name string) (*ssl.SSLCertificate, error) {
+		cert, err := r.Lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		return &ssl.SSLCertificate{
+			Hostname:        ssl.Hostname(name),
+			ExpiryDate:      types.NewExpiryDate(cert.NotAfter),
+			TimeLeft:        ssl.TimeLeft(time.Until(cert.NotAfter).Hours() / 24),
+			Issuer:          ssl.IssuerName(cert),
+			CertFingerprint: ssl.CertFingerprint(cert),
+			SANs:            cert.DNSNames,
+		}, nil
+	})
+}
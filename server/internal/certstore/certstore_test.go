@@ -0,0 +1,93 @@
+package certstore
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestCertificate creates a self-signed certificate for usage.
+func newTestCertificate(t *testing.T, subject pkix.Name, emails []string, notAfter time.Time, usage ...x509.ExtKeyUsage) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:   big.NewInt(time.Now().UnixNano()),
+		Subject:        subject,
+		EmailAddresses: emails,
+		NotBefore:      notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:       notAfter,
+		ExtKeyUsage:    usage,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+// TestName - certificates are named after their email address or common name, with a short fingerprint.
+func TestName(t *testing.T) {
+	expiry := time.Now().Add(200 * 24 * time.Hour)
+	smime := newTestCertificate(t, pkix.Name{CommonName: "Alice Example"}, []string{"alice@example.com"}, expiry, x509.ExtKeyUsageEmailProtection)
+	signing := newTestCertificate(t, pkix.Name{CommonName: "Example Corp / Release Signing"}, nil, expiry, x509.ExtKeyUsageCodeSigning)
+	bare := newTestCertificate(t, pkix.Name{}, nil, expiry)
+
+	assert.Equal(t, "cert://alice@example.com/"+Fingerprint(smime)[:16], Name(smime))
+	assert.True(t, strings.HasPrefix(Name(signing), "cert://Example-Corp-Release-Signing/"))
+	assert.True(t, strings.HasPrefix(Name(bare), "cert://certificate/"))
+	assert.NotEqual(t, Name(bare), Name(newTestCertificate(t, pkix.Name{}, nil, expiry)), "Different certificates get different names")
+
+	assert.Equal(t, UsageSMIME, Usage(smime))
+	assert.Equal(t, UsageCodeSigning, Usage(signing))
+	assert.Equal(t, UsageClient, Usage(newTestCertificate(t, pkix.Name{CommonName: "laptop"}, nil, expiry, x509.ExtKeyUsageClientAuth)))
+	assert.Empty(t, Usage(bare))
+}
+
+// TestRegister - a stored certificate is checked from its copy, and removed along with its domain.
+func TestRegister(t *testing.T) {
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	repo := NewRepository(db)
+	Register(repo)
+	domains := domain.NewService(domain.NewRepository(db), nil)
+
+	expiry := time.Now().Add(100 * 24 * time.Hour).UTC().Truncate(time.Second)
+	cert := newTestCertificate(t, pkix.Name{CommonName: "Bob"}, []string{"bob@example.com"}, expiry, x509.ExtKeyUsageEmailProtection)
+	d, err := domains.AddDomain(types.UserID(1), Name(cert))
+	require.NoError(t, err)
+
+	got, err := domains.GetDomain(d.DomainID)
+	require.NoError(t, err)
+	require.NotNil(t, got.LastError)
+	assert.Contains(t, got.LastError.String(), ErrNotStored.Error())
+
+	require.NoError(t, repo.Save(d.DomainID, cert))
+	require.NoError(t, domains.CheckDomainSSL(d.DomainID))
+	got, err = domains.GetDomain(d.DomainID)
+	require.NoError(t, err)
+	assert.Nil(t, got.LastError)
+	require.NotNil(t, got.ExpiryDate)
+	assert.True(t, got.ExpiryDate.Time().Equal(expiry))
+	assert.Equal(t, "Bob", got.Issuer, "Self-signed, so the issuer is the subject")
+
+	require.NoError(t, domains.RemoveDomain(d.DomainID))
+	_, err = repo.Lookup(Name(cert))
+	assert.ErrorIs(t, err, ErrNotStored)
+}
@@ -0,0 +1,493 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/ssl"
+)
+
+// AWSPrefix starts the ARN of every certificate the AWS provider imports
+const AWSPrefix = "arn:aws:"
+
+// Services the AWS provider imports certificates from
+const (
+	ServiceACM = "acm"
+	ServiceIAM = "iam"
+)
+
+// acmKeyTypes are every key type ACM lists, it only lists RSA 2048 certificates when none are given
+var acmKeyTypes = []string{"RSA_1024", "RSA_2048", "RSA_3072", "RSA_4096", "EC_prime256v1", "EC_secp384r1", "EC_secp521r1"}
+
+// AWSAccount holds the credentials for one AWS account.
+//
+// Empty keys use AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN, and a role ARN is
+// assumed with those credentials to reach another account
+type AWSAccount struct {
+	Name            string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	RoleARN         string
+}
+
+// awsAccount caches an account's ID and the credentials of its assumed role
+type awsAccount struct {
+	AWSAccount
+	mu      sync.Mutex
+	id      string
+	creds   awsCredentials
+	expires time.Time
+}
+
+// AWSProvider imports ACM certificates, and optionally IAM server certificates, from AWS accounts
+type AWSProvider struct {
+	accounts   []*awsAccount
+	regions    []string
+	iam        bool
+	httpClient *http.Client
+	// endpoint returns the URL of a service in a region, replaced by tests
+	endpoint func(service, region string) string
+	now      func() time.Time
+}
+
+// NewAWSProvider lists ACM certificates in each region of each account, and IAM server certificates too when iam is set.
+// A nil httpClient uses one with a 30 second timeout
+func NewAWSProvider(accounts []AWSAccount, regions []string, iam bool, httpClient *http.Client) (*AWSProvider, error) {
+	if len(accounts) == 0 {
+		return nil, errors.New("at least one AWS account is required")
+	}
+	if len(regions) == 0 && !iam {
+		return nil, errors.New("at least one AWS region is required")
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	p := &AWSProvider{
+		regions:    regions,
+		iam:        iam,
+		httpClient: httpClient,
+		endpoint:   awsEndpoint,
+		now:        time.Now,
+	}
+	for _, a := range accounts {
+		p.accounts = append(p.accounts, &awsAccount{AWSAccount: a})
+	}
+	return p, nil
+}
+
+// awsEndpoint is the public endpoint of a service, IAM and STS are global
+func awsEndpoint(service, region string) string {
+	if service == ServiceIAM || service == "sts" {
+		return "https://" + service + ".amazonaws.com/"
+	}
+	return "https://" + service + "." + region + ".amazonaws.com/"
+}
+
+func (p *AWSProvider) Name() string {
+	return "aws"
+}
+
+// Prefix is what the IDs of the provider's certificates start with
+func (p *AWSProvider) Prefix() string {
+	return AWSPrefix
+}
+
+// List returns the certificates of every account, along with an error for each account or region that failed
+func (p *AWSProvider) List(ctx context.Context) ([]Certificate, error) {
+	var certs []Certificate
+	var errs []error
+	for _, a := range p.accounts {
+		accountID, err := p.accountID(ctx, a)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("account %s: %w", a.label(), err))
+			continue
+		}
+		for _, region := range p.regions {
+			found, err := p.listACM(ctx, a, accountID, region)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("account %s, %s: %w", a.label(), region, err))
+			}
+			certs = append(certs, found...)
+		}
+		if p.iam {
+			found, err := p.listIAM(ctx, a, accountID)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("account %s, IAM: %w", a.label(), err))
+			}
+			certs = append(certs, found...)
+		}
+	}
+	return certs, errors.Join(errs...)
+}
+
+// Describe looks up a certificate by its ARN, using the configured account the ARN belongs to
+func (p *AWSProvider) Describe(ctx context.Context, id string) (*Certificate, error) {
+	// arn:aws:<service>:<region>:<account>:<resource>
+	parts := strings.SplitN(id, ":", 6)
+	if len(parts) != 6 || !strings.HasPrefix(id, AWSPrefix) {
+		return nil, fmt.Errorf("invalid ARN %q", id)
+	}
+	service, region, accountID, resource := parts[2], parts[3], parts[4], parts[5]
+
+	var account *awsAccount
+	for _, a := range p.accounts {
+		if id, err := p.accountID(ctx, a); err == nil && id == accountID {
+			account = a
+			break
+		}
+	}
+	if account == nil {
+		return nil, fmt.Errorf("no configured AWS account has the ID %s", accountID)
+	}
+
+	switch service {
+	case ServiceACM:
+		return p.describeACM(ctx, account, accountID, region, id)
+	case ServiceIAM:
+		return p.getIAM(ctx, account, accountID, resource[strings.LastIndex(resource, "/")+1:])
+	default:
+		return nil, fmt.Errorf("unsupported AWS service %q", service)
+	}
+}
+
+func (a *awsAccount) label() string {
+	if a.Name != "" {
+		return a.Name
+	}
+	return "default"
+}
+
+// baseCredentials are the account's keys, falling back to the environment
+func (a *awsAccount) baseCredentials() (awsCredentials, error) {
+	creds := awsCredentials{AccessKeyID: a.AccessKeyID, SecretAccessKey: a.SecretAccessKey, SessionToken: a.SessionToken}
+	if creds.AccessKeyID == "" {
+		creds = awsCredentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return awsCredentials{}, errors.New("no AWS access key configured")
+	}
+	return creds, nil
+}
+
+// credentials returns the keys requests to the account are signed with, assuming its role when it has one
+func (p *AWSProvider) credentials(ctx context.Context, a *awsAccount) (awsCredentials, error) {
+	base, err := a.baseCredentials()
+	if err != nil || a.RoleARN == "" {
+		return base, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	// Renew a little early so a long sync doesn't run past the expiry
+	if a.creds.AccessKeyID != "" && p.now().Add(5*time.Minute).Before(a.expires) {
+		return a.creds, nil
+	}
+
+	var out struct {
+		Credentials struct {
+			AccessKeyID     string    `xml:"AccessKeyId"`
+			SecretAccessKey string    `xml:"SecretAccessKey"`
+			SessionToken    string    `xml:"SessionToken"`
+			Expiration      time.Time `xml:"Expiration"`
+		} `xml:"AssumeRoleResult>Credentials"`
+	}
+	params := url.Values{"Action": {"AssumeRole"}, "Version": {"2011-06-15"}, "RoleArn": {a.RoleARN}, "RoleSessionName": {"sslcerttop"}}
+	if err := p.query(ctx, base, "sts", "us-east-1", params, &out); err != nil {
+		return awsCredentials{}, fmt.Errorf("failed to assume %s: %w", a.RoleARN, err)
+	}
+	a.creds = awsCredentials{
+		AccessKeyID:     out.Credentials.AccessKeyID,
+		SecretAccessKey: out.Credentials.SecretAccessKey,
+		SessionToken:    out.Credentials.SessionToken,
+	}
+	a.expires = out.Credentials.Expiration
+	return a.creds, nil
+}
+
+// accountID returns the ID of the account, from the role ARN or by asking STS who the keys belong to
+func (p *AWSProvider) accountID(ctx context.Context, a *awsAccount) (string, error) {
+	a.mu.Lock()
+	id := a.id
+	a.mu.Unlock()
+	if id != "" {
+		return id, nil
+	}
+
+	if parts := strings.Split(a.RoleARN, ":"); len(parts) == 6 {
+		id = parts[4]
+	} else {
+		creds, err := p.credentials(ctx, a)
+		if err != nil {
+			return "", err
+		}
+		var out struct {
+			Account string `xml:"GetCallerIdentityResult>Account"`
+		}
+		params := url.Values{"Action": {"GetCallerIdentity"}, "Version": {"2011-06-15"}}
+		if err := p.query(ctx, creds, "sts", "us-east-1", params, &out); err != nil {
+			return "", err
+		}
+		id = out.Account
+	}
+
+	a.mu.Lock()
+	a.id = id
+	a.mu.Unlock()
+	return id, nil
+}
+
+// awsTime is a timestamp in seconds since the epoch, as the JSON APIs send them
+type awsTime float64
+
+func (t *awsTime) time() *time.Time {
+	if t == nil {
+		return nil
+	}
+	sec := float64(*t)
+	v := time.Unix(int64(sec), int64((sec-float64(int64(sec)))*1e9)).UTC()
+	return &v
+}
+
+// acmCertificate is a certificate as ListCertificates and DescribeCertificate return it
+type acmCertificate struct {
+	CertificateArn     string   `json:"CertificateArn"`
+	DomainName         string   `json:"DomainName"`
+	Issuer             string   `json:"Issuer"`
+	Status             string   `json:"Status"`
+	Type               string   `json:"Type"`
+	NotAfter           *awsTime `json:"NotAfter"`
+	RenewalEligibility string   `json:"RenewalEligibility"`
+	InUse              bool     `json:"InUse"`
+	InUseBy            []string `json:"InUseBy"`
+}
+
+func (c acmCertificate) certificate(accountID, region string) Certificate {
+	issuer := c.Issuer
+	if issuer == "" && c.Type == "AMAZON_ISSUED" {
+		issuer = "Amazon"
+	}
+	return Certificate{
+		ID:                 c.CertificateArn,
+		Provider:           ServiceACM,
+		Account:            accountID,
+		Region:             region,
+		Domain:             c.DomainName,
+		Type:               c.Type,
+		Status:             c.Status,
+		NotAfter:           c.NotAfter.time(),
+		Issuer:             issuer,
+		RenewalEligibility: c.RenewalEligibility,
+		InUse:              c.InUse || len(c.InUseBy) > 0,
+	}
+}
+
+// listACM pages through the certificates of one region
+func (p *AWSProvider) listACM(ctx context.Context, a *awsAccount, accountID, region string) ([]Certificate, error) {
+	creds, err := p.credentials(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	var certs []Certificate
+	nextToken := ""
+	for {
+		in := map[string]any{
+			"Includes": map[string]any{"keyTypes": acmKeyTypes},
+			"MaxItems": 1000,
+		}
+		if nextToken != "" {
+			in["NextToken"] = nextToken
+		}
+		var out struct {
+			CertificateSummaryList []acmCertificate `json:"CertificateSummaryList"`
+			NextToken              string           `json:"NextToken"`
+		}
+		if err := p.acm(ctx, creds, region, "ListCertificates", in, &out); err != nil {
+			return certs, err
+		}
+		for _, c := range out.CertificateSummaryList {
+			certs = append(certs, c.certificate(accountID, region))
+		}
+		if out.NextToken == "" {
+			return certs, nil
+		}
+		nextToken = out.NextToken
+	}
+}
+
+func (p *AWSProvider) describeACM(ctx context.Context, a *awsAccount, accountID, region, arn string) (*Certificate, error) {
+	creds, err := p.credentials(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		Certificate acmCertificate `json:"Certificate"`
+	}
+	if err := p.acm(ctx, creds, region, "DescribeCertificate", map[string]string{"CertificateArn": arn}, &out); err != nil {
+		return nil, err
+	}
+	c := out.Certificate.certificate(accountID, region)
+	return &c, nil
+}
+
+// iamMetadata describes an IAM server certificate
+type iamMetadata struct {
+	Name       string    `xml:"ServerCertificateName"`
+	Arn        string    `xml:"Arn"`
+	Expiration time.Time `xml:"Expiration"`
+}
+
+func (m iamMetadata) certificate(accountID string) Certificate {
+	expiry := m.Expiration
+	return Certificate{
+		ID:       m.Arn,
+		Provider: ServiceIAM,
+		Account:  accountID,
+		Domain:   m.Name,
+		Type:     "IMPORTED",
+		Status:   "ISSUED",
+		NotAfter: &expiry,
+		// IAM never renews what was uploaded to it
+		RenewalEligibility: "INELIGIBLE",
+	}
+}
+
+// listIAM pages through the account's IAM server certificates
+func (p *AWSProvider) listIAM(ctx context.Context, a *awsAccount, accountID string) ([]Certificate, error) {
+	creds, err := p.credentials(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	var certs []Certificate
+	marker := ""
+	for {
+		params := url.Values{"Action": {"ListServerCertificates"}, "Version": {"2010-05-08"}}
+		if marker != "" {
+			params.Set("Marker", marker)
+		}
+		var out struct {
+			Certificates []iamMetadata `xml:"ListServerCertificatesResult>ServerCertificateMetadataList>member"`
+			IsTruncated  bool          `xml:"ListServerCertificatesResult>IsTruncated"`
+			Marker       string        `xml:"ListServerCertificatesResult>Marker"`
+		}
+		if err := p.query(ctx, creds, ServiceIAM, "us-east-1", params, &out); err != nil {
+			return certs, err
+		}
+		for _, m := range out.Certificates {
+			certs = append(certs, m.certificate(accountID))
+		}
+		if !out.IsTruncated || out.Marker == "" {
+			return certs, nil
+		}
+		marker = out.Marker
+	}
+}
+
+func (p *AWSProvider) getIAM(ctx context.Context, a *awsAccount, accountID, name string) (*Certificate, error) {
+	creds, err := p.credentials(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		Metadata iamMetadata `xml:"GetServerCertificateResult>ServerCertificate>ServerCertificateMetadata"`
+		Body     string      `xml:"GetServerCertificateResult>ServerCertificate>CertificateBody"`
+	}
+	params := url.Values{"Action": {"GetServerCertificate"}, "Version": {"2010-05-08"}, "ServerCertificateName": {name}}
+	if err := p.query(ctx, creds, ServiceIAM, "us-east-1", params, &out); err != nil {
+		return nil, err
+	}
+	c := out.Metadata.certificate(accountID)
+	if certs, err := ssl.ParseChainPEM([]byte(out.Body)); err == nil && len(certs) > 0 {
+		c.Issuer = ssl.IssuerName(certs[0])
+		if certs[0].Subject.CommonName != "" {
+			c.Domain = certs[0].Subject.CommonName
+		}
+	}
+	return &c, nil
+}
+
+// acm calls an ACM action, which takes and returns JSON
+func (p *AWSProvider) acm(ctx context.Context, creds awsCredentials, region, action string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	header := http.Header{
+		"Content-Type": {"application/x-amz-json-1.1"},
+		"X-Amz-Target": {"CertificateManager." + action},
+	}
+	data, err := p.do(ctx, creds, ServiceACM, region, action, header, body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// query calls an action of a query API such as IAM or STS, which take form values and return XML
+func (p *AWSProvider) query(ctx context.Context, creds awsCredentials, service, region string, params url.Values, out any) error {
+	header := http.Header{"Content-Type": {"application/x-www-form-urlencoded; charset=utf-8"}}
+	data, err := p.do(ctx, creds, service, region, params.Get("Action"), header, []byte(params.Encode()))
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(data, out)
+}
+
+// do signs and sends a request, returning the body of a successful response
+func (p *AWSProvider) do(ctx context.Context, creds awsCredentials, service, region, action string, header http.Header, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint(service, region), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header = header
+	signV4(req, body, creds, region, service, p.now())
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w", service, action, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s %s failed: %s", service, action, awsErrorMessage(resp.Status, data))
+	}
+	return data, nil
+}
+
+// awsErrorMessage pulls the code and message out of a JSON or XML error response
+func awsErrorMessage(status string, data []byte) string {
+	var jsonErr struct {
+		Type    string `json:"__type"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(data, &jsonErr) == nil && jsonErr.Type != "" {
+		return jsonErr.Type[strings.LastIndex(jsonErr.Type, "#")+1:] + ": " + jsonErr.Message
+	}
+	var xmlErr struct {
+		Code    string `xml:"Error>Code"`
+		Message string `xml:"Error>Message"`
+	}
+	if xml.Unmarshal(data, &xmlErr) == nil && xmlErr.Code != "" {
+		return xmlErr.Code + ": " + xmlErr.Message
+	}
+	return status
+}
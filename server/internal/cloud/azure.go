@@ -0,0 +1,234 @@
+package cloud
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// AzurePrefix starts the ID of every certificate the Azure provider imports, followed by the vault host and
+// the certificate's path, e.g. azure://shop.vault.azure.net/certificates/www
+const AzurePrefix = "azure://"
+
+// ServiceKeyVault names the certificates kept in Azure Key Vault
+const ServiceKeyVault = "keyvault"
+
+// keyVaultAPIVersion is the Key Vault REST API version requests use
+const keyVaultAPIVersion = "7.4"
+
+// AzureClient returns an HTTP client authorised as a service principal, empty values use AZURE_TENANT_ID,
+// AZURE_CLIENT_ID and AZURE_CLIENT_SECRET
+func AzureClient(tenantID, clientID, clientSecret string) (*http.Client, error) {
+	if tenantID == "" {
+		tenantID = os.Getenv("AZURE_TENANT_ID")
+	}
+	if clientID == "" {
+		clientID = os.Getenv("AZURE_CLIENT_ID")
+	}
+	if clientSecret == "" {
+		clientSecret = os.Getenv("AZURE_CLIENT_SECRET")
+	}
+	if tenantID == "" || clientID == "" || clientSecret == "" {
+		return nil, errors.New("Azure needs a tenant ID, a client ID and a client secret")
+	}
+
+	conf := &clientcredentials.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		TokenURL:     "https://login.microsoftonline.com/" + url.PathEscape(tenantID) + "/oauth2/v2.0/token",
+		Scopes:       []string{"https://vault.azure.net/.default"},
+	}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Timeout: 30 * time.Second})
+	client := conf.Client(ctx)
+	client.Timeout = 30 * time.Second
+	return client, nil
+}
+
+// AzureProvider imports the certificates of Key Vaults
+type AzureProvider struct {
+	vaults     []string
+	httpClient *http.Client
+	// scheme is https, replaced by tests
+	scheme string
+}
+
+// NewAzureProvider lists the certificates of each vault, given by name or URL, through an authorised httpClient,
+// see AzureClient
+func NewAzureProvider(vaults []string, httpClient *http.Client) (*AzureProvider, error) {
+	if len(vaults) == 0 {
+		return nil, errors.New("at least one Key Vault is required")
+	}
+	if httpClient == nil {
+		return nil, errors.New("an authorised HTTP client is required")
+	}
+	p := &AzureProvider{httpClient: httpClient, scheme: "https"}
+	for _, vault := range vaults {
+		p.vaults = append(p.vaults, vaultHost(vault))
+	}
+	return p, nil
+}
+
+// vaultHost turns a vault name or URL into its host, e.g. shop.vault.azure.net
+func vaultHost(vault string) string {
+	if u, err := url.Parse(vault); err == nil && u.Host != "" {
+		return u.Host
+	}
+	if strings.Contains(vault, ".") {
+		return vault
+	}
+	return vault + ".vault.azure.net"
+}
+
+func (p *AzureProvider) Name() string {
+	return "azure"
+}
+
+// Prefix is what the IDs of the provider's certificates start with
+func (p *AzureProvider) Prefix() string {
+	return AzurePrefix
+}
+
+// List returns the certificates of every vault, along with an error for each vault that failed.
+//
+// Each certificate is described too, the list only has its expiry and not its issuer or renewal policy
+func (p *AzureProvider) List(ctx context.Context) ([]Certificate, error) {
+	var certs []Certificate
+	var errs []error
+	for _, host := range p.vaults {
+		next := p.scheme + "://" + host + "/certificates?api-version=" + keyVaultAPIVersion
+		for next != "" {
+			var out struct {
+				Value []struct {
+					ID string `json:"id"`
+				} `json:"value"`
+				NextLink string `json:"nextLink"`
+			}
+			if err := p.get(ctx, next, &out); err != nil {
+				errs = append(errs, fmt.Errorf("vault %s: %w", host, err))
+				break
+			}
+			for _, item := range out.Value {
+				u, err := url.Parse(item.ID)
+				if err != nil {
+					continue
+				}
+				c, err := p.Describe(ctx, AzurePrefix+host+u.Path)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("vault %s: %w", host, err))
+					continue
+				}
+				certs = append(certs, *c)
+			}
+			next = out.NextLink
+		}
+	}
+	return certs, errors.Join(errs...)
+}
+
+// Describe looks up a certificate by its ID
+func (p *AzureProvider) Describe(ctx context.Context, id string) (*Certificate, error) {
+	rest := strings.TrimPrefix(id, AzurePrefix)
+	// <host>/certificates/<name>
+	parts := strings.Split(rest, "/")
+	if !strings.HasPrefix(id, AzurePrefix) || len(parts) != 3 || parts[1] != "certificates" {
+		return nil, fmt.Errorf("invalid Key Vault certificate %q", id)
+	}
+	host, name := parts[0], parts[2]
+
+	var out struct {
+		CER        string `json:"cer"`
+		Attributes struct {
+			Enabled bool   `json:"enabled"`
+			Exp     *int64 `json:"exp"`
+		} `json:"attributes"`
+		Policy struct {
+			Issuer struct {
+				Name string `json:"name"`
+			} `json:"issuer"`
+			LifetimeActions []struct {
+				Action struct {
+					ActionType string `json:"action_type"`
+				} `json:"action"`
+			} `json:"lifetime_actions"`
+		} `json:"policy"`
+	}
+	if err := p.get(ctx, p.scheme+"://"+host+"/certificates/"+url.PathEscape(name)+"?api-version="+keyVaultAPIVersion, &out); err != nil {
+		return nil, err
+	}
+
+	c := Certificate{
+		ID:       id,
+		Provider: ServiceKeyVault,
+		Account:  strings.Split(host, ".")[0],
+		Domain:   name,
+		Type:     out.Policy.Issuer.Name,
+		Status:   "DISABLED",
+		// Key Vault renews certificates from an integrated CA, or self-signed ones, when the policy says so
+		RenewalEligibility: "INELIGIBLE",
+	}
+	if out.Attributes.Enabled {
+		c.Status = "ENABLED"
+	}
+	if out.Attributes.Exp != nil {
+		expiry := time.Unix(*out.Attributes.Exp, 0).UTC()
+		c.NotAfter = &expiry
+	}
+	for _, a := range out.Policy.LifetimeActions {
+		if strings.EqualFold(a.Action.ActionType, "AutoRenew") && out.Policy.Issuer.Name != "Unknown" {
+			c.RenewalEligibility = "ELIGIBLE"
+		}
+	}
+	if der, err := base64.StdEncoding.DecodeString(out.CER); err == nil {
+		if cert, err := x509.ParseCertificate(der); err == nil {
+			c.Issuer = ssl.IssuerName(cert)
+			if cert.Subject.CommonName != "" {
+				c.Domain = cert.Subject.CommonName
+			}
+		}
+	}
+	return &c, nil
+}
+
+// get fetches a Key Vault URL into out
+func (p *AzureProvider) get(ctx context.Context, rawURL string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call Key Vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Code != "" {
+			return fmt.Errorf("Key Vault answered %s: %s: %s", resp.Status, apiErr.Error.Code, apiErr.Error.Message)
+		}
+		return errors.New("Key Vault answered " + resp.Status)
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,40 @@
+// This package imports certificates managed by cloud providers
+//
+// A provider lists the certificates it holds and checks one by its ID, so they are tracked like any other domain
+// and show up next to the network checks
+package cloud
+
+import (
+	"context"
+	"time"
+)
+
+// Certificate is a certificate held by a cloud provider
+type Certificate struct {
+	// ID names the certificate at the provider and is the name it is tracked under, e.g. an ARN
+	ID string `db:"id"`
+	// Provider is the service holding it, e.g. acm
+	Provider string `db:"provider"`
+	Account  string `db:"account"`
+	Region   string `db:"region"`
+	// Domain is the certificate's main domain name, or its name at the provider when it has none
+	Domain string `db:"domain"`
+	// Type tells how it was issued, e.g. AMAZON_ISSUED or IMPORTED
+	Type   string `db:"type"`
+	Status string `db:"status"`
+	// NotAfter is when it expires, nil while it hasn't been issued
+	NotAfter *time.Time `db:"not_after"`
+	Issuer   string     `db:"-"`
+	// RenewalEligibility tells whether the provider renews it, e.g. ELIGIBLE or INELIGIBLE
+	RenewalEligibility string `db:"renewal_eligibility"`
+	InUse              bool   `db:"in_use"`
+}
+
+// Provider lists and checks the certificates in one cloud account setup
+type Provider interface {
+	Name() string
+	// List returns every certificate the provider can see
+	List(ctx context.Context) ([]Certificate, error)
+	// Describe looks up one certificate by the ID List gave it
+	Describe(ctx context.Context, id string) (*Certificate, error)
+}
@@ -0,0 +1,91 @@
+package cloud
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+type Repository struct {
+	db     *sql.DB
+	writer *database.Writer
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db:     db,
+		writer: database.NewWriter(db),
+	}
+}
+
+// Entry is an imported certificate as last synced, with the domain it is tracked as
+type Entry struct {
+	DomainID types.DomainID
+	Certificate
+	SyncedAt time.Time
+}
+
+// SaveCertificate stores what the provider last reported about the certificate tracked as domainID
+func (r *Repository) SaveCertificate(domainID types.DomainID, c Certificate, syncedAt time.Time) error {
+	return r.writer.Transaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM cloud_certificates WHERE domain_id = ?`, domainID.Uint()); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO cloud_certificates (domain_id, provider, account, region, domain, type, status,
+		                   renewal_eligibility, in_use, synced_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			domainID.Uint(), c.Provider, c.Account, c.Region, c.Domain, c.Type, c.Status, c.RenewalEligibility, c.InUse, syncedAt)
+		return err
+	})
+}
+
+// GetEntriesByUserID lists the imported certificates a user tracks, by provider and domain
+func (r *Repository) GetEntriesByUserID(userID types.UserID) ([]Entry, error) {
+	query := `SELECT c.domain_id, d.domain_name, c.provider, c.account, c.region, c.domain, c.type, c.status,
+	          d.expiry_date, d.issuer, c.renewal_eligibility, c.in_use, c.synced_at
+	          FROM cloud_certificates c JOIN domains d ON d.id = c.domain_id
+	          WHERE d.user_id = ? ORDER BY c.provider, c.domain, d.domain_name`
+	rows, err := r.db.Query(query, userID.Uint())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var domainID uint
+		var notAfter sql.NullTime
+		var e Entry
+		err := rows.Scan(&domainID, &e.ID, &e.Provider, &e.Account, &e.Region, &e.Domain, &e.Type, &e.Status,
+			&notAfter, &e.Issuer, &e.RenewalEligibility, &e.InUse, &e.SyncedAt)
+		if err != nil {
+			return nil, err
+		}
+		e.DomainID = types.DomainID(domainID)
+		if notAfter.Valid {
+			e.NotAfter = &notAfter.Time
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// GetUserIDs lists the users who have imported certificates
+func (r *Repository) GetUserIDs() ([]types.UserID, error) {
+	rows, err := r.db.Query(`SELECT DISTINCT d.user_id FROM cloud_certificates c JOIN domains d ON d.id = c.domain_id ORDER BY d.user_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []types.UserID
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		users = append(users, types.UserID(id))
+	}
+	return users, rows.Err()
+}
@@ -0,0 +1,324 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSignV4 - the signature matches the get-vanilla case of the AWS test suite.
+func TestSignV4(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	require.NoError(t, err)
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	creds := awsCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+	signV4(req, nil, creds, "us-east-1", "service", now)
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"))
+}
+
+// newFakeAWS answers STS, ACM and IAM calls for account 123456789012 the way AWS does.
+func newFakeAWS(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.Header.Get("Authorization"), "Credential=AKIAEXAMPLE/")
+		body, _ := io.ReadAll(r.Body)
+
+		if target := r.Header.Get("X-Amz-Target"); target != "" {
+			var in map[string]any
+			require.NoError(t, json.Unmarshal(body, &in))
+			switch target {
+			case "CertificateManager.ListCertificates":
+				assert.NotNil(t, in["Includes"], "Every key type is asked for")
+				if in["NextToken"] == nil {
+					w.Write([]byte(`{"CertificateSummaryList":[{"CertificateArn":"arn:aws:acm:eu-west-1:123456789012:certificate/1",
+						"DomainName":"www.example.com","Status":"ISSUED","Type":"AMAZON_ISSUED","NotAfter":1767225600,
+						"RenewalEligibility":"ELIGIBLE","InUse":true}],"NextToken":"page2"}`))
+					return
+				}
+				w.Write([]byte(`{"CertificateSummaryList":[{"CertificateArn":"arn:aws:acm:eu-west-1:123456789012:certificate/2",
+					"DomainName":"new.example.com","Status":"PENDING_VALIDATION","Type":"AMAZON_ISSUED"}]}`))
+			case "CertificateManager.DescribeCertificate":
+				if in["CertificateArn"] != "arn:aws:acm:eu-west-1:123456789012:certificate/1" {
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(`{"__type":"com.amazonaws.acm#ResourceNotFoundException","message":"Could not find certificate"}`))
+					return
+				}
+				w.Write([]byte(`{"Certificate":{"CertificateArn":"arn:aws:acm:eu-west-1:123456789012:certificate/1",
+					"DomainName":"www.example.com","Issuer":"Amazon RSA 2048 M02","Status":"ISSUED","Type":"AMAZON_ISSUED",
+					"NotAfter":1767225600,"RenewalEligibility":"ELIGIBLE","InUseBy":["arn:aws:elasticloadbalancing:..."]}}`))
+			}
+			return
+		}
+
+		form, err := url.ParseQuery(string(body))
+		require.NoError(t, err)
+		switch form.Get("Action") {
+		case "GetCallerIdentity":
+			w.Write([]byte(`<GetCallerIdentityResponse><GetCallerIdentityResult><Account>123456789012</Account></GetCallerIdentityResult></GetCallerIdentityResponse>`))
+		case "ListServerCertificates":
+			w.Write([]byte(`<ListServerCertificatesResponse><ListServerCertificatesResult><ServerCertificateMetadataList>
+				<member><ServerCertificateName>legacy-elb</ServerCertificateName><Arn>arn:aws:iam::123456789012:server-certificate/legacy-elb</Arn>
+				<Expiration>2026-03-01T00:00:00Z</Expiration></member></ServerCertificateMetadataList><IsTruncated>false</IsTruncated>
+				</ListServerCertificatesResult></ListServerCertificatesResponse>`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<ErrorResponse><Error><Code>InvalidAction</Code><Message>unknown</Message></Error></ErrorResponse>`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestAWSProvider creates a provider for one account in eu-west-1 that talks to server.
+func newTestAWSProvider(t *testing.T, server *httptest.Server) *AWSProvider {
+	t.Helper()
+
+	p, err := NewAWSProvider([]AWSAccount{{AccessKeyID: "AKIAEXAMPLE", SecretAccessKey: "secret"}}, []string{"eu-west-1"}, true, nil)
+	require.NoError(t, err)
+	p.endpoint = func(service, region string) string {
+		return server.URL + "/" + service + "/" + region
+	}
+	return p
+}
+
+// TestAWSProvider_List - ACM pages and IAM server certificates are listed with the account they belong to.
+func TestAWSProvider_List(t *testing.T) {
+	p := newTestAWSProvider(t, newFakeAWS(t))
+
+	certs, err := p.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, certs, 3)
+
+	expiry := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, Certificate{
+		ID:                 "arn:aws:acm:eu-west-1:123456789012:certificate/1",
+		Provider:           ServiceACM,
+		Account:            "123456789012",
+		Region:             "eu-west-1",
+		Domain:             "www.example.com",
+		Type:               "AMAZON_ISSUED",
+		Status:             "ISSUED",
+		NotAfter:           &expiry,
+		Issuer:             "Amazon",
+		RenewalEligibility: "ELIGIBLE",
+		InUse:              true,
+	}, certs[0])
+	assert.Nil(t, certs[1].NotAfter, "Pending certificates have no expiry yet")
+	assert.Equal(t, ServiceIAM, certs[2].Provider)
+	assert.Equal(t, "legacy-elb", certs[2].Domain)
+	assert.Equal(t, "INELIGIBLE", certs[2].RenewalEligibility)
+}
+
+// TestAWSProvider_Describe - a certificate is looked up in the account named by its ARN.
+func TestAWSProvider_Describe(t *testing.T) {
+	p := newTestAWSProvider(t, newFakeAWS(t))
+
+	c, err := p.Describe(context.Background(), "arn:aws:acm:eu-west-1:123456789012:certificate/1")
+	require.NoError(t, err)
+	assert.Equal(t, "Amazon RSA 2048 M02", c.Issuer)
+	assert.True(t, c.InUse)
+
+	_, err = p.Describe(context.Background(), "arn:aws:acm:eu-west-1:123456789012:certificate/9")
+	assert.ErrorContains(t, err, "ResourceNotFoundException: Could not find certificate")
+	_, err = p.Describe(context.Background(), "arn:aws:acm:eu-west-1:999999999999:certificate/1")
+	assert.ErrorContains(t, err, "no configured AWS account")
+}
+
+// fakeProvider serves a fixed set of certificates.
+type fakeProvider struct {
+	certs []Certificate
+	err   error
+}
+
+func (p *fakeProvider) Name() string   { return "fake" }
+func (p *fakeProvider) Prefix() string { return "fake:" }
+
+func (p *fakeProvider) List(ctx context.Context) ([]Certificate, error) {
+	return p.certs, p.err
+}
+
+func (p *fakeProvider) Describe(ctx context.Context, id string) (*Certificate, error) {
+	for _, c := range p.certs {
+		if c.ID == id {
+			return &c, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+// TestImporter_Sync - certificates are tracked once, checked through the provider and listed with their renewal status.
+func TestImporter_Sync(t *testing.T) {
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	expiry := time.Now().Add(40 * 24 * time.Hour).UTC().Truncate(time.Second)
+	p := &fakeProvider{
+		certs: []Certificate{
+			{ID: "fake:1", Provider: "cm", Domain: "www.example.com", Status: "ISSUED", NotAfter: &expiry, Issuer: "Fake CA", RenewalEligibility: "ELIGIBLE"},
+			{ID: "fake:2", Provider: "cm", Domain: "new.example.com", Status: "PENDING_VALIDATION"},
+		},
+		err: errors.New("region us-east-1 failed"),
+	}
+	Register(p)
+
+	domains := domain.NewService(domain.NewRepository(db), nil)
+	repo := NewRepository(db)
+	importer := NewImporter(domains, repo)
+	userID := types.UserID(1)
+
+	results, err := importer.Sync(context.Background(), userID, 0, p)
+	assert.ErrorContains(t, err, "us-east-1", "Listing errors are passed on")
+	require.Len(t, results, 2)
+	assert.Equal(t, SyncAdded, results[0].Status)
+	assert.Equal(t, SyncAdded, results[1].Status)
+
+	d, err := domains.FindDomainByName(userID, "fake:1")
+	require.NoError(t, err)
+	require.NotNil(t, d.ExpiryDate)
+	assert.True(t, d.ExpiryDate.Time().Equal(expiry))
+	assert.Equal(t, "Fake CA", d.Issuer)
+	assert.Equal(t, []string{"cm", "fake"}, d.Tags)
+	pending, err := domains.FindDomainByName(userID, "fake:2")
+	require.NoError(t, err)
+	require.NotNil(t, pending.LastError)
+	assert.Contains(t, pending.LastError.String(), "PENDING_VALIDATION")
+
+	results, err = importer.Sync(context.Background(), userID, 0, p)
+	require.Error(t, err)
+	assert.Equal(t, SyncUpdated, results[0].Status)
+
+	entries, err := repo.GetEntriesByUserID(userID)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "new.example.com", entries[0].Domain)
+	assert.Equal(t, "www.example.com", entries[1].Domain)
+	assert.Equal(t, "ELIGIBLE", entries[1].RenewalEligibility)
+	assert.Equal(t, "fake:1", entries[1].ID)
+}
+
+// TestGCPProvider - global and regional load balancer certificates are listed across pages and looked up by path.
+func TestGCPProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/projects/shop/aggregated/sslCertificates":
+			if r.URL.Query().Get("pageToken") == "" {
+				w.Write([]byte(`{"items":{"global":{"sslCertificates":[{"name":"www","type":"MANAGED",
+					"selfLink":"https://compute.googleapis.com/compute/v1/projects/shop/global/sslCertificates/www",
+					"expireTime":"2026-01-01T00:00:00.000-00:00","managed":{"status":"ACTIVE","domains":["www.example.com"]}}]}},
+					"nextPageToken":"page2"}`))
+				return
+			}
+			w.Write([]byte(`{"items":{"regions/europe-west1":{"sslCertificates":[{"name":"api","type":"SELF_MANAGED",
+				"selfLink":"https://compute.googleapis.com/compute/v1/projects/shop/regions/europe-west1/sslCertificates/api",
+				"region":"https://compute.googleapis.com/compute/v1/projects/shop/regions/europe-west1",
+				"expireTime":"2026-02-01T00:00:00.000-00:00","subjectAlternativeNames":["api.example.com"]}]},
+				"regions/us-east1":{"warning":{"code":"NO_RESULTS_ON_PAGE"}}}}`))
+		case "/projects/shop/global/sslCertificates/www":
+			w.Write([]byte(`{"name":"www","type":"MANAGED","selfLink":"https://compute.googleapis.com/compute/v1/projects/shop/global/sslCertificates/www",
+				"expireTime":"2026-01-01T00:00:00.000-00:00","managed":{"status":"ACTIVE","domains":["www.example.com"]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":404,"message":"The resource was not found"}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	p, err := NewGCPProvider([]string{"shop", "gone"}, server.Client())
+	require.NoError(t, err)
+	p.baseURL = server.URL + "/"
+
+	certs, err := p.List(context.Background())
+	assert.ErrorContains(t, err, "project gone: Compute Engine answered 404 Not Found: The resource was not found")
+	require.Len(t, certs, 2)
+
+	expiry := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, "gcp://projects/shop/global/sslCertificates/www", certs[0].ID)
+	assert.Equal(t, "global", certs[0].Region)
+	assert.Equal(t, "www.example.com", certs[0].Domain)
+	assert.Equal(t, "ELIGIBLE", certs[0].RenewalEligibility)
+	require.NotNil(t, certs[0].NotAfter)
+	assert.True(t, certs[0].NotAfter.Equal(expiry))
+	assert.Equal(t, "europe-west1", certs[1].Region)
+	assert.Equal(t, "api.example.com", certs[1].Domain)
+	assert.Equal(t, "INELIGIBLE", certs[1].RenewalEligibility)
+
+	c, err := p.Describe(context.Background(), "gcp://projects/shop/global/sslCertificates/www")
+	require.NoError(t, err)
+	assert.Equal(t, "shop", c.Account)
+	assert.Equal(t, "ACTIVE", c.Status)
+	_, err = p.Describe(context.Background(), "gcp://www")
+	assert.ErrorContains(t, err, "invalid GCP certificate")
+}
+
+// TestAzureProvider - vault certificates are listed across pages with their expiry and renewal policy.
+func TestAzureProvider(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, keyVaultAPIVersion, r.URL.Query().Get("api-version"))
+		switch r.URL.Path {
+		case "/certificates":
+			if r.URL.Query().Get("page") == "" {
+				w.Write([]byte(`{"value":[{"id":"https://shop.vault.azure.net/certificates/www"}],
+					"nextLink":"` + server.URL + `/certificates?api-version=7.4&page=2"}`))
+				return
+			}
+			w.Write([]byte(`{"value":[{"id":"https://shop.vault.azure.net/certificates/legacy"}],"nextLink":null}`))
+		case "/certificates/www":
+			w.Write([]byte(`{"attributes":{"enabled":true,"exp":1767225600},"policy":{"issuer":{"name":"DigiCert"},
+				"lifetime_actions":[{"trigger":{"days_before_expiry":30},"action":{"action_type":"AutoRenew"}}]}}`))
+		case "/certificates/legacy":
+			w.Write([]byte(`{"attributes":{"enabled":false,"exp":1767225600},"policy":{"issuer":{"name":"Unknown"},
+				"lifetime_actions":[{"action":{"action_type":"EmailContacts"}}]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":"CertificateNotFound","message":"A certificate with (name/id) x was not found"}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	p, err := NewAzureProvider([]string{"https://" + server.Listener.Addr().String() + "/"}, server.Client())
+	require.NoError(t, err)
+	p.scheme = "http"
+	host := server.Listener.Addr().String()
+
+	certs, err := p.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, certs, 2)
+
+	expiry := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, Certificate{
+		ID:                 "azure://" + host + "/certificates/www",
+		Provider:           ServiceKeyVault,
+		Account:            "127",
+		Domain:             "www",
+		Type:               "DigiCert",
+		Status:             "ENABLED",
+		NotAfter:           &expiry,
+		RenewalEligibility: "ELIGIBLE",
+	}, certs[0])
+	assert.Equal(t, "DISABLED", certs[1].Status)
+	assert.Equal(t, "INELIGIBLE", certs[1].RenewalEligibility)
+
+	_, err = p.Describe(context.Background(), "azure://"+host+"/certificates/missing")
+	assert.ErrorContains(t, err, "CertificateNotFound")
+	assert.Equal(t, "shop.vault.azure.net", vaultHost("shop"))
+	assert.Equal(t, "shop.vault.azure.net", vaultHost("https://shop.vault.azure.net/"))
+}
@@ -0,0 +1,239 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
+)
+
+// GCPPrefix starts the ID of every certificate the GCP provider imports, followed by its resource path,
+// e.g. gcp://projects/shop/global/sslCertificates/www
+const GCPPrefix = "gcp://"
+
+// ServiceGCPLoadBalancer names the load balancer certificates of Compute Engine
+const ServiceGCPLoadBalancer = "compute"
+
+// gcpScope only allows reading Compute Engine resources
+const gcpScope = "https://www.googleapis.com/auth/compute.readonly"
+
+// GCPClient returns an HTTP client authorised by a service account key file,
+// an empty path uses GOOGLE_APPLICATION_CREDENTIALS
+func GCPClient(credentialsFile string) (*http.Client, error) {
+	if credentialsFile == "" {
+		credentialsFile = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	if credentialsFile == "" {
+		return nil, errors.New("no GCP service account key configured")
+	}
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GCP credentials: %w", err)
+	}
+	var key struct {
+		Type         string `json:"type"`
+		ClientEmail  string `json:"client_email"`
+		PrivateKey   string `json:"private_key"`
+		PrivateKeyID string `json:"private_key_id"`
+		TokenURI     string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("failed to parse GCP credentials: %w", err)
+	}
+	if key.Type != "service_account" {
+		return nil, fmt.Errorf("GCP credentials must be a service account key, got %q", key.Type)
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	conf := &jwt.Config{
+		Email:        key.ClientEmail,
+		PrivateKey:   []byte(key.PrivateKey),
+		PrivateKeyID: key.PrivateKeyID,
+		TokenURL:     key.TokenURI,
+		Scopes:       []string{gcpScope},
+	}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Timeout: 30 * time.Second})
+	client := conf.Client(ctx)
+	client.Timeout = 30 * time.Second
+	return client, nil
+}
+
+// GCPProvider imports the SSL certificates of Compute Engine load balancers, global and regional, from projects
+type GCPProvider struct {
+	projects   []string
+	httpClient *http.Client
+	// baseURL is the Compute Engine API, replaced by tests
+	baseURL string
+}
+
+// NewGCPProvider lists the certificates of each project through an authorised httpClient, see GCPClient
+func NewGCPProvider(projects []string, httpClient *http.Client) (*GCPProvider, error) {
+	if len(projects) == 0 {
+		return nil, errors.New("at least one GCP project is required")
+	}
+	if httpClient == nil {
+		return nil, errors.New("an authorised HTTP client is required")
+	}
+	return &GCPProvider{
+		projects:   projects,
+		httpClient: httpClient,
+		baseURL:    "https://compute.googleapis.com/compute/v1/",
+	}, nil
+}
+
+func (p *GCPProvider) Name() string {
+	return "gcp"
+}
+
+// Prefix is what the IDs of the provider's certificates start with
+func (p *GCPProvider) Prefix() string {
+	return GCPPrefix
+}
+
+// gcpCertificate is an sslCertificates resource
+type gcpCertificate struct {
+	Name        string   `json:"name"`
+	SelfLink    string   `json:"selfLink"`
+	Type        string   `json:"type"`
+	Certificate string   `json:"certificate"`
+	ExpireTime  string   `json:"expireTime"`
+	SANs        []string `json:"subjectAlternativeNames"`
+	Region      string   `json:"region"`
+	Managed     struct {
+		Status  string   `json:"status"`
+		Domains []string `json:"domains"`
+	} `json:"managed"`
+}
+
+func (c gcpCertificate) certificate(project string) Certificate {
+	path := c.SelfLink
+	if i := strings.Index(path, "projects/"); i >= 0 {
+		path = path[i:]
+	}
+	cert := Certificate{
+		ID:       GCPPrefix + path,
+		Provider: ServiceGCPLoadBalancer,
+		Account:  project,
+		Region:   "global",
+		Domain:   c.Name,
+		Type:     c.Type,
+		Status:   c.Managed.Status,
+		// Google only renews the certificates it manages
+		RenewalEligibility: "INELIGIBLE",
+	}
+	if c.Region != "" {
+		cert.Region = c.Region[strings.LastIndex(c.Region, "/")+1:]
+	}
+	switch {
+	case len(c.Managed.Domains) > 0:
+		cert.Domain = c.Managed.Domains[0]
+	case len(c.SANs) > 0:
+		cert.Domain = c.SANs[0]
+	}
+	if c.Type == "MANAGED" {
+		cert.RenewalEligibility = "ELIGIBLE"
+	} else {
+		cert.Status = "ACTIVE"
+	}
+	if expiry, err := time.Parse(time.RFC3339, c.ExpireTime); err == nil {
+		cert.NotAfter = &expiry
+	}
+	if certs, err := ssl.ParseChainPEM([]byte(c.Certificate)); err == nil && len(certs) > 0 {
+		cert.Issuer = ssl.IssuerName(certs[0])
+	}
+	return cert
+}
+
+// List returns the certificates of every project, along with an error for each project that failed
+func (p *GCPProvider) List(ctx context.Context) ([]Certificate, error) {
+	var certs []Certificate
+	var errs []error
+	for _, project := range p.projects {
+		pageToken := ""
+		for {
+			query := url.Values{}
+			if pageToken != "" {
+				query.Set("pageToken", pageToken)
+			}
+			var out struct {
+				Items map[string]struct {
+					SSLCertificates []gcpCertificate `json:"sslCertificates"`
+				} `json:"items"`
+				NextPageToken string `json:"nextPageToken"`
+			}
+			path := "projects/" + url.PathEscape(project) + "/aggregated/sslCertificates?" + query.Encode()
+			if err := p.get(ctx, path, &out); err != nil {
+				errs = append(errs, fmt.Errorf("project %s: %w", project, err))
+				break
+			}
+			for _, scope := range out.Items {
+				for _, c := range scope.SSLCertificates {
+					certs = append(certs, c.certificate(project))
+				}
+			}
+			if out.NextPageToken == "" {
+				break
+			}
+			pageToken = out.NextPageToken
+		}
+	}
+	return certs, errors.Join(errs...)
+}
+
+// Describe looks up a certificate by its ID
+func (p *GCPProvider) Describe(ctx context.Context, id string) (*Certificate, error) {
+	path := strings.TrimPrefix(id, GCPPrefix)
+	// projects/<project>/(global|regions/<region>)/sslCertificates/<name>
+	parts := strings.Split(path, "/")
+	if !strings.HasPrefix(id, GCPPrefix) || len(parts) < 5 || parts[0] != "projects" {
+		return nil, fmt.Errorf("invalid GCP certificate %q", id)
+	}
+	var out gcpCertificate
+	if err := p.get(ctx, path, &out); err != nil {
+		return nil, err
+	}
+	c := out.certificate(parts[1])
+	return &c, nil
+}
+
+// get fetches a Compute Engine resource into out
+func (p *GCPProvider) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call Compute Engine: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			return fmt.Errorf("Compute Engine answered %s: %s", resp.Status, apiErr.Error.Message)
+		}
+		return errors.New("Compute Engine answered " + resp.Status)
+	}
+	return json.Unmarshal(data, out)
+}
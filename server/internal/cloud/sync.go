@@ -0,0 +1,101 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// PrefixedProvider is a provider whose certificate IDs all start with the same prefix
+type PrefixedProvider interface {
+	Provider
+	Prefix() string
+}
+
+// Register makes checks of the provider's certificates ask the provider instead of connecting to them
+func Register(p PrefixedProvider) {
+	ssl.RegisterTargetChecker(p.Prefix(), func(ctx context.Context, name string) (*ssl.SSLCertificate, error) {
+		c, err := p.Describe(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if c.NotAfter == nil {
+			return nil, fmt.Errorf("certificate is %s and has no expiry yet", c.Status)
+		}
+		return &ssl.SSLCertificate{
+			Hostname:   ssl.Hostname(name),
+			ExpiryDate: types.NewExpiryDate(*c.NotAfter),
+			TimeLeft:   ssl.TimeLeft(time.Until(*c.NotAfter).Hours() / 24),
+			Issuer:     c.Issuer,
+		}, nil
+	})
+}
+
+// Sync statuses
+const (
+	SyncAdded   = "added"
+	SyncUpdated = "updated"
+)
+
+// SyncResult is what a sync did with one certificate
+type SyncResult struct {
+	Certificate
+	DomainID types.DomainID
+	// Status is one of the Sync constants, or the error that stopped the certificate from being imported
+	Status string
+}
+
+// Importer tracks the certificates of providers as domains
+type Importer struct {
+	domains *domain.Service
+	repo    *Repository
+	now     func() time.Time
+}
+
+func NewImporter(domains *domain.Service, repo *Repository) *Importer {
+	return &Importer{domains: domains, repo: repo, now: time.Now}
+}
+
+// Sync imports every certificate the provider lists for the user, sharing new ones with teamID when it isn't zero.
+//
+// New certificates are tracked under their ID and tagged with the provider and service, ones already tracked
+// are checked again. The provider has to be registered first so the checks reach it.
+//
+// Returns the result for each certificate, and the provider's error when it could only list some of them
+func (i *Importer) Sync(ctx context.Context, userID types.UserID, teamID types.TeamID, p Provider) ([]SyncResult, error) {
+	certs, listErr := p.List(ctx)
+
+	var results []SyncResult
+	for _, c := range certs {
+		result := SyncResult{Certificate: c, Status: SyncUpdated}
+		d, err := i.domains.FindDomainByName(userID, c.ID)
+		if errors.Is(err, domain.ErrNotFound) {
+			result.Status = SyncAdded
+			d, err = i.domains.AddTeamDomain(userID, teamID, c.ID)
+			if err == nil {
+				err = i.domains.SetTags(d.DomainID, []string{p.Name(), c.Provider})
+			}
+			if err == nil {
+				// Waits for the check queued while adding, so the sync reports checked certificates
+				err = i.domains.CheckDomainSSL(d.DomainID)
+			}
+		} else if err == nil {
+			err = i.domains.CheckDomainSSL(d.DomainID)
+		}
+		if err == nil {
+			err = i.repo.SaveCertificate(d.DomainID, c, i.now())
+		}
+		if err != nil {
+			result.Status = err.Error()
+		} else {
+			result.DomainID = d.DomainID
+		}
+		results = append(results, result)
+	}
+	return results, listErr
+}
@@ -0,0 +1,838 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/cron"
+	"github.com/samokw/ssl_tracker/internal/database"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of the config file inside the config directory
+const FileName = "config.yaml"
+
+// Config holds the settings that were previously hardcoded across the application
+type Config struct {
+	Database DatabaseConfig `yaml:"database"`
+	// Workers is the number of certificate checks run concurrently
+	Workers int `yaml:"workers"`
+	// QueueSize is how many checks can wait for a worker, queueing more waits until one starts
+	QueueSize int `yaml:"queue_size"`
+	// CheckTimeout limits how long a single certificate check may take
+	CheckTimeout  time.Duration       `yaml:"check_timeout"`
+	Thresholds    ThresholdsConfig    `yaml:"thresholds"`
+	Notifications NotificationsConfig `yaml:"notifications"`
+	Retention     RetentionConfig     `yaml:"retention"`
+	Renewal       RenewalConfig       `yaml:"renewal"`
+	Files         FilesConfig         `yaml:"files"`
+	Cloud         CloudConfig         `yaml:"cloud"`
+	MQTT          MQTTConfig          `yaml:"mqtt"`
+	Events        EventsConfig        `yaml:"events"`
+	Whois         WhoisConfig         `yaml:"whois"`
+	DNS           DNSConfig           `yaml:"dns"`
+	Trust         TrustConfig         `yaml:"trust"`
+	// Hooks run commands when a domain's status changes, empty for none
+	Hooks []HookConfig `yaml:"hooks"`
+	// Plugins are external programs adding notification channels or check types, empty for none
+	Plugins []PluginConfig `yaml:"plugins"`
+	// Compliance are org-wide policies every certificate is held to, empty for none
+	Compliance []PolicyConfig `yaml:"compliance"`
+	API        APIConfig      `yaml:"api"`
+	Theme      ThemeConfig    `yaml:"theme"`
+}
+
+// Database drivers
+const (
+	DriverSQLite = "sqlite"
+	DriverMySQL  = "mysql"
+)
+
+type DatabaseConfig struct {
+	// Driver is sqlite or mysql, which also covers MariaDB
+	Driver string `yaml:"driver"`
+	// Path is the SQLite database file, empty uses the default location
+	Path string `yaml:"path"`
+	// DSN is the MySQL data source name, e.g. user:pass@tcp(localhost:3306)/sslcerttop
+	DSN string `yaml:"dsn"`
+}
+
+// ThresholdsConfig holds the days before expiry that count as a warning, as critical and that trigger notifications
+type ThresholdsConfig struct {
+	Warning  int   `yaml:"warning"`
+	Critical int   `yaml:"critical"`
+	Notify   []int `yaml:"notify"`
+}
+
+// RetentionConfig holds how long old data is kept
+type RetentionConfig struct {
+	// CheckHistoryDays is how many days of check history the daemon keeps, zero keeps everything
+	CheckHistoryDays int `yaml:"check_history_days"`
+}
+
+// CheckHistory returns the check history retention period, zero when history is kept forever
+func (r RetentionConfig) CheckHistory() time.Duration {
+	return time.Duration(r.CheckHistoryDays) * 24 * time.Hour
+}
+
+// RenewalConfig runs a command or calls a webhook when a certificate comes within Threshold days of expiry.
+// Without a command or a webhook URL nothing is run
+type RenewalConfig struct {
+	Threshold int `yaml:"threshold"`
+	// Command runs through sh -c with SSLCERTTOP_DOMAIN, SSLCERTTOP_EXPIRY and SSLCERTTOP_DAYS_LEFT set,
+	// e.g. certbot renew --cert-name "$SSLCERTTOP_DOMAIN"
+	Command string `yaml:"command"`
+	// WebhookURL receives a JSON POST naming the domain, e.g. to start a renewal pipeline
+	WebhookURL string        `yaml:"webhook_url"`
+	Timeout    time.Duration `yaml:"timeout"`
+	// Retry is how long to wait before trying again when the check after an attempt still finds the old certificate
+	Retry time.Duration `yaml:"retry"`
+	// Tags limits renewals to domains with one of these tags, empty renews every domain
+	Tags []string `yaml:"tags"`
+}
+
+// Enabled reports whether there is anything to run
+func (r RenewalConfig) Enabled() bool {
+	return r.Command != "" || r.WebhookURL != ""
+}
+
+// FilesConfig holds settings for certificates tracked as files on this machine
+type FilesConfig struct {
+	// PKCS12Passwords are tried in turn on .p12 and .pfx files that don't open with an empty password
+	PKCS12Passwords []string `yaml:"pkcs12_passwords"`
+}
+
+// CloudConfig holds the cloud providers `cloud sync` imports certificates from
+type CloudConfig struct {
+	AWS   AWSConfig   `yaml:"aws"`
+	GCP   GCPConfig   `yaml:"gcp"`
+	Azure AzureConfig `yaml:"azure"`
+	// SyncInterval is how often the daemon imports again for everyone who has synced before, zero leaves it to `cloud sync`
+	SyncInterval time.Duration `yaml:"sync_interval"`
+}
+
+// AWSConfig lists ACM certificates in Regions, and IAM server certificates when IAM is set
+type AWSConfig struct {
+	Regions []string `yaml:"regions"`
+	IAM     bool     `yaml:"iam"`
+	// Accounts are synced in turn, empty uses the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables
+	Accounts []AWSAccountConfig `yaml:"accounts"`
+}
+
+// Enabled reports whether there is anything to sync
+func (a AWSConfig) Enabled() bool {
+	return len(a.Regions) > 0 || a.IAM
+}
+
+// AWSAccountConfig holds the credentials of one account, empty keys come from the environment
+type AWSAccountConfig struct {
+	Name            string `yaml:"name"`
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+	SessionToken    string `yaml:"session_token"`
+	// RoleARN is assumed with the keys to reach another account, e.g. arn:aws:iam::123456789012:role/sslcerttop
+	RoleARN string `yaml:"role_arn"`
+}
+
+// GCPConfig lists the load balancer certificates of Projects
+type GCPConfig struct {
+	Projects []string `yaml:"projects"`
+	// CredentialsFile is a service account key, empty uses GOOGLE_APPLICATION_CREDENTIALS
+	CredentialsFile string `yaml:"credentials_file"`
+}
+
+// Enabled reports whether there is anything to sync
+func (g GCPConfig) Enabled() bool {
+	return len(g.Projects) > 0
+}
+
+// AzureConfig lists the certificates of Key Vaults as a service principal, empty credentials come from
+// AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_CLIENT_SECRET
+type AzureConfig struct {
+	// Vaults are vault names or URLs, e.g. shop or https://shop.vault.azure.net
+	Vaults       []string `yaml:"vaults"`
+	TenantID     string   `yaml:"tenant_id"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+}
+
+// Enabled reports whether there is anything to sync
+func (a AzureConfig) Enabled() bool {
+	return len(a.Vaults) > 0
+}
+
+// MQTTConfig holds the broker the daemon publishes every check result to
+type MQTTConfig struct {
+	// Broker is the broker URL, e.g. tcp://localhost:1883 or ssl://broker:8883, empty disables publishing
+	Broker string `yaml:"broker"`
+	// ClientID defaults to sslcerttop-<hostname>
+	ClientID string `yaml:"client_id"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// Topic is a template over .Domain, .DomainID and .Status, e.g. sslcerttop/{{.Status}}/{{.Domain}}
+	Topic  string        `yaml:"topic"`
+	QoS    int           `yaml:"qos"`
+	Retain bool          `yaml:"retain"`
+	TLS    MQTTTLSConfig `yaml:"tls"`
+}
+
+// Enabled reports whether results are published
+func (m MQTTConfig) Enabled() bool {
+	return m.Broker != ""
+}
+
+// MQTTTLSConfig holds the TLS settings of ssl://, tls://, mqtts:// and wss:// brokers
+type MQTTTLSConfig struct {
+	// CAFile verifies the broker instead of the system roots
+	CAFile string `yaml:"ca_file"`
+	// CertFile and KeyFile authenticate with a client certificate
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// EventsConfig holds where the daemon logs check results and status changes as JSON lines
+type EventsConfig struct {
+	// Output is stdout, stderr or syslog, empty disables event logging
+	Output string `yaml:"output"`
+	// SyslogAddress is a remote syslog server such as udp://logs.example.com:514, empty uses the local one
+	SyslogAddress string `yaml:"syslog_address"`
+	SyslogTag     string `yaml:"syslog_tag"`
+	// ChangesOnly logs status changes without the check results in between
+	ChangesOnly bool `yaml:"changes_only"`
+}
+
+// HookConfig is a command the daemon runs when a domain's status changes from one of From to one of To
+type HookConfig struct {
+	// Command runs through sh -c with the check in SSLCERTTOP_DOMAIN, SSLCERTTOP_STATUS,
+	// SSLCERTTOP_PREVIOUS_STATUS and the like, and as JSON on stdin
+	Command string `yaml:"command"`
+	// From and To are statuses, valid, soon, warning, expired, error or unknown, empty for any
+	From []string `yaml:"from"`
+	To   []string `yaml:"to"`
+	// Timeout stops the command when it runs longer, zero for a minute
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// PluginConfig is an external program adding a notification channel, a check type or both
+type PluginConfig struct {
+	// Name identifies the plugin, and is the notification channel it adds
+	Name string `yaml:"name"`
+	// Command is the program run for each request, with Args
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+	// Notify adds the plugin as a notification channel
+	Notify bool `yaml:"notify"`
+	// Prefix has the plugin check the names starting with it, e.g. vault:, empty for none
+	Prefix string `yaml:"prefix"`
+	// Timeout stops a request that runs longer, zero for 30 seconds
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// maxPluginName keeps plugin names within what the database stores as a notification channel
+const maxPluginName = 32
+
+// WhoisConfig holds how the daemon tracks when the registration of each domain expires
+type WhoisConfig struct {
+	// Interval is how often each registration is looked up, zero turns registration tracking off
+	Interval time.Duration `yaml:"interval"`
+	// RDAPServer is asked about every domain, empty asks the RDAP server of each domain's registry
+	RDAPServer string `yaml:"rdap_server"`
+	// Warning and Critical are the days before a registration expires that count as a warning and as critical
+	Warning  int `yaml:"warning"`
+	Critical int `yaml:"critical"`
+	// Notify are the days before a registration expires at which notifications are sent
+	Notify []int `yaml:"notify"`
+}
+
+// DNSConfig holds how the daemon checks the DNS records domains are expected to have
+type DNSConfig struct {
+	// Interval is how often the records are looked up, zero turns DNS checks off
+	Interval time.Duration `yaml:"interval"`
+	// Resolver is the DNS server asked, e.g. 1.1.1.1 or 127.0.0.53:53, empty uses the system's nameservers
+	Resolver string `yaml:"resolver"`
+	// VerifyIssuer warns when the CAA records of a hostname don't authorize the CA of the certificate it serves
+	VerifyIssuer bool `yaml:"verify_issuer"`
+}
+
+// PolicyConfig is a compliance policy, certificates breaking any of its limits violate it
+type PolicyConfig struct {
+	Name string `yaml:"name"`
+	// MaxLifetimeDays caps how long certificates are valid for, zero for no cap
+	MaxLifetimeDays int `yaml:"max_lifetime_days"`
+	// MinRSABits is the smallest RSA key allowed, zero for any
+	MinRSABits int `yaml:"min_rsa_bits"`
+	// ForbidSignatures are hash algorithms no certificate of a chain may be signed with, e.g. SHA1
+	ForbidSignatures []string `yaml:"forbid_signatures"`
+	// From is the date, e.g. 2027-03-15, the policy takes effect, empty when it already has
+	From string `yaml:"from"`
+}
+
+// hookStatuses are the statuses hooks can run on changes from and to
+var hookStatuses = []string{"valid", "soon", "warning", "expired", "error", "unknown"}
+
+// PolicyDateFormat is how PolicyConfig.From is written
+const PolicyDateFormat = "2006-01-02"
+
+// TrustConfig holds the root certificates chains are verified against
+type TrustConfig struct {
+	// Roots is system, mozilla for the snapshot of Mozilla's store built in, or the path of a PEM bundle
+	Roots string `yaml:"roots"`
+	// Compare is a second store of the same kinds, chains only one of them trusts are reported. Empty compares
+	// with none
+	Compare string `yaml:"compare"`
+}
+
+// APIConfig holds settings of the REST API server
+type APIConfig struct {
+	// SessionLifetime is how long a token from /api/v1/auth/login lasts before it has to be refreshed
+	SessionLifetime time.Duration `yaml:"session_lifetime"`
+	OIDC            OIDCConfig    `yaml:"oidc"`
+}
+
+// OIDCConfig signs users in through an OpenID Connect provider, an empty issuer disables it
+type OIDCConfig struct {
+	// Issuer is the provider's URL, e.g. https://keycloak.example.com/realms/ops or https://accounts.google.com
+	Issuer       string `yaml:"issuer"`
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+	// RedirectURL is this server's /api/v1/auth/oidc/callback as the provider reaches it
+	RedirectURL string   `yaml:"redirect_url"`
+	Scopes      []string `yaml:"scopes"`
+}
+
+// NotificationsConfig holds the credentials of each notification channel
+type NotificationsConfig struct {
+	Email   EmailConfig   `yaml:"email"`
+	Discord WebhookConfig `yaml:"discord"`
+	Slack   WebhookConfig `yaml:"slack"`
+	Teams   WebhookConfig `yaml:"teams"`
+	// Webhooks receive every notification as signed JSON
+	Webhooks []OutboundWebhookConfig `yaml:"webhooks"`
+	// PagerDuty and Opsgenie are paged about expired or failing certificates of domains with IncidentTags
+	PagerDuty    PagerDutyConfig `yaml:"pagerduty"`
+	Opsgenie     OpsgenieConfig  `yaml:"opsgenie"`
+	IncidentTags []string        `yaml:"incident_tags"`
+	// ReminderInterval repeats a notification while its threshold is unchanged, zero notifies once per threshold
+	ReminderInterval time.Duration    `yaml:"reminder_interval"`
+	QuietHours       QuietHoursConfig `yaml:"quiet_hours"`
+	// Escalations re-send unacknowledged notifications over another channel
+	Escalations []EscalationConfig `yaml:"escalations"`
+	// TemplatesDir holds <channel>.tmpl message templates, empty uses templates in the config directory
+	TemplatesDir string `yaml:"templates_dir"`
+	// DashboardURL is linked from templates as .DashboardURL
+	DashboardURL string       `yaml:"dashboard_url"`
+	Digest       DigestConfig `yaml:"digest"`
+	Retry        RetryConfig  `yaml:"retry"`
+	// RateLimits cap how many notifications each channel sends, e.g. slack: 1/s or email: 100/h. An empty limit
+	// lifts a default one
+	RateLimits map[string]string `yaml:"rate_limits"`
+}
+
+// RetryConfig retries notifications that failed transiently, waiting Backoff before the first retry
+// and twice as long before each one after it
+type RetryConfig struct {
+	// MaxAttempts includes the first delivery, one disables retries
+	MaxAttempts int           `yaml:"max_attempts"`
+	Backoff     time.Duration `yaml:"backoff"`
+}
+
+// DigestConfig mails a summary of expiring, failing and renewed certificates on a cron schedule,
+// e.g. @daily or "0 8 * * 1". An empty schedule disables it
+type DigestConfig struct {
+	Schedule string `yaml:"schedule"`
+	// Days is how far ahead the digest lists expiring certificates
+	Days int `yaml:"days"`
+}
+
+// QuietHoursConfig is a daily window, in the daemon's local time, without non-critical notifications.
+// An empty start disables it
+type QuietHoursConfig struct {
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+	// CriticalDays is the threshold at or below which notifications are sent during quiet hours anyway
+	CriticalDays int `yaml:"critical_days"`
+}
+
+// EscalationConfig re-sends a notification at Threshold over Channel when nobody acknowledged it After it was sent
+type EscalationConfig struct {
+	Threshold int           `yaml:"threshold"`
+	After     time.Duration `yaml:"after"`
+	Channel   string        `yaml:"channel"`
+}
+
+// EmailConfig is the SMTP server notifications are mailed through, an empty host disables email
+type EmailConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// Security is starttls, tls for implicit TLS (usually port 465) or none
+	Security string   `yaml:"security"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+type WebhookConfig struct {
+	WebhookURL string `yaml:"webhook_url"`
+}
+
+type PagerDutyConfig struct {
+	RoutingKey string `yaml:"routing_key"`
+}
+
+type OpsgenieConfig struct {
+	APIKey string `yaml:"api_key"`
+	// APIURL selects the region, https://api.eu.opsgenie.com for EU accounts
+	APIURL string `yaml:"api_url"`
+}
+
+// OutboundWebhookConfig is an endpoint of the generic webhook channel, payloads are signed when Secret is set
+type OutboundWebhookConfig struct {
+	URL    string `yaml:"url"`
+	Secret string `yaml:"secret"`
+}
+
+// ThemeConfig overrides TUI colours, empty values keep the built in theme
+type ThemeConfig struct {
+	Accent             string `yaml:"accent"`
+	Highlight          string `yaml:"highlight"`
+	Text               string `yaml:"text"`
+	Subtle             string `yaml:"subtle"`
+	Muted              string `yaml:"muted"`
+	Error              string `yaml:"error"`
+	Warning            string `yaml:"warning"`
+	Border             string `yaml:"border"`
+	SelectedText       string `yaml:"selected_text"`
+	SelectedBackground string `yaml:"selected_background"`
+}
+
+// Default returns the settings used when nothing is configured
+func Default() *Config {
+	return &Config{
+		Database:     DatabaseConfig{Driver: DriverSQLite},
+		Workers:      20,
+		QueueSize:    100,
+		CheckTimeout: 10 * time.Second,
+		Thresholds: ThresholdsConfig{
+			Warning:  30,
+			Critical: 7,
+			Notify:   []int{30, 7, 1, 0},
+		},
+		Notifications: NotificationsConfig{
+			Email:        EmailConfig{Port: 587, Security: "starttls"},
+			IncidentTags: []string{"prod"},
+			QuietHours:   QuietHoursConfig{CriticalDays: 1},
+			Digest:       DigestConfig{Days: 30},
+			Retry:        RetryConfig{MaxAttempts: 5, Backoff: time.Minute},
+			RateLimits:   map[string]string{"slack": "1/s", "discord": "30/m", "teams": "4/s"},
+		},
+		Retention: RetentionConfig{CheckHistoryDays: 90},
+		Renewal:   RenewalConfig{Threshold: 30, Timeout: 5 * time.Minute, Retry: 24 * time.Hour},
+		Cloud:     CloudConfig{SyncInterval: 6 * time.Hour},
+		MQTT:      MQTTConfig{Topic: "sslcerttop/{{.Domain}}"},
+		Whois:     WhoisConfig{Warning: 60, Critical: 14, Notify: []int{60, 30, 7, 0}},
+		DNS:       DNSConfig{Interval: time.Hour, VerifyIssuer: true},
+		Trust:     TrustConfig{Roots: "system", Compare: "mozilla"},
+		Compliance: []PolicyConfig{
+			{Name: "max-lifetime", MaxLifetimeDays: 398},
+			{Name: "no-sha1", ForbidSignatures: []string{"SHA1", "MD5"}},
+		},
+		API: APIConfig{SessionLifetime: time.Hour},
+	}
+}
+
+// DefaultPath returns the config file location, $SSLCERTTOP_CONFIG or config.yaml in the config directory
+func DefaultPath() (string, error) {
+	if path := os.Getenv("SSLCERTTOP_CONFIG"); path != "" {
+		return path, nil
+	}
+	configDir, err := database.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, FileName), nil
+}
+
+// Load reads the config file at the default path and applies environment overrides
+func Load() (*Config, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config path: %w", err)
+	}
+	return LoadFile(path, os.LookupEnv)
+}
+
+// LoadFile reads the config file at path on top of the defaults, then applies overrides from lookupEnv.
+//
+// A missing file is not an error, the defaults are used instead, and neither is one holding only comments.
+//
+// Returns the validated config or an error if the file or an override is invalid
+func LoadFile(path string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if len(data) > 0 {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		// A file of comments alone, e.g. a commented out template, has no document to decode
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(lookupEnv); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, nil
+}
+
+// applyEnv overrides settings from SSLCERTTOP_* environment variables
+func (c *Config) applyEnv(lookupEnv func(string) (string, bool)) error {
+	overrides := []struct {
+		name string
+		set  func(string) error
+	}{
+		{"SSLCERTTOP_DB", setString(&c.Database.Path)},
+		{"SSLCERTTOP_DB_DRIVER", setString(&c.Database.Driver)},
+		{"SSLCERTTOP_DB_DSN", setString(&c.Database.DSN)},
+		{"SSLCERTTOP_WORKERS", setInt(&c.Workers)},
+		{"SSLCERTTOP_QUEUE_SIZE", setInt(&c.QueueSize)},
+		{"SSLCERTTOP_CHECK_TIMEOUT", setDuration(&c.CheckTimeout)},
+		{"SSLCERTTOP_WARN_DAYS", setInt(&c.Thresholds.Warning)},
+		{"SSLCERTTOP_CRIT_DAYS", setInt(&c.Thresholds.Critical)},
+		{"SSLCERTTOP_NOTIFY_DAYS", setInts(&c.Thresholds.Notify)},
+		{"SSLCERTTOP_RETENTION_DAYS", setInt(&c.Retention.CheckHistoryDays)},
+		{"SSLCERTTOP_RENEWAL_THRESHOLD", setInt(&c.Renewal.Threshold)},
+		{"SSLCERTTOP_RENEWAL_COMMAND", setString(&c.Renewal.Command)},
+		{"SSLCERTTOP_RENEWAL_WEBHOOK_URL", setString(&c.Renewal.WebhookURL)},
+		{"SSLCERTTOP_CLOUD_SYNC_INTERVAL", setDuration(&c.Cloud.SyncInterval)},
+		{"SSLCERTTOP_MQTT_BROKER", setString(&c.MQTT.Broker)},
+		{"SSLCERTTOP_MQTT_USERNAME", setString(&c.MQTT.Username)},
+		{"SSLCERTTOP_MQTT_PASSWORD", setString(&c.MQTT.Password)},
+		{"SSLCERTTOP_MQTT_TOPIC", setString(&c.MQTT.Topic)},
+		{"SSLCERTTOP_EVENTS_OUTPUT", setString(&c.Events.Output)},
+		{"SSLCERTTOP_EVENTS_SYSLOG_ADDRESS", setString(&c.Events.SyslogAddress)},
+		{"SSLCERTTOP_WHOIS_INTERVAL", setDuration(&c.Whois.Interval)},
+		{"SSLCERTTOP_DNS_INTERVAL", setDuration(&c.DNS.Interval)},
+		{"SSLCERTTOP_DNS_RESOLVER", setString(&c.DNS.Resolver)},
+		{"SSLCERTTOP_TRUST_ROOTS", setString(&c.Trust.Roots)},
+		{"SSLCERTTOP_TRUST_COMPARE", setString(&c.Trust.Compare)},
+		{"SSLCERTTOP_SESSION_LIFETIME", setDuration(&c.API.SessionLifetime)},
+		{"SSLCERTTOP_OIDC_ISSUER", setString(&c.API.OIDC.Issuer)},
+		{"SSLCERTTOP_OIDC_CLIENT_ID", setString(&c.API.OIDC.ClientID)},
+		{"SSLCERTTOP_OIDC_CLIENT_SECRET", setString(&c.API.OIDC.ClientSecret)},
+		{"SSLCERTTOP_OIDC_REDIRECT_URL", setString(&c.API.OIDC.RedirectURL)},
+		{"SSLCERTTOP_SMTP_HOST", setString(&c.Notifications.Email.Host)},
+		{"SSLCERTTOP_SMTP_PORT", setInt(&c.Notifications.Email.Port)},
+		{"SSLCERTTOP_SMTP_USERNAME", setString(&c.Notifications.Email.Username)},
+		{"SSLCERTTOP_SMTP_PASSWORD", setString(&c.Notifications.Email.Password)},
+		{"SSLCERTTOP_SMTP_SECURITY", setString(&c.Notifications.Email.Security)},
+		{"SSLCERTTOP_EMAIL_FROM", setString(&c.Notifications.Email.From)},
+		{"SSLCERTTOP_EMAIL_TO", setStrings(&c.Notifications.Email.To)},
+		{"SSLCERTTOP_DISCORD_WEBHOOK_URL", setString(&c.Notifications.Discord.WebhookURL)},
+		{"SSLCERTTOP_SLACK_WEBHOOK_URL", setString(&c.Notifications.Slack.WebhookURL)},
+		{"SSLCERTTOP_TEAMS_WEBHOOK_URL", setString(&c.Notifications.Teams.WebhookURL)},
+		{"SSLCERTTOP_PAGERDUTY_ROUTING_KEY", setString(&c.Notifications.PagerDuty.RoutingKey)},
+		{"SSLCERTTOP_OPSGENIE_API_KEY", setString(&c.Notifications.Opsgenie.APIKey)},
+		{"SSLCERTTOP_INCIDENT_TAGS", setStrings(&c.Notifications.IncidentTags)},
+		{"SSLCERTTOP_REMINDER_INTERVAL", setDuration(&c.Notifications.ReminderInterval)},
+		{"SSLCERTTOP_TEMPLATES_DIR", setString(&c.Notifications.TemplatesDir)},
+		{"SSLCERTTOP_DASHBOARD_URL", setString(&c.Notifications.DashboardURL)},
+		{"SSLCERTTOP_DIGEST_SCHEDULE", setString(&c.Notifications.Digest.Schedule)},
+	}
+
+	for _, o := range overrides {
+		value, ok := lookupEnv(o.name)
+		if !ok {
+			continue
+		}
+		if err := o.set(value); err != nil {
+			return fmt.Errorf("invalid %s: %w", o.name, err)
+		}
+	}
+	return nil
+}
+
+// Validate reports settings that can't work
+func (c *Config) Validate() error {
+	switch c.Database.Driver {
+	case DriverSQLite:
+	case DriverMySQL:
+		if c.Database.DSN == "" {
+			return errors.New("database.dsn is required for the mysql driver")
+		}
+	default:
+		return fmt.Errorf("unknown database.driver %q, expected sqlite or mysql", c.Database.Driver)
+	}
+	if c.Workers < 1 {
+		return fmt.Errorf("workers must be at least 1, got %d", c.Workers)
+	}
+	if c.QueueSize < 1 {
+		return fmt.Errorf("queue_size must be at least 1, got %d", c.QueueSize)
+	}
+	if c.CheckTimeout <= 0 {
+		return fmt.Errorf("check_timeout must be positive, got %s", c.CheckTimeout)
+	}
+	if c.Thresholds.Critical < 0 || c.Thresholds.Critical > c.Thresholds.Warning {
+		return fmt.Errorf("thresholds.critical (%d) must be between 0 and thresholds.warning (%d)", c.Thresholds.Critical, c.Thresholds.Warning)
+	}
+	if c.Retention.CheckHistoryDays < 0 {
+		return fmt.Errorf("retention.check_history_days must not be negative, got %d", c.Retention.CheckHistoryDays)
+	}
+	if r := c.Renewal; r.Enabled() {
+		if r.Threshold < 0 {
+			return fmt.Errorf("renewal.threshold must not be negative, got %d", r.Threshold)
+		}
+		if r.Timeout <= 0 {
+			return fmt.Errorf("renewal.timeout must be positive, got %s", r.Timeout)
+		}
+		if r.Retry <= 0 {
+			return fmt.Errorf("renewal.retry must be positive, got %s", r.Retry)
+		}
+	}
+	for i, a := range c.Cloud.AWS.Accounts {
+		if (a.AccessKeyID == "") != (a.SecretAccessKey == "") {
+			return fmt.Errorf("cloud.aws.accounts[%d] needs both an access_key_id and a secret_access_key", i)
+		}
+	}
+	if c.Cloud.SyncInterval < 0 {
+		return fmt.Errorf("cloud.sync_interval must not be negative, got %s", c.Cloud.SyncInterval)
+	}
+	if m := c.MQTT; m.Enabled() {
+		scheme, _, _ := strings.Cut(m.Broker, "://")
+		switch scheme {
+		case "tcp", "mqtt", "ssl", "tls", "mqtts", "ws", "wss":
+		default:
+			return fmt.Errorf("mqtt.broker %q must be a tcp://, ssl://, mqtt://, mqtts://, ws:// or wss:// URL", m.Broker)
+		}
+		if m.QoS < 0 || m.QoS > 2 {
+			return fmt.Errorf("mqtt.qos must be 0, 1 or 2, got %d", m.QoS)
+		}
+		if _, err := template.New("topic").Parse(m.Topic); err != nil || m.Topic == "" {
+			return fmt.Errorf("mqtt.topic %q is not a valid template", m.Topic)
+		}
+		if (m.TLS.CertFile == "") != (m.TLS.KeyFile == "") {
+			return errors.New("mqtt.tls needs both a cert_file and a key_file")
+		}
+	}
+	switch c.Events.Output {
+	case "", "stdout", "stderr", "syslog":
+	default:
+		return fmt.Errorf("unknown events.output %q, expected stdout, stderr or syslog", c.Events.Output)
+	}
+	if a := c.Events.SyslogAddress; a != "" {
+		network, _, _ := strings.Cut(a, "://")
+		switch network {
+		case "udp", "tcp", "unix", "unixgram":
+		default:
+			return fmt.Errorf("events.syslog_address %q must be a udp://, tcp:// or unix:// address", a)
+		}
+	}
+	for i, h := range c.Hooks {
+		if strings.TrimSpace(h.Command) == "" {
+			return fmt.Errorf("hooks[%d].command is required", i)
+		}
+		if h.Timeout < 0 {
+			return fmt.Errorf("hooks[%d].timeout must not be negative, got %s", i, h.Timeout)
+		}
+		for _, status := range slices.Concat(h.From, h.To) {
+			if !slices.Contains(hookStatuses, status) {
+				return fmt.Errorf("hooks[%d]: unknown status %q, expected %s", i, status, strings.Join(hookStatuses, ", "))
+			}
+		}
+	}
+	plugins := map[string]bool{}
+	for i, p := range c.Plugins {
+		if p.Name == "" || len(p.Name) > maxPluginName {
+			return fmt.Errorf("plugins[%d].name is required, up to %d characters", i, maxPluginName)
+		}
+		if plugins[p.Name] {
+			return fmt.Errorf("plugin %s is defined twice", p.Name)
+		}
+		plugins[p.Name] = true
+		if p.Command == "" {
+			return fmt.Errorf("plugin %s needs a command", p.Name)
+		}
+		if !p.Notify && p.Prefix == "" {
+			return fmt.Errorf("plugin %s needs notify or a prefix", p.Name)
+		}
+		if p.Timeout < 0 {
+			return fmt.Errorf("plugin %s: timeout must not be negative, got %s", p.Name, p.Timeout)
+		}
+	}
+	if w := c.Whois; w.Interval < 0 {
+		return fmt.Errorf("whois.interval must not be negative, got %s", w.Interval)
+	}
+	if s := c.Whois.RDAPServer; s != "" {
+		u, err := url.Parse(s)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("whois.rdap_server %q must be an http or https URL", s)
+		}
+	}
+	if w := c.Whois; w.Critical < 0 || w.Critical > w.Warning {
+		return fmt.Errorf("whois.critical (%d) must be between 0 and whois.warning (%d)", w.Critical, w.Warning)
+	}
+	for _, days := range c.Whois.Notify {
+		if days < 0 {
+			return fmt.Errorf("whois.notify must not be negative, got %d", days)
+		}
+	}
+	if c.DNS.Interval < 0 {
+		return fmt.Errorf("dns.interval must not be negative, got %s", c.DNS.Interval)
+	}
+	if c.Trust.Roots == "" {
+		return errors.New("trust.roots is required, system, mozilla or the path of a PEM bundle")
+	}
+	if c.Trust.Compare == c.Trust.Roots {
+		return fmt.Errorf("trust.compare must differ from trust.roots (%s), leave it empty to compare with none", c.Trust.Roots)
+	}
+	policies := map[string]bool{}
+	for i, p := range c.Compliance {
+		if p.Name == "" {
+			return fmt.Errorf("compliance[%d].name is required", i)
+		}
+		if policies[p.Name] {
+			return fmt.Errorf("compliance policy %s is defined twice", p.Name)
+		}
+		policies[p.Name] = true
+		if p.From != "" {
+			if _, err := time.Parse(PolicyDateFormat, p.From); err != nil {
+				return fmt.Errorf("compliance policy %s: from must be a date like 2027-03-15, got %q", p.Name, p.From)
+			}
+		}
+	}
+	if c.API.SessionLifetime <= 0 {
+		return fmt.Errorf("api.session_lifetime must be positive, got %s", c.API.SessionLifetime)
+	}
+	if o := c.API.OIDC; o.Issuer != "" && (o.ClientID == "" || o.RedirectURL == "") {
+		return errors.New("api.oidc needs a client_id and a redirect_url")
+	}
+	for _, days := range c.Thresholds.Notify {
+		if days < 0 {
+			return fmt.Errorf("thresholds.notify must not be negative, got %d", days)
+		}
+	}
+	if c.Notifications.ReminderInterval < 0 {
+		return fmt.Errorf("notifications.reminder_interval must not be negative, got %s", c.Notifications.ReminderInterval)
+	}
+	if q := c.Notifications.QuietHours; (q.Start == "") != (q.End == "") {
+		return errors.New("notifications.quiet_hours needs both a start and an end")
+	}
+	for i, e := range c.Notifications.Escalations {
+		if e.Channel == "" {
+			return fmt.Errorf("notifications.escalations[%d].channel is required", i)
+		}
+		if e.After <= 0 {
+			return fmt.Errorf("notifications.escalations[%d].after must be positive, got %s", i, e.After)
+		}
+	}
+	if c.Notifications.Retry.MaxAttempts < 1 {
+		return fmt.Errorf("notifications.retry.max_attempts must be at least 1, got %d", c.Notifications.Retry.MaxAttempts)
+	}
+	if c.Notifications.Retry.Backoff <= 0 {
+		return fmt.Errorf("notifications.retry.backoff must be positive, got %s", c.Notifications.Retry.Backoff)
+	}
+	if d := c.Notifications.Digest; d.Schedule != "" {
+		if _, err := cron.Parse(d.Schedule); err != nil {
+			return fmt.Errorf("notifications.digest.schedule: %w", err)
+		}
+		if d.Days < 1 {
+			return fmt.Errorf("notifications.digest.days must be at least 1, got %d", d.Days)
+		}
+		if c.Notifications.Email.Host == "" {
+			return errors.New("notifications.digest needs notifications.email to be set up")
+		}
+	}
+	for i, w := range c.Notifications.Webhooks {
+		if w.URL == "" {
+			return fmt.Errorf("notifications.webhooks[%d].url is required", i)
+		}
+	}
+	switch c.Notifications.Email.Security {
+	case "starttls", "tls", "none":
+	default:
+		return fmt.Errorf("unknown notifications.email.security %q, expected starttls, tls or none", c.Notifications.Email.Security)
+	}
+	return nil
+}
+
+func setString(dst *string) func(string) error {
+	return func(value string) error {
+		*dst = value
+		return nil
+	}
+}
+
+func setInt(dst *int) func(string) error {
+	return func(value string) error {
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return err
+		}
+		*dst = n
+		return nil
+	}
+}
+
+func setDuration(dst *time.Duration) func(string) error {
+	return func(value string) error {
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return err
+		}
+		*dst = d
+		return nil
+	}
+}
+
+// setStrings splits a comma separated list
+func setStrings(dst *[]string) func(string) error {
+	return func(value string) error {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*dst = items
+		return nil
+	}
+}
+
+// setInts splits a comma separated list of numbers
+func setInts(dst *[]int) func(string) error {
+	return func(value string) error {
+		var items []string
+		if err := setStrings(&items)(value); err != nil {
+			return err
+		}
+		numbers := make([]int, len(items))
+		for i, item := range items {
+			n, err := strconv.Atoi(item)
+			if err != nil {
+				return err
+			}
+			numbers[i] = n
+		}
+		*dst = numbers
+		return nil
+	}
+}
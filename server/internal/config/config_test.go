@@ -0,0 +1,186 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func noEnv(string) (string, bool) {
+	return "", false
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), FileName)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+// TestLoadFile_Missing - a missing file gives the defaults.
+func TestLoadFile_Missing(t *testing.T) {
+	cfg, err := LoadFile(filepath.Join(t.TempDir(), FileName), noEnv)
+	require.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+}
+
+// TestLoadFile_CommentsOnly - a file of nothing but comments gives the defaults.
+func TestLoadFile_CommentsOnly(t *testing.T) {
+	path := writeConfig(t, `# workers: 50
+# database:
+#   path: /var/lib/sslcerttop/data.db
+`)
+	cfg, err := LoadFile(path, noEnv)
+	require.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+}
+
+// TestLoadFile - file values replace defaults and unset ones are kept.
+func TestLoadFile(t *testing.T) {
+	path := writeConfig(t, `
+database:
+  path: /var/lib/sslcerttop/data.db
+workers: 5
+check_timeout: 3s
+thresholds:
+  notify: [14, 3]
+notifications:
+  email:
+    host: smtp.example.com
+    to: [ops@example.com]
+  slack:
+    webhook_url: https://hooks.slack.com/services/x
+  webhooks:
+    - url: https://hooks.example.com/certs
+      secret: s3cret
+  digest:
+    schedule: "@weekly"
+  retry:
+    max_attempts: 3
+theme:
+  accent: "#ff00ff"
+`)
+
+	cfg, err := LoadFile(path, noEnv)
+	require.NoError(t, err)
+	assert.Equal(t, "/var/lib/sslcerttop/data.db", cfg.Database.Path)
+	assert.Equal(t, 5, cfg.Workers)
+	assert.Equal(t, 3*time.Second, cfg.CheckTimeout)
+	assert.Equal(t, []int{14, 3}, cfg.Thresholds.Notify)
+	assert.Equal(t, 30, cfg.Thresholds.Warning)
+	assert.Equal(t, "smtp.example.com", cfg.Notifications.Email.Host)
+	assert.Equal(t, 587, cfg.Notifications.Email.Port)
+	assert.Equal(t, "starttls", cfg.Notifications.Email.Security)
+	assert.Equal(t, []string{"ops@example.com"}, cfg.Notifications.Email.To)
+	assert.Equal(t, "https://hooks.slack.com/services/x", cfg.Notifications.Slack.WebhookURL)
+	assert.Equal(t, []OutboundWebhookConfig{{URL: "https://hooks.example.com/certs", Secret: "s3cret"}}, cfg.Notifications.Webhooks)
+	assert.Equal(t, DigestConfig{Schedule: "@weekly", Days: 30}, cfg.Notifications.Digest)
+	assert.Equal(t, RetryConfig{MaxAttempts: 3, Backoff: time.Minute}, cfg.Notifications.Retry)
+	assert.Equal(t, "#ff00ff", cfg.Theme.Accent)
+}
+
+// TestLoadFile_Env - environment variables win over the file.
+func TestLoadFile_Env(t *testing.T) {
+	path := writeConfig(t, "workers: 5\n")
+	env := map[string]string{
+		"SSLCERTTOP_WORKERS":           "8",
+		"SSLCERTTOP_DB":                "/tmp/other.db",
+		"SSLCERTTOP_CHECK_TIMEOUT":     "30s",
+		"SSLCERTTOP_NOTIFY_DAYS":       "21, 7",
+		"SSLCERTTOP_EMAIL_TO":          "a@example.com,b@example.com",
+		"SSLCERTTOP_SMTP_PASSWORD":     "secret",
+		"SSLCERTTOP_REMINDER_INTERVAL": "72h",
+		"SSLCERTTOP_SESSION_LIFETIME":  "15m",
+		"SSLCERTTOP_RENEWAL_COMMAND":   "certbot renew",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	cfg, err := LoadFile(path, lookup)
+	require.NoError(t, err)
+	assert.Equal(t, 8, cfg.Workers)
+	assert.Equal(t, "/tmp/other.db", cfg.Database.Path)
+	assert.Equal(t, 30*time.Second, cfg.CheckTimeout)
+	assert.Equal(t, []int{21, 7}, cfg.Thresholds.Notify)
+	assert.Equal(t, []string{"a@example.com", "b@example.com"}, cfg.Notifications.Email.To)
+	assert.Equal(t, "secret", cfg.Notifications.Email.Password)
+	assert.Equal(t, 72*time.Hour, cfg.Notifications.ReminderInterval)
+	assert.Equal(t, 15*time.Minute, cfg.API.SessionLifetime)
+	assert.Equal(t, "certbot renew", cfg.Renewal.Command)
+	assert.True(t, cfg.Renewal.Enabled())
+
+	env = map[string]string{"SSLCERTTOP_WORKERS": "many"}
+	_, err = LoadFile(path, lookup)
+	assert.ErrorContains(t, err, "SSLCERTTOP_WORKERS")
+}
+
+// TestLoadFile_Invalid - typos and impossible values are rejected.
+func TestLoadFile_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"unknown field", "worker: 5\n"},
+		{"bad duration", "check_timeout: soon\n"},
+		{"no workers", "workers: 0\n"},
+		{"critical above warning", "thresholds:\n  warning: 7\n  critical: 30\n"},
+		{"negative notify", "thresholds:\n  notify: [-1]\n"},
+		{"negative retention", "retention:\n  check_history_days: -1\n"},
+		{"webhook without url", "notifications:\n  webhooks:\n    - secret: s3cret\n"},
+		{"unknown email security", "notifications:\n  email:\n    security: ssl\n"},
+		{"negative reminder interval", "notifications:\n  reminder_interval: -1h\n"},
+		{"quiet hours without end", "notifications:\n  quiet_hours:\n    start: \"22:00\"\n"},
+		{"escalation without delay", "notifications:\n  escalations:\n    - {threshold: 7, channel: pagerduty}\n"},
+		{"aws key without secret", "cloud:\n  aws:\n    accounts:\n      - access_key_id: AKIAEXAMPLE\n"},
+		{"bad digest schedule", "notifications:\n  email: {host: smtp.example.com}\n  digest: {schedule: \"every day\"}\n"},
+		{"digest without email", "notifications:\n  digest: {schedule: \"@daily\"}\n"},
+		{"no delivery attempts", "notifications:\n  retry: {max_attempts: 0}\n"},
+		{"zero retry backoff", "notifications:\n  retry: {backoff: 0s}\n"},
+		{"zero session lifetime", "api:\n  session_lifetime: 0s\n"},
+		{"zero renewal timeout", "renewal:\n  command: certbot renew\n  timeout: 0s\n"},
+		{"oidc without client", "api:\n  oidc: {issuer: \"https://accounts.google.com\"}\n"},
+		{"mqtt broker without scheme", "mqtt:\n  broker: localhost:1883\n"},
+		{"mqtt qos out of range", "mqtt:\n  broker: tcp://localhost:1883\n  qos: 3\n"},
+		{"bad mqtt topic", "mqtt:\n  broker: tcp://localhost:1883\n  topic: \"certs/{{.Domain\"\n"},
+		{"unknown events output", "events:\n  output: journald\n"},
+		{"syslog address without network", "events:\n  output: syslog\n  syslog_address: logs.example.com:514\n"},
+		{"mqtt cert without key", "mqtt:\n  broker: ssl://localhost:8883\n  tls: {cert_file: client.pem}\n"},
+		{"negative whois interval", "whois:\n  interval: -1h\n"},
+		{"whois critical above warning", "whois:\n  warning: 30\n  critical: 60\n"},
+		{"whois rdap server without scheme", "whois:\n  rdap_server: rdap.example.com\n"},
+		{"negative dns interval", "dns:\n  interval: -1h\n"},
+		{"compare with the same roots", "trust:\n  roots: mozilla\n  compare: mozilla\n"},
+		{"duplicate compliance policy", "compliance:\n  - {name: sha1, forbid_signatures: [SHA1]}\n  - {name: sha1, min_rsa_bits: 2048}\n"},
+		{"bad compliance date", "compliance:\n  - {name: short, max_lifetime_days: 100, from: 15/03/2027}\n"},
+		{"hook without a command", "hooks:\n  - {to: [expired]}\n"},
+		{"hook on an unknown status", "hooks:\n  - {command: echo, from: [ok]}\n"},
+		{"duplicate plugin", "plugins:\n  - {name: matrix, command: /bin/true, notify: true}\n  - {name: matrix, command: /bin/false, prefix: \"vault:\"}\n"},
+		{"plugin that does nothing", "plugins:\n  - {name: matrix, command: /bin/true}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadFile(writeConfig(t, tt.content), noEnv)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestLoadFile_Driver - mysql needs a DSN and unknown drivers are rejected.
+func TestLoadFile_Driver(t *testing.T) {
+	_, err := LoadFile(writeConfig(t, "database:\n  driver: mysql\n"), noEnv)
+	assert.ErrorContains(t, err, "dsn")
+
+	_, err = LoadFile(writeConfig(t, "database:\n  driver: postgres\n"), noEnv)
+	assert.ErrorContains(t, err, "postgres")
+
+	cfg, err := LoadFile(writeConfig(t, "database:\n  driver: mysql\n  dsn: u:p@tcp(db:3306)/sslcerttop\n"), noEnv)
+	require.NoError(t, err)
+	assert.Equal(t, DriverMySQL, cfg.Database.Driver)
+}
@@ -0,0 +1,113 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParse_Valid - these expressions should parse.
+func TestParse_Valid(t *testing.T) {
+	valid := []string{
+		"* * * * *",
+		"0 3 * * *",
+		"*/15 * * * *",
+		"0 9-17 * * 1-5",
+		"0 0 1,15 * *",
+		"30 2 * * 7",
+		"@daily",
+		"@hourly",
+	}
+
+	for _, expr := range valid {
+		t.Run(expr, func(t *testing.T) {
+			s, err := Parse(expr)
+			require.NoError(t, err)
+			assert.Equal(t, expr, s.String())
+		})
+	}
+}
+
+// TestParse_Invalid - these should fail with ErrInvalidExpression.
+func TestParse_Invalid(t *testing.T) {
+	invalid := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@sometimes",
+	}
+
+	for _, expr := range invalid {
+		t.Run(expr, func(t *testing.T) {
+			_, err := Parse(expr)
+			assert.ErrorIs(t, err, ErrInvalidExpression)
+		})
+	}
+}
+
+// TestSchedule_Next - spot checks of the next run time.
+func TestSchedule_Next(t *testing.T) {
+	base := time.Date(2025, time.March, 14, 10, 30, 0, 0, time.UTC) // Friday
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, time.March, 14, 10, 31, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2025, time.March, 15, 3, 0, 0, 0, time.UTC)},
+		{"*/20 * * * *", time.Date(2025, time.March, 14, 10, 40, 0, 0, time.UTC)},
+		{"0 9 * * 1", time.Date(2025, time.March, 17, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2025, time.March, 16, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			s, err := Parse(tc.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, s.Next(base))
+		})
+	}
+}
+
+// TestSchedule_DayOfMonthOrWeek - restricting both days matches either one.
+func TestSchedule_DayOfMonthOrWeek(t *testing.T) {
+	s, err := Parse("0 0 20 * 1") // the 20th or any Monday
+	require.NoError(t, err)
+
+	base := time.Date(2025, time.March, 14, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, time.March, 17, 0, 0, 0, 0, time.UTC), s.Next(base))
+}
+
+// TestSchedule_DueSince - due once a scheduled time has passed.
+func TestSchedule_DueSince(t *testing.T) {
+	s, err := Parse("0 3 * * *")
+	require.NoError(t, err)
+
+	last := time.Date(2025, time.March, 14, 2, 0, 0, 0, time.UTC)
+	assert.False(t, s.DueSince(last, last.Add(30*time.Minute)))
+	assert.True(t, s.DueSince(last, last.Add(2*time.Hour)))
+}
+
+// FuzzParse - random expressions shouldn't crash the parser.
+func FuzzParse(f *testing.F) {
+	f.Add("* * * * *")
+	f.Add("*/5 1-3,7 * * MON")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, expr string) {
+		s, err := Parse(expr)
+		if err == nil {
+			_ = s.Next(time.Now())
+		}
+	})
+}
@@ -0,0 +1,80 @@
+// This package provides the process management pieces of daemon mode
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrAlreadyRunning occurs when another live daemon holds the PID file
+var ErrAlreadyRunning = errors.New("daemon already running")
+
+// PIDFile is an acquired lock file containing the daemon's process ID
+type PIDFile struct {
+	path string
+}
+
+// AcquirePIDFile creates the PID file at path, failing if another live process owns it.
+//
+// A PID file left behind by a process that no longer exists is treated as stale and replaced
+func AcquirePIDFile(path string) (*PIDFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create PID file directory: %w", err)
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, writeErr := fmt.Fprintf(f, "%d\n", os.Getpid())
+			closeErr := f.Close()
+			if writeErr != nil || closeErr != nil {
+				os.Remove(path)
+				return nil, fmt.Errorf("failed to write PID file: %w", errors.Join(writeErr, closeErr))
+			}
+			return &PIDFile{path: path}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create PID file: %w", err)
+		}
+
+		pid, readErr := ReadPID(path)
+		if readErr == nil && processExists(pid) {
+			return nil, fmt.Errorf("%w (pid %d)", ErrAlreadyRunning, pid)
+		}
+		// Stale PID file, remove it and try again
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to remove stale PID file: %w", err)
+		}
+	}
+	return nil, fmt.Errorf("failed to acquire PID file %s", path)
+}
+
+// ReadPID returns the process ID stored in a PID file
+func ReadPID(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid PID file contents: %w", err)
+	}
+	return pid, nil
+}
+
+// Path returns the location of the PID file
+func (p *PIDFile) Path() string {
+	return p.path
+}
+
+// Release removes the PID file
+func (p *PIDFile) Release() error {
+	if err := os.Remove(p.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAcquirePIDFile - writes our PID and removes the file on release.
+func TestAcquirePIDFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "sslcerttop.pid")
+
+	pidFile, err := AcquirePIDFile(path)
+	require.NoError(t, err)
+
+	pid, err := ReadPID(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), pid)
+
+	require.NoError(t, pidFile.Release())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+// TestAcquirePIDFile_AlreadyRunning - a live process keeps the lock.
+func TestAcquirePIDFile_AlreadyRunning(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sslcerttop.pid")
+
+	pidFile, err := AcquirePIDFile(path)
+	require.NoError(t, err)
+	defer pidFile.Release()
+
+	_, err = AcquirePIDFile(path)
+	assert.ErrorIs(t, err, ErrAlreadyRunning)
+}
+
+// TestAcquirePIDFile_Stale - a PID file from a dead process is replaced.
+func TestAcquirePIDFile_Stale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sslcerttop.pid")
+	require.NoError(t, os.WriteFile(path, []byte("garbage\n"), 0644))
+
+	pidFile, err := AcquirePIDFile(path)
+	require.NoError(t, err)
+	defer pidFile.Release()
+
+	pid, err := ReadPID(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), pid)
+}
+
+// TestRelease_Twice - releasing an already removed file is not an error.
+func TestRelease_Twice(t *testing.T) {
+	pidFile, err := AcquirePIDFile(filepath.Join(t.TempDir(), "sslcerttop.pid"))
+	require.NoError(t, err)
+
+	require.NoError(t, pidFile.Release())
+	assert.NoError(t, pidFile.Release())
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package daemon
+
+import (
+	"os"
+)
+
+// processExists reports whether a process with the given ID is running
+func processExists(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
@@ -0,0 +1,166 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Service describes how a service manager should run the daemon
+type Service struct {
+	// Name is the unit name, or the launchd label
+	Name string
+	// Executable is the absolute path of the sslcerttop binary
+	Executable string
+	// Args follow `daemon` on the command line, e.g. --listen :8080
+	Args []string
+	// User runs a system-wide systemd unit, empty for user units and launchd agents
+	User string
+	// Environment is passed to the daemon, e.g. SSLCERTTOP_CONFIG
+	Environment map[string]string
+	// WritablePaths are the directories the daemon may write to, everything else is read-only to it
+	WritablePaths []string
+	// LogPath receives the daemon's output under launchd, systemd sends it to the journal
+	LogPath string
+}
+
+// command is the daemon's command line
+func (s Service) command() []string {
+	return append([]string{s.Executable, "daemon"}, s.Args...)
+}
+
+// SystemdUnit renders a systemd service unit for the daemon.
+//
+// The daemon only needs the network and its own files, so the unit drops all capabilities, mounts the file system
+// read-only apart from WritablePaths and restricts it to IP and Unix sockets. User units leave out the options a
+// user's service manager can't apply
+func SystemdUnit(s Service) string {
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	b.WriteString("Description=sslcerttop certificate expiry daemon\n")
+	b.WriteString("Documentation=https://github.com/samokw/ssl_tracker\n")
+	b.WriteString("Wants=network-online.target\n")
+	b.WriteString("After=network-online.target\n")
+
+	b.WriteString("\n[Service]\n")
+	b.WriteString("Type=simple\n")
+	quoted := make([]string, 0, len(s.command()))
+	for _, arg := range s.command() {
+		quoted = append(quoted, systemdQuote(arg))
+	}
+	fmt.Fprintf(&b, "ExecStart=%s\n", strings.Join(quoted, " "))
+	b.WriteString("Restart=on-failure\n")
+	b.WriteString("RestartSec=10s\n")
+	b.WriteString("UMask=0077\n")
+	if s.User != "" {
+		fmt.Fprintf(&b, "User=%s\n", s.User)
+	}
+	for _, key := range sortedKeys(s.Environment) {
+		fmt.Fprintf(&b, "Environment=%s\n", systemdQuote(key+"="+s.Environment[key]))
+	}
+
+	b.WriteString("\n# Hardening\n")
+	b.WriteString("NoNewPrivileges=yes\n")
+	b.WriteString("PrivateTmp=yes\n")
+	b.WriteString("ProtectSystem=strict\n")
+	b.WriteString("ProtectHome=read-only\n")
+	if len(s.WritablePaths) > 0 {
+		quoted := make([]string, 0, len(s.WritablePaths))
+		for _, path := range s.WritablePaths {
+			// A leading - lets the unit start before the directory exists
+			quoted = append(quoted, systemdQuote("-"+path))
+		}
+		fmt.Fprintf(&b, "ReadWritePaths=%s\n", strings.Join(quoted, " "))
+	}
+	b.WriteString("RestrictAddressFamilies=AF_INET AF_INET6 AF_UNIX\n")
+	b.WriteString("RestrictNamespaces=yes\n")
+	b.WriteString("RestrictRealtime=yes\n")
+	b.WriteString("RestrictSUIDSGID=yes\n")
+	b.WriteString("LockPersonality=yes\n")
+	b.WriteString("MemoryDenyWriteExecute=yes\n")
+	b.WriteString("SystemCallArchitectures=native\n")
+	b.WriteString("SystemCallFilter=@system-service\n")
+	b.WriteString("SystemCallErrorNumber=EPERM\n")
+	if s.User != "" {
+		b.WriteString("CapabilityBoundingSet=\n")
+		b.WriteString("PrivateDevices=yes\n")
+		b.WriteString("ProtectClock=yes\n")
+		b.WriteString("ProtectControlGroups=yes\n")
+		b.WriteString("ProtectHostname=yes\n")
+		b.WriteString("ProtectKernelLogs=yes\n")
+		b.WriteString("ProtectKernelModules=yes\n")
+		b.WriteString("ProtectKernelTunables=yes\n")
+	}
+
+	b.WriteString("\n[Install]\n")
+	if s.User != "" {
+		b.WriteString("WantedBy=multi-user.target\n")
+	} else {
+		b.WriteString("WantedBy=default.target\n")
+	}
+	return b.String()
+}
+
+// systemdQuote quotes a word of a unit file setting when needed, and escapes the specifiers systemd would expand
+func systemdQuote(word string) string {
+	word = strings.NewReplacer("%", "%%", "$", "$$").Replace(word)
+	if word != "" && !strings.ContainsAny(word, " \t\"'\\;") {
+		return word
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(word) + `"`
+}
+
+// LaunchdPlist renders a launchd property list running the daemon as a LaunchAgent, restarted when it exits
+// with an error
+func LaunchdPlist(s Service) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	b.WriteString(`<plist version="1.0">` + "\n<dict>\n")
+	plistString(&b, "Label", s.Name)
+	b.WriteString("\t<key>ProgramArguments</key>\n\t<array>\n")
+	for _, arg := range s.command() {
+		fmt.Fprintf(&b, "\t\t<string>%s</string>\n", xmlEscape(arg))
+	}
+	b.WriteString("\t</array>\n")
+	if len(s.Environment) > 0 {
+		b.WriteString("\t<key>EnvironmentVariables</key>\n\t<dict>\n")
+		for _, key := range sortedKeys(s.Environment) {
+			fmt.Fprintf(&b, "\t\t<key>%s</key>\n\t\t<string>%s</string>\n", xmlEscape(key), xmlEscape(s.Environment[key]))
+		}
+		b.WriteString("\t</dict>\n")
+	}
+	b.WriteString("\t<key>RunAtLoad</key>\n\t<true/>\n")
+	b.WriteString("\t<key>KeepAlive</key>\n\t<dict>\n\t\t<key>SuccessfulExit</key>\n\t\t<false/>\n\t</dict>\n")
+	b.WriteString("\t<key>ThrottleInterval</key>\n\t<integer>10</integer>\n")
+	b.WriteString("\t<key>ProcessType</key>\n\t<string>Background</string>\n")
+	// Files the daemon creates are private to the user
+	b.WriteString("\t<key>Umask</key>\n\t<integer>63</integer>\n")
+	if s.LogPath != "" {
+		plistString(&b, "StandardOutPath", s.LogPath)
+		plistString(&b, "StandardErrorPath", s.LogPath)
+	}
+	b.WriteString("</dict>\n</plist>\n")
+	return b.String()
+}
+
+func plistString(b *strings.Builder, key, value string) {
+	fmt.Fprintf(b, "\t<key>%s</key>\n\t<string>%s</string>\n", xmlEscape(key), xmlEscape(value))
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
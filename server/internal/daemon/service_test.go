@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSystemdUnit - arguments are quoted, specifiers escaped and the data directories left writable.
+func TestSystemdUnit(t *testing.T) {
+	unit := SystemdUnit(Service{
+		Name:          "sslcerttop",
+		Executable:    "/opt/ssl tracker/sslcerttop",
+		Args:          []string{"--schedule", "0 3 * * *", "--tag-schedule", "prod=0 %H"},
+		User:          "certs",
+		Environment:   map[string]string{"SSLCERTTOP_DB": "/srv/certs/sslcerttop.db"},
+		WritablePaths: []string{"/home/certs/.config/sslcerttop", "/srv/certs"},
+	})
+
+	assert.Contains(t, unit, `ExecStart="/opt/ssl tracker/sslcerttop" daemon --schedule "0 3 * * *" --tag-schedule "prod=0 %%H"`+"\n")
+	assert.Contains(t, unit, "User=certs\n")
+	assert.Contains(t, unit, "Environment=SSLCERTTOP_DB=/srv/certs/sslcerttop.db\n")
+	assert.Contains(t, unit, "ReadWritePaths=-/home/certs/.config/sslcerttop -/srv/certs\n")
+	assert.Contains(t, unit, "ProtectSystem=strict\n")
+	assert.Contains(t, unit, "CapabilityBoundingSet=\n")
+	assert.Contains(t, unit, "WantedBy=multi-user.target\n")
+
+	userUnit := SystemdUnit(Service{Name: "sslcerttop", Executable: "/usr/bin/sslcerttop"})
+	assert.NotContains(t, userUnit, "User=")
+	assert.NotContains(t, userUnit, "CapabilityBoundingSet")
+	assert.Contains(t, userUnit, "WantedBy=default.target\n")
+}
+
+// TestLaunchdPlist - the property list is well-formed XML with the arguments escaped.
+func TestLaunchdPlist(t *testing.T) {
+	plist := LaunchdPlist(Service{
+		Name:        "sslcerttop",
+		Executable:  "/usr/local/bin/sslcerttop",
+		Args:        []string{"--tag-schedule", "a&b=0 3 * * *"},
+		Environment: map[string]string{"SSLCERTTOP_CONFIG": "/Users/me/<certs>/config.yaml"},
+		LogPath:     "/Users/me/Library/Logs/sslcerttop.log",
+	})
+
+	decoder := xml.NewDecoder(strings.NewReader(plist))
+	decoder.Strict = true
+	for {
+		_, err := decoder.Token()
+		if err != nil {
+			require.ErrorIs(t, err, io.EOF)
+			break
+		}
+	}
+	assert.Contains(t, plist, "<string>a&amp;b=0 3 * * *</string>")
+	assert.Contains(t, plist, "<string>/Users/me/&lt;certs&gt;/config.yaml</string>")
+	assert.Contains(t, plist, "<key>StandardErrorPath</key>")
+}
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrLocked occurs when another sslcerttop instance holds the lock of a database
+var ErrLocked = errors.New("database is in use by another sslcerttop instance")
+
+// Lock claims a SQLite database for one sslcerttop instance, so a second one finds out before its writes clash.
+//
+// The lock is advisory and held by the operating system on a file next to the database, so it goes away with the
+// process holding it, even one that crashed
+type Lock struct {
+	file *os.File
+	path string
+}
+
+// LockHolder describes the instance holding the lock of a database
+type LockHolder struct {
+	PID     int
+	Command string
+	Since   time.Time
+}
+
+// LockPath is the lock file of a SQLite database
+func LockPath(dbPath string) string {
+	return dbPath + ".lock"
+}
+
+// SocketPath is where the daemon holding a SQLite database serves the API for instances to attach to
+func SocketPath(dbPath string) string {
+	return dbPath + ".sock"
+}
+
+// AcquireLock claims the database at dbPath for this process, recording the command it runs for the instances it
+// turns away. The database's directory is created if needed, the lock is taken before the database is.
+//
+// Fails with ErrLocked, describing the holder when it can be read, if another instance holds the lock
+func AcquireLock(dbPath, command string) (*Lock, error) {
+	path := LockPath(dbPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		if !errors.Is(err, errWouldBlock) {
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		holder, readErr := ReadLockHolder(dbPath)
+		if readErr != nil {
+			return nil, ErrLocked
+		}
+		return nil, fmt.Errorf("%w: %s (pid %d) has had it open since %s", ErrLocked, holder.Command, holder.PID,
+			holder.Since.Local().Format("2006-01-02 15:04"))
+	}
+
+	holder := fmt.Sprintf("%d\n%s\n%s\n", os.Getpid(), command, time.Now().UTC().Format(time.RFC3339))
+	if err := f.Truncate(0); err == nil {
+		_, err = f.WriteAt([]byte(holder), 0)
+	}
+	if err != nil {
+		unlockFile(f)
+		f.Close()
+		return nil, fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return &Lock{file: f, path: path}, nil
+}
+
+// ReadLockHolder describes the instance that last held the lock of the database at dbPath, which may have let go
+// of it since
+func ReadLockHolder(dbPath string) (*LockHolder, error) {
+	data, err := os.ReadFile(LockPath(dbPath))
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		return nil, errors.New("invalid lock file contents")
+	}
+	pid, err := strconv.Atoi(lines[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid lock file contents: %w", err)
+	}
+	since, err := time.Parse(time.RFC3339, lines[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid lock file contents: %w", err)
+	}
+	return &LockHolder{PID: pid, Command: lines[1], Since: since}, nil
+}
+
+// Path returns the location of the lock file
+func (l *Lock) Path() string {
+	return l.path
+}
+
+// Release lets another instance claim the database. The lock file stays, removing it could race with an instance
+// locking it
+func (l *Lock) Release() error {
+	return errors.Join(unlockFile(l.file), l.file.Close())
+}
@@ -0,0 +1,33 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAcquireLock - a second instance is turned away naming the holder, and gets the lock once it is released.
+func TestAcquireLock(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "sslcerttop.db")
+
+	lock, err := AcquireLock(dbPath, "sslcerttop daemon")
+	require.NoError(t, err)
+	assert.Equal(t, dbPath+".lock", lock.Path())
+
+	_, err = AcquireLock(dbPath, "sslcerttop")
+	require.ErrorIs(t, err, ErrLocked)
+	assert.Contains(t, err.Error(), "sslcerttop daemon")
+
+	holder, err := ReadLockHolder(dbPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), holder.PID)
+	assert.Equal(t, "sslcerttop daemon", holder.Command)
+
+	require.NoError(t, lock.Release())
+	lock, err = AcquireLock(dbPath, "sslcerttop")
+	require.NoError(t, err)
+	require.NoError(t, lock.Release())
+}
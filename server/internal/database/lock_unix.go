@@ -0,0 +1,26 @@
+//go:build !windows
+
+package database
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// errWouldBlock occurs when another process holds a file's lock
+var errWouldBlock = syscall.EWOULDBLOCK
+
+// lockFile takes an exclusive lock of f without waiting, failing with errWouldBlock if it is held
+func lockFile(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EAGAIN) {
+		return errWouldBlock
+	}
+	return err
+}
+
+// unlockFile releases the lock of f
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,27 @@
+//go:build windows
+
+package database
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// errWouldBlock occurs when another process holds a file's lock
+var errWouldBlock = windows.ERROR_LOCK_VIOLATION
+
+// lockOffset is where the locked byte lies, past anything written to the file so the holder can still be read
+const lockOffset = 1 << 30
+
+// lockFile takes an exclusive lock of f without waiting, failing with errWouldBlock if it is held
+func lockFile(f *os.File) error {
+	ol := &windows.Overlapped{Offset: lockOffset}
+	return windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, ol)
+}
+
+// unlockFile releases the lock of f
+func unlockFile(f *os.File) error {
+	ol := &windows.Overlapped{Offset: lockOffset}
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, ol)
+}
@@ -0,0 +1,477 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// InitMySQL initializes a MySQL or MariaDB connection from a DSN such as user:pass@tcp(host:3306)/sslcerttop
+func InitMySQL(dsn string) (*sql.DB, error) {
+	cfg, err := mysqlConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	db := sql.OpenDB(connector)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	if err := runMySQLMigrations(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	return db, nil
+}
+
+// mysqlConfig parses the DSN and sets the options the repositories rely on, DATETIME columns scanned as UTC time.Time
+func mysqlConfig(dsn string) (*mysql.Config, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MySQL DSN: %w", err)
+	}
+	cfg.ParseTime = true
+	cfg.Loc = time.UTC
+	return cfg, nil
+}
+
+// RedactDSN returns the DSN without its password so it can be logged
+func RedactDSN(dsn string) string {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "(invalid DSN)"
+	}
+	if cfg.Passwd != "" {
+		cfg.Passwd = "xxxxx"
+	}
+	return cfg.FormatDSN()
+}
+
+// runMySQLMigrations creates the same schema as runMigrations using MySQL types
+func runMySQLMigrations(db *sql.DB) error {
+	tables := []struct {
+		name   string
+		schema string
+	}{
+		{"users", `
+		CREATE TABLE IF NOT EXISTS users (
+			id INTEGER AUTO_INCREMENT PRIMARY KEY,
+			username VARCHAR(255) UNIQUE NOT NULL,
+			created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+			email VARCHAR(255) UNIQUE,
+			password_hash VARCHAR(255) NOT NULL DEFAULT '',
+			deactivated_at DATETIME(6)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`},
+		{"domains", `
+		CREATE TABLE IF NOT EXISTS domains (
+			id INTEGER AUTO_INCREMENT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			domain_name VARCHAR(253) NOT NULL,
+			created_at DATETIME(6) NOT NULL,
+			expiry_date DATETIME(6),
+			last_checked DATETIME(6),
+			last_error TEXT,
+			is_active BOOLEAN NOT NULL DEFAULT 1,
+			check_interval_seconds INTEGER NOT NULL DEFAULT 0,
+			check_schedule VARCHAR(255) NOT NULL DEFAULT '',
+			tags VARCHAR(1024) NOT NULL DEFAULT '',
+			issuer VARCHAR(255) NOT NULL DEFAULT '',
+			team_id INTEGER,
+			fingerprint VARCHAR(255) NOT NULL DEFAULT '',
+			registration_expiry DATETIME(6),
+			registration_checked DATETIME(6),
+			dns_expectations VARCHAR(1024) NOT NULL DEFAULT '',
+			dns_error TEXT,
+			dns_checked DATETIME(6),
+			warning TEXT,
+			auto_renew_days INTEGER NOT NULL DEFAULT 0,
+			auto_renew_via VARCHAR(255) NOT NULL DEFAULT '',
+			cert_fingerprint CHAR(64) NOT NULL DEFAULT '',
+			sans VARCHAR(4096) NOT NULL DEFAULT '',
+			next_check_at DATETIME(6),
+			expected_sans VARCHAR(4096) NOT NULL DEFAULT '',
+			issued_at DATETIME(6),
+			key_type VARCHAR(64) NOT NULL DEFAULT '',
+			signatures VARCHAR(255) NOT NULL DEFAULT '',
+			check_latency_ms INTEGER,
+			UNIQUE KEY uq_domains_user_name (user_id, domain_name),
+			CONSTRAINT fk_domains_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`},
+		{"notifications", `
+		CREATE TABLE IF NOT EXISTS notifications (
+			id INTEGER AUTO_INCREMENT PRIMARY KEY,
+			domain_id INTEGER NOT NULL,
+			days_before INTEGER NOT NULL,
+			notification_type VARCHAR(32) NOT NULL,
+			status VARCHAR(32) NOT NULL DEFAULT 'pending',
+			created_at DATETIME(6) NOT NULL,
+			sent_at DATETIME(6),
+			acknowledged_at DATETIME(6),
+			last_error TEXT,
+			escalated_from INTEGER,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at DATETIME(6),
+			last_status_code INTEGER,
+			CONSTRAINT fk_notifications_domain FOREIGN KEY (domain_id) REFERENCES domains (id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`},
+		{"check_history", `
+		CREATE TABLE IF NOT EXISTS check_history (
+			id INTEGER AUTO_INCREMENT PRIMARY KEY,
+			domain_id INTEGER NOT NULL,
+			checked_at DATETIME(6) NOT NULL,
+			expiry_date DATETIME(6),
+			error TEXT,
+			fingerprint VARCHAR(255) NOT NULL DEFAULT '',
+			INDEX idx_check_history_domain (domain_id, checked_at),
+			CONSTRAINT fk_check_history_domain FOREIGN KEY (domain_id) REFERENCES domains (id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`},
+		{"api_keys", `
+		CREATE TABLE IF NOT EXISTS api_keys (
+			id INTEGER AUTO_INCREMENT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			prefix VARCHAR(32) NOT NULL,
+			key_hash CHAR(64) UNIQUE NOT NULL,
+			scope VARCHAR(32) NOT NULL,
+			created_at DATETIME(6) NOT NULL,
+			last_used_at DATETIME(6),
+			revoked_at DATETIME(6),
+			CONSTRAINT fk_api_keys_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`},
+		{"open_alerts", `
+		CREATE TABLE IF NOT EXISTS open_alerts (
+			domain_id INTEGER NOT NULL,
+			provider VARCHAR(32) NOT NULL,
+			dedup_key VARCHAR(255) NOT NULL,
+			triggered_at DATETIME(6) NOT NULL,
+			PRIMARY KEY (domain_id, provider),
+			CONSTRAINT fk_open_alerts_domain FOREIGN KEY (domain_id) REFERENCES domains (id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`},
+		{"notification_rules", `
+		CREATE TABLE IF NOT EXISTS notification_rules (
+			id INTEGER AUTO_INCREMENT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			channel VARCHAR(32) NOT NULL,
+			thresholds VARCHAR(255) NOT NULL DEFAULT '',
+			on_error BOOLEAN NOT NULL DEFAULT 0,
+			tags VARCHAR(1024) NOT NULL DEFAULT '',
+			enabled BOOLEAN NOT NULL DEFAULT 1,
+			created_at DATETIME(6) NOT NULL,
+			CONSTRAINT fk_notification_rules_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`},
+		{"domain_acks", `
+		CREATE TABLE IF NOT EXISTS domain_acks (
+			domain_id INTEGER PRIMARY KEY,
+			note VARCHAR(1024) NOT NULL DEFAULT '',
+			expiry_date DATETIME(6),
+			expires_at DATETIME(6),
+			created_at DATETIME(6) NOT NULL,
+			CONSTRAINT fk_domain_acks_domain FOREIGN KEY (domain_id) REFERENCES domains (id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`},
+		{"digests", `
+		CREATE TABLE IF NOT EXISTS digests (
+			id INTEGER AUTO_INCREMENT PRIMARY KEY,
+			sent_at DATETIME(6) NOT NULL
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`},
+		{"user_sessions", `
+		CREATE TABLE IF NOT EXISTS user_sessions (
+			id INTEGER AUTO_INCREMENT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			token_hash CHAR(64) UNIQUE NOT NULL,
+			created_at DATETIME(6) NOT NULL,
+			expires_at DATETIME(6) NOT NULL,
+			CONSTRAINT fk_user_sessions_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`},
+		{"notification_attempts", `
+		CREATE TABLE IF NOT EXISTS notification_attempts (
+			id INTEGER AUTO_INCREMENT PRIMARY KEY,
+			notification_id INTEGER NOT NULL,
+			attempted_at DATETIME(6) NOT NULL,
+			status_code INTEGER,
+			error TEXT,
+			CONSTRAINT fk_notification_attempts_notification FOREIGN KEY (notification_id) REFERENCES notifications (id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`},
+		{"user_identities", `
+		CREATE TABLE IF NOT EXISTS user_identities (
+			id INTEGER AUTO_INCREMENT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			issuer VARCHAR(255) NOT NULL,
+			subject VARCHAR(255) NOT NULL,
+			created_at DATETIME(6) NOT NULL,
+			UNIQUE KEY uq_user_identities_subject (issuer, subject),
+			CONSTRAINT fk_user_identities_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`},
+		{"user_settings", `
+		CREATE TABLE IF NOT EXISTS user_settings (
+			user_id INTEGER PRIMARY KEY,
+			theme VARCHAR(64) NOT NULL DEFAULT '',
+			warning_days INTEGER NOT NULL,
+			critical_days INTEGER NOT NULL,
+			notification_channels VARCHAR(255) NOT NULL DEFAULT '',
+			timezone VARCHAR(64) NOT NULL DEFAULT '',
+			time_display VARCHAR(16) NOT NULL DEFAULT '',
+			columns VARCHAR(512) NOT NULL DEFAULT '',
+			updated_at DATETIME(6) NOT NULL,
+			CONSTRAINT fk_user_settings_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`},
+		{"teams", `
+		CREATE TABLE IF NOT EXISTS teams (
+			id INTEGER AUTO_INCREMENT PRIMARY KEY,
+			name VARCHAR(255) UNIQUE NOT NULL,
+			created_at DATETIME(6) NOT NULL
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`},
+		{"team_members", `
+		CREATE TABLE IF NOT EXISTS team_members (
+			team_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			role VARCHAR(16) NOT NULL,
+			created_at DATETIME(6) NOT NULL,
+			PRIMARY KEY (team_id, user_id),
+			CONSTRAINT fk_team_members_team FOREIGN KEY (team_id) REFERENCES teams (id) ON DELETE CASCADE,
+			CONSTRAINT fk_team_members_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`},
+		{"renewal_attempts", `
+		CREATE TABLE IF NOT EXISTS renewal_attempts (
+			id INTEGER AUTO_INCREMENT PRIMARY KEY,
+			domain_id INTEGER NOT NULL,
+			actions VARCHAR(64) NOT NULL,
+			triggered_at DATETIME(6) NOT NULL,
+			expiry_before DATETIME(6),
+			error TEXT,
+			output TEXT NOT NULL,
+			verified_at DATETIME(6),
+			renewed BOOLEAN NOT NULL DEFAULT FALSE,
+			INDEX idx_renewal_attempts_domain (domain_id),
+			CONSTRAINT fk_renewal_attempts_domain FOREIGN KEY (domain_id) REFERENCES domains (id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`},
+		{"cloud_certificates", `
+		CREATE TABLE IF NOT EXISTS cloud_certificates (
+			domain_id INTEGER PRIMARY KEY,
+			provider VARCHAR(32) NOT NULL,
+			account VARCHAR(255) NOT NULL DEFAULT '',
+			region VARCHAR(64) NOT NULL DEFAULT '',
+			domain VARCHAR(255) NOT NULL DEFAULT '',
+			type VARCHAR(32) NOT NULL DEFAULT '',
+			status VARCHAR(32) NOT NULL DEFAULT '',
+			renewal_eligibility VARCHAR(32) NOT NULL DEFAULT '',
+			in_use BOOLEAN NOT NULL DEFAULT FALSE,
+			synced_at DATETIME(6) NOT NULL,
+			CONSTRAINT fk_cloud_certificates_domain FOREIGN KEY (domain_id) REFERENCES domains (id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`},
+		{"stored_certificates", `
+		CREATE TABLE IF NOT EXISTS stored_certificates (
+			domain_id INTEGER PRIMARY KEY,
+			pem TEXT NOT NULL,
+			added_at DATETIME(6) NOT NULL,
+			CONSTRAINT fk_stored_certificates_domain FOREIGN KEY (domain_id) REFERENCES domains (id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`},
+		{"registration_alerts", `
+		CREATE TABLE IF NOT EXISTS registration_alerts (
+			registered_domain VARCHAR(255) NOT NULL,
+			expiry_date DATETIME(6) NOT NULL,
+			days_before INTEGER NOT NULL,
+			sent_at DATETIME(6) NOT NULL,
+			PRIMARY KEY (registered_domain, expiry_date, days_before)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`},
+		{"maintenance_windows", `
+		CREATE TABLE IF NOT EXISTS maintenance_windows (
+			id INTEGER AUTO_INCREMENT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			domain_id INTEGER,
+			tag VARCHAR(255) NOT NULL DEFAULT '',
+			start_at DATETIME(6),
+			schedule VARCHAR(255) NOT NULL DEFAULT '',
+			duration_seconds INTEGER NOT NULL,
+			note VARCHAR(1024) NOT NULL DEFAULT '',
+			created_at DATETIME(6) NOT NULL,
+			CONSTRAINT fk_maintenance_windows_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+			CONSTRAINT fk_maintenance_windows_domain FOREIGN KEY (domain_id) REFERENCES domains (id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`},
+		{"activity", `
+		CREATE TABLE IF NOT EXISTS activity (
+			id INTEGER AUTO_INCREMENT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			team_id INTEGER,
+			domain_id INTEGER NOT NULL,
+			domain_name VARCHAR(253) NOT NULL,
+			kind VARCHAR(32) NOT NULL,
+			detail TEXT NOT NULL,
+			created_at DATETIME(6) NOT NULL,
+			INDEX idx_activity_user (user_id, created_at),
+			CONSTRAINT fk_activity_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`},
+	}
+
+	for _, table := range tables {
+		if _, err := db.Exec(table.schema); err != nil {
+			return fmt.Errorf("failed to create %s table: %w", table.name, err)
+		}
+	}
+
+	// Columns added since MySQL support was released
+	if err := addMySQLColumnIfMissing(db, "users", "email", "VARCHAR(255) UNIQUE"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "users", "password_hash", "VARCHAR(255) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "users", "deactivated_at", "DATETIME(6)"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "domains", "issuer", "VARCHAR(255) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "domains", "team_id", "INTEGER"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "domains", "fingerprint", "VARCHAR(255) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "check_history", "fingerprint", "VARCHAR(255) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "domains", "registration_expiry", "DATETIME(6)"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "domains", "registration_checked", "DATETIME(6)"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "domains", "dns_expectations", "VARCHAR(1024) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "domains", "dns_error", "TEXT"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "domains", "dns_checked", "DATETIME(6)"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "domains", "warning", "TEXT"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "domains", "auto_renew_days", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "domains", "auto_renew_via", "VARCHAR(255) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "domains", "cert_fingerprint", "CHAR(64) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "domains", "sans", "VARCHAR(4096) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "domains", "next_check_at", "DATETIME(6)"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "domains", "expected_sans", "VARCHAR(4096) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "domains", "issued_at", "DATETIME(6)"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "domains", "key_type", "VARCHAR(64) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "domains", "signatures", "VARCHAR(255) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "domains", "check_latency_ms", "INTEGER"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "user_settings", "time_display", "VARCHAR(16) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "user_settings", "columns", "VARCHAR(512) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "notifications", "escalated_from", "INTEGER"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "notifications", "attempts", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "notifications", "next_attempt_at", "DATETIME(6)"); err != nil {
+		return err
+	}
+	if err := addMySQLColumnIfMissing(db, "notifications", "last_status_code", "INTEGER"); err != nil {
+		return err
+	}
+
+	defaultUser := `INSERT IGNORE INTO users (id, username) VALUES (1, 'default')`
+	if _, err := db.Exec(defaultUser); err != nil {
+		return fmt.Errorf("failed to insert default user: %w", err)
+	}
+
+	// Databases created before foreign keys were introduced get them added
+	for _, fk := range mysqlForeignKeys {
+		if err := addMySQLForeignKey(db, fk.table, fk.name, fk.column, fk.parent); err != nil {
+			return err
+		}
+	}
+
+	if err := normalizeDomainNames(db); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// addMySQLColumnIfMissing adds a column to an existing table so older databases pick up new fields
+func addMySQLColumnIfMissing(db *sql.DB, table, column, definition string) error {
+	var count int
+	query := `SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?`
+	if err := db.QueryRow(query, table, column).Scan(&count); err != nil {
+		return fmt.Errorf("failed to inspect %s table: %w", table, err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	alter := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition)
+	if _, err := db.Exec(alter); err != nil {
+		return fmt.Errorf("failed to add %s.%s column: %w", table, column, err)
+	}
+	return nil
+}
+
+var mysqlForeignKeys = []struct {
+	table, name, column, parent string
+}{
+	{"domains", "fk_domains_user", "user_id", "users"},
+	{"notifications", "fk_notifications_domain", "domain_id", "domains"},
+	{"check_history", "fk_check_history_domain", "domain_id", "domains"},
+	{"api_keys", "fk_api_keys_user", "user_id", "users"},
+}
+
+// addMySQLForeignKey adds a cascading foreign key to a table that lacks it, deleting rows whose parent is gone
+func addMySQLForeignKey(db *sql.DB, table, name, column, parent string) error {
+	var count int
+	query := `SELECT COUNT(*) FROM information_schema.REFERENTIAL_CONSTRAINTS WHERE CONSTRAINT_SCHEMA = DATABASE() AND CONSTRAINT_NAME = ?`
+	if err := db.QueryRow(query, name).Scan(&count); err != nil {
+		return fmt.Errorf("failed to inspect %s foreign keys: %w", table, err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	orphans := fmt.Sprintf(`DELETE FROM %s WHERE %s NOT IN (SELECT id FROM %s)`, table, column, parent)
+	if _, err := db.Exec(orphans); err != nil {
+		return fmt.Errorf("failed to add foreign keys to %s table: %w", table, err)
+	}
+	constraint := fmt.Sprintf(`ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (id) ON DELETE CASCADE`, table, name, column, parent)
+	if _, err := db.Exec(constraint); err != nil {
+		return fmt.Errorf("failed to add foreign keys to %s table: %w", table, err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package database_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRedactDSN - passwords never reach the logs.
+func TestRedactDSN(t *testing.T) {
+	redacted := database.RedactDSN("tracker:hunter2@tcp(db:3306)/sslcerttop")
+	assert.NotContains(t, redacted, "hunter2")
+	assert.Contains(t, redacted, "db:3306")
+}
+
+// TestInitMySQL - runs the repositories against a real server when SSLCERTTOP_TEST_MYSQL_DSN is set.
+func TestInitMySQL(t *testing.T) {
+	dsn := os.Getenv("SSLCERTTOP_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("SSLCERTTOP_TEST_MYSQL_DSN not set")
+	}
+
+	db, err := database.InitMySQL(dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	// Migrations must be safe to run again
+	db.Close()
+	db, err = database.InitMySQL(dsn)
+	require.NoError(t, err)
+
+	repo := domain.NewRepository(db)
+	d := domain.Domain{
+		UserID:     types.UserID(1),
+		DomainName: domain.NewDomainName("mysql-" + time.Now().Format("150405.000000") + ".example.com"),
+		CreatedAt:  domain.NewCreatedAt(time.Now()),
+		IsActive:   true,
+		Tags:       []string{"prod"},
+	}
+	require.NoError(t, repo.CreateDomain(&d))
+	t.Cleanup(func() { repo.DeleteDomain(d.DomainID) })
+
+	expiry := time.Now().Add(90 * 24 * time.Hour).Truncate(time.Second)
+	require.NoError(t, repo.UpdateSSLInfo(d.DomainID, &expiry, nil))
+
+	got, err := repo.GetDomainByID(d.DomainID)
+	require.NoError(t, err)
+	assert.Equal(t, d.DomainName, got.DomainName)
+	assert.Equal(t, []string{"prod"}, got.Tags)
+	require.NotNil(t, got.ExpiryDate)
+	assert.True(t, expiry.Equal(got.ExpiryDate.Time()))
+
+	history, err := repo.GetCheckHistory(d.DomainID, 10)
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+}
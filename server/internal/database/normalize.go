@@ -0,0 +1,150 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/samokw/ssl_tracker/internal/ssl"
+)
+
+// normalizeDomainNames stores every domain under the name ssl.NormalizeTarget gives it.
+//
+// Names that only differed by case or a trailing dot were tracked twice, the oldest of them is kept and everything
+// recorded about the others is moved onto it before they are deleted
+func normalizeDomainNames(db *sql.DB) error {
+	rows, err := db.Query(`SELECT id, user_id, domain_name FROM domains ORDER BY id`)
+	if err != nil {
+		return fmt.Errorf("failed to read domain names: %w", err)
+	}
+	defer rows.Close()
+
+	type key struct {
+		userID int64
+		name   string
+	}
+	keepers := map[key]int64{}
+	renames := map[int64]string{}
+	duplicates := map[int64]int64{}
+	for rows.Next() {
+		var id, userID int64
+		var name string
+		if err := rows.Scan(&id, &userID, &name); err != nil {
+			return fmt.Errorf("failed to read domain names: %w", err)
+		}
+		normalized := ssl.NormalizeTarget(name)
+		k := key{userID, normalized}
+		if keeper, ok := keepers[k]; ok {
+			duplicates[id] = keeper
+			continue
+		}
+		keepers[k] = id
+		if normalized != name {
+			renames[id] = normalized
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read domain names: %w", err)
+	}
+	rows.Close()
+	if len(renames) == 0 && len(duplicates) == 0 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Duplicates go first so the renamed keepers don't collide with them
+	for id, keeper := range duplicates {
+		if err := mergeDomain(tx, id, keeper); err != nil {
+			return fmt.Errorf("failed to merge duplicate domain %d: %w", id, err)
+		}
+		if _, err := tx.Exec(`DELETE FROM domains WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("failed to merge duplicate domain %d: %w", id, err)
+		}
+	}
+	for id, name := range renames {
+		if _, err := tx.Exec(`UPDATE domains SET domain_name = ? WHERE id = ?`, name, id); err != nil {
+			return fmt.Errorf("failed to normalize domain %d: %w", id, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to normalize domain names: %w", err)
+	}
+
+	slog.Info("Normalized domain names", "renamed", len(renames), "merged", len(duplicates))
+	return nil
+}
+
+// domainHistoryTables hold any number of rows per domain, all of them move to the domain kept
+var domainHistoryTables = []string{"check_history", "notifications", "renewal_attempts", "maintenance_windows", "activity"}
+
+// domainStateTables hold one row per domain, the kept domain's row wins over the duplicate's
+var domainStateTables = []string{"domain_acks", "cloud_certificates", "stored_certificates"}
+
+// mergeDomain moves what is recorded about a duplicate domain onto the domain kept, before the duplicate is deleted
+// and anything left on it cascades away
+func mergeDomain(tx *sql.Tx, id, keeper int64) error {
+	for _, table := range domainHistoryTables {
+		if _, err := tx.Exec(`UPDATE `+table+` SET domain_id = ? WHERE domain_id = ?`, keeper, id); err != nil {
+			return err
+		}
+	}
+	for _, table := range domainStateTables {
+		var kept int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM `+table+` WHERE domain_id = ?`, keeper).Scan(&kept); err != nil {
+			return err
+		}
+		if kept > 0 {
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE `+table+` SET domain_id = ? WHERE domain_id = ?`, keeper, id); err != nil {
+			return err
+		}
+	}
+	return mergeOpenAlerts(tx, id, keeper)
+}
+
+// mergeOpenAlerts moves the incidents open for a duplicate domain onto the domain kept, so they are resolved once
+// it is healthy. The kept domain has one incident per provider, a second one can't be resolved from here and is
+// logged so it can be closed by hand
+func mergeOpenAlerts(tx *sql.Tx, id, keeper int64) error {
+	rows, err := tx.Query(`SELECT provider, dedup_key FROM open_alerts WHERE domain_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	alerts := map[string]string{}
+	for rows.Next() {
+		var provider, dedupKey string
+		if err := rows.Scan(&provider, &dedupKey); err != nil {
+			return err
+		}
+		alerts[provider] = dedupKey
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	for provider, dedupKey := range alerts {
+		var keptKey string
+		err := tx.QueryRow(`SELECT dedup_key FROM open_alerts WHERE domain_id = ? AND provider = ?`, keeper, provider).Scan(&keptKey)
+		if err == sql.ErrNoRows {
+			if _, err := tx.Exec(`UPDATE open_alerts SET domain_id = ? WHERE domain_id = ? AND provider = ?`, keeper, id, provider); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if keptKey != dedupKey {
+			slog.Warn("Incident of a merged duplicate domain has to be resolved by hand", "domain_id", id, "provider", provider, "dedup_key", dedupKey)
+		}
+	}
+	return nil
+}
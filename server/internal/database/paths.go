@@ -0,0 +1,124 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+const (
+	appDir     = "sslcerttop"
+	dbFileName = "sslcerttop.db"
+)
+
+// GetConfigDir returns $XDG_CONFIG_HOME/sslcerttop, falling back to ~/.config/sslcerttop
+func GetConfigDir() (string, error) {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// GetDataDir returns $XDG_DATA_HOME/sslcerttop, falling back to ~/.local/share/sslcerttop
+func GetDataDir() (string, error) {
+	return xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+}
+
+// xdgDir resolves an XDG base directory, ignoring relative values as the spec requires
+func xdgDir(env, fallback string) (string, error) {
+	if base := os.Getenv(env); filepath.IsAbs(base) {
+		return filepath.Join(base, appDir), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, fallback, appDir), nil
+}
+
+// GetDefaultDBPath returns the database location in the data directory
+func GetDefaultDBPath() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, dbFileName), nil
+}
+
+// GetLegacyDBPath returns where databases were kept before they moved to the data directory
+func GetLegacyDBPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".config", appDir, dbFileName), nil
+}
+
+// MigrateLegacyDB moves a database from the legacy location to dbPath.
+//
+// Nothing happens when dbPath already exists or there is no legacy database.
+//
+// Returns true if a database was moved
+func MigrateLegacyDB(legacyPath, dbPath string) (bool, error) {
+	if legacyPath == dbPath {
+		return false, nil
+	}
+	if _, err := os.Stat(dbPath); !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	if _, err := os.Stat(legacyPath); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return false, fmt.Errorf("failed to create database directory: %w", err)
+	}
+	// The journal files belong to the database and must move with it
+	for _, suffix := range []string{"", "-wal", "-shm", "-journal"} {
+		if err := moveFile(legacyPath+suffix, dbPath+suffix); err != nil {
+			if suffix != "" && errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return false, fmt.Errorf("failed to move database to %s: %w", dbPath, err)
+		}
+	}
+
+	slog.Info("Moved database to the data directory", "from", legacyPath, "to", dbPath)
+	return true, nil
+}
+
+// moveFile renames a file, copying it when the destination is on another filesystem
+func moveFile(from, to string) error {
+	if err := os.Rename(from, to); err == nil || errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(to)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(to)
+		return err
+	}
+	return os.Remove(from)
+}
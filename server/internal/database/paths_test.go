@@ -0,0 +1,61 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestXDGDirs - XDG variables are honoured and relative values ignored.
+func TestXDGDirs(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	t.Setenv("XDG_CONFIG_HOME", "/etc/xdg-config")
+	t.Setenv("XDG_DATA_HOME", "relative/data")
+
+	configDir, err := GetConfigDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/etc/xdg-config", "sslcerttop"), configDir)
+
+	dbPath, err := GetDefaultDBPath()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, ".local", "share", "sslcerttop", "sslcerttop.db"), dbPath)
+}
+
+// TestMigrateLegacyDB - the database and its journal files move, an existing database is left alone.
+func TestMigrateLegacyDB(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "config", "sslcerttop.db")
+	target := filepath.Join(dir, "data", "sslcerttop", "sslcerttop.db")
+
+	moved, err := MigrateLegacyDB(legacy, target)
+	require.NoError(t, err)
+	assert.False(t, moved, "nothing to move")
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(legacy), 0755))
+	require.NoError(t, os.WriteFile(legacy, []byte("db"), 0600))
+	require.NoError(t, os.WriteFile(legacy+"-wal", []byte("wal"), 0600))
+
+	moved, err = MigrateLegacyDB(legacy, target)
+	require.NoError(t, err)
+	assert.True(t, moved)
+
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "db", string(data))
+	assert.FileExists(t, target+"-wal")
+	assert.NoFileExists(t, legacy)
+	assert.NoFileExists(t, legacy+"-wal")
+
+	require.NoError(t, os.WriteFile(legacy, []byte("stale"), 0600))
+	moved, err = MigrateLegacyDB(legacy, target)
+	require.NoError(t, err)
+	assert.False(t, moved, "existing database wins")
+	data, err = os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "db", string(data))
+}
@@ -1,24 +1,52 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 
 	_ "modernc.org/sqlite"
 )
 
+// sqlitePragmas let readers run alongside the single writer and make writers wait for the lock instead of failing
+var sqlitePragmas = url.Values{"_pragma": {
+	"journal_mode(WAL)",
+	"busy_timeout(5000)",
+	"foreign_keys(ON)",
+	"synchronous(NORMAL)",
+}}
+
 // InitSQLite initializes the SQLite database connection
 func InitSQLite(dbPath string) (*sql.DB, error) {
+	db, err := OpenSQLite(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Run migrations
+	if err := runMigrations(db); err != nil {
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	return db, nil
+}
+
+// OpenSQLite connects to the SQLite database without migrating it, for a database the instance holding its lock
+// already migrated
+func OpenSQLite(dbPath string) (*sql.DB, error) {
 	// Create directory if it doesn't exist
 	dir := filepath.Dir(dbPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create database directory: %w", err)
 	}
 
-	// Open database connection
-	db, err := sql.Open("sqlite", dbPath)
+	// Open database connection, the pragmas are applied to every connection in the pool
+	db, err := sql.Open("sqlite", dbPath+"?"+sqlitePragmas.Encode())
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -27,42 +55,358 @@ func InitSQLite(dbPath string) (*sql.DB, error) {
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
-
-	// Run migrations
-	if err := runMigrations(db); err != nil {
-		return nil, fmt.Errorf("failed to run migrations: %w", err)
-	}
-
 	return db, nil
 }
 
-func runMigrations(db *sql.DB) error {
-	domainsTable := `
+// sqliteTables are created in order so every table exists before the tables referencing it
+var sqliteTables = []struct {
+	name   string
+	schema string
+	// parents filters the rows kept when the table is rebuilt to add its foreign keys
+	parents string
+}{
+	{"users", `
+	CREATE TABLE IF NOT EXISTS users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT UNIQUE NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		email TEXT,
+		password_hash TEXT NOT NULL DEFAULT '',
+		deactivated_at DATETIME
+	);`, ""},
+	{"domains", `
 	CREATE TABLE IF NOT EXISTS domains (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
 		domain_name TEXT NOT NULL,
 		created_at DATETIME NOT NULL,
 		expiry_date DATETIME,
 		last_checked DATETIME,
 		last_error TEXT,
 		is_active BOOLEAN NOT NULL DEFAULT 1,
+		check_interval_seconds INTEGER NOT NULL DEFAULT 0,
+		check_schedule TEXT NOT NULL DEFAULT '',
+		tags TEXT NOT NULL DEFAULT '',
+		issuer TEXT NOT NULL DEFAULT '',
+		team_id INTEGER,
+		fingerprint TEXT NOT NULL DEFAULT '',
+		registration_expiry DATETIME,
+		registration_checked DATETIME,
+		dns_expectations TEXT NOT NULL DEFAULT '',
+		dns_error TEXT,
+		dns_checked DATETIME,
+		warning TEXT,
+		auto_renew_days INTEGER NOT NULL DEFAULT 0,
+		auto_renew_via TEXT NOT NULL DEFAULT '',
+		cert_fingerprint TEXT NOT NULL DEFAULT '',
+		sans TEXT NOT NULL DEFAULT '',
+		next_check_at DATETIME,
+		expected_sans TEXT NOT NULL DEFAULT '',
+		issued_at DATETIME,
+		key_type TEXT NOT NULL DEFAULT '',
+		signatures TEXT NOT NULL DEFAULT '',
+		check_latency_ms INTEGER,
 		UNIQUE(user_id, domain_name)
-	);`
+	);`, "user_id IN (SELECT id FROM users)"},
+	{"notifications", `
+	CREATE TABLE IF NOT EXISTS notifications (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		domain_id INTEGER NOT NULL REFERENCES domains (id) ON DELETE CASCADE,
+		days_before INTEGER NOT NULL,
+		notification_type TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		created_at DATETIME NOT NULL,
+		sent_at DATETIME,
+		acknowledged_at DATETIME,
+		last_error TEXT,
+		escalated_from INTEGER,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at DATETIME,
+		last_status_code INTEGER
+	);`, "domain_id IN (SELECT id FROM domains)"},
+	{"check_history", `
+	CREATE TABLE IF NOT EXISTS check_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		domain_id INTEGER NOT NULL REFERENCES domains (id) ON DELETE CASCADE,
+		checked_at DATETIME NOT NULL,
+		expiry_date DATETIME,
+		error TEXT,
+		fingerprint TEXT NOT NULL DEFAULT ''
+	);`, "domain_id IN (SELECT id FROM domains)"},
+	{"api_keys", `
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		key_hash TEXT UNIQUE NOT NULL,
+		scope TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		last_used_at DATETIME,
+		revoked_at DATETIME
+	);`, "user_id IN (SELECT id FROM users)"},
+	{"open_alerts", `
+	CREATE TABLE IF NOT EXISTS open_alerts (
+		domain_id INTEGER NOT NULL REFERENCES domains (id) ON DELETE CASCADE,
+		provider TEXT NOT NULL,
+		dedup_key TEXT NOT NULL,
+		triggered_at DATETIME NOT NULL,
+		PRIMARY KEY (domain_id, provider)
+	);`, "domain_id IN (SELECT id FROM domains)"},
+	{"notification_rules", `
+	CREATE TABLE IF NOT EXISTS notification_rules (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		channel TEXT NOT NULL,
+		thresholds TEXT NOT NULL DEFAULT '',
+		on_error BOOLEAN NOT NULL DEFAULT 0,
+		tags TEXT NOT NULL DEFAULT '',
+		enabled BOOLEAN NOT NULL DEFAULT 1,
+		created_at DATETIME NOT NULL
+	);`, "user_id IN (SELECT id FROM users)"},
+	{"domain_acks", `
+	CREATE TABLE IF NOT EXISTS domain_acks (
+		domain_id INTEGER PRIMARY KEY REFERENCES domains (id) ON DELETE CASCADE,
+		note TEXT NOT NULL DEFAULT '',
+		expiry_date DATETIME,
+		expires_at DATETIME,
+		created_at DATETIME NOT NULL
+	);`, "domain_id IN (SELECT id FROM domains)"},
+	{"digests", `
+	CREATE TABLE IF NOT EXISTS digests (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		sent_at DATETIME NOT NULL
+	);`, ""},
+	{"user_sessions", `
+	CREATE TABLE IF NOT EXISTS user_sessions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		token_hash TEXT UNIQUE NOT NULL,
+		created_at DATETIME NOT NULL,
+		expires_at DATETIME NOT NULL
+	);`, "user_id IN (SELECT id FROM users)"},
+	{"notification_attempts", `
+	CREATE TABLE IF NOT EXISTS notification_attempts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		notification_id INTEGER NOT NULL REFERENCES notifications (id) ON DELETE CASCADE,
+		attempted_at DATETIME NOT NULL,
+		status_code INTEGER,
+		error TEXT
+	);`, "notification_id IN (SELECT id FROM notifications)"},
+	{"user_identities", `
+	CREATE TABLE IF NOT EXISTS user_identities (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		issuer TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		UNIQUE(issuer, subject)
+	);`, "user_id IN (SELECT id FROM users)"},
+	{"user_settings", `
+	CREATE TABLE IF NOT EXISTS user_settings (
+		user_id INTEGER PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+		theme TEXT NOT NULL DEFAULT '',
+		warning_days INTEGER NOT NULL,
+		critical_days INTEGER NOT NULL,
+		notification_channels TEXT NOT NULL DEFAULT '',
+		timezone TEXT NOT NULL DEFAULT '',
+		time_display TEXT NOT NULL DEFAULT '',
+		columns TEXT NOT NULL DEFAULT '',
+		updated_at DATETIME NOT NULL
+	);`, "user_id IN (SELECT id FROM users)"},
+	{"teams", `
+	CREATE TABLE IF NOT EXISTS teams (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT UNIQUE NOT NULL,
+		created_at DATETIME NOT NULL
+	);`, ""},
+	{"team_members", `
+	CREATE TABLE IF NOT EXISTS team_members (
+		team_id INTEGER NOT NULL REFERENCES teams (id) ON DELETE CASCADE,
+		user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		role TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		PRIMARY KEY (team_id, user_id)
+	);`, "team_id IN (SELECT id FROM teams) AND user_id IN (SELECT id FROM users)"},
+	{"renewal_attempts", `
+	CREATE TABLE IF NOT EXISTS renewal_attempts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		domain_id INTEGER NOT NULL REFERENCES domains (id) ON DELETE CASCADE,
+		actions TEXT NOT NULL,
+		triggered_at DATETIME NOT NULL,
+		expiry_before DATETIME,
+		error TEXT,
+		output TEXT NOT NULL DEFAULT '',
+		verified_at DATETIME,
+		renewed BOOLEAN NOT NULL DEFAULT 0
+	);`, "domain_id IN (SELECT id FROM domains)"},
+	{"cloud_certificates", `
+	CREATE TABLE IF NOT EXISTS cloud_certificates (
+		domain_id INTEGER PRIMARY KEY REFERENCES domains (id) ON DELETE CASCADE,
+		provider TEXT NOT NULL,
+		account TEXT NOT NULL DEFAULT '',
+		region TEXT NOT NULL DEFAULT '',
+		domain TEXT NOT NULL DEFAULT '',
+		type TEXT NOT NULL DEFAULT '',
+		status TEXT NOT NULL DEFAULT '',
+		renewal_eligibility TEXT NOT NULL DEFAULT '',
+		in_use BOOLEAN NOT NULL DEFAULT 0,
+		synced_at DATETIME NOT NULL
+	);`, "domain_id IN (SELECT id FROM domains)"},
+	{"stored_certificates", `
+	CREATE TABLE IF NOT EXISTS stored_certificates (
+		domain_id INTEGER PRIMARY KEY REFERENCES domains (id) ON DELETE CASCADE,
+		pem TEXT NOT NULL,
+		added_at DATETIME NOT NULL
+	);`, "domain_id IN (SELECT id FROM domains)"},
+	{"registration_alerts", `
+	CREATE TABLE IF NOT EXISTS registration_alerts (
+		registered_domain TEXT NOT NULL,
+		expiry_date DATETIME NOT NULL,
+		days_before INTEGER NOT NULL,
+		sent_at DATETIME NOT NULL,
+		PRIMARY KEY (registered_domain, expiry_date, days_before)
+	);`, ""},
+	{"maintenance_windows", `
+	CREATE TABLE IF NOT EXISTS maintenance_windows (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		domain_id INTEGER REFERENCES domains (id) ON DELETE CASCADE,
+		tag TEXT NOT NULL DEFAULT '',
+		start_at DATETIME,
+		schedule TEXT NOT NULL DEFAULT '',
+		duration_seconds INTEGER NOT NULL,
+		note TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL
+	);`, "user_id IN (SELECT id FROM users) AND (domain_id IS NULL OR domain_id IN (SELECT id FROM domains))"},
+	// activity outlives the domains it is about, so deletions stay in the feed
+	{"activity", `
+	CREATE TABLE IF NOT EXISTS activity (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		team_id INTEGER,
+		domain_id INTEGER NOT NULL,
+		domain_name TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		detail TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL
+	);`, "user_id IN (SELECT id FROM users)"},
+}
 
-	if _, err := db.Exec(domainsTable); err != nil {
-		return fmt.Errorf("failed to create domains table: %w", err)
-	}
+// sqliteIndexes are created after the tables, rebuilding a table drops its indexes
+var sqliteIndexes = []string{
+	`CREATE INDEX IF NOT EXISTS idx_check_history_domain ON check_history (domain_id, checked_at)`,
+	`CREATE INDEX IF NOT EXISTS idx_notifications_domain ON notifications (domain_id)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email)`,
+	`CREATE INDEX IF NOT EXISTS idx_notification_attempts_notification ON notification_attempts (notification_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_renewal_attempts_domain ON renewal_attempts (domain_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_activity_user ON activity (user_id, created_at)`,
+}
 
-	usersTable := `
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		username TEXT UNIQUE NOT NULL,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-	);`
+func runMigrations(db *sql.DB) error {
+	for _, table := range sqliteTables {
+		if _, err := db.Exec(table.schema); err != nil {
+			return fmt.Errorf("failed to create %s table: %w", table.name, err)
+		}
+	}
 
-	if _, err := db.Exec(usersTable); err != nil {
-		return fmt.Errorf("failed to create users table: %w", err)
+	// Columns added since the users and domains tables were first released
+	if err := addColumnIfMissing(db, "users", "email", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "users", "password_hash", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "users", "deactivated_at", "DATETIME"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "domains", "check_interval_seconds", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "domains", "check_schedule", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "domains", "tags", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "domains", "issuer", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "domains", "team_id", "INTEGER"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "domains", "fingerprint", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "check_history", "fingerprint", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "domains", "registration_expiry", "DATETIME"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "domains", "registration_checked", "DATETIME"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "domains", "dns_expectations", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "domains", "dns_error", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "domains", "dns_checked", "DATETIME"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "domains", "warning", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "domains", "auto_renew_days", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "domains", "auto_renew_via", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "domains", "cert_fingerprint", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "domains", "sans", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "domains", "next_check_at", "DATETIME"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "domains", "expected_sans", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "domains", "issued_at", "DATETIME"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "domains", "key_type", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "domains", "signatures", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "domains", "check_latency_ms", "INTEGER"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "user_settings", "time_display", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "user_settings", "columns", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "notifications", "escalated_from", "INTEGER"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "notifications", "attempts", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "notifications", "next_attempt_at", "DATETIME"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "notifications", "last_status_code", "INTEGER"); err != nil {
+		return err
 	}
 
 	defaultUser := `INSERT OR IGNORE INTO users (id, username) VALUES (1, 'default');`
@@ -70,21 +414,134 @@ func runMigrations(db *sql.DB) error {
 		return fmt.Errorf("failed to insert default user: %w", err)
 	}
 
+	// Tables created before foreign keys were introduced have to be rebuilt to get them
+	for _, table := range sqliteTables {
+		if table.parents == "" {
+			continue
+		}
+		if err := addForeignKeys(db, table.name, table.schema, table.parents); err != nil {
+			return err
+		}
+	}
+
+	if err := normalizeDomainNames(db); err != nil {
+		return err
+	}
+
+	for _, index := range sqliteIndexes {
+		if _, err := db.Exec(index); err != nil {
+			return fmt.Errorf("failed to create index: %w", err)
+		}
+	}
+
 	return nil
 }
 
-func GetConfigDir() (string, error) {
-	homeDir, err := os.UserHomeDir()
+// addForeignKeys rebuilds a table without foreign keys from its current schema, dropping rows whose parent is gone.
+//
+// SQLite can't add constraints to an existing table, so this follows its documented procedure:
+// copy into a new table with foreign keys switched off, then swap the tables
+func addForeignKeys(db *sql.DB, table, schema, parents string) error {
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_foreign_key_list(?)`, table).Scan(&count); err != nil {
+		return fmt.Errorf("failed to inspect %s foreign keys: %w", table, err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	columns, err := tableColumns(db, table)
 	if err != nil {
-		return "", err
+		return err
+	}
+	columnList := strings.Join(columns, ", ")
+	newTable := table + "_new"
+	newSchema := strings.Replace(schema, "CREATE TABLE IF NOT EXISTS "+table+" (", "CREATE TABLE "+newTable+" (", 1)
+
+	// foreign_keys is per connection and can't change inside a transaction
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	steps := []string{
+		`DROP TABLE IF EXISTS ` + newTable,
+		newSchema,
+		fmt.Sprintf(`INSERT INTO %s (%s) SELECT %s FROM %s WHERE %s`, newTable, columnList, columnList, table, parents),
+		`DROP TABLE ` + table,
+		fmt.Sprintf(`ALTER TABLE %s RENAME TO %s`, newTable, table),
+	}
+	for _, step := range steps {
+		if _, err := tx.ExecContext(ctx, step); err != nil {
+			return fmt.Errorf("failed to add foreign keys to %s table: %w", table, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to add foreign keys to %s table: %w", table, err)
 	}
-	return filepath.Join(homeDir, ".config", "sslcerttop"), nil
+
+	slog.Info("Added foreign keys", "table", table)
+	return nil
+}
+
+// tableColumns returns the column names of a table in order
+func tableColumns(db *sql.DB, table string) ([]string, error) {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect %s table: %w", table, err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to inspect %s table: %w", table, err)
+		}
+		columns = append(columns, name)
+	}
+	return columns, rows.Err()
 }
 
-func GetDefaultDBPath() (string, error) {
-	configDir, err := GetConfigDir()
+// addColumnIfMissing adds a column to an existing table so older databases pick up new fields
+func addColumnIfMissing(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
 	if err != nil {
-		return "", err
+		return fmt.Errorf("failed to inspect %s table: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return fmt.Errorf("failed to inspect %s table: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to inspect %s table: %w", table, err)
 	}
-	return filepath.Join(configDir, "sslcerttop.db"), nil
+	rows.Close()
+
+	query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to add %s.%s column: %w", table, column, err)
+	}
+	return nil
 }
@@ -0,0 +1,357 @@
+package database_test
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestInitSQLite_Pragmas - every pooled connection uses WAL, waits for locks and enforces foreign keys.
+func TestInitSQLite_Pragmas(t *testing.T) {
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	// Hold connections open so the checks below can't all reuse one
+	db.SetMaxIdleConns(4)
+	for i := 0; i < 4; i++ {
+		conn, err := db.Conn(t.Context())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		var journalMode string
+		var busyTimeout, foreignKeys int
+		require.NoError(t, conn.QueryRowContext(t.Context(), "PRAGMA journal_mode").Scan(&journalMode))
+		require.NoError(t, conn.QueryRowContext(t.Context(), "PRAGMA busy_timeout").Scan(&busyTimeout))
+		require.NoError(t, conn.QueryRowContext(t.Context(), "PRAGMA foreign_keys").Scan(&foreignKeys))
+		assert.Equal(t, "wal", journalMode)
+		assert.Equal(t, 5000, busyTimeout)
+		assert.Equal(t, 1, foreignKeys)
+	}
+}
+
+// TestWriter_Concurrent - parallel check results from many workers are all recorded without lock errors.
+func TestWriter_Concurrent(t *testing.T) {
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	repo := domain.NewRepository(db)
+	var ids []types.DomainID
+	for i := 0; i < 20; i++ {
+		d := domain.Domain{
+			UserID:     types.UserID(1),
+			DomainName: domain.NewDomainName(fmt.Sprintf("site%d.example.com", i)),
+			CreatedAt:  domain.NewCreatedAt(time.Now()),
+			IsActive:   true,
+		}
+		require.NoError(t, repo.CreateDomain(&d))
+		ids = append(ids, d.DomainID)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(ids)*10)
+	for _, id := range ids {
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				expiry := time.Now().Add(30 * 24 * time.Hour)
+				// A separate repository shares the lock through the database
+				errs <- domain.NewRepository(db).UpdateSSLInfo(id, &expiry, nil)
+			}()
+		}
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM check_history").Scan(&count))
+	assert.Equal(t, len(ids)*10, count)
+}
+
+// TestUpdateSSLInfoBatch - a batch stores every result with its history and skips deleted domains.
+func TestUpdateSSLInfoBatch(t *testing.T) {
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	repo := domain.NewRepository(db)
+	var updates []domain.SSLUpdate
+	checkErr := "connection refused"
+	for i := 0; i < 3; i++ {
+		d := domain.Domain{
+			UserID:     types.UserID(1),
+			DomainName: domain.NewDomainName(fmt.Sprintf("site%d.example.com", i)),
+			CreatedAt:  domain.NewCreatedAt(time.Now()),
+			IsActive:   true,
+		}
+		require.NoError(t, repo.CreateDomain(&d))
+		expiry := time.Now().Add(time.Duration(i+1) * 24 * time.Hour)
+		updates = append(updates, domain.SSLUpdate{DomainID: d.DomainID, ExpiryDate: &expiry, CheckedAt: time.Now()})
+	}
+	updates[2].ExpiryDate = nil
+	updates[2].Error = &checkErr
+	updates = append(updates, domain.SSLUpdate{DomainID: types.DomainID(999), Error: &checkErr, CheckedAt: time.Now()})
+
+	require.NoError(t, repo.UpdateSSLInfoBatch(updates))
+
+	for _, u := range updates[:3] {
+		d, err := repo.GetDomainByID(u.DomainID)
+		require.NoError(t, err)
+		require.NotNil(t, d.LastChecked)
+		assert.Equal(t, u.ExpiryDate != nil, d.ExpiryDate != nil)
+		assert.Equal(t, u.Error != nil, d.LastError != nil)
+
+		history, err := repo.GetCheckHistory(u.DomainID, 10)
+		require.NoError(t, err)
+		assert.Len(t, history, 1)
+	}
+
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM check_history").Scan(&count))
+	assert.Equal(t, 3, count, "no history for the missing domain")
+
+	// Failed checks keep the issuer of the last successful one
+	id := updates[0].DomainID
+	require.NoError(t, repo.UpdateSSLInfoBatch([]domain.SSLUpdate{{DomainID: id, ExpiryDate: updates[0].ExpiryDate, CheckedAt: time.Now(), Issuer: "R11"}}))
+	require.NoError(t, repo.UpdateSSLInfoBatch([]domain.SSLUpdate{{DomainID: id, Error: &checkErr, CheckedAt: time.Now()}}))
+	d, err := repo.GetDomainByID(id)
+	require.NoError(t, err)
+	assert.Equal(t, "R11", d.Issuer)
+}
+
+// TestUpdateNextChecks - planned checks survive a reload, missing domains are skipped.
+func TestUpdateNextChecks(t *testing.T) {
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	repo := domain.NewRepository(db)
+	d := domain.Domain{UserID: types.UserID(1), DomainName: domain.NewDomainName("example.com"), CreatedAt: domain.NewCreatedAt(time.Now()), IsActive: true}
+	require.NoError(t, repo.CreateDomain(&d))
+
+	next := time.Date(2025, time.March, 14, 10, 30, 0, 0, time.UTC)
+	require.NoError(t, repo.UpdateNextChecks(map[types.DomainID]time.Time{d.DomainID: next, types.DomainID(999): next}))
+
+	got, err := repo.GetDomainByID(d.DomainID)
+	require.NoError(t, err)
+	require.NotNil(t, got.NextCheckAt)
+	assert.True(t, next.Equal(*got.NextCheckAt))
+}
+
+// TestInitSQLite_AddsForeignKeys - tables from before foreign keys are rebuilt with them, orphans dropped and data kept.
+func TestInitSQLite_AddsForeignKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	// The schema as it was before foreign keys
+	old, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	for _, stmt := range []string{
+		`CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, username TEXT UNIQUE NOT NULL, created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP)`,
+		`CREATE TABLE domains (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, domain_name TEXT NOT NULL, created_at DATETIME NOT NULL,
+			expiry_date DATETIME, last_checked DATETIME, last_error TEXT, is_active BOOLEAN NOT NULL DEFAULT 1, UNIQUE(user_id, domain_name))`,
+		`CREATE TABLE check_history (id INTEGER PRIMARY KEY AUTOINCREMENT, domain_id INTEGER NOT NULL, checked_at DATETIME NOT NULL, expiry_date DATETIME, error TEXT)`,
+		`INSERT INTO users (id, username) VALUES (1, 'default')`,
+		`INSERT INTO domains (id, user_id, domain_name, created_at) VALUES (5, 1, 'example.com', CURRENT_TIMESTAMP)`,
+		`INSERT INTO check_history (domain_id, checked_at) VALUES (5, CURRENT_TIMESTAMP), (42, CURRENT_TIMESTAMP)`,
+	} {
+		_, err := old.Exec(stmt)
+		require.NoError(t, err, stmt)
+	}
+	require.NoError(t, old.Close())
+
+	db, err := database.InitSQLite(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	for _, table := range []string{"domains", "notifications", "check_history", "api_keys"} {
+		var count int
+		require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM pragma_foreign_key_list(?)`, table).Scan(&count))
+		assert.Equal(t, 1, count, table)
+	}
+
+	repo := domain.NewRepository(db)
+	d, err := repo.GetDomainByID(types.DomainID(5))
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", d.DomainName.String())
+
+	var history int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM check_history`).Scan(&history))
+	assert.Equal(t, 1, history, "orphaned history is dropped")
+
+	// Running the migrations again leaves the rebuilt tables alone
+	require.NoError(t, db.Close())
+	db, err = database.InitSQLite(path)
+	require.NoError(t, err)
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM check_history`).Scan(&history))
+	assert.Equal(t, 1, history)
+}
+
+// TestInitSQLite_NormalizesDomainNames - names differing by case or a trailing dot are merged into the oldest.
+func TestInitSQLite_NormalizesDomainNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := database.InitSQLite(path)
+	require.NoError(t, err)
+	for _, stmt := range []string{
+		`INSERT INTO domains (id, user_id, domain_name, created_at) VALUES (1, 1, 'Example.com', CURRENT_TIMESTAMP)`,
+		`INSERT INTO domains (id, user_id, domain_name, created_at) VALUES (2, 1, 'example.com.', CURRENT_TIMESTAMP)`,
+		`INSERT INTO domains (id, user_id, domain_name, created_at) VALUES (3, 1, 'example.com', CURRENT_TIMESTAMP)`,
+		`INSERT INTO domains (id, user_id, domain_name, created_at) VALUES (4, 1, 'Other.ORG', CURRENT_TIMESTAMP)`,
+		`INSERT INTO check_history (domain_id, checked_at) VALUES (1, CURRENT_TIMESTAMP), (2, CURRENT_TIMESTAMP), (3, CURRENT_TIMESTAMP)`,
+	} {
+		_, err := db.Exec(stmt)
+		require.NoError(t, err, stmt)
+	}
+	require.NoError(t, db.Close())
+
+	db, err = database.InitSQLite(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	rows, err := db.Query(`SELECT id, domain_name FROM domains ORDER BY id`)
+	require.NoError(t, err)
+	defer rows.Close()
+	names := map[int]string{}
+	for rows.Next() {
+		var id int
+		var name string
+		require.NoError(t, rows.Scan(&id, &name))
+		names[id] = name
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, map[int]string{1: "example.com", 4: "other.org"}, names)
+
+	var history int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM check_history WHERE domain_id = 1`).Scan(&history))
+	assert.Equal(t, 3, history, "the duplicates' history is kept")
+}
+
+// TestInitSQLite_NormalizeMergesDomainRecords - what was recorded about a duplicate moves to the domain kept rather
+// than cascading away with it.
+func TestInitSQLite_NormalizeMergesDomainRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := database.InitSQLite(path)
+	require.NoError(t, err)
+	for _, stmt := range []string{
+		`INSERT INTO domains (id, user_id, domain_name, created_at) VALUES (1, 1, 'example.com', CURRENT_TIMESTAMP)`,
+		`INSERT INTO domains (id, user_id, domain_name, created_at) VALUES (2, 1, 'Example.com', CURRENT_TIMESTAMP)`,
+		`INSERT INTO open_alerts (domain_id, provider, dedup_key, triggered_at) VALUES (1, 'pagerduty', 'sslcerttop-domain-1', CURRENT_TIMESTAMP)`,
+		`INSERT INTO open_alerts (domain_id, provider, dedup_key, triggered_at) VALUES (2, 'opsgenie', 'sslcerttop-domain-2', CURRENT_TIMESTAMP)`,
+		`INSERT INTO domain_acks (domain_id, note, created_at) VALUES (2, 'replacing the load balancer', CURRENT_TIMESTAMP)`,
+		`INSERT INTO stored_certificates (domain_id, pem, added_at) VALUES (1, 'kept', CURRENT_TIMESTAMP)`,
+		`INSERT INTO stored_certificates (domain_id, pem, added_at) VALUES (2, 'duplicate', CURRENT_TIMESTAMP)`,
+		`INSERT INTO cloud_certificates (domain_id, provider, synced_at) VALUES (2, 'aws', CURRENT_TIMESTAMP)`,
+		`INSERT INTO renewal_attempts (domain_id, actions, triggered_at) VALUES (2, 'command', CURRENT_TIMESTAMP)`,
+		`INSERT INTO maintenance_windows (user_id, domain_id, duration_seconds, created_at) VALUES (1, 2, 3600, CURRENT_TIMESTAMP)`,
+		`INSERT INTO activity (user_id, domain_id, domain_name, kind, created_at) VALUES (1, 2, 'Example.com', 'added', CURRENT_TIMESTAMP)`,
+	} {
+		_, err := db.Exec(stmt)
+		require.NoError(t, err, stmt)
+	}
+	require.NoError(t, db.Close())
+
+	db, err = database.InitSQLite(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	for table, want := range map[string]int{
+		"open_alerts":         2,
+		"domain_acks":         1,
+		"stored_certificates": 1,
+		"cloud_certificates":  1,
+		"renewal_attempts":    1,
+		"maintenance_windows": 1,
+		"activity":            1,
+	} {
+		var count int
+		require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM `+table+` WHERE domain_id = 1`).Scan(&count))
+		assert.Equal(t, want, count, table)
+	}
+	var pem string
+	require.NoError(t, db.QueryRow(`SELECT pem FROM stored_certificates WHERE domain_id = 1`).Scan(&pem))
+	assert.Equal(t, "kept", pem, "The kept domain's certificate wins")
+}
+
+// TestDeleteDomain_Cascades - deleting a domain removes its history and notifications.
+func TestDeleteDomain_Cascades(t *testing.T) {
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	repo := domain.NewRepository(db)
+	d := domain.Domain{
+		UserID:     types.UserID(1),
+		DomainName: domain.NewDomainName("example.com"),
+		CreatedAt:  domain.NewCreatedAt(time.Now()),
+		IsActive:   true,
+	}
+	require.NoError(t, repo.CreateDomain(&d))
+	expiry := time.Now().Add(24 * time.Hour)
+	require.NoError(t, repo.UpdateSSLInfo(d.DomainID, &expiry, nil))
+	_, err = db.Exec(`INSERT INTO notifications (domain_id, days_before, notification_type, created_at) VALUES (?, 7, 'email', ?)`, d.DomainID.Uint(), time.Now())
+	require.NoError(t, err)
+
+	require.NoError(t, repo.DeleteDomain(d.DomainID))
+
+	for _, table := range []string{"check_history", "notifications"} {
+		var count int
+		require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM `+table).Scan(&count))
+		assert.Zero(t, count, table)
+	}
+
+	_, err = db.Exec(`INSERT INTO check_history (domain_id, checked_at) VALUES (999, ?)`, time.Now())
+	assert.Error(t, err, "history for a missing domain is rejected")
+}
+
+// TestPruneCheckHistory - only entries older than the retention period are deleted.
+func TestPruneCheckHistory(t *testing.T) {
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	repo := domain.NewRepository(db)
+	d := domain.Domain{
+		UserID:     types.UserID(1),
+		DomainName: domain.NewDomainName("example.com"),
+		CreatedAt:  domain.NewCreatedAt(time.Now()),
+		IsActive:   true,
+	}
+	require.NoError(t, repo.CreateDomain(&d))
+
+	var updates []domain.SSLUpdate
+	for _, age := range []int{200, 100, 91, 89, 1} {
+		updates = append(updates, domain.SSLUpdate{DomainID: d.DomainID, CheckedAt: time.Now().AddDate(0, 0, -age)})
+	}
+	require.NoError(t, repo.UpdateSSLInfoBatch(updates))
+
+	service := domain.NewService(repo, nil)
+	retention := 90 * 24 * time.Hour
+
+	count, err := service.PruneCheckHistory(retention, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	deleted, err := service.PruneCheckHistory(retention, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+
+	history, err := repo.GetCheckHistory(d.DomainID, 10)
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+
+	_, err = service.PruneCheckHistory(0, false)
+	assert.Error(t, err)
+}
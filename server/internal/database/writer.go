@@ -0,0 +1,49 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+// writeLocks holds one lock per database so every repository of a database shares it
+var writeLocks sync.Map // *sql.DB -> *sync.Mutex
+
+// Writer serializes writes to a database.
+//
+// SQLite allows a single writer at a time, concurrent writers otherwise wait out busy_timeout
+// or fail with "database is locked". Reads don't need the Writer, WAL lets them run alongside a write
+type Writer struct {
+	db *sql.DB
+	mu *sync.Mutex
+}
+
+// NewWriter returns a Writer for db sharing its lock with every other Writer of db
+func NewWriter(db *sql.DB) *Writer {
+	mu, _ := writeLocks.LoadOrStore(db, &sync.Mutex{})
+	return &Writer{db: db, mu: mu.(*sync.Mutex)}
+}
+
+// Exec runs a single write statement
+func (w *Writer) Exec(query string, args ...any) (sql.Result, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.db.Exec(query, args...)
+}
+
+// Transaction runs fn in a transaction, committing when it returns nil and rolling back otherwise
+func (w *Writer) Transaction(fn func(tx *sql.Tx) error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	tx, err := w.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
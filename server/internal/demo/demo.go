@@ -0,0 +1,246 @@
+// Package demo fills an in-memory tracker with made up domains and checks them with a fake checker, so the TUI can
+// be shown without network access or a real database
+package demo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/notification"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/samokw/ssl_tracker/internal/user"
+)
+
+// Site is a made up domain and the certificate it serves
+type Site struct {
+	Name   string
+	Tags   []string
+	Issuer string
+	// DaysLeft is how long the certificate has left when the demo starts
+	DaysLeft int
+	// Lifetime is how long the site's certificates last, they were renewed a third of it before expiring
+	Lifetime int
+	// Error is what checking the site fails with, empty when it succeeds
+	Error string
+	// Signature is the signature algorithm of the site's certificate, SHA256-RSA when empty
+	Signature string
+}
+
+// Sites are the domains the demo starts with, covering every status
+var Sites = []Site{
+	{Name: "www.example.com", Tags: []string{"prod", "web"}, Issuer: "R11", DaysLeft: 74, Lifetime: 90},
+	{Name: "api.example.com", Tags: []string{"prod", "api"}, Issuer: "R11", DaysLeft: 61, Lifetime: 90},
+	{Name: "shop.example.com", Tags: []string{"prod", "web"}, Issuer: "DigiCert Global G2 TLS RSA SHA256 2020 CA1", DaysLeft: 212, Lifetime: 397},
+	{Name: "status.example.com", Tags: []string{"prod"}, Issuer: "E6", DaysLeft: 23, Lifetime: 90},
+	{Name: "mail.example.com", Tags: []string{"prod", "mail"}, Issuer: "Sectigo RSA Domain Validation Secure Server CA", DaysLeft: 5, Lifetime: 365},
+	{Name: "staging.example.com", Tags: []string{"staging"}, Issuer: "R10", DaysLeft: 12, Lifetime: 90},
+	{Name: "legacy.example.org", Tags: []string{"legacy"}, Issuer: "GlobalSign RSA OV SSL CA 2018", DaysLeft: -9, Lifetime: 365, Signature: "SHA1-RSA"},
+	{Name: "vpn.example.org", Tags: []string{"infra"}, Issuer: "R11", DaysLeft: 40, Lifetime: 90, Error: "dial tcp 192.0.2.10:443: i/o timeout"},
+	{Name: "intranet.example.net", Tags: []string{"infra"}, Issuer: "Example Corp Issuing CA", DaysLeft: 130, Lifetime: 730,
+		Error: "tls: failed to verify certificate: x509: certificate signed by unknown authority"},
+	{Name: "old.example.net", Tags: []string{"legacy"}, Error: "lookup old.example.net: no such host"},
+}
+
+// issuers are given to domains added during the demo
+var issuers = []string{"R10", "R11", "E5", "E6", "Sectigo RSA Domain Validation Secure Server CA"}
+
+// historyDays is how far back the seeded check history goes, and historyStep how often it was checked
+const (
+	historyDays = 240
+	historyStep = 3
+)
+
+// Checker makes up the certificates of the demo's sites, and of any other name from a hash of it
+type Checker struct {
+	sites map[string]Site
+	start time.Time
+}
+
+// NewChecker checks the sites as of start
+func NewChecker(sites []Site, start time.Time) *Checker {
+	c := &Checker{sites: map[string]Site{}, start: start}
+	for _, s := range sites {
+		c.sites[s.Name] = s
+	}
+	return c
+}
+
+// CheckSSLCertificate returns the made up certificate of a name, or the error it is made up to fail with
+func (c *Checker) CheckSSLCertificate(_ context.Context, name string) (*ssl.SSLCertificate, error) {
+	site, ok := c.sites[name]
+	if !ok {
+		h := fnv.New32a()
+		h.Write([]byte(name))
+		sum := int(h.Sum32())
+		site = Site{Name: name, Issuer: issuers[sum%len(issuers)], DaysLeft: 15 + sum%300}
+	}
+	if site.Error != "" {
+		return nil, errors.New(site.Error)
+	}
+	expiry := c.start.AddDate(0, 0, site.DaysLeft)
+	signature := site.Signature
+	if signature == "" {
+		signature = "SHA256-RSA"
+	}
+	return &ssl.SSLCertificate{
+		Hostname:   ssl.Hostname(name),
+		ExpiryDate: types.NewExpiryDate(expiry),
+		TimeLeft:   ssl.TimeLeft(int(time.Until(expiry).Hours() / 24)),
+		Issuer:     site.Issuer,
+		SANs:       []string{name},
+		IssuedAt:   expiry.AddDate(0, 0, -max(site.Lifetime, 90)),
+		KeyType:    "RSA 2048",
+		Signatures: []string{signature},
+	}, nil
+}
+
+// ValidateTarget accepts any valid hostname without resolving it
+func (c *Checker) ValidateTarget(name string) error {
+	_, err := ssl.NewHostname(name)
+	return err
+}
+
+// Seed tracks the sites for the default user with a made up check history, so history, triage and cadence views
+// have something to show
+func Seed(repo domain.DomainRepository, sites []Site, now time.Time) error {
+	for _, s := range sites {
+		d := domain.Domain{
+			UserID:     user.DefaultUserID,
+			DomainName: domain.NewDomainName(s.Name),
+			CreatedAt:  domain.NewCreatedAt(now.AddDate(0, 0, -historyDays)),
+			IsActive:   true,
+		}
+		if err := repo.CreateDomain(&d); err != nil {
+			return err
+		}
+		if err := repo.UpdateTags(d.DomainID, s.Tags); err != nil {
+			return err
+		}
+		if err := repo.UpdateSSLInfoBatch(history(d.DomainID, s, now)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// history makes up a site's checks, oldest first. Certificates were renewed a third of their lifetime before
+// expiring, and a failing site started failing a few checks ago
+func history(id types.DomainID, s Site, now time.Time) []domain.SSLUpdate {
+	var updates []domain.SSLUpdate
+	for days := historyDays; days >= 0; days -= historyStep {
+		checkedAt := now.AddDate(0, 0, -days)
+		update := domain.SSLUpdate{DomainID: id, CheckedAt: checkedAt, Issuer: s.Issuer}
+		if s.Error != "" && days < 4*historyStep {
+			msg := s.Error
+			update.Error = &msg
+		} else if s.Lifetime > 0 {
+			update.ExpiryDate = servedExpiry(s, now, checkedAt)
+		} else {
+			continue
+		}
+		updates = append(updates, update)
+	}
+	return updates
+}
+
+// servedExpiry is the expiry of the certificate a site served at a time, counting back from the current one
+func servedExpiry(s Site, now, at time.Time) *time.Time {
+	expiry := now.AddDate(0, 0, s.DaysLeft)
+	renewedAt := expiry.AddDate(0, 0, -s.Lifetime)
+	for at.Before(renewedAt) {
+		// The certificate before was replaced when it had a third of its lifetime left
+		expiry = renewedAt.AddDate(0, 0, s.Lifetime/3)
+		renewedAt = expiry.AddDate(0, 0, -s.Lifetime)
+	}
+	return &expiry
+}
+
+// Notifications are the notifications of the demo, made up from its sites
+type Notifications struct {
+	notifications []notification.Notification
+}
+
+// NewNotifications makes up notifications for the sites close to expiring, as of now
+func NewNotifications(repo domain.DomainRepository, now time.Time) (*Notifications, error) {
+	domains, err := repo.GetDomainsByUserID(user.DefaultUserID)
+	if err != nil {
+		return nil, err
+	}
+	n := &Notifications{}
+	for _, d := range domains {
+		expiry := d.ExpiryTime()
+		if expiry == nil || expiry.Sub(now) > 30*24*time.Hour {
+			continue
+		}
+		sent := now.Add(-time.Duration(len(n.notifications)+1) * time.Hour)
+		n.notifications = append(n.notifications, notification.Notification{
+			NotificationID:   uint(len(n.notifications) + 1),
+			DomainID:         d.DomainID,
+			DomainName:       d.DomainName.String(),
+			ExpiryDate:       expiry,
+			DaysBefore:       max(0, int(expiry.Sub(now).Hours()/24)),
+			NotificationType: notification.NotificationTypeSlack,
+			Status:           notification.StatusSent,
+			CreatedAt:        sent,
+			SentAt:           &sent,
+		})
+	}
+	return n, nil
+}
+
+func (n *Notifications) GetUsersNotifications(types.UserID) ([]notification.Notification, error) {
+	return append([]notification.Notification(nil), n.notifications...), nil
+}
+
+// Resend pretends to send a notification again
+func (n *Notifications) Resend(id uint) error {
+	return n.update(id, func(notif *notification.Notification) {
+		now := time.Now()
+		notif.Status, notif.SentAt = notification.StatusSent, &now
+	})
+}
+
+func (n *Notifications) Acknowledge(id uint) error {
+	return n.update(id, func(notif *notification.Notification) {
+		now := time.Now()
+		notif.Status, notif.AcknowledgedAt = notification.StatusAcknowledged, &now
+	})
+}
+
+func (n *Notifications) GetUsersAcks(types.UserID) ([]notification.DomainAck, error) {
+	return nil, nil
+}
+
+func (n *Notifications) update(id uint, change func(*notification.Notification)) error {
+	for i := range n.notifications {
+		if n.notifications[i].NotificationID == id {
+			change(&n.notifications[i])
+			return nil
+		}
+	}
+	return fmt.Errorf("notification with ID %d not found", id)
+}
+
+// Open seeds an in-memory tracker with the sites, checked with a Checker from then on. Stop the returned
+// CertService when done
+func Open(sites []Site, workers int, timeout time.Duration) (*domain.Service, *Notifications, *ssl.CertService, error) {
+	now := time.Now()
+
+	repo := domain.NewMemoryRepository()
+	if err := Seed(repo, sites, now); err != nil {
+		return nil, nil, nil, err
+	}
+	notifications, err := NewNotifications(repo, now)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	sslService := ssl.NewCertServiceWithPool(workers, timeout)
+	domains := domain.NewService(repo, sslService)
+	domains.SetChecker(NewChecker(sites, now))
+	return domains, notifications, sslService, nil
+}
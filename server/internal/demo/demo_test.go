@@ -0,0 +1,54 @@
+package demo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSeed - the seeded domains cover every status and have a history to learn renewals from.
+func TestSeed(t *testing.T) {
+	now := time.Now()
+	repo := domain.NewMemoryRepository()
+	require.NoError(t, Seed(repo, Sites, now))
+
+	domains, err := repo.GetDomainsByUserID(user.DefaultUserID)
+	require.NoError(t, err)
+	require.Len(t, domains, len(Sites))
+	statuses := map[string]bool{}
+	for _, d := range domains {
+		statuses[d.Status()] = true
+	}
+	for _, status := range []string{"valid", "soon", "warning", "expired", "error"} {
+		assert.True(t, statuses[status], "A domain is %s", status)
+	}
+
+	www, err := repo.CheckForDuplicateDomains(user.DefaultUserID, "www.example.com")
+	require.NoError(t, err)
+	require.NotNil(t, www)
+	history, err := repo.GetExpiryHistory(www.DomainID)
+	require.NoError(t, err)
+	cadence := domain.RenewalCadence(history, www.ExpiryTime())
+	assert.NotEmpty(t, cadence.Renewals)
+	assert.InDelta(t, 60, cadence.Interval.Hours()/24, historyStep)
+}
+
+// TestChecker - sites fail the way they are made up to, other names get a certificate that stays the same.
+func TestChecker(t *testing.T) {
+	c := NewChecker(Sites, time.Now())
+
+	_, err := c.CheckSSLCertificate(context.Background(), "old.example.net")
+	assert.ErrorContains(t, err, "no such host")
+
+	first, err := c.CheckSSLCertificate(context.Background(), "new.example.com")
+	require.NoError(t, err)
+	again, err := c.CheckSSLCertificate(context.Background(), "new.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, first.ExpiryDate, again.ExpiryDate)
+	assert.NotEmpty(t, first.Issuer)
+}
@@ -0,0 +1,81 @@
+package dnscheck
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/samokw/ssl_tracker/internal/ssl"
+)
+
+// caIdentifiers maps a word in the organization name of well known CAs, as their certificates carry it, to the
+// issuer domains their CAA records are written with
+var caIdentifiers = map[string][]string{
+	"let's encrypt":         {"letsencrypt.org"},
+	"digicert":              {"digicert.com", "symantec.com", "thawte.com", "geotrust.com", "rapidssl.com", "digicert.ne.jp"},
+	"thawte":                {"thawte.com", "digicert.com"},
+	"geotrust":              {"geotrust.com", "digicert.com"},
+	"rapidssl":              {"rapidssl.com", "digicert.com"},
+	"cloudflare":            {"digicert.com", "cloudflare.com"},
+	"sectigo":               {"sectigo.com", "comodoca.com", "comodo.com"},
+	"comodo":                {"comodoca.com", "comodo.com", "sectigo.com"},
+	"zerossl":               {"sectigo.com", "zerossl.com"},
+	"google trust services": {"pki.goog"},
+	"amazon":                {"amazon.com", "amazontrust.com", "awstrust.com", "amazonaws.com"},
+	"globalsign":            {"globalsign.com"},
+	"microsoft":             {"microsoft.com"},
+	"entrust":               {"entrust.net", "affirmtrust.com"},
+	"godaddy":               {"godaddy.com", "starfieldtech.com"},
+	"starfield":             {"starfieldtech.com", "godaddy.com"},
+	"buypass":               {"buypass.com", "buypass.no"},
+	"ssl corporation":       {"ssl.com"},
+	"identrust":             {"identrust.com"},
+	"actalis":               {"actalis.it"},
+	"asseco":                {"certum.pl", "certum.eu"},
+	"certum":                {"certum.pl", "certum.eu"},
+	"harica":                {"harica.gr"},
+	"hellenic academic":     {"harica.gr"},
+}
+
+// CAIdentifiers are the issuer domains CAA records authorize the CA with this organization name by, none when
+// the CA isn't known
+func CAIdentifiers(organization string) []string {
+	organization = strings.ToLower(organization)
+	var identifiers []string
+	for word, ids := range caIdentifiers {
+		if strings.Contains(organization, word) {
+			identifiers = append(identifiers, ids...)
+		}
+	}
+	slices.Sort(identifiers)
+	return slices.Compact(identifiers)
+}
+
+// VerifyIssuer describes why the CAA records of hostname don't authorize the CA that issued its certificate, the
+// sign of a certificate issued by mistake or by an attacker. It is empty when they do, when there are no CAA
+// records and any CA may issue, and when it can't tell because the CA isn't known or the lookup failed.
+//
+// It is an ssl.IssuerVerifier
+func (r *Resolver) VerifyIssuer(ctx context.Context, hostname ssl.Hostname, organization string) string {
+	identifiers := CAIdentifiers(organization)
+	if len(identifiers) == 0 {
+		return ""
+	}
+	authorized, err := r.issuers(ctx, hostname.String())
+	if err != nil {
+		slog.Debug("Failed to look up CAA records", "hostname", hostname, "error", err)
+		return ""
+	}
+	if len(authorized) == 0 {
+		return ""
+	}
+	for _, id := range identifiers {
+		if slices.Contains(authorized, id) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("certificate issued by %s, which the CAA records don't authorize (they authorize %s)",
+		organization, strings.Join(authorized, ", "))
+}
@@ -0,0 +1,231 @@
+// Package dnscheck verifies that the DNS records of tracked names are the ones expected of them, so a name
+// pointed somewhere else, or whose CAA records let other CAs issue for it, gets flagged
+package dnscheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+)
+
+// lookupTimeout limits each query sent to a DNS server
+const lookupTimeout = 5 * time.Second
+
+// resolvConf lists the system's nameservers
+const resolvConf = "/etc/resolv.conf"
+
+// noCA stands for the issuers of CAA records that authorize no CA at all
+const noCA = "no CA"
+
+var (
+	// ErrNoRecords is returned for tracked names that have no DNS records of their own, e.g. files and IPs
+	ErrNoRecords = errors.New("name has no DNS records to check")
+	// ErrMismatch is wrapped by every difference between the records found and those expected
+	ErrMismatch = errors.New("DNS mismatch")
+)
+
+// Hostname is the name whose records are checked for a tracked name, the host of SSH targets
+func Hostname(name string) (string, error) {
+	host := name
+	if ssl.IsSSHTarget(name) {
+		var err error
+		if host, _, err = ssl.SSHAddress(name); err != nil {
+			return "", err
+		}
+	} else if strings.Contains(name, "://") {
+		return "", ErrNoRecords
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if net.ParseIP(host) != nil || ssl.ValidateHostname(host) != nil {
+		return "", ErrNoRecords
+	}
+	return host, nil
+}
+
+// Resolver looks records up from DNS servers
+type Resolver struct {
+	client  *dns.Client
+	servers []string
+}
+
+// NewResolver asks server, given as host or host:port, or the system's nameservers when it is empty
+func NewResolver(server string) (*Resolver, error) {
+	var servers []string
+	if server != "" {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+		}
+		servers = []string{server}
+	} else {
+		conf, err := dns.ClientConfigFromFile(resolvConf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the system's nameservers: %w", err)
+		}
+		for _, s := range conf.Servers {
+			servers = append(servers, net.JoinHostPort(s, conf.Port))
+		}
+	}
+	if len(servers) == 0 {
+		return nil, errors.New("no DNS server to ask")
+	}
+	return &Resolver{client: &dns.Client{Timeout: lookupTimeout}, servers: servers}, nil
+}
+
+// Check looks up the records of hostname of every type expected and returns how they differ, wrapping
+// ErrMismatch, or nil when they match
+func (r *Resolver) Check(ctx context.Context, hostname string, expectations []domain.DNSExpectation) error {
+	expected := make(map[string][]string)
+	var recordTypes []string
+	for _, e := range expectations {
+		if _, ok := expected[e.Type]; !ok {
+			recordTypes = append(recordTypes, e.Type)
+		}
+		expected[e.Type] = append(expected[e.Type], e.Value)
+	}
+
+	var errs []error
+	for _, recordType := range recordTypes {
+		var found []string
+		var err error
+		if recordType == domain.DNSRecordCAA {
+			found, err = r.issuers(ctx, hostname)
+		} else {
+			found, err = r.values(ctx, hostname, recordType)
+		}
+		if err != nil {
+			return err
+		}
+		if err := compare(recordType, found, expected[recordType]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// compare describes how the values of the records found differ from those expected
+func compare(recordType string, found, expected []string) error {
+	slices.Sort(found)
+	found = slices.Compact(found)
+	slices.Sort(expected)
+	if slices.Equal(found, expected) {
+		return nil
+	}
+	if recordType == domain.DNSRecordCAA {
+		if len(found) == 0 {
+			return fmt.Errorf("%w: no CAA record restricts issuance, so any CA may issue instead of only %s",
+				ErrMismatch, strings.Join(expected, ", "))
+		}
+		return fmt.Errorf("%w: CAA authorizes %s instead of %s", ErrMismatch, strings.Join(found, ", "), strings.Join(expected, ", "))
+	}
+	if len(found) == 0 {
+		return fmt.Errorf("%w: no %s record, expected %s", ErrMismatch, recordType, strings.Join(expected, ", "))
+	}
+	return fmt.Errorf("%w: %s is %s instead of %s", ErrMismatch, recordType, strings.Join(found, ", "), strings.Join(expected, ", "))
+}
+
+// values are the A or AAAA addresses of a name, following CNAMEs, or the target of its CNAME
+func (r *Resolver) values(ctx context.Context, name, recordType string) ([]string, error) {
+	qtype := dns.StringToType[recordType]
+	answer, err := r.lookup(ctx, name, qtype)
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	for _, rr := range answer {
+		switch rr := rr.(type) {
+		case *dns.A:
+			values = append(values, rr.A.String())
+		case *dns.AAAA:
+			values = append(values, rr.AAAA.String())
+		case *dns.CNAME:
+			// A CNAME query only answers with the name's own record
+			if qtype == dns.TypeCNAME && strings.EqualFold(rr.Hdr.Name, dns.Fqdn(name)) {
+				values = append(values, strings.ToLower(strings.TrimSuffix(rr.Target, ".")))
+			}
+		}
+	}
+	return values, nil
+}
+
+// issuers are the CAs the CAA records relevant to a name authorize to issue for it, found as RFC 8659 does by
+// climbing towards the top-level domain until a name has CAA records.
+//
+// None means any CA may issue, while records without an issuer, like `0 issue ";"`, authorize no CA and are
+// reported as such
+func (r *Resolver) issuers(ctx context.Context, name string) ([]string, error) {
+	labels := strings.Split(name, ".")
+	for i := 0; i < len(labels)-1; i++ {
+		answer, err := r.lookup(ctx, strings.Join(labels[i:], "."), dns.TypeCAA)
+		if err != nil {
+			return nil, err
+		}
+		var records []*dns.CAA
+		for _, rr := range answer {
+			if caa, ok := rr.(*dns.CAA); ok {
+				records = append(records, caa)
+			}
+		}
+		if len(records) == 0 {
+			continue
+		}
+
+		var issuers []string
+		restricted := false
+		for _, caa := range records {
+			if !strings.EqualFold(caa.Tag, "issue") {
+				continue
+			}
+			restricted = true
+			issuer, _, _ := strings.Cut(caa.Value, ";")
+			if issuer = strings.ToLower(strings.TrimSpace(issuer)); issuer != "" {
+				issuers = append(issuers, issuer)
+			}
+		}
+		if restricted && len(issuers) == 0 {
+			return []string{noCA}, nil
+		}
+		return issuers, nil
+	}
+	return nil, nil
+}
+
+// lookup asks each server in turn for the records of a name until one answers, retrying over TCP when the
+// answer doesn't fit in UDP. A name that doesn't exist has no records
+func (r *Resolver) lookup(ctx context.Context, name string, qtype uint16) ([]dns.RR, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+
+	var errs []error
+	for _, server := range r.servers {
+		resp, _, err := r.client.ExchangeContext(ctx, msg, server)
+		if err == nil && resp.Truncated {
+			tcp := *r.client
+			tcp.Net = "tcp"
+			resp, _, err = tcp.ExchangeContext(ctx, msg, server)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			errs = append(errs, err)
+			continue
+		}
+		switch resp.Rcode {
+		case dns.RcodeSuccess:
+			return resp.Answer, nil
+		case dns.RcodeNameError:
+			return nil, nil
+		default:
+			errs = append(errs, fmt.Errorf("%s answered %s", server, dns.RcodeToString[resp.Rcode]))
+		}
+	}
+	return nil, fmt.Errorf("failed to look up %s records of %s: %w", dns.TypeToString[qtype], name, errors.Join(errs...))
+}
@@ -0,0 +1,192 @@
+package dnscheck
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveDNS answers queries from records in zone file syntax, and returns a resolver asking it
+func serveDNS(t *testing.T, records ...string) *Resolver {
+	t.Helper()
+
+	var zone []dns.RR
+	for _, record := range records {
+		rr, err := dns.NewRR(record)
+		require.NoError(t, err)
+		zone = append(zone, rr)
+	}
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		q := req.Question[0]
+		name, exists := q.Name, false
+		// Follow CNAMEs the way a recursive resolver does, except when asked for the CNAME itself
+		for hops := 0; hops < 8; hops++ {
+			var cname *dns.CNAME
+			for _, rr := range zone {
+				if !strings.EqualFold(rr.Header().Name, name) {
+					continue
+				}
+				exists = true
+				if c, ok := rr.(*dns.CNAME); ok && q.Qtype != dns.TypeCNAME {
+					cname = c
+				}
+				if rr.Header().Rrtype == q.Qtype || cname != nil {
+					resp.Answer = append(resp.Answer, rr)
+				}
+			}
+			if cname == nil {
+				break
+			}
+			name = cname.Target
+		}
+		if !exists {
+			resp.Rcode = dns.RcodeNameError
+		}
+		w.WriteMsg(resp)
+	})}
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+
+	resolver, err := NewResolver(pc.LocalAddr().String())
+	require.NoError(t, err)
+	return resolver
+}
+
+// expect parses expectations in TYPE=value form
+func expect(t *testing.T, values ...string) []domain.DNSExpectation {
+	t.Helper()
+	expectations, err := domain.NormalizeDNSExpectations(values)
+	require.NoError(t, err)
+	return expectations
+}
+
+// TestHostname - SSH targets are checked by their host, files, cloud certificates and IPs not at all.
+func TestHostname(t *testing.T) {
+	for name, want := range map[string]string{
+		"www.Example.com.":          "www.example.com",
+		"ssh://bastion.example.com": "bastion.example.com",
+	} {
+		got, err := Hostname(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, got, name)
+	}
+
+	for _, name := range []string{"192.0.2.1", "file:///etc/ssl/cert.pem", "acm://us-east-1/abc"} {
+		_, err := Hostname(name)
+		assert.ErrorIs(t, err, ErrNoRecords, name)
+	}
+}
+
+// TestResolver_Check - records have to be exactly the ones expected, and CAA records are found on parent names.
+func TestResolver_Check(t *testing.T) {
+	resolver := serveDNS(t,
+		"example.com. 300 IN A 192.0.2.1",
+		"example.com. 300 IN CAA 0 issue \"letsencrypt.org; validationmethods=dns-01\"",
+		"example.com. 300 IN CAA 0 iodef \"mailto:security@example.com\"",
+		"www.example.com. 300 IN CNAME example.com.",
+		"two.example.com. 300 IN A 192.0.2.1",
+		"two.example.com. 300 IN A 192.0.2.2",
+		"example.net. 300 IN A 198.51.100.1",
+		"locked.example.org. 300 IN CAA 0 issue \";\"",
+	)
+	ctx := context.Background()
+
+	assert.NoError(t, resolver.Check(ctx, "example.com", expect(t, "A=192.0.2.1", "CAA=letsencrypt.org")))
+	assert.NoError(t, resolver.Check(ctx, "www.example.com", expect(t, "CNAME=example.com", "A=192.0.2.1", "CAA=letsencrypt.org")))
+
+	err := resolver.Check(ctx, "two.example.com", expect(t, "A=192.0.2.1"))
+	assert.ErrorIs(t, err, ErrMismatch)
+	assert.ErrorContains(t, err, "A is 192.0.2.1, 192.0.2.2 instead of 192.0.2.1")
+
+	err = resolver.Check(ctx, "www.example.com", expect(t, "CAA=digicert.com", "AAAA=2001:db8::1"))
+	assert.ErrorContains(t, err, "CAA authorizes letsencrypt.org instead of digicert.com")
+	assert.ErrorContains(t, err, "no AAAA record, expected 2001:db8::1")
+
+	err = resolver.Check(ctx, "example.net", expect(t, "CAA=letsencrypt.org"))
+	assert.ErrorContains(t, err, "any CA may issue")
+	err = resolver.Check(ctx, "locked.example.org", expect(t, "CAA=letsencrypt.org"))
+	assert.ErrorContains(t, err, "CAA authorizes no CA")
+	err = resolver.Check(ctx, "missing.example.com", expect(t, "A=192.0.2.1"))
+	assert.ErrorContains(t, err, "no A record")
+}
+
+// TestCAIdentifiers - well known CAs are found by their organization, unknown ones aren't guessed.
+func TestCAIdentifiers(t *testing.T) {
+	assert.Equal(t, []string{"letsencrypt.org"}, CAIdentifiers("Let's Encrypt"))
+	assert.Contains(t, CAIdentifiers("DigiCert Inc"), "digicert.com")
+	assert.Equal(t, []string{"pki.goog"}, CAIdentifiers("Google Trust Services LLC"))
+	assert.Empty(t, CAIdentifiers("Example Internal CA"))
+}
+
+// TestResolver_VerifyIssuer - a CA the CAA records don't authorize is warned about, anything it can't tell isn't.
+func TestResolver_VerifyIssuer(t *testing.T) {
+	resolver := serveDNS(t,
+		"example.com. 300 IN CAA 0 issue \"letsencrypt.org\"",
+		"example.com. 300 IN CAA 0 issuewild \"digicert.com\"",
+		"shop.example.com. 300 IN A 192.0.2.1",
+		"example.net. 300 IN A 198.51.100.1",
+	)
+	ctx := context.Background()
+
+	assert.Empty(t, resolver.VerifyIssuer(ctx, "shop.example.com", "Let's Encrypt"))
+	warning := resolver.VerifyIssuer(ctx, "shop.example.com", "DigiCert Inc")
+	assert.Contains(t, warning, "DigiCert Inc")
+	assert.Contains(t, warning, "letsencrypt.org")
+
+	assert.Empty(t, resolver.VerifyIssuer(ctx, "example.net", "DigiCert Inc"), "Without CAA records any CA may issue")
+	assert.Empty(t, resolver.VerifyIssuer(ctx, "shop.example.com", "Example Internal CA"), "Unknown CAs can't be verified")
+}
+
+// TestTracker_Check - mismatches are recorded on the domain and cleared once the records match.
+func TestTracker_Check(t *testing.T) {
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	domainRepo := domain.NewRepository(db)
+	domains := domain.NewService(domainRepo, nil)
+
+	var ids []types.DomainID
+	for _, name := range []string{"example.com", "other.example.com"} {
+		d := &domain.Domain{UserID: types.UserID(1), DomainName: domain.NewDomainName(name), CreatedAt: domain.NewCreatedAt(time.Now()), IsActive: true}
+		require.NoError(t, domainRepo.CreateDomain(d))
+		ids = append(ids, d.DomainID)
+	}
+	require.NoError(t, domains.SetDNSExpectations(ids[0], expect(t, "A=192.0.2.9")))
+
+	tracker := NewTracker(domains, serveDNS(t, "example.com. 300 IN A 192.0.2.1"), time.Hour)
+	require.NoError(t, tracker.Update(context.Background()))
+
+	d, err := domains.GetDomain(ids[0])
+	require.NoError(t, err)
+	require.NotNil(t, d.DNSError)
+	assert.Contains(t, *d.DNSError, "A is 192.0.2.1 instead of 192.0.2.9")
+	assert.NotNil(t, d.DNSChecked)
+	other, err := domains.GetDomain(ids[1])
+	require.NoError(t, err)
+	assert.Nil(t, other.DNSChecked, "Domains without expectations aren't checked")
+
+	require.NoError(t, domains.SetDNSExpectations(ids[0], expect(t, "A=192.0.2.1")))
+	d, err = domains.GetDomain(ids[0])
+	require.NoError(t, err)
+	assert.Nil(t, d.DNSChecked, "New expectations forget the last check")
+	require.NoError(t, tracker.Check(context.Background(), []domain.Domain{*d}))
+	d, err = domains.GetDomain(ids[0])
+	require.NoError(t, err)
+	assert.Nil(t, d.DNSError)
+	assert.NotNil(t, d.DNSChecked)
+}
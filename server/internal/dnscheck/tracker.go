@@ -0,0 +1,90 @@
+package dnscheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+)
+
+// Tracker checks the records of every domain with DNS expectations and records whether they match
+type Tracker struct {
+	domains  *domain.Service
+	resolver *Resolver
+	interval time.Duration
+	now      func() time.Time
+}
+
+// NewTracker checks the records of every domain once each interval
+func NewTracker(domains *domain.Service, resolver *Resolver, interval time.Duration) *Tracker {
+	return &Tracker{
+		domains:  domains,
+		resolver: resolver,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Run checks records until the context is cancelled
+func (t *Tracker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := t.Update(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Failed to check DNS records", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Update checks the records of every active domain with DNS expectations
+func (t *Tracker) Update(ctx context.Context) error {
+	domains, err := t.domains.GetActiveDomains()
+	if err != nil {
+		return fmt.Errorf("failed to get domains: %w", err)
+	}
+	return t.Check(ctx, domains)
+}
+
+// Check looks up the records of domains with DNS expectations and records how they differ from them. A failed
+// lookup is recorded as the mismatch, since a name whose records can't be found is as broken
+func (t *Tracker) Check(ctx context.Context, domains []domain.Domain) error {
+	var errs []error
+	for _, d := range domains {
+		if len(d.DNSExpectations) == 0 {
+			continue
+		}
+		hostname, err := Hostname(d.DomainName.String())
+		if err != nil {
+			continue
+		}
+
+		var mismatch *string
+		if err := t.resolver.Check(ctx, hostname, d.DNSExpectations); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			msg := err.Error()
+			mismatch = &msg
+		}
+		switch {
+		case mismatch != nil && (d.DNSError == nil || *d.DNSError != *mismatch):
+			slog.Warn("DNS records don't match their expectations", "domain", d.DomainName, "mismatch", *mismatch)
+		case mismatch == nil && d.DNSError != nil:
+			slog.Info("DNS records match their expectations again", "domain", d.DomainName)
+		}
+		if err := t.domains.SetDNSCheck(d.DomainID, mismatch, t.now()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Kinds of activity recorded about domains
+const (
+	ActivityAdded   = "added"
+	ActivityDeleted = "deleted"
+	ActivityRenewed = "renewed"
+	// ActivityStatus is a check that moved a domain to another status
+	ActivityStatus = "status"
+)
+
+// ActivityLog keeps what happened to domains for the activity feed, activity.Repository does this
+type ActivityLog interface {
+	Record(d Domain, kind, detail string, at time.Time) error
+}
+
+// SetActivityLog records the domains added and deleted, their renewals and their status changes, nothing is
+// recorded unless set
+func (s *Service) SetActivityLog(log ActivityLog) {
+	s.activity = log
+}
+
+// record adds to the activity log, a failure only costs the feed an entry so it is logged rather than returned
+func (s *Service) record(d Domain, kind, detail string, at time.Time) {
+	if s.activity == nil {
+		return
+	}
+	if err := s.activity.Record(d, kind, detail, at); err != nil {
+		slog.Error("Failed to record activity", "domain", d.DomainName.String(), "kind", kind, "error", err)
+	}
+}
+
+// recordCheck records the status change and renewal a stored check of before found. The first check of a domain
+// changes nothing, it only shows where the domain starts
+func (s *Service) recordCheck(before Domain, expiry *time.Time, checkErr *string, checkedAt time.Time) {
+	if before.LastChecked == nil {
+		return
+	}
+	was := before.StatusWith(s.thresholds, checkedAt)
+	now := EvaluateStatus(expiry, checkErr, s.thresholds, checkedAt)
+	if was.Level != now.Level {
+		s.record(before, ActivityStatus, fmt.Sprintf("%s → %s, %s", was.Level, now.Level, now.Reason), checkedAt)
+	}
+	// A later expiry is a new certificate, the same way RenewalCadence tells renewals
+	if previous := before.ExpiryTime(); previous != nil && expiry != nil && expiry.After(*previous) {
+		s.record(before, ActivityRenewed, "now expires "+expiry.UTC().Format(time.DateOnly), checkedAt)
+	}
+}
@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// Cadence is how a domain's certificate has been renewed so far, learned from its check history
+type Cadence struct {
+	// Renewals are when checks first saw each new certificate, oldest first
+	Renewals []time.Time
+	// Interval is the typical time between renewals, zero with fewer than two of them
+	Interval time.Duration
+	// Lead is the typical time the replaced certificate had left when it was renewed, zero without renewals
+	Lead time.Duration
+	// NextRenewal is when the certificate is expected to be renewed next, nil when there is no telling
+	NextRenewal *time.Time
+}
+
+// RenewalCadence works out the cadence from a domain's checks, in any order, and the expiry of the certificate it
+// serves now.
+//
+// The next renewal is expected the typical lead before the current expiry, or an interval after the last renewal
+// when the expiry isn't known
+func RenewalCadence(history []CheckRecord, expiry *time.Time) Cadence {
+	checks := make([]CheckRecord, 0, len(history))
+	for _, rec := range history {
+		if rec.ExpiryDate != nil {
+			checks = append(checks, rec)
+		}
+	}
+	sort.SliceStable(checks, func(i, j int) bool { return checks[i].CheckedAt.Before(checks[j].CheckedAt) })
+
+	var c Cadence
+	var intervals, leads []time.Duration
+	for i := 1; i < len(checks); i++ {
+		previous := checks[i-1].ExpiryDate.Time()
+		if !checks[i].ExpiryDate.Time().After(previous) {
+			continue
+		}
+		renewedAt := checks[i].CheckedAt
+		if n := len(c.Renewals); n > 0 {
+			intervals = append(intervals, renewedAt.Sub(c.Renewals[n-1]))
+		}
+		c.Renewals = append(c.Renewals, renewedAt)
+		leads = append(leads, previous.Sub(renewedAt))
+	}
+	c.Interval = median(intervals)
+	c.Lead = median(leads)
+
+	switch {
+	case len(c.Renewals) == 0:
+	case expiry != nil:
+		next := expiry.Add(-c.Lead)
+		c.NextRenewal = &next
+	case c.Interval > 0:
+		next := c.Renewals[len(c.Renewals)-1].Add(c.Interval)
+		c.NextRenewal = &next
+	}
+	return c
+}
+
+// Slipped reports whether the certificate should have been renewed by now, going by its cadence
+func (c Cadence) Slipped(now time.Time) bool {
+	return c.NextRenewal != nil && now.After(c.NextRenewal.Add(RenewalGrace))
+}
+
+// median is the middle of durations, zero when there are none
+func median(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -0,0 +1,128 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy is an org-wide rule every certificate is held to, e.g. a maximum lifetime or no SHA-1 signatures
+type Policy struct {
+	Name string
+	// MaxLifetimeDays caps how long certificates are valid for, zero for no cap
+	MaxLifetimeDays int
+	// MinRSABits is the smallest RSA key allowed, zero for any
+	MinRSABits int
+	// ForbiddenSignatures are hash algorithms, e.g. SHA1 or MD5, no certificate of a chain may be signed with
+	ForbiddenSignatures []string
+	// From is when the policy takes effect, zero when it always has. Until then certificates breaking it count
+	// down to it rather than violate it
+	From time.Time
+}
+
+// Validate reports policies that could never be broken
+func (p Policy) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("policy name cannot be empty")
+	}
+	if p.MaxLifetimeDays < 0 || p.MinRSABits < 0 {
+		return fmt.Errorf("policy %s must not have negative limits", p.Name)
+	}
+	if p.MaxLifetimeDays == 0 && p.MinRSABits == 0 && len(p.ForbiddenSignatures) == 0 {
+		return fmt.Errorf("policy %s needs max_lifetime_days, min_rsa_bits or forbid_signatures", p.Name)
+	}
+	return nil
+}
+
+// Violation is a policy a certificate breaks
+type Violation struct {
+	Policy string
+	// Reason says how the certificate breaks it, e.g. "valid for 825 days, at most 398 allowed"
+	Reason string
+	// From is when the policy takes effect, zero when it always has
+	From time.Time
+}
+
+// ComplianceLevel is how a certificate measures up to the policies
+type ComplianceLevel string
+
+const (
+	// ComplianceUnknown is a domain no check has recorded the certificate details of, e.g. an SSH host
+	ComplianceUnknown ComplianceLevel = "unknown"
+	Compliant         ComplianceLevel = "compliant"
+	// ComplianceDue is a certificate that only breaks policies yet to take effect
+	ComplianceDue ComplianceLevel = "due"
+	NonCompliant  ComplianceLevel = "violating"
+)
+
+// ComplianceStatus is how a domain's certificate measures up to the policies and which it breaks
+type ComplianceStatus struct {
+	Level      ComplianceLevel
+	Violations []Violation
+	// DaysLeft is whole days until the first policy the certificate breaks takes effect, nil unless Level is due
+	DaysLeft *int
+}
+
+// ComplianceWith checks the certificate last seen on the domain against policies as seen at now. Policies the
+// details recorded can't tell about, e.g. the lifetime of a certificate whose check failed, are skipped
+func (d Domain) ComplianceWith(policies []Policy, now time.Time) ComplianceStatus {
+	if d.KeyType == "" {
+		return ComplianceStatus{Level: ComplianceUnknown}
+	}
+	status := ComplianceStatus{Level: Compliant}
+	for _, p := range policies {
+		reasons := d.breaches(p)
+		if len(reasons) == 0 {
+			continue
+		}
+		status.Violations = append(status.Violations, Violation{Policy: p.Name, Reason: strings.Join(reasons, ", "), From: p.From})
+		if !now.Before(p.From) {
+			status.Level = NonCompliant
+			continue
+		}
+		days := int(p.From.Sub(now).Hours() / 24)
+		if status.Level != NonCompliant && (status.DaysLeft == nil || days < *status.DaysLeft) {
+			status.Level, status.DaysLeft = ComplianceDue, &days
+		}
+	}
+	if status.Level == NonCompliant {
+		status.DaysLeft = nil
+	}
+	return status
+}
+
+// breaches lists how the certificate last seen breaks a policy, none when it doesn't
+func (d Domain) breaches(p Policy) []string {
+	var reasons []string
+	if expiry := d.ExpiryTime(); p.MaxLifetimeDays > 0 && expiry != nil && d.IssuedAt != nil {
+		if days := int(expiry.Sub(*d.IssuedAt).Hours() / 24); days > p.MaxLifetimeDays {
+			reasons = append(reasons, fmt.Sprintf("valid for %d days, at most %d allowed", days, p.MaxLifetimeDays))
+		}
+	}
+	if bits, ok := strings.CutPrefix(d.KeyType, "RSA "); ok && p.MinRSABits > 0 {
+		if n, err := strconv.Atoi(bits); err == nil && n < p.MinRSABits {
+			reasons = append(reasons, fmt.Sprintf("%d-bit RSA key, at least %d required", n, p.MinRSABits))
+		}
+	}
+	for _, algorithm := range d.Signatures {
+		if signedWith(algorithm, p.ForbiddenSignatures) {
+			reasons = append(reasons, "signed with "+algorithm)
+		}
+	}
+	return reasons
+}
+
+// signedWith reports whether a signature algorithm, e.g. SHA1-RSA or ECDSA-SHA1, uses any of the hashes
+func signedWith(algorithm string, hashes []string) bool {
+	for _, part := range strings.Split(algorithm, "-") {
+		if slices.ContainsFunc(hashes, func(h string) bool {
+			return strings.EqualFold(strings.ReplaceAll(h, "-", ""), part)
+		}) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,10 @@
 package domain
 
 import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/samokw/ssl_tracker/internal/types"
@@ -63,4 +67,269 @@ type Domain struct {
 	LastChecked *LastChecked      `db:"last_checked"`
 	LastError   *LastError        `db:"last_error"`
 	IsActive    bool              `db:"is_active"`
+	// CheckInterval overrides how often the daemon checks this domain, zero uses the default
+	CheckInterval time.Duration `db:"check_interval_seconds"`
+	// CheckSchedule is a cron expression for when the daemon checks this domain, empty uses the default
+	CheckSchedule string `db:"check_schedule"`
+	// NextCheckAt is when the daemon plans to check this domain again, spread out so checks don't all fall due
+	// at once. nil until planned, and unused with a cron schedule
+	NextCheckAt *time.Time `db:"next_check_at"`
+	// Tags group domains, e.g. by environment
+	Tags []string `db:"tags"`
+	// Issuer names the CA of the certificate last seen, empty until a check succeeds
+	Issuer string `db:"issuer"`
+	// TeamID shares the domain with every member of a team, zero keeps it private to UserID, who added it
+	TeamID types.TeamID `db:"team_id"`
+	// Fingerprint is the SSH host key last seen, empty for other targets
+	Fingerprint string `db:"fingerprint"`
+	// CertFingerprint is the hex SHA-256 of the certificate last seen, shared by every name serving the same
+	// certificate, empty for targets without one
+	CertFingerprint string `db:"cert_fingerprint"`
+	// SANs are the DNS names the certificate last seen is valid for
+	SANs []string `db:"sans"`
+	// IssuedAt is when the certificate last seen became valid, nil until a check records it
+	IssuedAt *time.Time `db:"issued_at"`
+	// KeyType describes the public key of the certificate last seen, e.g. RSA 2048, empty until a check records it
+	KeyType string `db:"key_type"`
+	// Signatures are the signature algorithms of the certificate last seen and its intermediates, e.g. SHA256-RSA
+	Signatures []string `db:"signatures"`
+	// CheckLatency is how long the last check took, nil until a check through the worker pool records it
+	CheckLatency *time.Duration `db:"check_latency_ms"`
+	// ExpectedSANs are the DNS names the certificate has to cover, checks of one that doesn't cover them all fail
+	ExpectedSANs []string `db:"expected_sans"`
+	// Warning describes a problem the last check found with a certificate that is otherwise fine, e.g. a CA
+	// the CAA records don't authorize, nil when there was none
+	Warning *string `db:"warning"`
+	// RegistrationExpiry is when the registration of the domain the name belongs to expires, nil until looked up
+	RegistrationExpiry *time.Time `db:"registration_expiry"`
+	// RegistrationChecked is when the registration was last looked up, nil if it never was
+	RegistrationChecked *time.Time `db:"registration_checked"`
+	// DNSExpectations are the records the name is expected to have, none skips DNS checks
+	DNSExpectations []DNSExpectation `db:"dns_expectations"`
+	// DNSError describes how the records last looked up differ from DNSExpectations, nil when they matched
+	DNSError *string `db:"dns_error"`
+	// DNSChecked is when the records were last looked up, nil if they never were
+	DNSChecked *time.Time `db:"dns_checked"`
+	// AutoRenewDays is how many days before expiry the certificate is expected to be renewed automatically,
+	// zero when it isn't
+	AutoRenewDays int `db:"auto_renew_days"`
+	// AutoRenewVia names what renews the certificate, e.g. cert-manager, empty when not recorded
+	AutoRenewVia string `db:"auto_renew_via"`
+}
+
+// NormalizeTags lowercases, trims, deduplicates and sorts tags
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool)
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// ParseTags splits a comma separated tag list
+func ParseTags(tags string) []string {
+	return NormalizeTags(strings.Split(tags, ","))
+}
+
+// HasTag reports whether the domain carries the tag
+func (d Domain) HasTag(tag string) bool {
+	for _, t := range d.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDue reports whether the domain should be checked again at the given time
+func (d Domain) IsDue(now time.Time, defaultInterval time.Duration) bool {
+	if !d.IsActive {
+		return false
+	}
+	if d.LastChecked == nil {
+		return true
+	}
+	interval := d.CheckInterval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return !now.Before(d.LastChecked.Time().Add(interval))
+}
+
+// CheckRecord is one entry in a domain's check history
+type CheckRecord struct {
+	ID         uint              `db:"id"`
+	DomainID   types.DomainID    `db:"domain_id"`
+	CheckedAt  time.Time         `db:"checked_at"`
+	ExpiryDate *types.ExpiryDate `db:"expiry_date"`
+	Error      *LastError        `db:"error"`
+	// Fingerprint is the SSH host key the check saw
+	Fingerprint string `db:"fingerprint"`
+}
+
+// ExpiryTime is the certificate's expiry as a time, nil when it isn't known
+func (d Domain) ExpiryTime() *time.Time {
+	if d.ExpiryDate == nil {
+		return nil
+	}
+	t := d.ExpiryDate.Time()
+	return &t
+}
+
+// RenewalGrace is how long past its renewal date a certificate may still be served before its automatic renewal
+// counts as overdue, leaving room for renewal jobs that run daily and for the checks between them
+const RenewalGrace = 48 * time.Hour
+
+// RenewalDue is when the certificate last seen should be renewed automatically, nil when it isn't expected to be
+// or its expiry isn't known
+func (d Domain) RenewalDue() *time.Time {
+	expiry := d.ExpiryTime()
+	if d.AutoRenewDays <= 0 || expiry == nil {
+		return nil
+	}
+	due := expiry.AddDate(0, 0, -d.AutoRenewDays)
+	return &due
+}
+
+// RenewalOverdue reports whether the certificate last seen should have been renewed automatically by now, a sign
+// that whatever renews it is broken. A renewed certificate has a later expiry, which clears it
+func (d Domain) RenewalOverdue(now time.Time) bool {
+	due := d.RenewalDue()
+	return due != nil && now.After(due.Add(RenewalGrace))
+}
+
+// Status summarises the certificate state as one of valid, soon, warning, expired, error or unknown, with the
+// default thresholds
+func (d Domain) Status() string {
+	return string(d.StatusWith(DefaultStatusThresholds, time.Now()).Level)
+}
+
+// CertificateStatus is Domain.Status for a check result as seen at now
+func CertificateStatus(expiry *time.Time, lastError *string, now time.Time) string {
+	return string(EvaluateStatus(expiry, lastError, DefaultStatusThresholds, now).Level)
+}
+
+// Registration statuses
+const (
+	RegistrationValid    = "valid"
+	RegistrationWarning  = "warning"
+	RegistrationCritical = "critical"
+	RegistrationExpired  = "expired"
+	RegistrationUnknown  = "unknown"
+)
+
+// RegistrationStatus summarises the domain's registration at now as one of the Registration constants, given
+// the days before it expires that count as a warning and as critical
+func (d Domain) RegistrationStatus(warning, critical int, now time.Time) string {
+	if d.RegistrationExpiry == nil {
+		return RegistrationUnknown
+	}
+	daysLeft := d.RegistrationExpiry.Sub(now).Hours() / 24
+	switch {
+	case daysLeft < 0:
+		return RegistrationExpired
+	case daysLeft < float64(critical):
+		return RegistrationCritical
+	case daysLeft < float64(warning):
+		return RegistrationWarning
+	default:
+		return RegistrationValid
+	}
+}
+
+// DNS record types a domain's records can be expected to match
+const (
+	DNSRecordA     = "A"
+	DNSRecordAAAA  = "AAAA"
+	DNSRecordCNAME = "CNAME"
+	// DNSRecordCAA pins the CA allowed to issue for the name by its CAA issuer domain, e.g. letsencrypt.org
+	DNSRecordCAA = "CAA"
+)
+
+// DNSExpectation is a record the name is expected to have. Expecting a type means its records have to be
+// exactly the values expected for it, so an unexpected address or CA counts as a mismatch too
+type DNSExpectation struct {
+	Type  string
+	Value string
+}
+
+// String formats the expectation as TYPE=value
+func (e DNSExpectation) String() string {
+	return e.Type + "=" + e.Value
+}
+
+// ParseDNSExpectation parses TYPE=value, e.g. A=192.0.2.1, CNAME=shop.example.net or CAA=letsencrypt.org
+func ParseDNSExpectation(s string) (DNSExpectation, error) {
+	recordType, value, ok := strings.Cut(strings.TrimSpace(s), "=")
+	if !ok {
+		return DNSExpectation{}, fmt.Errorf("%w: DNS expectation %q is not TYPE=value", ErrInvalidInput, s)
+	}
+	e := DNSExpectation{Type: strings.ToUpper(strings.TrimSpace(recordType)), Value: strings.TrimSpace(value)}
+	switch e.Type {
+	case DNSRecordA, DNSRecordAAAA:
+		addr, err := netip.ParseAddr(e.Value)
+		if err != nil || addr.Is4() != (e.Type == DNSRecordA) {
+			return DNSExpectation{}, fmt.Errorf("%w: %q is not an %s address", ErrInvalidInput, e.Value, e.Type)
+		}
+		e.Value = addr.String()
+	case DNSRecordCNAME, DNSRecordCAA:
+		e.Value = strings.TrimSuffix(strings.ToLower(e.Value), ".")
+		if e.Value == "" || strings.ContainsAny(e.Value, " ,;") {
+			return DNSExpectation{}, fmt.Errorf("%w: %q is not a domain name", ErrInvalidInput, e.Value)
+		}
+	default:
+		return DNSExpectation{}, fmt.Errorf("%w: unsupported DNS record type %q, use A, AAAA, CNAME or CAA", ErrInvalidInput, recordType)
+	}
+	return e, nil
+}
+
+// ParseDNSExpectations parses a comma separated list of expectations, deduplicated and sorted
+func ParseDNSExpectations(s string) ([]DNSExpectation, error) {
+	var parts []string
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) != "" {
+			parts = append(parts, part)
+		}
+	}
+	return NormalizeDNSExpectations(parts)
+}
+
+// NormalizeDNSExpectations parses expectations in TYPE=value form, deduplicated and sorted
+func NormalizeDNSExpectations(values []string) ([]DNSExpectation, error) {
+	seen := make(map[DNSExpectation]bool)
+	expectations := []DNSExpectation{}
+	for _, v := range values {
+		e, err := ParseDNSExpectation(v)
+		if err != nil {
+			return nil, err
+		}
+		if seen[e] {
+			continue
+		}
+		seen[e] = true
+		expectations = append(expectations, e)
+	}
+	sort.Slice(expectations, func(i, j int) bool {
+		if expectations[i].Type != expectations[j].Type {
+			return expectations[i].Type < expectations[j].Type
+		}
+		return expectations[i].Value < expectations[j].Value
+	})
+	return expectations, nil
+}
+
+// FormatDNSExpectations joins expectations into the comma separated list ParseDNSExpectations reads
+func FormatDNSExpectations(expectations []DNSExpectation) string {
+	parts := make([]string, len(expectations))
+	for i, e := range expectations {
+		parts[i] = e.String()
+	}
+	return strings.Join(parts, ",")
 }
@@ -3,43 +3,134 @@ package domain
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/samokw/ssl_tracker/internal/database"
 	"github.com/samokw/ssl_tracker/internal/types"
 )
 
+// DomainRepository stores domains and their check history
+type DomainRepository interface {
+	// CheckForDuplicateDomains returns the user's domain with that name, or nil if there is none
+	CheckForDuplicateDomains(userID types.UserID, domainName string) (*Domain, error)
+	// CreateDomain stores a new domain and sets its DomainID
+	CreateDomain(domain *Domain) error
+	// GetDomainsByUserID returns the domains a user keeps to themselves, not those shared with a team
+	GetDomainsByUserID(userID types.UserID) ([]Domain, error)
+	GetDomainsByTeamID(teamID types.TeamID) ([]Domain, error)
+	// FindTeamDomain returns the team's domain with that name, or nil if there is none
+	FindTeamDomain(teamID types.TeamID, domainName string) (*Domain, error)
+	// UpdateTeam shares a domain with a team, zero makes it private to whoever added it again
+	UpdateTeam(domainID types.DomainID, teamID types.TeamID) error
+	GetActiveDomains() ([]Domain, error)
+	GetDomainByID(domainID types.DomainID) (*Domain, error)
+	// DeleteDomain removes a domain along with its history
+	DeleteDomain(domainID types.DomainID) error
+	UpdateSSLInfo(domainID types.DomainID, expiryDate *time.Time, lastError *string) error
+	UpdateSSLInfoBatch(updates []SSLUpdate) error
+	GetCheckHistory(domainID types.DomainID, limit int) ([]CheckRecord, error)
+	// GetExpiryHistory returns every check of a domain that found a certificate, oldest first
+	GetExpiryHistory(domainID types.DomainID) ([]CheckRecord, error)
+	DeleteCheckHistoryBefore(cutoff time.Time) (int64, error)
+	CountCheckHistoryBefore(cutoff time.Time) (int64, error)
+	UpdateCheckSchedule(domainID types.DomainID, schedule string) error
+	UpdateTags(domainID types.DomainID, tags []string) error
+	UpdateIssuer(domainID types.DomainID, issuer string) error
+	UpdateFingerprint(domainID types.DomainID, fingerprint string) error
+	// UpdateCertificate records the fingerprint, DNS names and details of the certificate a domain serves
+	UpdateCertificate(domainID types.DomainID, cert CertificateDetails) error
+	// UpdateExpectedSANs replaces the DNS names a domain's certificate has to cover
+	UpdateExpectedSANs(domainID types.DomainID, sans []string) error
+	// UpdateWarning records a problem with a domain's certificate that is otherwise fine, nil when there is none
+	UpdateWarning(domainID types.DomainID, warning *string) error
+	// UpdateRegistration records when a domain's registration expires, nil when the lookup couldn't tell
+	UpdateRegistration(domainID types.DomainID, expiry *time.Time, checkedAt time.Time) error
+	// UpdateDNSExpectations replaces the records a domain is expected to have, forgetting the last DNS check
+	UpdateDNSExpectations(domainID types.DomainID, expectations []DNSExpectation) error
+	// UpdateDNSCheck records how a domain's records differed from its expectations, nil when they matched
+	UpdateDNSCheck(domainID types.DomainID, mismatch *string, checkedAt time.Time) error
+	// UpdateAutoRenewal records how many days before expiry a domain's certificate renews automatically and
+	// what renews it, zero days when it doesn't
+	UpdateAutoRenewal(domainID types.DomainID, days int, via string) error
+	// UpdateNextChecks records when domains are due to be checked again, skipping domains that no longer exist
+	UpdateNextChecks(next map[types.DomainID]time.Time) error
+}
+
+var (
+	_ DomainRepository = (*Repository)(nil)
+	_ DomainRepository = (*MemoryRepository)(nil)
+)
+
+// Repository is the SQL implementation of DomainRepository
 type Repository struct {
-	db *sql.DB
+	db     *sql.DB
+	writer *database.Writer
 }
 
 func NewRepository(db *sql.DB) *Repository {
 	return &Repository{
-		db: db,
+		db:     db,
+		writer: database.NewWriter(db),
 	}
 }
 
-func (r *Repository) scanDomainRow(row *sql.Row) (Domain, error) {
+// domainColumns is the column list every domain query selects, in scan order
+const domainColumns = `id, user_id, domain_name, created_at, expiry_date, last_checked, last_error, is_active, check_interval_seconds, check_schedule, tags, issuer, team_id, fingerprint, registration_expiry, registration_checked, dns_expectations, dns_error, dns_checked, warning, auto_renew_days, auto_renew_via, cert_fingerprint, sans, next_check_at, expected_sans, issued_at, key_type, signatures, check_latency_ms`
+
+// scanner is implemented by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func (r *Repository) scanDomain(row scanner) (Domain, error) {
 	// We need to use default types and then convert them to our types
 	var domainID, userID uint
 	var domainName string
 	var createdAt time.Time
-	var expiryDate, lastChecked sql.NullTime
-	var lastError sql.NullString
+	var expiryDate, lastChecked, registrationExpiry, registrationChecked, dnsChecked, nextCheckAt, issuedAt sql.NullTime
+	var lastError, dnsError, warning sql.NullString
 	var isActive bool
+	var checkIntervalSeconds int64
+	var autoRenewDays int
+	var checkSchedule, tags, issuer, fingerprint, dnsExpectations, autoRenewVia, certFingerprint, sans, expectedSANs, keyType, signatures string
+	var teamID, latencyMs sql.NullInt64
 
 	// scan information from the database
-	err := row.Scan(&domainID, &userID, &domainName, &createdAt, &expiryDate, &lastChecked, &lastError, &isActive)
+	err := row.Scan(&domainID, &userID, &domainName, &createdAt, &expiryDate, &lastChecked, &lastError, &isActive,
+		&checkIntervalSeconds, &checkSchedule, &tags, &issuer, &teamID, &fingerprint,
+		&registrationExpiry, &registrationChecked, &dnsExpectations, &dnsError, &dnsChecked, &warning,
+		&autoRenewDays, &autoRenewVia, &certFingerprint, &sans, &nextCheckAt, &expectedSANs,
+		&issuedAt, &keyType, &signatures, &latencyMs)
+	if err != nil {
+		return Domain{}, err
+	}
+	expectations, err := ParseDNSExpectations(dnsExpectations)
 	if err != nil {
 		return Domain{}, err
 	}
 
 	// Create the object domain we will return
 	domain := Domain{
-		DomainID:   types.DomainID(domainID),
-		UserID:     types.UserID(userID),
-		DomainName: NewDomainName(domainName),
-		CreatedAt:  NewCreatedAt(createdAt),
-		IsActive:   isActive,
+		DomainID:        types.DomainID(domainID),
+		UserID:          types.UserID(userID),
+		DomainName:      NewDomainName(domainName),
+		CreatedAt:       NewCreatedAt(createdAt),
+		IsActive:        isActive,
+		CheckInterval:   time.Duration(checkIntervalSeconds) * time.Second,
+		CheckSchedule:   checkSchedule,
+		Tags:            ParseTags(tags),
+		Issuer:          issuer,
+		TeamID:          types.TeamID(teamID.Int64),
+		Fingerprint:     fingerprint,
+		DNSExpectations: expectations,
+		AutoRenewDays:   autoRenewDays,
+		AutoRenewVia:    autoRenewVia,
+		CertFingerprint: certFingerprint,
+		SANs:            ParseSANs(sans),
+		ExpectedSANs:    ParseSANs(expectedSANs),
+		KeyType:         keyType,
+		Signatures:      parseSignatures(signatures),
 	}
 	if expiryDate.Valid {
 		ed := types.NewExpiryDate(expiryDate.Time)
@@ -59,58 +150,49 @@ func (r *Repository) scanDomainRow(row *sql.Row) (Domain, error) {
 	} else {
 		domain.LastError = nil
 	}
-	return domain, nil
-}
-
-func (r *Repository) scanDomain(rows *sql.Rows) (Domain, error) {
-	// We need to use default types and then convert them to our types
-	var domainID, userID uint
-	var domainName string
-	var createdAt time.Time
-	var expiryDate, lastChecked sql.NullTime
-	var lastError sql.NullString
-	var isActive bool
-
-	// scan information from the database
-	err := rows.Scan(&domainID, &userID, &domainName, &createdAt, &expiryDate, &lastChecked, &lastError, &isActive)
-	if err != nil {
-		return Domain{}, err
+	if registrationExpiry.Valid {
+		domain.RegistrationExpiry = &registrationExpiry.Time
 	}
-
-	// Create the object domain we will return
-	domain := Domain{
-		DomainID:   types.DomainID(domainID),
-		UserID:     types.UserID(userID),
-		DomainName: NewDomainName(domainName),
-		CreatedAt:  NewCreatedAt(createdAt),
-		IsActive:   isActive,
+	if registrationChecked.Valid {
+		domain.RegistrationChecked = &registrationChecked.Time
 	}
-	if expiryDate.Valid {
-		ed := types.NewExpiryDate(expiryDate.Time)
-		domain.ExpiryDate = &ed
-	} else {
-		domain.ExpiryDate = nil
+	if dnsError.Valid {
+		domain.DNSError = &dnsError.String
 	}
-	if lastChecked.Valid {
-		lc := NewLastChecked(lastChecked.Time)
-		domain.LastChecked = &lc
-	} else {
-		domain.LastChecked = nil
+	if warning.Valid {
+		domain.Warning = &warning.String
 	}
-	if lastError.Valid {
-		le := NewLastError(lastError.String)
-		domain.LastError = &le
-	} else {
-		domain.LastError = nil
+	if dnsChecked.Valid {
+		domain.DNSChecked = &dnsChecked.Time
+	}
+	if nextCheckAt.Valid {
+		domain.NextCheckAt = &nextCheckAt.Time
+	}
+	if issuedAt.Valid {
+		domain.IssuedAt = &issuedAt.Time
+	}
+	if latencyMs.Valid {
+		latency := time.Duration(latencyMs.Int64) * time.Millisecond
+		domain.CheckLatency = &latency
 	}
 	return domain, nil
 }
 
+// parseSignatures splits the comma separated signature algorithms of a chain
+func parseSignatures(signatures string) []string {
+	var algorithms []string
+	for _, algorithm := range strings.Split(signatures, ",") {
+		if algorithm != "" {
+			algorithms = append(algorithms, algorithm)
+		}
+	}
+	return algorithms
+}
+
 func (r *Repository) CheckForDuplicateDomains(userID types.UserID, domainName string) (*Domain, error) {
-	query := `SELECT id, user_id, domain_name, created_at, expiry_date, last_checked, last_error, is_active 
-              FROM domains WHERE user_id = ? AND domain_name = ?`
+	query := `SELECT ` + domainColumns + ` FROM domains WHERE user_id = ? AND domain_name = ?`
 	row := r.db.QueryRow(query, userID.Uint(), domainName)
-	domain, err := r.scanDomainRow(row)
+	domain, err := r.scanDomain(row)
 	if err != nil {
 		if err == sql.ErrNoRows { // We found no duplicate
 			return nil, nil
@@ -123,21 +205,33 @@ func (r *Repository) CheckForDuplicateDomains(userID types.UserID, domainName st
 
 func (r *Repository) CreateDomain(domain *Domain) error {
 	if err := types.ValidateUserID(domain.UserID); err != nil {
-		return fmt.Errorf("invalid user ID: %w", err)
+		return fmt.Errorf("%w: invalid user ID: %w", ErrInvalidInput, err)
 	}
 
 	if domain.DomainName.String() == "" {
-		return fmt.Errorf("domain name cannot be empty")
+		return fmt.Errorf("%w: domain name cannot be empty", ErrInvalidInput)
 	}
 	existingDomain, err := r.CheckForDuplicateDomains(domain.UserID, domain.DomainName.String())
 	if err != nil {
 		return fmt.Errorf("error checking for duplicate domain: %w", err)
 	}
 	if existingDomain != nil {
-		return fmt.Errorf("domain %s already exists for this user", domain.DomainName.String())
+		return fmt.Errorf("domain %s %w for this user", domain.DomainName.String(), ErrDuplicate)
+	}
+	var teamID sql.NullInt64
+	if domain.TeamID != 0 {
+		existingDomain, err := r.FindTeamDomain(domain.TeamID, domain.DomainName.String())
+		if err != nil {
+			return fmt.Errorf("error checking for duplicate domain: %w", err)
+		}
+		if existingDomain != nil {
+			return fmt.Errorf("domain %s %w for this team", domain.DomainName.String(), ErrDuplicate)
+		}
+		teamID = sql.NullInt64{Int64: int64(domain.TeamID), Valid: true}
 	}
-	query := `INSERT INTO domains (user_id, domain_name, is_active, created_at) VALUES (?, ?, ?, ?)`
-	result, err := r.db.Exec(query, domain.UserID.Uint(), domain.DomainName.String(), domain.IsActive, domain.CreatedAt.Time())
+	query := `INSERT INTO domains (user_id, domain_name, is_active, created_at, check_interval_seconds, check_schedule, tags, team_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := r.writer.Exec(query, domain.UserID.Uint(), domain.DomainName.String(), domain.IsActive, domain.CreatedAt.Time(),
+		int64(domain.CheckInterval.Seconds()), domain.CheckSchedule, strings.Join(NormalizeTags(domain.Tags), ","), teamID)
 	if err != nil {
 		return err
 	}
@@ -150,8 +244,18 @@ func (r *Repository) CreateDomain(domain *Domain) error {
 }
 
 func (r *Repository) GetDomainsByUserID(userID types.UserID) ([]Domain, error) {
-	query := `SELECT id, user_id, domain_name, created_at, expiry_date, last_checked, last_error, is_active FROM domains WHERE user_id = ?`
-	rows, err := r.db.Query(query, userID.Uint())
+	query := `SELECT ` + domainColumns + ` FROM domains WHERE user_id = ? AND team_id IS NULL`
+	return r.queryDomains(query, userID.Uint())
+}
+
+// GetDomainsByTeamID returns the domains shared with a team
+func (r *Repository) GetDomainsByTeamID(teamID types.TeamID) ([]Domain, error) {
+	query := `SELECT ` + domainColumns + ` FROM domains WHERE team_id = ?`
+	return r.queryDomains(query, teamID.Uint())
+}
+
+func (r *Repository) queryDomains(query string, args ...any) ([]Domain, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -165,17 +269,56 @@ func (r *Repository) GetDomainsByUserID(userID types.UserID) ([]Domain, error) {
 		}
 		domains = append(domains, domain)
 	}
-	return domains, nil
+	return domains, rows.Err()
+}
+
+// FindTeamDomain returns the team's domain with that name, or nil if there is none
+func (r *Repository) FindTeamDomain(teamID types.TeamID, domainName string) (*Domain, error) {
+	query := `SELECT ` + domainColumns + ` FROM domains WHERE team_id = ? AND domain_name = ?`
+	domain, err := r.scanDomain(r.db.QueryRow(query, teamID.Uint(), domainName))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &domain, nil
+}
+
+// UpdateTeam shares a domain with a team, zero makes it private to whoever added it again
+func (r *Repository) UpdateTeam(domainID types.DomainID, teamID types.TeamID) error {
+	var team sql.NullInt64
+	if teamID != 0 {
+		team = sql.NullInt64{Int64: int64(teamID), Valid: true}
+	}
+	result, err := r.writer.Exec(`UPDATE domains SET team_id = ? WHERE id = ?`, team, domainID.Uint())
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("domain with ID %d %w", domainID.Uint(), ErrNotFound)
+	}
+	return nil
+}
+
+// GetActiveDomains returns the active domains of every user
+func (r *Repository) GetActiveDomains() ([]Domain, error) {
+	query := `SELECT ` + domainColumns + ` FROM domains WHERE is_active = 1`
+	return r.queryDomains(query)
 }
 
 // View a domain by its ID
 func (r *Repository) GetDomainByID(domainID types.DomainID) (*Domain, error) {
-	query := `SELECT id, user_id, domain_name, created_at, expiry_date, last_checked, last_error, is_active FROM domains WHERE id = ?`
+	query := `SELECT ` + domainColumns + ` FROM domains WHERE id = ?`
 	row := r.db.QueryRow(query, domainID.Uint())
-	domain, err := r.scanDomainRow(row)
+	domain, err := r.scanDomain(row)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("domain with ID %d not found", domainID.Uint())
+			return nil, fmt.Errorf("domain with ID %d %w", domainID.Uint(), ErrNotFound)
 		}
 		return nil, err
 	}
@@ -185,7 +328,7 @@ func (r *Repository) GetDomainByID(domainID types.DomainID) (*Domain, error) {
 // Delete A domain by its ID
 func (r *Repository) DeleteDomain(domainID types.DomainID) error {
 	query := `DELETE FROM domains WHERE id = ?`
-	result, err := r.db.Exec(query, domainID.Uint())
+	result, err := r.writer.Exec(query, domainID.Uint())
 	if err != nil {
 		return err
 	}
@@ -195,34 +338,383 @@ func (r *Repository) DeleteDomain(domainID types.DomainID) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("domain with ID %d not found", domainID.Uint())
+		return fmt.Errorf("domain with ID %d %w", domainID.Uint(), ErrNotFound)
 	}
 
 	return nil
 }
 
+// SSLUpdate is the outcome of one certificate check to store for a domain
+type SSLUpdate struct {
+	DomainID   types.DomainID
+	ExpiryDate *time.Time
+	Error      *string
+	CheckedAt  time.Time
+	// Issuer of a successful check, an empty one keeps the issuer last seen
+	Issuer string
+	// Fingerprint of the SSH host key seen, an empty one keeps the fingerprint last seen
+	Fingerprint string
+	// CertFingerprint of the certificate seen, an empty one keeps the certificate fingerprint last seen
+	CertFingerprint string
+	// SANs of the certificate seen, none keeps the names last seen
+	SANs []string
+	// Warning a successful check found, nil when it found none
+	Warning *string
+	// IssuedAt, KeyType and Signatures of the certificate seen, unset ones keep those last seen
+	IssuedAt   *time.Time
+	KeyType    string
+	Signatures []string
+	// Latency is how long the check took, zero when it wasn't measured
+	Latency time.Duration
+}
+
+// CertificateDetails describe the certificate a domain serves
+type CertificateDetails struct {
+	Fingerprint string
+	SANs        []string
+	// IssuedAt, KeyType and Signatures are what compliance policies are checked against
+	IssuedAt   *time.Time
+	KeyType    string
+	Signatures []string
+}
+
 // Update A domains info based on the ssl check
 func (r *Repository) UpdateSSLInfo(domainID types.DomainID, expiryDate *time.Time, lastError *string) error {
-	now := time.Now()
-	query := `UPDATE domains SET expiry_date = ?, last_checked = ?, last_error = ? WHERE id = ?`
+	update := SSLUpdate{DomainID: domainID, ExpiryDate: expiryDate, Error: lastError, CheckedAt: time.Now()}
+	return r.writer.Transaction(func(tx *sql.Tx) error {
+		found, err := updateSSLInfo(tx, update)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("domain with ID %d %w", domainID.Uint(), ErrNotFound)
+		}
+		return nil
+	})
+}
+
+// UpdateSSLInfoBatch stores many check results in a single transaction.
+//
+// Results for domains that no longer exist, e.g. deleted during a sweep, are skipped
+func (r *Repository) UpdateSSLInfoBatch(updates []SSLUpdate) error {
+	if len(updates) == 0 {
+		return nil
+	}
+	return r.writer.Transaction(func(tx *sql.Tx) error {
+		for _, update := range updates {
+			if _, err := updateSSLInfo(tx, update); err != nil {
+				return fmt.Errorf("failed to update domain %d: %w", update.DomainID.Uint(), err)
+			}
+		}
+		return nil
+	})
+}
 
+// updateSSLInfo stores a check result and its history entry, returning false if the domain doesn't exist
+func updateSSLInfo(tx *sql.Tx, update SSLUpdate) (bool, error) {
 	var expiryNull sql.NullTime
 	var errorNull sql.NullString
+	var latencyNull sql.NullInt64
 
-	if expiryDate != nil {
-		expiryNull.Time = *expiryDate
+	if update.ExpiryDate != nil {
+		expiryNull.Time = *update.ExpiryDate
 		expiryNull.Valid = true
-	} else {
-		expiryNull.Valid = false
 	}
-
-	if lastError != nil {
-		errorNull.String = *lastError
+	if update.Error != nil {
+		errorNull.String = *update.Error
 		errorNull.Valid = true
-	} else {
-		errorNull.Valid = false
 	}
-	result, err := r.db.Exec(query, expiryNull, now, errorNull, domainID.Uint())
+	if update.Latency > 0 {
+		latencyNull.Int64 = update.Latency.Milliseconds()
+		latencyNull.Valid = true
+	}
+
+	query := `UPDATE domains SET expiry_date = ?, last_checked = ?, last_error = ?, issuer = COALESCE(NULLIF(?, ''), issuer),
+              fingerprint = COALESCE(NULLIF(?, ''), fingerprint), cert_fingerprint = COALESCE(NULLIF(?, ''), cert_fingerprint),
+              sans = COALESCE(NULLIF(?, ''), sans), warning = ?, issued_at = COALESCE(?, issued_at),
+              key_type = COALESCE(NULLIF(?, ''), key_type), signatures = COALESCE(NULLIF(?, ''), signatures),
+              check_latency_ms = ? WHERE id = ?`
+	result, err := tx.Exec(query, expiryNull, update.CheckedAt, errorNull, update.Issuer, update.Fingerprint, update.CertFingerprint,
+		strings.Join(update.SANs, ","), update.Warning, update.IssuedAt, update.KeyType, strings.Join(update.Signatures, ","),
+		latencyNull, update.DomainID.Uint())
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	// Keep a record of every check for the domain's history
+	historyQuery := `INSERT INTO check_history (domain_id, checked_at, expiry_date, error, fingerprint) VALUES (?, ?, ?, ?, ?)`
+	_, err = tx.Exec(historyQuery, update.DomainID.Uint(), update.CheckedAt, expiryNull, errorNull, update.Fingerprint)
+	return err == nil, err
+}
+
+// GetCheckHistory returns the most recent checks of a domain, newest first
+func (r *Repository) GetCheckHistory(domainID types.DomainID, limit int) ([]CheckRecord, error) {
+	query := `SELECT id, domain_id, checked_at, expiry_date, error, fingerprint FROM check_history
+              WHERE domain_id = ? ORDER BY checked_at DESC, id DESC LIMIT ?`
+	rows, err := r.db.Query(query, domainID.Uint(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanCheckRecords(rows)
+}
+
+// GetExpiryHistory returns every check of a domain that found a certificate, oldest first
+func (r *Repository) GetExpiryHistory(domainID types.DomainID) ([]CheckRecord, error) {
+	query := `SELECT id, domain_id, checked_at, expiry_date, error, fingerprint FROM check_history
+              WHERE domain_id = ? AND expiry_date IS NOT NULL ORDER BY checked_at, id`
+	rows, err := r.db.Query(query, domainID.Uint())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanCheckRecords(rows)
+}
+
+// scanCheckRecords reads check history rows in the column order of GetCheckHistory
+func scanCheckRecords(rows *sql.Rows) ([]CheckRecord, error) {
+	records := []CheckRecord{}
+	for rows.Next() {
+		var id, recordDomainID uint
+		var checkedAt time.Time
+		var expiryDate sql.NullTime
+		var checkError sql.NullString
+		var fingerprint string
+		if err := rows.Scan(&id, &recordDomainID, &checkedAt, &expiryDate, &checkError, &fingerprint); err != nil {
+			return nil, err
+		}
+
+		record := CheckRecord{
+			ID:          id,
+			DomainID:    types.DomainID(recordDomainID),
+			CheckedAt:   checkedAt,
+			Fingerprint: fingerprint,
+		}
+		if expiryDate.Valid {
+			ed := types.NewExpiryDate(expiryDate.Time)
+			record.ExpiryDate = &ed
+		}
+		if checkError.Valid {
+			le := NewLastError(checkError.String)
+			record.Error = &le
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// DeleteCheckHistoryBefore removes history entries recorded before the cutoff.
+//
+// Returns how many entries were removed
+func (r *Repository) DeleteCheckHistoryBefore(cutoff time.Time) (int64, error) {
+	result, err := r.writer.Exec(`DELETE FROM check_history WHERE checked_at < ?`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// CountCheckHistoryBefore returns how many history entries were recorded before the cutoff
+func (r *Repository) CountCheckHistoryBefore(cutoff time.Time) (int64, error) {
+	var count int64
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM check_history WHERE checked_at < ?`, cutoff).Scan(&count)
+	return count, err
+}
+
+// UpdateCheckSchedule sets the cron expression used to schedule a domain's checks
+func (r *Repository) UpdateCheckSchedule(domainID types.DomainID, schedule string) error {
+	result, err := r.writer.Exec(`UPDATE domains SET check_schedule = ? WHERE id = ?`, schedule, domainID.Uint())
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("domain with ID %d %w", domainID.Uint(), ErrNotFound)
+	}
+	return nil
+}
+
+// UpdateTags replaces the tags of a domain
+func (r *Repository) UpdateTags(domainID types.DomainID, tags []string) error {
+	result, err := r.writer.Exec(`UPDATE domains SET tags = ? WHERE id = ?`, strings.Join(NormalizeTags(tags), ","), domainID.Uint())
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("domain with ID %d %w", domainID.Uint(), ErrNotFound)
+	}
+	return nil
+}
+
+// UpdateIssuer records the CA of a domain's certificate
+func (r *Repository) UpdateIssuer(domainID types.DomainID, issuer string) error {
+	result, err := r.writer.Exec(`UPDATE domains SET issuer = ? WHERE id = ?`, issuer, domainID.Uint())
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("domain with ID %d %w", domainID.Uint(), ErrNotFound)
+	}
+	return nil
+}
+
+// UpdateFingerprint records the SSH host key of a domain, an empty fingerprint accepts whichever key is seen next
+func (r *Repository) UpdateFingerprint(domainID types.DomainID, fingerprint string) error {
+	result, err := r.writer.Exec(`UPDATE domains SET fingerprint = ? WHERE id = ?`, fingerprint, domainID.Uint())
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("domain with ID %d %w", domainID.Uint(), ErrNotFound)
+	}
+	return nil
+}
+
+// UpdateCertificate records the fingerprint, DNS names and details of the certificate a domain serves
+func (r *Repository) UpdateCertificate(domainID types.DomainID, cert CertificateDetails) error {
+	result, err := r.writer.Exec(`UPDATE domains SET cert_fingerprint = ?, sans = ?, issued_at = ?, key_type = ?, signatures = ? WHERE id = ?`,
+		cert.Fingerprint, strings.Join(NormalizeSANs(cert.SANs), ","), cert.IssuedAt, cert.KeyType,
+		strings.Join(cert.Signatures, ","), domainID.Uint())
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("domain with ID %d %w", domainID.Uint(), ErrNotFound)
+	}
+	return nil
+}
+
+// UpdateWarning records a problem with a domain's certificate that is otherwise fine, nil when there is none
+func (r *Repository) UpdateWarning(domainID types.DomainID, warning *string) error {
+	result, err := r.writer.Exec(`UPDATE domains SET warning = ? WHERE id = ?`, warning, domainID.Uint())
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("domain with ID %d %w", domainID.Uint(), ErrNotFound)
+	}
+	return nil
+}
+
+// UpdateNextChecks records when domains are due to be checked again in a single transaction, skipping domains
+// that no longer exist
+func (r *Repository) UpdateNextChecks(next map[types.DomainID]time.Time) error {
+	if len(next) == 0 {
+		return nil
+	}
+	return r.writer.Transaction(func(tx *sql.Tx) error {
+		for domainID, at := range next {
+			if _, err := tx.Exec(`UPDATE domains SET next_check_at = ? WHERE id = ?`, at, domainID.Uint()); err != nil {
+				return fmt.Errorf("failed to update domain %d: %w", domainID.Uint(), err)
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateRegistration records when a domain's registration expires, nil when the lookup couldn't tell
+func (r *Repository) UpdateRegistration(domainID types.DomainID, expiry *time.Time, checkedAt time.Time) error {
+	result, err := r.writer.Exec(`UPDATE domains SET registration_expiry = ?, registration_checked = ? WHERE id = ?`,
+		expiry, checkedAt, domainID.Uint())
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("domain with ID %d %w", domainID.Uint(), ErrNotFound)
+	}
+	return nil
+}
+
+// UpdateDNSExpectations replaces the records a domain is expected to have, forgetting the last DNS check
+func (r *Repository) UpdateDNSExpectations(domainID types.DomainID, expectations []DNSExpectation) error {
+	result, err := r.writer.Exec(`UPDATE domains SET dns_expectations = ?, dns_error = NULL, dns_checked = NULL WHERE id = ?`,
+		FormatDNSExpectations(expectations), domainID.Uint())
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("domain with ID %d %w", domainID.Uint(), ErrNotFound)
+	}
+	return nil
+}
+
+// UpdateDNSCheck records how a domain's records differed from its expectations, nil when they matched
+func (r *Repository) UpdateDNSCheck(domainID types.DomainID, mismatch *string, checkedAt time.Time) error {
+	result, err := r.writer.Exec(`UPDATE domains SET dns_error = ?, dns_checked = ? WHERE id = ?`,
+		mismatch, checkedAt, domainID.Uint())
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("domain with ID %d %w", domainID.Uint(), ErrNotFound)
+	}
+	return nil
+}
+
+// UpdateExpectedSANs replaces the DNS names a domain's certificate has to cover
+func (r *Repository) UpdateExpectedSANs(domainID types.DomainID, sans []string) error {
+	result, err := r.writer.Exec(`UPDATE domains SET expected_sans = ? WHERE id = ?`,
+		strings.Join(NormalizeSANs(sans), ","), domainID.Uint())
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("domain with ID %d %w", domainID.Uint(), ErrNotFound)
+	}
+	return nil
+}
+
+// UpdateAutoRenewal records how many days before expiry a domain's certificate renews automatically and what
+// renews it, zero days when it doesn't
+func (r *Repository) UpdateAutoRenewal(domainID types.DomainID, days int, via string) error {
+	result, err := r.writer.Exec(`UPDATE domains SET auto_renew_days = ?, auto_renew_via = ? WHERE id = ?`,
+		days, via, domainID.Uint())
 	if err != nil {
 		return err
 	}
@@ -231,7 +723,7 @@ func (r *Repository) UpdateSSLInfo(domainID types.DomainID, expiryDate *time.Tim
 		return err
 	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("domain with ID %d not found", domainID.Uint())
+		return fmt.Errorf("domain with ID %d %w", domainID.Uint(), ErrNotFound)
 	}
 	return nil
 }
@@ -2,67 +2,288 @@ package domain
 
 import (
 	"context"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/samokw/ssl_tracker/internal/cron"
 	"github.com/samokw/ssl_tracker/internal/ssl"
 	"github.com/samokw/ssl_tracker/internal/types"
 )
 
 type Service struct {
-	domainRepo *Repository
+	domainRepo DomainRepository
 	sslService *ssl.CertService
+	checker    ssl.Checker
+	teams      Teams
+	thresholds StatusThresholds
+	policies   []Policy
+	activity   ActivityLog
+	incidents  Incidents
 }
 
-func NewService(domainRepo *Repository, sslService *ssl.CertService) *Service {
-	return &Service{
+// Teams tells which teams a user belongs to, team.Service does this
+type Teams interface {
+	GetTeamIDs(userID types.UserID) ([]types.TeamID, error)
+}
+
+// Incidents resolves the on-call incidents open for a domain, notification.Alerter does this
+type Incidents interface {
+	ResolveDomain(ctx context.Context, d Domain) error
+}
+
+// NewService creates a service storing every result of sslService, which it should be the only user of. A nil
+// sslService checks one domain at a time without a worker pool
+func NewService(domainRepo DomainRepository, sslService *ssl.CertService) *Service {
+	s := &Service{
 		domainRepo: domainRepo,
 		sslService: sslService,
+		checker:    ssl.DefaultChecker,
+		thresholds: DefaultStatusThresholds,
+	}
+	if sslService != nil {
+		// Results are stored in batches so a large sweep doesn't issue one write per domain
+		sslService.SetBatchResultHandler(s.storeResults)
+	}
+	return s
+}
+
+// SetTeams lets users share domains with the teams they belong to, without it every domain is private
+func (s *Service) SetTeams(teams Teams) {
+	s.teams = teams
+}
+
+// SetIncidents resolves the incidents open for domains as they are removed, they stay open unless set
+func (s *Service) SetIncidents(incidents Incidents) {
+	s.incidents = incidents
+}
+
+// SetChecker changes what checks domains and validates the names added, ssl.DefaultChecker unless set. The worker
+// pool checks with it too, so it must be set before the first check
+func (s *Service) SetChecker(checker ssl.Checker) {
+	s.checker = checker
+	if s.sslService != nil {
+		s.sslService.SetChecker(checker)
+	}
+}
+
+// PoolSize is how many checks the worker pool runs at once and how many more it queues
+func (s *Service) PoolSize() (ssl.PoolSize, error) {
+	if s.sslService == nil {
+		return ssl.PoolSize{}, errNoPool
+	}
+	return s.sslService.PoolSize(), nil
+}
+
+// PoolStats is what the worker pool is doing and has done
+func (s *Service) PoolStats() (ssl.PoolStats, error) {
+	if s.sslService == nil {
+		return ssl.PoolStats{}, errNoPool
+	}
+	return s.sslService.PoolStats(), nil
+}
+
+// ResizePool changes how many checks the worker pool runs at once and how many more it queues, taking effect
+// right away
+func (s *Service) ResizePool(size ssl.PoolSize) error {
+	if s.sslService == nil {
+		return errNoPool
+	}
+	if err := s.sslService.ResizePool(size); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+	return nil
+}
+
+// SetStatusThresholds sets the days before expiry a certificate is expiring soon and critical in Status
+func (s *Service) SetStatusThresholds(t StatusThresholds) {
+	s.thresholds = t
+}
+
+// Status is the state of a domain's certificate with the configured thresholds, what every view of a domain should
+// show rather than working it out again
+func (s *Service) Status(d Domain) DomainStatus {
+	return d.StatusWith(s.thresholds, time.Now())
+}
+
+// SetCompliancePolicies sets the org-wide policies Compliance checks certificates against
+func (s *Service) SetCompliancePolicies(policies []Policy) {
+	s.policies = policies
+}
+
+// Compliance is how a domain's certificate measures up to the configured policies
+func (s *Service) Compliance(d Domain) ComplianceStatus {
+	return d.ComplianceWith(s.policies, time.Now())
+}
+
+// memberOf reports whether a user belongs to a team
+func (s *Service) memberOf(userID types.UserID, teamID types.TeamID) (bool, error) {
+	if s.teams == nil {
+		return false, nil
 	}
+	teamIDs, err := s.teams.GetTeamIDs(userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get teams: %w", err)
+	}
+	return slices.Contains(teamIDs, teamID), nil
+}
+
+// CanAccess reports whether a user may see and manage a domain, their own or one shared with their team
+func (s *Service) CanAccess(userID types.UserID, d Domain) (bool, error) {
+	if d.TeamID == 0 {
+		return d.UserID == userID, nil
+	}
+	return s.memberOf(userID, d.TeamID)
 }
 
 func (s *Service) AddDomain(userID types.UserID, domainName string) (*Domain, error) {
-	err := ssl.ValidateHostnameDNS(domainName)
+	return s.AddTeamDomain(userID, 0, domainName)
+}
+
+// AddTeamDomain tracks a domain shared with one of the user's teams, zero keeps it private like AddDomain.
+//
+// The first check is queued on the worker pool rather than waited for, the domain is returned unchecked. Without a
+// worker pool it is checked before returning
+func (s *Service) AddTeamDomain(userID types.UserID, teamID types.TeamID, domainName string) (*Domain, error) {
+	domain, err := s.AddTeamDomainUnchecked(userID, teamID, domainName)
 	if err != nil {
 		return nil, err
 	}
+
+	if s.sslService != nil {
+		// The result is stored by the result handler, CheckDomainSSL waits for it
+		s.sslService.Start()
+		s.sslService.CheckDomain(domain.DomainName.String(), int(domain.DomainID), int(userID))
+		return domain, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cert, err := s.checker.CheckSSLCertificate(ctx, domain.DomainName.String())
+	s.storeCheck(*domain, cert, err)
+
+	return domain, nil
+}
+
+// AddTeamDomainUnchecked tracks a domain like AddTeamDomain without checking it, for callers that have to set
+// something up before its first check can succeed.
+//
+// Hostnames are stored lower-case without a trailing dot, see ssl.NormalizeTarget
+func (s *Service) AddTeamDomainUnchecked(userID types.UserID, teamID types.TeamID, domainName string) (*Domain, error) {
+	domainName = ssl.NormalizeTarget(domainName)
+	err := ssl.Validate(s.checker, domainName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+	if teamID != 0 {
+		member, err := s.memberOf(userID, teamID)
+		if err != nil {
+			return nil, err
+		}
+		if !member {
+			return nil, fmt.Errorf("team with ID %d %w", teamID.Uint(), ErrNotFound)
+		}
+	}
 	domain := Domain{
 		UserID:     userID,
 		DomainName: NewDomainName(domainName),
 		CreatedAt:  NewCreatedAt(time.Now()),
 		IsActive:   true,
+		TeamID:     teamID,
 	}
 	err = s.domainRepo.CreateDomain(&domain)
 	if err != nil {
 		return nil, err
 	}
+	s.record(domain, ActivityAdded, "", domain.CreatedAt.Time())
+	return &domain, nil
+}
 
-	hostname, err := ssl.NewHostname(domainName)
+// GetUsersDomains returns a user's own domains followed by those shared with their teams
+func (s *Service) GetUsersDomains(userID types.UserID) ([]Domain, error) {
+	domains, err := s.domainRepo.GetDomainsByUserID(userID)
+	if err != nil || s.teams == nil {
+		return domains, err
+	}
+	teamIDs, err := s.teams.GetTeamIDs(userID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid hostname: %w", err)
+		return nil, fmt.Errorf("failed to get teams: %w", err)
 	}
+	for _, teamID := range teamIDs {
+		shared, err := s.domainRepo.GetDomainsByTeamID(teamID)
+		if err != nil {
+			return nil, err
+		}
+		domains = append(domains, shared...)
+	}
+	return domains, nil
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	cert, err := ssl.CheckSSLCertificate(ctx, hostname)
+// ShareDomain moves a domain the user can access to one of their teams, zero makes it private to whoever added it again
+func (s *Service) ShareDomain(userID types.UserID, domainID types.DomainID, teamID types.TeamID) error {
+	d, err := s.domainRepo.GetDomainByID(domainID)
 	if err != nil {
-		errorStr := err.Error()
-		s.domainRepo.UpdateSSLInfo(domain.DomainID, nil, &errorStr)
-	} else {
-		expiryTime := cert.ExpiryDate.Time()
-		s.domainRepo.UpdateSSLInfo(domain.DomainID, &expiryTime, nil)
+		return err
+	}
+	ok, err := s.CanAccess(userID, *d)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("domain with ID %d %w", domainID.Uint(), ErrNotFound)
+	}
+	if d.TeamID == teamID {
+		return nil
 	}
 
-	return &domain, nil
+	if teamID != 0 {
+		member, err := s.memberOf(userID, teamID)
+		if err != nil {
+			return err
+		}
+		if !member {
+			return fmt.Errorf("team with ID %d %w", teamID.Uint(), ErrNotFound)
+		}
+		existing, err := s.domainRepo.FindTeamDomain(teamID, d.DomainName.String())
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return fmt.Errorf("domain %s %w for this team", d.DomainName.String(), ErrDuplicate)
+		}
+	}
+	return s.domainRepo.UpdateTeam(domainID, teamID)
 }
 
-func (s *Service) GetUsersDomains(userID types.UserID) ([]Domain, error) {
-	return s.domainRepo.GetDomainsByUserID(userID)
+// GetDomain looks up a single domain by its ID
+func (s *Service) GetDomain(domainID types.DomainID) (*Domain, error) {
+	return s.domainRepo.GetDomainByID(domainID)
 }
 
+// RemoveDomain stops tracking a domain, resolving the incidents open for it first. An on-call provider failing
+// doesn't keep the domain, the incident is logged so it can be closed by hand
 func (s *Service) RemoveDomain(domainID types.DomainID) error {
-	return s.domainRepo.DeleteDomain(domainID)
+	d, err := s.domainRepo.GetDomainByID(domainID)
+	if err != nil {
+		return err
+	}
+	if s.incidents != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := s.incidents.ResolveDomain(ctx, *d)
+		cancel()
+		if err != nil {
+			slog.Error("Failed to resolve incidents of removed domain", "domain", d.DomainName.String(), "error", err)
+		}
+	}
+	if err := s.domainRepo.DeleteDomain(domainID); err != nil {
+		return err
+	}
+	s.record(*d, ActivityDeleted, "", time.Now())
+	return nil
 }
 
 // CheckDomainSSL checks the SSL certificate for a specific domain
@@ -73,28 +294,274 @@ func (s *Service) CheckDomainSSL(domainID types.DomainID) error {
 		return fmt.Errorf("failed to get domain: %w", err)
 	}
 
-	// Validate and create hostname
+	// Check SSL certificate, reading it from disk for file targets
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if s.sslService != nil {
+		// Joins a sweep that has the domain queued rather than checking it twice, the result is stored with the
+		// sweep's
+		result := s.sslService.CheckNow(ctx, domain.DomainName.String(), int(domain.DomainID), int(domain.UserID))
+		return result.StoreError
+	}
+	cert, err := s.checker.CheckSSLCertificate(ctx, domain.DomainName.String())
+	return s.storeCheck(*domain, cert, err)
+}
+
+// storeCheck records the outcome of a check made outside the worker pool
+func (s *Service) storeCheck(d Domain, cert *ssl.SSLCertificate, checkErr error) error {
+	if checkErr == nil {
+		checkErr = verifyHostKey(d, cert)
+	}
+	if checkErr == nil {
+		checkErr = verifyExpectedSANs(d, cert)
+	}
+	if checkErr != nil {
+		errorStr := checkErr.Error()
+		if err := s.domainRepo.UpdateSSLInfo(d.DomainID, nil, &errorStr); err != nil {
+			return err
+		}
+		s.recordCheck(d, nil, &errorStr, time.Now())
+		return nil
+	}
+
+	if err := s.domainRepo.UpdateSSLInfo(d.DomainID, expiryOf(cert), nil); err != nil {
+		return err
+	}
+	s.recordCheck(d, expiryOf(cert), nil, time.Now())
+	if err := s.domainRepo.UpdateIssuer(d.DomainID, cert.Issuer); err != nil {
+		return err
+	}
+	if err := s.domainRepo.UpdateWarning(d.DomainID, warningOf(cert)); err != nil {
+		return err
+	}
+	if cert.CertFingerprint != "" {
+		if err := s.domainRepo.UpdateCertificate(d.DomainID, certificateDetails(cert)); err != nil {
+			return err
+		}
+	}
+	if cert.Fingerprint != "" {
+		return s.domainRepo.UpdateFingerprint(d.DomainID, cert.Fingerprint)
+	}
+	return nil
+}
+
+// verifyHostKey fails when an SSH server presents a different host key from the one last seen, which stays
+// recorded until the new one is accepted
+func verifyHostKey(d Domain, cert *ssl.SSLCertificate) error {
+	if d.Fingerprint == "" || cert.Fingerprint == "" || cert.Fingerprint == d.Fingerprint {
+		return nil
+	}
+	return fmt.Errorf("%w: %s presented %s instead of %s", ErrHostKeyChanged, d.DomainName, cert.Fingerprint, d.Fingerprint)
+}
+
+// verifyExpectedSANs fails when a certificate doesn't cover every name the domain expects it to, e.g. after a
+// renewal dropped one
+func verifyExpectedSANs(d Domain, cert *ssl.SSLCertificate) error {
+	if len(d.ExpectedSANs) == 0 {
+		return nil
+	}
+	missing := d.MissingSANs(NormalizeSANs(cert.SANs))
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrMissingSANs, strings.Join(missing, ", "))
+}
+
+// SetExpectedSANs replaces the DNS names a domain's certificate has to cover, none stops verifying them
+func (s *Service) SetExpectedSANs(domainID types.DomainID, names []string) error {
+	d, err := s.domainRepo.GetDomainByID(domainID)
+	if err != nil {
+		return err
+	}
+	names = NormalizeSANs(names)
+	if len(names) > 0 && ssl.IsSSHTarget(d.DomainName.String()) {
+		return fmt.Errorf("%w: SSH host keys don't cover names", ErrInvalidInput)
+	}
+	for _, name := range names {
+		if err := ssl.ValidateHostname(strings.TrimPrefix(name, "*.")); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidInput, name, err)
+		}
+	}
+	return s.domainRepo.UpdateExpectedSANs(domainID, names)
+}
+
+// AcceptHostKey trusts whichever host key an SSH target presents now, after it changed, and checks it again
+func (s *Service) AcceptHostKey(domainID types.DomainID) error {
+	d, err := s.domainRepo.GetDomainByID(domainID)
+	if err != nil {
+		return err
+	}
+	if !ssl.IsSSHTarget(d.DomainName.String()) {
+		return fmt.Errorf("%w: %s is not an SSH target", ErrInvalidInput, d.DomainName)
+	}
+	if err := s.domainRepo.UpdateFingerprint(domainID, ""); err != nil {
+		return err
+	}
+	return s.CheckDomainSSL(domainID)
+}
+
+// expiryOf is when a certificate expires, nil for SSH host keys, which don't
+func expiryOf(cert *ssl.SSLCertificate) *time.Time {
+	if cert.ExpiryDate.Time().IsZero() {
+		return nil
+	}
+	expiryTime := cert.ExpiryDate.Time()
+	return &expiryTime
+}
+
+// GetCertificateChain fetches the certificate chain currently served by a domain, or stored in its file
+func (s *Service) GetCertificateChain(domainID types.DomainID) ([]*x509.Certificate, error) {
+	domain, err := s.domainRepo.GetDomainByID(domainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get domain: %w", err)
+	}
+
+	if ssl.IsFileTarget(domain.DomainName.String()) {
+		return ssl.LoadCertificateFile(ssl.FilePath(domain.DomainName.String()))
+	}
+
 	hostname, err := ssl.NewHostname(domain.DomainName.String())
 	if err != nil {
-		// Update with error
-		errorStr := err.Error()
-		return s.domainRepo.UpdateSSLInfo(domainID, nil, &errorStr)
+		return nil, fmt.Errorf("invalid hostname: %w", err)
 	}
 
-	// Check SSL certificate
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	cert, err := ssl.CheckSSLCertificate(ctx, hostname)
+	return ssl.FetchCertificateChain(ctx, hostname)
+}
+
+// HandshakeLog connects to a domain the way its checks do and describes each step, to see where a failing check
+// goes wrong. Only TLS hosts have one, not files or SSH servers
+func (s *Service) HandshakeLog(domainID types.DomainID) ([]string, error) {
+	domain, err := s.domainRepo.GetDomainByID(domainID)
 	if err != nil {
-		// Update with error
-		errorStr := err.Error()
-		return s.domainRepo.UpdateSSLInfo(domainID, nil, &errorStr)
+		return nil, fmt.Errorf("failed to get domain: %w", err)
 	}
 
-	// Update with successful result
-	expiryTime := cert.ExpiryDate.Time()
-	return s.domainRepo.UpdateSSLInfo(domainID, &expiryTime, nil)
+	name := domain.DomainName.String()
+	if ssl.IsFileTarget(name) || ssl.IsSSHTarget(name) {
+		return nil, fmt.Errorf("%s has no TLS handshake to log", name)
+	}
+	hostname, err := ssl.NewHostname(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hostname: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	return ssl.HandshakeLog(ctx, hostname), nil
+}
+
+// GetCheckHistory returns up to limit of the most recent checks of a domain
+func (s *Service) GetCheckHistory(domainID types.DomainID, limit int) ([]CheckRecord, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	return s.domainRepo.GetCheckHistory(domainID, limit)
+}
+
+// GetRenewalCadence works out how a domain's certificate has been renewed from its check history, and when it
+// is expected to be renewed next
+func (s *Service) GetRenewalCadence(d Domain) (Cadence, error) {
+	history, err := s.domainRepo.GetExpiryHistory(d.DomainID)
+	if err != nil {
+		return Cadence{}, err
+	}
+	return RenewalCadence(history, d.ExpiryTime()), nil
+}
+
+// PruneCheckHistory deletes check history older than the retention period.
+//
+// Returns the number of entries deleted, or that would be deleted when dryRun is set
+func (s *Service) PruneCheckHistory(retention time.Duration, dryRun bool) (int64, error) {
+	if retention <= 0 {
+		return 0, fmt.Errorf("%w: retention must be positive, got %s", ErrInvalidInput, retention)
+	}
+	cutoff := time.Now().Add(-retention)
+	if dryRun {
+		return s.domainRepo.CountCheckHistoryBefore(cutoff)
+	}
+	return s.domainRepo.DeleteCheckHistoryBefore(cutoff)
+}
+
+// GetActiveDomains returns the active domains of every user
+func (s *Service) GetActiveDomains() ([]Domain, error) {
+	return s.domainRepo.GetActiveDomains()
+}
+
+// SubscribeResults receives every certificate check result from the worker pool after it has been stored.
+//
+// Returns the channel and a function to unsubscribe
+func (s *Service) SubscribeResults(buffer int) (<-chan ssl.Result, func()) {
+	return s.sslService.Subscribe(buffer)
+}
+
+// FindDomainByName looks up a domain the user can access by its name, preferring their own over a team's
+func (s *Service) FindDomainByName(userID types.UserID, domainName string) (*Domain, error) {
+	domainName = ssl.NormalizeTarget(domainName)
+	domains, err := s.GetUsersDomains(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range domains {
+		if d.DomainName.String() == domainName {
+			return &d, nil
+		}
+	}
+	return nil, fmt.Errorf("domain %s %w", domainName, ErrNotFound)
+}
+
+// SetCheckSchedule sets the cron expression for a domain's checks, an empty expression clears it
+func (s *Service) SetCheckSchedule(domainID types.DomainID, schedule string) error {
+	schedule = strings.TrimSpace(schedule)
+	if schedule != "" {
+		if _, err := cron.Parse(schedule); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidInput, err)
+		}
+	}
+	return s.domainRepo.UpdateCheckSchedule(domainID, schedule)
+}
+
+// SetTags replaces the tags of a domain
+func (s *Service) SetTags(domainID types.DomainID, tags []string) error {
+	return s.domainRepo.UpdateTags(domainID, tags)
+}
+
+// SetRegistration records when a domain's registration expires as looked up at checkedAt, nil when unknown
+func (s *Service) SetRegistration(domainID types.DomainID, expiry *time.Time, checkedAt time.Time) error {
+	return s.domainRepo.UpdateRegistration(domainID, expiry, checkedAt)
+}
+
+// SetDNSExpectations replaces the records a domain is expected to have, none stops checking its DNS
+func (s *Service) SetDNSExpectations(domainID types.DomainID, expectations []DNSExpectation) error {
+	return s.domainRepo.UpdateDNSExpectations(domainID, expectations)
+}
+
+// SetDNSCheck records how a domain's records differed from its expectations as looked up at checkedAt, nil
+// when they matched
+func (s *Service) SetDNSCheck(domainID types.DomainID, mismatch *string, checkedAt time.Time) error {
+	return s.domainRepo.UpdateDNSCheck(domainID, mismatch, checkedAt)
+}
+
+// SetAutoRenewal records that a domain's certificate renews automatically days before it expires through via,
+// e.g. cert-manager, so it is alerted on when it doesn't. Zero days stops expecting it
+func (s *Service) SetAutoRenewal(domainID types.DomainID, days int, via string) error {
+	if days < 0 {
+		return fmt.Errorf("%w: auto-renewal days must not be negative, got %d", ErrInvalidInput, days)
+	}
+	via = strings.TrimSpace(via)
+	if days == 0 {
+		via = ""
+	}
+	return s.domainRepo.UpdateAutoRenewal(domainID, days, via)
+}
+
+// SetNextChecks records when domains are due to be checked again
+func (s *Service) SetNextChecks(next map[types.DomainID]time.Time) error {
+	return s.domainRepo.UpdateNextChecks(next)
 }
 
 // CheckAllDomainsSSLSync checks SSL certificates for all domains synchronously and waits for completion
@@ -104,41 +571,155 @@ func (s *Service) CheckAllDomainsSSLSync(userID types.UserID) error {
 		return fmt.Errorf("failed to get domains: %w", err)
 	}
 
+	return s.CheckDomainsSSLSync(domains)
+}
+
+// CheckFailedDomainsSSLSync checks again only the domains whose last check failed and waits for completion, to
+// retry transient errors without a full sweep
+func (s *Service) CheckFailedDomainsSSLSync(userID types.UserID) error {
+	domains, err := s.GetUsersDomains(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get domains: %w", err)
+	}
+
+	return s.CheckDomainsSSLSync(FailedDomains(domains))
+}
+
+// FailedDomains are the domains whose last check failed
+func FailedDomains(domains []Domain) []Domain {
+	var failed []Domain
+	for _, d := range domains {
+		if d.LastError != nil {
+			failed = append(failed, d)
+		}
+	}
+	return failed
+}
+
+// CheckDomainsSSLSync checks SSL certificates for the given domains concurrently and waits for completion.
+//
+// Domains another caller already has queued or running aren't checked again, their pending result is waited for.
+// Failed checks are stored like any other result, a *StoreError names the domains whose results couldn't be
+func (s *Service) CheckDomainsSSLSync(domains []Domain) error {
 	if len(domains) == 0 {
 		return nil
 	}
 
-	// Use a channel to track completion
-	done := make(chan bool, len(domains))
-
 	// Start the SSL service (now safe to call multiple times)
 	s.sslService.Start()
 
-	// Set up result handler to update the database and signal completion
-	s.sslService.SetResultHandler(func(result ssl.Result) {
-		if result.Error != nil {
-			errorStr := result.Error.Error()
-			s.domainRepo.UpdateSSLInfo(types.DomainID(result.Task.DomainID), nil, &errorStr)
-		} else {
-			expiryTime := result.Certificate.ExpiryDate.Time()
-			s.domainRepo.UpdateSSLInfo(types.DomainID(result.Task.DomainID), &expiryTime, nil)
-		}
-		done <- true
-	})
-
 	// Submit all domains to the worker pool
-	for _, domain := range domains {
-		s.sslService.CheckDomain(
+	pending := make([]<-chan ssl.Result, len(domains))
+	for i, domain := range domains {
+		pending[i] = s.sslService.CheckDomain(
 			domain.DomainName.String(),
 			int(domain.DomainID),
-			int(userID),
+			int(domain.UserID),
 		)
 	}
 
-	// Wait for all domains to be processed
-	for i := 0; i < len(domains); i++ {
-		<-done
+	// Wait for all domains to be processed and stored
+	lost := map[string]error{}
+	for i, ch := range pending {
+		result, ok := <-ch
+		switch {
+		case !ok:
+			lost[domains[i].DomainName.String()] = errors.New("the certificate service stopped before checking it")
+		case result.StoreError != nil:
+			lost[domains[i].DomainName.String()] = result.StoreError
+		}
+	}
+	if len(lost) > 0 {
+		return &StoreError{Domains: lost}
 	}
-
 	return nil
 }
+
+// storeResults stores a batch of worker pool results
+func (s *Service) storeResults(results []ssl.Result) {
+	updates := make([]SSLUpdate, len(results))
+	// before are the domains as they were before the checks, nil for those that no longer exist
+	before := make([]*Domain, len(results))
+	for i := range results {
+		before[i], _ = s.domainRepo.GetDomainByID(types.DomainID(results[i].Task.DomainID))
+		s.verifyResult(&results[i], before[i])
+		updates[i] = newSSLUpdate(results[i])
+	}
+	if err := s.domainRepo.UpdateSSLInfoBatch(updates); err != nil {
+		// The batch is one transaction, none of it was stored
+		slog.Error("Failed to store check results", "count", len(updates), "error", err)
+		for i := range results {
+			results[i].StoreError = err
+		}
+		return
+	}
+	for i, u := range updates {
+		if before[i] != nil {
+			s.recordCheck(*before[i], u.ExpiryDate, u.Error, u.CheckedAt)
+		}
+	}
+}
+
+// newSSLUpdate converts a worker pool result into the update stored for its domain
+func newSSLUpdate(result ssl.Result) SSLUpdate {
+	update := SSLUpdate{
+		DomainID:  types.DomainID(result.Task.DomainID),
+		CheckedAt: result.CheckedAt,
+		Latency:   result.Latency,
+	}
+	if result.Error != nil {
+		errorStr := result.Error.Error()
+		update.Error = &errorStr
+	} else {
+		update.ExpiryDate = expiryOf(result.Certificate)
+		update.Issuer = result.Certificate.Issuer
+		update.Fingerprint = result.Certificate.Fingerprint
+		update.CertFingerprint = result.Certificate.CertFingerprint
+		update.SANs = NormalizeSANs(result.Certificate.SANs)
+		update.Warning = warningOf(result.Certificate)
+		details := certificateDetails(result.Certificate)
+		update.IssuedAt, update.KeyType, update.Signatures = details.IssuedAt, details.KeyType, details.Signatures
+	}
+	return update
+}
+
+// certificateDetails are what is recorded about the certificate a check saw
+func certificateDetails(cert *ssl.SSLCertificate) CertificateDetails {
+	details := CertificateDetails{
+		Fingerprint: cert.CertFingerprint,
+		SANs:        cert.SANs,
+		KeyType:     cert.KeyType,
+		Signatures:  cert.Signatures,
+	}
+	if !cert.IssuedAt.IsZero() {
+		issuedAt := cert.IssuedAt
+		details.IssuedAt = &issuedAt
+	}
+	return details
+}
+
+// warningOf is the warning a check found about a certificate, nil when there is none
+func warningOf(cert *ssl.SSLCertificate) *string {
+	if cert.Warning == "" {
+		return nil
+	}
+	warning := cert.Warning
+	return &warning
+}
+
+// verifyResult turns the result of an SSH check that saw a changed host key, or of a certificate missing names
+// the domain expects, into a failure before it is stored and passed on to subscribers. d is the domain as it was
+// before the check, nil when it no longer exists
+func (s *Service) verifyResult(result *ssl.Result, d *Domain) {
+	if result.Error != nil || d == nil {
+		return
+	}
+	err := verifyHostKey(*d, result.Certificate)
+	if err == nil {
+		err = verifyExpectedSANs(*d, result.Certificate)
+	}
+	if err != nil {
+		result.Error = err
+		result.Certificate = nil
+	}
+}
@@ -0,0 +1,320 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestService creates a service over an in-memory repository holding one domain.
+func newTestService(t *testing.T) (*Service, *MemoryRepository, types.DomainID) {
+	t.Helper()
+
+	repo := NewMemoryRepository()
+	d := Domain{
+		UserID:     types.UserID(1),
+		DomainName: NewDomainName("example.com"),
+		CreatedAt:  NewCreatedAt(time.Now()),
+		IsActive:   true,
+		Tags:       []string{"Prod", "web"},
+	}
+	require.NoError(t, repo.CreateDomain(&d))
+	return NewService(repo, nil), repo, d.DomainID
+}
+
+// TestService_FindDomainByName - only the owner's domain is found.
+func TestService_FindDomainByName(t *testing.T) {
+	s, _, id := newTestService(t)
+
+	d, err := s.FindDomainByName(types.UserID(1), "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, id, d.DomainID)
+	assert.Equal(t, []string{"prod", "web"}, d.Tags)
+
+	_, err = s.FindDomainByName(types.UserID(2), "example.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+// TestService_SetCheckSchedule - invalid cron expressions are rejected before they are stored.
+func TestService_SetCheckSchedule(t *testing.T) {
+	s, _, id := newTestService(t)
+
+	require.NoError(t, s.SetCheckSchedule(id, " 0 3 * * * "))
+	d, err := s.GetDomain(id)
+	require.NoError(t, err)
+	assert.Equal(t, "0 3 * * *", d.CheckSchedule)
+
+	assert.ErrorIs(t, s.SetCheckSchedule(id, "every day"), ErrInvalidInput)
+	assert.ErrorIs(t, s.SetCheckSchedule(types.DomainID(99), ""), ErrNotFound)
+}
+
+// TestService_SentinelErrors - failures wrap the sentinel errors while keeping their details.
+func TestService_SentinelErrors(t *testing.T) {
+	s, repo, _ := newTestService(t)
+
+	err := repo.CreateDomain(&Domain{UserID: types.UserID(1), DomainName: NewDomainName("example.com")})
+	assert.ErrorIs(t, err, ErrDuplicate)
+	assert.EqualError(t, err, "domain example.com already exists for this user")
+
+	err = repo.CreateDomain(&Domain{UserID: types.UserID(1)})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	_, err = s.GetDomain(types.DomainID(99))
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.EqualError(t, err, "domain with ID 99 not found")
+
+	_, err = s.PruneCheckHistory(0, true)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
+
+// TestService_History - results are recorded, limited and pruned.
+func TestService_History(t *testing.T) {
+	s, repo, id := newTestService(t)
+
+	checkErr := "connection refused"
+	expiry := time.Now().Add(60 * 24 * time.Hour)
+	require.NoError(t, repo.UpdateSSLInfoBatch([]SSLUpdate{
+		{DomainID: id, Error: &checkErr, CheckedAt: time.Now().AddDate(0, 0, -100)},
+		{DomainID: id, ExpiryDate: &expiry, CheckedAt: time.Now(), Issuer: "R11"},
+	}))
+
+	d, err := s.GetDomain(id)
+	require.NoError(t, err)
+	assert.Equal(t, "valid", d.Status())
+	assert.Equal(t, "R11", d.Issuer)
+
+	history, err := s.GetCheckHistory(id, 0)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Nil(t, history[0].Error, "newest first")
+
+	deleted, err := s.PruneCheckHistory(90*24*time.Hour, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	require.NoError(t, s.RemoveDomain(id))
+	history, err = s.GetCheckHistory(id, 0)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+// TestService_GetRenewalCadence - 90 day certificates renewed with 30 days left renew every 60 days.
+func TestService_GetRenewalCadence(t *testing.T) {
+	s, repo, id := newTestService(t)
+
+	start := time.Now().AddDate(0, 0, -200)
+	expiry := start.AddDate(0, 0, 40)
+	var updates []SSLUpdate
+	for day := 0; day <= 200; day++ {
+		checkedAt := start.AddDate(0, 0, day)
+		if expiry.Sub(checkedAt) <= 30*24*time.Hour {
+			expiry = checkedAt.AddDate(0, 0, 90)
+		}
+		e := expiry
+		updates = append(updates, SSLUpdate{DomainID: id, ExpiryDate: &e, CheckedAt: checkedAt})
+	}
+	require.NoError(t, repo.UpdateSSLInfoBatch(updates))
+
+	d, err := s.GetDomain(id)
+	require.NoError(t, err)
+	cadence, err := s.GetRenewalCadence(*d)
+	require.NoError(t, err)
+	assert.Len(t, cadence.Renewals, 4)
+	assert.Equal(t, 60*24*time.Hour, cadence.Interval)
+	assert.Equal(t, 30*24*time.Hour, cadence.Lead)
+	require.NotNil(t, cadence.NextRenewal)
+	assert.WithinDuration(t, expiry.AddDate(0, 0, -30), *cadence.NextRenewal, time.Minute)
+	assert.False(t, cadence.Slipped(start.AddDate(0, 0, 200)))
+	assert.True(t, cadence.Slipped(expiry.AddDate(0, 0, -20)), "Still not renewed 10 days after it should have been")
+
+	assert.Nil(t, RenewalCadence(nil, &expiry).NextRenewal, "No history, no prediction")
+}
+
+// TestService_HostKeyChanged - a host key differing from the one last seen fails the check until it is accepted.
+func TestService_HostKeyChanged(t *testing.T) {
+	fingerprint := "SHA256:first"
+	ssl.RegisterTargetChecker("hostkey-test:", func(context.Context, string) (*ssl.SSLCertificate, error) {
+		return &ssl.SSLCertificate{Fingerprint: fingerprint}, nil
+	})
+	repo := NewMemoryRepository()
+	d := Domain{UserID: 1, DomainName: NewDomainName("hostkey-test:bastion"), CreatedAt: NewCreatedAt(time.Now()), IsActive: true}
+	require.NoError(t, repo.CreateDomain(&d))
+	s := NewService(repo, nil)
+
+	require.NoError(t, s.CheckDomainSSL(d.DomainID))
+	got, err := s.GetDomain(d.DomainID)
+	require.NoError(t, err)
+	assert.Equal(t, "SHA256:first", got.Fingerprint)
+	assert.Nil(t, got.ExpiryDate, "Host keys don't expire")
+	assert.Nil(t, got.LastError)
+
+	fingerprint = "SHA256:second"
+	require.NoError(t, s.CheckDomainSSL(d.DomainID))
+	require.NoError(t, s.CheckDomainSSL(d.DomainID))
+	got, err = s.GetDomain(d.DomainID)
+	require.NoError(t, err)
+	require.NotNil(t, got.LastError, "The change keeps failing checks")
+	assert.Contains(t, got.LastError.String(), "host key changed")
+	assert.Equal(t, "SHA256:first", got.Fingerprint)
+
+	assert.ErrorIs(t, s.AcceptHostKey(d.DomainID), ErrInvalidInput, "Only SSH targets have host keys to accept")
+	require.NoError(t, repo.UpdateFingerprint(d.DomainID, ""))
+	require.NoError(t, s.CheckDomainSSL(d.DomainID))
+	got, err = s.GetDomain(d.DomainID)
+	require.NoError(t, err)
+	assert.Nil(t, got.LastError)
+	assert.Equal(t, "SHA256:second", got.Fingerprint)
+}
+
+// TestService_ExpectedSANs - checks fail while the certificate doesn't cover every expected name.
+func TestService_ExpectedSANs(t *testing.T) {
+	s, _, id := newTestService(t)
+	sans := []string{"example.com", "*.example.com"}
+	s.SetChecker(ssl.CheckerFunc(func(context.Context, string) (*ssl.SSLCertificate, error) {
+		return &ssl.SSLCertificate{ExpiryDate: types.NewExpiryDate(time.Now().AddDate(0, 2, 0)), SANs: sans}, nil
+	}))
+
+	assert.ErrorIs(t, s.SetExpectedSANs(id, []string{"example..com"}), ErrInvalidInput)
+	require.NoError(t, s.SetExpectedSANs(id, []string{"WWW.example.com", "example.com", "api.example.com"}))
+	require.NoError(t, s.CheckDomainSSL(id))
+	d, err := s.GetDomain(id)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api.example.com", "example.com", "www.example.com"}, d.ExpectedSANs)
+	assert.Nil(t, d.LastError, "The wildcard covers www and api")
+
+	sans = []string{"example.com", "www.example.com"}
+	require.NoError(t, s.CheckDomainSSL(id))
+	d, err = s.GetDomain(id)
+	require.NoError(t, err)
+	require.NotNil(t, d.LastError)
+	assert.Equal(t, ErrMissingSANs.Error()+": api.example.com", d.LastError.String())
+
+	require.NoError(t, s.SetExpectedSANs(id, nil))
+	require.NoError(t, s.CheckDomainSSL(id))
+	d, err = s.GetDomain(id)
+	require.NoError(t, err)
+	assert.Nil(t, d.LastError)
+}
+
+// TestService_CheckFailedDomains - only the domains whose last check failed are checked again.
+func TestService_CheckFailedDomains(t *testing.T) {
+	var mu sync.Mutex
+	checked := map[string]int{}
+	ssl.RegisterTargetChecker("retry-test:", func(_ context.Context, name string) (*ssl.SSLCertificate, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		checked[name]++
+		return &ssl.SSLCertificate{ExpiryDate: types.NewExpiryDate(time.Now().AddDate(0, 2, 0))}, nil
+	})
+	repo := NewMemoryRepository()
+	var ids []types.DomainID
+	for _, name := range []string{"retry-test:ok", "retry-test:failed"} {
+		d := Domain{UserID: 1, DomainName: NewDomainName(name), CreatedAt: NewCreatedAt(time.Now()), IsActive: true}
+		require.NoError(t, repo.CreateDomain(&d))
+		ids = append(ids, d.DomainID)
+	}
+	checkErr := "i/o timeout"
+	require.NoError(t, repo.UpdateSSLInfo(ids[1], nil, &checkErr))
+	pool := ssl.NewCertServiceWithPool(2, time.Second)
+	defer pool.Stop()
+	s := NewService(repo, pool)
+
+	require.NoError(t, s.CheckFailedDomainsSSLSync(1))
+	assert.Equal(t, map[string]int{"retry-test:failed": 1}, checked)
+	got, err := s.GetDomain(ids[1])
+	require.NoError(t, err)
+	assert.Nil(t, got.LastError)
+}
+
+// TestService_AddDomainChecksInBackground - adding returns before the first check, which is stored once it is done.
+func TestService_AddDomainChecksInBackground(t *testing.T) {
+	release := make(chan struct{})
+	ssl.RegisterTargetChecker("add-test:", func(context.Context, string) (*ssl.SSLCertificate, error) {
+		<-release
+		return &ssl.SSLCertificate{ExpiryDate: types.NewExpiryDate(time.Now().AddDate(0, 2, 0))}, nil
+	})
+	pool := ssl.NewCertServiceWithPool(1, time.Second)
+	defer pool.Stop()
+	s := NewService(NewMemoryRepository(), pool)
+
+	added, err := s.AddDomain(1, "add-test:a")
+	require.NoError(t, err)
+	got, err := s.GetDomain(added.DomainID)
+	require.NoError(t, err)
+	assert.Nil(t, got.LastChecked, "Added before the check finished")
+
+	close(release)
+	require.NoError(t, s.CheckDomainSSL(added.DomainID), "Waits for the queued check")
+	got, err = s.GetDomain(added.DomainID)
+	require.NoError(t, err)
+	assert.NotNil(t, got.LastChecked)
+	assert.Equal(t, "valid", got.Status())
+}
+
+// failingStore is a repository that can't store check results
+type failingStore struct {
+	*MemoryRepository
+}
+
+func (failingStore) UpdateSSLInfoBatch([]SSLUpdate) error {
+	return errors.New("database is locked")
+}
+
+// TestService_CheckStoreError - results that couldn't be stored are reported per domain instead of passing silently.
+func TestService_CheckStoreError(t *testing.T) {
+	ssl.RegisterTargetChecker("store-test:", func(context.Context, string) (*ssl.SSLCertificate, error) {
+		return &ssl.SSLCertificate{ExpiryDate: types.NewExpiryDate(time.Now().AddDate(0, 2, 0))}, nil
+	})
+	repo := NewMemoryRepository()
+	d := Domain{UserID: 1, DomainName: NewDomainName("store-test:a"), CreatedAt: NewCreatedAt(time.Now()), IsActive: true}
+	require.NoError(t, repo.CreateDomain(&d))
+	pool := ssl.NewCertServiceWithPool(1, time.Second)
+	defer pool.Stop()
+	s := NewService(failingStore{repo}, pool)
+
+	err := s.CheckAllDomainsSSLSync(1)
+	var storeErr *StoreError
+	require.ErrorAs(t, err, &storeErr)
+	assert.Contains(t, storeErr.Domains, "store-test:a")
+	assert.ErrorContains(t, err, "database is locked")
+
+	assert.ErrorContains(t, s.CheckDomainSSL(d.DomainID), "database is locked")
+}
+
+// TestMemoryRepository_KeepsIssuer - a failed check keeps the issuer last seen.
+func TestMemoryRepository_KeepsIssuer(t *testing.T) {
+	_, repo, id := newTestService(t)
+
+	checkErr := "connection refused"
+	expiry := time.Now().Add(60 * 24 * time.Hour)
+	require.NoError(t, repo.UpdateSSLInfoBatch([]SSLUpdate{
+		{DomainID: id, ExpiryDate: &expiry, CheckedAt: time.Now(), Issuer: "R11"},
+		{DomainID: id, Error: &checkErr, CheckedAt: time.Now()},
+	}))
+
+	d, err := repo.GetDomainByID(id)
+	require.NoError(t, err)
+	assert.Equal(t, "R11", d.Issuer)
+}
+
+// TestMemoryRepository_Isolation - callers can't change stored domains through returned values.
+func TestMemoryRepository_Isolation(t *testing.T) {
+	_, repo, id := newTestService(t)
+
+	d, err := repo.GetDomainByID(id)
+	require.NoError(t, err)
+	d.Tags[0] = "changed"
+
+	d, err = repo.GetDomainByID(id)
+	require.NoError(t, err)
+	assert.Equal(t, "prod", d.Tags[0])
+
+	assert.Error(t, repo.CreateDomain(&Domain{UserID: types.UserID(1), DomainName: NewDomainName("example.com")}))
+}
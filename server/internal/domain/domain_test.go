@@ -5,7 +5,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/samokw/ssl_tracker/internal/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestDomainName - basic creation and String() method.
@@ -113,3 +115,270 @@ func FuzzLastError(f *testing.F) {
 		assert.Equal(t, input, le.String())
 	})
 }
+
+// TestDomain_IsDue - never-checked domains are due, otherwise the interval decides.
+func TestDomain_IsDue(t *testing.T) {
+	now := time.Now()
+	checkedHourAgo := NewLastChecked(now.Add(-1 * time.Hour))
+
+	tests := []struct {
+		name   string
+		domain Domain
+		want   bool
+	}{
+		{"never checked", Domain{IsActive: true}, true},
+		{"inactive", Domain{IsActive: false}, false},
+		{"within default interval", Domain{IsActive: true, LastChecked: &checkedHourAgo}, false},
+		{"own shorter interval", Domain{IsActive: true, LastChecked: &checkedHourAgo, CheckInterval: 30 * time.Minute}, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.domain.IsDue(now, 24*time.Hour))
+		})
+	}
+}
+
+// TestNormalizeTags - tags are trimmed, lowercased, deduplicated and sorted.
+func TestNormalizeTags(t *testing.T) {
+	assert.Equal(t, []string{"prod", "web"}, NormalizeTags([]string{" Web", "prod", "", "PROD"}))
+	assert.Equal(t, []string{}, ParseTags(""))
+	assert.Equal(t, []string{"api", "staging"}, ParseTags("staging, api"))
+}
+
+// TestDomain_HasTag - looks up a tag on the domain.
+func TestDomain_HasTag(t *testing.T) {
+	d := Domain{Tags: []string{"prod"}}
+	assert.True(t, d.HasTag("prod"))
+	assert.False(t, d.HasTag("staging"))
+}
+
+// TestDomain_Status - errors win over expiry, then thresholds apply.
+func TestDomain_Status(t *testing.T) {
+	expiry := func(days int) *types.ExpiryDate {
+		e := types.NewExpiryDate(time.Now().Add(time.Duration(days)*24*time.Hour + time.Hour))
+		return &e
+	}
+	lastError := NewLastError("timeout")
+
+	assert.Equal(t, "unknown", Domain{}.Status())
+	assert.Equal(t, "error", Domain{ExpiryDate: expiry(90), LastError: &lastError}.Status())
+	assert.Equal(t, "expired", Domain{ExpiryDate: expiry(-2)}.Status())
+	assert.Equal(t, "warning", Domain{ExpiryDate: expiry(3)}.Status())
+	assert.Equal(t, "soon", Domain{ExpiryDate: expiry(20)}.Status())
+	assert.Equal(t, "valid", Domain{ExpiryDate: expiry(90)}.Status())
+}
+
+// TestDomain_StatusWith - the thresholds given decide the level, and the status says why.
+func TestDomain_StatusWith(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	expiring := func(days int) Domain {
+		e := types.NewExpiryDate(now.Add(time.Duration(days)*24*time.Hour + time.Hour))
+		return Domain{ExpiryDate: &e}
+	}
+	strict := StatusThresholds{Warning: 60, Critical: 21}
+
+	s := expiring(20).StatusWith(strict, now)
+	assert.Equal(t, StatusCritical, s.Level)
+	assert.Equal(t, "expires in 20 days", s.Reason)
+	require.NotNil(t, s.DaysLeft)
+	assert.Equal(t, 20, *s.DaysLeft)
+	assert.Equal(t, StatusExpiringSoon, expiring(20).StatusWith(DefaultStatusThresholds, now).Level)
+
+	s = expiring(-3).StatusWith(strict, now)
+	assert.Equal(t, StatusExpired, s.Level)
+	assert.Equal(t, "expired on 2026-02-26", s.Reason)
+
+	lastError := NewLastError("i/o timeout")
+	s = Domain{LastError: &lastError}.StatusWith(strict, now)
+	assert.Equal(t, StatusError, s.Level)
+	assert.Equal(t, "i/o timeout", s.Reason)
+	assert.Nil(t, s.DaysLeft)
+}
+
+// TestDomain_RegistrationStatus - registrations use their own thresholds.
+func TestDomain_RegistrationStatus(t *testing.T) {
+	now := time.Now()
+	expiring := func(days int) Domain {
+		e := now.Add(time.Duration(days)*24*time.Hour + time.Hour)
+		return Domain{RegistrationExpiry: &e}
+	}
+
+	assert.Equal(t, RegistrationUnknown, Domain{}.RegistrationStatus(60, 14, now))
+	assert.Equal(t, RegistrationExpired, expiring(-1).RegistrationStatus(60, 14, now))
+	assert.Equal(t, RegistrationCritical, expiring(10).RegistrationStatus(60, 14, now))
+	assert.Equal(t, RegistrationWarning, expiring(45).RegistrationStatus(60, 14, now))
+	assert.Equal(t, RegistrationValid, expiring(365).RegistrationStatus(60, 14, now))
+}
+
+// TestParseDNSExpectations - expectations are validated, normalized and sorted.
+func TestParseDNSExpectations(t *testing.T) {
+	expectations, err := ParseDNSExpectations("caa=LetsEncrypt.org., A=192.0.2.1, AAAA=2001:DB8::1,a=192.0.2.1, CNAME=lb.example.net")
+	require.NoError(t, err)
+	assert.Equal(t, "A=192.0.2.1,AAAA=2001:db8::1,CAA=letsencrypt.org,CNAME=lb.example.net", FormatDNSExpectations(expectations))
+
+	empty, err := ParseDNSExpectations("")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	for _, invalid := range []string{"192.0.2.1", "A=2001:db8::1", "AAAA=192.0.2.1", "MX=mail.example.com", "CNAME="} {
+		_, err := ParseDNSExpectations(invalid)
+		assert.ErrorIs(t, err, ErrInvalidInput, invalid)
+	}
+}
+
+// TestSharedCertificates - domains serving one certificate are grouped, wildcards cover a single label.
+func TestSharedCertificates(t *testing.T) {
+	tracked := func(id uint, name, fingerprint string, sans ...string) Domain {
+		return Domain{DomainID: types.DomainID(id), DomainName: NewDomainName(name), CertFingerprint: fingerprint, SANs: NormalizeSANs(sans)}
+	}
+	domains := []Domain{
+		tracked(1, "www.example.com", "aa", "*.example.com", "example.com"),
+		tracked(2, "example.com", "aa", "*.example.com", "example.com"),
+		tracked(3, "api.example.com", "bb", "api.example.com"),
+		tracked(4, "a.b.example.com", "cc", "a.b.example.com"),
+		tracked(5, "shop.example.net", "dd", "shop.example.net"),
+		tracked(6, "file:///etc/ssl/web.pem", "ee", "shop.example.net"),
+	}
+
+	groups := SharedCertificates(domains)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "aa", groups[0].Fingerprint)
+	assert.Equal(t, "example.com", groups[0].Domains[0].DomainName.String())
+	assert.Len(t, groups[0].Domains, 2)
+
+	coverage := CoveredBySANs(domains)
+	require.Len(t, coverage, 2)
+	assert.Equal(t, "example.com", coverage[0].Domain.DomainName.String(), "Reported once for both domains serving it")
+	require.Len(t, coverage[0].Covered, 1)
+	assert.Equal(t, "api.example.com", coverage[0].Covered[0].DomainName.String())
+	assert.Equal(t, "file:///etc/ssl/web.pem", coverage[1].Domain.DomainName.String())
+	assert.Equal(t, "shop.example.net", coverage[1].Covered[0].DomainName.String())
+
+	assert.Equal(t, []string{"*.example.com", "example.com"}, ParseSANs("Example.com.,*.example.com,example.com"))
+}
+
+// TestDiagnoseError - errors are classified and only the latest streak of the same class is counted.
+func TestDiagnoseError(t *testing.T) {
+	assert.Equal(t, ErrorDNS, ClassifyError("failed to connect to nope.example: dial tcp: lookup nope.example: no such host"))
+	assert.Equal(t, ErrorTimeout, ClassifyError("failed to connect to example.com: dial tcp 192.0.2.1:443: i/o timeout"))
+	assert.Equal(t, ErrorHandshake, ClassifyError("TLS handshake failed for example.com: remote error: tls: internal error"))
+	assert.Equal(t, ErrorExpired, ClassifyError("x509: certificate has expired or is not yet valid"))
+	assert.Equal(t, ErrorOther, ClassifyError("connection refused"))
+
+	now := time.Now()
+	timeout, dns := NewLastError("i/o timeout"), NewLastError("no such host")
+	failing := Domain{DomainID: types.NewDomainID(1), LastError: &timeout}
+	history := []CheckRecord{
+		{CheckedAt: now.Add(-time.Hour), Error: &timeout},
+		{CheckedAt: now.Add(-2 * time.Hour), Error: &timeout},
+		{CheckedAt: now.Add(-3 * time.Hour), Error: &dns},
+		{CheckedAt: now.Add(-4 * time.Hour), Error: &timeout},
+	}
+	diagnosis, ok := DiagnoseError(failing, history, now)
+	require.True(t, ok)
+	assert.Equal(t, ErrorTimeout, diagnosis.Class)
+	assert.Equal(t, 2, diagnosis.Occurrences)
+	assert.Equal(t, now.Add(-2*time.Hour), diagnosis.FirstSeen)
+	assert.Equal(t, now.Add(-time.Hour), diagnosis.LastSeen)
+
+	expired := types.NewExpiryDate(now.Add(-time.Hour))
+	diagnosis, ok = DiagnoseError(Domain{DomainID: types.NewDomainID(2), ExpiryDate: &expired}, nil, now)
+	require.True(t, ok)
+	assert.Equal(t, ErrorExpired, diagnosis.Class)
+	assert.Equal(t, 1, diagnosis.Occurrences)
+
+	valid := types.NewExpiryDate(now.AddDate(0, 1, 0))
+	_, ok = DiagnoseError(Domain{ExpiryDate: &valid}, nil, now)
+	assert.False(t, ok)
+
+	groups := GroupErrors([]DomainError{{Class: ErrorOther}, {Class: ErrorDNS}, {Class: ErrorDNS}})
+	require.Len(t, groups, 2)
+	assert.Equal(t, ErrorDNS, groups[0].Class)
+	assert.Len(t, groups[0].Domains, 2)
+}
+
+// TestDomain_ComplianceWith - policies in effect are violated, later ones count down to the day they take effect.
+func TestDomain_ComplianceWith(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	issuedAt := now.AddDate(0, 0, -30)
+	expiry := types.NewExpiryDate(issuedAt.AddDate(0, 0, 365))
+	d := Domain{ExpiryDate: &expiry, IssuedAt: &issuedAt, KeyType: "RSA 2048", Signatures: []string{"SHA256-RSA"}}
+	lifetime := Policy{Name: "max-lifetime", MaxLifetimeDays: 398}
+	shorter := Policy{Name: "cabf-2027", MaxLifetimeDays: 100, From: now.AddDate(0, 0, 45)}
+	noSHA1 := Policy{Name: "no-sha1", ForbiddenSignatures: []string{"SHA-1"}}
+
+	assert.Equal(t, ComplianceUnknown, Domain{}.ComplianceWith([]Policy{lifetime}, now).Level)
+	assert.Equal(t, Compliant, d.ComplianceWith([]Policy{lifetime, noSHA1}, now).Level)
+
+	c := d.ComplianceWith([]Policy{lifetime, shorter}, now)
+	assert.Equal(t, ComplianceDue, c.Level)
+	require.NotNil(t, c.DaysLeft)
+	assert.Equal(t, 45, *c.DaysLeft)
+	require.Len(t, c.Violations, 1)
+	assert.Equal(t, "valid for 365 days, at most 100 allowed", c.Violations[0].Reason)
+
+	d.Signatures = append(d.Signatures, "ECDSA-SHA1")
+	c = d.ComplianceWith([]Policy{shorter, noSHA1, {Name: "rsa-3072", MinRSABits: 3072}}, now)
+	assert.Equal(t, NonCompliant, c.Level)
+	assert.Nil(t, c.DaysLeft)
+	require.Len(t, c.Violations, 3)
+	assert.Equal(t, "signed with ECDSA-SHA1", c.Violations[1].Reason)
+	assert.Equal(t, "2048-bit RSA key, at least 3072 required", c.Violations[2].Reason)
+}
+
+// TestExpiryForecast - certificates count in the month they expire per group, shared ones once, and their
+// replacements are projected from their lifetime.
+func TestExpiryForecast(t *testing.T) {
+	from := time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC)
+	tracked := func(id uint, name, fingerprint, issuer string, expiry time.Time, lifetimeDays int, tags ...string) Domain {
+		e := types.NewExpiryDate(expiry)
+		d := Domain{DomainID: types.DomainID(id), DomainName: NewDomainName(name), CertFingerprint: fingerprint,
+			Issuer: issuer, ExpiryDate: &e, Tags: tags}
+		if lifetimeDays > 0 {
+			issued := expiry.AddDate(0, 0, -lifetimeDays)
+			d.IssuedAt = &issued
+		}
+		return d
+	}
+	domains := []Domain{
+		tracked(1, "a.example.com", "aa", "R3", time.Date(2025, 11, 10, 0, 0, 0, 0, time.UTC), 90, "prod"),
+		tracked(2, "b.example.com", "aa", "R3", time.Date(2025, 11, 10, 0, 0, 0, 0, time.UTC), 90, "prod"),
+		tracked(3, "c.example.com", "cc", "DigiCert", time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC), 0, "prod", "web"),
+		tracked(4, "old.example.com", "dd", "", time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC), 90),
+		tracked(5, "later.example.com", "ee", "DigiCert", time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), 0, "prod"),
+		{DomainID: 6, DomainName: "unchecked.example.com"},
+	}
+
+	forecast, err := ExpiryForecast(domains, from, 6, ForecastByTag)
+	require.NoError(t, err)
+	type row struct {
+		month     string
+		group     string
+		expiring  int
+		projected int
+	}
+	var rows []row
+	for _, f := range forecast {
+		rows = append(rows, row{f.Month.Format("2006-01"), f.Group, f.Expiring, f.Projected})
+	}
+	assert.Equal(t, []row{
+		{"2025-10", "untagged", 1, 0},
+		{"2025-11", "prod", 1, 0},
+		{"2025-12", "prod", 1, 0},
+		{"2025-12", "web", 1, 0},
+		{"2026-02", "prod", 0, 1},
+	}, rows, "Shared certificates count once, expired ones in the first month, renewals after the forecast not at all")
+
+	forecast, err = ExpiryForecast(domains, from, 12, ForecastByIssuer)
+	require.NoError(t, err)
+	assert.Equal(t, ForecastUnknown, forecast[0].Group)
+	last := forecast[len(forecast)-1]
+	assert.Equal(t, "2026-08 R3", last.Month.Format("2006-01")+" "+last.Group, "Renewed every 90 days")
+	assert.Equal(t, 1, last.Total())
+
+	_, err = ExpiryForecast(domains, from, 6, "team")
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	_, err = ExpiryForecast(domains, from, 0, ForecastByNone)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Sentinel errors returned by the domain layer, wrapped with details.
+// Check them with errors.Is to map failures to user-facing messages or status codes
+var (
+	// ErrNotFound is returned when a domain doesn't exist
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is returned when a user already tracks a domain
+	ErrDuplicate = errors.New("already exists")
+	// ErrInvalidInput is returned when a request fails validation
+	ErrInvalidInput = errors.New("invalid input")
+	// ErrHostKeyChanged is returned when an SSH server presents a different host key from the one last seen
+	ErrHostKeyChanged = errors.New("host key changed")
+	// ErrMissingSANs is returned when a certificate doesn't cover every name a domain expects it to
+	ErrMissingSANs = errors.New("certificate is missing expected names")
+)
+
+// errNoPool is returned for the worker pool of a service that checks without one
+var errNoPool = errors.New("checks run one at a time without a worker pool")
+
+// StoreError reports the domains whose check results couldn't be stored, so a sweep whose results were lost doesn't
+// pass for a successful one
+type StoreError struct {
+	// Domains maps the name of each domain whose result was lost to why
+	Domains map[string]error
+}
+
+func (e *StoreError) Error() string {
+	names := make([]string, 0, len(e.Domains))
+	for name := range e.Domains {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	if len(names) == 1 {
+		return fmt.Sprintf("failed to store the check result of %s: %v", names[0], e.Domains[names[0]])
+	}
+	listed := strings.Join(names[:min(3, len(names))], ", ")
+	if len(names) > 3 {
+		listed += ", ..."
+	}
+	return fmt.Sprintf("failed to store the check results of %d domains (%s): %v", len(names), listed, e.Domains[names[0]])
+}
+
+// Unwrap returns the error of every domain
+func (e *StoreError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Domains))
+	for _, err := range e.Domains {
+		errs = append(errs, err)
+	}
+	return errs
+}
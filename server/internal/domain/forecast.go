@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// What an expiry forecast is broken down by
+const (
+	ForecastByTag    = "tag"
+	ForecastByIssuer = "issuer"
+	ForecastByNone   = "none"
+)
+
+// Groups of certificates without a tag or issuer to go by, and of all certificates when the forecast isn't broken
+// down
+const (
+	ForecastUntagged = "untagged"
+	ForecastUnknown  = "unknown"
+	ForecastAll      = "all"
+)
+
+// ForecastMonth counts the certificates of a group expiring in a month
+type ForecastMonth struct {
+	// Month is the first day of the month, at midnight where the forecast started
+	Month time.Time
+	Group string
+	// Expiring counts the certificates served now that expire in the month
+	Expiring int
+	// Projected counts the expiries of the certificates expected to replace them, each renewed with the lifetime
+	// of the one served now, and the auto-renewal lead if it has one
+	Projected int
+}
+
+// Total is how many certificates of the group are expected to expire in the month
+func (f ForecastMonth) Total() int {
+	return f.Expiring + f.Projected
+}
+
+// ExpiryForecast counts the certificates expiring in each of months months starting with the one from is in, broken
+// down by tag or issuer. Domains serving the same certificate count it once, and a domain with several tags counts
+// in each. Certificates expired already count in the first month, their renewal being due, but no renewal is
+// projected for them. Months and groups without expiries are left out, the rest are ordered by month, then group
+func ExpiryForecast(domains []Domain, from time.Time, months int, by string) ([]ForecastMonth, error) {
+	switch by {
+	case ForecastByTag, ForecastByIssuer, ForecastByNone:
+	default:
+		return nil, fmt.Errorf("%w: forecasts are broken down by %s, %s or %s, not %q", ErrInvalidInput,
+			ForecastByTag, ForecastByIssuer, ForecastByNone, by)
+	}
+	if months < 1 {
+		return nil, fmt.Errorf("%w: a forecast covers at least a month", ErrInvalidInput)
+	}
+
+	start := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location())
+	end := start.AddDate(0, months, 0)
+	type key struct {
+		month int
+		group string
+	}
+	counts := make(map[key]*ForecastMonth)
+	count := func(month int, groups []string, projected bool) {
+		for _, g := range groups {
+			k := key{month, g}
+			f, ok := counts[k]
+			if !ok {
+				f = &ForecastMonth{Month: start.AddDate(0, month, 0), Group: g}
+				counts[k] = f
+			}
+			if projected {
+				f.Projected++
+			} else {
+				f.Expiring++
+			}
+		}
+	}
+	monthOf := func(t time.Time) int {
+		t = t.In(start.Location())
+		return (t.Year()-start.Year())*12 + int(t.Month()-start.Month())
+	}
+
+	seen := make(map[string]bool)
+	for _, d := range byName(domains) {
+		expiry := d.ExpiryTime()
+		if expiry == nil || !expiry.Before(end) {
+			continue
+		}
+		if d.CertFingerprint != "" {
+			if seen[d.CertFingerprint] {
+				continue
+			}
+			seen[d.CertFingerprint] = true
+		}
+		groups := forecastGroups(d, by)
+		if expiry.Before(from) {
+			count(0, groups, false)
+			continue
+		}
+		count(monthOf(*expiry), groups, false)
+
+		if d.IssuedAt == nil {
+			continue
+		}
+		// Each replacement is issued when the one before is renewed and lives as long
+		step := expiry.Sub(*d.IssuedAt) - time.Duration(d.AutoRenewDays)*24*time.Hour
+		if step < 24*time.Hour {
+			continue
+		}
+		for next := expiry.Add(step); next.Before(end); next = next.Add(step) {
+			count(monthOf(next), groups, true)
+		}
+	}
+
+	forecast := make([]ForecastMonth, 0, len(counts))
+	for _, f := range counts {
+		forecast = append(forecast, *f)
+	}
+	sort.Slice(forecast, func(i, j int) bool {
+		if !forecast[i].Month.Equal(forecast[j].Month) {
+			return forecast[i].Month.Before(forecast[j].Month)
+		}
+		return forecast[i].Group < forecast[j].Group
+	})
+	return forecast, nil
+}
+
+// forecastGroups are the groups a domain's certificate counts in
+func forecastGroups(d Domain, by string) []string {
+	switch by {
+	case ForecastByTag:
+		if len(d.Tags) == 0 {
+			return []string{ForecastUntagged}
+		}
+		return d.Tags
+	case ForecastByIssuer:
+		if d.Issuer == "" {
+			return []string{ForecastUnknown}
+		}
+		return []string{d.Issuer}
+	default:
+		return []string{ForecastAll}
+	}
+}
@@ -0,0 +1,400 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// MemoryRepository is an in-memory DomainRepository for tests and running without a database
+type MemoryRepository struct {
+	mu            sync.Mutex
+	domains       map[types.DomainID]Domain
+	history       []CheckRecord
+	nextDomainID  uint
+	nextHistoryID uint
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		domains:       make(map[types.DomainID]Domain),
+		nextDomainID:  1,
+		nextHistoryID: 1,
+	}
+}
+
+// clone copies a domain so callers can't modify what the repository holds
+func clone(d Domain) Domain {
+	d.Tags = append([]string{}, d.Tags...)
+	return d
+}
+
+func (r *MemoryRepository) CheckForDuplicateDomains(userID types.UserID, domainName string) (*Domain, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.findByName(userID, domainName), nil
+}
+
+func (r *MemoryRepository) findByName(userID types.UserID, domainName string) *Domain {
+	for _, d := range r.domains {
+		if d.UserID == userID && d.DomainName.String() == domainName {
+			found := clone(d)
+			return &found
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) CreateDomain(domain *Domain) error {
+	if err := types.ValidateUserID(domain.UserID); err != nil {
+		return fmt.Errorf("%w: invalid user ID: %w", ErrInvalidInput, err)
+	}
+	if domain.DomainName.String() == "" {
+		return fmt.Errorf("%w: domain name cannot be empty", ErrInvalidInput)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.findByName(domain.UserID, domain.DomainName.String()) != nil {
+		return fmt.Errorf("domain %s %w for this user", domain.DomainName.String(), ErrDuplicate)
+	}
+	if domain.TeamID != 0 && r.findTeamDomain(domain.TeamID, domain.DomainName.String()) != nil {
+		return fmt.Errorf("domain %s %w for this team", domain.DomainName.String(), ErrDuplicate)
+	}
+
+	domain.DomainID = types.NewDomainID(r.nextDomainID)
+	r.nextDomainID++
+
+	stored := clone(*domain)
+	stored.Tags = NormalizeTags(stored.Tags)
+	stored.ExpiryDate, stored.LastChecked, stored.LastError = nil, nil, nil
+	r.domains[stored.DomainID] = stored
+	return nil
+}
+
+// sorted returns the domains matching keep in ID order, the order the SQL repository returns them in
+func (r *MemoryRepository) sorted(keep func(Domain) bool) []Domain {
+	domains := []Domain{}
+	for _, d := range r.domains {
+		if keep(d) {
+			domains = append(domains, clone(d))
+		}
+	}
+	sort.Slice(domains, func(i, j int) bool { return domains[i].DomainID < domains[j].DomainID })
+	return domains
+}
+
+func (r *MemoryRepository) GetDomainsByUserID(userID types.UserID) ([]Domain, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sorted(func(d Domain) bool { return d.UserID == userID && d.TeamID == 0 }), nil
+}
+
+func (r *MemoryRepository) GetDomainsByTeamID(teamID types.TeamID) ([]Domain, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sorted(func(d Domain) bool { return d.TeamID == teamID }), nil
+}
+
+func (r *MemoryRepository) FindTeamDomain(teamID types.TeamID, domainName string) (*Domain, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.findTeamDomain(teamID, domainName), nil
+}
+
+func (r *MemoryRepository) findTeamDomain(teamID types.TeamID, domainName string) *Domain {
+	for _, d := range r.domains {
+		if d.TeamID == teamID && d.DomainName.String() == domainName {
+			found := clone(d)
+			return &found
+		}
+	}
+	return nil
+}
+
+// GetActiveDomains returns the active domains of every user
+func (r *MemoryRepository) GetActiveDomains() ([]Domain, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sorted(func(d Domain) bool { return d.IsActive }), nil
+}
+
+func (r *MemoryRepository) GetDomainByID(domainID types.DomainID) (*Domain, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, ok := r.domains[domainID]
+	if !ok {
+		return nil, fmt.Errorf("domain with ID %d %w", domainID.Uint(), ErrNotFound)
+	}
+	found := clone(d)
+	return &found, nil
+}
+
+// DeleteDomain removes a domain along with its history
+func (r *MemoryRepository) DeleteDomain(domainID types.DomainID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.domains[domainID]; !ok {
+		return fmt.Errorf("domain with ID %d %w", domainID.Uint(), ErrNotFound)
+	}
+	delete(r.domains, domainID)
+	r.removeHistory(func(rec CheckRecord) bool { return rec.DomainID == domainID })
+	return nil
+}
+
+func (r *MemoryRepository) UpdateSSLInfo(domainID types.DomainID, expiryDate *time.Time, lastError *string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	update := SSLUpdate{DomainID: domainID, ExpiryDate: expiryDate, Error: lastError, CheckedAt: time.Now()}
+	if !r.applySSLUpdate(update) {
+		return fmt.Errorf("domain with ID %d %w", domainID.Uint(), ErrNotFound)
+	}
+	return nil
+}
+
+// UpdateSSLInfoBatch stores many check results, skipping domains that no longer exist
+func (r *MemoryRepository) UpdateSSLInfoBatch(updates []SSLUpdate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, update := range updates {
+		r.applySSLUpdate(update)
+	}
+	return nil
+}
+
+// applySSLUpdate stores a check result and its history entry, returning false if the domain doesn't exist
+func (r *MemoryRepository) applySSLUpdate(update SSLUpdate) bool {
+	d, ok := r.domains[update.DomainID]
+	if !ok {
+		return false
+	}
+
+	record := CheckRecord{ID: r.nextHistoryID, DomainID: update.DomainID, CheckedAt: update.CheckedAt, Fingerprint: update.Fingerprint}
+	r.nextHistoryID++
+
+	d.ExpiryDate, d.LastError = nil, nil
+	if update.ExpiryDate != nil {
+		expiry := types.NewExpiryDate(*update.ExpiryDate)
+		d.ExpiryDate = &expiry
+		record.ExpiryDate = &expiry
+	}
+	if update.Error != nil {
+		lastError := NewLastError(*update.Error)
+		d.LastError = &lastError
+		record.Error = &lastError
+	}
+	if update.Issuer != "" {
+		d.Issuer = update.Issuer
+	}
+	d.Warning = update.Warning
+	if update.Fingerprint != "" {
+		d.Fingerprint = update.Fingerprint
+	}
+	if update.CertFingerprint != "" {
+		d.CertFingerprint = update.CertFingerprint
+	}
+	if len(update.SANs) > 0 {
+		d.SANs = NormalizeSANs(update.SANs)
+	}
+	if update.IssuedAt != nil {
+		d.IssuedAt = update.IssuedAt
+	}
+	if update.KeyType != "" {
+		d.KeyType = update.KeyType
+	}
+	if len(update.Signatures) > 0 {
+		d.Signatures = update.Signatures
+	}
+	d.CheckLatency = nil
+	if update.Latency > 0 {
+		latency := update.Latency
+		d.CheckLatency = &latency
+	}
+	lastChecked := NewLastChecked(update.CheckedAt)
+	d.LastChecked = &lastChecked
+
+	r.domains[update.DomainID] = d
+	r.history = append(r.history, record)
+	return true
+}
+
+// GetCheckHistory returns the most recent checks of a domain, newest first
+func (r *MemoryRepository) GetCheckHistory(domainID types.DomainID, limit int) ([]CheckRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	records := []CheckRecord{}
+	for _, rec := range r.history {
+		if rec.DomainID == domainID {
+			records = append(records, rec)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].CheckedAt.Equal(records[j].CheckedAt) {
+			return records[i].CheckedAt.After(records[j].CheckedAt)
+		}
+		return records[i].ID > records[j].ID
+	})
+	if len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+// GetExpiryHistory returns every check of a domain that found a certificate, oldest first
+func (r *MemoryRepository) GetExpiryHistory(domainID types.DomainID) ([]CheckRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	records := []CheckRecord{}
+	for _, rec := range r.history {
+		if rec.DomainID == domainID && rec.ExpiryDate != nil {
+			records = append(records, rec)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].CheckedAt.Before(records[j].CheckedAt) })
+	return records, nil
+}
+
+func (r *MemoryRepository) DeleteCheckHistoryBefore(cutoff time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.removeHistory(func(rec CheckRecord) bool { return rec.CheckedAt.Before(cutoff) }), nil
+}
+
+func (r *MemoryRepository) CountCheckHistoryBefore(cutoff time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for _, rec := range r.history {
+		if rec.CheckedAt.Before(cutoff) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// removeHistory drops the history entries matching remove, returning how many were dropped
+func (r *MemoryRepository) removeHistory(remove func(CheckRecord) bool) int64 {
+	kept := r.history[:0]
+	for _, rec := range r.history {
+		if !remove(rec) {
+			kept = append(kept, rec)
+		}
+	}
+	removed := int64(len(r.history) - len(kept))
+	r.history = kept
+	return removed
+}
+
+func (r *MemoryRepository) UpdateCheckSchedule(domainID types.DomainID, schedule string) error {
+	return r.update(domainID, func(d *Domain) { d.CheckSchedule = schedule })
+}
+
+func (r *MemoryRepository) UpdateTags(domainID types.DomainID, tags []string) error {
+	return r.update(domainID, func(d *Domain) { d.Tags = NormalizeTags(tags) })
+}
+
+// UpdateIssuer records the CA of a domain's certificate
+func (r *MemoryRepository) UpdateIssuer(domainID types.DomainID, issuer string) error {
+	return r.update(domainID, func(d *Domain) { d.Issuer = issuer })
+}
+
+// UpdateWarning records a problem with a domain's certificate that is otherwise fine, nil when there is none
+func (r *MemoryRepository) UpdateWarning(domainID types.DomainID, warning *string) error {
+	return r.update(domainID, func(d *Domain) { d.Warning = warning })
+}
+
+// UpdateFingerprint records the SSH host key of a domain, an empty fingerprint accepts whichever key is seen next
+func (r *MemoryRepository) UpdateFingerprint(domainID types.DomainID, fingerprint string) error {
+	return r.update(domainID, func(d *Domain) { d.Fingerprint = fingerprint })
+}
+
+// UpdateCertificate records the fingerprint, DNS names and details of the certificate a domain serves
+func (r *MemoryRepository) UpdateCertificate(domainID types.DomainID, cert CertificateDetails) error {
+	return r.update(domainID, func(d *Domain) {
+		d.CertFingerprint = cert.Fingerprint
+		d.SANs = NormalizeSANs(cert.SANs)
+		d.IssuedAt, d.KeyType, d.Signatures = cert.IssuedAt, cert.KeyType, cert.Signatures
+	})
+}
+
+// UpdateExpectedSANs replaces the DNS names a domain's certificate has to cover
+func (r *MemoryRepository) UpdateExpectedSANs(domainID types.DomainID, sans []string) error {
+	return r.update(domainID, func(d *Domain) { d.ExpectedSANs = NormalizeSANs(sans) })
+}
+
+// UpdateTeam shares a domain with a team, zero makes it private to whoever added it again
+func (r *MemoryRepository) UpdateTeam(domainID types.DomainID, teamID types.TeamID) error {
+	return r.update(domainID, func(d *Domain) { d.TeamID = teamID })
+}
+
+func (r *MemoryRepository) update(domainID types.DomainID, change func(*Domain)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, ok := r.domains[domainID]
+	if !ok {
+		return fmt.Errorf("domain with ID %d %w", domainID.Uint(), ErrNotFound)
+	}
+	change(&d)
+	r.domains[domainID] = d
+	return nil
+}
+
+// UpdateNextChecks records when domains are due to be checked again, skipping domains that no longer exist
+func (r *MemoryRepository) UpdateNextChecks(next map[types.DomainID]time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for domainID, at := range next {
+		if d, ok := r.domains[domainID]; ok {
+			d.NextCheckAt = &at
+			r.domains[domainID] = d
+		}
+	}
+	return nil
+}
+
+// UpdateRegistration records when a domain's registration expires, nil when the lookup couldn't tell
+func (r *MemoryRepository) UpdateRegistration(domainID types.DomainID, expiry *time.Time, checkedAt time.Time) error {
+	return r.update(domainID, func(d *Domain) {
+		d.RegistrationExpiry = expiry
+		d.RegistrationChecked = &checkedAt
+	})
+}
+
+// UpdateDNSExpectations replaces the records a domain is expected to have, forgetting the last DNS check
+func (r *MemoryRepository) UpdateDNSExpectations(domainID types.DomainID, expectations []DNSExpectation) error {
+	return r.update(domainID, func(d *Domain) {
+		d.DNSExpectations = expectations
+		d.DNSError = nil
+		d.DNSChecked = nil
+	})
+}
+
+// UpdateDNSCheck records how a domain's records differed from its expectations, nil when they matched
+func (r *MemoryRepository) UpdateDNSCheck(domainID types.DomainID, mismatch *string, checkedAt time.Time) error {
+	return r.update(domainID, func(d *Domain) {
+		d.DNSError = mismatch
+		d.DNSChecked = &checkedAt
+	})
+}
+
+// UpdateAutoRenewal records how many days before expiry a domain's certificate renews automatically and what
+// renews it, zero days when it doesn't
+func (r *MemoryRepository) UpdateAutoRenewal(domainID types.DomainID, days int, via string) error {
+	return r.update(domainID, func(d *Domain) {
+		d.AutoRenewDays = days
+		d.AutoRenewVia = via
+	})
+}
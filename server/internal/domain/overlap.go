@@ -0,0 +1,150 @@
+package domain
+
+import (
+	"net"
+	"sort"
+	"strings"
+)
+
+// NormalizeSANs lowercases, deduplicates and sorts the DNS names of a certificate
+func NormalizeSANs(sans []string) []string {
+	seen := make(map[string]bool)
+	var normalized []string
+	for _, san := range sans {
+		san = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(san), "."))
+		if san == "" || seen[san] {
+			continue
+		}
+		seen[san] = true
+		normalized = append(normalized, san)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// ParseSANs splits a comma separated list of DNS names
+func ParseSANs(sans string) []string {
+	return NormalizeSANs(strings.Split(sans, ","))
+}
+
+// Hostname is the name a certificate has to be valid for to cover the domain, empty for IP addresses, SSH
+// hosts, files and cloud certificates
+func (d Domain) Hostname() string {
+	name := strings.ToLower(strings.TrimSuffix(d.DomainName.String(), "."))
+	if strings.Contains(name, "://") || net.ParseIP(name) != nil {
+		return ""
+	}
+	return name
+}
+
+// Covers reports whether the certificate last seen on the domain is valid for a hostname, a wildcard
+// matching a single label
+func (d Domain) Covers(hostname string) bool {
+	return sansCover(d.SANs, hostname)
+}
+
+// MissingSANs are the names of ExpectedSANs the DNS names of a certificate don't cover
+func (d Domain) MissingSANs(sans []string) []string {
+	var missing []string
+	for _, name := range d.ExpectedSANs {
+		if !sansCover(sans, name) {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// sansCover reports whether a certificate with the DNS names sans is valid for a hostname
+func sansCover(sans []string, hostname string) bool {
+	for _, san := range sans {
+		if san == hostname {
+			return true
+		}
+		if parent, ok := strings.CutPrefix(san, "*."); ok {
+			if label, rest, found := strings.Cut(hostname, "."); found && label != "" && rest == parent {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CertificateGroup is a certificate served by more than one tracked domain
+type CertificateGroup struct {
+	Fingerprint string
+	Domains     []Domain
+}
+
+// SharedCertificates groups the domains serving the same certificate, leaving out certificates a single domain
+// serves. Groups are ordered by the name of their first domain
+func SharedCertificates(domains []Domain) []CertificateGroup {
+	domains = byName(domains)
+	var groups []CertificateGroup
+	index := make(map[string]int)
+	for _, d := range domains {
+		if d.CertFingerprint == "" {
+			continue
+		}
+		i, ok := index[d.CertFingerprint]
+		if !ok {
+			i = len(groups)
+			index[d.CertFingerprint] = i
+			groups = append(groups, CertificateGroup{Fingerprint: d.CertFingerprint})
+		}
+		groups[i].Domains = append(groups[i].Domains, d)
+	}
+
+	shared := []CertificateGroup{}
+	for _, g := range groups {
+		if len(g.Domains) > 1 {
+			shared = append(shared, g)
+		}
+	}
+	return shared
+}
+
+// SANCoverage is a domain whose certificate is also valid for other tracked domains that serve certificates of
+// their own, which could be renewed as one
+type SANCoverage struct {
+	Domain  Domain
+	Covered []Domain
+}
+
+// CoveredBySANs finds the domains whose certificate covers other tracked domains. Of the domains serving the
+// same certificate only the first by name is reported
+func CoveredBySANs(domains []Domain) []SANCoverage {
+	domains = byName(domains)
+	coverage := []SANCoverage{}
+	seen := make(map[string]bool)
+	for _, d := range domains {
+		if len(d.SANs) == 0 || seen[d.CertFingerprint] {
+			continue
+		}
+		if d.CertFingerprint != "" {
+			seen[d.CertFingerprint] = true
+		}
+
+		c := SANCoverage{Domain: d}
+		for _, other := range domains {
+			if other.DomainID == d.DomainID || (other.CertFingerprint != "" && other.CertFingerprint == d.CertFingerprint) {
+				continue
+			}
+			if hostname := other.Hostname(); hostname != "" && d.Covers(hostname) {
+				c.Covered = append(c.Covered, other)
+			}
+		}
+		if len(c.Covered) > 0 {
+			coverage = append(coverage, c)
+		}
+	}
+	return coverage
+}
+
+// byName is a copy of domains sorted by name
+func byName(domains []Domain) []Domain {
+	sorted := append([]Domain(nil), domains...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].DomainName.String() < sorted[j].DomainName.String()
+	})
+	return sorted
+}
@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// StatusLevel is how urgently a domain's certificate needs attention
+type StatusLevel string
+
+// The levels keep the names API clients, alerts and status pages already match on, so critical stays "warning"
+const (
+	StatusValid        StatusLevel = "valid"
+	StatusExpiringSoon StatusLevel = "soon"
+	StatusCritical     StatusLevel = "warning"
+	StatusExpired      StatusLevel = "expired"
+	StatusError        StatusLevel = "error"
+	StatusUnknown      StatusLevel = "unknown"
+)
+
+// StatusThresholds are the days before expiry a certificate is expiring soon and critical
+type StatusThresholds struct {
+	Warning  int
+	Critical int
+}
+
+// DefaultStatusThresholds are the thresholds used unless configured otherwise
+var DefaultStatusThresholds = StatusThresholds{Warning: 30, Critical: 7}
+
+// DomainStatus is the state of a domain's certificate and why it is in that state
+type DomainStatus struct {
+	Level StatusLevel
+	// Reason explains the level to people, e.g. "expires in 5 days" or the error of the last check
+	Reason string
+	// DaysLeft is whole days until the certificate expires, negative once it has, nil when its expiry isn't known
+	DaysLeft *int
+}
+
+// EvaluateStatus works out the status of a check result as seen at now. A failed check is an error whatever the
+// expiry last seen
+func EvaluateStatus(expiry *time.Time, lastError *string, t StatusThresholds, now time.Time) DomainStatus {
+	var s DomainStatus
+	if expiry != nil {
+		days := int(expiry.Sub(now).Hours() / 24)
+		s.DaysLeft = &days
+	}
+	switch {
+	case lastError != nil:
+		s.Level, s.Reason = StatusError, *lastError
+	case expiry == nil:
+		s.Level, s.Reason = StatusUnknown, "no certificate seen yet"
+	case expiry.Before(now):
+		s.Level, s.Reason = StatusExpired, "expired on "+expiry.UTC().Format(time.DateOnly)
+	default:
+		daysLeft := expiry.Sub(now).Hours() / 24
+		s.Reason = fmt.Sprintf("expires in %d days", *s.DaysLeft)
+		switch {
+		case daysLeft < float64(t.Critical):
+			s.Level = StatusCritical
+		case daysLeft < float64(t.Warning):
+			s.Level = StatusExpiringSoon
+		default:
+			s.Level = StatusValid
+		}
+	}
+	return s
+}
+
+// StatusWith is the status of the domain's last check as seen at now
+func (d Domain) StatusWith(t StatusThresholds, now time.Time) DomainStatus {
+	var lastError *string
+	if d.LastError != nil {
+		e := d.LastError.String()
+		lastError = &e
+	}
+	return EvaluateStatus(d.ExpiryTime(), lastError, t, now)
+}
@@ -0,0 +1,149 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrorClass groups check failures by what is likely broken
+type ErrorClass string
+
+const (
+	ErrorDNS       ErrorClass = "dns"
+	ErrorTimeout   ErrorClass = "timeout"
+	ErrorHandshake ErrorClass = "handshake"
+	ErrorExpired   ErrorClass = "expired"
+	ErrorOther     ErrorClass = "other"
+)
+
+// ErrorClasses lists the error classes in the order they are triaged
+var ErrorClasses = []ErrorClass{ErrorDNS, ErrorTimeout, ErrorHandshake, ErrorExpired, ErrorOther}
+
+// Label is the error class as shown to people
+func (c ErrorClass) Label() string {
+	switch c {
+	case ErrorDNS:
+		return "DNS failure"
+	case ErrorTimeout:
+		return "Timeout"
+	case ErrorHandshake:
+		return "TLS handshake"
+	case ErrorExpired:
+		return "Expired"
+	}
+	return "Other"
+}
+
+// ClassifyError works out the error class of a check error from its message
+func ClassifyError(message string) ErrorClass {
+	m := strings.ToLower(message)
+	switch {
+	case strings.Contains(m, "expired"):
+		return ErrorExpired
+	case strings.Contains(m, "no such host"), strings.Contains(m, "lookup "), strings.Contains(m, "could not find the hostname"):
+		return ErrorDNS
+	case strings.Contains(m, "timeout"), strings.Contains(m, "timed out"), strings.Contains(m, "deadline exceeded"):
+		return ErrorTimeout
+	case strings.Contains(m, "handshake"), strings.Contains(m, "tls:"), strings.Contains(m, "x509:"):
+		return ErrorHandshake
+	}
+	return ErrorOther
+}
+
+// DomainError is how long a domain has been failing the same way
+type DomainError struct {
+	Domain  Domain
+	Class   ErrorClass
+	Message string
+	// Occurrences counts the checks in a row that failed this way, at least one
+	Occurrences int
+	FirstSeen   time.Time
+	LastSeen    time.Time
+}
+
+// ErrorGroup is every domain failing with one error class
+type ErrorGroup struct {
+	Class   ErrorClass
+	Domains []DomainError
+}
+
+// Occurrences totals the failed checks of the group's domains
+func (g ErrorGroup) Occurrences() int {
+	total := 0
+	for _, d := range g.Domains {
+		total += d.Occurrences
+	}
+	return total
+}
+
+// checkClass is the error class of a check, empty when it succeeded with a certificate that was still valid
+func checkClass(rec CheckRecord) ErrorClass {
+	switch {
+	case rec.Error != nil:
+		return ClassifyError(rec.Error.String())
+	case rec.ExpiryDate != nil && rec.ExpiryDate.Time().Before(rec.CheckedAt):
+		return ErrorExpired
+	}
+	return ""
+}
+
+// DiagnoseError reports how a failing domain has been failing from its check history, newest first. ok is false
+// when the domain isn't failing.
+//
+// Only the latest streak of checks failing with the same class counts, failures before the domain last recovered
+// or failed differently are history
+func DiagnoseError(d Domain, history []CheckRecord, now time.Time) (DomainError, bool) {
+	var current DomainError
+	switch {
+	case d.LastError != nil:
+		current.Class, current.Message = ClassifyError(d.LastError.String()), d.LastError.String()
+	case d.Status() == "expired":
+		current.Class, current.Message = ErrorExpired, "certificate expired on "+d.ExpiryDate.Time().UTC().Format(time.DateOnly)
+	default:
+		return DomainError{}, false
+	}
+	current.Domain = d
+
+	for _, rec := range history {
+		if checkClass(rec) != current.Class {
+			break
+		}
+		if current.Occurrences == 0 {
+			current.LastSeen = rec.CheckedAt
+		}
+		current.FirstSeen = rec.CheckedAt
+		current.Occurrences++
+	}
+	if current.Occurrences == 0 {
+		// No check recorded it yet, e.g. a certificate that ran out since it was last checked
+		seen := now
+		if d.LastChecked != nil {
+			seen = d.LastChecked.Time()
+		}
+		current.Occurrences, current.FirstSeen, current.LastSeen = 1, seen, seen
+	}
+	return current, true
+}
+
+// GroupErrors groups failing domains by error class in the order of ErrorClasses, longest failing first within
+// each class
+func GroupErrors(failing []DomainError) []ErrorGroup {
+	var groups []ErrorGroup
+	for _, class := range ErrorClasses {
+		group := ErrorGroup{Class: class}
+		for _, f := range failing {
+			if f.Class == class {
+				group.Domains = append(group.Domains, f)
+			}
+		}
+		if len(group.Domains) == 0 {
+			continue
+		}
+		sort.SliceStable(group.Domains, func(i, j int) bool {
+			return group.Domains[i].FirstSeen.Before(group.Domains[j].FirstSeen)
+		})
+		groups = append(groups, group)
+	}
+	return groups
+}
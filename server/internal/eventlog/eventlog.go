@@ -0,0 +1,191 @@
+// Package eventlog writes check results and status changes as JSON lines, for log pipelines such as Loki or ELK
+// to ingest and alert on
+package eventlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// Outputs events can be written to
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+	OutputSyslog = "syslog"
+)
+
+// Kinds of events
+const (
+	EventCheck        = "check"
+	EventStatusChange = "status_change"
+)
+
+// Event is one log line
+type Event struct {
+	Time     time.Time `json:"time"`
+	Event    string    `json:"event"`
+	DomainID uint      `json:"domain_id"`
+	Domain   string    `json:"domain"`
+	Status   string    `json:"status"`
+	// PreviousStatus is the status before the check, empty when it isn't known
+	PreviousStatus string     `json:"previous_status,omitempty"`
+	ExpiryDate     *time.Time `json:"expiry_date"`
+	DaysLeft       *int       `json:"days_left"`
+	Issuer         string     `json:"issuer,omitempty"`
+	Error          *string    `json:"error"`
+	// Warning is a problem with a certificate that is otherwise fine, e.g. a CA the CAA records don't authorize
+	Warning *string  `json:"warning,omitempty"`
+	Tags    []string `json:"tags"`
+}
+
+// Options configure a Logger
+type Options struct {
+	// Output is stdout, stderr or syslog
+	Output string
+	// SyslogAddress is a remote syslog server such as udp://logs.example.com:514, empty uses the local one
+	SyslogAddress string
+	// SyslogTag names the program in syslog messages, sslcerttop when empty
+	SyslogTag string
+	// ChangesOnly leaves out check events, logging status changes alone
+	ChangesOnly bool
+}
+
+// Logger writes events to a stream or syslog
+type Logger struct {
+	changesOnly bool
+	// write emits a line, with the event to pick its severity by
+	write  func(e Event, line []byte) error
+	closer io.Closer
+}
+
+// New writes events to w, one JSON object per line
+func New(w io.Writer, changesOnly bool) *Logger {
+	return &Logger{
+		changesOnly: changesOnly,
+		write: func(_ Event, line []byte) error {
+			_, err := w.Write(append(line, '\n'))
+			return err
+		},
+	}
+}
+
+// Open creates a Logger for the output opts name
+func Open(opts Options) (*Logger, error) {
+	switch opts.Output {
+	case OutputStdout:
+		return New(os.Stdout, opts.ChangesOnly), nil
+	case OutputStderr:
+		return New(os.Stderr, opts.ChangesOnly), nil
+	case OutputSyslog:
+		tag := opts.SyslogTag
+		if tag == "" {
+			tag = "sslcerttop"
+		}
+		return openSyslog(opts.SyslogAddress, tag, opts.ChangesOnly)
+	default:
+		return nil, fmt.Errorf("unknown event output %q, expected stdout, stderr or syslog", opts.Output)
+	}
+}
+
+// Run logs the result of every check, and every status change, until ctx is done
+func (l *Logger) Run(ctx context.Context, domains *domain.Service) error {
+	results, unsubscribe := domains.SubscribeResults(64)
+	defer unsubscribe()
+
+	statuses := map[types.DomainID]string{}
+	if active, err := domains.GetActiveDomains(); err == nil {
+		for _, d := range active {
+			statuses[d.DomainID] = d.Status()
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case result, ok := <-results:
+			if !ok {
+				return nil
+			}
+			d, err := domains.GetDomain(types.DomainID(result.Task.DomainID))
+			if err != nil {
+				slog.Error("Failed to look up checked domain", "domain", result.Task.Domain, "error", err)
+				continue
+			}
+			for _, e := range Events(*d, result, statuses[d.DomainID]) {
+				if err := l.Log(e); err != nil {
+					slog.Error("Failed to log check event", "domain", e.Domain, "error", err)
+				}
+			}
+			statuses[d.DomainID] = d.Status()
+		}
+	}
+}
+
+// Events describes a check of d, which already holds the result: a check event, followed by a status change event
+// when the status moved away from previous
+func Events(d domain.Domain, result ssl.Result, previous string) []Event {
+	e := Event{
+		Time:           result.CheckedAt.UTC(),
+		Event:          EventCheck,
+		DomainID:       d.DomainID.Uint(),
+		Domain:         d.DomainName.String(),
+		Status:         d.Status(),
+		PreviousStatus: previous,
+		ExpiryDate:     d.ExpiryTime(),
+		Issuer:         d.Issuer,
+		Warning:        d.Warning,
+		Tags:           d.Tags,
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if e.Tags == nil {
+		e.Tags = []string{}
+	}
+	if e.ExpiryDate != nil {
+		days := int(e.ExpiryDate.Sub(e.Time).Hours() / 24)
+		e.DaysLeft = &days
+	}
+	if result.Error != nil {
+		msg := result.Error.Error()
+		e.Error = &msg
+	}
+
+	events := []Event{e}
+	if previous != "" && previous != e.Status {
+		change := e
+		change.Event = EventStatusChange
+		events = append(events, change)
+	}
+	return events
+}
+
+// Log writes an event, check events are skipped when only changes are logged
+func (l *Logger) Log(e Event) error {
+	if l.changesOnly && e.Event == EventCheck {
+		return nil
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return l.write(e, line)
+}
+
+// Close releases the syslog connection
+func (l *Logger) Close() error {
+	if l.closer != nil {
+		return l.closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package eventlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDomain(days int) domain.Domain {
+	expiry := types.NewExpiryDate(time.Now().Add(time.Duration(days)*24*time.Hour + time.Hour))
+	return domain.Domain{DomainID: 4, DomainName: domain.NewDomainName("www.example.com"), IsActive: true, ExpiryDate: &expiry, Tags: []string{"web"}}
+}
+
+// TestEvents - a check is one event, and one more when it changed the status.
+func TestEvents(t *testing.T) {
+	result := ssl.Result{CheckedAt: time.Now()}
+
+	events := Events(testDomain(90), result, "valid")
+	require.Len(t, events, 1)
+	assert.Equal(t, EventCheck, events[0].Event)
+	assert.Equal(t, "valid", events[0].Status)
+	assert.Equal(t, 90, *events[0].DaysLeft)
+
+	events = Events(testDomain(5), result, "soon")
+	require.Len(t, events, 2)
+	assert.Equal(t, EventStatusChange, events[1].Event)
+	assert.Equal(t, "soon", events[1].PreviousStatus)
+	assert.Equal(t, "warning", events[1].Status)
+
+	assert.Len(t, Events(testDomain(5), result, ""), 1, "A first check isn't a change")
+
+	failed := Events(domain.Domain{DomainName: domain.NewDomainName("down.example.com")}, ssl.Result{Error: errors.New("timeout")}, "")
+	require.NotNil(t, failed[0].Error)
+	assert.Equal(t, "timeout", *failed[0].Error)
+	assert.False(t, failed[0].Time.IsZero())
+	assert.Equal(t, []string{}, failed[0].Tags)
+}
+
+// TestLog - events are written as one JSON object per line, check events left out when only changes are logged.
+func TestLog(t *testing.T) {
+	events := Events(testDomain(5), ssl.Result{CheckedAt: time.Now()}, "soon")
+
+	var buf bytes.Buffer
+	l := New(&buf, false)
+	for _, e := range events {
+		require.NoError(t, l.Log(e))
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	var got map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &got))
+	assert.Equal(t, "status_change", got["event"])
+	assert.Equal(t, "www.example.com", got["domain"])
+	assert.Equal(t, []any{"web"}, got["tags"])
+
+	buf.Reset()
+	l = New(&buf, true)
+	for _, e := range events {
+		require.NoError(t, l.Log(e))
+	}
+	assert.Equal(t, 1, strings.Count(buf.String(), "\n"))
+	assert.Contains(t, buf.String(), `"event":"status_change"`)
+}
+
+// TestOpen - unknown outputs are refused.
+func TestOpen(t *testing.T) {
+	_, err := Open(Options{Output: "journald"})
+	assert.Error(t, err)
+	l, err := Open(Options{Output: OutputStdout})
+	require.NoError(t, err)
+	assert.NoError(t, l.Close())
+}
@@ -0,0 +1,49 @@
+//go:build !windows
+
+package eventlog
+
+import (
+	"fmt"
+	"log/syslog"
+	"strings"
+)
+
+// openSyslog connects to address, network://host:port, or the local syslog daemon when it is empty
+func openSyslog(address, tag string, changesOnly bool) (*Logger, error) {
+	var network, raddr string
+	if address != "" {
+		var ok bool
+		if network, raddr, ok = strings.Cut(address, "://"); !ok {
+			return nil, fmt.Errorf("syslog address %q must be a udp://, tcp:// or unix:// address", address)
+		}
+	}
+	w, err := syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	return &Logger{
+		changesOnly: changesOnly,
+		write: func(e Event, line []byte) error {
+			switch severity(e) {
+			case "err":
+				return w.Err(string(line))
+			case "warning":
+				return w.Warning(string(line))
+			default:
+				return w.Info(string(line))
+			}
+		},
+		closer: w,
+	}, nil
+}
+
+// severity ranks an event so syslog filters can single out the ones needing attention
+func severity(e Event) string {
+	switch e.Status {
+	case "error", "expired":
+		return "err"
+	case "warning":
+		return "warning"
+	}
+	return "info"
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package eventlog
+
+import "errors"
+
+// openSyslog fails, Windows has no syslog
+func openSyslog(address, tag string, changesOnly bool) (*Logger, error) {
+	return nil, errors.New("syslog is not available on Windows, use stdout or stderr")
+}
@@ -0,0 +1,97 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/samokw/ssl_tracker/internal/apikey"
+	"github.com/samokw/ssl_tracker/internal/types"
+	trackerv1 "github.com/samokw/ssl_tracker/proto/tracker/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type contextKey int
+
+const userContextKey contextKey = iota
+
+// readOnlyMethods may be called with read-only keys
+var readOnlyMethods = map[string]bool{
+	trackerv1.TrackerService_ListDomains_FullMethodName:        true,
+	trackerv1.TrackerService_GetDomain_FullMethodName:          true,
+	trackerv1.TrackerService_GetDomainHistory_FullMethodName:   true,
+	trackerv1.TrackerService_StreamCheckResults_FullMethodName: true,
+}
+
+// RequireAPIKeys makes every call require a key in the authorization metadata.
+//
+// Read-only keys may only call methods that don't change anything
+func (s *Server) RequireAPIKeys(keyService *apikey.Service) {
+	s.keyService = keyService
+}
+
+// authenticate resolves the caller's key and returns a context carrying their user
+func (s *Server) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if s.keyService == nil {
+		return ctx, nil
+	}
+
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token, _ = strings.CutPrefix(values[0], "Bearer ")
+		}
+	}
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing API key")
+	}
+
+	key, err := s.keyService.Authenticate(strings.TrimSpace(token))
+	if err != nil {
+		if errors.Is(err, apikey.ErrInvalidKey) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if !readOnlyMethods[method] && !key.Scope.CanWrite() {
+		return nil, status.Error(codes.PermissionDenied, "API key is read-only")
+	}
+	return context.WithValue(ctx, userContextKey, key.UserID), nil
+}
+
+func (s *Server) unaryAuth(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) streamAuth(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticatedStream carries the authenticated context into stream handlers
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (a *authenticatedStream) Context() context.Context {
+	return a.ctx
+}
+
+// userFromContext returns the user a call acts on behalf of
+func userFromContext(ctx context.Context) types.UserID {
+	if userID, ok := ctx.Value(userContextKey).(types.UserID); ok {
+		return userID
+	}
+	return types.UserID(1) // Use default user when keys aren't required
+}
@@ -0,0 +1,58 @@
+package grpcapi
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/samokw/ssl_tracker/internal/apikey"
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/types"
+	trackerv1 "github.com/samokw/ssl_tracker/proto/tracker/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func withKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+key)
+}
+
+// TestRequireAPIKeys - calls need a valid key and read-only keys can't write.
+func TestRequireAPIKeys(t *testing.T) {
+	client, _, repo, server := newTestServer(t)
+	d := createDomain(t, repo, "example.com")
+	id := uint64(d.DomainID.Uint())
+
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "keys.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	keys := apikey.NewService(apikey.NewRepository(db))
+	readKey, _, err := keys.CreateKey(types.UserID(1), "reader", apikey.ScopeRead)
+	require.NoError(t, err)
+	writeKey, _, err := keys.CreateKey(types.UserID(1), "writer", apikey.ScopeReadWrite)
+	require.NoError(t, err)
+	server.RequireAPIKeys(keys)
+
+	_, err = client.ListDomains(context.Background(), &trackerv1.ListDomainsRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.ListDomains(withKey("sct_wrong"), &trackerv1.ListDomainsRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.ListDomains(withKey(readKey), &trackerv1.ListDomainsRequest{})
+	assert.NoError(t, err)
+
+	_, err = client.DeleteDomain(withKey(readKey), &trackerv1.DeleteDomainRequest{Id: id})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.DeleteDomain(withKey(writeKey), &trackerv1.DeleteDomainRequest{Id: id})
+	assert.NoError(t, err)
+
+	stream, err := client.StreamCheckResults(context.Background(), &trackerv1.StreamCheckResultsRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
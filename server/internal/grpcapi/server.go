@@ -0,0 +1,307 @@
+// This package serves the tracker over gRPC using the stubs generated from proto/tracker/v1
+//
+// It mirrors the REST API and reuses the domain service so both behave the same
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/apikey"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+	trackerv1 "github.com/samokw/ssl_tracker/proto/tracker/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server implements the TrackerService
+type Server struct {
+	trackerv1.UnimplementedTrackerServiceServer
+	domainService *domain.Service
+	keyService    *apikey.Service
+}
+
+// NewServer creates a gRPC tracker service
+func NewServer(domainService *domain.Service) *Server {
+	return &Server{
+		domainService: domainService,
+	}
+}
+
+// NewGRPCServer creates a gRPC server with the service and its authentication registered
+func (s *Server) NewGRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(s.unaryAuth),
+		grpc.ChainStreamInterceptor(s.streamAuth),
+	)
+	grpcServer := grpc.NewServer(opts...)
+	trackerv1.RegisterTrackerServiceServer(grpcServer, s)
+	return grpcServer
+}
+
+// ListenAndServe serves the service on addr until the context is cancelled
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	grpcServer := s.NewGRPCServer()
+
+	errCh := make(chan error, 1)
+	go func() {
+		slog.Info("gRPC server listening", "addr", lis.Addr().String())
+		errCh <- grpcServer.Serve(lis)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		// Streams only end when their context does, so don't wait on them forever
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(10 * time.Second):
+			grpcServer.Stop()
+		}
+		slog.Info("gRPC server stopped")
+		return <-errCh
+	}
+}
+
+// lookupDomain loads a domain of the calling user, answering NotFound if it isn't theirs
+func (s *Server) lookupDomain(ctx context.Context, id uint64) (*domain.Domain, error) {
+	if id == 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid domain ID")
+	}
+	d, err := s.domainService.GetDomain(types.NewDomainID(uint(id)))
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if err != nil || !s.visible(userFromContext(ctx), d) {
+		return nil, status.Errorf(codes.NotFound, "domain with ID %d not found", id)
+	}
+	return d, nil
+}
+
+// visible reports whether a user may see a domain, their own or one shared with their team
+func (s *Server) visible(userID types.UserID, d *domain.Domain) bool {
+	ok, err := s.domainService.CanAccess(userID, *d)
+	return err == nil && ok
+}
+
+// domainError converts an error from the domain layer to a status with the matching code
+func domainError(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		code = codes.NotFound
+	case errors.Is(err, domain.ErrDuplicate):
+		code = codes.AlreadyExists
+	case errors.Is(err, domain.ErrInvalidInput):
+		code = codes.InvalidArgument
+	}
+	return status.Error(code, err.Error())
+}
+
+// ListDomains lists every tracked domain
+func (s *Server) ListDomains(ctx context.Context, req *trackerv1.ListDomainsRequest) (*trackerv1.ListDomainsResponse, error) {
+	domains, err := s.domainService.GetUsersDomains(userFromContext(ctx))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return newListDomainsResponse(domains), nil
+}
+
+// GetDomain looks up a single domain
+func (s *Server) GetDomain(ctx context.Context, req *trackerv1.GetDomainRequest) (*trackerv1.Domain, error) {
+	d, err := s.lookupDomain(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return newDomain(*d), nil
+}
+
+// AddDomain tracks a new domain and checks its certificate
+func (s *Server) AddDomain(ctx context.Context, req *trackerv1.AddDomainRequest) (*trackerv1.Domain, error) {
+	if ssl.IsFileTarget(req.GetDomain()) {
+		// Files are read from the server's disk, so only someone on that machine may point at them
+		return nil, status.Error(codes.InvalidArgument, "certificate files can only be added on the server")
+	}
+	// The first check runs in the background, the domain is returned unchecked
+	d, err := s.domainService.AddDomain(userFromContext(ctx), req.GetDomain())
+	if err != nil {
+		return nil, domainError(err)
+	}
+	return newDomain(*d), nil
+}
+
+// DeleteDomain stops tracking a domain
+func (s *Server) DeleteDomain(ctx context.Context, req *trackerv1.DeleteDomainRequest) (*trackerv1.DeleteDomainResponse, error) {
+	d, err := s.lookupDomain(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.domainService.RemoveDomain(d.DomainID); err != nil {
+		return nil, domainError(err)
+	}
+	return &trackerv1.DeleteDomainResponse{}, nil
+}
+
+// CheckDomain checks a domain's certificate now
+func (s *Server) CheckDomain(ctx context.Context, req *trackerv1.CheckDomainRequest) (*trackerv1.Domain, error) {
+	d, err := s.lookupDomain(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.domainService.CheckDomainSSL(d.DomainID); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	checked, err := s.domainService.GetDomain(d.DomainID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return newDomain(*checked), nil
+}
+
+// CheckAllDomains checks every tracked domain
+func (s *Server) CheckAllDomains(ctx context.Context, req *trackerv1.CheckAllDomainsRequest) (*trackerv1.ListDomainsResponse, error) {
+	userID := userFromContext(ctx)
+	if err := s.domainService.CheckAllDomainsSSLSync(userID); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return s.ListDomains(ctx, &trackerv1.ListDomainsRequest{})
+}
+
+// GetDomainHistory lists past checks of a domain, newest first
+func (s *Server) GetDomainHistory(ctx context.Context, req *trackerv1.GetDomainHistoryRequest) (*trackerv1.GetDomainHistoryResponse, error) {
+	if req.GetLimit() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit must not be negative")
+	}
+	d, err := s.lookupDomain(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := s.domainService.GetCheckHistory(d.DomainID, min(int(req.GetLimit()), 1000))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &trackerv1.GetDomainHistoryResponse{Records: make([]*trackerv1.CheckRecord, len(records))}
+	for i, rec := range records {
+		r := &trackerv1.CheckRecord{CheckedAt: timestamppb.New(rec.CheckedAt)}
+		if rec.ExpiryDate != nil {
+			r.ExpiryDate = timestamppb.New(rec.ExpiryDate.Time())
+		}
+		if rec.Error != nil {
+			checkErr := rec.Error.String()
+			r.Error = &checkErr
+		}
+		resp.Records[i] = r
+	}
+	return resp, nil
+}
+
+// StreamCheckResults sends every completed check of the caller's domains until the client goes away
+func (s *Server) StreamCheckResults(req *trackerv1.StreamCheckResultsRequest, stream grpc.ServerStreamingServer[trackerv1.CheckResult]) error {
+	ctx := stream.Context()
+	userID := userFromContext(ctx)
+
+	// Subscribe before reading the current state so no result falls in between
+	results, unsubscribe := s.domainService.SubscribeResults(64)
+	defer unsubscribe()
+
+	domains, err := s.domainService.GetUsersDomains(userID)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	statuses := make(map[types.DomainID]string, len(domains))
+	for _, d := range domains {
+		statuses[d.DomainID] = d.Status()
+	}
+
+	// Tell the client the stream is live so it knows no later result will be missed
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case result, ok := <-results:
+			if !ok {
+				return status.Error(codes.Unavailable, "worker pool stopped")
+			}
+			d, err := s.domainService.GetDomain(types.DomainID(result.Task.DomainID))
+			if err != nil || !s.visible(userID, d) {
+				continue
+			}
+
+			msg := &trackerv1.CheckResult{
+				Domain:         newDomain(*d),
+				CheckedAt:      timestamppb.New(result.CheckedAt),
+				PreviousStatus: statuses[d.DomainID],
+			}
+			if result.Error != nil {
+				checkErr := result.Error.Error()
+				msg.Error = &checkErr
+			}
+			statuses[d.DomainID] = d.Status()
+
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func newListDomainsResponse(domains []domain.Domain) *trackerv1.ListDomainsResponse {
+	resp := &trackerv1.ListDomainsResponse{Domains: make([]*trackerv1.Domain, len(domains))}
+	for i, d := range domains {
+		resp.Domains[i] = newDomain(d)
+	}
+	return resp
+}
+
+func newDomain(d domain.Domain) *trackerv1.Domain {
+	pb := &trackerv1.Domain{
+		Id:            uint64(d.DomainID.Uint()),
+		Domain:        d.DomainName.String(),
+		CreatedAt:     timestamppb.New(d.CreatedAt.Time()),
+		IsActive:      d.IsActive,
+		Status:        d.Status(),
+		Tags:          d.Tags,
+		CheckSchedule: d.CheckSchedule,
+	}
+	if d.ExpiryDate != nil {
+		expiry := d.ExpiryDate.Time()
+		daysLeft := int32(time.Until(expiry).Hours() / 24)
+		pb.ExpiryDate = timestamppb.New(expiry)
+		pb.DaysLeft = &daysLeft
+	}
+	if d.LastChecked != nil {
+		pb.LastChecked = timestamppb.New(d.LastChecked.Time())
+	}
+	if d.LastError != nil {
+		lastError := d.LastError.String()
+		pb.LastError = &lastError
+	}
+	return pb
+}
@@ -0,0 +1,149 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+	trackerv1 "github.com/samokw/ssl_tracker/proto/tracker/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient serves the tracker over an in-memory connection backed by a fresh database.
+func newTestClient(t *testing.T) (trackerv1.TrackerServiceClient, *domain.Service, *domain.Repository) {
+	t.Helper()
+
+	client, domainService, repo, _ := newTestServer(t)
+	return client, domainService, repo
+}
+
+// newTestServer is newTestClient that also returns the server so tests can configure it.
+func newTestServer(t *testing.T) (trackerv1.TrackerServiceClient, *domain.Service, *domain.Repository, *Server) {
+	t.Helper()
+
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	sslService := ssl.NewCertService()
+	t.Cleanup(sslService.Stop)
+
+	repo := domain.NewRepository(db)
+	domainService := domain.NewService(repo, sslService)
+
+	lis := bufconn.Listen(1 << 20)
+	server := NewServer(domainService)
+	grpcServer := server.NewGRPCServer()
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return trackerv1.NewTrackerServiceClient(conn), domainService, repo, server
+}
+
+func createDomain(t *testing.T, repo *domain.Repository, name string) domain.Domain {
+	t.Helper()
+
+	d := domain.Domain{
+		UserID:     types.UserID(1),
+		DomainName: domain.NewDomainName(name),
+		CreatedAt:  domain.NewCreatedAt(time.Now()),
+		IsActive:   true,
+	}
+	require.NoError(t, repo.CreateDomain(&d))
+	return d
+}
+
+// TestServer_CRUD - lists, gets and deletes domains with gRPC status codes for failures.
+func TestServer_CRUD(t *testing.T) {
+	client, _, repo := newTestClient(t)
+	ctx := context.Background()
+	d := createDomain(t, repo, "example.com")
+	id := uint64(d.DomainID.Uint())
+
+	list, err := client.ListDomains(ctx, &trackerv1.ListDomainsRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.GetDomains(), 1)
+	assert.Equal(t, "example.com", list.GetDomains()[0].GetDomain())
+	assert.Equal(t, "unknown", list.GetDomains()[0].GetStatus())
+
+	got, err := client.GetDomain(ctx, &trackerv1.GetDomainRequest{Id: id})
+	require.NoError(t, err)
+	assert.Nil(t, got.GetExpiryDate())
+
+	_, err = client.AddDomain(ctx, &trackerv1.AddDomainRequest{Domain: "not a domain"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.DeleteDomain(ctx, &trackerv1.DeleteDomainRequest{Id: id})
+	require.NoError(t, err)
+
+	_, err = client.GetDomain(ctx, &trackerv1.GetDomainRequest{Id: id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.GetDomain(ctx, &trackerv1.GetDomainRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// TestServer_GetDomainHistory - recorded checks are returned newest first.
+func TestServer_GetDomainHistory(t *testing.T) {
+	client, _, repo := newTestClient(t)
+	d := createDomain(t, repo, "example.com")
+
+	checkErr := "connection refused"
+	expiry := time.Now().Add(60 * 24 * time.Hour)
+	require.NoError(t, repo.UpdateSSLInfo(d.DomainID, nil, &checkErr))
+	require.NoError(t, repo.UpdateSSLInfo(d.DomainID, &expiry, nil))
+
+	resp, err := client.GetDomainHistory(context.Background(), &trackerv1.GetDomainHistoryRequest{Id: uint64(d.DomainID.Uint())})
+	require.NoError(t, err)
+	require.Len(t, resp.GetRecords(), 2)
+	assert.NotNil(t, resp.GetRecords()[0].GetExpiryDate())
+	assert.Equal(t, checkErr, resp.GetRecords()[1].GetError())
+}
+
+// TestServer_StreamCheckResults - pool results are streamed with the previous status.
+func TestServer_StreamCheckResults(t *testing.T) {
+	client, domainService, repo := newTestClient(t)
+
+	// Invalid hostname fails the check in the worker pool without touching the network
+	d := createDomain(t, repo, "invalid..domain")
+	expiry := time.Now().Add(90 * 24 * time.Hour)
+	require.NoError(t, repo.UpdateSSLInfo(d.DomainID, &expiry, nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.StreamCheckResults(ctx, &trackerv1.StreamCheckResultsRequest{})
+	require.NoError(t, err)
+
+	// The stream is registered once the header arrives
+	_, err = stream.Header()
+	require.NoError(t, err)
+	require.NoError(t, domainService.CheckDomainsSSLSync([]domain.Domain{d}))
+
+	result, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(d.DomainID.Uint()), result.GetDomain().GetId())
+	assert.Equal(t, "valid", result.GetPreviousStatus())
+	assert.Equal(t, "error", result.GetDomain().GetStatus())
+	assert.NotEmpty(t, result.GetError())
+}
@@ -0,0 +1,229 @@
+// Package hooks runs commands when the status of a domain changes, e.g. from valid to warning or from error back
+// to valid, as a generic way to hook sslcerttop into other tools
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+const (
+	// DefaultTimeout is how long a hook may run unless configured otherwise
+	DefaultTimeout = time.Minute
+	// queueSize is how many changes can wait for the hooks of earlier ones to finish before being dropped
+	queueSize = 256
+	// maxOutput is how much of a hook's output is logged
+	maxOutput = 4096
+)
+
+// Hook is a command run through sh -c when a domain's status changes from one of From to one of To
+type Hook struct {
+	Command string
+	// From and To are status levels, e.g. valid, soon, warning, expired or error, empty matching any
+	From []string
+	To   []string
+	// Timeout stops the command when it runs longer, zero uses DefaultTimeout
+	Timeout time.Duration
+}
+
+// Matches reports whether the hook runs for a change from one status to another
+func (h Hook) Matches(from, to string) bool {
+	return (len(h.From) == 0 || slices.Contains(h.From, from)) && (len(h.To) == 0 || slices.Contains(h.To, to))
+}
+
+// Change is a check that moved a domain to another status, it is passed to hooks as JSON on stdin
+type Change struct {
+	DomainID uint   `json:"domain_id"`
+	Domain   string `json:"domain"`
+	Status   string `json:"status"`
+	// PreviousStatus is the status before the check
+	PreviousStatus string `json:"previous_status"`
+	// StatusReason explains the status, e.g. how many days are left or why the check failed
+	StatusReason string     `json:"status_reason"`
+	ExpiryDate   *time.Time `json:"expiry_date"`
+	DaysLeft     *int       `json:"days_left"`
+	Issuer       string     `json:"issuer,omitempty"`
+	Error        *string    `json:"error"`
+	Warning      *string    `json:"warning,omitempty"`
+	Tags         []string   `json:"tags"`
+	CheckedAt    time.Time  `json:"checked_at"`
+}
+
+// NewChange describes a check of d, which already holds the result and is in status, previous is the status
+// before the check
+func NewChange(d domain.Domain, status domain.DomainStatus, result ssl.Result, previous string) Change {
+	c := Change{
+		DomainID:       d.DomainID.Uint(),
+		Domain:         d.DomainName.String(),
+		Status:         string(status.Level),
+		PreviousStatus: previous,
+		StatusReason:   status.Reason,
+		ExpiryDate:     d.ExpiryTime(),
+		DaysLeft:       status.DaysLeft,
+		Issuer:         d.Issuer,
+		Warning:        d.Warning,
+		Tags:           d.Tags,
+		CheckedAt:      result.CheckedAt.UTC(),
+	}
+	if c.CheckedAt.IsZero() {
+		c.CheckedAt = time.Now().UTC()
+	}
+	if c.Tags == nil {
+		c.Tags = []string{}
+	}
+	if result.Error != nil {
+		msg := result.Error.Error()
+		c.Error = &msg
+	}
+	return c
+}
+
+// Environ is the change as the SSLCERTTOP_* environment variables hooks read
+func (c Change) Environ() []string {
+	expiry, daysLeft, errMsg, warning := "", "", "", ""
+	if c.ExpiryDate != nil {
+		expiry = c.ExpiryDate.UTC().Format(time.RFC3339)
+	}
+	if c.DaysLeft != nil {
+		daysLeft = strconv.Itoa(*c.DaysLeft)
+	}
+	if c.Error != nil {
+		errMsg = *c.Error
+	}
+	if c.Warning != nil {
+		warning = *c.Warning
+	}
+	return []string{
+		"SSLCERTTOP_DOMAIN=" + c.Domain,
+		"SSLCERTTOP_DOMAIN_ID=" + strconv.FormatUint(uint64(c.DomainID), 10),
+		"SSLCERTTOP_STATUS=" + c.Status,
+		"SSLCERTTOP_PREVIOUS_STATUS=" + c.PreviousStatus,
+		"SSLCERTTOP_STATUS_REASON=" + c.StatusReason,
+		"SSLCERTTOP_EXPIRY=" + expiry,
+		"SSLCERTTOP_DAYS_LEFT=" + daysLeft,
+		"SSLCERTTOP_ISSUER=" + c.Issuer,
+		"SSLCERTTOP_ERROR=" + errMsg,
+		"SSLCERTTOP_WARNING=" + warning,
+		"SSLCERTTOP_TAGS=" + strings.Join(c.Tags, ","),
+		"SSLCERTTOP_CHECKED_AT=" + c.CheckedAt.Format(time.RFC3339),
+	}
+}
+
+// Runner runs the hooks matching each status change, one change at a time so a domain's hooks run in order
+type Runner struct {
+	hooks []Hook
+}
+
+func NewRunner(hooks []Hook) *Runner {
+	return &Runner{hooks: hooks}
+}
+
+// Run watches every check for status changes and runs the hooks matching them until ctx is done
+func (r *Runner) Run(ctx context.Context, domains *domain.Service) error {
+	results, unsubscribe := domains.SubscribeResults(64)
+	defer unsubscribe()
+
+	// Hooks run apart from reading results, so slow ones don't make the pool drop results
+	changes := make(chan Change, queueSize)
+	defer close(changes)
+	go func() {
+		for c := range changes {
+			r.Fire(ctx, c)
+		}
+	}()
+
+	// Statuses before the first check of each domain, so the first result can tell a change too
+	statuses := map[types.DomainID]string{}
+	if active, err := domains.GetActiveDomains(); err == nil {
+		for _, d := range active {
+			statuses[d.DomainID] = string(domains.Status(d).Level)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case result, ok := <-results:
+			if !ok {
+				return nil
+			}
+			d, err := domains.GetDomain(types.DomainID(result.Task.DomainID))
+			if err != nil {
+				slog.Error("Failed to look up checked domain", "domain", result.Task.Domain, "error", err)
+				continue
+			}
+			previous := statuses[d.DomainID]
+			c := NewChange(*d, domains.Status(*d), result, previous)
+			statuses[d.DomainID] = c.Status
+			if previous == "" || previous == c.Status {
+				continue
+			}
+			select {
+			case changes <- c:
+			default:
+				slog.Warn("Too many status changes waiting for hooks, skipping one", "domain", c.Domain,
+					"from", c.PreviousStatus, "to", c.Status)
+			}
+		}
+	}
+}
+
+// Fire runs every hook matching the change in turn, logging those that fail
+func (r *Runner) Fire(ctx context.Context, c Change) {
+	for _, h := range r.hooks {
+		if !h.Matches(c.PreviousStatus, c.Status) {
+			continue
+		}
+		out, err := h.Run(ctx, c)
+		if err != nil {
+			slog.Error("Status hook failed", "domain", c.Domain, "from", c.PreviousStatus, "to", c.Status,
+				"command", h.Command, "error", err, "output", out)
+			continue
+		}
+		slog.Info("Ran status hook", "domain", c.Domain, "from", c.PreviousStatus, "to", c.Status, "command", h.Command)
+	}
+}
+
+// Run executes the hook for a change, with the change in its environment and as JSON on stdin.
+//
+// Returns what the command printed, failing when it exits non-zero or runs out of time
+func (h Hook) Run(ctx context.Context, c Change) (string, error) {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode change: %w", err)
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.Command)
+	cmd.Env = append(os.Environ(), c.Environ()...)
+	cmd.Stdin = strings.NewReader(string(payload))
+	// Children the shell started could otherwise keep the output open, and the hook running, past the timeout
+	cmd.WaitDelay = time.Second
+	out, err := cmd.CombinedOutput()
+	output := strings.TrimSpace(string(out[:min(len(out), maxOutput)]))
+	if ctx.Err() == context.DeadlineExceeded {
+		return output, fmt.Errorf("hook timed out after %s", timeout)
+	}
+	if err != nil {
+		return output, fmt.Errorf("hook failed: %w", err)
+	}
+	return output, nil
+}
@@ -0,0 +1,39 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHook_Matches - empty From or To match any status.
+func TestHook_Matches(t *testing.T) {
+	assert.True(t, Hook{}.Matches("valid", "warning"))
+	assert.True(t, Hook{To: []string{"expired", "error"}}.Matches("warning", "error"))
+	assert.False(t, Hook{To: []string{"expired"}}.Matches("warning", "error"))
+	assert.True(t, Hook{From: []string{"error"}, To: []string{"valid"}}.Matches("error", "valid"))
+	assert.False(t, Hook{From: []string{"error"}, To: []string{"valid"}}.Matches("soon", "valid"))
+}
+
+// TestHook_Run - the change reaches the command in its environment and on stdin.
+func TestHook_Run(t *testing.T) {
+	d := domain.Domain{DomainID: 7, DomainName: domain.NewDomainName("example.com"), Tags: []string{"prod", "web"}}
+	c := NewChange(d, domain.DomainStatus{Level: domain.StatusError, Reason: "connection refused"},
+		ssl.Result{Error: errors.New("connection refused")}, "valid")
+
+	out, err := Hook{Command: `echo "$SSLCERTTOP_DOMAIN $SSLCERTTOP_PREVIOUS_STATUS>$SSLCERTTOP_STATUS $SSLCERTTOP_TAGS"; head -c 14`}.Run(context.Background(), c)
+	require.NoError(t, err)
+	assert.Equal(t, "example.com valid>error prod,web\n{\"domain_id\":7", out)
+
+	_, err = Hook{Command: "exit 3"}.Run(context.Background(), c)
+	assert.ErrorContains(t, err, "exit status 3")
+
+	_, err = Hook{Command: "sleep 5", Timeout: 50 * time.Millisecond}.Run(context.Background(), c)
+	assert.ErrorContains(t, err, "timed out")
+}
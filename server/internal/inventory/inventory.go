@@ -0,0 +1,230 @@
+// Package inventory reads the hostnames infrastructure code provisions, from Terraform's JSON output or a plain
+// JSON list, so new endpoints can be tracked as soon as they exist
+package inventory
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TerraformTag is given to every hostname found in Terraform output
+const TerraformTag = "terraform"
+
+// Entry is a hostname found in an inventory
+type Entry struct {
+	// Source is what the hostname came from, the resource address for Terraform
+	Source   string
+	Hostname string
+	Tags     []string
+}
+
+// IsJSON reports whether data looks like JSON rather than a web server configuration
+func IsJSON(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && (data[0] == '{' || data[0] == '[') && json.Valid(data)
+}
+
+// Parse reads the output of `terraform show -json`, for a plan or a state, or a plain inventory:
+//
+//	{"domains": ["www.example.com", {"name": "api.example.com", "tags": ["prod"]}]}
+//
+// The domains list may also be given on its own. Wildcards are left out, as they can't be checked
+func Parse(data []byte) ([]Entry, error) {
+	var probe struct {
+		FormatVersion string          `json:"format_version"`
+		Domains       json.RawMessage `json:"domains"`
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		return parseDomains(data)
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("invalid inventory: %w", err)
+	}
+	switch {
+	case probe.FormatVersion != "":
+		return parseTerraform(data)
+	case probe.Domains != nil:
+		return parseDomains(probe.Domains)
+	default:
+		return nil, errors.New("invalid inventory: expected terraform show -json output or a domains list")
+	}
+}
+
+// parseDomains reads a list of hostnames, each a string or an object with a name and tags
+func parseDomains(data []byte) ([]Entry, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("invalid inventory: %w", err)
+	}
+	var entries []Entry
+	for i, item := range items {
+		var e struct {
+			Name string   `json:"name"`
+			Tags []string `json:"tags"`
+		}
+		if err := json.Unmarshal(item, &e.Name); err != nil {
+			if err := json.Unmarshal(item, &e); err != nil {
+				return nil, fmt.Errorf("invalid inventory: domain %d: %w", i+1, err)
+			}
+		}
+		if host, ok := hostname(e.Name); ok {
+			entries = append(entries, Entry{Source: fmt.Sprintf("domains[%d]", i), Hostname: host, Tags: e.Tags})
+		}
+	}
+	return entries, nil
+}
+
+// terraformModule is a module of the values Terraform reports, a plan's planned values or a state's values
+type terraformModule struct {
+	Resources []struct {
+		Address string         `json:"address"`
+		Mode    string         `json:"mode"`
+		Type    string         `json:"type"`
+		Values  map[string]any `json:"values"`
+	} `json:"resources"`
+	ChildModules []terraformModule `json:"child_modules"`
+}
+
+// parseTerraform reads the hostnames of certificates and DNS records, from the planned values of a plan or the
+// values of a state
+func parseTerraform(data []byte) ([]Entry, error) {
+	var out struct {
+		PlannedValues *struct {
+			RootModule terraformModule `json:"root_module"`
+		} `json:"planned_values"`
+		Values *struct {
+			RootModule terraformModule `json:"root_module"`
+		} `json:"values"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("invalid terraform output: %w", err)
+	}
+
+	var root terraformModule
+	switch {
+	case out.PlannedValues != nil:
+		root = out.PlannedValues.RootModule
+	case out.Values != nil:
+		root = out.Values.RootModule
+	}
+	var entries []Entry
+	seen := map[string]bool{}
+	walkTerraform(root, func(address, resourceType string, values map[string]any) {
+		for _, name := range terraformHostnames(resourceType, values) {
+			host, ok := hostname(name)
+			if !ok || seen[host] {
+				continue
+			}
+			seen[host] = true
+			entries = append(entries, Entry{Source: address, Hostname: host, Tags: []string{TerraformTag}})
+		}
+	})
+	return entries, nil
+}
+
+func walkTerraform(m terraformModule, visit func(address, resourceType string, values map[string]any)) {
+	for _, r := range m.Resources {
+		if r.Mode == "managed" || r.Mode == "" {
+			visit(r.Address, r.Type, r.Values)
+		}
+	}
+	for _, child := range m.ChildModules {
+		walkTerraform(child, visit)
+	}
+}
+
+// terraformHostnames returns the names a resource makes reachable. DNS records only count when they point
+// somewhere an HTTPS server can answer, so TXT, MX and similar records are skipped
+func terraformHostnames(resourceType string, values map[string]any) []string {
+	str := func(key string) string {
+		s, _ := values[key].(string)
+		return s
+	}
+	// join puts a record name relative to its zone in front of the zone, @ naming the zone itself
+	join := func(name, zone string) string {
+		if name == "" || name == "@" {
+			return zone
+		}
+		if zone == "" || strings.HasSuffix(strings.TrimSuffix(name, "."), strings.TrimSuffix(zone, ".")) {
+			return name
+		}
+		return name + "." + zone
+	}
+	webRecord := func(recordType string) bool {
+		switch strings.ToUpper(recordType) {
+		case "A", "AAAA", "CNAME", "ALIAS":
+			return true
+		}
+		return false
+	}
+
+	switch resourceType {
+	case "aws_acm_certificate":
+		names := []string{str("domain_name")}
+		if sans, ok := values["subject_alternative_names"].([]any); ok {
+			for _, san := range sans {
+				if s, ok := san.(string); ok {
+					names = append(names, s)
+				}
+			}
+		}
+		// Terraform lists the names unordered
+		sort.Strings(names[1:])
+		return names
+	case "aws_route53_record":
+		if webRecord(str("type")) {
+			if fqdn := str("fqdn"); fqdn != "" {
+				return []string{fqdn}
+			}
+			return []string{str("name")}
+		}
+	case "google_dns_record_set", "cloudflare_record", "cloudflare_dns_record":
+		if webRecord(str("type")) {
+			if host := str("hostname"); host != "" {
+				return []string{host}
+			}
+			return []string{str("name")}
+		}
+	case "digitalocean_record":
+		if webRecord(str("type")) {
+			if fqdn := str("fqdn"); fqdn != "" {
+				return []string{fqdn}
+			}
+			return []string{join(str("name"), str("domain"))}
+		}
+	case "azurerm_dns_a_record", "azurerm_dns_aaaa_record", "azurerm_dns_cname_record":
+		if fqdn := str("fqdn"); fqdn != "" {
+			return []string{fqdn}
+		}
+		return []string{join(str("name"), str("zone_name"))}
+	case "google_compute_managed_ssl_certificate", "google_certificate_manager_certificate":
+		var names []string
+		if managed, ok := values["managed"].([]any); ok {
+			for _, m := range managed {
+				block, _ := m.(map[string]any)
+				domains, _ := block["domains"].([]any)
+				for _, d := range domains {
+					if s, ok := d.(string); ok {
+						names = append(names, s)
+					}
+				}
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// hostname cleans up a name, reporting false for wildcards and names that aren't hostnames
+func hostname(name string) (string, bool) {
+	name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+	if name == "" || !strings.Contains(name, ".") || strings.ContainsAny(name, "*@ /:_") {
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,60 @@
+package inventory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParse_Terraform - certificates and web-facing DNS records in nested modules are found in a plan.
+func TestParse_Terraform(t *testing.T) {
+	plan := `{
+  "format_version": "1.2",
+  "terraform_version": "1.9.0",
+  "prior_state": {"values": {"root_module": {"resources": [
+    {"address": "aws_route53_record.gone", "mode": "managed", "type": "aws_route53_record", "values": {"name": "gone.example.com", "type": "A"}}
+  ]}}},
+  "planned_values": {"root_module": {
+    "resources": [
+      {"address": "aws_acm_certificate.www", "mode": "managed", "type": "aws_acm_certificate",
+       "values": {"domain_name": "www.example.com", "subject_alternative_names": ["example.com", "*.example.com", "www.example.com"]}},
+      {"address": "aws_route53_record.mx", "mode": "managed", "type": "aws_route53_record", "values": {"name": "example.com", "type": "MX"}},
+      {"address": "data.aws_route53_zone.main", "mode": "data", "type": "aws_route53_record", "values": {"name": "data.example.com", "type": "A"}}
+    ],
+    "child_modules": [{"address": "module.api", "resources": [
+      {"address": "module.api.aws_route53_record.api", "mode": "managed", "type": "aws_route53_record", "values": {"name": "api.example.com.", "type": "CNAME"}},
+      {"address": "module.api.digitalocean_record.app", "mode": "managed", "type": "digitalocean_record", "values": {"name": "app", "domain": "example.org", "type": "A"}},
+      {"address": "module.api.azurerm_dns_a_record.apex", "mode": "managed", "type": "azurerm_dns_a_record", "values": {"name": "@", "zone_name": "example.net"}}
+    ]}]
+  }}
+}`
+	entries, err := Parse([]byte(plan))
+	require.NoError(t, err)
+
+	var hosts []string
+	for _, e := range entries {
+		hosts = append(hosts, e.Hostname)
+		assert.Equal(t, []string{TerraformTag}, e.Tags)
+	}
+	assert.Equal(t, []string{"www.example.com", "example.com", "api.example.com", "app.example.org", "example.net"}, hosts)
+	assert.Equal(t, "module.api.aws_route53_record.api", entries[2].Source)
+}
+
+// TestParse_Inventory - a domains list takes plain names and names with tags.
+func TestParse_Inventory(t *testing.T) {
+	entries, err := Parse([]byte(`{"domains": ["www.example.com", {"name": "API.example.com.", "tags": ["prod"]}, "*.example.com"]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{Source: "domains[0]", Hostname: "www.example.com"},
+		{Source: "domains[1]", Hostname: "api.example.com", Tags: []string{"prod"}},
+	}, entries)
+
+	entries, err = Parse([]byte(`["shop.example.com"]`))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	_, err = Parse([]byte(`{"hosts": []}`))
+	assert.Error(t, err)
+	assert.False(t, IsJSON([]byte("server { listen 443 ssl; }")))
+}
@@ -0,0 +1,264 @@
+// Package mqtt publishes certificate check results to an MQTT broker, for home automation and IoT tools such as
+// Node-RED and Home Assistant to react to
+package mqtt
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// DefaultTopic puts the results of each domain on a topic of its own
+const DefaultTopic = "sslcerttop/{{.Domain}}"
+
+// publishTimeout is how long a publish may wait for the broker to acknowledge it
+const publishTimeout = 10 * time.Second
+
+// Message is the JSON payload published for each check
+type Message struct {
+	DomainID uint   `json:"domain_id"`
+	Domain   string `json:"domain"`
+	Status   string `json:"status"`
+	// StatusReason explains the status, e.g. how many days are left or why the check failed
+	StatusReason string `json:"status_reason"`
+	// PreviousStatus is the status before this check, empty when it isn't known
+	PreviousStatus string     `json:"previous_status,omitempty"`
+	ExpiryDate     *time.Time `json:"expiry_date"`
+	DaysLeft       *int       `json:"days_left"`
+	Issuer         string     `json:"issuer,omitempty"`
+	Error          *string    `json:"error"`
+	Tags           []string   `json:"tags"`
+	CheckedAt      time.Time  `json:"checked_at"`
+}
+
+// Changed reports whether the check moved the domain to a different status
+func (m Message) Changed() bool {
+	return m.PreviousStatus != "" && m.PreviousStatus != m.Status
+}
+
+// topicData is what topic templates can use, the domain with characters MQTT treats specially replaced
+type topicData struct {
+	Domain   string
+	DomainID uint
+	Status   string
+}
+
+// topicReplacer keeps a domain, or a file target's path, within one topic level and out of wildcards
+var topicReplacer = strings.NewReplacer("/", "_", "+", "_", "#", "_")
+
+// Options configure a Publisher
+type Options struct {
+	// Broker is the broker URL, e.g. tcp://localhost:1883, ssl://broker:8883 or wss://broker/mqtt
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+	// Topic is a template of the topic each result goes to, DefaultTopic when empty
+	Topic  string
+	QoS    byte
+	Retain bool
+	// TLS is used for ssl://, tls://, mqtts:// and wss:// brokers, nil uses the system roots
+	TLS *tls.Config
+}
+
+// Publisher sends a Message for every check result
+type Publisher struct {
+	topic  *template.Template
+	qos    byte
+	retain bool
+	client paho.Client
+	// publish is client.Publish, replaced by tests
+	publish func(topic string, qos byte, retain bool, payload []byte) error
+}
+
+// ParseTopic checks a topic template
+func ParseTopic(topic string) (*template.Template, error) {
+	if topic == "" {
+		topic = DefaultTopic
+	}
+	tmpl, err := template.New("topic").Option("missingkey=error").Parse(topic)
+	if err != nil {
+		return nil, fmt.Errorf("invalid topic template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// NewPublisher connects to the broker in the background, retrying until it is reachable, so a broker that is down
+// doesn't stop the daemon
+func NewPublisher(opts Options) (*Publisher, error) {
+	tmpl, err := ParseTopic(opts.Topic)
+	if err != nil {
+		return nil, err
+	}
+	if opts.QoS > 2 {
+		return nil, fmt.Errorf("qos must be 0, 1 or 2, got %d", opts.QoS)
+	}
+	clientID := opts.ClientID
+	if clientID == "" {
+		host, _ := os.Hostname()
+		clientID = "sslcerttop-" + host
+	}
+
+	clientOpts := paho.NewClientOptions().
+		AddBroker(brokerURL(opts.Broker)).
+		SetClientID(clientID).
+		SetUsername(opts.Username).
+		SetPassword(opts.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(10 * time.Second).
+		SetOnConnectHandler(func(paho.Client) { slog.Info("Connected to MQTT broker", "broker", opts.Broker) }).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			slog.Warn("Lost connection to MQTT broker", "broker", opts.Broker, "error", err)
+		})
+	if opts.TLS != nil {
+		clientOpts.SetTLSConfig(opts.TLS)
+	}
+	client := paho.NewClient(clientOpts)
+	client.Connect()
+
+	p := &Publisher{topic: tmpl, qos: opts.QoS, retain: opts.Retain, client: client}
+	p.publish = func(topic string, qos byte, retain bool, payload []byte) error {
+		// paho would queue the message until it reconnects, holding up every result behind it
+		if !client.IsConnectionOpen() {
+			return errors.New("not connected to the broker")
+		}
+		token := client.Publish(topic, qos, retain, payload)
+		if !token.WaitTimeout(publishTimeout) {
+			return errors.New("timed out waiting for the broker")
+		}
+		return token.Error()
+	}
+	return p, nil
+}
+
+// brokerURL accepts the mqtt:// and mqtts:// schemes too, paho knows them as tcp:// and ssl://
+func brokerURL(broker string) string {
+	switch {
+	case strings.HasPrefix(broker, "mqtt://"):
+		return "tcp://" + strings.TrimPrefix(broker, "mqtt://")
+	case strings.HasPrefix(broker, "mqtts://"):
+		return "ssl://" + strings.TrimPrefix(broker, "mqtts://")
+	}
+	return broker
+}
+
+// TLSConfig builds the TLS settings of a broker connection: caFile replaces the system roots, certFile and keyFile
+// authenticate this client, and insecure skips verifying the broker's certificate
+func TLSConfig(caFile, certFile, keyFile string, insecure bool) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: insecure}
+	if caFile != "" {
+		data, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates in CA file %s", caFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// Run publishes the result of every check until ctx is done
+func (p *Publisher) Run(ctx context.Context, domains *domain.Service) error {
+	results, unsubscribe := domains.SubscribeResults(64)
+	defer unsubscribe()
+
+	// Statuses before the first check of each domain, so the first result can tell a change too
+	statuses := map[types.DomainID]string{}
+	if active, err := domains.GetActiveDomains(); err == nil {
+		for _, d := range active {
+			statuses[d.DomainID] = string(domains.Status(d).Level)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case result, ok := <-results:
+			if !ok {
+				return nil
+			}
+			d, err := domains.GetDomain(types.DomainID(result.Task.DomainID))
+			if err != nil {
+				slog.Error("Failed to look up checked domain", "domain", result.Task.Domain, "error", err)
+				continue
+			}
+			m := NewMessage(*d, domains.Status(*d), result, statuses[d.DomainID])
+			statuses[d.DomainID] = m.Status
+			if err := p.Publish(m); err != nil {
+				slog.Error("Failed to publish check result", "domain", m.Domain, "error", err)
+			}
+		}
+	}
+}
+
+// NewMessage describes a check of d, which already holds the result and is in status, previous is the status
+// before the check
+func NewMessage(d domain.Domain, status domain.DomainStatus, result ssl.Result, previous string) Message {
+	m := Message{
+		DomainID:       d.DomainID.Uint(),
+		Domain:         d.DomainName.String(),
+		Status:         string(status.Level),
+		StatusReason:   status.Reason,
+		PreviousStatus: previous,
+		DaysLeft:       status.DaysLeft,
+		ExpiryDate:     d.ExpiryTime(),
+		Issuer:         d.Issuer,
+		Tags:           d.Tags,
+		CheckedAt:      result.CheckedAt,
+	}
+	if m.Tags == nil {
+		m.Tags = []string{}
+	}
+	if result.Error != nil {
+		msg := result.Error.Error()
+		m.Error = &msg
+	}
+	return m
+}
+
+// Publish sends a message to the topic its template gives
+func (p *Publisher) Publish(m Message) error {
+	var topic bytes.Buffer
+	data := topicData{Domain: topicReplacer.Replace(m.Domain), DomainID: m.DomainID, Status: m.Status}
+	if err := p.topic.Execute(&topic, data); err != nil {
+		return fmt.Errorf("failed to render topic: %w", err)
+	}
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return p.publish(topic.String(), p.qos, p.retain, payload)
+}
+
+// Close disconnects from the broker, giving queued messages a moment to go out
+func (p *Publisher) Close() {
+	if p.client != nil {
+		p.client.Disconnect(250)
+	}
+}
@@ -0,0 +1,97 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// published is a message a test publisher sent
+type published struct {
+	topic   string
+	qos     byte
+	retain  bool
+	payload []byte
+}
+
+// testPublisher records what it publishes instead of talking to a broker.
+func testPublisher(t *testing.T, topic string, sent *[]published) *Publisher {
+	tmpl, err := ParseTopic(topic)
+	require.NoError(t, err)
+	p := &Publisher{topic: tmpl, qos: 1, retain: true}
+	p.publish = func(topic string, qos byte, retain bool, payload []byte) error {
+		*sent = append(*sent, published{topic, qos, retain, payload})
+		return nil
+	}
+	return p
+}
+
+// TestNewMessage - a message carries the checked domain's status, expiry and error.
+func TestNewMessage(t *testing.T) {
+	expiry := types.NewExpiryDate(time.Now().Add(10*24*time.Hour + time.Hour))
+	d := domain.Domain{DomainID: 7, DomainName: domain.NewDomainName("shop.example.com"), IsActive: true, ExpiryDate: &expiry, Issuer: "R11"}
+	checkedAt := time.Now()
+
+	m := NewMessage(d, d.StatusWith(domain.DefaultStatusThresholds, checkedAt), ssl.Result{CheckedAt: checkedAt}, "valid")
+	assert.Equal(t, uint(7), m.DomainID)
+	assert.Equal(t, "shop.example.com", m.Domain)
+	assert.Equal(t, "soon", m.Status)
+	assert.Equal(t, "expires in 10 days", m.StatusReason)
+	require.NotNil(t, m.DaysLeft)
+	assert.Equal(t, 10, *m.DaysLeft)
+	assert.Nil(t, m.Error)
+	assert.Equal(t, []string{}, m.Tags, "Tags are an empty list rather than null")
+	assert.True(t, m.Changed())
+
+	m = NewMessage(d, d.StatusWith(domain.DefaultStatusThresholds, checkedAt), ssl.Result{Error: errors.New("connection refused"), CheckedAt: checkedAt}, "")
+	require.NotNil(t, m.Error)
+	assert.Equal(t, "connection refused", *m.Error)
+	assert.False(t, m.Changed(), "Without a previous status there is no change to tell")
+}
+
+// TestPublish - the topic template is filled in with the domain, kept to one level, and the payload is JSON.
+func TestPublish(t *testing.T) {
+	var sent []published
+	p := testPublisher(t, "home/certs/{{.Status}}/{{.Domain}}", &sent)
+
+	require.NoError(t, p.Publish(Message{DomainID: 1, Domain: "www.example.com", Status: "valid"}))
+	require.NoError(t, p.Publish(Message{DomainID: 2, Domain: "file:/etc/ssl/site.pem", Status: "expired"}))
+	require.Len(t, sent, 2)
+	assert.Equal(t, "home/certs/valid/www.example.com", sent[0].topic)
+	assert.Equal(t, "home/certs/expired/file:_etc_ssl_site.pem", sent[1].topic)
+	assert.Equal(t, byte(1), sent[0].qos)
+	assert.True(t, sent[0].retain)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(sent[0].payload, &got))
+	assert.Equal(t, "www.example.com", got["domain"])
+	assert.Equal(t, "valid", got["status"])
+	assert.Nil(t, got["error"])
+}
+
+// TestParseTopic - an empty topic falls back to the default and broken templates are refused.
+func TestParseTopic(t *testing.T) {
+	var sent []published
+	p := testPublisher(t, "", &sent)
+	require.NoError(t, p.Publish(Message{Domain: "www.example.com"}))
+	assert.Equal(t, "sslcerttop/www.example.com", sent[0].topic)
+
+	_, err := ParseTopic("certs/{{.Domain")
+	assert.Error(t, err)
+	p = testPublisher(t, "certs/{{.Nope}}", &sent)
+	assert.Error(t, p.Publish(Message{Domain: "www.example.com"}))
+}
+
+// TestBrokerURL - mqtt:// and mqtts:// are accepted as the schemes paho knows.
+func TestBrokerURL(t *testing.T) {
+	assert.Equal(t, "tcp://localhost:1883", brokerURL("mqtt://localhost:1883"))
+	assert.Equal(t, "ssl://broker:8883", brokerURL("mqtts://broker:8883"))
+	assert.Equal(t, "wss://broker/mqtt", brokerURL("wss://broker/mqtt"))
+}
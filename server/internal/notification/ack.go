@@ -0,0 +1,39 @@
+package notification
+
+import (
+	"errors"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// MaxAckNoteLength is the longest note an acknowledgement can carry
+const MaxAckNoteLength = 1024
+
+// ErrNoAck is returned when a domain has no acknowledgement
+var ErrNoAck = errors.New("domain is not acknowledged")
+
+// DomainAck silences a domain's notifications while someone deals with its certificate,
+// e.g. "renewal scheduled for Friday"
+type DomainAck struct {
+	DomainID   types.DomainID `db:"domain_id"`
+	DomainName string         `db:"domain_name"`
+	Note       string         `db:"note"`
+	// ExpiryDate is the certificate's expiry when acknowledged, a different certificate ends the ack
+	ExpiryDate *time.Time `db:"expiry_date"`
+	// ExpiresAt ends the ack, nil keeps it until the certificate changes
+	ExpiresAt *time.Time `db:"expires_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+// Active reports whether the ack still silences a domain whose certificate expires at expiry
+func (a DomainAck) Active(expiry *time.Time, now time.Time) bool {
+	if a.ExpiresAt != nil && !now.Before(*a.ExpiresAt) {
+		return false
+	}
+	if a.ExpiryDate == nil || expiry == nil {
+		return a.ExpiryDate == nil && expiry == nil
+	}
+	// Certificates expire on whole seconds, so compare those rather than the stored precision
+	return a.ExpiryDate.Unix() == expiry.Unix()
+}
@@ -0,0 +1,63 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDomainAck_Active - acks end when they expire or the certificate changes.
+func TestDomainAck_Active(t *testing.T) {
+	now := time.Now()
+	expiry := now.Add(5 * 24 * time.Hour)
+	renewed := now.Add(90 * 24 * time.Hour)
+	later := now.Add(time.Hour)
+
+	a := DomainAck{ExpiryDate: &expiry}
+	assert.True(t, a.Active(&expiry, now))
+	sameSecond := expiry.Truncate(time.Second)
+	assert.True(t, a.Active(&sameSecond, now), "Stored precision doesn't matter")
+	assert.False(t, a.Active(&renewed, now), "A new certificate ends the ack")
+	assert.False(t, a.Active(nil, now))
+
+	a.ExpiresAt = &later
+	assert.True(t, a.Active(&expiry, now))
+	assert.False(t, a.Active(&expiry, later), "The ack ends at ExpiresAt")
+
+	assert.True(t, DomainAck{}.Active(nil, now), "Domains never checked can be acknowledged too")
+}
+
+// TestService_Acks - acknowledging replaces the earlier ack and removing forgets it.
+func TestService_Acks(t *testing.T) {
+	s := NewService(NewRepository(newTestDB(t)))
+	expiry := time.Now().Add(5 * 24 * time.Hour)
+	dom := testDomain(expiry)
+	dom.UserID = types.UserID(1)
+
+	_, err := s.GetDomainAck(dom.DomainID)
+	assert.ErrorIs(t, err, ErrNoAck)
+
+	past := time.Now().Add(-time.Hour)
+	_, err = s.AcknowledgeDomain(dom, "too late", &past)
+	assert.Error(t, err)
+
+	_, err = s.AcknowledgeDomain(dom, "first", nil)
+	require.NoError(t, err)
+	until := time.Now().Add(72 * time.Hour)
+	_, err = s.AcknowledgeDomain(dom, " renewal scheduled for Friday ", &until)
+	require.NoError(t, err)
+
+	acks, err := s.GetUsersAcks(types.UserID(1))
+	require.NoError(t, err)
+	require.Len(t, acks, 1, "A new ack replaces the old one")
+	assert.Equal(t, "example.com", acks[0].DomainName)
+	assert.Equal(t, "renewal scheduled for Friday", acks[0].Note)
+	require.NotNil(t, acks[0].ExpiresAt)
+	assert.True(t, acks[0].Active(&expiry, time.Now()))
+
+	require.NoError(t, s.RemoveAck(dom.DomainID))
+	assert.ErrorIs(t, s.RemoveAck(dom.DomainID), ErrNoAck)
+}
@@ -0,0 +1,69 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ChatSender posts notifications as a text message to a Slack or Discord incoming webhook
+type ChatSender struct {
+	nType      NotificationType
+	webhookURL string
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewSlackSender creates a sender for a Slack webhook, a nil httpClient uses one with a 30 second timeout.
+//
+// Returns an error if the webhook URL isn't an absolute http(s) URL
+func NewSlackSender(webhookURL string, httpClient *http.Client) (*ChatSender, error) {
+	return newChatSender(NotificationTypeSlack, webhookURL, httpClient)
+}
+
+// NewDiscordSender creates a sender for a Discord webhook, a nil httpClient uses one with a 30 second timeout.
+//
+// Returns an error if the webhook URL isn't an absolute http(s) URL
+func NewDiscordSender(webhookURL string, httpClient *http.Client) (*ChatSender, error) {
+	return newChatSender(NotificationTypeDiscord, webhookURL, httpClient)
+}
+
+func newChatSender(nType NotificationType, webhookURL string, httpClient *http.Client) (*ChatSender, error) {
+	if err := validateWebhookURL(webhookURL); err != nil {
+		return nil, err
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: webhookTimeout}
+	}
+	return &ChatSender{
+		nType:      nType,
+		webhookURL: webhookURL,
+		httpClient: httpClient,
+		now:        time.Now,
+	}, nil
+}
+
+func (s *ChatSender) Type() NotificationType {
+	return s.nType
+}
+
+// Send posts the notification's title in bold above the same text an email has
+func (s *ChatSender) Send(ctx context.Context, n Notification) error {
+	data := newMessageData(n, s.now())
+	body := data.body
+	if body == "" {
+		var b bytes.Buffer
+		if err := emailBody.Execute(&b, data); err != nil {
+			return fmt.Errorf("failed to render message: %w", err)
+		}
+		body = b.String()
+	}
+
+	// Both take Markdown, but Slack marks bold with single asterisks and only Slack calls the message text
+	if s.nType == NotificationTypeSlack {
+		return postJSON(ctx, s.httpClient, s.webhookURL, map[string]string{"text": "*" + data.Title() + "*\n" + body}, nil)
+	}
+	return postJSON(ctx, s.httpClient, s.webhookURL, map[string]string{"content": "**" + data.Title() + "**\n" + body}, nil)
+}
@@ -0,0 +1,48 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestChatSender_Send - Slack gets the message as text, Discord as content, each with the title in bold.
+func TestChatSender_Send(t *testing.T) {
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	_, err := NewSlackSender("ftp://example.com/hook", nil)
+	assert.Error(t, err)
+
+	slack, err := NewSlackSender(server.URL, server.Client())
+	require.NoError(t, err)
+	discord, err := NewDiscordSender(server.URL, server.Client())
+	require.NoError(t, err)
+	assert.Equal(t, NotificationTypeSlack, slack.Type())
+	assert.Equal(t, NotificationTypeDiscord, discord.Type())
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	expiry := now.Add(3*24*time.Hour + time.Hour)
+	n := Notification{DomainName: "example.com", ExpiryDate: &expiry, DaysBefore: 7}
+
+	slack.now = func() time.Time { return now }
+	require.NoError(t, slack.Send(context.Background(), n))
+	assert.Contains(t, got["text"], "*SSL certificate for example.com expires in 3 days*\n")
+	assert.Contains(t, got["text"], "Threshold:  7 days")
+
+	discord.now = func() time.Time { return now }
+	n.Body = "Renew example.com before Friday"
+	require.NoError(t, discord.Send(context.Background(), n))
+	assert.Equal(t, "**SSL certificate for example.com expires in 3 days**\nRenew example.com before Friday", got["content"])
+}
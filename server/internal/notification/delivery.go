@@ -0,0 +1,87 @@
+package notification
+
+import (
+	"errors"
+	"net/http"
+	"net/textproto"
+	"time"
+)
+
+// maxRetryDelay caps the backoff between delivery attempts
+const maxRetryDelay = time.Hour
+
+// DefaultRetryPolicy tries a notification five times, waiting 1, 2, 4 and 8 minutes in between
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 5, Backoff: time.Minute}
+
+// RetryPolicy decides how often and how soon transiently failed notifications are sent again
+type RetryPolicy struct {
+	// MaxAttempts includes the first delivery, one never retries
+	MaxAttempts int
+	// Backoff is the wait before the first retry, doubling for each one after it
+	Backoff time.Duration
+}
+
+// Delay is the wait after the given number of failed attempts
+func (p RetryPolicy) Delay(attempts int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// DeliveryAttempt records one try at delivering a notification
+type DeliveryAttempt struct {
+	ID             uint      `db:"id"`
+	NotificationID uint      `db:"notification_id"`
+	AttemptedAt    time.Time `db:"attempted_at"`
+	// StatusCode is the HTTP or SMTP reply code, nil when the server never answered
+	StatusCode *int    `db:"status_code"`
+	Error      *string `db:"error"`
+}
+
+// Succeeded reports whether the attempt delivered the notification
+func (a DeliveryAttempt) Succeeded() bool {
+	return a.Error == nil
+}
+
+// DeliveryError is a delivery the receiving server answered with a failure
+type DeliveryError struct {
+	StatusCode int
+	// Permanent failures are not retried, e.g. a rejected payload or a revoked webhook
+	Permanent bool
+	Err       error
+}
+
+func (e *DeliveryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// newHTTPError classifies a non-2xx response, client errors other than timeouts and rate limits are permanent
+func newHTTPError(statusCode int, err error) *DeliveryError {
+	permanent := statusCode >= 400 && statusCode < 500
+	switch statusCode {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+		permanent = false
+	}
+	return &DeliveryError{StatusCode: statusCode, Permanent: permanent, Err: err}
+}
+
+// classifyDeliveryError returns the reply code of a failed delivery, if any, and whether retrying is pointless.
+//
+// SMTP 5xx replies are permanent, anything without a reply such as a refused connection is transient
+func classifyDeliveryError(err error) (statusCode *int, permanent bool) {
+	var deliveryErr *DeliveryError
+	if errors.As(err, &deliveryErr) {
+		return &deliveryErr.StatusCode, deliveryErr.Permanent
+	}
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) {
+		return &smtpErr.Code, smtpErr.Code >= 500
+	}
+	return nil, false
+}
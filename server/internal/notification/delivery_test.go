@@ -0,0 +1,162 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRetryPolicy_Delay - the backoff doubles per attempt and is capped.
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 10, Backoff: time.Minute}
+
+	assert.Equal(t, time.Minute, p.Delay(1))
+	assert.Equal(t, 2*time.Minute, p.Delay(2))
+	assert.Equal(t, 8*time.Minute, p.Delay(4))
+	assert.Equal(t, maxRetryDelay, p.Delay(20))
+}
+
+// TestClassifyDeliveryError - client errors and SMTP 5xx replies are permanent, the rest is retried.
+func TestClassifyDeliveryError(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantCode      *int
+		wantPermanent bool
+	}{
+		{"network", errors.New("connection refused"), nil, false},
+		{"server error", newHTTPError(502, errors.New("bad gateway")), intPtr(502), false},
+		{"rate limited", newHTTPError(429, errors.New("slow down")), intPtr(429), false},
+		{"not found", newHTTPError(404, errors.New("gone")), intPtr(404), true},
+		{"wrapped", fmt.Errorf("teams: %w", newHTTPError(400, errors.New("bad card"))), intPtr(400), true},
+		{"smtp busy", fmt.Errorf("smtp server rejected message: %w", &textproto.Error{Code: 451, Msg: "try later"}), intPtr(451), false},
+		{"smtp rejected", fmt.Errorf("smtp server rejected recipient: %w", &textproto.Error{Code: 550, Msg: "no such user"}), intPtr(550), true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			code, permanent := classifyDeliveryError(tc.err)
+			assert.Equal(t, tc.wantCode, code)
+			assert.Equal(t, tc.wantPermanent, permanent)
+		})
+	}
+}
+
+func intPtr(i int) *int { return &i }
+
+// TestPost_StatusCode - non-2xx webhook responses carry their status code.
+func TestPost_StatusCode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	err := post(context.Background(), server.Client(), server.URL, []byte(`{}`), nil)
+	var deliveryErr *DeliveryError
+	require.ErrorAs(t, err, &deliveryErr)
+	assert.Equal(t, http.StatusServiceUnavailable, deliveryErr.StatusCode)
+	assert.False(t, deliveryErr.Permanent)
+}
+
+// TestDispatcher_Retry - transient failures are retried after the backoff until they succeed.
+func TestDispatcher_Retry(t *testing.T) {
+	repo := NewRepository(newTestDB(t))
+	slack := &fakeSender{nType: NotificationTypeSlack, err: newHTTPError(503, errors.New("unavailable"))}
+	d := NewDispatcher(repo, DefaultThresholds, slack)
+	d.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, Backoff: time.Minute})
+	now := time.Now()
+	d.now = func() time.Time { return now }
+
+	n := Notification{DomainID: types.DomainID(1), DaysBefore: 30, NotificationType: NotificationTypeSlack}
+	require.NoError(t, repo.CreateNotification(&n))
+
+	require.NoError(t, d.DeliverPending(context.Background()))
+	got, err := repo.GetNotificationByID(n.NotificationID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusRetrying, got.Status)
+	assert.Equal(t, 1, got.Attempts)
+	require.NotNil(t, got.LastStatusCode)
+	assert.Equal(t, 503, *got.LastStatusCode)
+	require.NotNil(t, got.NextAttemptAt)
+	assert.WithinDuration(t, now.Add(time.Minute), *got.NextAttemptAt, time.Second)
+
+	// Not due yet
+	require.NoError(t, d.DeliverPending(context.Background()))
+	assert.Len(t, slack.sent, 1)
+
+	slack.err = nil
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, d.DeliverPending(context.Background()))
+	assert.Len(t, slack.sent, 2)
+
+	got, err = repo.GetNotificationByID(n.NotificationID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusSent, got.Status)
+	assert.Equal(t, 2, got.Attempts)
+	assert.Nil(t, got.NextAttemptAt)
+	assert.Nil(t, got.LastError)
+	assert.NotNil(t, got.SentAt)
+
+	attempts, err := repo.GetAttempts(n.NotificationID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+	assert.False(t, attempts[0].Succeeded())
+	assert.Equal(t, 503, *attempts[0].StatusCode)
+	assert.True(t, attempts[1].Succeeded())
+}
+
+// TestDispatcher_RetryGivesUp - permanent failures and exhausted retries fail the notification.
+func TestDispatcher_RetryGivesUp(t *testing.T) {
+	repo := NewRepository(newTestDB(t))
+	service := NewService(repo)
+	slack := &fakeSender{nType: NotificationTypeSlack, err: errors.New("connection refused")}
+	teams := &fakeSender{nType: NotificationTypeTeams, err: newHTTPError(404, errors.New("webhook gone"))}
+	d := NewDispatcher(repo, DefaultThresholds, slack, teams)
+	d.SetRetryPolicy(RetryPolicy{MaxAttempts: 2, Backoff: time.Minute})
+	now := time.Now()
+	d.now = func() time.Time { return now }
+
+	viaSlack := Notification{DomainID: types.DomainID(1), DaysBefore: 30, NotificationType: NotificationTypeSlack}
+	require.NoError(t, repo.CreateNotification(&viaSlack))
+	viaTeams := Notification{DomainID: types.DomainID(1), DaysBefore: 30, NotificationType: NotificationTypeTeams}
+	require.NoError(t, repo.CreateNotification(&viaTeams))
+
+	require.NoError(t, d.DeliverPending(context.Background()))
+	got, err := repo.GetNotificationByID(viaTeams.NotificationID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, got.Status)
+	assert.Equal(t, 1, got.Attempts)
+
+	now = now.Add(time.Hour)
+	require.NoError(t, d.DeliverPending(context.Background()))
+	got, err = repo.GetNotificationByID(viaSlack.NotificationID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, got.Status)
+	assert.Equal(t, 2, got.Attempts)
+	assert.Nil(t, got.LastStatusCode)
+
+	now = now.Add(time.Hour)
+	require.NoError(t, d.DeliverPending(context.Background()))
+	assert.Len(t, slack.sent, 2)
+	assert.Len(t, teams.sent, 1)
+
+	// Resending starts over, keeping the attempt history
+	require.NoError(t, service.Resend(viaSlack.NotificationID))
+	got, err = repo.GetNotificationByID(viaSlack.NotificationID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, got.Status)
+	assert.Equal(t, 0, got.Attempts)
+
+	attempts, err := service.GetAttempts(viaSlack.NotificationID)
+	require.NoError(t, err)
+	assert.Len(t, attempts, 2)
+}
@@ -0,0 +1,312 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"log/slog"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/cron"
+)
+
+// DefaultDigestDays is how far ahead a digest lists expiring certificates
+const DefaultDigestDays = 30
+
+// untaggedGroup is the digest group of domains without tags
+const untaggedGroup = "untagged"
+
+// DigestDomain is a domain listed in a digest
+type DigestDomain struct {
+	Domain     string
+	ExpiryDate *time.Time
+	DaysLeft   int
+	Tags       []string
+	Error      string
+	// FailingSince is the first failed check of the current run of failures
+	FailingSince *time.Time
+}
+
+// DigestGroup lists the expiring domains carrying a tag, soonest first
+type DigestGroup struct {
+	Tag     string
+	Domains []DigestDomain
+}
+
+// DigestRenewal is a certificate replaced by one expiring later
+type DigestRenewal struct {
+	Domain         string
+	RenewedAt      time.Time
+	PreviousExpiry time.Time
+	ExpiryDate     time.Time
+}
+
+// Digest summarises the certificates that need attention and what changed since the previous digest
+type Digest struct {
+	GeneratedAt time.Time
+	// Since is when the previous digest was sent, nil for the first one
+	Since *time.Time
+	Days  int
+	// Expiring groups certificates expiring within Days by tag, a domain with several tags is in each group
+	Expiring []DigestGroup
+	// NewErrors are domains whose checks started failing since the previous digest and still fail
+	NewErrors []DigestDomain
+	Renewed   []DigestRenewal
+}
+
+// Empty reports whether the digest has nothing to report
+func (d Digest) Empty() bool {
+	return len(d.Expiring) == 0 && len(d.NewErrors) == 0 && len(d.Renewed) == 0
+}
+
+// ExpiringCount is the number of distinct domains in the expiring groups
+func (d Digest) ExpiringCount() int {
+	seen := map[string]bool{}
+	for _, g := range d.Expiring {
+		for _, dom := range g.Domains {
+			seen[dom.Domain] = true
+		}
+	}
+	return len(seen)
+}
+
+// Subject is the one line summary used as the email subject
+func (d Digest) Subject() string {
+	return fmt.Sprintf("sslcerttop digest: %d expiring, %d new %s, %d renewed",
+		d.ExpiringCount(), len(d.NewErrors), plural(len(d.NewErrors), "error", "errors"), len(d.Renewed))
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// DigestSender delivers digests, EmailSender is the only one
+type DigestSender interface {
+	SendDigest(ctx context.Context, d Digest) error
+}
+
+// Digester sends a digest whenever its schedule comes round
+type Digester struct {
+	notificationRepo *Repository
+	sender           DigestSender
+	schedule         *cron.Schedule
+	days             int
+	// started stands in for the previous digest until the first one is sent
+	started time.Time
+}
+
+// NewDigester creates a digester listing certificates expiring within days, DefaultDigestDays when zero
+func NewDigester(notificationRepo *Repository, sender DigestSender, schedule *cron.Schedule, days int) *Digester {
+	if days <= 0 {
+		days = DefaultDigestDays
+	}
+	return &Digester{
+		notificationRepo: notificationRepo,
+		sender:           sender,
+		schedule:         schedule,
+		days:             days,
+		started:          time.Now(),
+	}
+}
+
+// SendIfDue sends a digest when a scheduled time has passed since the previous one
+func (d *Digester) SendIfDue(ctx context.Context, now time.Time) error {
+	last, err := d.notificationRepo.LastDigestAt()
+	if err != nil {
+		return fmt.Errorf("failed to get last digest: %w", err)
+	}
+	from := d.started
+	if last != nil {
+		from = *last
+	}
+	if !d.schedule.DueSince(from, now) {
+		return nil
+	}
+	return d.Send(ctx, now)
+}
+
+// Send builds a digest as of now, mails it and records it as the previous digest
+func (d *Digester) Send(ctx context.Context, now time.Time) error {
+	digest, err := d.Build(now)
+	if err != nil {
+		return err
+	}
+	if err := d.sender.SendDigest(ctx, digest); err != nil {
+		return fmt.Errorf("failed to send digest: %w", err)
+	}
+	if err := d.notificationRepo.RecordDigest(now); err != nil {
+		return fmt.Errorf("failed to record digest: %w", err)
+	}
+	slog.Info("Digest sent", "expiring", digest.ExpiringCount(), "new_errors", len(digest.NewErrors), "renewed", len(digest.Renewed))
+	return nil
+}
+
+// Build collects the digest as of now, covering changes since the previous digest or the last Days days
+func (d *Digester) Build(now time.Time) (Digest, error) {
+	last, err := d.notificationRepo.LastDigestAt()
+	if err != nil {
+		return Digest{}, fmt.Errorf("failed to get last digest: %w", err)
+	}
+	since := now.AddDate(0, 0, -d.days)
+	if last != nil {
+		since = *last
+	}
+
+	domains, err := d.notificationRepo.GetDigestDomains()
+	if err != nil {
+		return Digest{}, fmt.Errorf("failed to get domains: %w", err)
+	}
+	renewals, err := d.notificationRepo.GetRenewals(since)
+	if err != nil {
+		return Digest{}, fmt.Errorf("failed to get renewals: %w", err)
+	}
+	return newDigest(domains, renewals, last, since, now, d.days), nil
+}
+
+// newDigest sorts domains into the digest's sections
+func newDigest(domains []DigestDomain, renewals []DigestRenewal, last *time.Time, since, now time.Time, days int) Digest {
+	digest := Digest{GeneratedAt: now, Since: last, Days: days, Renewed: renewals}
+	horizon := now.AddDate(0, 0, days)
+
+	groups := map[string][]DigestDomain{}
+	for _, dom := range domains {
+		if dom.ExpiryDate != nil {
+			dom.DaysLeft = int(dom.ExpiryDate.Sub(now).Hours() / 24)
+		}
+		if dom.FailingSince != nil && !dom.FailingSince.Before(since) {
+			digest.NewErrors = append(digest.NewErrors, dom)
+		}
+		if dom.ExpiryDate == nil || dom.ExpiryDate.After(horizon) {
+			continue
+		}
+		tags := dom.Tags
+		if len(tags) == 0 {
+			tags = []string{untaggedGroup}
+		}
+		for _, tag := range tags {
+			groups[tag] = append(groups[tag], dom)
+		}
+	}
+
+	for tag, doms := range groups {
+		sort.Slice(doms, func(i, j int) bool { return doms[i].ExpiryDate.Before(*doms[j].ExpiryDate) })
+		digest.Expiring = append(digest.Expiring, DigestGroup{Tag: tag, Domains: doms})
+	}
+	// Tags alphabetically with the untagged domains last
+	sort.Slice(digest.Expiring, func(i, j int) bool {
+		a, b := digest.Expiring[i].Tag, digest.Expiring[j].Tag
+		if (a == untaggedGroup) != (b == untaggedGroup) {
+			return b == untaggedGroup
+		}
+		return a < b
+	})
+	return digest
+}
+
+var digestFuncs = template.FuncMap{
+	"date": func(t time.Time) string { return t.Format("2006-01-02") },
+	"join": strings.Join,
+}
+
+var digestText = template.Must(template.New("digest").Funcs(digestFuncs).Parse(`SSL certificate digest for {{date .GeneratedAt}}
+{{- if .Since}}, changes since {{date .Since}}{{end}}
+
+Expiring in the next {{.Days}} days
+{{- range .Expiring}}
+
+  {{.Tag}}
+{{- range .Domains}}
+    {{.Domain}}  {{date .ExpiryDate}}  {{if lt .DaysLeft 0}}expired{{else}}{{.DaysLeft}} days{{end}}
+{{- end}}
+{{- else}}
+  Nothing expires soon.
+{{- end}}
+
+New errors
+{{- range .NewErrors}}
+  {{.Domain}}  failing since {{date .FailingSince}}: {{.Error}}
+{{- else}}
+  None.
+{{- end}}
+
+Recently renewed
+{{- range .Renewed}}
+  {{.Domain}}  {{date .PreviousExpiry}} -> {{date .ExpiryDate}}
+{{- else}}
+  None.
+{{- end}}
+
+--
+sslcerttop
+`))
+
+var digestHTML = htmltemplate.Must(htmltemplate.New("digest").Funcs(htmltemplate.FuncMap(digestFuncs)).Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif">
+<h2>SSL certificate digest for {{date .GeneratedAt}}</h2>
+{{- if .Since}}
+<p>Changes since {{date .Since}}.</p>
+{{- end}}
+<h3>Expiring in the next {{.Days}} days</h3>
+{{- range .Expiring}}
+<h4>{{.Tag}}</h4>
+<table cellpadding="4">
+<tr><th align="left">Domain</th><th align="left">Expires</th><th align="left">Days left</th></tr>
+{{- range .Domains}}
+<tr><td>{{.Domain}}</td><td>{{date .ExpiryDate}}</td><td>{{if lt .DaysLeft 0}}expired{{else}}{{.DaysLeft}}{{end}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>Nothing expires soon.</p>
+{{- end}}
+<h3>New errors</h3>
+{{- if .NewErrors}}
+<table cellpadding="4">
+<tr><th align="left">Domain</th><th align="left">Failing since</th><th align="left">Error</th></tr>
+{{- range .NewErrors}}
+<tr><td>{{.Domain}}</td><td>{{date .FailingSince}}</td><td>{{.Error}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>None.</p>
+{{- end}}
+<h3>Recently renewed</h3>
+{{- if .Renewed}}
+<table cellpadding="4">
+<tr><th align="left">Domain</th><th align="left">Previous expiry</th><th align="left">New expiry</th></tr>
+{{- range .Renewed}}
+<tr><td>{{.Domain}}</td><td>{{date .PreviousExpiry}}</td><td>{{date .ExpiryDate}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>None.</p>
+{{- end}}
+<p style="color: #888">sslcerttop</p>
+</body>
+</html>
+`))
+
+// Text renders the digest as plain text
+func (d Digest) Text() (string, error) {
+	var b bytes.Buffer
+	if err := digestText.Execute(&b, d); err != nil {
+		return "", fmt.Errorf("failed to render digest: %w", err)
+	}
+	return b.String(), nil
+}
+
+// HTML renders the digest as an HTML document
+func (d Digest) HTML() (string, error) {
+	var b bytes.Buffer
+	if err := digestHTML.Execute(&b, d); err != nil {
+		return "", fmt.Errorf("failed to render digest: %w", err)
+	}
+	return b.String(), nil
+}
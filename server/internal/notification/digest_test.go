@@ -0,0 +1,111 @@
+package notification
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/cron"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDigestSender struct {
+	sent []Digest
+}
+
+func (f *fakeDigestSender) SendDigest(ctx context.Context, d Digest) error {
+	f.sent = append(f.sent, d)
+	return nil
+}
+
+// TestDigester_Build - expiring certificates are grouped by tag, new errors and renewals are listed.
+func TestDigester_Build(t *testing.T) {
+	repo := NewRepository(newTestDB(t))
+	now := time.Now()
+	day := 24 * time.Hour
+
+	_, err := repo.db.Exec(`UPDATE domains SET expiry_date = ?, tags = 'web,prod' WHERE id = 1`, now.Add(10*day))
+	require.NoError(t, err)
+	_, err = repo.db.Exec(`INSERT INTO domains (id, user_id, domain_name, created_at, expiry_date) VALUES
+		(2, 1, 'soon.example', ?, ?), (3, 1, 'later.example', ?, ?), (4, 1, 'renewed.example', ?, ?)`,
+		now, now.Add(3*day), now, now.Add(60*day), now, now.Add(90*day))
+	require.NoError(t, err)
+	_, err = repo.db.Exec(`INSERT INTO domains (id, user_id, domain_name, created_at, last_error, tags) VALUES
+		(5, 1, 'broken.example', ?, 'connection refused', 'prod'), (6, 1, 'stale.example', ?, 'timeout', '')`, now, now)
+	require.NoError(t, err)
+	_, err = repo.db.Exec(`INSERT INTO check_history (domain_id, checked_at, expiry_date, error) VALUES
+		(4, ?, ?, NULL), (4, ?, ?, NULL),
+		(5, ?, NULL, NULL), (5, ?, NULL, 'connection refused'),
+		(6, ?, NULL, 'timeout'), (6, ?, NULL, 'timeout')`,
+		now.Add(-5*day), now.Add(2*day), now.Add(-time.Hour), now.Add(90*day),
+		now.Add(-2*day), now.Add(-time.Hour),
+		now.Add(-3*day), now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, repo.RecordDigest(now.Add(-day)))
+
+	digest, err := NewDigester(repo, nil, nil, 30).Build(now)
+	require.NoError(t, err)
+
+	require.NotNil(t, digest.Since)
+	require.Len(t, digest.Expiring, 3)
+	assert.Equal(t, "prod", digest.Expiring[0].Tag)
+	assert.Equal(t, "web", digest.Expiring[1].Tag)
+	assert.Equal(t, "untagged", digest.Expiring[2].Tag, "Untagged domains come last")
+	assert.Equal(t, "soon.example", digest.Expiring[2].Domains[0].Domain)
+	assert.Equal(t, 2, digest.ExpiringCount())
+
+	require.Len(t, digest.NewErrors, 1, "Domains already failing before the last digest aren't new")
+	assert.Equal(t, "broken.example", digest.NewErrors[0].Domain)
+	assert.Equal(t, "connection refused", digest.NewErrors[0].Error)
+
+	require.Len(t, digest.Renewed, 1)
+	assert.Equal(t, "renewed.example", digest.Renewed[0].Domain)
+	assert.Equal(t, "sslcerttop digest: 2 expiring, 1 new error, 1 renewed", digest.Subject())
+
+	text, err := digest.Text()
+	require.NoError(t, err)
+	assert.Contains(t, text, "broken.example  failing since")
+	html, err := digest.HTML()
+	require.NoError(t, err)
+	assert.Contains(t, html, "<td>renewed.example</td>")
+}
+
+// TestDigester_SendIfDue - a digest goes out once per scheduled time and is recorded.
+func TestDigester_SendIfDue(t *testing.T) {
+	repo := NewRepository(newTestDB(t))
+	sender := &fakeDigestSender{}
+	schedule, err := cron.Parse("@daily")
+	require.NoError(t, err)
+	d := NewDigester(repo, sender, schedule, 0)
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	d.started = start
+
+	require.NoError(t, d.SendIfDue(context.Background(), start.Add(time.Hour)))
+	assert.Empty(t, sender.sent, "Nothing is due before midnight")
+
+	require.NoError(t, d.SendIfDue(context.Background(), start.Add(13*time.Hour)))
+	require.NoError(t, d.SendIfDue(context.Background(), start.Add(14*time.Hour)))
+	require.Len(t, sender.sent, 1)
+	assert.Equal(t, DefaultDigestDays, sender.sent[0].Days)
+
+	last, err := repo.LastDigestAt()
+	require.NoError(t, err)
+	require.NotNil(t, last)
+	assert.True(t, last.Equal(start.Add(13*time.Hour)))
+}
+
+// TestEmailSender_DigestMessage - digests are mailed as plain text with an HTML alternative.
+func TestEmailSender_DigestMessage(t *testing.T) {
+	s, err := NewEmailSender(EmailConfig{Host: "smtp.example.com", From: "certs@example.com", To: []string{"a@example.com"}})
+	require.NoError(t, err)
+
+	msg, err := s.buildDigestMessage(Digest{GeneratedAt: time.Now(), Days: 30})
+	require.NoError(t, err)
+	text := string(msg)
+	assert.Contains(t, text, "Subject: sslcerttop digest: 0 expiring, 0 new errors, 0 renewed\r\n")
+	assert.Contains(t, text, "Content-Type: multipart/alternative; boundary=")
+	assert.Contains(t, text, "Content-Type: text/plain; charset=utf-8")
+	assert.Contains(t, text, "Content-Type: text/html; charset=utf-8")
+	assert.Contains(t, text, "Nothing expires soon.\r\n")
+}
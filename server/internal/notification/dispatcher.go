@@ -0,0 +1,523 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// DefaultThresholds are the days before expiry at which notifications are sent, zero meaning expired
+var DefaultThresholds = []int{30, 7, 1, 0}
+
+// Sender delivers notifications over a single channel
+type Sender interface {
+	// Type is the channel this sender delivers to
+	Type() NotificationType
+	// Send delivers the notification, returning an error if delivery failed
+	Send(ctx context.Context, n Notification) error
+}
+
+// Dispatcher queues notifications when domains cross expiry thresholds and delivers them through senders
+type Dispatcher struct {
+	notificationRepo *Repository
+	senders          map[NotificationType]Sender
+	thresholds       []int
+	reminder         time.Duration
+	quietHours       *QuietHours
+	escalations      []Escalation
+	templates        *Templates
+	retry            RetryPolicy
+	limiter          *RateLimiter
+	now              func() time.Time
+}
+
+func NewDispatcher(notificationRepo *Repository, thresholds []int, senders ...Sender) *Dispatcher {
+	sorted := append([]int(nil), thresholds...)
+	sort.Ints(sorted)
+
+	d := &Dispatcher{
+		notificationRepo: notificationRepo,
+		senders:          make(map[NotificationType]Sender),
+		thresholds:       sorted,
+		retry:            DefaultRetryPolicy,
+		now:              time.Now,
+	}
+	for _, s := range senders {
+		d.senders[s.Type()] = s
+	}
+	return d
+}
+
+// HasSenders reports whether any delivery channel is configured
+func (d *Dispatcher) HasSenders() bool {
+	return len(d.senders) > 0
+}
+
+// Channels lists the configured delivery channels by name
+func (d *Dispatcher) Channels() []NotificationType {
+	channels := make([]NotificationType, 0, len(d.senders))
+	for nType := range d.senders {
+		channels = append(channels, nType)
+	}
+	sort.Slice(channels, func(i, j int) bool { return channels[i] < channels[j] })
+	return channels
+}
+
+// SendTest delivers a sample notification through a channel right away, without storing it.
+//
+// Incidents opened on on-call channels are resolved again straight after
+func (d *Dispatcher) SendTest(ctx context.Context, channel NotificationType) error {
+	sender, ok := d.senders[channel]
+	if !ok {
+		return fmt.Errorf("channel %q is not configured", channel)
+	}
+
+	now := d.now()
+	expiry := now.Add(7 * 24 * time.Hour)
+	n := Notification{
+		DomainName:       "example.com",
+		ExpiryDate:       &expiry,
+		DaysBefore:       7,
+		NotificationType: channel,
+		Issuer:           "Example CA",
+		Tags:             []string{"test"},
+	}
+	if d.templates != nil {
+		if err := d.templates.Render(&n, now); err != nil {
+			return err
+		}
+	}
+	if n.Subject == "" {
+		n.Subject = "[test] " + newMessageData(n, now).Title()
+	}
+
+	if s, ok := sender.(*IncidentSender); ok {
+		return s.sendTest(ctx, n)
+	}
+	return sender.Send(ctx, n)
+}
+
+// SetReminderInterval repeats a notification while its threshold is still the tightest crossed one
+// and the last one was queued at least interval ago, zero never repeats
+func (d *Dispatcher) SetReminderInterval(interval time.Duration) {
+	d.reminder = interval
+}
+
+// SetQuietHours holds back non-critical notifications during the window, nil delivers at any time
+func (d *Dispatcher) SetQuietHours(q *QuietHours) {
+	d.quietHours = q
+}
+
+// SetEscalations sets the escalations Escalate applies
+func (d *Dispatcher) SetEscalations(escalations ...Escalation) {
+	d.escalations = escalations
+}
+
+// SetRetryPolicy sets how transiently failed notifications are retried, DefaultRetryPolicy unless set
+func (d *Dispatcher) SetRetryPolicy(p RetryPolicy) {
+	d.retry = p
+}
+
+// SetRateLimiter spaces out the deliveries of each channel to its rate limit
+func (d *Dispatcher) SetRateLimiter(l *RateLimiter) {
+	d.limiter = l
+}
+
+// RateLimited has a sender that delivers outside the queue, e.g. of registration notifications, count towards
+// and wait for the same rate limits as the queue. Without a rate limiter it is returned as it is
+func (d *Dispatcher) RateLimited(s Sender) Sender {
+	if d.limiter == nil {
+		return s
+	}
+	return d.limiter.Limit(s)
+}
+
+// SetTemplates renders messages from custom templates for the channels that have one
+func (d *Dispatcher) SetTemplates(t *Templates) {
+	d.templates = t
+}
+
+// CrossedThreshold returns the tightest threshold a certificate expiring at expiry has crossed.
+//
+// Returns false when the certificate is not yet within any threshold
+func (d *Dispatcher) CrossedThreshold(expiry time.Time, now time.Time) (int, bool) {
+	return CrossedThreshold(d.thresholds, expiry, now)
+}
+
+// Alert is a notification a domain calls for, queued unless an earlier one covers it
+type Alert struct {
+	DomainID types.DomainID
+	// Threshold is the days before expiry crossed, or ErrorThreshold or RenewalOverdueThreshold
+	Threshold int
+	Channel   NotificationType
+	// Rule names the rule calling for the alert, empty for domains of users without rules
+	Rule string
+	// since is when the alert's cause began, notifications queued from then on cover it. Failing checks are
+	// covered by any notification since the last successful check instead
+	since time.Time
+}
+
+// Evaluate queues a notification on every channel for the threshold the domain has crossed.
+//
+// A threshold is only queued once per certificate and channel, apart from reminders
+func (d *Dispatcher) Evaluate(domainID types.DomainID, expiry *time.Time, now time.Time) error {
+	return d.queueAll(d.thresholdAlerts(domainID, expiry, now, nil), now)
+}
+
+// thresholdAlerts are the alerts on channels, nil meaning every channel, for the threshold a certificate expiring
+// at expiry has crossed
+func (d *Dispatcher) thresholdAlerts(domainID types.DomainID, expiry *time.Time, now time.Time, channels []NotificationType) []Alert {
+	if expiry == nil {
+		return nil
+	}
+	threshold, crossed := d.CrossedThreshold(*expiry, now)
+	if !crossed {
+		return nil
+	}
+
+	var alerts []Alert
+	for nType, sender := range d.senders {
+		if _, ok := sender.(*IncidentSender); ok {
+			continue // Paging is only done by rules or the Alerter
+		}
+		if channels != nil && !slices.Contains(channels, nType) {
+			continue
+		}
+		alerts = append(alerts, thresholdAlert(domainID, threshold, nType, *expiry))
+	}
+	return alerts
+}
+
+// thresholdAlert is the alert for a threshold of a certificate expiring at expiry. Notifications queued before
+// the certificate entered the threshold belong to an earlier certificate, so a renewed certificate notifies again
+func thresholdAlert(domainID types.DomainID, threshold int, nType NotificationType, expiry time.Time) Alert {
+	return Alert{DomainID: domainID, Threshold: threshold, Channel: nType, since: expiry.AddDate(0, 0, -(threshold + 1))}
+}
+
+// EvaluateDomain queues notifications for a freshly checked domain.
+//
+// The enabled rules of the domain's user decide the channels and thresholds, without any rules
+// the user's preferred channels, or every channel, are notified at the dispatcher's thresholds as in Evaluate.
+// Certificates overdue for their automatic renewal notify on the same channels, and failing checks on the rules
+// that ask for it unless a maintenance window is open for the domain.
+// An active acknowledgement of the domain queues nothing
+func (d *Dispatcher) EvaluateDomain(dom domain.Domain, now time.Time) error {
+	alerts, err := d.domainAlerts(dom, now)
+	if err != nil {
+		return err
+	}
+	return d.queueAll(alerts, now)
+}
+
+// domainAlerts are the alerts EvaluateDomain queues for a domain at now, unless earlier ones cover them
+func (d *Dispatcher) domainAlerts(dom domain.Domain, now time.Time) ([]Alert, error) {
+	expiry := dom.ExpiryTime()
+	if acked, err := d.acknowledged(dom.DomainID, expiry, now); err != nil || acked {
+		return nil, err
+	}
+
+	rules, err := d.notificationRepo.GetRulesByUserID(dom.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification rules: %w", err)
+	}
+	// Maintenance windows only hold back notifications about failing checks
+	inMaintenance := false
+	if dom.LastError != nil {
+		if inMaintenance, err = d.notificationRepo.InMaintenance(dom, now); err != nil {
+			return nil, err
+		}
+	}
+	enabled := rules[:0]
+	for _, r := range rules {
+		if r.Enabled {
+			enabled = append(enabled, r)
+		}
+	}
+	if len(enabled) == 0 {
+		channels, err := d.notificationRepo.GetPreferredChannels(dom.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get preferred channels: %w", err)
+		}
+		alerts := d.thresholdAlerts(dom.DomainID, expiry, now, channels)
+		return append(alerts, d.renewalAlerts(dom, now, channels)...), nil
+	}
+
+	var alerts []Alert
+	for _, r := range enabled {
+		if !r.Matches(dom) {
+			continue
+		}
+		if _, ok := d.senders[r.Channel]; !ok {
+			slog.Warn("Notification rule uses a channel that isn't configured", "rule", r.Name, "channel", r.Channel.String())
+			continue
+		}
+
+		var ruleAlerts []Alert
+		if r.OnError && dom.LastError != nil && !inMaintenance {
+			ruleAlerts = append(ruleAlerts, Alert{DomainID: dom.DomainID, Threshold: ErrorThreshold, Channel: r.Channel})
+		}
+		if dom.RenewalOverdue(now) {
+			ruleAlerts = append(ruleAlerts, renewalAlert(dom, r.Channel))
+		}
+		if expiry != nil {
+			if threshold, crossed := r.CrossedThreshold(*expiry, now); crossed {
+				ruleAlerts = append(ruleAlerts, thresholdAlert(dom.DomainID, threshold, r.Channel, *expiry))
+			}
+		}
+		for _, a := range ruleAlerts {
+			a.Rule = r.Name
+			alerts = append(alerts, a)
+		}
+	}
+	return alerts, nil
+}
+
+// renewalAlerts are the alerts on channels, nil meaning every channel, when the domain's certificate is overdue
+// for its automatic renewal
+func (d *Dispatcher) renewalAlerts(dom domain.Domain, now time.Time, channels []NotificationType) []Alert {
+	if !dom.RenewalOverdue(now) {
+		return nil
+	}
+	var alerts []Alert
+	for nType, sender := range d.senders {
+		if _, ok := sender.(*IncidentSender); ok {
+			continue
+		}
+		if channels != nil && !slices.Contains(channels, nType) {
+			continue
+		}
+		alerts = append(alerts, renewalAlert(dom, nType))
+	}
+	return alerts
+}
+
+// renewalAlert is the alert that a certificate is overdue for its automatic renewal. Notifications queued before
+// the certificate was due to renew belong to an earlier certificate
+func renewalAlert(dom domain.Domain, nType NotificationType) Alert {
+	return Alert{DomainID: dom.DomainID, Threshold: RenewalOverdueThreshold, Channel: nType, since: *dom.RenewalDue()}
+}
+
+// queueAll queues the alerts no earlier notification covers
+func (d *Dispatcher) queueAll(alerts []Alert, now time.Time) error {
+	for _, a := range alerts {
+		covered, err := d.covered(a, now)
+		if err != nil {
+			return err
+		}
+		if covered {
+			continue
+		}
+		if err := d.queue(a.DomainID, a.Threshold, a.Channel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// covered reports whether a notification was already queued for an alert's cause, so each failing run of checks
+// notifies once and each threshold once per certificate, unless a reminder is due
+func (d *Dispatcher) covered(a Alert, now time.Time) (bool, error) {
+	if a.Threshold == ErrorThreshold {
+		exists, err := d.notificationRepo.ErrorNotificationExists(a.DomainID, a.Channel)
+		if err != nil {
+			return false, fmt.Errorf("failed to check for existing notification: %w", err)
+		}
+		return exists, nil
+	}
+	last, err := d.notificationRepo.LastQueuedAt(a.DomainID, a.Threshold, a.Channel, a.since)
+	if err != nil {
+		return false, fmt.Errorf("failed to check for existing notification: %w", err)
+	}
+	return last != nil && (d.reminder <= 0 || now.Sub(*last) < d.reminder), nil
+}
+
+// acknowledged reports whether a domain whose certificate expires at expiry has an active acknowledgement
+func (d *Dispatcher) acknowledged(domainID types.DomainID, expiry *time.Time, now time.Time) (bool, error) {
+	a, err := d.notificationRepo.GetAck(domainID)
+	if errors.Is(err, ErrNoAck) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get acknowledgement: %w", err)
+	}
+	return a.Active(expiry, now), nil
+}
+
+// queue stores a pending notification for a domain, threshold and channel
+func (d *Dispatcher) queue(domainID types.DomainID, threshold int, nType NotificationType) error {
+	n := Notification{
+		DomainID:         domainID,
+		DaysBefore:       threshold,
+		NotificationType: nType,
+	}
+	if err := d.notificationRepo.CreateNotification(&n); err != nil {
+		return fmt.Errorf("failed to queue notification: %w", err)
+	}
+	return nil
+}
+
+// Escalate queues a notification over an escalation's channel for every notification at its threshold
+// that is still unacknowledged the escalation's delay after it was sent.
+//
+// Notifications whose certificate has since been renewed past the threshold, or whose domain
+// has been acknowledged, aren't escalated
+func (d *Dispatcher) Escalate(now time.Time) error {
+	for _, e := range d.escalations {
+		if _, ok := d.senders[e.Channel]; !ok {
+			slog.Warn("Escalation uses a channel that isn't configured", "channel", e.Channel.String())
+			continue
+		}
+		unacknowledged, err := d.notificationRepo.GetUnescalated(e.Threshold, now.Add(-e.After))
+		if err != nil {
+			return fmt.Errorf("failed to get unacknowledged notifications: %w", err)
+		}
+		for _, n := range unacknowledged {
+			if n.ExpiryDate == nil {
+				continue
+			}
+			if _, crossed := CrossedThreshold([]int{e.Threshold}, *n.ExpiryDate, now); !crossed {
+				continue
+			}
+			acked, err := d.acknowledged(n.DomainID, n.ExpiryDate, now)
+			if err != nil {
+				return err
+			}
+			if acked {
+				continue
+			}
+			escalated := Notification{
+				DomainID:         n.DomainID,
+				DaysBefore:       n.DaysBefore,
+				NotificationType: e.Channel,
+				EscalatedFrom:    &n.NotificationID,
+			}
+			if err := d.notificationRepo.CreateNotification(&escalated); err != nil {
+				return fmt.Errorf("failed to queue escalation: %w", err)
+			}
+			slog.Info("Notification escalated", "domain", n.DomainName, "from", n.NotificationType.String(), "to", e.Channel.String())
+		}
+	}
+	return nil
+}
+
+// DeliverPending sends every pending notification, and every retry that is due, through its channel and
+// records the attempt. Notifications of domains sharing a certificate go out as one.
+//
+// Transient failures are retried with a growing backoff until the retry policy runs out of attempts,
+// permanent ones fail straight away. Notifications held by quiet hours stay queued until a delivery after the window ends,
+// and those of a channel at its rate limit until a delivery once it has room again
+func (d *Dispatcher) DeliverPending(ctx context.Context) error {
+	now := d.now()
+	pending, err := d.notificationRepo.GetPendingNotifications()
+	if err != nil {
+		return fmt.Errorf("failed to get pending notifications: %w", err)
+	}
+	retries, err := d.notificationRepo.GetRetriesDue(now)
+	if err != nil {
+		return fmt.Errorf("failed to get notifications to retry: %w", err)
+	}
+
+	var errs []error
+	for _, group := range groupByCertificate(append(pending, retries...)) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		n := group[0]
+		sender, ok := d.senders[n.NotificationType]
+		if !ok {
+			continue // Channel no longer configured, leave it queued
+		}
+		if d.quietHours != nil && d.quietHours.Holds(n, now) {
+			continue
+		}
+		if d.limiter != nil {
+			if d.limiter.Delay(n.NotificationType) > maxRateWait {
+				continue // The channel is at its rate limit, it stays queued for a later delivery
+			}
+			if err := d.limiter.Wait(ctx, n.NotificationType); err != nil {
+				return err
+			}
+		}
+		for _, shared := range group[1:] {
+			n.SharedWith = append(n.SharedWith, shared.DomainName)
+		}
+		if d.templates != nil {
+			if err := d.templates.Render(&n, now); err != nil {
+				slog.Warn("Falling back to the default message", "channel", n.NotificationType.String(), "error", err)
+			}
+		}
+
+		sendErr := sender.Send(ctx, n)
+		if sendErr != nil && ctx.Err() != nil {
+			return ctx.Err() // Interrupted by shutdown rather than the channel, don't count it
+		}
+		for _, member := range group {
+			if err := d.recordAttempt(member, sendErr, d.now()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// groupByCertificate collapses the notifications of domains serving the same certificate, over the same channel
+// and at the same threshold, into one group each, so a certificate behind many names alerts once rather than once
+// per name. Groups keep the order of their first notification
+func groupByCertificate(notifications []Notification) [][]Notification {
+	var groups [][]Notification
+	index := make(map[string]int)
+	for _, n := range notifications {
+		if n.CertFingerprint != "" {
+			key := fmt.Sprintf("%s/%d/%s", n.NotificationType, n.DaysBefore, n.CertFingerprint)
+			if i, ok := index[key]; ok {
+				groups[i] = append(groups[i], n)
+				continue
+			}
+			index[key] = len(groups)
+		}
+		groups = append(groups, []Notification{n})
+	}
+	return groups
+}
+
+// recordAttempt stores the outcome of delivering n and decides whether to try again
+func (d *Dispatcher) recordAttempt(n Notification, sendErr error, now time.Time) error {
+	attempt := &DeliveryAttempt{NotificationID: n.NotificationID, AttemptedAt: now}
+	if sendErr == nil {
+		return d.notificationRepo.RecordAttempt(attempt, StatusSent, nil)
+	}
+
+	errStr := sendErr.Error()
+	statusCode, permanent := classifyDeliveryError(sendErr)
+	attempt.StatusCode = statusCode
+	attempt.Error = &errStr
+	attempts := n.Attempts + 1
+
+	if permanent || attempts >= d.retry.MaxAttempts {
+		slog.Error("Notification delivery failed",
+			"domain", n.DomainName,
+			"channel", n.NotificationType.String(),
+			"attempts", attempts,
+			"permanent", permanent,
+			"error", sendErr,
+		)
+		return d.notificationRepo.RecordAttempt(attempt, StatusFailed, nil)
+	}
+
+	next := now.Add(d.retry.Delay(attempts))
+	slog.Warn("Notification delivery failed, retrying",
+		"domain", n.DomainName,
+		"channel", n.NotificationType.String(),
+		"attempts", attempts,
+		"next_attempt_at", next,
+		"error", sendErr,
+	)
+	return d.notificationRepo.RecordAttempt(attempt, StatusRetrying, &next)
+}
@@ -0,0 +1,463 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSender records what it was asked to send.
+type fakeSender struct {
+	nType NotificationType
+	sent  []Notification
+	err   error
+}
+
+func (f *fakeSender) Type() NotificationType { return f.nType }
+
+func (f *fakeSender) Send(ctx context.Context, n Notification) error {
+	f.sent = append(f.sent, n)
+	return f.err
+}
+
+// TestDispatcher_CrossedThreshold - the tightest crossed threshold wins.
+func TestDispatcher_CrossedThreshold(t *testing.T) {
+	d := NewDispatcher(nil, DefaultThresholds)
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		expiry   time.Time
+		want     int
+		wantSent bool
+	}{
+		{"healthy", now.Add(90 * 24 * time.Hour), 0, false},
+		{"within 30 days", now.Add(20 * 24 * time.Hour), 30, true},
+		{"within 7 days", now.Add(5 * 24 * time.Hour), 7, true},
+		{"expired", now.Add(-time.Hour), 0, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := d.CrossedThreshold(tc.expiry, now)
+			assert.Equal(t, tc.wantSent, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+// TestDispatcher_EvaluateOnce - a threshold is only queued once per channel.
+func TestDispatcher_EvaluateOnce(t *testing.T) {
+	repo := NewRepository(newTestDB(t))
+	d := NewDispatcher(repo, DefaultThresholds, &fakeSender{nType: NotificationTypeSlack})
+
+	expiry := time.Now().Add(5 * 24 * time.Hour)
+	require.NoError(t, d.Evaluate(types.DomainID(1), &expiry, time.Now()))
+	require.NoError(t, d.Evaluate(types.DomainID(1), &expiry, time.Now()))
+
+	pending, err := repo.GetPendingNotifications()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, 7, pending[0].DaysBefore)
+}
+
+// TestDispatcher_Reminders - a reminder interval repeats the current threshold once the interval has passed.
+func TestDispatcher_Reminders(t *testing.T) {
+	repo := NewRepository(newTestDB(t))
+	d := NewDispatcher(repo, DefaultThresholds, &fakeSender{nType: NotificationTypeSlack})
+	d.SetReminderInterval(24 * time.Hour)
+
+	now := time.Now()
+	expiry := now.Add(5 * 24 * time.Hour)
+	require.NoError(t, d.Evaluate(types.DomainID(1), &expiry, now))
+	require.NoError(t, d.Evaluate(types.DomainID(1), &expiry, now.Add(12*time.Hour)))
+
+	pending, err := repo.GetPendingNotifications()
+	require.NoError(t, err)
+	require.Len(t, pending, 1, "Repeats within the interval are suppressed")
+
+	require.NoError(t, d.Evaluate(types.DomainID(1), &expiry, now.Add(25*time.Hour)))
+	pending, err = repo.GetPendingNotifications()
+	require.NoError(t, err)
+	assert.Len(t, pending, 2)
+}
+
+// TestDispatcher_EvaluateRenewed - a renewed certificate notifies again at thresholds the old one already hit.
+func TestDispatcher_EvaluateRenewed(t *testing.T) {
+	repo := NewRepository(newTestDB(t))
+	d := NewDispatcher(repo, DefaultThresholds, &fakeSender{nType: NotificationTypeSlack})
+
+	// The previous certificate hit its 30 day threshold three months ago
+	old := Notification{DomainID: types.DomainID(1), DaysBefore: 30, NotificationType: NotificationTypeSlack, CreatedAt: time.Now().Add(-90 * 24 * time.Hour)}
+	require.NoError(t, repo.CreateNotification(&old))
+
+	expiry := time.Now().Add(20 * 24 * time.Hour)
+	require.NoError(t, d.Evaluate(types.DomainID(1), &expiry, time.Now()))
+	require.NoError(t, d.Evaluate(types.DomainID(1), &expiry, time.Now()))
+
+	pending, err := repo.GetPendingNotifications()
+	require.NoError(t, err)
+	assert.Len(t, pending, 2)
+}
+
+// TestDispatcher_DeliverPending - outcomes are recorded per notification, failures are retried.
+func TestDispatcher_DeliverPending(t *testing.T) {
+	repo := NewRepository(newTestDB(t))
+	slack := &fakeSender{nType: NotificationTypeSlack}
+	email := &fakeSender{nType: NotificationTypeEmail, err: errors.New("smtp down")}
+	d := NewDispatcher(repo, DefaultThresholds, slack, email)
+
+	expiry := time.Now().Add(20 * 24 * time.Hour)
+	require.NoError(t, d.Evaluate(types.DomainID(1), &expiry, time.Now()))
+	require.NoError(t, d.DeliverPending(context.Background()))
+
+	assert.Len(t, slack.sent, 1)
+	assert.Len(t, email.sent, 1)
+
+	notifications, err := repo.GetNotificationsByUserID(types.UserID(1))
+	require.NoError(t, err)
+	statuses := map[NotificationType]NotificationStatus{}
+	for _, n := range notifications {
+		statuses[n.NotificationType] = n.Status
+	}
+	assert.Equal(t, StatusSent, statuses[NotificationTypeSlack])
+	assert.Equal(t, StatusRetrying, statuses[NotificationTypeEmail])
+}
+
+// TestDispatcher_DeliverPendingSharedCertificate - domains serving the same certificate are notified once.
+func TestDispatcher_DeliverPendingSharedCertificate(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewRepository(db)
+	slack := &fakeSender{nType: NotificationTypeSlack}
+	d := NewDispatcher(repo, []int{7}, slack)
+
+	_, err := db.Exec(`INSERT INTO domains (id, user_id, domain_name, created_at) VALUES (2, 1, 'www.example.com', ?), (3, 1, 'example.org', ?)`, time.Now(), time.Now())
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE domains SET cert_fingerprint = 'aa' WHERE id IN (1, 2)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE domains SET cert_fingerprint = 'bb' WHERE id = 3`)
+	require.NoError(t, err)
+
+	expiry := time.Now().Add(5 * 24 * time.Hour)
+	for id := 1; id <= 3; id++ {
+		require.NoError(t, d.Evaluate(types.DomainID(id), &expiry, time.Now()))
+	}
+	require.NoError(t, d.DeliverPending(context.Background()))
+
+	require.Len(t, slack.sent, 2)
+	assert.Equal(t, "example.com", slack.sent[0].DomainName)
+	assert.Equal(t, []string{"www.example.com"}, slack.sent[0].SharedWith)
+	assert.Empty(t, slack.sent[1].SharedWith)
+
+	notifications, err := repo.GetNotificationsByUserID(types.UserID(1))
+	require.NoError(t, err)
+	require.Len(t, notifications, 3)
+	for _, n := range notifications {
+		assert.Equal(t, StatusSent, n.Status, n.DomainName)
+	}
+}
+
+// TestDispatcher_EvaluateDomainRules - rules pick channels and thresholds per domain tag.
+func TestDispatcher_EvaluateDomainRules(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewRepository(db)
+	slack := &fakeSender{nType: NotificationTypeSlack}
+	email := &fakeSender{nType: NotificationTypeEmail}
+	d := NewDispatcher(repo, DefaultThresholds, slack, email)
+
+	require.NoError(t, repo.CreateRule(&Rule{UserID: types.UserID(1), Name: "slack", Channel: NotificationTypeSlack, Thresholds: []int{30, 7}, Enabled: true}))
+	require.NoError(t, repo.CreateRule(&Rule{UserID: types.UserID(1), Name: "prod email", Channel: NotificationTypeEmail, Thresholds: []int{2}, Tags: []string{"prod"}, Enabled: true}))
+	require.NoError(t, repo.CreateRule(&Rule{UserID: types.UserID(1), Name: "off", Channel: NotificationTypeEmail, Thresholds: []int{30}, Enabled: false}))
+
+	dom := testDomain(time.Now().Add(20*24*time.Hour), "web")
+	dom.UserID = types.UserID(1)
+	require.NoError(t, d.EvaluateDomain(dom, time.Now()))
+
+	pending, err := repo.GetPendingNotifications()
+	require.NoError(t, err)
+	require.Len(t, pending, 1, "Only the slack rule applies and the disabled rule is ignored")
+	assert.Equal(t, NotificationTypeSlack, pending[0].NotificationType)
+	assert.Equal(t, 30, pending[0].DaysBefore)
+
+	dom = testDomain(time.Now().Add(36*time.Hour), "prod")
+	dom.UserID = types.UserID(1)
+	require.NoError(t, d.EvaluateDomain(dom, time.Now()))
+
+	pending, err = repo.GetPendingNotifications()
+	require.NoError(t, err)
+	got := map[NotificationType][]int{}
+	for _, n := range pending {
+		got[n.NotificationType] = append(got[n.NotificationType], n.DaysBefore)
+	}
+	assert.Equal(t, []int{30, 7}, got[NotificationTypeSlack])
+	assert.Equal(t, []int{2}, got[NotificationTypeEmail])
+}
+
+// TestDispatcher_EvaluateDomainPreferredChannels - without rules only the user's preferred channels are notified.
+func TestDispatcher_EvaluateDomainPreferredChannels(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewRepository(db)
+	slack := &fakeSender{nType: NotificationTypeSlack}
+	email := &fakeSender{nType: NotificationTypeEmail}
+	d := NewDispatcher(repo, DefaultThresholds, slack, email)
+
+	_, err := db.Exec(`INSERT INTO user_settings (user_id, warning_days, critical_days, notification_channels, updated_at) VALUES (1, 30, 7, 'email', ?)`, time.Now())
+	require.NoError(t, err)
+
+	dom := testDomain(time.Now().Add(20*24*time.Hour), "web")
+	dom.UserID = types.UserID(1)
+	require.NoError(t, d.EvaluateDomain(dom, time.Now()))
+
+	pending, err := repo.GetPendingNotifications()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, NotificationTypeEmail, pending[0].NotificationType)
+}
+
+// TestDispatcher_EvaluateDomainOnError - failing checks notify once until a check succeeds again.
+func TestDispatcher_EvaluateDomainOnError(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewRepository(db)
+	d := NewDispatcher(repo, DefaultThresholds, &fakeSender{nType: NotificationTypeSlack})
+	require.NoError(t, repo.CreateRule(&Rule{UserID: types.UserID(1), Name: "errors", Channel: NotificationTypeSlack, OnError: true, Enabled: true}))
+
+	checkErr := domain.NewLastError("connection refused")
+	failing := domain.Domain{DomainID: types.DomainID(1), UserID: types.UserID(1), LastError: &checkErr}
+	countErrors := func() int {
+		pending, err := repo.GetNotificationsByUserID(types.UserID(1))
+		require.NoError(t, err)
+		count := 0
+		for _, n := range pending {
+			if n.DaysBefore == ErrorThreshold {
+				count++
+			}
+		}
+		return count
+	}
+
+	require.NoError(t, d.EvaluateDomain(failing, time.Now()))
+	require.NoError(t, d.EvaluateDomain(failing, time.Now()))
+	assert.Equal(t, 1, countErrors())
+
+	// A successful check ends the run of failures
+	_, err := db.Exec(`INSERT INTO check_history (domain_id, checked_at, expiry_date) VALUES (1, ?, ?)`,
+		time.Now().Add(time.Second), time.Now().Add(90*24*time.Hour))
+	require.NoError(t, err)
+
+	require.NoError(t, d.EvaluateDomain(failing, time.Now()))
+	assert.Equal(t, 2, countErrors())
+}
+
+// TestDispatcher_EvaluateDomainRenewalOverdue - a certificate still served past its auto-renewal date notifies
+// once, and again only once the renewed certificate is overdue too.
+func TestDispatcher_EvaluateDomainRenewalOverdue(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewRepository(db)
+	d := NewDispatcher(repo, []int{7}, &fakeSender{nType: NotificationTypeSlack})
+	_, err := db.Exec(`UPDATE domains SET auto_renew_days = 30, auto_renew_via = 'cert-manager' WHERE id = 1`)
+	require.NoError(t, err)
+	now := time.Now()
+	countOverdue := func() int {
+		notifications, err := repo.GetNotificationsByUserID(types.UserID(1))
+		require.NoError(t, err)
+		count := 0
+		for _, n := range notifications {
+			if n.DaysBefore == RenewalOverdueThreshold {
+				assert.Equal(t, "cert-manager", n.AutoRenewVia)
+				count++
+			}
+		}
+		return count
+	}
+
+	dom := testDomain(now.Add(29 * 24 * time.Hour))
+	dom.UserID, dom.AutoRenewDays = types.UserID(1), 30
+	require.NoError(t, d.EvaluateDomain(dom, now))
+	assert.Equal(t, 0, countOverdue(), "Still within the grace period")
+
+	dom = testDomain(now.Add(20 * 24 * time.Hour))
+	dom.UserID, dom.AutoRenewDays = types.UserID(1), 30
+	require.NoError(t, d.EvaluateDomain(dom, now))
+	require.NoError(t, d.EvaluateDomain(dom, now))
+	assert.Equal(t, 1, countOverdue())
+
+	// The renewed certificate is only overdue once its own renewal date has passed
+	renewed := testDomain(now.Add(80 * 24 * time.Hour))
+	renewed.UserID, renewed.AutoRenewDays = types.UserID(1), 30
+	require.NoError(t, d.EvaluateDomain(renewed, now))
+	assert.Equal(t, 1, countOverdue())
+	later := now.Add(60 * 24 * time.Hour)
+	require.NoError(t, d.EvaluateDomain(renewed, later))
+	assert.Equal(t, 2, countOverdue())
+}
+
+// TestDispatcher_QuietHours - non-critical notifications wait for the end of quiet hours.
+func TestDispatcher_QuietHours(t *testing.T) {
+	repo := NewRepository(newTestDB(t))
+	slack := &fakeSender{nType: NotificationTypeSlack}
+	d := NewDispatcher(repo, DefaultThresholds, slack)
+	q, err := NewQuietHours("22:00", "07:00", 1)
+	require.NoError(t, err)
+	d.SetQuietHours(q)
+
+	weekAway := time.Now().Add(5 * 24 * time.Hour)
+	require.NoError(t, d.Evaluate(types.DomainID(1), &weekAway, time.Now()))
+	expired := time.Now().Add(-time.Hour)
+	require.NoError(t, d.Evaluate(types.DomainID(1), &expired, time.Now()))
+
+	d.now = func() time.Time { return time.Date(2025, time.March, 14, 23, 0, 0, 0, time.Local) }
+	require.NoError(t, d.DeliverPending(context.Background()))
+	require.Len(t, slack.sent, 1)
+	assert.Equal(t, 0, slack.sent[0].DaysBefore, "Expiry is critical")
+
+	d.now = func() time.Time { return time.Date(2025, time.March, 15, 7, 0, 0, 0, time.Local) }
+	require.NoError(t, d.DeliverPending(context.Background()))
+	require.Len(t, slack.sent, 2)
+	assert.Equal(t, 7, slack.sent[1].DaysBefore)
+}
+
+// TestDispatcher_Escalate - unacknowledged notifications escalate once after the delay.
+func TestDispatcher_Escalate(t *testing.T) {
+	repo := NewRepository(newTestDB(t))
+	slack := &fakeSender{nType: NotificationTypeSlack}
+	email := &fakeSender{nType: NotificationTypeEmail}
+	d := NewDispatcher(repo, []int{7}, slack, email)
+	d.SetEscalations(Escalation{Threshold: 7, After: 24 * time.Hour, Channel: NotificationTypeEmail})
+
+	expiry := time.Now().Add(5 * 24 * time.Hour)
+	_, err := repo.db.Exec(`UPDATE domains SET expiry_date = ? WHERE id = 1`, expiry)
+	require.NoError(t, err)
+	require.NoError(t, d.Evaluate(types.DomainID(1), &expiry, time.Now()))
+	require.NoError(t, d.DeliverPending(context.Background()))
+	require.Len(t, slack.sent, 1)
+	require.Len(t, email.sent, 1, "Without rules every channel gets the threshold")
+
+	require.NoError(t, d.Escalate(time.Now().Add(time.Hour)))
+	pending, err := repo.GetPendingNotifications()
+	require.NoError(t, err)
+	assert.Empty(t, pending, "Nothing escalates before the delay")
+
+	require.NoError(t, repo.UpdateStatus(slack.sent[0].NotificationID, StatusAcknowledged, nil))
+	require.NoError(t, d.Escalate(time.Now().Add(25*time.Hour)))
+	require.NoError(t, d.Escalate(time.Now().Add(26*time.Hour)))
+
+	pending, err = repo.GetPendingNotifications()
+	require.NoError(t, err)
+	require.Len(t, pending, 1, "Only the unacknowledged email escalates, and only once")
+	assert.Equal(t, NotificationTypeEmail, pending[0].NotificationType)
+	require.NotNil(t, pending[0].EscalatedFrom)
+	assert.Equal(t, email.sent[0].NotificationID, *pending[0].EscalatedFrom)
+}
+
+// TestDispatcher_EscalateRenewed - a certificate renewed in the meantime doesn't escalate.
+func TestDispatcher_EscalateRenewed(t *testing.T) {
+	repo := NewRepository(newTestDB(t))
+	slack := &fakeSender{nType: NotificationTypeSlack}
+	d := NewDispatcher(repo, []int{7}, slack, &fakeSender{nType: NotificationTypeEmail})
+	d.SetEscalations(Escalation{Threshold: 7, After: 24 * time.Hour, Channel: NotificationTypeEmail})
+
+	expiry := time.Now().Add(5 * 24 * time.Hour)
+	require.NoError(t, d.Evaluate(types.DomainID(1), &expiry, time.Now()))
+	require.NoError(t, d.DeliverPending(context.Background()))
+
+	_, err := repo.db.Exec(`UPDATE domains SET expiry_date = ? WHERE id = 1`, time.Now().Add(90*24*time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, d.Escalate(time.Now().Add(25*time.Hour)))
+
+	pending, err := repo.GetPendingNotifications()
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+// TestDispatcher_Acknowledged - an acknowledged domain neither queues nor escalates until its certificate changes.
+func TestDispatcher_Acknowledged(t *testing.T) {
+	repo := NewRepository(newTestDB(t))
+	slack := &fakeSender{nType: NotificationTypeSlack}
+	d := NewDispatcher(repo, []int{30, 7}, slack, &fakeSender{nType: NotificationTypeEmail})
+	d.SetEscalations(Escalation{Threshold: 30, After: time.Hour, Channel: NotificationTypeEmail})
+
+	expiry := time.Now().Add(20 * 24 * time.Hour)
+	_, err := repo.db.Exec(`UPDATE domains SET expiry_date = ? WHERE id = 1`, expiry)
+	require.NoError(t, err)
+	dom := testDomain(expiry)
+	require.NoError(t, repo.CreateNotification(&Notification{DomainID: dom.DomainID, DaysBefore: 30, NotificationType: NotificationTypeSlack}))
+	require.NoError(t, d.DeliverPending(context.Background()))
+	require.Len(t, slack.sent, 1)
+
+	_, err = NewService(repo).AcknowledgeDomain(dom, "renewal scheduled for Friday", nil)
+	require.NoError(t, err)
+	require.NoError(t, d.Escalate(time.Now().Add(2*time.Hour)))
+	require.NoError(t, d.EvaluateDomain(dom, time.Now().Add(15*24*time.Hour)))
+
+	pending, err := repo.GetPendingNotifications()
+	require.NoError(t, err)
+	assert.Empty(t, pending, "Nothing is queued while the ack is active")
+
+	require.NoError(t, d.EvaluateDomain(testDomain(time.Now().Add(5*24*time.Hour)), time.Now()))
+	pending, err = repo.GetPendingNotifications()
+	require.NoError(t, err)
+	assert.Len(t, pending, 2, "A different certificate ends the ack")
+}
+
+// TestDispatcher_SendTest - a sample goes out right away, on-call channels resolve it again and nothing is stored.
+func TestDispatcher_SendTest(t *testing.T) {
+	repo := NewRepository(newTestDB(t))
+	slack := &fakeSender{nType: NotificationTypeSlack}
+	provider := &fakeProvider{}
+	d := NewDispatcher(repo, DefaultThresholds, slack, NewIncidentSender(provider, repo))
+	assert.Equal(t, []NotificationType{"fake", NotificationTypeSlack}, d.Channels())
+
+	ctx := context.Background()
+	require.NoError(t, d.SendTest(ctx, NotificationTypeSlack))
+	require.Len(t, slack.sent, 1)
+	assert.Equal(t, "[test] SSL certificate for example.com expires in 7 days", slack.sent[0].Subject)
+
+	require.NoError(t, d.SendTest(ctx, "fake"))
+	require.Len(t, provider.triggered, 1)
+	assert.Equal(t, []string{testDedupKey}, provider.resolved)
+
+	assert.Error(t, d.SendTest(ctx, NotificationTypeEmail))
+
+	notifications, err := repo.GetNotificationsByUserID(types.UserID(1))
+	require.NoError(t, err)
+	assert.Empty(t, notifications)
+}
+
+// TestDispatcher_Simulate - a simulation finds the alerts the rules call for at a date without queueing them.
+func TestDispatcher_Simulate(t *testing.T) {
+	repo := NewRepository(newTestDB(t))
+	d := NewDispatcher(repo, DefaultThresholds, &fakeSender{nType: NotificationTypeSlack})
+	require.NoError(t, repo.CreateRule(&Rule{UserID: types.UserID(1), Name: "slack", Channel: NotificationTypeSlack, Thresholds: []int{30, 7}, Enabled: true}))
+
+	now := time.Now()
+	dom := testDomain(now.Add(20*24*time.Hour), "web")
+	dom.UserID = types.UserID(1)
+
+	alerts, err := d.Simulate([]domain.Domain{dom}, now.Add(15*24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, 7, alerts[0].Threshold)
+	assert.Equal(t, "slack", alerts[0].Rule)
+	assert.Equal(t, "7 days before expiry", alerts[0].Describe())
+	assert.False(t, alerts[0].Covered)
+
+	alerts, err = d.Simulate([]domain.Domain{dom}, now.Add(-30*24*time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, alerts, "Nothing fires before the first threshold")
+
+	pending, err := repo.GetPendingNotifications()
+	require.NoError(t, err)
+	assert.Empty(t, pending, "Simulations queue nothing")
+
+	require.NoError(t, d.EvaluateDomain(dom, now))
+	alerts, err = d.Simulate([]domain.Domain{dom}, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.True(t, alerts[0].Covered, "The 30 day notification was queued already")
+}
@@ -0,0 +1,299 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// EmailSecurity is how the connection to the SMTP server is protected
+type EmailSecurity string
+
+const (
+	// EmailSecurityStartTLS upgrades a plain connection, failing if the server can't
+	EmailSecurityStartTLS EmailSecurity = "starttls"
+	// EmailSecurityTLS connects over TLS from the start
+	EmailSecurityTLS EmailSecurity = "tls"
+	// EmailSecurityNone sends in the clear, only suitable for a local relay
+	EmailSecurityNone EmailSecurity = "none"
+)
+
+// emailTimeout bounds a delivery when the context has no deadline
+const emailTimeout = 30 * time.Second
+
+var emailBody = template.Must(template.New("body").Parse(`{{if .Failing -}}
+Checking the SSL certificate for {{.Domain}} failed.
+{{- else if .RenewalOverdue -}}
+The SSL certificate for {{.Domain}} should have been renewed {{.AutoRenewDays}} days before it expires{{with .AutoRenewVia}} by {{.}}{{end}}, but it still expires in {{.DaysLeft}} days.
+{{- else if and .Registration .Expired -}}
+The registration of {{.Domain}} has expired.
+{{- else if .Registration -}}
+The registration of {{.Domain}} expires in {{.DaysLeft}} days.
+{{- else if .Expired -}}
+The SSL certificate for {{.Domain}} has expired.
+{{- else -}}
+The SSL certificate for {{.Domain}} expires in {{.DaysLeft}} days.
+{{- end}}
+
+Domain:     {{.Domain}}
+{{- range .SharedWith}}
+            {{.}}
+{{- end}}
+Expires:    {{.Expires}}
+{{if .Failing -}}
+Error:      {{.Error}}
+{{- else if .RenewalOverdue -}}
+Renews:     {{.AutoRenewDays}} days before expiry{{with .AutoRenewVia}} via {{.}}{{end}}
+{{- else -}}
+Threshold:  {{.Threshold}} days
+{{- end}}
+
+{{if .RenewalOverdue -}}
+Find out why the automatic renewal didn't happen before the certificate expires.
+{{- else if .Registration -}}
+Renew the domain with its registrar to stop further alerts for it.
+{{- else -}}
+Renew the certificate to stop further alerts for this domain.
+{{- end}}
+
+--
+sslcerttop
+`))
+
+// EmailConfig is the SMTP server and addresses an EmailSender uses
+type EmailConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Security EmailSecurity
+	From     string
+	To       []string
+}
+
+// EmailSender mails notifications through an SMTP server
+type EmailSender struct {
+	config EmailConfig
+	now    func() time.Time
+}
+
+// NewEmailSender creates a sender for the given server.
+//
+// Returns an error if the settings are incomplete
+func NewEmailSender(config EmailConfig) (*EmailSender, error) {
+	if config.Host == "" {
+		return nil, errors.New("email host is required")
+	}
+	if config.From == "" {
+		return nil, errors.New("email sender address is required")
+	}
+	if len(config.To) == 0 {
+		return nil, errors.New("at least one email recipient is required")
+	}
+	switch config.Security {
+	case EmailSecurityStartTLS, EmailSecurityTLS, EmailSecurityNone:
+	case "":
+		config.Security = EmailSecurityStartTLS
+	default:
+		return nil, fmt.Errorf("unknown email security %q", config.Security)
+	}
+	if config.Port == 0 {
+		config.Port = 587
+	}
+	return &EmailSender{
+		config: config,
+		now:    time.Now,
+	}, nil
+}
+
+func (s *EmailSender) Type() NotificationType {
+	return NotificationTypeEmail
+}
+
+// Send mails the notification to every recipient
+func (s *EmailSender) Send(ctx context.Context, n Notification) error {
+	msg, err := s.buildMessage(n)
+	if err != nil {
+		return err
+	}
+	return s.deliver(ctx, msg)
+}
+
+// SendDigest mails the digest to every recipient as plain text with an HTML alternative
+func (s *EmailSender) SendDigest(ctx context.Context, d Digest) error {
+	msg, err := s.buildDigestMessage(d)
+	if err != nil {
+		return err
+	}
+	return s.deliver(ctx, msg)
+}
+
+// deliver hands a complete message to the SMTP server
+func (s *EmailSender) deliver(ctx context.Context, msg []byte) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, emailTimeout)
+		defer cancel()
+	}
+
+	c, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if s.config.Username != "" {
+		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+		if err := c.Auth(auth); err != nil {
+			return fmt.Errorf("smtp authentication failed: %w", err)
+		}
+	}
+	if err := c.Mail(s.config.From); err != nil {
+		return fmt.Errorf("smtp server rejected sender: %w", err)
+	}
+	for _, to := range s.config.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("smtp server rejected recipient %s: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp server rejected message: %w", err)
+	}
+	return c.Quit()
+}
+
+// dial connects to the server and secures the connection as configured
+func (s *EmailSender) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	tlsConfig := &tls.Config{ServerName: s.config.Host}
+
+	var conn net.Conn
+	var err error
+	if s.config.Security == EmailSecurityTLS {
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to smtp server %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to greet smtp server %s: %w", addr, err)
+	}
+	if s.config.Security == EmailSecurityStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			c.Close()
+			return nil, fmt.Errorf("smtp server %s does not support STARTTLS", addr)
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to start TLS with smtp server %s: %w", addr, err)
+		}
+	}
+	return c, nil
+}
+
+// buildMessage renders the notification as a plain text email with headers
+func (s *EmailSender) buildMessage(n Notification) ([]byte, error) {
+	now := s.now()
+	data := newMessageData(n, now)
+
+	var body bytes.Buffer
+	if data.body != "" {
+		body.WriteString(data.body + "\n")
+	} else if err := emailBody.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("failed to render email body: %w", err)
+	}
+
+	var msg bytes.Buffer
+	s.writeHeaders(&msg, data.Title(), now,
+		[2]string{"Content-Type", "text/plain; charset=utf-8"},
+		[2]string{"Content-Transfer-Encoding", "8bit"},
+	)
+	msg.WriteString(crlf(body.String()))
+	return msg.Bytes(), nil
+}
+
+// buildDigestMessage renders the digest as a multipart/alternative email with headers
+func (s *EmailSender) buildDigestMessage(d Digest) ([]byte, error) {
+	text, err := d.Text()
+	if err != nil {
+		return nil, err
+	}
+	html, err := d.HTML()
+	if err != nil {
+		return nil, err
+	}
+
+	var parts bytes.Buffer
+	mw := multipart.NewWriter(&parts)
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(crlf(part.body))); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	s.writeHeaders(&msg, d.Subject(), s.now(),
+		[2]string{"Content-Type", "multipart/alternative; boundary=" + mw.Boundary()},
+	)
+	msg.Write(parts.Bytes())
+	return msg.Bytes(), nil
+}
+
+// writeHeaders writes the addressing headers followed by the content headers and the blank line ending them
+func (s *EmailSender) writeHeaders(msg *bytes.Buffer, subject string, now time.Time, content ...[2]string) {
+	headers := [][2]string{
+		{"From", s.config.From},
+		{"To", strings.Join(s.config.To, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", subject)},
+		{"Date", now.Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+	}
+	for _, h := range append(headers, content...) {
+		fmt.Fprintf(msg, "%s: %s\r\n", h[0], h[1])
+	}
+	msg.WriteString("\r\n")
+}
+
+// crlf converts line endings to the CRLF SMTP expects
+func crlf(s string) string {
+	return strings.ReplaceAll(s, "\n", "\r\n")
+}
@@ -0,0 +1,167 @@
+package notification
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTPServer accepts one plain SMTP session and returns the commands and message it received.
+func fakeSMTPServer(t *testing.T) (int, <-chan []string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var lines []string
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ESMTP")
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				break
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+			switch {
+			case inData && line == ".":
+				inData = false
+				reply("250 queued")
+			case inData:
+			case strings.HasPrefix(line, "EHLO"):
+				reply("250 localhost")
+			case line == "DATA":
+				inData = true
+				reply("354 go ahead")
+			case line == "QUIT":
+				reply("221 bye")
+				received <- lines
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+		received <- lines
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, received
+}
+
+// TestNewEmailSender - incomplete settings are rejected and defaults filled in.
+func TestNewEmailSender(t *testing.T) {
+	_, err := NewEmailSender(EmailConfig{From: "certs@example.com", To: []string{"ops@example.com"}})
+	assert.ErrorContains(t, err, "host")
+
+	_, err = NewEmailSender(EmailConfig{Host: "smtp.example.com", To: []string{"ops@example.com"}})
+	assert.ErrorContains(t, err, "sender")
+
+	_, err = NewEmailSender(EmailConfig{Host: "smtp.example.com", From: "certs@example.com"})
+	assert.ErrorContains(t, err, "recipient")
+
+	_, err = NewEmailSender(EmailConfig{Host: "smtp.example.com", From: "certs@example.com", To: []string{"ops@example.com"}, Security: "ssl"})
+	assert.ErrorContains(t, err, "ssl")
+
+	s, err := NewEmailSender(EmailConfig{Host: "smtp.example.com", From: "certs@example.com", To: []string{"ops@example.com"}})
+	require.NoError(t, err)
+	assert.Equal(t, EmailSecurityStartTLS, s.config.Security)
+	assert.Equal(t, 587, s.config.Port)
+	assert.Equal(t, NotificationTypeEmail, s.Type())
+}
+
+// TestEmailSender_BuildMessage - the message carries the domain, days left and expiry.
+func TestEmailSender_BuildMessage(t *testing.T) {
+	s, err := NewEmailSender(EmailConfig{Host: "smtp.example.com", From: "certs@example.com", To: []string{"a@example.com", "b@example.com"}})
+	require.NoError(t, err)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	expiry := now.Add(5*24*time.Hour + time.Hour)
+	msg, err := s.buildMessage(Notification{DomainName: "example.com", ExpiryDate: &expiry, DaysBefore: 7})
+	require.NoError(t, err)
+	text := string(msg)
+	assert.Contains(t, text, "To: a@example.com, b@example.com\r\n")
+	assert.Contains(t, text, "Subject: SSL certificate for example.com expires in 5 days\r\n")
+	assert.Contains(t, text, "Expires:    2026-03-06 13:00 UTC\r\n")
+	assert.Contains(t, text, "Threshold:  7 days\r\n")
+
+	msg, err = s.buildMessage(Notification{DomainName: "example.com", ExpiryDate: &expiry, DaysBefore: 7, Timezone: "Asia/Tokyo"})
+	require.NoError(t, err)
+	assert.Contains(t, string(msg), "Expires:    2026-03-06 22:00 JST\r\n", "Shown in the owner's timezone")
+	msg, err = s.buildMessage(Notification{DomainName: "example.com", ExpiryDate: &expiry, DaysBefore: 7, TimeDisplay: user.TimeRelative})
+	require.NoError(t, err)
+	assert.Contains(t, string(msg), "Expires:    in 5 days\r\n")
+
+	expired := now.Add(-time.Hour)
+	msg, err = s.buildMessage(Notification{DomainName: "example.com", ExpiryDate: &expired})
+	require.NoError(t, err)
+	assert.Contains(t, string(msg), "Subject: SSL certificate for example.com has expired\r\n")
+
+	msg, err = s.buildMessage(Notification{DomainName: "example.com", ExpiryDate: &expiry, DaysBefore: 7, Registration: true})
+	require.NoError(t, err)
+	assert.Contains(t, string(msg), "Subject: Domain registration for example.com expires in 5 days\r\n")
+	assert.Contains(t, string(msg), "Renew the domain with its registrar")
+
+	msg, err = s.buildMessage(Notification{DomainName: "example.com", ExpiryDate: &expiry, DaysBefore: RenewalOverdueThreshold,
+		AutoRenewDays: 30, AutoRenewVia: "cert-manager"})
+	require.NoError(t, err)
+	assert.Contains(t, string(msg), "Subject: Auto-renewal of the SSL certificate for example.com is likely broken\r\n")
+	assert.Contains(t, string(msg), "Renews:     30 days before expiry via cert-manager\r\n")
+}
+
+// TestEmailSender_Send - the message is delivered to every recipient.
+func TestEmailSender_Send(t *testing.T) {
+	port, received := fakeSMTPServer(t)
+	s, err := NewEmailSender(EmailConfig{
+		Host:     "127.0.0.1",
+		Port:     port,
+		Security: EmailSecurityNone,
+		From:     "certs@example.com",
+		To:       []string{"a@example.com", "b@example.com"},
+	})
+	require.NoError(t, err)
+
+	expiry := time.Now().Add(20 * 24 * time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, s.Send(ctx, Notification{DomainName: "example.com", ExpiryDate: &expiry, DaysBefore: 30}))
+
+	lines := strings.Join(<-received, "\n")
+	assert.Contains(t, lines, "MAIL FROM:<certs@example.com>")
+	assert.Contains(t, lines, "RCPT TO:<a@example.com>")
+	assert.Contains(t, lines, "RCPT TO:<b@example.com>")
+	assert.Contains(t, lines, "The SSL certificate for example.com expires in")
+}
+
+// TestEmailSender_RequiresStartTLS - servers without STARTTLS are refused rather than used in the clear.
+func TestEmailSender_RequiresStartTLS(t *testing.T) {
+	port, _ := fakeSMTPServer(t)
+	s, err := NewEmailSender(EmailConfig{
+		Host: "127.0.0.1",
+		Port: port,
+		From: "certs@example.com",
+		To:   []string{"ops@example.com"},
+	})
+	require.NoError(t, err)
+
+	err = s.Send(context.Background(), Notification{DomainName: "example.com"})
+	assert.ErrorContains(t, err, "STARTTLS")
+	assert.ErrorContains(t, err, strconv.Itoa(port))
+}
@@ -0,0 +1,334 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// Incident is a certificate problem paged out to an on-call provider
+type Incident struct {
+	// DedupKey identifies the incident across triggers and its resolve
+	DedupKey   string
+	Domain     string
+	Status     string
+	Summary    string
+	ExpiryDate *time.Time
+	Error      *string
+	Tags       []string
+}
+
+// IncidentProvider opens and resolves incidents with an on-call service
+type IncidentProvider interface {
+	// Name identifies the provider in the open alerts it records
+	Name() string
+	// Trigger opens the incident, or updates it if one with the same key is open
+	Trigger(ctx context.Context, incident Incident) error
+	// Resolve closes the incident with the key
+	Resolve(ctx context.Context, dedupKey string) error
+}
+
+// Alerter pages on expired or failing certificates of matching domains and resolves every open incident,
+// including those notification rules opened, once the certificate is healthy.
+//
+// Users with enabled notification rules are only paged by their rules, acknowledged domains aren't paged, and
+// neither are failing checks during a maintenance window
+type Alerter struct {
+	notificationRepo *Repository
+	providers        []IncidentProvider
+	tags             []string
+}
+
+// NewAlerter creates an alerter for domains carrying any of tags, every domain when tags is empty
+func NewAlerter(notificationRepo *Repository, tags []string, providers ...IncidentProvider) *Alerter {
+	normalized := make([]string, 0, len(tags))
+	for _, t := range tags {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			normalized = append(normalized, t)
+		}
+	}
+	return &Alerter{
+		notificationRepo: notificationRepo,
+		providers:        providers,
+		tags:             normalized,
+	}
+}
+
+// HasProviders reports whether any on-call provider is configured
+func (a *Alerter) HasProviders() bool {
+	return len(a.providers) > 0
+}
+
+// DedupKey is the incident key of a domain, stable across restarts
+func DedupKey(domainID uint) string {
+	return fmt.Sprintf("sslcerttop-domain-%d", domainID)
+}
+
+// incidentKey is the incident key the Alerter pages a domain with. Domains serving the same certificate share
+// the key of the certificate, so they are paged as one incident
+func incidentKey(domainID types.DomainID, fingerprint string) string {
+	if len(fingerprint) < certificateKeyLength {
+		return DedupKey(domainID.Uint())
+	}
+	return "sslcerttop-cert-" + fingerprint[:certificateKeyLength]
+}
+
+// certificateKeyLength is how much of a certificate's fingerprint its incident key uses
+const certificateKeyLength = 16
+
+// Evaluate triggers an incident for a matching domain whose certificate is expired or failing,
+// and resolves an open one when the certificate is valid again
+func (a *Alerter) Evaluate(ctx context.Context, d domain.Domain) error {
+	status := d.Status()
+	healthy := status == "valid" || status == "soon"
+	failing := (status == "expired" || status == "error") && a.matches(d)
+	if failing {
+		hasRules, err := a.hasRules(d)
+		if err != nil {
+			return err
+		}
+		failing = !hasRules
+	}
+	if failing {
+		acked, err := a.acknowledged(d)
+		if err != nil {
+			return err
+		}
+		failing = !acked
+	}
+	if failing && status == "error" {
+		inMaintenance, err := a.notificationRepo.InMaintenance(d, time.Now())
+		if err != nil {
+			return err
+		}
+		failing = !inMaintenance
+	}
+
+	var errs []error
+	for _, p := range a.providers {
+		openKey, err := a.notificationRepo.OpenAlertKey(d.DomainID, p.Name())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to look up open alert: %w", err))
+			continue
+		}
+
+		switch {
+		case failing && openKey == "":
+			key := incidentKey(d.DomainID, d.CertFingerprint)
+			// Another domain serving the same certificate may have paged already
+			shared, err := a.notificationRepo.IsAlertKeyOpen(p.Name(), key)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to look up open alert: %w", err))
+				continue
+			}
+			if !shared {
+				if err := p.Trigger(ctx, newIncident(d, key, status)); err != nil {
+					errs = append(errs, fmt.Errorf("failed to trigger %s incident for %s: %w", p.Name(), d.DomainName.String(), err))
+					continue
+				}
+				slog.Info("Incident triggered", "domain", d.DomainName.String(), "provider", p.Name(), "status", status)
+			}
+			if err := a.notificationRepo.OpenAlert(d.DomainID, p.Name(), key); err != nil {
+				errs = append(errs, err)
+			}
+
+		case healthy && openKey != "":
+			resolved, err := a.resolve(ctx, p, d, openKey)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			if !resolved {
+				continue
+			}
+			slog.Info("Incident resolved", "domain", d.DomainName.String(), "provider", p.Name(), "status", status)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ResolveDomain resolves every incident open for a domain that is about to be removed, nothing would resolve
+// them once it is gone
+func (a *Alerter) ResolveDomain(ctx context.Context, d domain.Domain) error {
+	var errs []error
+	for _, p := range a.providers {
+		openKey, err := a.notificationRepo.OpenAlertKey(d.DomainID, p.Name())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to look up open alert: %w", err))
+			continue
+		}
+		if openKey == "" {
+			continue
+		}
+		resolved, err := a.resolve(ctx, p, d, openKey)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if !resolved {
+			continue
+		}
+		slog.Info("Incident resolved", "domain", d.DomainName.String(), "provider", p.Name(), "reason", "removed")
+	}
+	return errors.Join(errs...)
+}
+
+// resolve closes the domain's open alert with a provider and resolves its incident, unless another domain serving
+// the same certificate still has it open, reporting whether the incident was resolved. The alert is kept open when
+// the provider fails so it is tried again
+func (a *Alerter) resolve(ctx context.Context, p IncidentProvider, d domain.Domain, openKey string) (bool, error) {
+	if err := a.notificationRepo.CloseAlert(d.DomainID, p.Name()); err != nil {
+		return false, err
+	}
+	// The incident stays open while another domain serving the same certificate is still unhealthy
+	shared, err := a.notificationRepo.IsAlertKeyOpen(p.Name(), openKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up open alert: %w", err)
+	}
+	if shared {
+		return false, nil
+	}
+	if err := p.Resolve(ctx, openKey); err != nil {
+		err = fmt.Errorf("failed to resolve %s incident for %s: %w", p.Name(), d.DomainName.String(), err)
+		if reopenErr := a.notificationRepo.OpenAlert(d.DomainID, p.Name(), openKey); reopenErr != nil {
+			return false, errors.Join(err, reopenErr)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// hasRules reports whether the domain's user has enabled notification rules
+func (a *Alerter) hasRules(d domain.Domain) (bool, error) {
+	rules, err := a.notificationRepo.GetRulesByUserID(d.UserID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get notification rules: %w", err)
+	}
+	for _, r := range rules {
+		if r.Enabled {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// acknowledged reports whether the domain has an active acknowledgement, which holds off new incidents
+func (a *Alerter) acknowledged(d domain.Domain) (bool, error) {
+	ack, err := a.notificationRepo.GetAck(d.DomainID)
+	if errors.Is(err, ErrNoAck) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get acknowledgement: %w", err)
+	}
+	return ack.Active(d.ExpiryTime(), time.Now()), nil
+}
+
+// matches reports whether the domain carries one of the alerter's tags
+func (a *Alerter) matches(d domain.Domain) bool {
+	if len(a.tags) == 0 {
+		return true
+	}
+	for _, t := range a.tags {
+		if d.HasTag(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func newIncident(d domain.Domain, dedupKey, status string) Incident {
+	incident := Incident{
+		DedupKey: dedupKey,
+		Domain:   d.DomainName.String(),
+		Status:   status,
+		Tags:     d.Tags,
+	}
+	if d.ExpiryDate != nil {
+		t := d.ExpiryDate.Time()
+		incident.ExpiryDate = &t
+	}
+	if d.LastError != nil {
+		e := d.LastError.String()
+		incident.Error = &e
+	}
+
+	if status == "expired" {
+		incident.Summary = fmt.Sprintf("SSL certificate for %s has expired", incident.Domain)
+	} else {
+		incident.Summary = fmt.Sprintf("SSL certificate check for %s is failing", incident.Domain)
+	}
+	return incident
+}
+
+// IncidentSender lets notification rules page through an on-call provider.
+//
+// The incident is recorded as open so the Alerter resolves it once the certificate is valid again
+type IncidentSender struct {
+	provider         IncidentProvider
+	notificationRepo *Repository
+	now              func() time.Time
+}
+
+func NewIncidentSender(provider IncidentProvider, notificationRepo *Repository) *IncidentSender {
+	return &IncidentSender{
+		provider:         provider,
+		notificationRepo: notificationRepo,
+		now:              time.Now,
+	}
+}
+
+func (s *IncidentSender) Type() NotificationType {
+	return NewNotificationType(s.provider.Name())
+}
+
+// Send triggers an incident for the notification's domain
+func (s *IncidentSender) Send(ctx context.Context, n Notification) error {
+	now := s.now()
+	incident := Incident{
+		DedupKey:   incidentKey(n.DomainID, n.CertFingerprint),
+		Domain:     n.DomainName,
+		Status:     n.CertificateStatus(now),
+		Summary:    newMessageData(n, now).Title(),
+		ExpiryDate: n.ExpiryDate,
+		Error:      n.CheckError,
+	}
+	if err := s.provider.Trigger(ctx, incident); err != nil {
+		return err
+	}
+
+	open, err := s.notificationRepo.IsAlertOpen(n.DomainID, s.provider.Name())
+	if err != nil {
+		return fmt.Errorf("failed to look up open alert: %w", err)
+	}
+	if open {
+		return nil
+	}
+	return s.notificationRepo.OpenAlert(n.DomainID, s.provider.Name(), incident.DedupKey)
+}
+
+// testDedupKey is the incident key of test notifications, separate from every domain's
+const testDedupKey = "sslcerttop-test"
+
+// sendTest triggers an incident for a sample notification and resolves it again
+func (s *IncidentSender) sendTest(ctx context.Context, n Notification) error {
+	incident := Incident{
+		DedupKey:   testDedupKey,
+		Domain:     n.DomainName,
+		Status:     n.CertificateStatus(s.now()),
+		Summary:    newMessageData(n, s.now()).Title(),
+		ExpiryDate: n.ExpiryDate,
+		Tags:       n.Tags,
+	}
+	if err := s.provider.Trigger(ctx, incident); err != nil {
+		return err
+	}
+	if err := s.provider.Resolve(ctx, testDedupKey); err != nil {
+		return fmt.Errorf("test incident was triggered but not resolved: %w", err)
+	}
+	return nil
+}
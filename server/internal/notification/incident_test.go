@@ -0,0 +1,226 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider records the incidents it was asked to trigger and resolve.
+type fakeProvider struct {
+	triggered []Incident
+	resolved  []string
+}
+
+func (f *fakeProvider) Name() string { return "fake" }
+
+func (f *fakeProvider) Trigger(ctx context.Context, incident Incident) error {
+	f.triggered = append(f.triggered, incident)
+	return nil
+}
+
+func (f *fakeProvider) Resolve(ctx context.Context, dedupKey string) error {
+	f.resolved = append(f.resolved, dedupKey)
+	return nil
+}
+
+// testDomain is domain 1 of newTestDB expiring at expiry.
+func testDomain(expiry time.Time, tags ...string) domain.Domain {
+	e := types.NewExpiryDate(expiry)
+	return domain.Domain{
+		DomainID:   types.DomainID(1),
+		DomainName: domain.NewDomainName("example.com"),
+		ExpiryDate: &e,
+		Tags:       tags,
+	}
+}
+
+// TestAlerter_Lifecycle - failing tagged domains trigger once and resolve when healthy.
+func TestAlerter_Lifecycle(t *testing.T) {
+	repo := NewRepository(newTestDB(t))
+	provider := &fakeProvider{}
+	a := NewAlerter(repo, []string{"Prod"}, provider)
+	ctx := context.Background()
+	expired := time.Now().Add(-time.Hour)
+
+	require.NoError(t, a.Evaluate(ctx, testDomain(expired, "staging")))
+	assert.Empty(t, provider.triggered, "Domains without the tag aren't paged")
+
+	require.NoError(t, a.Evaluate(ctx, testDomain(expired, "prod")))
+	require.NoError(t, a.Evaluate(ctx, testDomain(expired, "prod")))
+	require.Len(t, provider.triggered, 1)
+	assert.Equal(t, DedupKey(1), provider.triggered[0].DedupKey)
+	assert.Equal(t, "expired", provider.triggered[0].Status)
+
+	unknown := testDomain(expired, "prod")
+	unknown.ExpiryDate = nil
+	require.NoError(t, a.Evaluate(ctx, unknown))
+	assert.Empty(t, provider.resolved, "An unknown status doesn't resolve")
+
+	require.NoError(t, a.Evaluate(ctx, testDomain(time.Now().Add(60*24*time.Hour), "prod")))
+	assert.Equal(t, []string{DedupKey(1)}, provider.resolved)
+
+	open, err := repo.IsAlertOpen(types.DomainID(1), provider.Name())
+	require.NoError(t, err)
+	assert.False(t, open)
+}
+
+// TestAlerter_SharedCertificate - domains serving the same certificate share one incident until all are healthy.
+func TestAlerter_SharedCertificate(t *testing.T) {
+	db := newTestDB(t)
+	_, err := db.Exec(`INSERT INTO domains (id, user_id, domain_name, created_at) VALUES (2, 1, 'www.example.com', ?)`, time.Now())
+	require.NoError(t, err)
+	repo := NewRepository(db)
+	provider := &fakeProvider{}
+	a := NewAlerter(repo, nil, provider)
+	ctx := context.Background()
+
+	fingerprint := "0123456789abcdef0123456789abcdef"
+	expired, renewed := time.Now().Add(-time.Hour), time.Now().Add(60*24*time.Hour)
+	apex, www := testDomain(expired), testDomain(expired)
+	www.DomainID, www.DomainName = types.DomainID(2), domain.NewDomainName("www.example.com")
+	apex.CertFingerprint, www.CertFingerprint = fingerprint, fingerprint
+
+	require.NoError(t, a.Evaluate(ctx, apex))
+	require.NoError(t, a.Evaluate(ctx, www))
+	require.Len(t, provider.triggered, 1)
+	assert.Equal(t, "sslcerttop-cert-0123456789abcdef", provider.triggered[0].DedupKey)
+
+	apex.ExpiryDate = testDomain(renewed).ExpiryDate
+	require.NoError(t, a.Evaluate(ctx, apex))
+	assert.Empty(t, provider.resolved, "The incident stays open while www still serves the expired certificate")
+
+	www.ExpiryDate = apex.ExpiryDate
+	require.NoError(t, a.Evaluate(ctx, www))
+	assert.Equal(t, []string{"sslcerttop-cert-0123456789abcdef"}, provider.resolved)
+}
+
+// TestAlerter_ResolveDomain - removing a domain resolves its incident, unless another domain still shares it.
+func TestAlerter_ResolveDomain(t *testing.T) {
+	db := newTestDB(t)
+	_, err := db.Exec(`INSERT INTO domains (id, user_id, domain_name, created_at) VALUES (2, 1, 'www.example.com', ?)`, time.Now())
+	require.NoError(t, err)
+	repo := NewRepository(db)
+	provider := &fakeProvider{}
+	a := NewAlerter(repo, nil, provider)
+	domains := domain.NewService(domain.NewRepository(db), nil)
+	domains.SetIncidents(a)
+	ctx := context.Background()
+
+	fingerprint := "0123456789abcdef0123456789abcdef"
+	apex, www := testDomain(time.Now().Add(-time.Hour)), testDomain(time.Now().Add(-time.Hour))
+	www.DomainID, www.DomainName = types.DomainID(2), domain.NewDomainName("www.example.com")
+	apex.CertFingerprint, www.CertFingerprint = fingerprint, fingerprint
+	require.NoError(t, a.Evaluate(ctx, apex))
+	require.NoError(t, a.Evaluate(ctx, www))
+	require.Len(t, provider.triggered, 1)
+
+	require.NoError(t, domains.RemoveDomain(apex.DomainID))
+	assert.Empty(t, provider.resolved, "www still serves the expired certificate")
+
+	require.NoError(t, domains.RemoveDomain(www.DomainID))
+	assert.Equal(t, []string{"sslcerttop-cert-0123456789abcdef"}, provider.resolved)
+	open, err := repo.IsAlertKeyOpen(provider.Name(), "sslcerttop-cert-0123456789abcdef")
+	require.NoError(t, err)
+	assert.False(t, open)
+}
+
+// TestPagerDutyProvider - events carry the routing key, action and dedup key.
+func TestPagerDutyProvider(t *testing.T) {
+	var events []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		events = append(events, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	p, err := NewPagerDutyProvider("routing-key", server.Client())
+	require.NoError(t, err)
+	p.eventsURL = server.URL
+
+	ctx := context.Background()
+	require.NoError(t, p.Trigger(ctx, Incident{DedupKey: "k", Domain: "example.com", Status: "expired", Summary: "expired"}))
+	require.NoError(t, p.Resolve(ctx, "k"))
+
+	require.Len(t, events, 2)
+	assert.Equal(t, "routing-key", events[0]["routing_key"])
+	assert.Equal(t, "trigger", events[0]["event_action"])
+	assert.Equal(t, "k", events[0]["dedup_key"])
+	assert.Equal(t, "critical", events[0]["payload"].(map[string]any)["severity"])
+	assert.Equal(t, "resolve", events[1]["event_action"])
+	assert.Equal(t, "k", events[1]["dedup_key"])
+
+	_, err = NewPagerDutyProvider("", nil)
+	assert.Error(t, err)
+}
+
+// TestOpsgenieProvider - alerts are created and closed by alias with the API key.
+func TestOpsgenieProvider(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GenieKey api-key", r.Header.Get("Authorization"))
+		paths = append(paths, r.URL.RequestURI())
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	p, err := NewOpsgenieProvider("api-key", server.URL+"/", server.Client())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, p.Trigger(ctx, Incident{DedupKey: "k", Domain: "example.com", Status: "error", Summary: "failing"}))
+	require.NoError(t, p.Resolve(ctx, "k"))
+	assert.Equal(t, []string{"/v2/alerts", "/v2/alerts/k/close?identifierType=alias"}, paths)
+}
+
+// TestAlerter_RulesReplaceDefaultPaging - users with enabled rules are paged by their rules, but incidents still resolve.
+func TestAlerter_RulesReplaceDefaultPaging(t *testing.T) {
+	repo := NewRepository(newTestDB(t))
+	provider := &fakeProvider{}
+	a := NewAlerter(repo, nil, provider)
+	ctx := context.Background()
+	require.NoError(t, repo.CreateRule(&Rule{UserID: types.UserID(1), Name: "page", Channel: NotificationTypeSlack, OnError: true, Enabled: true}))
+
+	expired := testDomain(time.Now().Add(-time.Hour))
+	expired.UserID = types.UserID(1)
+	require.NoError(t, a.Evaluate(ctx, expired))
+	assert.Empty(t, provider.triggered)
+
+	sender := NewIncidentSender(provider, repo)
+	assert.Equal(t, NewNotificationType("fake"), sender.Type())
+	expiry := time.Now().Add(-time.Hour)
+	require.NoError(t, sender.Send(ctx, Notification{DomainID: types.DomainID(1), DomainName: "example.com", ExpiryDate: &expiry}))
+	require.Len(t, provider.triggered, 1)
+
+	healthy := testDomain(time.Now().Add(60 * 24 * time.Hour))
+	healthy.UserID = types.UserID(1)
+	require.NoError(t, a.Evaluate(ctx, healthy))
+	assert.Equal(t, []string{DedupKey(1)}, provider.resolved)
+}
+
+// TestAlerter_Acknowledged - acknowledged domains aren't paged.
+func TestAlerter_Acknowledged(t *testing.T) {
+	repo := NewRepository(newTestDB(t))
+	provider := &fakeProvider{}
+	a := NewAlerter(repo, nil, provider)
+	expired := testDomain(time.Now().Add(-time.Hour))
+
+	_, err := NewService(repo).AcknowledgeDomain(expired, "replacing the load balancer", nil)
+	require.NoError(t, err)
+	require.NoError(t, a.Evaluate(context.Background(), expired))
+	assert.Empty(t, provider.triggered)
+
+	require.NoError(t, repo.DeleteAck(expired.DomainID))
+	require.NoError(t, a.Evaluate(context.Background(), expired))
+	assert.Len(t, provider.triggered, 1)
+}
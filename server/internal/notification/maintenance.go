@@ -0,0 +1,89 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/cron"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// MaintenanceWindow holds back notifications and incidents about failing checks of a domain, or of every domain
+// with a tag, while planned work is expected to break them. Checks still run during the window.
+//
+// A window is either one-off, lasting Duration from Start, or recurring, lasting Duration from each time of Schedule
+type MaintenanceWindow struct {
+	WindowID uint         `db:"id"`
+	UserID   types.UserID `db:"user_id"`
+	// DomainID limits the window to one domain, zero applies it to the domains with Tag
+	DomainID   types.DomainID `db:"domain_id"`
+	DomainName string         `db:"domain_name"`
+	Tag        string         `db:"tag"`
+	// Start is when a one-off window begins, nil for recurring ones
+	Start *time.Time `db:"start_at"`
+	// Schedule is a cron expression for when a recurring window begins, empty for one-off ones
+	Schedule  string        `db:"schedule"`
+	Duration  time.Duration `db:"duration_seconds"`
+	Note      string        `db:"note"`
+	CreatedAt time.Time     `db:"created_at"`
+}
+
+// Validate reports windows that could never apply
+func (w MaintenanceWindow) Validate() error {
+	if (w.DomainID == 0) == (strings.TrimSpace(w.Tag) == "") {
+		return errors.New("maintenance window needs either a domain or a tag")
+	}
+	if w.Duration <= 0 {
+		return fmt.Errorf("maintenance window duration must be positive, got %s", w.Duration)
+	}
+	if (w.Start == nil) == (w.Schedule == "") {
+		return errors.New("maintenance window needs either a start or a schedule")
+	}
+	if w.Schedule != "" {
+		if _, err := cron.Parse(w.Schedule); err != nil {
+			return err
+		}
+	}
+	if len(w.Note) > MaxAckNoteLength {
+		return fmt.Errorf("maintenance window note is longer than %d characters", MaxAckNoteLength)
+	}
+	return nil
+}
+
+// Matches reports whether the window applies to a domain
+func (w MaintenanceWindow) Matches(d domain.Domain) bool {
+	if w.DomainID != 0 {
+		return w.DomainID == d.DomainID
+	}
+	return d.HasTag(w.Tag)
+}
+
+// Active reports whether the window is open at now
+func (w MaintenanceWindow) Active(now time.Time) bool {
+	if w.Start != nil {
+		return !now.Before(*w.Start) && now.Before(w.Start.Add(w.Duration))
+	}
+	schedule, err := cron.Parse(w.Schedule)
+	if err != nil {
+		return false
+	}
+	return schedule.DueSince(now.Add(-w.Duration), now)
+}
+
+// Ended reports whether a one-off window is over for good, recurring windows never are
+func (w MaintenanceWindow) Ended(now time.Time) bool {
+	return w.Start != nil && !now.Before(w.Start.Add(w.Duration))
+}
+
+// InMaintenance reports whether any of windows is open for a domain at now
+func InMaintenance(windows []MaintenanceWindow, d domain.Domain, now time.Time) bool {
+	for _, w := range windows {
+		if w.Matches(d) && w.Active(now) {
+			return true
+		}
+	}
+	return false
+}
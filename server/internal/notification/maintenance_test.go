@@ -0,0 +1,78 @@
+package notification
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMaintenanceWindow_Active - one-off windows are open from their start, recurring ones after each scheduled time.
+func TestMaintenanceWindow_Active(t *testing.T) {
+	start := time.Date(2026, 3, 1, 22, 0, 0, 0, time.UTC)
+	oneOff := MaintenanceWindow{DomainID: types.DomainID(1), Start: &start, Duration: 2 * time.Hour}
+	require.NoError(t, oneOff.Validate())
+	assert.False(t, oneOff.Active(start.Add(-time.Minute)))
+	assert.True(t, oneOff.Active(start))
+	assert.True(t, oneOff.Active(start.Add(119*time.Minute)))
+	assert.False(t, oneOff.Active(start.Add(2*time.Hour)))
+	assert.True(t, oneOff.Ended(start.Add(2*time.Hour)))
+
+	// Sundays from 02:00 to 05:00
+	weekly := MaintenanceWindow{Tag: "prod", Schedule: "0 2 * * 0", Duration: 3 * time.Hour}
+	require.NoError(t, weekly.Validate())
+	sunday := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	assert.False(t, weekly.Active(sunday.Add(time.Hour)))
+	assert.True(t, weekly.Active(sunday.Add(2*time.Hour)))
+	assert.True(t, weekly.Active(sunday.Add(4*time.Hour)))
+	assert.False(t, weekly.Active(sunday.Add(5*time.Hour)))
+	assert.False(t, weekly.Active(sunday.AddDate(0, 0, 1).Add(3*time.Hour)))
+	assert.False(t, weekly.Ended(sunday.AddDate(1, 0, 0)))
+
+	assert.True(t, weekly.Matches(domain.Domain{Tags: []string{"prod"}}))
+	assert.False(t, weekly.Matches(domain.Domain{Tags: []string{"staging"}}))
+
+	assert.Error(t, MaintenanceWindow{Start: &start, Duration: time.Hour}.Validate(), "Needs a domain or tag")
+	assert.Error(t, MaintenanceWindow{Tag: "prod", Duration: time.Hour}.Validate(), "Needs a start or schedule")
+	assert.Error(t, MaintenanceWindow{Tag: "prod", Schedule: "every sunday", Duration: time.Hour}.Validate())
+}
+
+// TestDispatcher_EvaluateDomainMaintenance - failing checks don't notify or page during a maintenance window.
+func TestDispatcher_EvaluateDomainMaintenance(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewRepository(db)
+	s := NewService(repo)
+	d := NewDispatcher(repo, []int{7}, &fakeSender{nType: NotificationTypeSlack})
+
+	now := time.Now()
+	start := now.Add(-time.Hour)
+	w := MaintenanceWindow{Tag: "Web", Start: &start, Duration: 2 * time.Hour}
+	require.NoError(t, s.AddMaintenanceWindow(types.UserID(1), &w))
+
+	checkErr := domain.NewLastError("connection refused")
+	failing := testDomain(now.Add(60*24*time.Hour), "web")
+	failing.UserID, failing.LastError = types.UserID(1), &checkErr
+	provider := &fakeProvider{}
+	require.NoError(t, NewAlerter(repo, nil, provider).Evaluate(context.Background(), failing))
+	assert.Empty(t, provider.triggered)
+
+	require.NoError(t, repo.CreateRule(&Rule{UserID: types.UserID(1), Name: "errors", Channel: NotificationTypeSlack, OnError: true, Enabled: true}))
+	require.NoError(t, d.EvaluateDomain(failing, now))
+	notifications, err := repo.GetNotificationsByUserID(types.UserID(1))
+	require.NoError(t, err)
+	assert.Empty(t, notifications)
+
+	// Still failing once the window is over
+	require.NoError(t, d.EvaluateDomain(failing, now.Add(2*time.Hour)))
+	notifications, err = repo.GetNotificationsByUserID(types.UserID(1))
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, ErrorThreshold, notifications[0].DaysBefore)
+
+	require.NoError(t, s.RemoveMaintenanceWindow(types.UserID(1), w.WindowID))
+	assert.Error(t, s.RemoveMaintenanceWindow(types.UserID(1), w.WindowID))
+}
@@ -0,0 +1,134 @@
+package notification
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/user"
+)
+
+// messageData is what every channel renders a notification from
+type messageData struct {
+	Domain     string
+	ExpiryDate *time.Time
+	DaysLeft   int
+	Expired    bool
+	Threshold  int
+	// Failing is set for notifications about failing checks, with the check's Error
+	Failing bool
+	Error   string
+	// Registration is set for notifications about the domain's registration rather than its certificate
+	Registration bool
+	// RenewalOverdue is set for notifications that the certificate wasn't renewed automatically AutoRenewDays
+	// before it expires, through AutoRenewVia if it is known
+	RenewalOverdue bool
+	AutoRenewDays  int
+	AutoRenewVia   string
+	// SharedWith are the other domains serving the same certificate, notified about together with Domain
+	SharedWith []string
+	Issuer     string
+	Tags       []string
+	// DashboardURL links to where the certificates are managed, empty when not configured
+	DashboardURL string
+
+	// settings are the owner's timezone and time display, now what relative times count from
+	settings user.Settings
+	now      time.Time
+
+	// subject and body override the channel's message with rendered custom templates
+	subject, body string
+}
+
+// newMessageData works out how long the notification's certificate has left at now
+func newMessageData(n Notification, now time.Time) messageData {
+	data := messageData{
+		Domain:        n.DomainName,
+		ExpiryDate:    n.ExpiryDate,
+		Threshold:     n.DaysBefore,
+		Registration:  n.Registration,
+		AutoRenewDays: n.AutoRenewDays,
+		AutoRenewVia:  n.AutoRenewVia,
+		SharedWith:    n.SharedWith,
+		Issuer:        n.Issuer,
+		Tags:          n.Tags,
+		settings:      user.Settings{Timezone: n.Timezone, TimeDisplay: n.TimeDisplay},
+		now:           now,
+		subject:       n.Subject,
+		body:          n.Body,
+	}
+	if n.ExpiryDate != nil {
+		data.DaysLeft = int(n.ExpiryDate.Sub(now).Hours() / 24)
+		data.Expired = n.ExpiryDate.Before(now)
+	} else {
+		data.DaysLeft = n.DaysBefore
+		data.Expired = n.DaysBefore == 0
+	}
+	if n.DaysBefore == ErrorThreshold {
+		data.Failing = true
+		data.Expired = false
+		if n.CheckError != nil {
+			data.Error = *n.CheckError
+		}
+	}
+	if n.DaysBefore == RenewalOverdueThreshold {
+		data.RenewalOverdue = true
+	}
+	return data
+}
+
+// Title is the one line summary used as a subject or heading
+func (d messageData) Title() string {
+	if d.subject != "" {
+		return d.subject
+	}
+	if d.Failing {
+		return fmt.Sprintf("SSL certificate check for %s is failing", d.names())
+	}
+	if d.RenewalOverdue {
+		return fmt.Sprintf("Auto-renewal of the SSL certificate for %s is likely broken", d.names())
+	}
+	if d.Registration && d.Expired {
+		return fmt.Sprintf("Domain registration for %s has expired", d.names())
+	}
+	if d.Registration {
+		return fmt.Sprintf("Domain registration for %s expires in %d days", d.Domain, d.DaysLeft)
+	}
+	if d.Expired {
+		return fmt.Sprintf("SSL certificate for %s has expired", d.names())
+	}
+	return fmt.Sprintf("SSL certificate for %s expires in %d days", d.Domain, d.DaysLeft)
+}
+
+// names are the domains a title is about, counting the others that share the certificate
+func (d messageData) names() string {
+	switch len(d.SharedWith) {
+	case 0:
+		return d.Domain
+	case 1:
+		return d.Domain + " and " + d.SharedWith[0]
+	default:
+		return fmt.Sprintf("%s and %d other domains", d.Domain, len(d.SharedWith))
+	}
+}
+
+// renews describes when and how the certificate is expected to renew automatically
+func (d messageData) renews() string {
+	renews := fmt.Sprintf("%d days before expiry", d.AutoRenewDays)
+	if d.AutoRenewVia != "" {
+		renews += " via " + d.AutoRenewVia
+	}
+	return renews
+}
+
+// Expires formats the expiry date for display the way the domain's owner chose
+func (d messageData) Expires() string {
+	const layout = "2006-01-02 15:04 MST"
+	if d.ExpiryDate == nil {
+		return "unknown"
+	}
+	if d.settings.Timezone == "" && d.settings.TimeDisplay != user.TimeRelative {
+		// Owners without a timezone of their own get the server's
+		return d.ExpiryDate.Format(layout)
+	}
+	return d.settings.FormatTime(*d.ExpiryDate, d.now, layout)
+}
@@ -1,9 +1,13 @@
 package notification
 
 import (
+	"fmt"
+	"slices"
 	"time"
 
+	"github.com/samokw/ssl_tracker/internal/domain"
 	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/samokw/ssl_tracker/internal/user"
 )
 
 type NotificationType string
@@ -12,8 +16,35 @@ const (
 	NotificationTypeEmail   NotificationType = "email"
 	NotificationTypeDiscord NotificationType = "discord"
 	NotificationTypeSlack   NotificationType = "slack"
+	NotificationTypeTeams   NotificationType = "teams"
+	NotificationTypeWebhook NotificationType = "webhook"
+	// Incident channels page an on-call provider, for notification rules
+	NotificationTypePagerDuty NotificationType = "pagerduty"
+	NotificationTypeOpsgenie  NotificationType = "opsgenie"
 )
 
+// NotificationTypes lists every channel rules can use
+var NotificationTypes = []NotificationType{
+	NotificationTypeEmail,
+	NotificationTypeDiscord,
+	NotificationTypeSlack,
+	NotificationTypeTeams,
+	NotificationTypeWebhook,
+	NotificationTypePagerDuty,
+	NotificationTypeOpsgenie,
+}
+
+// RegisterNotificationType adds a channel rules can use, e.g. one a plugin delivers to.
+//
+// Returns an error when a channel of that name already exists
+func RegisterNotificationType(nType NotificationType) error {
+	if slices.Contains(NotificationTypes, nType) {
+		return fmt.Errorf("notification channel %s already exists", nType)
+	}
+	NotificationTypes = append(NotificationTypes, nType)
+	return nil
+}
+
 func NewNotificationType(nType string) NotificationType {
 	return NotificationType(nType)
 }
@@ -22,10 +53,90 @@ func (n NotificationType) String() string {
 	return string(n)
 }
 
+// NotificationStatus is the delivery state of a notification
+type NotificationStatus string
+
+const (
+	StatusPending NotificationStatus = "pending"
+	StatusSent    NotificationStatus = "sent"
+	// StatusRetrying notifications failed transiently and are sent again at NextAttemptAt
+	StatusRetrying NotificationStatus = "retrying"
+	// StatusFailed notifications failed permanently or ran out of attempts
+	StatusFailed       NotificationStatus = "failed"
+	StatusAcknowledged NotificationStatus = "acknowledged"
+)
+
+func NewNotificationStatus(status string) NotificationStatus {
+	return NotificationStatus(status)
+}
+
+func (s NotificationStatus) String() string {
+	return string(s)
+}
+
 type Notification struct {
-	NotificationID   uint             `db:"id"`
-	DomainID         types.DomainID   `db:"domain_id"`
-	DaysBefore       int              `db:"days_before"`
-	SentAt           time.Time        `db:"sent_at"`
-	NotificationType NotificationType `db:"notification_type"`
+	NotificationID   uint               `db:"id"`
+	DomainID         types.DomainID     `db:"domain_id"`
+	DomainName       string             `db:"domain_name"`
+	ExpiryDate       *time.Time         `db:"expiry_date"`
+	DaysBefore       int                `db:"days_before"`
+	NotificationType NotificationType   `db:"notification_type"`
+	Status           NotificationStatus `db:"status"`
+	CreatedAt        time.Time          `db:"created_at"`
+	SentAt           *time.Time         `db:"sent_at"`
+	AcknowledgedAt   *time.Time         `db:"acknowledged_at"`
+	LastError        *string            `db:"last_error"`
+	// EscalatedFrom is the unacknowledged notification this one escalates
+	EscalatedFrom *uint `db:"escalated_from"`
+	// Attempts counts deliveries tried since the notification was last queued
+	Attempts       int        `db:"attempts"`
+	NextAttemptAt  *time.Time `db:"next_attempt_at"`
+	LastStatusCode *int       `db:"last_status_code"`
+
+	// The domain's latest check and the one before it, for channels that report status changes
+	LastChecked        *time.Time `db:"last_checked"`
+	CheckError         *string    `db:"check_error"`
+	PreviousCheckedAt  *time.Time `db:"previous_checked_at"`
+	PreviousExpiryDate *time.Time `db:"previous_expiry_date"`
+	PreviousCheckError *string    `db:"previous_check_error"`
+
+	// The domain's certificate issuer and tags, for templates
+	Issuer string   `db:"issuer"`
+	Tags   []string `db:"tags"`
+
+	// AutoRenewDays and AutoRenewVia are how the domain's certificate is expected to renew automatically, for
+	// notifications that it didn't
+	AutoRenewDays int    `db:"auto_renew_days"`
+	AutoRenewVia  string `db:"auto_renew_via"`
+
+	// CertFingerprint identifies the domain's certificate, empty when it has none
+	CertFingerprint string `db:"cert_fingerprint"`
+	// SharedWith names the other domains serving the same certificate whose notifications at the same threshold
+	// are delivered as this one
+	SharedWith []string `db:"-"`
+
+	// Timezone and TimeDisplay are how the domain's owner wants times shown in messages
+	Timezone    string           `db:"timezone"`
+	TimeDisplay user.TimeDisplay `db:"time_display"`
+
+	// Registration notifications warn that the domain's registration, not its certificate, is about to expire.
+	// They are sent straight away rather than queued, so they have no ID
+	Registration bool `db:"-"`
+
+	// Subject and Body are rendered from custom templates before delivery, empty uses the channel's own message
+	Subject string `db:"-"`
+	Body    string `db:"-"`
+}
+
+// CertificateStatus is the status of the domain's certificate at now
+func (n Notification) CertificateStatus(now time.Time) string {
+	return domain.CertificateStatus(n.ExpiryDate, n.CheckError, now)
+}
+
+// PreviousStatus is the status the check before the latest one found, empty if there was none
+func (n Notification) PreviousStatus() string {
+	if n.PreviousCheckedAt == nil {
+		return ""
+	}
+	return domain.CertificateStatus(n.PreviousExpiryDate, n.PreviousCheckError, *n.PreviousCheckedAt)
 }
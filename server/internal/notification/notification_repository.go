@@ -0,0 +1,887 @@
+package notification
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/samokw/ssl_tracker/internal/user"
+)
+
+type Repository struct {
+	db     *sql.DB
+	writer *database.Writer
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db:     db,
+		writer: database.NewWriter(db),
+	}
+}
+
+// selectNotifications joins each notification with its domain's latest check, the check before it and its owner's time settings
+const selectNotifications = `SELECT n.id, n.domain_id, d.domain_name, d.expiry_date, n.days_before, n.notification_type, n.status,
+              n.created_at, n.sent_at, n.acknowledged_at, n.last_error, n.escalated_from,
+              n.attempts, n.next_attempt_at, n.last_status_code,
+              d.last_checked, d.last_error, p.checked_at, p.expiry_date, p.error, d.issuer, d.tags,
+              d.auto_renew_days, d.auto_renew_via, d.cert_fingerprint, COALESCE(us.timezone, ''), COALESCE(us.time_display, '')
+              FROM notifications n JOIN domains d ON d.id = n.domain_id
+              LEFT JOIN user_settings us ON us.user_id = d.user_id
+              LEFT JOIN check_history p ON p.id = (
+                  SELECT h.id FROM check_history h WHERE h.domain_id = n.domain_id ORDER BY h.id DESC LIMIT 1 OFFSET 1)`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func (r *Repository) scanNotification(row scanner) (Notification, error) {
+	var id, domainID uint
+	var domainName, notificationType, status, issuer, tags, timezone, timeDisplay string
+	var daysBefore, attempts, autoRenewDays int
+	var autoRenewVia, certFingerprint string
+	var createdAt time.Time
+	var expiryDate, sentAt, acknowledgedAt, nextAttemptAt, lastChecked, previousCheckedAt, previousExpiryDate sql.NullTime
+	var lastError, checkError, previousCheckError sql.NullString
+	var escalatedFrom, lastStatusCode sql.NullInt64
+
+	err := row.Scan(&id, &domainID, &domainName, &expiryDate, &daysBefore, &notificationType, &status,
+		&createdAt, &sentAt, &acknowledgedAt, &lastError, &escalatedFrom,
+		&attempts, &nextAttemptAt, &lastStatusCode,
+		&lastChecked, &checkError, &previousCheckedAt, &previousExpiryDate, &previousCheckError, &issuer, &tags,
+		&autoRenewDays, &autoRenewVia, &certFingerprint, &timezone, &timeDisplay)
+	if err != nil {
+		return Notification{}, err
+	}
+
+	n := Notification{
+		NotificationID:   id,
+		DomainID:         types.DomainID(domainID),
+		DomainName:       domainName,
+		DaysBefore:       daysBefore,
+		NotificationType: NewNotificationType(notificationType),
+		Status:           NewNotificationStatus(status),
+		CreatedAt:        createdAt,
+		Attempts:         attempts,
+		Issuer:           issuer,
+		Tags:             domain.ParseTags(tags),
+		AutoRenewDays:    autoRenewDays,
+		AutoRenewVia:     autoRenewVia,
+		CertFingerprint:  certFingerprint,
+		Timezone:         timezone,
+		TimeDisplay:      user.TimeDisplay(timeDisplay),
+	}
+	if expiryDate.Valid {
+		n.ExpiryDate = &expiryDate.Time
+	}
+	if sentAt.Valid {
+		n.SentAt = &sentAt.Time
+	}
+	if acknowledgedAt.Valid {
+		n.AcknowledgedAt = &acknowledgedAt.Time
+	}
+	if lastError.Valid {
+		n.LastError = &lastError.String
+	}
+	if escalatedFrom.Valid {
+		from := uint(escalatedFrom.Int64)
+		n.EscalatedFrom = &from
+	}
+	if nextAttemptAt.Valid {
+		n.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if lastStatusCode.Valid {
+		code := int(lastStatusCode.Int64)
+		n.LastStatusCode = &code
+	}
+	if lastChecked.Valid {
+		n.LastChecked = &lastChecked.Time
+	}
+	if checkError.Valid {
+		n.CheckError = &checkError.String
+	}
+	if previousCheckedAt.Valid {
+		n.PreviousCheckedAt = &previousCheckedAt.Time
+	}
+	if previousExpiryDate.Valid {
+		n.PreviousExpiryDate = &previousExpiryDate.Time
+	}
+	if previousCheckError.Valid {
+		n.PreviousCheckError = &previousCheckError.String
+	}
+	return n, nil
+}
+
+// CreateNotification stores a new pending notification
+func (r *Repository) CreateNotification(n *Notification) error {
+	if err := types.ValidateDomainID(n.DomainID); err != nil {
+		return fmt.Errorf("invalid domain ID: %w", err)
+	}
+	if n.Status == "" {
+		n.Status = StatusPending
+	}
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now()
+	}
+
+	var escalatedFrom sql.NullInt64
+	if n.EscalatedFrom != nil {
+		escalatedFrom = sql.NullInt64{Int64: int64(*n.EscalatedFrom), Valid: true}
+	}
+
+	query := `INSERT INTO notifications (domain_id, days_before, notification_type, status, created_at, escalated_from) VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := r.writer.Exec(query, n.DomainID.Uint(), n.DaysBefore, n.NotificationType.String(), n.Status.String(), n.CreatedAt, escalatedFrom)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	n.NotificationID = uint(id)
+	return nil
+}
+
+// GetNotificationsByUserID lists the notifications for all domains of a user, newest first
+func (r *Repository) GetNotificationsByUserID(userID types.UserID) ([]Notification, error) {
+	query := selectNotifications + ` WHERE d.user_id = ? ORDER BY n.created_at DESC, n.id DESC`
+	rows, err := r.db.Query(query, userID.Uint())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []Notification{}
+	for rows.Next() {
+		n, err := r.scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// GetPendingNotifications lists notifications of every user still waiting to be delivered, oldest first
+func (r *Repository) GetPendingNotifications() ([]Notification, error) {
+	query := selectNotifications + ` WHERE n.status = ? ORDER BY n.created_at, n.id`
+	rows, err := r.db.Query(query, StatusPending.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []Notification{}
+	for rows.Next() {
+		n, err := r.scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// GetRetriesDue lists notifications of every user whose next delivery attempt is due at now, oldest first
+func (r *Repository) GetRetriesDue(now time.Time) ([]Notification, error) {
+	query := selectNotifications + ` WHERE n.status = ? AND n.next_attempt_at <= ? ORDER BY n.created_at, n.id`
+	// next_attempt_at is written in local time and compared as text
+	rows, err := r.db.Query(query, StatusRetrying.String(), now.Local())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []Notification{}
+	for rows.Next() {
+		n, err := r.scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// LastQueuedAt returns when the latest notification for a domain, threshold and channel created since since was queued,
+// nil when there is none
+func (r *Repository) LastQueuedAt(domainID types.DomainID, daysBefore int, notificationType NotificationType, since time.Time) (*time.Time, error) {
+	query := `SELECT created_at FROM notifications
+              WHERE domain_id = ? AND days_before = ? AND notification_type = ? AND created_at >= ?
+              ORDER BY created_at DESC LIMIT 1`
+	var createdAt time.Time
+	// created_at is written in local time and compared as text
+	err := r.db.QueryRow(query, domainID.Uint(), daysBefore, notificationType.String(), since.Local()).Scan(&createdAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &createdAt, nil
+}
+
+// GetUnescalated lists sent, unacknowledged notifications at a threshold sent before sentBefore
+// that haven't been escalated yet
+func (r *Repository) GetUnescalated(daysBefore int, sentBefore time.Time) ([]Notification, error) {
+	query := selectNotifications + ` WHERE n.days_before = ? AND n.status = ? AND n.escalated_from IS NULL AND n.sent_at <= ?
+              AND NOT EXISTS (SELECT 1 FROM notifications e WHERE e.escalated_from = n.id)
+              ORDER BY n.id`
+	rows, err := r.db.Query(query, daysBefore, StatusSent.String(), sentBefore.Local())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []Notification{}
+	for rows.Next() {
+		n, err := r.scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// GetNotificationByID looks up a single notification
+func (r *Repository) GetNotificationByID(id uint) (*Notification, error) {
+	row := r.db.QueryRow(selectNotifications+` WHERE n.id = ?`, id)
+	n, err := r.scanNotification(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("notification with ID %d not found", id)
+		}
+		return nil, err
+	}
+	return &n, nil
+}
+
+// UpdateStatus changes the delivery status of a notification.
+//
+// Sent notifications record the send time, failed ones the error and acknowledged ones the acknowledgement time.
+// Pending ones start over with no attempts
+func (r *Repository) UpdateStatus(id uint, status NotificationStatus, lastError *string) error {
+	now := time.Now()
+
+	var query string
+	var args []any
+	switch status {
+	case StatusSent:
+		query = `UPDATE notifications SET status = ?, sent_at = ?, last_error = NULL WHERE id = ?`
+		args = []any{status.String(), now, id}
+	case StatusAcknowledged:
+		query = `UPDATE notifications SET status = ?, acknowledged_at = ? WHERE id = ?`
+		args = []any{status.String(), now, id}
+	case StatusPending:
+		query = `UPDATE notifications SET status = ?, last_error = NULL, attempts = 0, next_attempt_at = NULL, last_status_code = NULL WHERE id = ?`
+		args = []any{status.String(), id}
+	default:
+		var errorNull sql.NullString
+		if lastError != nil {
+			errorNull.String = *lastError
+			errorNull.Valid = true
+		}
+		query = `UPDATE notifications SET status = ?, last_error = ? WHERE id = ?`
+		args = []any{status.String(), errorNull, id}
+	}
+
+	result, err := r.writer.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("notification with ID %d not found", id)
+	}
+	return nil
+}
+
+// RecordAttempt stores a delivery attempt and moves its notification to status, to be tried again at
+// nextAttemptAt when it is retrying
+func (r *Repository) RecordAttempt(a *DeliveryAttempt, status NotificationStatus, nextAttemptAt *time.Time) error {
+	var statusCode sql.NullInt64
+	if a.StatusCode != nil {
+		statusCode = sql.NullInt64{Int64: int64(*a.StatusCode), Valid: true}
+	}
+	var attemptError sql.NullString
+	if a.Error != nil {
+		attemptError = sql.NullString{String: *a.Error, Valid: true}
+	}
+	var next sql.NullTime
+	if nextAttemptAt != nil {
+		next = sql.NullTime{Time: *nextAttemptAt, Valid: true}
+	}
+
+	return r.writer.Transaction(func(tx *sql.Tx) error {
+		query := `INSERT INTO notification_attempts (notification_id, attempted_at, status_code, error) VALUES (?, ?, ?, ?)`
+		result, err := tx.Exec(query, a.NotificationID, a.AttemptedAt, statusCode, attemptError)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		a.ID = uint(id)
+
+		update := `UPDATE notifications SET status = ?, attempts = attempts + 1, next_attempt_at = ?, last_status_code = ?, last_error = ? WHERE id = ?`
+		args := []any{status.String(), next, statusCode, attemptError, a.NotificationID}
+		if status == StatusSent {
+			update = `UPDATE notifications SET status = ?, attempts = attempts + 1, next_attempt_at = ?, last_status_code = ?, last_error = ?, sent_at = ? WHERE id = ?`
+			args = []any{status.String(), next, statusCode, attemptError, a.AttemptedAt, a.NotificationID}
+		}
+		result, err = tx.Exec(update, args...)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("notification with ID %d not found", a.NotificationID)
+		}
+		return nil
+	})
+}
+
+// GetAttempts lists the delivery attempts of a notification, oldest first
+func (r *Repository) GetAttempts(notificationID uint) ([]DeliveryAttempt, error) {
+	query := `SELECT id, notification_id, attempted_at, status_code, error FROM notification_attempts
+              WHERE notification_id = ? ORDER BY id`
+	rows, err := r.db.Query(query, notificationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []DeliveryAttempt{}
+	for rows.Next() {
+		var a DeliveryAttempt
+		var statusCode sql.NullInt64
+		var attemptError sql.NullString
+		if err := rows.Scan(&a.ID, &a.NotificationID, &a.AttemptedAt, &statusCode, &attemptError); err != nil {
+			return nil, err
+		}
+		if statusCode.Valid {
+			code := int(statusCode.Int64)
+			a.StatusCode = &code
+		}
+		if attemptError.Valid {
+			a.Error = &attemptError.String
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
+
+// IsAlertOpen reports whether an incident was triggered for a domain with a provider and not yet resolved
+func (r *Repository) IsAlertOpen(domainID types.DomainID, provider string) (bool, error) {
+	query := `SELECT COUNT(*) FROM open_alerts WHERE domain_id = ? AND provider = ?`
+	var count int
+	if err := r.db.QueryRow(query, domainID.Uint(), provider).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// OpenAlertKey is the deduplication key of the incident open for a domain with a provider, empty when there is none
+func (r *Repository) OpenAlertKey(domainID types.DomainID, provider string) (string, error) {
+	var dedupKey string
+	err := r.db.QueryRow(`SELECT dedup_key FROM open_alerts WHERE domain_id = ? AND provider = ?`, domainID.Uint(), provider).Scan(&dedupKey)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return dedupKey, err
+}
+
+// IsAlertKeyOpen reports whether any domain has an incident with a deduplication key open with a provider
+func (r *Repository) IsAlertKeyOpen(provider, dedupKey string) (bool, error) {
+	query := `SELECT COUNT(*) FROM open_alerts WHERE provider = ? AND dedup_key = ?`
+	var count int
+	if err := r.db.QueryRow(query, provider, dedupKey).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// OpenAlert records that an incident was triggered for a domain with a provider
+func (r *Repository) OpenAlert(domainID types.DomainID, provider, dedupKey string) error {
+	query := `INSERT INTO open_alerts (domain_id, provider, dedup_key, triggered_at) VALUES (?, ?, ?, ?)`
+	_, err := r.writer.Exec(query, domainID.Uint(), provider, dedupKey, time.Now())
+	return err
+}
+
+// CloseAlert forgets the incident of a domain with a provider once it is resolved
+func (r *Repository) CloseAlert(domainID types.DomainID, provider string) error {
+	query := `DELETE FROM open_alerts WHERE domain_id = ? AND provider = ?`
+	_, err := r.writer.Exec(query, domainID.Uint(), provider)
+	return err
+}
+
+const ruleColumns = `id, user_id, name, channel, thresholds, on_error, tags, enabled, created_at`
+
+func (r *Repository) scanRule(row scanner) (Rule, error) {
+	var id, userID uint
+	var name, channel, thresholds, tags string
+	var onError, enabled bool
+	var createdAt time.Time
+	if err := row.Scan(&id, &userID, &name, &channel, &thresholds, &onError, &tags, &enabled, &createdAt); err != nil {
+		return Rule{}, err
+	}
+	parsed, err := ParseThresholds(thresholds)
+	if err != nil {
+		return Rule{}, fmt.Errorf("rule %d: %w", id, err)
+	}
+	return Rule{
+		RuleID:     id,
+		UserID:     types.UserID(userID),
+		Name:       name,
+		Channel:    NewNotificationType(channel),
+		Thresholds: parsed,
+		OnError:    onError,
+		Tags:       domain.ParseTags(tags),
+		Enabled:    enabled,
+		CreatedAt:  createdAt,
+	}, nil
+}
+
+// CreateRule stores a new rule
+func (r *Repository) CreateRule(rule *Rule) error {
+	if err := types.ValidateUserID(rule.UserID); err != nil {
+		return fmt.Errorf("invalid user ID: %w", err)
+	}
+	if rule.CreatedAt.IsZero() {
+		rule.CreatedAt = time.Now()
+	}
+
+	query := `INSERT INTO notification_rules (user_id, name, channel, thresholds, on_error, tags, enabled, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := r.writer.Exec(query, rule.UserID.Uint(), rule.Name, rule.Channel.String(), FormatThresholds(rule.Thresholds),
+		rule.OnError, strings.Join(domain.NormalizeTags(rule.Tags), ","), rule.Enabled, rule.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	rule.RuleID = uint(id)
+	return nil
+}
+
+// GetPreferredChannels returns the channels a user limited notifications without rules to, nil when they didn't
+func (r *Repository) GetPreferredChannels(userID types.UserID) ([]NotificationType, error) {
+	var channels string
+	err := r.db.QueryRow(`SELECT notification_channels FROM user_settings WHERE user_id = ?`, userID.Uint()).Scan(&channels)
+	if err == sql.ErrNoRows || (err == nil && channels == "") {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var preferred []NotificationType
+	for _, c := range strings.Split(channels, ",") {
+		preferred = append(preferred, NewNotificationType(c))
+	}
+	return preferred, nil
+}
+
+// GetRulesByUserID lists a user's rules in the order they were created
+func (r *Repository) GetRulesByUserID(userID types.UserID) ([]Rule, error) {
+	rows, err := r.db.Query(`SELECT `+ruleColumns+` FROM notification_rules WHERE user_id = ? ORDER BY id`, userID.Uint())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []Rule{}
+	for rows.Next() {
+		rule, err := r.scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// GetRuleByID looks up a single rule
+func (r *Repository) GetRuleByID(id uint) (*Rule, error) {
+	rule, err := r.scanRule(r.db.QueryRow(`SELECT `+ruleColumns+` FROM notification_rules WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("rule with ID %d not found", id)
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// SetRuleEnabled switches a rule on or off
+func (r *Repository) SetRuleEnabled(id uint, enabled bool) error {
+	result, err := r.writer.Exec(`UPDATE notification_rules SET enabled = ? WHERE id = ?`, enabled, id)
+	if err != nil {
+		return err
+	}
+	return requireRow(result, id)
+}
+
+// DeleteRule removes a rule
+func (r *Repository) DeleteRule(id uint) error {
+	result, err := r.writer.Exec(`DELETE FROM notification_rules WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return requireRow(result, id)
+}
+
+// requireRow fails when a statement on a rule changed nothing
+func requireRow(result sql.Result, id uint) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("rule with ID %d not found", id)
+	}
+	return nil
+}
+
+// ErrorNotificationExists reports whether a failing-check notification was queued for a domain and channel
+// since its last successful check, so each run of failures notifies once
+func (r *Repository) ErrorNotificationExists(domainID types.DomainID, notificationType NotificationType) (bool, error) {
+	query := `SELECT COUNT(*) FROM notifications n
+              WHERE n.domain_id = ? AND n.days_before = ? AND n.notification_type = ?
+              AND NOT EXISTS (SELECT 1 FROM check_history h
+                  WHERE h.domain_id = n.domain_id AND h.error IS NULL AND h.checked_at > n.created_at)`
+	var count int
+	if err := r.db.QueryRow(query, domainID.Uint(), ErrorThreshold, notificationType.String()).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+const selectAcks = `SELECT a.domain_id, d.domain_name, a.note, a.expiry_date, a.expires_at, a.created_at
+              FROM domain_acks a JOIN domains d ON d.id = a.domain_id`
+
+func (r *Repository) scanAck(row scanner) (DomainAck, error) {
+	var domainID uint
+	var domainName, note string
+	var expiryDate, expiresAt sql.NullTime
+	var createdAt time.Time
+	if err := row.Scan(&domainID, &domainName, &note, &expiryDate, &expiresAt, &createdAt); err != nil {
+		return DomainAck{}, err
+	}
+
+	a := DomainAck{
+		DomainID:   types.DomainID(domainID),
+		DomainName: domainName,
+		Note:       note,
+		CreatedAt:  createdAt,
+	}
+	if expiryDate.Valid {
+		a.ExpiryDate = &expiryDate.Time
+	}
+	if expiresAt.Valid {
+		a.ExpiresAt = &expiresAt.Time
+	}
+	return a, nil
+}
+
+// SetAck stores the acknowledgement of a domain, replacing any earlier one
+func (r *Repository) SetAck(a *DomainAck) error {
+	if err := types.ValidateDomainID(a.DomainID); err != nil {
+		return fmt.Errorf("invalid domain ID: %w", err)
+	}
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+
+	return r.writer.Transaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM domain_acks WHERE domain_id = ?`, a.DomainID.Uint()); err != nil {
+			return err
+		}
+		query := `INSERT INTO domain_acks (domain_id, note, expiry_date, expires_at, created_at) VALUES (?, ?, ?, ?, ?)`
+		_, err := tx.Exec(query, a.DomainID.Uint(), a.Note, a.ExpiryDate, a.ExpiresAt, a.CreatedAt)
+		return err
+	})
+}
+
+// GetAck looks up the acknowledgement of a domain, returning ErrNoAck without one
+func (r *Repository) GetAck(domainID types.DomainID) (*DomainAck, error) {
+	a, err := r.scanAck(r.db.QueryRow(selectAcks+` WHERE a.domain_id = ?`, domainID.Uint()))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNoAck
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+// GetAcksByUserID lists the acknowledgements of a user's domains by domain name
+func (r *Repository) GetAcksByUserID(userID types.UserID) ([]DomainAck, error) {
+	rows, err := r.db.Query(selectAcks+` WHERE d.user_id = ? ORDER BY d.domain_name`, userID.Uint())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	acks := []DomainAck{}
+	for rows.Next() {
+		a, err := r.scanAck(rows)
+		if err != nil {
+			return nil, err
+		}
+		acks = append(acks, a)
+	}
+	return acks, rows.Err()
+}
+
+// DeleteAck removes the acknowledgement of a domain, returning ErrNoAck without one
+func (r *Repository) DeleteAck(domainID types.DomainID) error {
+	result, err := r.writer.Exec(`DELETE FROM domain_acks WHERE domain_id = ?`, domainID.Uint())
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNoAck
+	}
+	return nil
+}
+
+const selectMaintenanceWindows = `SELECT w.id, w.user_id, w.domain_id, COALESCE(d.domain_name, ''), w.tag, w.start_at, w.schedule,
+              w.duration_seconds, w.note, w.created_at
+              FROM maintenance_windows w LEFT JOIN domains d ON d.id = w.domain_id`
+
+func (r *Repository) scanMaintenanceWindow(row scanner) (MaintenanceWindow, error) {
+	var id, userID uint
+	var domainID sql.NullInt64
+	var domainName, tag, schedule, note string
+	var startAt sql.NullTime
+	var durationSeconds int64
+	var createdAt time.Time
+	if err := row.Scan(&id, &userID, &domainID, &domainName, &tag, &startAt, &schedule, &durationSeconds, &note, &createdAt); err != nil {
+		return MaintenanceWindow{}, err
+	}
+
+	w := MaintenanceWindow{
+		WindowID:   id,
+		UserID:     types.UserID(userID),
+		DomainID:   types.DomainID(domainID.Int64),
+		DomainName: domainName,
+		Tag:        tag,
+		Schedule:   schedule,
+		Duration:   time.Duration(durationSeconds) * time.Second,
+		Note:       note,
+		CreatedAt:  createdAt,
+	}
+	if startAt.Valid {
+		w.Start = &startAt.Time
+	}
+	return w, nil
+}
+
+// CreateMaintenanceWindow stores a new maintenance window
+func (r *Repository) CreateMaintenanceWindow(w *MaintenanceWindow) error {
+	if err := types.ValidateUserID(w.UserID); err != nil {
+		return fmt.Errorf("invalid user ID: %w", err)
+	}
+	if w.CreatedAt.IsZero() {
+		w.CreatedAt = time.Now()
+	}
+	var domainID *uint
+	if w.DomainID != 0 {
+		id := w.DomainID.Uint()
+		domainID = &id
+	}
+
+	query := `INSERT INTO maintenance_windows (user_id, domain_id, tag, start_at, schedule, duration_seconds, note, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := r.writer.Exec(query, w.UserID.Uint(), domainID, w.Tag, w.Start, w.Schedule, int64(w.Duration/time.Second), w.Note, w.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	w.WindowID = uint(id)
+	return nil
+}
+
+// GetMaintenanceWindowsByUserID lists a user's maintenance windows in the order they were created
+func (r *Repository) GetMaintenanceWindowsByUserID(userID types.UserID) ([]MaintenanceWindow, error) {
+	rows, err := r.db.Query(selectMaintenanceWindows+` WHERE w.user_id = ? ORDER BY w.id`, userID.Uint())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	windows := []MaintenanceWindow{}
+	for rows.Next() {
+		w, err := r.scanMaintenanceWindow(rows)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, rows.Err()
+}
+
+// GetMaintenanceWindowsForDomain lists the maintenance windows that may apply to a domain, those set on it and
+// the tag windows of its user
+func (r *Repository) GetMaintenanceWindowsForDomain(d domain.Domain) ([]MaintenanceWindow, error) {
+	rows, err := r.db.Query(selectMaintenanceWindows+` WHERE w.domain_id = ? OR (w.domain_id IS NULL AND w.user_id = ?) ORDER BY w.id`,
+		d.DomainID.Uint(), d.UserID.Uint())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	windows := []MaintenanceWindow{}
+	for rows.Next() {
+		w, err := r.scanMaintenanceWindow(rows)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, rows.Err()
+}
+
+// InMaintenance reports whether a maintenance window is open for a domain at now
+func (r *Repository) InMaintenance(d domain.Domain, now time.Time) (bool, error) {
+	windows, err := r.GetMaintenanceWindowsForDomain(d)
+	if err != nil {
+		return false, fmt.Errorf("failed to get maintenance windows: %w", err)
+	}
+	return InMaintenance(windows, d, now), nil
+}
+
+// GetMaintenanceWindowByID looks up a single maintenance window
+func (r *Repository) GetMaintenanceWindowByID(id uint) (*MaintenanceWindow, error) {
+	w, err := r.scanMaintenanceWindow(r.db.QueryRow(selectMaintenanceWindows+` WHERE w.id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("maintenance window with ID %d not found", id)
+		}
+		return nil, err
+	}
+	return &w, nil
+}
+
+// DeleteMaintenanceWindow removes a maintenance window
+func (r *Repository) DeleteMaintenanceWindow(id uint) error {
+	result, err := r.writer.Exec(`DELETE FROM maintenance_windows WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("maintenance window with ID %d not found", id)
+	}
+	return nil
+}
+
+// LastDigestAt is when the previous digest was sent, nil before the first one
+func (r *Repository) LastDigestAt() (*time.Time, error) {
+	var sentAt time.Time
+	err := r.db.QueryRow(`SELECT sent_at FROM digests ORDER BY id DESC LIMIT 1`).Scan(&sentAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sentAt, nil
+}
+
+// RecordDigest remembers that a digest was sent
+func (r *Repository) RecordDigest(sentAt time.Time) error {
+	_, err := r.writer.Exec(`INSERT INTO digests (sent_at) VALUES (?)`, sentAt)
+	return err
+}
+
+// GetDigestDomains lists the active domains of every user with the start of their current run of failed checks
+func (r *Repository) GetDigestDomains() ([]DigestDomain, error) {
+	// The failing run starts with the first failed check after the latest successful one
+	query := `SELECT d.domain_name, d.expiry_date, d.last_error, d.tags, f.checked_at
+              FROM domains d
+              LEFT JOIN check_history f ON d.last_error IS NOT NULL AND f.id = (
+                  SELECT MIN(h.id) FROM check_history h WHERE h.domain_id = d.id AND h.error IS NOT NULL
+                  AND h.id > COALESCE((SELECT MAX(s.id) FROM check_history s WHERE s.domain_id = d.id AND s.error IS NULL), 0))
+              WHERE d.is_active = 1
+              ORDER BY d.domain_name`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	domains := []DigestDomain{}
+	for rows.Next() {
+		var name, tags string
+		var expiryDate, failingSince sql.NullTime
+		var lastError sql.NullString
+		if err := rows.Scan(&name, &expiryDate, &lastError, &tags, &failingSince); err != nil {
+			return nil, err
+		}
+		dom := DigestDomain{
+			Domain: name,
+			Tags:   domain.ParseTags(tags),
+			Error:  lastError.String,
+		}
+		if expiryDate.Valid {
+			dom.ExpiryDate = &expiryDate.Time
+		}
+		if failingSince.Valid {
+			dom.FailingSince = &failingSince.Time
+		}
+		domains = append(domains, dom)
+	}
+	return domains, rows.Err()
+}
+
+// GetRenewals lists checks since since that found a certificate expiring later than the one checked before
+func (r *Repository) GetRenewals(since time.Time) ([]DigestRenewal, error) {
+	query := `SELECT d.domain_name, h.checked_at, p.expiry_date, h.expiry_date
+              FROM check_history h JOIN domains d ON d.id = h.domain_id
+              JOIN check_history p ON p.id = (
+                  SELECT MAX(q.id) FROM check_history q WHERE q.domain_id = h.domain_id AND q.id < h.id AND q.expiry_date IS NOT NULL)
+              WHERE h.checked_at >= ? AND h.expiry_date IS NOT NULL
+              ORDER BY h.checked_at`
+	// checked_at is written in local time and compared as text
+	rows, err := r.db.Query(query, since.Local())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	renewals := []DigestRenewal{}
+	for rows.Next() {
+		var rn DigestRenewal
+		if err := rows.Scan(&rn.Domain, &rn.RenewedAt, &rn.PreviousExpiry, &rn.ExpiryDate); err != nil {
+			return nil, err
+		}
+		// Expiry dates can be written in different zones, so compare them here rather than as text
+		if rn.ExpiryDate.After(rn.PreviousExpiry) {
+			renewals = append(renewals, rn)
+		}
+	}
+	return renewals, rows.Err()
+}
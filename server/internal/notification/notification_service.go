@@ -0,0 +1,173 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+type Service struct {
+	notificationRepo *Repository
+}
+
+func NewService(notificationRepo *Repository) *Service {
+	return &Service{
+		notificationRepo: notificationRepo,
+	}
+}
+
+// GetUsersNotifications lists sent and pending notifications for a user's domains
+func (s *Service) GetUsersNotifications(userID types.UserID) ([]Notification, error) {
+	return s.notificationRepo.GetNotificationsByUserID(userID)
+}
+
+// GetNotification looks up a single notification
+func (s *Service) GetNotification(id uint) (*Notification, error) {
+	return s.notificationRepo.GetNotificationByID(id)
+}
+
+// Resend puts a notification back in the pending queue so it is delivered again with a fresh set of attempts
+func (s *Service) Resend(id uint) error {
+	if _, err := s.notificationRepo.GetNotificationByID(id); err != nil {
+		return fmt.Errorf("failed to get notification: %w", err)
+	}
+	return s.notificationRepo.UpdateStatus(id, StatusPending, nil)
+}
+
+// GetAttempts lists the delivery attempts of a notification, oldest first
+func (s *Service) GetAttempts(id uint) ([]DeliveryAttempt, error) {
+	if _, err := s.notificationRepo.GetNotificationByID(id); err != nil {
+		return nil, fmt.Errorf("failed to get notification: %w", err)
+	}
+	return s.notificationRepo.GetAttempts(id)
+}
+
+// Acknowledge marks a notification as seen so it no longer needs attention
+func (s *Service) Acknowledge(id uint) error {
+	n, err := s.notificationRepo.GetNotificationByID(id)
+	if err != nil {
+		return fmt.Errorf("failed to get notification: %w", err)
+	}
+	if n.Status == StatusAcknowledged {
+		return nil
+	}
+	return s.notificationRepo.UpdateStatus(id, StatusAcknowledged, nil)
+}
+
+// AddRule stores a new rule for a user, enabled unless it says otherwise
+func (s *Service) AddRule(userID types.UserID, rule *Rule) error {
+	rule.UserID = userID
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	return s.notificationRepo.CreateRule(rule)
+}
+
+// GetUsersRules lists a user's notification rules
+func (s *Service) GetUsersRules(userID types.UserID) ([]Rule, error) {
+	return s.notificationRepo.GetRulesByUserID(userID)
+}
+
+// SetRuleEnabled switches one of a user's rules on or off
+func (s *Service) SetRuleEnabled(userID types.UserID, id uint, enabled bool) error {
+	if _, err := s.usersRule(userID, id); err != nil {
+		return err
+	}
+	return s.notificationRepo.SetRuleEnabled(id, enabled)
+}
+
+// RemoveRule deletes one of a user's rules
+func (s *Service) RemoveRule(userID types.UserID, id uint) error {
+	if _, err := s.usersRule(userID, id); err != nil {
+		return err
+	}
+	return s.notificationRepo.DeleteRule(id)
+}
+
+// usersRule looks up a rule, treating other users' rules as missing
+func (s *Service) usersRule(userID types.UserID, id uint) (*Rule, error) {
+	rule, err := s.notificationRepo.GetRuleByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if rule.UserID != userID {
+		return nil, fmt.Errorf("rule with ID %d not found", id)
+	}
+	return rule, nil
+}
+
+// AcknowledgeDomain silences a domain's notifications until its certificate changes or, when expiresAt
+// is set, until then. A new acknowledgement replaces the domain's earlier one
+func (s *Service) AcknowledgeDomain(d domain.Domain, note string, expiresAt *time.Time) (*DomainAck, error) {
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, errors.New("acknowledgement must expire in the future")
+	}
+	note = strings.TrimSpace(note)
+	if len(note) > MaxAckNoteLength {
+		return nil, fmt.Errorf("acknowledgement note is longer than %d characters", MaxAckNoteLength)
+	}
+
+	a := DomainAck{
+		DomainID:   d.DomainID,
+		DomainName: d.DomainName.String(),
+		Note:       note,
+		ExpiryDate: d.ExpiryTime(),
+		ExpiresAt:  expiresAt,
+	}
+	if err := s.notificationRepo.SetAck(&a); err != nil {
+		return nil, fmt.Errorf("failed to acknowledge domain: %w", err)
+	}
+	return &a, nil
+}
+
+// GetDomainAck looks up a domain's acknowledgement, returning ErrNoAck without one
+func (s *Service) GetDomainAck(domainID types.DomainID) (*DomainAck, error) {
+	return s.notificationRepo.GetAck(domainID)
+}
+
+// GetUsersAcks lists the acknowledgements of a user's domains, including ones that have ended
+func (s *Service) GetUsersAcks(userID types.UserID) ([]DomainAck, error) {
+	return s.notificationRepo.GetAcksByUserID(userID)
+}
+
+// RemoveAck lets a domain notify again, returning ErrNoAck if it wasn't acknowledged
+func (s *Service) RemoveAck(domainID types.DomainID) error {
+	return s.notificationRepo.DeleteAck(domainID)
+}
+
+// AddMaintenanceWindow stores a new maintenance window for a user, whose domain the caller has checked they may see
+func (s *Service) AddMaintenanceWindow(userID types.UserID, w *MaintenanceWindow) error {
+	w.UserID = userID
+	w.Tag = strings.ToLower(strings.TrimSpace(w.Tag))
+	w.Note = strings.TrimSpace(w.Note)
+	if err := w.Validate(); err != nil {
+		return err
+	}
+	return s.notificationRepo.CreateMaintenanceWindow(w)
+}
+
+// GetUsersMaintenanceWindows lists a user's maintenance windows, including ones that have ended
+func (s *Service) GetUsersMaintenanceWindows(userID types.UserID) ([]MaintenanceWindow, error) {
+	return s.notificationRepo.GetMaintenanceWindowsByUserID(userID)
+}
+
+// RemoveMaintenanceWindow deletes one of a user's maintenance windows
+func (s *Service) RemoveMaintenanceWindow(userID types.UserID, id uint) error {
+	w, err := s.notificationRepo.GetMaintenanceWindowByID(id)
+	if err != nil {
+		return err
+	}
+	if w.UserID != userID {
+		return fmt.Errorf("maintenance window with ID %d not found", id)
+	}
+	return s.notificationRepo.DeleteMaintenanceWindow(id)
+}
+
+// InMaintenance reports whether a maintenance window is open for a domain at now
+func (s *Service) InMaintenance(d domain.Domain, now time.Time) (bool, error) {
+	return s.notificationRepo.InMaintenance(d, now)
+}
@@ -0,0 +1,102 @@
+package notification
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDB creates a migrated SQLite database with one domain for user 1.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`INSERT INTO domains (id, user_id, domain_name, created_at) VALUES (1, 1, 'example.com', ?)`, time.Now())
+	require.NoError(t, err)
+	return db
+}
+
+// TestNotificationType - basic creation and String() method.
+func TestNotificationType(t *testing.T) {
+	nt := NewNotificationType("slack")
+	assert.Equal(t, NotificationTypeSlack, nt)
+	assert.Equal(t, "slack", nt.String())
+}
+
+// TestNotificationStatus - basic creation and String() method.
+func TestNotificationStatus(t *testing.T) {
+	status := NewNotificationStatus("sent")
+	assert.Equal(t, StatusSent, status)
+	assert.Equal(t, "sent", status.String())
+}
+
+// TestRepository_CreateAndList - created notifications are listed with their domain name.
+func TestRepository_CreateAndList(t *testing.T) {
+	repo := NewRepository(newTestDB(t))
+
+	n := Notification{
+		DomainID:         types.DomainID(1),
+		DaysBefore:       7,
+		NotificationType: NotificationTypeEmail,
+	}
+	require.NoError(t, repo.CreateNotification(&n))
+	assert.NotZero(t, n.NotificationID)
+
+	notifications, err := repo.GetNotificationsByUserID(types.UserID(1))
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+
+	got := notifications[0]
+	assert.Equal(t, "example.com", got.DomainName)
+	assert.Equal(t, StatusPending, got.Status)
+	assert.Equal(t, 7, got.DaysBefore)
+	assert.Nil(t, got.SentAt)
+}
+
+// TestRepository_CreateInvalidDomain - zero domain IDs are rejected.
+func TestRepository_CreateInvalidDomain(t *testing.T) {
+	repo := NewRepository(newTestDB(t))
+
+	err := repo.CreateNotification(&Notification{NotificationType: NotificationTypeSlack})
+	assert.Error(t, err)
+}
+
+// TestService_ResendAndAcknowledge - resend requeues, acknowledge records the time.
+func TestService_ResendAndAcknowledge(t *testing.T) {
+	repo := NewRepository(newTestDB(t))
+	service := NewService(repo)
+
+	n := Notification{DomainID: types.DomainID(1), DaysBefore: 30, NotificationType: NotificationTypeSlack}
+	require.NoError(t, repo.CreateNotification(&n))
+
+	errMsg := "webhook returned 500"
+	require.NoError(t, repo.UpdateStatus(n.NotificationID, StatusFailed, &errMsg))
+
+	require.NoError(t, service.Resend(n.NotificationID))
+	got, err := repo.GetNotificationByID(n.NotificationID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, got.Status)
+
+	require.NoError(t, service.Acknowledge(n.NotificationID))
+	got, err = repo.GetNotificationByID(n.NotificationID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusAcknowledged, got.Status)
+	assert.NotNil(t, got.AcknowledgedAt)
+}
+
+// TestService_UnknownNotification - operations on missing notifications fail.
+func TestService_UnknownNotification(t *testing.T) {
+	service := NewService(NewRepository(newTestDB(t)))
+
+	assert.Error(t, service.Resend(42))
+	assert.Error(t, service.Acknowledge(42))
+}
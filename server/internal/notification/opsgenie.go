@@ -0,0 +1,89 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OpsgenieAPIURL is the Opsgenie API for accounts in the US region, EU accounts use https://api.eu.opsgenie.com
+const OpsgenieAPIURL = "https://api.opsgenie.com"
+
+// OpsgenieProvider creates and closes Opsgenie alerts, using the dedup key as the alert alias
+type OpsgenieProvider struct {
+	apiKey     string
+	apiURL     string
+	httpClient *http.Client
+}
+
+// NewOpsgenieProvider creates a provider for an API integration key, an empty apiURL uses OpsgenieAPIURL
+// and a nil httpClient uses one with a 30 second timeout
+func NewOpsgenieProvider(apiKey, apiURL string, httpClient *http.Client) (*OpsgenieProvider, error) {
+	if apiKey == "" {
+		return nil, errors.New("opsgenie API key is required")
+	}
+	if apiURL == "" {
+		apiURL = OpsgenieAPIURL
+	}
+	if err := validateWebhookURL(apiURL); err != nil {
+		return nil, err
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: webhookTimeout}
+	}
+	return &OpsgenieProvider{
+		apiKey:     apiKey,
+		apiURL:     strings.TrimSuffix(apiURL, "/"),
+		httpClient: httpClient,
+	}, nil
+}
+
+func (p *OpsgenieProvider) Name() string {
+	return NotificationTypeOpsgenie.String()
+}
+
+// Trigger creates an alert, Opsgenie counts repeats of an open alert with the same alias instead of opening another
+func (p *OpsgenieProvider) Trigger(ctx context.Context, incident Incident) error {
+	priority := "P2"
+	if incident.Status == "expired" {
+		priority = "P1"
+	}
+	details := map[string]string{
+		"domain": incident.Domain,
+		"status": incident.Status,
+	}
+	if incident.ExpiryDate != nil {
+		details["expiry_date"] = incident.ExpiryDate.UTC().Format(time.RFC3339)
+	}
+	description := incident.Summary
+	if incident.Error != nil {
+		details["error"] = *incident.Error
+		description += "\n\n" + *incident.Error
+	}
+
+	return postJSON(ctx, p.httpClient, p.apiURL+"/v2/alerts", map[string]any{
+		"message":     incident.Summary,
+		"alias":       incident.DedupKey,
+		"description": description,
+		"priority":    priority,
+		"source":      "sslcerttop",
+		"entity":      incident.Domain,
+		"tags":        incident.Tags,
+		"details":     details,
+	}, p.header())
+}
+
+// Resolve closes the alert with the dedup key as its alias
+func (p *OpsgenieProvider) Resolve(ctx context.Context, dedupKey string) error {
+	target := p.apiURL + "/v2/alerts/" + url.PathEscape(dedupKey) + "/close?identifierType=alias"
+	return postJSON(ctx, p.httpClient, target, map[string]any{"source": "sslcerttop"}, p.header())
+}
+
+func (p *OpsgenieProvider) header() http.Header {
+	header := http.Header{}
+	header.Set("Authorization", "GenieKey "+p.apiKey)
+	return header
+}
@@ -0,0 +1,81 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// PagerDutyEventsURL is the PagerDuty Events API v2 endpoint
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDutyProvider sends incidents to a PagerDuty service through the Events API v2
+type PagerDutyProvider struct {
+	routingKey string
+	eventsURL  string
+	httpClient *http.Client
+}
+
+// NewPagerDutyProvider creates a provider for the service with the integration routing key,
+// a nil httpClient uses one with a 30 second timeout
+func NewPagerDutyProvider(routingKey string, httpClient *http.Client) (*PagerDutyProvider, error) {
+	if routingKey == "" {
+		return nil, errors.New("pagerduty routing key is required")
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: webhookTimeout}
+	}
+	return &PagerDutyProvider{
+		routingKey: routingKey,
+		eventsURL:  PagerDutyEventsURL,
+		httpClient: httpClient,
+	}, nil
+}
+
+func (p *PagerDutyProvider) Name() string {
+	return NotificationTypePagerDuty.String()
+}
+
+// Trigger sends a trigger event, PagerDuty groups repeated ones with the same dedup key into one incident
+func (p *PagerDutyProvider) Trigger(ctx context.Context, incident Incident) error {
+	severity := "error"
+	if incident.Status == "expired" {
+		severity = "critical"
+	}
+	details := map[string]any{
+		"domain": incident.Domain,
+		"status": incident.Status,
+	}
+	if incident.ExpiryDate != nil {
+		details["expiry_date"] = incident.ExpiryDate.UTC().Format(time.RFC3339)
+	}
+	if incident.Error != nil {
+		details["error"] = *incident.Error
+	}
+	if len(incident.Tags) > 0 {
+		details["tags"] = incident.Tags
+	}
+
+	return postJSON(ctx, p.httpClient, p.eventsURL, map[string]any{
+		"routing_key":  p.routingKey,
+		"event_action": "trigger",
+		"dedup_key":    incident.DedupKey,
+		"payload": map[string]any{
+			"summary":        incident.Summary,
+			"source":         incident.Domain,
+			"severity":       severity,
+			"component":      "ssl-certificate",
+			"custom_details": details,
+		},
+	}, nil)
+}
+
+// Resolve sends a resolve event for the dedup key
+func (p *PagerDutyProvider) Resolve(ctx context.Context, dedupKey string) error {
+	return postJSON(ctx, p.httpClient, p.eventsURL, map[string]any{
+		"routing_key":  p.routingKey,
+		"event_action": "resolve",
+		"dedup_key":    dedupKey,
+	}, nil)
+}
@@ -0,0 +1,77 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// QuietHours holds back non-critical notifications during a daily window and delivers them once it ends
+type QuietHours struct {
+	// start and end are minutes after midnight, the window wraps past midnight when end is before start
+	start, end int
+	// criticalDays is the threshold at or below which notifications are delivered regardless
+	criticalDays int
+}
+
+// NewQuietHours creates a window from start to end given as 15:04 in the daemon's local time.
+//
+// Notifications at criticalDays or fewer, including failing checks, are never held back
+func NewQuietHours(start, end string, criticalDays int) (*QuietHours, error) {
+	s, err := minuteOfDay(start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quiet hours start: %w", err)
+	}
+	e, err := minuteOfDay(end)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quiet hours end: %w", err)
+	}
+	if s == e {
+		return nil, errors.New("quiet hours must not start and end at the same time")
+	}
+	return &QuietHours{start: s, end: e, criticalDays: criticalDays}, nil
+}
+
+// Contains reports whether t falls within the window
+func (q *QuietHours) Contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if q.start < q.end {
+		return m >= q.start && m < q.end
+	}
+	return m >= q.start || m < q.end
+}
+
+// Holds reports whether delivery of a notification waits for the window to end
+func (q *QuietHours) Holds(n Notification, t time.Time) bool {
+	return n.DaysBefore > q.criticalDays && q.Contains(t)
+}
+
+func minuteOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM, got %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Escalation re-sends a notification at Threshold over Channel when it is still unacknowledged After it was sent
+type Escalation struct {
+	Threshold int
+	After     time.Duration
+	Channel   NotificationType
+}
+
+// Validate reports escalations that could never fire
+func (e Escalation) Validate() error {
+	if e.Threshold < 0 {
+		return fmt.Errorf("escalation threshold must not be negative, got %d", e.Threshold)
+	}
+	if e.After <= 0 {
+		return fmt.Errorf("escalation delay must be positive, got %s", e.After)
+	}
+	if !slices.Contains(NotificationTypes, e.Channel) {
+		return fmt.Errorf("unknown escalation channel %q", e.Channel)
+	}
+	return nil
+}
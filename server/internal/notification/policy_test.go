@@ -0,0 +1,57 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestQuietHours_Contains - windows may wrap past midnight.
+func TestQuietHours_Contains(t *testing.T) {
+	night, err := NewQuietHours("22:00", "07:00", 1)
+	require.NoError(t, err)
+	lunch, err := NewQuietHours("12:00", "13:30", 1)
+	require.NoError(t, err)
+
+	at := func(hour, minute int) time.Time {
+		return time.Date(2025, time.March, 14, hour, minute, 0, 0, time.Local)
+	}
+	assert.True(t, night.Contains(at(23, 0)))
+	assert.True(t, night.Contains(at(6, 59)))
+	assert.False(t, night.Contains(at(7, 0)))
+	assert.False(t, night.Contains(at(21, 59)))
+	assert.True(t, lunch.Contains(at(13, 0)))
+	assert.False(t, lunch.Contains(at(13, 30)))
+}
+
+// TestQuietHours_Holds - critical thresholds and failing checks are never held back.
+func TestQuietHours_Holds(t *testing.T) {
+	q, err := NewQuietHours("22:00", "07:00", 1)
+	require.NoError(t, err)
+	night := time.Date(2025, time.March, 14, 23, 0, 0, 0, time.Local)
+
+	assert.True(t, q.Holds(Notification{DaysBefore: 7}, night))
+	assert.False(t, q.Holds(Notification{DaysBefore: 1}, night))
+	assert.False(t, q.Holds(Notification{DaysBefore: ErrorThreshold}, night))
+	assert.False(t, q.Holds(Notification{DaysBefore: 7}, night.Add(9*time.Hour)))
+}
+
+// TestNewQuietHours_Invalid - times must be HH:MM and the window can't be empty.
+func TestNewQuietHours_Invalid(t *testing.T) {
+	_, err := NewQuietHours("10pm", "07:00", 1)
+	assert.Error(t, err)
+	_, err = NewQuietHours("22:00", "24:00", 1)
+	assert.Error(t, err)
+	_, err = NewQuietHours("22:00", "22:00", 1)
+	assert.Error(t, err)
+}
+
+// TestEscalation_Validate - escalations need a delay and a known channel.
+func TestEscalation_Validate(t *testing.T) {
+	assert.NoError(t, Escalation{Threshold: 7, After: 24 * time.Hour, Channel: NotificationTypePagerDuty}.Validate())
+	assert.Error(t, Escalation{Threshold: 7, Channel: NotificationTypePagerDuty}.Validate())
+	assert.Error(t, Escalation{Threshold: 7, After: time.Hour, Channel: "sms"}.Validate())
+	assert.Error(t, Escalation{Threshold: -1, After: time.Hour, Channel: NotificationTypeSlack}.Validate())
+}
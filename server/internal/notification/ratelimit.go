@@ -0,0 +1,124 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRateWait is the longest a queued notification waits for its channel's rate limit, those that would wait
+// longer stay queued for a later delivery
+const maxRateWait = 5 * time.Second
+
+// RateLimit caps a channel at Count notifications every Per
+type RateLimit struct {
+	Count int
+	Per   time.Duration
+}
+
+// ParseRateLimit reads a limit such as 1/s, 30/m, 100/h or 5/10s
+func ParseRateLimit(s string) (RateLimit, error) {
+	count, per, ok := strings.Cut(strings.TrimSpace(s), "/")
+	n, err := strconv.Atoi(count)
+	if !ok || err != nil || n < 1 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, expected a count and a period such as 1/s or 30/m", s)
+	}
+	// A bare unit is one of it
+	if per != "" && (per[0] < '0' || per[0] > '9') {
+		per = "1" + per
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, expected a count and a period such as 1/s or 30/m", s)
+	}
+	return RateLimit{Count: n, Per: d}, nil
+}
+
+func (r RateLimit) String() string {
+	return fmt.Sprintf("%d/%s", r.Count, r.Per)
+}
+
+// RateLimiter spaces out the notifications of each channel to its rate limit. Everything sending notifications
+// shares one, so their notifications add up
+type RateLimiter struct {
+	limits map[NotificationType]RateLimit
+	now    func() time.Time
+	mu     sync.Mutex
+	// sent are when the notifications of each channel within its last period went out
+	sent map[NotificationType][]time.Time
+}
+
+// NewRateLimiter limits the channels in limits, the others aren't limited
+func NewRateLimiter(limits map[NotificationType]RateLimit) *RateLimiter {
+	return &RateLimiter{limits: limits, now: time.Now, sent: make(map[NotificationType][]time.Time)}
+}
+
+// Delay is how long until the channel may send again, zero when it may now
+func (l *RateLimiter) Delay(channel NotificationType) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.delay(channel, l.now())
+}
+
+// delay is Delay with l.mu held, forgetting the sends that fell out of the channel's period
+func (l *RateLimiter) delay(channel NotificationType, now time.Time) time.Duration {
+	limit, ok := l.limits[channel]
+	if !ok {
+		return 0
+	}
+	sent := l.sent[channel]
+	for len(sent) > 0 && !now.Before(sent[0].Add(limit.Per)) {
+		sent = sent[1:]
+	}
+	l.sent[channel] = sent
+	if len(sent) < limit.Count {
+		return 0
+	}
+	return sent[len(sent)-limit.Count].Add(limit.Per).Sub(now)
+}
+
+// Wait blocks until the channel may send and counts the notification about to go out
+func (l *RateLimiter) Wait(ctx context.Context, channel NotificationType) error {
+	for {
+		l.mu.Lock()
+		now := l.now()
+		wait := l.delay(channel, now)
+		if wait <= 0 {
+			if _, ok := l.limits[channel]; ok {
+				l.sent[channel] = append(l.sent[channel], now)
+			}
+			l.mu.Unlock()
+			return nil
+		}
+		l.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Limit has a sender wait for its channel's rate limit before each delivery
+func (l *RateLimiter) Limit(s Sender) Sender {
+	return &rateLimitedSender{Sender: s, limiter: l}
+}
+
+// rateLimitedSender waits for its channel's rate limit before each delivery
+type rateLimitedSender struct {
+	Sender
+	limiter *RateLimiter
+}
+
+func (s *rateLimitedSender) Send(ctx context.Context, n Notification) error {
+	if err := s.limiter.Wait(ctx, s.Type()); err != nil {
+		return err
+	}
+	return s.Sender.Send(ctx, n)
+}
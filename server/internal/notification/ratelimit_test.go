@@ -0,0 +1,65 @@
+package notification
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseRateLimit - a bare unit is one of it.
+func TestParseRateLimit(t *testing.T) {
+	limit, err := ParseRateLimit("1/s")
+	require.NoError(t, err)
+	assert.Equal(t, RateLimit{Count: 1, Per: time.Second}, limit)
+
+	limit, err = ParseRateLimit("5/10m")
+	require.NoError(t, err)
+	assert.Equal(t, RateLimit{Count: 5, Per: 10 * time.Minute}, limit)
+
+	for _, invalid := range []string{"", "1", "0/s", "x/s", "1/", "1/fortnight"} {
+		_, err := ParseRateLimit(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+// TestDispatcher_RateLimit - notifications beyond a channel's limit stay queued, across restarts too.
+func TestDispatcher_RateLimit(t *testing.T) {
+	repo := NewRepository(newTestDB(t))
+	slack := &fakeSender{nType: NotificationTypeSlack}
+	teams := &fakeSender{nType: NotificationTypeTeams}
+	now := time.Now()
+	limits := map[NotificationType]RateLimit{NotificationTypeSlack: {Count: 2, Per: time.Hour}}
+	newDispatcher := func() *Dispatcher {
+		d := NewDispatcher(repo, DefaultThresholds, slack, teams)
+		d.now = func() time.Time { return now }
+		limiter := NewRateLimiter(limits)
+		limiter.now = d.now
+		d.SetRateLimiter(limiter)
+		return d
+	}
+
+	for _, days := range []int{30, 7, 1} {
+		for _, channel := range []NotificationType{NotificationTypeSlack, NotificationTypeTeams} {
+			require.NoError(t, repo.CreateNotification(&Notification{DomainID: types.DomainID(1), DaysBefore: days, NotificationType: channel}))
+		}
+	}
+
+	d := newDispatcher()
+	require.NoError(t, d.DeliverPending(context.Background()))
+	assert.Len(t, slack.sent, 2)
+	assert.Len(t, teams.sent, 3, "Channels without a limit aren't held up")
+
+	pending, err := repo.GetPendingNotifications()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, NotificationTypeSlack, pending[0].NotificationType)
+
+	// A restarted daemon starts with fresh limits and delivers what is still queued
+	d = newDispatcher()
+	require.NoError(t, d.DeliverPending(context.Background()))
+	assert.Len(t, slack.sent, 3)
+}
@@ -0,0 +1,122 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// ErrorThreshold is the DaysBefore of notifications about failing checks rather than an expiry threshold
+const ErrorThreshold = -1
+
+// RenewalOverdueThreshold is the DaysBefore of notifications that a certificate expected to renew automatically
+// wasn't renewed in time
+const RenewalOverdueThreshold = -2
+
+// Rule decides which domains notify over a channel and when.
+//
+// Once a user has an enabled rule, rules replace the global thresholds for that user's domains
+type Rule struct {
+	RuleID  uint             `db:"id"`
+	UserID  types.UserID     `db:"user_id"`
+	Name    string           `db:"name"`
+	Channel NotificationType `db:"channel"`
+	// Thresholds are days before expiry to notify at, zero meaning expired
+	Thresholds []int `db:"thresholds"`
+	// OnError notifies once each time checks of a domain start failing
+	OnError bool `db:"on_error"`
+	// Tags limit the rule to domains with any of them, empty matches every domain
+	Tags      []string  `db:"tags"`
+	Enabled   bool      `db:"enabled"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// Validate reports rules that could never notify
+func (r Rule) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return errors.New("rule name cannot be empty")
+	}
+	if !slices.Contains(NotificationTypes, r.Channel) {
+		return fmt.Errorf("unknown rule channel %q", r.Channel)
+	}
+	if len(r.Thresholds) == 0 && !r.OnError {
+		return errors.New("rule needs thresholds or on error")
+	}
+	for _, t := range r.Thresholds {
+		if t < 0 {
+			return fmt.Errorf("rule thresholds must not be negative, got %d", t)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the rule applies to a domain
+func (r Rule) Matches(d domain.Domain) bool {
+	if len(r.Tags) == 0 {
+		return true
+	}
+	for _, t := range r.Tags {
+		if d.HasTag(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// CrossedThreshold returns the tightest of the rule's thresholds a certificate expiring at expiry has crossed
+func (r Rule) CrossedThreshold(expiry time.Time, now time.Time) (int, bool) {
+	return CrossedThreshold(r.Thresholds, expiry, now)
+}
+
+// FormatThresholds joins thresholds for display and storage, e.g. "30,7,0"
+func FormatThresholds(thresholds []int) string {
+	parts := make([]string, len(thresholds))
+	for i, t := range thresholds {
+		parts[i] = strconv.Itoa(t)
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseThresholds parses comma separated days, largest first and without duplicates
+func ParseThresholds(s string) ([]int, error) {
+	seen := map[int]bool{}
+	var thresholds []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		t, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid threshold %q", part)
+		}
+		if !seen[t] {
+			seen[t] = true
+			thresholds = append(thresholds, t)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(thresholds)))
+	return thresholds, nil
+}
+
+// CrossedThreshold returns the tightest of thresholds that something expiring at expiry has crossed
+func CrossedThreshold(thresholds []int, expiry time.Time, now time.Time) (int, bool) {
+	daysLeft := int(expiry.Sub(now).Hours() / 24)
+	if expiry.Before(now) {
+		daysLeft = -1
+	}
+	crossed, found := 0, false
+	for _, t := range thresholds {
+		if daysLeft <= t && (!found || t < crossed) {
+			crossed, found = t, true
+		}
+	}
+	return crossed, found
+}
@@ -0,0 +1,82 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseThresholds - days are deduplicated and sorted largest first.
+func TestParseThresholds(t *testing.T) {
+	got, err := ParseThresholds(" 7,30, 7,0,")
+	require.NoError(t, err)
+	assert.Equal(t, []int{30, 7, 0}, got)
+	assert.Equal(t, "30,7,0", FormatThresholds(got))
+
+	got, err = ParseThresholds("")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	_, err = ParseThresholds("30,soon")
+	assert.Error(t, err)
+}
+
+// TestRule_Validate - rules need a name, a known channel and something to notify on.
+func TestRule_Validate(t *testing.T) {
+	valid := Rule{Name: "slack", Channel: NotificationTypeSlack, Thresholds: []int{30}}
+	assert.NoError(t, valid.Validate())
+
+	errorsOnly := Rule{Name: "page", Channel: NotificationTypePagerDuty, OnError: true}
+	assert.NoError(t, errorsOnly.Validate())
+
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"no name", Rule{Channel: NotificationTypeSlack, Thresholds: []int{30}}},
+		{"unknown channel", Rule{Name: "sms", Channel: "sms", Thresholds: []int{30}}},
+		{"nothing to notify on", Rule{Name: "slack", Channel: NotificationTypeSlack}},
+		{"negative threshold", Rule{Name: "slack", Channel: NotificationTypeSlack, Thresholds: []int{-1}}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Error(t, tc.rule.Validate())
+		})
+	}
+}
+
+// TestService_Rules - rules are stored per user and other users' rules can't be changed.
+func TestService_Rules(t *testing.T) {
+	db := newTestDB(t)
+	_, err := db.Exec(`INSERT INTO users (id, username) VALUES (2, 'other')`)
+	require.NoError(t, err)
+	s := NewService(NewRepository(db))
+
+	rule := Rule{Name: "slack", Channel: NotificationTypeSlack, Thresholds: []int{30, 7}, Tags: []string{"Prod"}, Enabled: true}
+	require.NoError(t, s.AddRule(types.UserID(1), &rule))
+	assert.NotZero(t, rule.RuleID)
+
+	rules, err := s.GetUsersRules(types.UserID(1))
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, []int{30, 7}, rules[0].Thresholds)
+	assert.Equal(t, []string{"prod"}, rules[0].Tags)
+	assert.True(t, rules[0].Enabled)
+
+	assert.Error(t, s.SetRuleEnabled(types.UserID(2), rule.RuleID, false))
+	assert.Error(t, s.RemoveRule(types.UserID(2), rule.RuleID))
+
+	require.NoError(t, s.SetRuleEnabled(types.UserID(1), rule.RuleID, false))
+	rules, err = s.GetUsersRules(types.UserID(1))
+	require.NoError(t, err)
+	assert.False(t, rules[0].Enabled)
+
+	require.NoError(t, s.RemoveRule(types.UserID(1), rule.RuleID))
+	rules, err = s.GetUsersRules(types.UserID(1))
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+
+	assert.Error(t, s.AddRule(types.UserID(1), &Rule{Name: "bad", Channel: "sms", Thresholds: []int{1}}))
+}
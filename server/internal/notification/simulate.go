@@ -0,0 +1,55 @@
+package notification
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+)
+
+// SimulatedAlert is an alert a simulation found a domain would call for
+type SimulatedAlert struct {
+	Alert
+	Domain domain.Domain
+	// Covered is set when a notification queued already covers the alert, so it wouldn't be queued again
+	Covered bool
+}
+
+// Describe says what the alert is about, e.g. "7 days before expiry"
+func (a SimulatedAlert) Describe() string {
+	switch a.Threshold {
+	case ErrorThreshold:
+		return "failing check"
+	case RenewalOverdueThreshold:
+		return "overdue renewal"
+	case 0:
+		return "expired"
+	default:
+		return fmt.Sprintf("%d days before expiry", a.Threshold)
+	}
+}
+
+// Simulate works out the alerts EvaluateDomain would queue for each domain at at, without queueing any, to try
+// rule changes out safely. The domains are taken as they are, as if no check ran until then, and acknowledgements,
+// maintenance windows and reminders are applied as of at
+func (d *Dispatcher) Simulate(domains []domain.Domain, at time.Time) ([]SimulatedAlert, error) {
+	var simulated []SimulatedAlert
+	for _, dom := range domains {
+		alerts, err := d.domainAlerts(dom, at)
+		if err != nil {
+			return nil, fmt.Errorf("failed to simulate %s: %w", dom.DomainName.String(), err)
+		}
+		// Without rules every channel is notified, in no particular order
+		slices.SortStableFunc(alerts, func(a, b Alert) int { return strings.Compare(a.Channel.String(), b.Channel.String()) })
+		for _, a := range alerts {
+			covered, err := d.covered(a, at)
+			if err != nil {
+				return nil, err
+			}
+			simulated = append(simulated, SimulatedAlert{Alert: a, Domain: dom, Covered: covered})
+		}
+	}
+	return simulated, nil
+}
@@ -0,0 +1,102 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TeamsSender posts notifications to a Microsoft Teams incoming webhook as Adaptive Cards
+type TeamsSender struct {
+	webhookURL string
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewTeamsSender creates a sender for a Teams webhook, a nil httpClient uses one with a 30 second timeout.
+//
+// Returns an error if the webhook URL isn't an absolute http(s) URL
+func NewTeamsSender(webhookURL string, httpClient *http.Client) (*TeamsSender, error) {
+	if err := validateWebhookURL(webhookURL); err != nil {
+		return nil, err
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: webhookTimeout}
+	}
+	return &TeamsSender{
+		webhookURL: webhookURL,
+		httpClient: httpClient,
+		now:        time.Now,
+	}, nil
+}
+
+func (s *TeamsSender) Type() NotificationType {
+	return NotificationTypeTeams
+}
+
+// Send posts the notification as a card
+func (s *TeamsSender) Send(ctx context.Context, n Notification) error {
+	return postJSON(ctx, s.httpClient, s.webhookURL, teamsMessage(newMessageData(n, s.now())), nil)
+}
+
+// teamsMessage builds the webhook payload wrapping an Adaptive Card
+func teamsMessage(data messageData) map[string]any {
+	style := "warning"
+	if data.Failing || data.Expired || data.DaysLeft <= 7 {
+		style = "attention"
+	}
+	facts := []any{
+		map[string]string{"title": "Domain", "value": data.Domain},
+	}
+	if len(data.SharedWith) > 0 {
+		facts = append(facts, map[string]string{"title": "Same certificate", "value": strings.Join(data.SharedWith, ", ")})
+	}
+	facts = append(facts, map[string]string{"title": "Expires", "value": data.Expires()})
+	if data.Failing {
+		facts = append(facts, map[string]string{"title": "Error", "value": data.Error})
+	} else if data.RenewalOverdue {
+		facts = append(facts, map[string]string{"title": "Renews", "value": data.renews()})
+	} else {
+		facts = append(facts, map[string]string{"title": "Threshold", "value": fmt.Sprintf("%d days", data.Threshold)})
+	}
+
+	details := map[string]any{
+		"type":  "FactSet",
+		"facts": facts,
+	}
+	if data.body != "" {
+		details = map[string]any{
+			"type": "TextBlock",
+			"text": data.body,
+			"wrap": true,
+		}
+	}
+
+	card := map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body": []any{
+			map[string]any{
+				"type":   "TextBlock",
+				"text":   data.Title(),
+				"weight": "Bolder",
+				"size":   "Medium",
+				"color":  style,
+				"wrap":   true,
+			},
+			details,
+		},
+	}
+	return map[string]any{
+		"type": "message",
+		"attachments": []any{
+			map[string]any{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content":     card,
+			},
+		},
+	}
+}
@@ -0,0 +1,68 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewTeamsSender - only absolute http(s) webhook URLs are accepted.
+func TestNewTeamsSender(t *testing.T) {
+	_, err := NewTeamsSender("", nil)
+	assert.Error(t, err)
+
+	_, err = NewTeamsSender("ftp://example.com/hook", nil)
+	assert.Error(t, err)
+
+	s, err := NewTeamsSender("https://example.webhook.office.com/webhookb2/x", nil)
+	require.NoError(t, err)
+	assert.Equal(t, NotificationTypeTeams, s.Type())
+}
+
+// TestTeamsSender_Send - the notification is posted as an Adaptive Card.
+func TestTeamsSender_Send(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	s, err := NewTeamsSender(server.URL, server.Client())
+	require.NoError(t, err)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	expiry := now.Add(3*24*time.Hour + time.Hour)
+	require.NoError(t, s.Send(context.Background(), Notification{DomainName: "example.com", ExpiryDate: &expiry, DaysBefore: 7}))
+
+	attachments := got["attachments"].([]any)
+	require.Len(t, attachments, 1)
+	attachment := attachments[0].(map[string]any)
+	assert.Equal(t, "application/vnd.microsoft.card.adaptive", attachment["contentType"])
+	body := attachment["content"].(map[string]any)["body"].([]any)
+	title := body[0].(map[string]any)
+	assert.Equal(t, "SSL certificate for example.com expires in 3 days", title["text"])
+	assert.Equal(t, "attention", title["color"])
+}
+
+// TestTeamsSender_Failure - non-2xx answers fail the delivery with the server's message.
+func TestTeamsSender_Failure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Webhook message delivery failed", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	s, err := NewTeamsSender(server.URL, server.Client())
+	require.NoError(t, err)
+	err = s.Send(context.Background(), Notification{DomainName: "example.com", DaysBefore: 0})
+	assert.ErrorContains(t, err, "400")
+	assert.ErrorContains(t, err, "delivery failed")
+}
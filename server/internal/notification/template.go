@@ -0,0 +1,126 @@
+package notification
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateExt is the extension of template files, named after their channel, e.g. email.tmpl
+const templateExt = ".tmpl"
+
+// templateFuncs are the functions available to custom templates besides the text/template builtins
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// Templates override the message of a channel with a text/template per channel.
+//
+// The template renders the message body, a nested {{define "subject"}} overrides the subject or heading
+type Templates struct {
+	byChannel    map[NotificationType]*template.Template
+	dashboardURL string
+}
+
+// LoadTemplates parses every <channel>.tmpl in dir, a missing dir loads no templates.
+// dashboardURL is available to templates as .DashboardURL.
+//
+// Returns an error for files of unknown channels and templates that don't parse or render
+func LoadTemplates(dir string, dashboardURL string) (*Templates, error) {
+	t := &Templates{
+		byChannel:    make(map[NotificationType]*template.Template),
+		dashboardURL: dashboardURL,
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates: %w", err)
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != templateExt {
+			continue
+		}
+		channel := NewNotificationType(strings.TrimSuffix(e.Name(), templateExt))
+		if !slices.Contains(NotificationTypes, channel) {
+			return nil, fmt.Errorf("template %s is not for a known channel", e.Name())
+		}
+		content, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template: %w", err)
+		}
+		if err := t.Add(channel, string(content)); err != nil {
+			return nil, fmt.Errorf("template %s: %w", e.Name(), err)
+		}
+	}
+	return t, nil
+}
+
+// Add parses text as the template of a channel and checks it renders
+func (t *Templates) Add(channel NotificationType, text string) error {
+	tmpl, err := template.New(channel.String()).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return err
+	}
+
+	// Unknown fields only fail when executed, so render an example now rather than at delivery
+	expiry := time.Now().Add(7 * 24 * time.Hour)
+	example := Notification{DomainName: "example.com", ExpiryDate: &expiry, DaysBefore: 7, Issuer: "Example CA", Tags: []string{"prod"}}
+	if _, _, err := render(tmpl, t.data(example, time.Now())); err != nil {
+		return err
+	}
+	t.byChannel[channel] = tmpl
+	return nil
+}
+
+// Has reports whether a channel has a custom template
+func (t *Templates) Has(channel NotificationType) bool {
+	_, ok := t.byChannel[channel]
+	return ok
+}
+
+// Render sets the notification's Subject and Body from its channel's template, leaving them empty without one
+func (t *Templates) Render(n *Notification, now time.Time) error {
+	tmpl, ok := t.byChannel[n.NotificationType]
+	if !ok {
+		return nil
+	}
+	subject, body, err := render(tmpl, t.data(*n, now))
+	if err != nil {
+		return fmt.Errorf("failed to render %s template: %w", n.NotificationType.String(), err)
+	}
+	n.Subject, n.Body = subject, body
+	return nil
+}
+
+func (t *Templates) data(n Notification, now time.Time) messageData {
+	data := newMessageData(n, now)
+	data.DashboardURL = t.dashboardURL
+	return data
+}
+
+// render executes the body and, if the template defines one, the subject
+func render(tmpl *template.Template, data messageData) (string, string, error) {
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, data); err != nil {
+		return "", "", err
+	}
+	var subject bytes.Buffer
+	if sub := tmpl.Lookup("subject"); sub != nil {
+		if err := sub.Execute(&subject, data); err != nil {
+			return "", "", err
+		}
+	}
+	return strings.TrimSpace(subject.String()), strings.TrimSpace(body.String()), nil
+}
@@ -0,0 +1,73 @@
+package notification
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoadTemplates - channel templates are loaded by file name and a missing directory is fine.
+func TestLoadTemplates(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "email.tmpl"), []byte(
+		`{{define "subject"}}[{{upper (join .Tags ",")}}] {{.Domain}}: {{.DaysLeft}} days{{end}}
+{{.Domain}} ({{.Issuer}}) expires {{.Expires}}.
+Manage it at {{.DashboardURL}}
+`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0o600))
+
+	templates, err := LoadTemplates(dir, "https://certs.example.com")
+	require.NoError(t, err)
+	assert.True(t, templates.Has(NotificationTypeEmail))
+	assert.False(t, templates.Has(NotificationTypeTeams))
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	expiry := now.Add(5*24*time.Hour + time.Hour)
+	n := Notification{NotificationType: NotificationTypeEmail, DomainName: "example.com", ExpiryDate: &expiry, DaysBefore: 7, Issuer: "R11", Tags: []string{"prod", "web"}}
+	require.NoError(t, templates.Render(&n, now))
+	assert.Equal(t, "[PROD,WEB] example.com: 5 days", n.Subject)
+	assert.Equal(t, "example.com (R11) expires 2026-03-06 13:00 UTC.\nManage it at https://certs.example.com", n.Body)
+
+	teams := Notification{NotificationType: NotificationTypeTeams, DomainName: "example.com"}
+	require.NoError(t, templates.Render(&teams, now))
+	assert.Empty(t, teams.Subject)
+	assert.Empty(t, teams.Body)
+
+	templates, err = LoadTemplates(filepath.Join(dir, "missing"), "")
+	require.NoError(t, err)
+	assert.False(t, templates.Has(NotificationTypeEmail))
+}
+
+// TestLoadTemplates_Invalid - unknown channels, syntax errors and unknown fields fail at load.
+func TestLoadTemplates_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"sms.tmpl":   "{{.Domain}}",
+		"email.tmpl": "{{.Domain",
+		"teams.tmpl": "{{.Hostname}}",
+	}
+	for file, content := range tests {
+		t.Run(file, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(content), 0o600))
+			_, err := LoadTemplates(dir, "")
+			assert.ErrorContains(t, err, file)
+		})
+	}
+}
+
+// TestEmailSender_TemplatedMessage - a rendered subject and body replace the built in ones.
+func TestEmailSender_TemplatedMessage(t *testing.T) {
+	s, err := NewEmailSender(EmailConfig{Host: "smtp.example.com", From: "certs@example.com", To: []string{"a@example.com"}})
+	require.NoError(t, err)
+
+	msg, err := s.buildMessage(Notification{DomainName: "example.com", DaysBefore: 7, Subject: "Renew example.com", Body: "Line one\nLine two"})
+	require.NoError(t, err)
+	text := string(msg)
+	assert.Contains(t, text, "Subject: Renew example.com\r\n")
+	assert.Contains(t, text, "\r\n\r\nLine one\r\nLine two\r\n")
+	assert.NotContains(t, text, "Threshold:")
+}
@@ -0,0 +1,265 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// webhookTimeout bounds a webhook delivery when no HTTP client is given
+	webhookTimeout = 30 * time.Second
+	// webhookProgressTTL is how long the endpoints that accepted a partly delivered notification are remembered,
+	// well beyond any retry
+	webhookProgressTTL = 24 * time.Hour
+
+	// WebhookEventHeader names the event a webhook payload describes
+	WebhookEventHeader = "X-SSLCertTop-Event"
+	// WebhookSignatureHeader carries sha256=<hex HMAC-SHA256 of the body> when the endpoint has a secret
+	WebhookSignatureHeader = "X-SSLCertTop-Signature"
+
+	// WebhookEventThreshold is sent when a certificate crosses a notification threshold
+	WebhookEventThreshold = "certificate.threshold"
+	// WebhookEventError is sent when checks of a certificate start failing
+	WebhookEventError = "certificate.error"
+	// WebhookEventRegistration is sent when a domain's registration crosses a registration threshold
+	WebhookEventRegistration = "domain.registration"
+	// WebhookEventRenewalOverdue is sent when a certificate expected to renew automatically wasn't renewed in time
+	WebhookEventRenewalOverdue = "certificate.renewal_overdue"
+)
+
+// WebhookEndpoint is a URL notifications are posted to, signed with Secret if it is set
+type WebhookEndpoint struct {
+	URL    string
+	Secret string
+}
+
+// WebhookCheck is a certificate check in a webhook payload
+type WebhookCheck struct {
+	CheckedAt  *time.Time `json:"checked_at"`
+	ExpiryDate *time.Time `json:"expiry_date"`
+	Error      *string    `json:"error"`
+}
+
+// WebhookPayload is the JSON body posted to webhook endpoints
+type WebhookPayload struct {
+	Event          string `json:"event"`
+	NotificationID uint   `json:"notification_id"`
+	DomainID       uint   `json:"domain_id"`
+	Domain         string `json:"domain"`
+	// SharedWith are the other domains serving the same certificate, notified about in this one payload
+	SharedWith     []string      `json:"shared_with,omitempty"`
+	Status         string        `json:"status"`
+	PreviousStatus *string       `json:"previous_status"`
+	ExpiryDate     *time.Time    `json:"expiry_date"`
+	DaysLeft       int           `json:"days_left"`
+	Threshold      int           `json:"threshold"`
+	Check          WebhookCheck  `json:"check"`
+	PreviousCheck  *WebhookCheck `json:"previous_check"`
+	SentAt         time.Time     `json:"sent_at"`
+}
+
+// WebhookSender posts notifications as JSON to every configured endpoint
+type WebhookSender struct {
+	endpoints  []WebhookEndpoint
+	httpClient *http.Client
+	now        func() time.Time
+
+	mu sync.Mutex
+	// progress holds the endpoints that accepted each notification some other endpoint failed, so retries skip them
+	progress map[uint]*webhookProgress
+}
+
+// webhookProgress is how far a partly delivered notification got
+type webhookProgress struct {
+	// accepted holds the indexes of the endpoints that accepted the notification
+	accepted map[int]bool
+	since    time.Time
+}
+
+// NewWebhookSender creates a sender for the endpoints, a nil httpClient uses one with a 30 second timeout.
+//
+// Returns an error if there are no endpoints or one has an invalid URL
+func NewWebhookSender(endpoints []WebhookEndpoint, httpClient *http.Client) (*WebhookSender, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("at least one webhook endpoint is required")
+	}
+	for _, e := range endpoints {
+		if err := validateWebhookURL(e.URL); err != nil {
+			return nil, err
+		}
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: webhookTimeout}
+	}
+	return &WebhookSender{
+		endpoints:  endpoints,
+		httpClient: httpClient,
+		now:        time.Now,
+		progress:   make(map[uint]*webhookProgress),
+	}, nil
+}
+
+func (s *WebhookSender) Type() NotificationType {
+	return NotificationTypeWebhook
+}
+
+// Send posts the notification to every endpoint, failing if any of them rejects it. Sending it again, as retries do,
+// posts it only to the endpoints that haven't accepted it yet, so receivers don't get it twice. Notifications
+// without an ID, like test messages, go to every endpoint each time
+func (s *WebhookSender) Send(ctx context.Context, n Notification) error {
+	now := s.now()
+	payload := NewWebhookPayload(n, now)
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	progress := s.progressOf(n.NotificationID, now)
+	var errs []error
+	for i, e := range s.endpoints {
+		if progress.accepted[i] {
+			continue
+		}
+		header := http.Header{}
+		header.Set(WebhookEventHeader, payload.Event)
+		if e.Secret != "" {
+			header.Set(WebhookSignatureHeader, SignWebhook(e.Secret, body))
+		}
+		if err := post(ctx, s.httpClient, e.URL, body, header); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		progress.accepted[i] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(errs) == 0 {
+		delete(s.progress, n.NotificationID)
+	} else if n.NotificationID != 0 {
+		s.progress[n.NotificationID] = progress
+	}
+	return errors.Join(errs...)
+}
+
+// progressOf is how far earlier attempts delivered a notification, forgetting notifications given up on long ago
+func (s *WebhookSender) progressOf(notificationID uint, now time.Time) *webhookProgress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, p := range s.progress {
+		if now.Sub(p.since) > webhookProgressTTL {
+			delete(s.progress, id)
+		}
+	}
+	if p, ok := s.progress[notificationID]; ok && notificationID != 0 {
+		// A copy, Send updates it without holding the lock
+		return &webhookProgress{accepted: maps.Clone(p.accepted), since: p.since}
+	}
+	return &webhookProgress{accepted: make(map[int]bool), since: now}
+}
+
+// SignWebhook returns the signature header value for a body, receivers compare it with hmac.Equal
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewWebhookPayload describes the notification and the checks behind it, as webhooks and plugins receive it
+func NewWebhookPayload(n Notification, now time.Time) WebhookPayload {
+	data := newMessageData(n, now)
+	payload := WebhookPayload{
+		Event:          WebhookEventThreshold,
+		NotificationID: n.NotificationID,
+		DomainID:       n.DomainID.Uint(),
+		Domain:         n.DomainName,
+		SharedWith:     n.SharedWith,
+		Status:         n.CertificateStatus(now),
+		ExpiryDate:     n.ExpiryDate,
+		DaysLeft:       data.DaysLeft,
+		Threshold:      n.DaysBefore,
+		Check: WebhookCheck{
+			CheckedAt:  n.LastChecked,
+			ExpiryDate: n.ExpiryDate,
+			Error:      n.CheckError,
+		},
+		SentAt: now.UTC(),
+	}
+	if n.DaysBefore == ErrorThreshold {
+		payload.Event = WebhookEventError
+	}
+	if n.DaysBefore == RenewalOverdueThreshold {
+		payload.Event = WebhookEventRenewalOverdue
+	}
+	if n.Registration {
+		payload.Event = WebhookEventRegistration
+	}
+	if previous := n.PreviousStatus(); previous != "" {
+		payload.PreviousStatus = &previous
+		payload.PreviousCheck = &WebhookCheck{
+			CheckedAt:  n.PreviousCheckedAt,
+			ExpiryDate: n.PreviousExpiryDate,
+			Error:      n.PreviousCheckError,
+		}
+	}
+	return payload
+}
+
+// validateWebhookURL checks a webhook URL can be posted to
+func validateWebhookURL(webhookURL string) error {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q, expected an http or https URL", webhookURL)
+	}
+	return nil
+}
+
+// postJSON posts payload as JSON with the extra headers, treating any non-2xx response as a failed delivery
+func postJSON(ctx context.Context, httpClient *http.Client, target string, payload any, header http.Header) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+	return post(ctx, httpClient, target, body, header)
+}
+
+// post sends a JSON body with the extra headers, treating any non-2xx response as a failed delivery.
+//
+// Returns a *DeliveryError carrying the status code when the server answered
+func post(ctx context.Context, httpClient *http.Client, target string, body []byte, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return newHTTPError(resp.StatusCode, fmt.Errorf("webhook %s answered %s: %s", req.URL.Host, resp.Status, bytes.TrimSpace(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package notification
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewWebhookSender - endpoints are required and must be http(s).
+func TestNewWebhookSender(t *testing.T) {
+	_, err := NewWebhookSender(nil, nil)
+	assert.Error(t, err)
+
+	_, err = NewWebhookSender([]WebhookEndpoint{{URL: "hooks.example.com/certs"}}, nil)
+	assert.Error(t, err)
+
+	s, err := NewWebhookSender([]WebhookEndpoint{{URL: "https://hooks.example.com/certs"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, NotificationTypeWebhook, s.Type())
+}
+
+// TestWebhookSender_Send - the payload reports the latest and previous check and is signed per endpoint.
+func TestWebhookSender_Send(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewRepository(db)
+
+	now := time.Now()
+	expiry := now.Add(5 * 24 * time.Hour)
+	previousExpiry := now.Add(20 * 24 * time.Hour)
+	_, err := db.Exec(`INSERT INTO check_history (domain_id, checked_at, expiry_date) VALUES (1, ?, ?), (1, ?, ?)`,
+		now.Add(-time.Hour), previousExpiry, now, expiry)
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE domains SET expiry_date = ?, last_checked = ? WHERE id = 1`, expiry, now)
+	require.NoError(t, err)
+	require.NoError(t, repo.CreateNotification(&Notification{DomainID: types.DomainID(1), DaysBefore: 7, NotificationType: NotificationTypeWebhook}))
+
+	pending, err := repo.GetPendingNotifications()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+
+	type request struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan request, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{header: r.Header, body: body}
+	}))
+	defer server.Close()
+
+	s, err := NewWebhookSender([]WebhookEndpoint{
+		{URL: server.URL + "/signed", Secret: "s3cret"},
+		{URL: server.URL + "/unsigned"},
+	}, server.Client())
+	require.NoError(t, err)
+	require.NoError(t, s.Send(context.Background(), pending[0]))
+
+	signed := <-requests
+	assert.Equal(t, WebhookEventThreshold, signed.header.Get(WebhookEventHeader))
+	assert.True(t, hmac.Equal([]byte(SignWebhook("s3cret", signed.body)), []byte(signed.header.Get(WebhookSignatureHeader))))
+
+	var payload WebhookPayload
+	require.NoError(t, json.Unmarshal(signed.body, &payload))
+	assert.Equal(t, "example.com", payload.Domain)
+	assert.Equal(t, "warning", payload.Status)
+	require.NotNil(t, payload.PreviousStatus)
+	assert.Equal(t, "soon", *payload.PreviousStatus)
+	assert.Equal(t, 7, payload.Threshold)
+	assert.Equal(t, 4, payload.DaysLeft)
+	require.NotNil(t, payload.Check.CheckedAt)
+	require.NotNil(t, payload.PreviousCheck)
+	assert.WithinDuration(t, previousExpiry, *payload.PreviousCheck.ExpiryDate, time.Second)
+
+	unsigned := <-requests
+	assert.Empty(t, unsigned.header.Get(WebhookSignatureHeader))
+}
+
+// TestWebhookSender_NoPreviousCheck - the first check of a domain has no previous status.
+func TestWebhookSender_NoPreviousCheck(t *testing.T) {
+	expiry := time.Now().Add(-time.Hour)
+	payload := NewWebhookPayload(Notification{DomainName: "example.com", ExpiryDate: &expiry}, time.Now())
+	assert.Equal(t, "expired", payload.Status)
+	assert.Nil(t, payload.PreviousStatus)
+	assert.Nil(t, payload.PreviousCheck)
+}
+
+// TestWebhookSender_Retry - a retry after one endpoint failed only posts to that endpoint.
+func TestWebhookSender_Retry(t *testing.T) {
+	var okCalls, flakyCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ok" {
+			okCalls.Add(1)
+			return
+		}
+		if flakyCalls.Add(1) == 1 {
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	s, err := NewWebhookSender([]WebhookEndpoint{{URL: server.URL + "/ok"}, {URL: server.URL + "/flaky"}}, server.Client())
+	require.NoError(t, err)
+	n := Notification{NotificationID: 7, DomainName: "example.com", DaysBefore: 7}
+
+	assert.ErrorContains(t, s.Send(context.Background(), n), "503")
+	require.NoError(t, s.Send(context.Background(), n))
+	assert.Equal(t, int32(1), okCalls.Load(), "The endpoint that accepted it isn't sent it again")
+	assert.Equal(t, int32(2), flakyCalls.Load())
+
+	require.NoError(t, s.Send(context.Background(), n))
+	assert.Equal(t, int32(2), okCalls.Load(), "Once delivered everywhere, sending it again reaches every endpoint")
+	assert.Equal(t, int32(3), flakyCalls.Load())
+}
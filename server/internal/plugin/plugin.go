@@ -0,0 +1,183 @@
+// Package plugin runs external programs that add notification channels or check types, so sslcerttop can be
+// extended without forking it.
+//
+// A plugin is run once per request, with the request as a JSON object on stdin, and answers with a JSON object on
+// stdout before exiting. Anything it writes to stderr is kept for the error when it fails
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/notification"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+const (
+	// ProtocolVersion is sent with every request, so plugins can tell when the format changes
+	ProtocolVersion = 1
+	// DefaultTimeout is how long a request may take unless configured otherwise
+	DefaultTimeout = 30 * time.Second
+	// maxStderr is how much of what a failing plugin printed to stderr ends up in its error
+	maxStderr = 1024
+)
+
+// Kinds of requests
+const (
+	RequestNotify = "notify"
+	RequestCheck  = "check"
+)
+
+// Request is what a plugin reads from stdin
+type Request struct {
+	Version int    `json:"version"`
+	Type    string `json:"type"`
+	// Name is the tracked name to check, set on check requests
+	Name string `json:"name,omitempty"`
+	// Notification is the notification to deliver, set on notify requests in the format webhooks receive
+	Notification *notification.WebhookPayload `json:"notification,omitempty"`
+}
+
+// Response is what a plugin writes to stdout
+type Response struct {
+	// Error fails the request, the certificate couldn't be checked or the notification wasn't delivered
+	Error string `json:"error,omitempty"`
+	// Certificate answers check requests
+	Certificate *Certificate `json:"certificate,omitempty"`
+}
+
+// Certificate is the certificate a check request found, only ExpiryDate is required
+type Certificate struct {
+	ExpiryDate         time.Time `json:"expiry_date"`
+	Issuer             string    `json:"issuer"`
+	IssuerOrganization string    `json:"issuer_organization"`
+	// IssuedAt is when the certificate became valid, zero when unknown
+	IssuedAt time.Time `json:"issued_at"`
+	// Fingerprint is the hex SHA-256 of the certificate
+	Fingerprint string   `json:"fingerprint"`
+	SANs        []string `json:"sans"`
+	// KeyType describes the public key, e.g. RSA 2048 or ECDSA P-256
+	KeyType    string   `json:"key_type"`
+	Signatures []string `json:"signatures"`
+	// Warning is a problem with a certificate that is otherwise fine
+	Warning string `json:"warning"`
+}
+
+// Plugin is an external program run for each request
+type Plugin struct {
+	// Name identifies the plugin, and is the notification channel it adds
+	Name    string
+	Command string
+	Args    []string
+	// Timeout stops a request that runs longer, zero uses DefaultTimeout
+	Timeout time.Duration
+}
+
+// Call runs the plugin for one request.
+//
+// Returns its response, or an error when it couldn't be run, exited non-zero, answered with something other than
+// JSON or answered with an error
+func (p Plugin) Call(ctx context.Context, req Request) (*Response, error) {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req.Version = ProtocolVersion
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Command, p.Args...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Children the plugin started could otherwise keep the output open, and the request running, past the timeout
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("plugin %s timed out after %s", p.Name, timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("plugin %s failed: %w: %s", p.Name, err, msg[:min(len(msg), maxStderr)])
+		}
+		return nil, fmt.Errorf("plugin %s failed: %w", p.Name, err)
+	}
+
+	var resp Response
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("plugin %s answered with invalid JSON: %w", p.Name, err)
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
+
+// Check asks the plugin for the certificate of a tracked name
+func (p Plugin) Check(ctx context.Context, name string) (*ssl.SSLCertificate, error) {
+	resp, err := p.Call(ctx, Request{Type: RequestCheck, Name: name})
+	if err != nil {
+		return nil, err
+	}
+	c := resp.Certificate
+	if c == nil || c.ExpiryDate.IsZero() {
+		return nil, fmt.Errorf("plugin %s answered without a certificate expiry", p.Name)
+	}
+	return &ssl.SSLCertificate{
+		Hostname:           ssl.Hostname(name),
+		ExpiryDate:         types.NewExpiryDate(c.ExpiryDate),
+		TimeLeft:           ssl.TimeLeft(time.Until(c.ExpiryDate).Hours() / 24),
+		Issuer:             c.Issuer,
+		IssuerOrganization: c.IssuerOrganization,
+		Warning:            c.Warning,
+		CertFingerprint:    strings.ToLower(c.Fingerprint),
+		SANs:               c.SANs,
+		IssuedAt:           c.IssuedAt,
+		KeyType:            c.KeyType,
+		Signatures:         c.Signatures,
+	}, nil
+}
+
+// RegisterChecker has the plugin check the names starting with prefix
+func (p Plugin) RegisterChecker(prefix string) {
+	ssl.RegisterTargetChecker(prefix, p.Check)
+}
+
+// RegisterChannel adds the plugin as a notification channel rules can use, named after it.
+//
+// Returns an error when a channel of that name already exists
+func (p Plugin) RegisterChannel() error {
+	return notification.RegisterNotificationType(notification.NotificationType(p.Name))
+}
+
+// Sender delivers notifications through a plugin, as the channel named after it
+type Sender struct {
+	plugin Plugin
+	now    func() time.Time
+}
+
+func NewSender(p Plugin) *Sender {
+	return &Sender{plugin: p, now: time.Now}
+}
+
+func (s *Sender) Type() notification.NotificationType {
+	return notification.NotificationType(s.plugin.Name)
+}
+
+// Send has the plugin deliver the notification
+func (s *Sender) Send(ctx context.Context, n notification.Notification) error {
+	payload := notification.NewWebhookPayload(n, s.now())
+	_, err := s.plugin.Call(ctx, Request{Type: RequestNotify, Notification: &payload})
+	return err
+}
@@ -0,0 +1,71 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/notification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePlugin creates an executable shell script plugin.
+func writePlugin(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "plugin")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755))
+	return path
+}
+
+// TestPlugin_Check - the certificate the plugin answers with becomes the check result.
+func TestPlugin_Check(t *testing.T) {
+	p := Plugin{Name: "vault", Command: writePlugin(t, `
+read -r request
+case "$request" in
+*'"type":"check","name":"vault:web"'*) echo '{"certificate": {"expiry_date": "2030-01-02T00:00:00Z", "issuer": "Vault CA", "fingerprint": "AB12"}}' ;;
+*) echo '{"error": "no such certificate"}' ;;
+esac
+`)}
+
+	cert, err := p.Check(context.Background(), "vault:web")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC), cert.ExpiryDate.Time())
+	assert.Equal(t, "Vault CA", cert.Issuer)
+	assert.Equal(t, "ab12", cert.CertFingerprint)
+
+	_, err = p.Check(context.Background(), "vault:db")
+	assert.EqualError(t, err, "no such certificate")
+}
+
+// TestPlugin_Call - failing, silent and slow plugins fail the request.
+func TestPlugin_Call(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := Plugin{Name: "broken", Command: writePlugin(t, "echo 'token missing' >&2; exit 2")}.Call(ctx, Request{Type: RequestCheck})
+	assert.ErrorContains(t, err, "plugin broken failed: exit status 2: token missing")
+
+	_, err = Plugin{Name: "silent", Command: writePlugin(t, "true")}.Call(ctx, Request{Type: RequestCheck})
+	assert.ErrorContains(t, err, "invalid JSON")
+
+	_, err = Plugin{Name: "slow", Command: writePlugin(t, "sleep 5"), Timeout: 50 * time.Millisecond}.Call(ctx, Request{Type: RequestCheck})
+	assert.ErrorContains(t, err, "timed out")
+}
+
+// TestSender - notifications reach the plugin in the webhook payload format.
+func TestSender(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "request.json")
+	p := Plugin{Name: "matrix", Command: writePlugin(t, `cat > "$1"; echo '{}'`), Args: []string{out}}
+
+	s := NewSender(p)
+	assert.Equal(t, notification.NotificationType("matrix"), s.Type())
+	expiry := time.Now().Add(72 * time.Hour)
+	require.NoError(t, s.Send(context.Background(), notification.Notification{DomainName: "example.com", ExpiryDate: &expiry, DaysBefore: 7}))
+
+	request, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Contains(t, string(request), `"version":1,"type":"notify","notification":{"event":"certificate.threshold"`)
+	assert.Contains(t, string(request), `"domain":"example.com"`)
+}
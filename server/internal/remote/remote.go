@@ -0,0 +1,315 @@
+// This package implements the TUI services on top of a remote sslcerttop server
+//
+// The server decides which user the API key acts for, so user IDs passed in are ignored
+package remote
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/samokw/ssl_tracker/client"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/notification"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+const (
+	// requestTimeout bounds ordinary API calls
+	requestTimeout = 30 * time.Second
+	// checkAllTimeout bounds checking every domain, which the server does synchronously
+	checkAllTimeout = 10 * time.Minute
+	// watchRetry is how long to wait before following the server's checks again once the stream broke off
+	watchRetry = 5 * time.Second
+)
+
+// DomainService manages domains through the REST API
+type DomainService struct {
+	client *client.Client
+}
+
+func NewDomainService(c *client.Client) *DomainService {
+	return &DomainService{
+		client: c,
+	}
+}
+
+// GetUsersDomains lists the domains tracked by the server
+func (s *DomainService) GetUsersDomains(userID types.UserID) ([]domain.Domain, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	remote, err := s.client.ListDomains(ctx)
+	if err != nil {
+		return nil, err
+	}
+	domains := make([]domain.Domain, len(remote))
+	for i, d := range remote {
+		domains[i] = toDomain(d)
+	}
+	return domains, nil
+}
+
+// GetDomain fetches a domain from the server
+func (s *DomainService) GetDomain(domainID types.DomainID) (*domain.Domain, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	remote, err := s.client.GetDomain(ctx, domainID.Uint())
+	if err != nil {
+		return nil, domainError(err)
+	}
+	d := toDomain(*remote)
+	return &d, nil
+}
+
+// AddDomain tracks a new domain on the server
+func (s *DomainService) AddDomain(userID types.UserID, domainName string) (*domain.Domain, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	added, err := s.client.AddDomain(ctx, domainName)
+	if err != nil {
+		return nil, domainError(err)
+	}
+	d := toDomain(*added)
+	return &d, nil
+}
+
+// RemoveDomain stops tracking a domain on the server
+func (s *DomainService) RemoveDomain(domainID types.DomainID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	return domainError(s.client.DeleteDomain(ctx, domainID.Uint()))
+}
+
+// CheckDomainSSL has the server check a domain's certificate now
+func (s *DomainService) CheckDomainSSL(domainID types.DomainID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	_, err := s.client.CheckDomain(ctx, domainID.Uint())
+	return domainError(err)
+}
+
+// CheckAllDomainsSSLSync has the server check every domain and waits for it to finish
+func (s *DomainService) CheckAllDomainsSSLSync(userID types.UserID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), checkAllTimeout)
+	defer cancel()
+
+	_, err := s.client.CheckAllDomains(ctx)
+	return err
+}
+
+// CheckFailedDomainsSSLSync has the server check the domains whose last check failed and waits for it to finish
+func (s *DomainService) CheckFailedDomainsSSLSync(userID types.UserID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), checkAllTimeout)
+	defer cancel()
+
+	_, err := s.client.CheckFailedDomains(ctx)
+	return err
+}
+
+// GetCertificateChain fetches the chain a domain serves, as seen from the server
+func (s *DomainService) GetCertificateChain(domainID types.DomainID) ([]*x509.Certificate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	chain, err := s.client.GetCertificateChain(ctx, domainID.Uint())
+	if err != nil {
+		return nil, err
+	}
+	return ssl.ParseChainPEM([]byte(chain))
+}
+
+// GetCheckHistory lists up to limit of a domain's most recent checks, newest first
+func (s *DomainService) GetCheckHistory(domainID types.DomainID, limit int) ([]domain.CheckRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	remote, err := s.client.GetDomainHistory(ctx, domainID.Uint(), limit)
+	if err != nil {
+		return nil, domainError(err)
+	}
+	records := make([]domain.CheckRecord, len(remote))
+	for i, rec := range remote {
+		records[i] = domain.CheckRecord{DomainID: domainID, CheckedAt: rec.CheckedAt}
+		if rec.ExpiryDate != nil {
+			expiry := types.NewExpiryDate(*rec.ExpiryDate)
+			records[i].ExpiryDate = &expiry
+		}
+		if rec.Error != nil {
+			checkErr := domain.NewLastError(*rec.Error)
+			records[i].Error = &checkErr
+		}
+	}
+	return records, nil
+}
+
+// WatchChecks calls checked with each domain the server checks, as of the check, until ctx is cancelled. The
+// server's event stream is followed again when it breaks off, e.g. while the server restarts
+func (s *DomainService) WatchChecks(ctx context.Context, checked func(domain.Domain)) error {
+	for {
+		// Streams only end with an error, which is the same as the retry
+		_ = s.client.WatchChecks(ctx, func(e client.CheckEvent) { checked(toDomain(e.Domain)) })
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(watchRetry):
+		}
+	}
+}
+
+// NotificationService manages notifications through the REST API
+type NotificationService struct {
+	client *client.Client
+}
+
+func NewNotificationService(c *client.Client) *NotificationService {
+	return &NotificationService{
+		client: c,
+	}
+}
+
+// GetUsersNotifications lists the notifications on the server
+func (s *NotificationService) GetUsersNotifications(userID types.UserID) ([]notification.Notification, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	remote, err := s.client.ListNotifications(ctx)
+	if err != nil {
+		return nil, err
+	}
+	notifications := make([]notification.Notification, len(remote))
+	for i, n := range remote {
+		notifications[i] = toNotification(n)
+	}
+	return notifications, nil
+}
+
+// Resend queues a notification for delivery again
+func (s *NotificationService) Resend(id uint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	_, err := s.client.ResendNotification(ctx, id)
+	return err
+}
+
+// Acknowledge marks a notification as acknowledged
+func (s *NotificationService) Acknowledge(id uint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	_, err := s.client.AcknowledgeNotification(ctx, id)
+	return err
+}
+
+// GetUsersAcks lists the domain acknowledgements on the server
+func (s *NotificationService) GetUsersAcks(userID types.UserID) ([]notification.DomainAck, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	remote, err := s.client.ListAcks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	acks := make([]notification.DomainAck, len(remote))
+	for i, a := range remote {
+		acks[i] = notification.DomainAck{
+			DomainID:   types.NewDomainID(a.DomainID),
+			DomainName: a.Domain,
+			Note:       a.Note,
+			ExpiryDate: a.ExpiryDate,
+			ExpiresAt:  a.ExpiresAt,
+			CreatedAt:  a.CreatedAt,
+		}
+	}
+	return acks, nil
+}
+
+// sentinelError keeps the server's message while matching the domain error its status code stands for
+type sentinelError struct {
+	err      error
+	sentinel error
+}
+
+func (e *sentinelError) Error() string { return e.err.Error() }
+
+func (e *sentinelError) Unwrap() []error { return []error{e.err, e.sentinel} }
+
+// domainError makes API errors match the domain package's sentinel errors, so callers handle them the same as local ones
+func domainError(err error) error {
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	switch apiErr.StatusCode {
+	case http.StatusNotFound:
+		return &sentinelError{err: err, sentinel: domain.ErrNotFound}
+	case http.StatusConflict:
+		return &sentinelError{err: err, sentinel: domain.ErrDuplicate}
+	case http.StatusBadRequest:
+		return &sentinelError{err: err, sentinel: domain.ErrInvalidInput}
+	default:
+		return err
+	}
+}
+
+func toDomain(d client.Domain) domain.Domain {
+	result := domain.Domain{
+		DomainID:           types.NewDomainID(d.ID),
+		DomainName:         domain.NewDomainName(d.Domain),
+		CreatedAt:          domain.NewCreatedAt(d.CreatedAt),
+		IsActive:           d.IsActive,
+		CheckSchedule:      d.CheckSchedule,
+		Tags:               d.Tags,
+		RegistrationExpiry: d.RegistrationExpiry,
+		DNSError:           d.DNSError,
+		Warning:            d.Warning,
+		AutoRenewDays:      d.AutoRenewDays,
+		AutoRenewVia:       d.AutoRenewVia,
+		CertFingerprint:    d.CertFingerprint,
+		SANs:               d.SANs,
+		ExpectedSANs:       d.ExpectedSANs,
+		IssuedAt:           d.IssuedAt,
+		KeyType:            d.KeyType,
+		Signatures:         d.Signatures,
+	}
+	if d.ExpiryDate != nil {
+		expiry := types.NewExpiryDate(*d.ExpiryDate)
+		result.ExpiryDate = &expiry
+	}
+	if d.LastChecked != nil {
+		lastChecked := domain.NewLastChecked(*d.LastChecked)
+		result.LastChecked = &lastChecked
+	}
+	if d.LastError != nil {
+		lastError := domain.NewLastError(*d.LastError)
+		result.LastError = &lastError
+	}
+	return result
+}
+
+func toNotification(n client.Notification) notification.Notification {
+	return notification.Notification{
+		NotificationID:   n.ID,
+		DomainID:         types.NewDomainID(n.DomainID),
+		DomainName:       n.Domain,
+		ExpiryDate:       n.ExpiryDate,
+		DaysBefore:       n.DaysBefore,
+		NotificationType: notification.NewNotificationType(n.Channel),
+		Status:           notification.NewNotificationStatus(n.Status),
+		CreatedAt:        n.CreatedAt,
+		SentAt:           n.SentAt,
+		AcknowledgedAt:   n.AcknowledgedAt,
+		LastError:        n.LastError,
+		Attempts:         n.Attempts,
+		NextAttemptAt:    n.NextAttemptAt,
+		LastStatusCode:   n.LastStatusCode,
+	}
+}
@@ -0,0 +1,127 @@
+package remote
+
+import (
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/client"
+	"github.com/samokw/ssl_tracker/internal/api"
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/notification"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/tui"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ tui.DomainService       = (*DomainService)(nil)
+	_ tui.CheckFeed           = (*DomainService)(nil)
+	_ tui.NotificationService = (*NotificationService)(nil)
+)
+
+// newTestServices serves a fresh database over the REST API and returns remote services talking to it.
+func newTestServices(t *testing.T) (*DomainService, *NotificationService, *domain.Repository, *notification.Repository) {
+	t.Helper()
+
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	sslService := ssl.NewCertService()
+	t.Cleanup(sslService.Stop)
+
+	domainRepo := domain.NewRepository(db)
+	notificationRepo := notification.NewRepository(db)
+	server := api.NewServer(domain.NewService(domainRepo, sslService), notification.NewService(notificationRepo))
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	c := client.NewClient(ts.URL, ts.Client())
+	return NewDomainService(c), NewNotificationService(c), domainRepo, notificationRepo
+}
+
+// TestDomainService - domains round-trip through the API into domain types.
+func TestDomainService(t *testing.T) {
+	domains, _, repo, _ := newTestServices(t)
+
+	d := domain.Domain{
+		UserID:     types.UserID(1),
+		DomainName: domain.NewDomainName("example.com"),
+		CreatedAt:  domain.NewCreatedAt(time.Now()),
+		IsActive:   true,
+		Tags:       []string{"prod"},
+	}
+	require.NoError(t, repo.CreateDomain(&d))
+	expiry := time.Now().Add(40 * 24 * time.Hour)
+	require.NoError(t, repo.UpdateSSLInfo(d.DomainID, &expiry, nil))
+
+	list, err := domains.GetUsersDomains(types.UserID(1))
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	got := list[0]
+	assert.Equal(t, d.DomainID, got.DomainID)
+	assert.Equal(t, "example.com", got.DomainName.String())
+	assert.Equal(t, []string{"prod"}, got.Tags)
+	require.NotNil(t, got.ExpiryDate)
+	assert.WithinDuration(t, expiry, got.ExpiryDate.Time(), time.Second)
+	require.NotNil(t, got.LastChecked)
+	assert.Nil(t, got.LastError)
+	assert.Equal(t, "valid", got.Status())
+
+	one, err := domains.GetDomain(d.DomainID)
+	require.NoError(t, err)
+	assert.Equal(t, got, *one)
+
+	_, err = domains.AddDomain(types.UserID(1), "not a domain")
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+	require.NoError(t, domains.RemoveDomain(d.DomainID))
+	list, err = domains.GetUsersDomains(types.UserID(1))
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	assert.ErrorIs(t, domains.CheckDomainSSL(d.DomainID), domain.ErrNotFound, "Removed domains can't be checked")
+	_, err = domains.GetDomain(d.DomainID)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+// TestNotificationService - notifications can be listed and acknowledged remotely.
+func TestNotificationService(t *testing.T) {
+	_, notifications, repo, notificationRepo := newTestServices(t)
+
+	d := domain.Domain{
+		UserID:     types.UserID(1),
+		DomainName: domain.NewDomainName("example.com"),
+		CreatedAt:  domain.NewCreatedAt(time.Now()),
+		IsActive:   true,
+	}
+	require.NoError(t, repo.CreateDomain(&d))
+	n := notification.Notification{DomainID: d.DomainID, DaysBefore: 7, NotificationType: notification.NotificationTypeEmail}
+	require.NoError(t, notificationRepo.CreateNotification(&n))
+
+	list, err := notifications.GetUsersNotifications(types.UserID(1))
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, notification.NotificationTypeEmail, list[0].NotificationType)
+	assert.Equal(t, notification.StatusPending, list[0].Status)
+	assert.Equal(t, "example.com", list[0].DomainName)
+
+	require.NoError(t, notifications.Acknowledge(n.NotificationID))
+	list, err = notifications.GetUsersNotifications(types.UserID(1))
+	require.NoError(t, err)
+	assert.Equal(t, notification.StatusAcknowledged, list[0].Status)
+
+	assert.Error(t, notifications.Resend(999))
+
+	require.NoError(t, notificationRepo.SetAck(&notification.DomainAck{DomainID: d.DomainID, Note: "renewal scheduled for Friday"}))
+	acks, err := notifications.GetUsersAcks(types.UserID(1))
+	require.NoError(t, err)
+	require.Len(t, acks, 1)
+	assert.Equal(t, d.DomainID, acks[0].DomainID)
+	assert.Equal(t, "renewal scheduled for Friday", acks[0].Note)
+}
@@ -0,0 +1,119 @@
+package renewal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// maxOutput is how much of an action's output is kept with the attempt
+const maxOutput = 4096
+
+// Request describes the certificate an action should renew
+type Request struct {
+	Domain     string     `json:"domain"`
+	ExpiryDate *time.Time `json:"expiry_date"`
+	DaysLeft   int        `json:"days_left"`
+}
+
+// Action starts a renewal, returning what it printed or answered
+type Action interface {
+	Name() string
+	Run(ctx context.Context, req Request) (string, error)
+}
+
+// CommandAction runs a shell command with the request in its environment
+type CommandAction struct {
+	command string
+}
+
+func NewCommandAction(command string) *CommandAction {
+	return &CommandAction{command: command}
+}
+
+func (a *CommandAction) Name() string {
+	return "command"
+}
+
+// Run executes the command through sh -c, failing when it exits non-zero
+func (a *CommandAction) Run(ctx context.Context, req Request) (string, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", a.command)
+	expiry := ""
+	if req.ExpiryDate != nil {
+		expiry = req.ExpiryDate.UTC().Format(time.RFC3339)
+	}
+	cmd.Env = append(os.Environ(),
+		"SSLCERTTOP_DOMAIN="+req.Domain,
+		"SSLCERTTOP_EXPIRY="+expiry,
+		"SSLCERTTOP_DAYS_LEFT="+strconv.Itoa(req.DaysLeft),
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return truncate(out), fmt.Errorf("renewal command failed: %w", err)
+	}
+	return truncate(out), nil
+}
+
+// WebhookAction posts the request as JSON to a URL
+type WebhookAction struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewWebhookAction posts to url, using http.DefaultClient when httpClient is nil
+func NewWebhookAction(url string, httpClient *http.Client) *WebhookAction {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &WebhookAction{url: url, httpClient: httpClient}
+}
+
+func (a *WebhookAction) Name() string {
+	return "webhook"
+}
+
+// Run posts the request, failing on any non-2xx response
+func (a *WebhookAction) Run(ctx context.Context, req Request) (string, error) {
+	body, err := json.Marshal(struct {
+		Event string `json:"event"`
+		Request
+	}{Event: "renewal", Request: req})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpClient.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to call renewal webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	answer, _ := io.ReadAll(io.LimitReader(resp.Body, maxOutput))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return truncate(answer), errors.New("renewal webhook answered " + resp.Status)
+	}
+	return truncate(answer), nil
+}
+
+// truncate keeps the start of an action's output
+func truncate(out []byte) string {
+	out = bytes.TrimSpace(out)
+	if len(out) > maxOutput {
+		out = out[:maxOutput]
+	}
+	return string(out)
+}
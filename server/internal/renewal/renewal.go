@@ -0,0 +1,59 @@
+// This package starts certificate renewals when a domain gets close to expiry
+//
+// It runs a command or calls a webhook once a certificate crosses the renewal threshold and records
+// whether the check after the attempt found a new certificate
+package renewal
+
+import (
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// Attempt is one run of the renewal actions for a domain
+type Attempt struct {
+	AttemptID  uint           `db:"id"`
+	DomainID   types.DomainID `db:"domain_id"`
+	DomainName string         `db:"domain_name"`
+	// Actions names what was run, e.g. command,webhook
+	Actions     string    `db:"actions"`
+	TriggeredAt time.Time `db:"triggered_at"`
+	// ExpiryBefore is the expiry of the certificate the attempt was meant to replace
+	ExpiryBefore *time.Time `db:"expiry_before"`
+	Error        *string    `db:"error"`
+	Output       string     `db:"output"`
+	// VerifiedAt is when the check after the attempt ran, nil until it has
+	VerifiedAt *time.Time `db:"verified_at"`
+	// Renewed is set when that check found a certificate expiring later than ExpiryBefore
+	Renewed bool `db:"renewed"`
+}
+
+// Attempt outcomes
+const (
+	OutcomeFailed     = "failed"
+	OutcomePending    = "pending"
+	OutcomeRenewed    = "renewed"
+	OutcomeNotRenewed = "not renewed"
+)
+
+// Outcome summarises an attempt as one of the Outcome constants
+func (a Attempt) Outcome() string {
+	switch {
+	case a.Renewed:
+		return OutcomeRenewed
+	case a.Error != nil:
+		return OutcomeFailed
+	case a.VerifiedAt == nil:
+		return OutcomePending
+	default:
+		return OutcomeNotRenewed
+	}
+}
+
+// sameCertificate reports whether an attempt was made for the certificate expiring at expiry
+func (a Attempt) sameCertificate(expiry *time.Time) bool {
+	if a.ExpiryBefore == nil || expiry == nil {
+		return a.ExpiryBefore == nil && expiry == nil
+	}
+	return a.ExpiryBefore.Unix() == expiry.Unix()
+}
@@ -0,0 +1,107 @@
+package renewal
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+type Repository struct {
+	db     *sql.DB
+	writer *database.Writer
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db:     db,
+		writer: database.NewWriter(db),
+	}
+}
+
+const selectAttempts = `SELECT a.id, a.domain_id, d.domain_name, a.actions, a.triggered_at, a.expiry_before,
+              a.error, a.output, a.verified_at, a.renewed
+              FROM renewal_attempts a JOIN domains d ON d.id = a.domain_id`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAttempt(row scanner) (Attempt, error) {
+	var id, domainID uint
+	var expiryBefore, verifiedAt sql.NullTime
+	var attemptErr sql.NullString
+	var a Attempt
+	err := row.Scan(&id, &domainID, &a.DomainName, &a.Actions, &a.TriggeredAt, &expiryBefore,
+		&attemptErr, &a.Output, &verifiedAt, &a.Renewed)
+	if err != nil {
+		return Attempt{}, err
+	}
+	a.AttemptID = id
+	a.DomainID = types.DomainID(domainID)
+	if expiryBefore.Valid {
+		a.ExpiryBefore = &expiryBefore.Time
+	}
+	if attemptErr.Valid {
+		a.Error = &attemptErr.String
+	}
+	if verifiedAt.Valid {
+		a.VerifiedAt = &verifiedAt.Time
+	}
+	return a, nil
+}
+
+// CreateAttempt stores an attempt and sets its ID
+func (r *Repository) CreateAttempt(a *Attempt) error {
+	query := `INSERT INTO renewal_attempts (domain_id, actions, triggered_at, expiry_before, error, output, verified_at, renewed)
+              VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := r.writer.Exec(query, a.DomainID.Uint(), a.Actions, a.TriggeredAt, a.ExpiryBefore, a.Error, a.Output, a.VerifiedAt, a.Renewed)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	a.AttemptID = uint(id)
+	return nil
+}
+
+// GetLatestAttempt returns the domain's most recent attempt, nil if there has been none
+func (r *Repository) GetLatestAttempt(domainID types.DomainID) (*Attempt, error) {
+	a, err := scanAttempt(r.db.QueryRow(selectAttempts+` WHERE a.domain_id = ? ORDER BY a.id DESC LIMIT 1`, domainID.Uint()))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// GetAttemptsByUserID lists the attempts for a user's domains, newest first
+func (r *Repository) GetAttemptsByUserID(userID types.UserID, limit int) ([]Attempt, error) {
+	query := selectAttempts + ` WHERE d.user_id = ? ORDER BY a.id DESC LIMIT ?`
+	rows, err := r.db.Query(query, userID.Uint(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []Attempt{}
+	for rows.Next() {
+		a, err := scanAttempt(rows)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
+
+// RecordVerification stores what the check after an attempt found
+func (r *Repository) RecordVerification(id uint, verifiedAt time.Time, renewed bool) error {
+	_, err := r.writer.Exec(`UPDATE renewal_attempts SET verified_at = ?, renewed = ? WHERE id = ?`, verifiedAt, renewed, id)
+	return err
+}
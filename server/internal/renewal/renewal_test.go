@@ -0,0 +1,156 @@
+package renewal
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAction records the requests it was run for.
+type fakeAction struct {
+	runs []Request
+	err  error
+}
+
+func (a *fakeAction) Name() string {
+	return "fake"
+}
+
+func (a *fakeAction) Run(ctx context.Context, req Request) (string, error) {
+	a.runs = append(a.runs, req)
+	return "ran", a.err
+}
+
+// newTestRepository creates a repository over a fresh database holding one domain.
+func newTestRepository(t *testing.T) (*Repository, domain.Domain) {
+	t.Helper()
+
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	d := domain.Domain{
+		UserID:     types.UserID(1),
+		DomainName: domain.NewDomainName("example.com"),
+		CreatedAt:  domain.NewCreatedAt(time.Now()),
+		IsActive:   true,
+		Tags:       []string{"certbot"},
+	}
+	require.NoError(t, domain.NewRepository(db).CreateDomain(&d))
+	return NewRepository(db), d
+}
+
+// checked returns the domain as a check at checkedAt found it.
+func checked(d domain.Domain, checkedAt, expiry time.Time) domain.Domain {
+	e := types.NewExpiryDate(expiry)
+	c := domain.NewLastChecked(checkedAt)
+	d.ExpiryDate = &e
+	d.LastChecked = &c
+	return d
+}
+
+// TestRenewer_Evaluate - crossing the threshold renews once and the next check records the outcome.
+func TestRenewer_Evaluate(t *testing.T) {
+	repo, d := newTestRepository(t)
+	action := &fakeAction{}
+	r := NewRenewer(repo, 14, time.Minute, 24*time.Hour, action)
+	now := time.Now().Truncate(time.Second)
+	r.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	oldExpiry := now.Add(20 * 24 * time.Hour)
+	require.NoError(t, r.Evaluate(ctx, checked(d, now, oldExpiry)))
+	assert.Empty(t, action.runs, "Not within the threshold yet")
+
+	oldExpiry = now.Add(10*24*time.Hour + time.Hour)
+	require.NoError(t, r.Evaluate(ctx, checked(d, now, oldExpiry)))
+	require.Len(t, action.runs, 1)
+	assert.Equal(t, Request{Domain: "example.com", ExpiryDate: &oldExpiry, DaysLeft: 10}, action.runs[0])
+
+	require.NoError(t, r.Evaluate(ctx, checked(d, now, oldExpiry)))
+	assert.Len(t, action.runs, 1, "Waits for the check after the attempt")
+
+	latest, err := repo.GetLatestAttempt(d.DomainID)
+	require.NoError(t, err)
+	assert.Equal(t, OutcomePending, latest.Outcome())
+	assert.Equal(t, "fake", latest.Actions)
+	assert.Equal(t, "ran", latest.Output)
+
+	now = now.Add(time.Hour)
+	require.NoError(t, r.Evaluate(ctx, checked(d, now, oldExpiry)))
+	latest, err = repo.GetLatestAttempt(d.DomainID)
+	require.NoError(t, err)
+	assert.Equal(t, OutcomeNotRenewed, latest.Outcome())
+	assert.Len(t, action.runs, 1, "Not retried before the retry period")
+
+	now = now.Add(24 * time.Hour)
+	require.NoError(t, r.Evaluate(ctx, checked(d, now, oldExpiry)))
+	require.Len(t, action.runs, 2, "Retried once the retry period passed")
+
+	now = now.Add(time.Hour)
+	newExpiry := now.Add(90 * 24 * time.Hour)
+	require.NoError(t, r.Evaluate(ctx, checked(d, now, newExpiry)))
+	assert.Len(t, action.runs, 2)
+	attempts, err := repo.GetAttemptsByUserID(types.UserID(1), 10)
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+	assert.Equal(t, OutcomeRenewed, attempts[0].Outcome())
+	assert.Equal(t, OutcomeNotRenewed, attempts[1].Outcome())
+}
+
+// TestRenewer_FailedAndTags - failed actions are recorded and untagged domains are left alone.
+func TestRenewer_FailedAndTags(t *testing.T) {
+	repo, d := newTestRepository(t)
+	action := &fakeAction{err: errors.New("certbot exited with status 1")}
+	r := NewRenewer(repo, 14, time.Minute, 24*time.Hour, action)
+	r.SetTags([]string{"acme"})
+	now := time.Now()
+	expiry := now.Add(5 * 24 * time.Hour)
+
+	require.NoError(t, r.Evaluate(context.Background(), checked(d, now, expiry)))
+	assert.Empty(t, action.runs)
+
+	r.SetTags([]string{"acme", "certbot"})
+	require.NoError(t, r.Evaluate(context.Background(), checked(d, now, expiry)))
+	require.Len(t, action.runs, 1)
+	latest, err := repo.GetLatestAttempt(d.DomainID)
+	require.NoError(t, err)
+	assert.Equal(t, OutcomeFailed, latest.Outcome())
+	assert.Contains(t, *latest.Error, "status 1")
+}
+
+// TestActions - the command sees the domain in its environment and the webhook gets it as JSON.
+func TestActions(t *testing.T) {
+	expiry := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	req := Request{Domain: "example.com", ExpiryDate: &expiry, DaysLeft: 3}
+
+	out, err := NewCommandAction(`echo "$SSLCERTTOP_DOMAIN $SSLCERTTOP_EXPIRY $SSLCERTTOP_DAYS_LEFT"`).Run(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "example.com 2026-03-01T12:00:00Z 3", out)
+	_, err = NewCommandAction("exit 2").Run(context.Background(), req)
+	assert.Error(t, err)
+
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.Write([]byte("queued"))
+	}))
+	defer server.Close()
+
+	out, err = NewWebhookAction(server.URL, nil).Run(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "queued", out)
+	assert.JSONEq(t, `{"event":"renewal","domain":"example.com","expiry_date":"2026-03-01T12:00:00Z","days_left":3}`, body)
+}
@@ -0,0 +1,145 @@
+package renewal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+)
+
+// Renewer runs the renewal actions for domains that come within the threshold of expiry
+type Renewer struct {
+	repo      *Repository
+	actions   []Action
+	threshold int
+	timeout   time.Duration
+	retry     time.Duration
+	tags      []string
+	now       func() time.Time
+}
+
+// NewRenewer renews certificates with threshold days or fewer left, giving the actions timeout to finish.
+//
+// A certificate the check after an attempt still finds is tried again once retry has passed
+func NewRenewer(repo *Repository, threshold int, timeout, retry time.Duration, actions ...Action) *Renewer {
+	return &Renewer{
+		repo:      repo,
+		actions:   actions,
+		threshold: threshold,
+		timeout:   timeout,
+		retry:     retry,
+		now:       time.Now,
+	}
+}
+
+// SetTags limits renewals to domains with one of the tags, empty renews every domain
+func (r *Renewer) SetTags(tags []string) {
+	r.tags = tags
+}
+
+// HasActions reports whether there is anything to run
+func (r *Renewer) HasActions() bool {
+	return len(r.actions) > 0
+}
+
+// applies reports whether a domain's certificate is renewed by the actions
+func (r *Renewer) applies(d domain.Domain) bool {
+	if len(r.tags) == 0 {
+		return true
+	}
+	for _, tag := range d.Tags {
+		if slices.Contains(r.tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate looks at a freshly checked domain.
+//
+// It records whether the check found a new certificate after a pending attempt, and runs the actions
+// when the certificate is within the threshold and hasn't been tried within the retry period
+func (r *Renewer) Evaluate(ctx context.Context, d domain.Domain) error {
+	if !r.HasActions() || !r.applies(d) {
+		return nil
+	}
+	now := r.now()
+	expiry := d.ExpiryTime()
+
+	latest, err := r.repo.GetLatestAttempt(d.DomainID)
+	if err != nil {
+		return fmt.Errorf("failed to look up renewal attempts: %w", err)
+	}
+	if latest != nil && latest.Outcome() == OutcomePending {
+		if d.LastChecked == nil || !d.LastChecked.Time().After(latest.TriggeredAt) {
+			// The check that can tell whether it worked hasn't run yet
+			return nil
+		}
+		renewed := expiry != nil && (latest.ExpiryBefore == nil || expiry.After(*latest.ExpiryBefore))
+		if err := r.repo.RecordVerification(latest.AttemptID, d.LastChecked.Time(), renewed); err != nil {
+			return fmt.Errorf("failed to record renewal outcome: %w", err)
+		}
+		if renewed {
+			slog.Info("Certificate renewed", "domain", d.DomainName.String(), "expiry", expiry)
+			return nil
+		}
+		slog.Warn("Renewal did not replace the certificate", "domain", d.DomainName.String(), "attempt", latest.AttemptID)
+	}
+
+	if expiry == nil {
+		return nil
+	}
+	daysLeft := int(expiry.Sub(now).Hours() / 24)
+	if daysLeft > r.threshold {
+		return nil
+	}
+	if latest != nil && latest.sameCertificate(expiry) && now.Sub(latest.TriggeredAt) < r.retry {
+		return nil
+	}
+	return r.run(ctx, d, Request{Domain: d.DomainName.String(), ExpiryDate: expiry, DaysLeft: daysLeft}, now)
+}
+
+// run executes every action for a domain and stores the attempt
+func (r *Renewer) run(ctx context.Context, d domain.Domain, req Request, now time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var names, outputs []string
+	var errs []error
+	for _, action := range r.actions {
+		names = append(names, action.Name())
+		out, err := action.Run(ctx, req)
+		if out != "" {
+			outputs = append(outputs, out)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	a := Attempt{
+		DomainID:     d.DomainID,
+		DomainName:   req.Domain,
+		Actions:      strings.Join(names, ","),
+		TriggeredAt:  now,
+		ExpiryBefore: req.ExpiryDate,
+		Output:       strings.Join(outputs, "\n"),
+	}
+	if err := errors.Join(errs...); err != nil {
+		msg := err.Error()
+		a.Error = &msg
+		slog.Error("Renewal failed", "domain", req.Domain, "days_left", req.DaysLeft, "error", err)
+	} else {
+		slog.Info("Renewal started", "domain", req.Domain, "days_left", req.DaysLeft, "actions", a.Actions)
+	}
+
+	if err := r.repo.CreateAttempt(&a); err != nil {
+		return fmt.Errorf("failed to record renewal attempt: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,328 @@
+// This package runs unattended SSL certificate sweeps
+//
+// It periodically checks every domain that is due, persists the results and dispatches notifications
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/cron"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/notification"
+	"github.com/samokw/ssl_tracker/internal/renewal"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// Scheduler sweeps due domains on a fixed tick
+type Scheduler struct {
+	domainService   *domain.Service
+	dispatcher      *notification.Dispatcher
+	alerter         *notification.Alerter
+	digester        *notification.Digester
+	renewer         *renewal.Renewer
+	tick            time.Duration
+	defaultInterval time.Duration
+	schedule        *cron.Schedule
+	tagSchedules    map[string]*cron.Schedule
+	jitter          float64
+	random          func() float64 // Returns values in [0, 1), replaced in tests
+	lastBeat        atomic.Int64   // Unix nanoseconds of the last loop iteration, zero when not running
+	sweeping        atomic.Bool
+}
+
+// NewScheduler creates a scheduler that looks for due domains every tick.
+//
+// Domains without their own check interval are checked every defaultInterval
+func NewScheduler(domainService *domain.Service, dispatcher *notification.Dispatcher, tick, defaultInterval time.Duration) *Scheduler {
+	return &Scheduler{
+		domainService:   domainService,
+		dispatcher:      dispatcher,
+		tick:            tick,
+		defaultInterval: defaultInterval,
+		random:          rand.Float64,
+	}
+}
+
+// SetJitter moves each interval-based check by up to fraction of its interval either way, e.g. 0.1 for ±10%, so
+// checks drift apart instead of all falling due together.
+//
+// With jitter, domains overdue without a planned check, e.g. on the first start, are spread across their interval
+// instead of all being checked at once
+func (s *Scheduler) SetJitter(fraction float64) {
+	s.jitter = fraction
+}
+
+// SetSchedules replaces the fixed interval with cron schedules.
+//
+// A domain uses its own schedule first, then the schedule of its first matching tag, then the global one.
+// Without any schedule the default interval applies
+func (s *Scheduler) SetSchedules(global *cron.Schedule, byTag map[string]*cron.Schedule) {
+	s.schedule = global
+	s.tagSchedules = byTag
+}
+
+// SetAlerter pages on-call providers about failing certificates after every sweep
+func (s *Scheduler) SetAlerter(alerter *notification.Alerter) {
+	s.alerter = alerter
+}
+
+// SetDigester sends digests from the sweep whenever their schedule comes round, nil sends none
+func (s *Scheduler) SetDigester(digester *notification.Digester) {
+	s.digester = digester
+}
+
+// SetRenewer starts renewals for checked domains close to expiry, nil starts none
+func (s *Scheduler) SetRenewer(renewer *renewal.Renewer) {
+	s.renewer = renewer
+}
+
+// scheduleFor picks the cron schedule that governs a domain, or nil to use intervals
+func (s *Scheduler) scheduleFor(d domain.Domain) *cron.Schedule {
+	if d.CheckSchedule != "" {
+		sched, err := cron.Parse(d.CheckSchedule)
+		if err == nil {
+			return sched
+		}
+		slog.Warn("Ignoring invalid domain schedule", "domain", d.DomainName.String(), "schedule", d.CheckSchedule, "error", err)
+	}
+	for _, tag := range d.Tags {
+		if sched, ok := s.tagSchedules[tag]; ok {
+			return sched
+		}
+	}
+	return s.schedule
+}
+
+// isDue reports whether a domain should be checked in this sweep
+func (s *Scheduler) isDue(d domain.Domain, now time.Time) bool {
+	if !d.IsActive {
+		return false
+	}
+	if d.LastChecked == nil {
+		return true
+	}
+	if sched := s.scheduleFor(d); sched != nil {
+		return sched.DueSince(d.LastChecked.Time(), now)
+	}
+	if d.NextCheckAt != nil {
+		return !now.Before(*d.NextCheckAt)
+	}
+	return d.IsDue(now, s.defaultInterval)
+}
+
+// intervalOf is how often a domain without a cron schedule is checked
+func (s *Scheduler) intervalOf(d domain.Domain) time.Duration {
+	if d.CheckInterval > 0 {
+		return d.CheckInterval
+	}
+	return s.defaultInterval
+}
+
+// nextCheck is an interval after from, moved by up to the jitter either way
+func (s *Scheduler) nextCheck(d domain.Domain, from time.Time) time.Time {
+	interval := s.intervalOf(d)
+	offset := time.Duration((2*s.random() - 1) * s.jitter * float64(interval))
+	return from.Add(interval + offset)
+}
+
+// plan spreads domains that were checked before but have no check planned, e.g. after an upgrade or with jitter
+// turned on, across their interval, so a restart doesn't check them all at once. Domains not due yet keep their
+// place, overdue ones get a random time within the next interval
+func (s *Scheduler) plan(domains []domain.Domain, now time.Time) []domain.Domain {
+	if s.jitter <= 0 {
+		return domains
+	}
+	next := map[types.DomainID]time.Time{}
+	for i, d := range domains {
+		if !d.IsActive || d.LastChecked == nil || d.NextCheckAt != nil || s.scheduleFor(d) != nil {
+			continue
+		}
+		at := s.nextCheck(d, d.LastChecked.Time())
+		if at.Before(now) {
+			at = now.Add(time.Duration(s.random() * float64(s.intervalOf(d))))
+		}
+		next[d.DomainID] = at
+		domains[i].NextCheckAt = &at
+	}
+	if len(next) > 0 {
+		slog.Info("Spread checks across their interval", "domains", len(next))
+		if err := s.domainService.SetNextChecks(next); err != nil {
+			slog.Error("Failed to store planned checks", "error", err)
+		}
+	}
+	return domains
+}
+
+// planChecked plans the next check of freshly checked domains without a cron schedule
+func (s *Scheduler) planChecked(due []domain.Domain, checkedAt time.Time) {
+	next := map[types.DomainID]time.Time{}
+	for _, d := range due {
+		if s.scheduleFor(d) == nil {
+			next[d.DomainID] = s.nextCheck(d, checkedAt)
+		}
+	}
+	if err := s.domainService.SetNextChecks(next); err != nil {
+		slog.Error("Failed to store planned checks", "error", err)
+	}
+}
+
+// storedDomains are the domains of a sweep whose results were stored despite storeErr
+func storedDomains(domains []domain.Domain, storeErr *domain.StoreError) []domain.Domain {
+	stored := make([]domain.Domain, 0, len(domains))
+	for _, d := range domains {
+		if _, lost := storeErr.Domains[d.DomainName.String()]; !lost {
+			stored = append(stored, d)
+		}
+	}
+	return stored
+}
+
+// Run sweeps immediately and then on every tick until the context is cancelled
+func (s *Scheduler) Run(ctx context.Context) error {
+	slog.Info("Scheduler started", "tick", s.tick.String(), "default_interval", s.defaultInterval.String())
+
+	ticker := time.NewTicker(s.tick)
+	defer ticker.Stop()
+	defer s.lastBeat.Store(0)
+
+	for {
+		s.lastBeat.Store(time.Now().UnixNano())
+		s.sweeping.Store(true)
+		if err := s.Sweep(ctx); err != nil {
+			slog.Error("Sweep failed", "error", err)
+		}
+		s.sweeping.Store(false)
+		s.lastBeat.Store(time.Now().UnixNano())
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Scheduler stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Healthy reports whether Run is alive, returning an error if it isn't running or has missed its ticks.
+//
+// A long sweep counts as alive
+func (s *Scheduler) Healthy(ctx context.Context) error {
+	beat := s.lastBeat.Load()
+	if beat == 0 {
+		return errors.New("scheduler is not running")
+	}
+	if s.sweeping.Load() {
+		return nil
+	}
+	if since := time.Since(time.Unix(0, beat)); since > 2*s.tick+time.Minute {
+		return fmt.Errorf("scheduler last ran %s ago", since.Round(time.Second))
+	}
+	return nil
+}
+
+// Sweep checks every due domain, then queues and delivers notifications and updates incidents
+func (s *Scheduler) Sweep(ctx context.Context) error {
+	now := time.Now()
+	domains, err := s.domainService.GetActiveDomains()
+	if err != nil {
+		return fmt.Errorf("failed to get domains: %w", err)
+	}
+
+	due := []domain.Domain{}
+	for _, d := range s.plan(domains, now) {
+		if s.isDue(d, now) {
+			due = append(due, d)
+		}
+	}
+	if len(due) == 0 {
+		// Held back and escalated notifications and digests still go out between checks
+		return s.deliver(ctx, now)
+	}
+
+	return s.SweepDomains(ctx, due)
+}
+
+// SweepDomains checks domains whether they are due or not, then queues and delivers notifications and updates
+// incidents as Sweep does
+func (s *Scheduler) SweepDomains(ctx context.Context, domains []domain.Domain) error {
+	now := time.Now()
+	slog.Info("Sweep started", "domains", len(domains))
+	if err := s.domainService.CheckDomainsSSLSync(domains); err != nil {
+		// The domains whose results were stored wait for their next check all the same, or they'd be checked
+		// again on every tick
+		var storeErr *domain.StoreError
+		if errors.As(err, &storeErr) {
+			s.planChecked(storedDomains(domains, storeErr), now)
+		}
+		return fmt.Errorf("failed to check domains: %w", err)
+	}
+	s.planChecked(domains, now)
+
+	s.evaluate(ctx, domains)
+
+	if err := s.deliver(ctx, time.Now()); err != nil {
+		return err
+	}
+
+	slog.Info("Sweep completed", "domains", len(domains), "duration", time.Since(now))
+	return nil
+}
+
+// evaluate updates incidents, starts renewals and queues notifications for freshly checked domains
+func (s *Scheduler) evaluate(ctx context.Context, due []domain.Domain) {
+	notify := s.dispatcher != nil && s.dispatcher.HasSenders()
+	alert := s.alerter != nil && s.alerter.HasProviders()
+	renew := s.renewer != nil && s.renewer.HasActions()
+	if !notify && !alert && !renew {
+		return
+	}
+
+	for _, d := range due {
+		checked, err := s.domainService.GetDomain(d.DomainID)
+		if err != nil {
+			slog.Error("Failed to reload domain", "domain", d.DomainName.String(), "error", err)
+			continue
+		}
+		if alert {
+			if err := s.alerter.Evaluate(ctx, *checked); err != nil {
+				slog.Error("Failed to update incidents", "domain", d.DomainName.String(), "error", err)
+			}
+		}
+		if renew {
+			if err := s.renewer.Evaluate(ctx, *checked); err != nil {
+				slog.Error("Failed to evaluate renewal", "domain", d.DomainName.String(), "error", err)
+			}
+		}
+		if !notify {
+			continue
+		}
+		if err := s.dispatcher.EvaluateDomain(*checked, time.Now()); err != nil {
+			slog.Error("Failed to evaluate notifications", "domain", d.DomainName.String(), "error", err)
+		}
+	}
+}
+
+// deliver sends a digest if one is due, escalates unacknowledged notifications and sends everything pending
+func (s *Scheduler) deliver(ctx context.Context, now time.Time) error {
+	if s.digester != nil {
+		if err := s.digester.SendIfDue(ctx, now); err != nil {
+			slog.Error("Failed to send digest", "error", err)
+		}
+	}
+	if s.dispatcher == nil || !s.dispatcher.HasSenders() {
+		return nil
+	}
+	if err := s.dispatcher.Escalate(now); err != nil {
+		slog.Error("Failed to escalate notifications", "error", err)
+	}
+	if err := s.dispatcher.DeliverPending(ctx); err != nil {
+		return fmt.Errorf("failed to deliver notifications: %w", err)
+	}
+	return nil
+}
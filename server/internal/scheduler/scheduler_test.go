@@ -0,0 +1,213 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/cron"
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/notification"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/samokw/ssl_tracker/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestScheduler creates a scheduler backed by an empty SQLite database.
+func newTestScheduler(t *testing.T) *Scheduler {
+	t.Helper()
+
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	sslService := ssl.NewCertService()
+	t.Cleanup(sslService.Stop)
+
+	domainService := domain.NewService(domain.NewRepository(db), sslService)
+	dispatcher := notification.NewDispatcher(notification.NewRepository(db), notification.DefaultThresholds)
+	return NewScheduler(domainService, dispatcher, 10*time.Millisecond, time.Hour)
+}
+
+// TestScheduler_SweepEmpty - nothing to do is not an error.
+func TestScheduler_SweepEmpty(t *testing.T) {
+	s := newTestScheduler(t)
+	assert.NoError(t, s.Sweep(context.Background()))
+}
+
+// TestScheduler_RunStopsOnCancel - Run returns once the context is cancelled.
+func TestScheduler_RunStopsOnCancel(t *testing.T) {
+	s := newTestScheduler(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- s.Run(ctx)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run() did not stop after cancel")
+	}
+}
+
+// TestScheduler_IsDue - domain schedules beat tag schedules beat the global schedule.
+func TestScheduler_IsDue(t *testing.T) {
+	s := newTestScheduler(t)
+
+	hourly, err := cron.Parse("@hourly")
+	require.NoError(t, err)
+	yearly, err := cron.Parse("@yearly")
+	require.NoError(t, err)
+	s.SetSchedules(yearly, map[string]*cron.Schedule{"prod": hourly})
+
+	now := time.Date(2025, time.March, 14, 10, 30, 0, 0, time.Local)
+	lastChecked := domain.NewLastChecked(now.Add(-45 * time.Minute))
+
+	tests := []struct {
+		name   string
+		domain domain.Domain
+		want   bool
+	}{
+		{"never checked", domain.Domain{IsActive: true}, true},
+		{"global yearly schedule", domain.Domain{IsActive: true, LastChecked: &lastChecked}, false},
+		{"prod tag hourly schedule", domain.Domain{IsActive: true, LastChecked: &lastChecked, Tags: []string{"prod"}}, true},
+		{"own schedule wins", domain.Domain{IsActive: true, LastChecked: &lastChecked, Tags: []string{"prod"}, CheckSchedule: "@daily"}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, s.isDue(tc.domain, now))
+		})
+	}
+}
+
+// TestScheduler_Plan - overdue domains are spread across their interval, the rest keep their place with jitter.
+func TestScheduler_Plan(t *testing.T) {
+	s := newTestScheduler(t)
+	s.SetJitter(0.5)
+	s.random = func() float64 { return 0.75 }
+
+	now := time.Date(2025, time.March, 14, 10, 30, 0, 0, time.Local)
+	overdue := domain.NewLastChecked(now.Add(-48 * time.Hour))
+	recent := domain.NewLastChecked(now.Add(-20 * time.Minute))
+	domains := s.plan([]domain.Domain{
+		{DomainID: 1, IsActive: true, LastChecked: &overdue},
+		{DomainID: 2, IsActive: true, LastChecked: &recent},
+		{DomainID: 3, IsActive: true},
+		{DomainID: 4, IsActive: true, LastChecked: &overdue, CheckSchedule: "@daily"},
+	}, now)
+
+	require.NotNil(t, domains[0].NextCheckAt)
+	assert.Equal(t, now.Add(45*time.Minute), *domains[0].NextCheckAt, "Somewhere within the next hour")
+	require.NotNil(t, domains[1].NextCheckAt)
+	assert.Equal(t, now.Add(55*time.Minute), *domains[1].NextCheckAt, "An hour after the last check, plus a quarter hour of jitter")
+	assert.Nil(t, domains[2].NextCheckAt, "Never checked domains are due right away")
+	assert.Nil(t, domains[3].NextCheckAt, "Cron schedules aren't moved")
+
+	assert.False(t, s.isDue(domains[0], now))
+	assert.True(t, s.isDue(domains[0], now.Add(45*time.Minute)))
+	assert.True(t, s.isDue(domains[2], now))
+}
+
+// TestScheduler_Healthy - only healthy while Run is looping.
+func TestScheduler_Healthy(t *testing.T) {
+	s := newTestScheduler(t)
+	assert.Error(t, s.Healthy(context.Background()), "Not running yet")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- s.Run(ctx)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return s.Healthy(context.Background()) == nil
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	assert.Error(t, s.Healthy(context.Background()), "Stopped")
+}
+
+// TestScheduler_SweepDomains - the domains are checked whether they are due or not.
+func TestScheduler_SweepDomains(t *testing.T) {
+	s := newTestScheduler(t)
+	var checks atomic.Int32
+	s.domainService.SetChecker(offlineChecker(func(_ context.Context, name string) (*ssl.SSLCertificate, error) {
+		checks.Add(1)
+		expiry := time.Now().AddDate(0, 0, 60)
+		return &ssl.SSLCertificate{Hostname: ssl.Hostname(name), ExpiryDate: types.NewExpiryDate(expiry), TimeLeft: 60}, nil
+	}))
+
+	d, err := s.domainService.AddTeamDomainUnchecked(user.DefaultUserID, 0, "example.com")
+	require.NoError(t, err)
+	for range 2 {
+		require.NoError(t, s.SweepDomains(context.Background(), []domain.Domain{*d}))
+	}
+
+	assert.Equal(t, int32(2), checks.Load())
+	checked, err := s.domainService.GetDomain(d.DomainID)
+	require.NoError(t, err)
+	require.NotNil(t, checked.ExpiryTime())
+	assert.False(t, s.isDue(*checked, time.Now()))
+}
+
+// TestScheduler_SweepPlansStoredResults - a sweep that lost some results still plans the next check of the domains
+// whose results were stored, only the others stay due.
+func TestScheduler_SweepPlansStoredResults(t *testing.T) {
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	sslService := ssl.NewCertService()
+	t.Cleanup(sslService.Stop)
+	s := NewScheduler(domain.NewService(domain.NewRepository(db), sslService), nil, 10*time.Millisecond, time.Hour)
+	s.domainService.SetChecker(offlineChecker(func(_ context.Context, name string) (*ssl.SSLCertificate, error) {
+		if name == "www.example.com" {
+			// Lands in a later batch than example.com's result, so only its batch fails
+			time.Sleep(time.Second)
+		}
+		expiry := time.Now().AddDate(0, 0, 60)
+		return &ssl.SSLCertificate{Hostname: ssl.Hostname(name), ExpiryDate: types.NewExpiryDate(expiry), TimeLeft: 60}, nil
+	}))
+
+	apex, err := s.domainService.AddTeamDomainUnchecked(user.DefaultUserID, 0, "example.com")
+	require.NoError(t, err)
+	www, err := s.domainService.AddTeamDomainUnchecked(user.DefaultUserID, 0, "www.example.com")
+	require.NoError(t, err)
+	_, err = db.Exec(fmt.Sprintf(`CREATE TRIGGER fail_www BEFORE UPDATE OF last_checked ON domains WHEN NEW.id = %d
+		BEGIN SELECT RAISE(ABORT, 'disk full'); END`, www.DomainID.Uint()))
+	require.NoError(t, err)
+
+	err = s.SweepDomains(context.Background(), []domain.Domain{*apex, *www})
+	var storeErr *domain.StoreError
+	require.ErrorAs(t, err, &storeErr)
+	assert.Len(t, storeErr.Domains, 1)
+
+	stored, err := s.domainService.GetDomain(apex.DomainID)
+	require.NoError(t, err)
+	assert.False(t, s.isDue(*stored, time.Now()), "The stored result's next check is planned")
+	lost, err := s.domainService.GetDomain(www.DomainID)
+	require.NoError(t, err)
+	assert.True(t, s.isDue(*lost, time.Now()), "The lost result's domain is checked again")
+}
+
+// offlineChecker accepts every name without resolving it
+type offlineChecker ssl.CheckerFunc
+
+func (c offlineChecker) CheckSSLCertificate(ctx context.Context, name string) (*ssl.SSLCertificate, error) {
+	return c(ctx, name)
+}
+
+func (offlineChecker) ValidateTarget(string) error { return nil }
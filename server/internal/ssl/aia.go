@@ -0,0 +1,167 @@
+package ssl
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// maxAIADepth is how many missing issuers are fetched before the chain is given up on
+	maxAIADepth = 3
+	// maxAIASize caps the size of a fetched issuer certificate
+	maxAIASize = 1 << 20
+)
+
+// aiaClient fetches issuer certificates. AIA URLs are plain HTTP, the certificates vouch for themselves
+var aiaClient = &http.Client{Timeout: 10 * time.Second}
+
+// verifyChain verifies the certificates a server sent for dnsName against roots. When the server left out an
+// intermediate, the missing issuers are fetched from the Authority Information Access URLs of the certificates
+// the way browsers do: a chain that verifies then passes with a warning rather than failing, since clients
+// without AIA fetching, such as curl, Go and most API clients, will still reject it. A chain only one of roots
+// and compare trusts is reported too, as a warning when roots trusts it and in the error otherwise.
+//
+// Returns the warnings, empty for a complete chain both stores trust, or the verification error of the chain
+func verifyChain(ctx context.Context, certs []*x509.Certificate, dnsName string, roots RootStore, compare *RootStore) (string, error) {
+	if len(certs) == 0 {
+		return "", errors.New("no certificates found")
+	}
+	opts := x509.VerifyOptions{DNSName: dnsName, Roots: roots.Pool, Intermediates: x509.NewCertPool()}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	// Verifies the chain, with the intermediates fetched so far, against the other store
+	trustedByCompare := func() bool {
+		o := opts
+		o.Roots = compare.Pool
+		_, err := certs[0].Verify(o)
+		return err == nil
+	}
+
+	fetched, err := completeChain(ctx, certs, opts)
+	if err != nil {
+		var unknownAuthority x509.UnknownAuthorityError
+		if compare != nil && errors.As(err, &unknownAuthority) && trustedByCompare() {
+			err = fmt.Errorf("%w, though %s trusts it", err, compare)
+		}
+		return "", err
+	}
+	var warnings []string
+	if len(fetched) > 0 {
+		warnings = append(warnings, fmt.Sprintf("incomplete chain served: the server doesn't send %s, clients that "+
+			"don't fetch missing intermediates will reject the certificate", strings.Join(fetched, ", ")))
+	}
+	if compare != nil && !trustedByCompare() {
+		warnings = append(warnings, fmt.Sprintf("trusted by %s but not by %s", roots, compare))
+	}
+	return strings.Join(warnings, "; "), nil
+}
+
+// completeChain verifies the leaf of certs, fetching up to maxAIADepth missing issuers into opts.Intermediates
+// when it doesn't verify for want of them.
+//
+// Returns the names of the issuers fetched, or the verification error of the chain as served
+func completeChain(ctx context.Context, certs []*x509.Certificate, opts x509.VerifyOptions) ([]string, error) {
+	leaf := certs[0]
+	_, err := leaf.Verify(opts)
+	var unknownAuthority x509.UnknownAuthorityError
+	if err == nil || !errors.As(err, &unknownAuthority) {
+		return nil, err
+	}
+
+	// The last certificate sent is the one whose issuer is missing when the chain is in order
+	current := certs[len(certs)-1]
+	var fetched []string
+	for range maxAIADepth {
+		issuer, fetchErr := fetchIssuer(ctx, current)
+		if fetchErr != nil {
+			return nil, err
+		}
+		opts.Intermediates.AddCert(issuer)
+		fetched = append(fetched, IssuerName(current))
+		if _, verifyErr := leaf.Verify(opts); verifyErr == nil {
+			return fetched, nil
+		}
+		current = issuer
+	}
+	return nil, err
+}
+
+// fetchIssuer downloads the certificate that issued cert from its Authority Information Access URLs
+func fetchIssuer(ctx context.Context, cert *x509.Certificate) (*x509.Certificate, error) {
+	if len(cert.IssuingCertificateURL) == 0 {
+		return nil, fmt.Errorf("%s names no issuer URL", cert.Subject.CommonName)
+	}
+	var lastErr error
+	for _, url := range cert.IssuingCertificateURL {
+		issuer, err := fetchCertificate(ctx, url)
+		if err == nil && cert.CheckSignatureFrom(issuer) == nil {
+			return issuer, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("%s didn't issue %s", url, cert.Subject.CommonName)
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// fetchCertificate downloads a DER certificate, or the first of a PKCS#7 bundle, or a PEM one
+func fetchCertificate(ctx context.Context, url string) (*x509.Certificate, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("unsupported issuer URL %s", url)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := aiaClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAIASize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+
+	if cert, err := x509.ParseCertificate(data); err == nil {
+		return cert, nil
+	}
+	if certs, err := ParseChainPKCS7(data); err == nil && len(certs) > 0 {
+		return certs[0], nil
+	}
+	if certs, err := ParseChainPEM(data); err == nil && len(certs) > 0 {
+		return certs[0], nil
+	}
+	return nil, fmt.Errorf("%s holds no certificate", url)
+}
+
+// verifyingConfig is the TLS configuration of checks, verifying the chain with verifyChain against the root stores
+// set and recording its warning. Failures read like those of the standard verification
+func verifyingConfig(ctx context.Context, hostname Hostname, warning *string) *tls.Config {
+	return &tls.Config{
+		ServerName: hostname.String(),
+		// The chain is verified below instead, with the missing intermediates fetched
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			roots, compare := rootStores()
+			w, err := verifyChain(ctx, cs.PeerCertificates, hostname.String(), roots, compare)
+			if err != nil {
+				return &tls.CertificateVerificationError{UnverifiedCertificates: cs.PeerCertificates, Err: err}
+			}
+			*warning = w
+			return nil
+		},
+	}
+}
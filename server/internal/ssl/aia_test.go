@@ -0,0 +1,90 @@
+package ssl
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestIssued creates a certificate signed by parent, a self-signed CA when parent is nil.
+func newTestIssued(t *testing.T, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(24 * time.Hour)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+// TestVerifyChain - a missing intermediate fetched from the AIA URL is a warning, not a failure.
+func TestVerifyChain(t *testing.T) {
+	ca := &x509.Certificate{Subject: pkix.Name{CommonName: "Test Root"}, IsCA: true, BasicConstraintsValid: true,
+		KeyUsage: x509.KeyUsageCertSign}
+	root, rootKey := newTestIssued(t, ca, nil, nil)
+	intermediate, intermediateKey := newTestIssued(t, &x509.Certificate{Subject: pkix.Name{CommonName: "Test Intermediate"},
+		IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign}, root, rootKey)
+
+	served := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !served {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(intermediate.Raw)
+	}))
+	t.Cleanup(server.Close)
+
+	leaf, _ := newTestIssued(t, &x509.Certificate{Subject: pkix.Name{CommonName: "example.com"}, DNSNames: []string{"example.com"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, IssuingCertificateURL: []string{server.URL + "/int.der"}},
+		intermediate, intermediateKey)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(root)
+	roots := RootStore{Name: "test.pem", Pool: pool}
+	ctx := context.Background()
+
+	warning, err := verifyChain(ctx, []*x509.Certificate{leaf, intermediate}, "example.com", roots, nil)
+	require.NoError(t, err)
+	assert.Empty(t, warning)
+
+	warning, err = verifyChain(ctx, []*x509.Certificate{leaf}, "example.com", roots, nil)
+	require.NoError(t, err)
+	assert.Contains(t, warning, "incomplete chain served")
+	assert.Contains(t, warning, "Test Intermediate")
+
+	// Another store without the root disagrees either way round
+	other := &RootStore{Name: "other.pem", Pool: x509.NewCertPool()}
+	warning, err = verifyChain(ctx, []*x509.Certificate{leaf, intermediate}, "example.com", roots, other)
+	require.NoError(t, err)
+	assert.Equal(t, "trusted by the root store test.pem but not by the root store other.pem", warning)
+	_, err = verifyChain(ctx, []*x509.Certificate{leaf, intermediate}, "example.com", *other, &roots)
+	assert.ErrorAs(t, err, &x509.UnknownAuthorityError{})
+	assert.ErrorContains(t, err, "though the root store test.pem trusts it")
+
+	served = false
+	_, err = verifyChain(ctx, []*x509.Certificate{leaf}, "example.com", roots, nil)
+	assert.ErrorAs(t, err, &x509.UnknownAuthorityError{}, "An unrecoverable chain fails as untrusted")
+
+	_, err = verifyChain(ctx, []*x509.Certificate{leaf, intermediate}, "other.com", roots, nil)
+	assert.ErrorAs(t, err, &x509.HostnameError{})
+}
@@ -4,14 +4,21 @@
 package ssl
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"net/url"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -34,6 +41,27 @@ type SSLCertificate struct {
 	ExpiryDate types.ExpiryDate
 	// TimeLeft is the number days left until the certificate expires
 	TimeLeft TimeLeft
+	// Issuer names the CA that issued the certificate
+	Issuer string
+	// IssuerOrganization is the organization of the issuing CA, e.g. Let's Encrypt
+	IssuerOrganization string
+	// Warning describes a problem with a certificate that is otherwise fine, e.g. a CA its hostname's CAA
+	// records don't authorize
+	Warning string
+	// Fingerprint identifies the key seen, only SSH host checks record one
+	Fingerprint string
+	// CertFingerprint is the hex SHA-256 of the certificate, the same for every name serving it. Empty for SSH
+	// host keys and cloud certificates
+	CertFingerprint string
+	// SANs are the DNS names the certificate is valid for, empty for SSH host keys and cloud certificates
+	SANs []string
+	// IssuedAt is when the certificate became valid, zero for SSH host keys and cloud certificates
+	IssuedAt time.Time
+	// KeyType describes the certificate's public key, e.g. RSA 2048 or ECDSA P-256
+	KeyType string
+	// Signatures are the signature algorithms of the certificate and the intermediates sent with it, e.g.
+	// SHA256-RSA
+	Signatures []string
 }
 
 // Common hostname validation errors.
@@ -89,6 +117,12 @@ func ValidateHostname(hostname string) error {
 	return nil
 }
 
+// NormalizeHostname lower-cases a hostname and strips the trailing dot of a fully qualified name, so
+// Example.COM. and example.com are tracked as one
+func NormalizeHostname(hostname string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(hostname)), ".")
+}
+
 // ValidateHostnameDNS checks if a hostname can be resolved
 //
 // # It first validates the format, then runs a Host lookup on the hostname
@@ -128,6 +162,7 @@ func ValidateURL(hostname string) error {
 //
 // Returns the validated Hostname or an error if the validation fails
 func NewHostname(hostname string) (Hostname, error) {
+	hostname = NormalizeHostname(hostname)
 	if err := ValidateHostname(hostname); err != nil {
 		return "", err
 	}
@@ -175,9 +210,8 @@ func CheckSSLCertificate(ctx context.Context, hostname Hostname) (*SSLCertificat
 
 	logger.Debug("TCP connection established")
 
-	client := tls.Client(conn, &tls.Config{
-		ServerName: hostname.String(),
-	})
+	var warning string
+	client := tls.Client(conn, verifyingConfig(ctx, hostname, &warning))
 	err = client.HandshakeContext(ctx)
 	if err != nil {
 		logger.Error("TLS handshake failed", "error", err)
@@ -206,8 +240,67 @@ func CheckSSLCertificate(ctx context.Context, hostname Hostname) (*SSLCertificat
 	)
 
 	return &SSLCertificate{
-		Hostname:   hostname,
-		ExpiryDate: expiryDate,
-		TimeLeft:   timeLeft,
+		Hostname:           hostname,
+		ExpiryDate:         expiryDate,
+		TimeLeft:           timeLeft,
+		Issuer:             IssuerName(cert),
+		IssuerOrganization: issuerOrganization(cert),
+		CertFingerprint:    CertFingerprint(cert),
+		SANs:               cert.DNSNames,
+		Warning:            warning,
+		IssuedAt:           cert.NotBefore,
+		KeyType:            KeyType(cert),
+		Signatures:         ChainSignatures(certs),
 	}, nil
 }
+
+// CertFingerprint is the hex SHA-256 of the certificate
+func CertFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// KeyType describes the public key of a certificate, e.g. RSA 2048, ECDSA P-256 or Ed25519
+func KeyType(cert *x509.Certificate) string {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + key.Curve.Params().Name
+	}
+	return cert.PublicKeyAlgorithm.String()
+}
+
+// ChainSignatures are the distinct signature algorithms of a chain. The signature on a self-signed root isn't
+// relied on and is left out
+func ChainSignatures(certs []*x509.Certificate) []string {
+	var algorithms []string
+	for _, cert := range certs {
+		if cert.IsCA && bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+			continue
+		}
+		if algorithm := cert.SignatureAlgorithm.String(); !slices.Contains(algorithms, algorithm) {
+			algorithms = append(algorithms, algorithm)
+		}
+	}
+	return algorithms
+}
+
+// issuerOrganization is the organization of the certificate's issuer, empty when it names none
+func issuerOrganization(cert *x509.Certificate) string {
+	if len(cert.Issuer.Organization) == 0 {
+		return ""
+	}
+	return cert.Issuer.Organization[0]
+}
+
+// IssuerName is the common name of the certificate's issuer, or its organization when there is none
+func IssuerName(cert *x509.Certificate) string {
+	if cert.Issuer.CommonName != "" {
+		return cert.Issuer.CommonName
+	}
+	if len(cert.Issuer.Organization) > 0 {
+		return cert.Issuer.Organization[0]
+	}
+	return ""
+}
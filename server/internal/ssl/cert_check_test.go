@@ -63,6 +63,25 @@ func TestNewHostname(t *testing.T) {
 	assert.Equal(t, Hostname(""), h)
 }
 
+// TestNormalizeTarget - hostnames are lower-cased without a trailing dot, paths and identifiers are kept.
+func TestNormalizeTarget(t *testing.T) {
+	tests := map[string]string{
+		"example.com":                             "example.com",
+		" Example.COM. ":                          "example.com",
+		"ssh://Bastion.Example.com.":              "ssh://bastion.example.com",
+		"ssh://Bastion.Example.com:222":           "ssh://bastion.example.com:222",
+		"file:///etc/ssl/Site.crt":                "file:///etc/ssl/Site.crt",
+		"arn:aws:acm:us-east-1:1:certificate/AbC": "arn:aws:acm:us-east-1:1:certificate/AbC",
+	}
+	for name, want := range tests {
+		assert.Equal(t, want, NormalizeTarget(name), name)
+	}
+
+	h, err := NewHostname("WWW.Example.com.")
+	require.NoError(t, err)
+	assert.Equal(t, Hostname("www.example.com"), h)
+}
+
 // TestCheckSSLCertificate_CancelledContext - returns error if context cancelled.
 func TestCheckSSLCertificate_CancelledContext(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -0,0 +1,148 @@
+package ssl
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// FetchCertificateChain connects to the hostname and returns the certificate chain served by it.
+//
+// The leaf certificate is the first element, followed by any intermediates the server sent. A chain that doesn't
+// verify, e.g. an expired or untrusted one, is returned too, those are the ones worth inspecting.
+//
+// Returns the chain or an error if the connection or handshake failed
+func FetchCertificateChain(ctx context.Context, hostname Hostname) ([]*x509.Certificate, error) {
+	if !hostname.IsValid() {
+		return nil, ErrInvalidHostname
+	}
+	return fetchChain(ctx, hostname, net.JoinHostPort(hostname.String(), "443"))
+}
+
+// fetchChain connects to addr to fetch the chain served for hostname
+func fetchChain(ctx context.Context, hostname Hostname, addr string) ([]*x509.Certificate, error) {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", hostname, err)
+	}
+	defer conn.Close()
+
+	// Servers missing an intermediate still have their chain exported, as served
+	var warning string
+	client := tls.Client(conn, verifyingConfig(ctx, hostname, &warning))
+	if err := client.HandshakeContext(ctx); err != nil {
+		var verifyErr *tls.CertificateVerificationError
+		if errors.As(err, &verifyErr) && len(verifyErr.UnverifiedCertificates) > 0 {
+			return verifyErr.UnverifiedCertificates, nil
+		}
+		return nil, fmt.Errorf("TLS handshake failed for %s: %w", hostname, err)
+	}
+	defer client.Close()
+
+	certs := client.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificates found for %s", hostname)
+	}
+	return certs, nil
+}
+
+// EncodeChainPEM encodes a certificate chain as concatenated PEM blocks
+func EncodeChainPEM(certs []*x509.Certificate) string {
+	var b strings.Builder
+	for _, cert := range certs {
+		_ = pem.Encode(&b, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	return b.String()
+}
+
+// contentInfo and signedData are the PKCS#7 structures of a certificate bundle, a SignedData without content or
+// signers (RFC 2315)
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"optional"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      contentInfo
+	Certificates     asn1.RawValue
+	SignerInfos      asn1.RawValue
+}
+
+var (
+	oidData       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+)
+
+// EncodeChainPKCS7 encodes a certificate chain as a DER PKCS#7 bundle, the .p7b files Windows and Java tools read
+func EncodeChainPKCS7(certs []*x509.Certificate) ([]byte, error) {
+	var raw []byte
+	for _, cert := range certs {
+		raw = append(raw, cert.Raw...)
+	}
+	emptySet := asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true}
+	sd, err := asn1.Marshal(signedData{
+		Version:          1,
+		DigestAlgorithms: emptySet,
+		ContentInfo:      contentInfo{ContentType: oidData},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: raw},
+		SignerInfos:      emptySet,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode PKCS#7 bundle: %w", err)
+	}
+	return asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sd},
+	})
+}
+
+// ParseChainPKCS7 decodes the certificates of a DER PKCS#7 bundle, like the .p7c files some CAs publish their
+// intermediates as
+func ParseChainPKCS7(data []byte) ([]*x509.Certificate, error) {
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(data, &ci); err != nil {
+		return nil, fmt.Errorf("failed to parse PKCS#7 bundle: %w", err)
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("PKCS#7 bundle holds %v, not signed data", ci.ContentType)
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("failed to parse PKCS#7 bundle: %w", err)
+	}
+	return x509.ParseCertificates(sd.Certificates.Bytes)
+}
+
+// ParseChainPEM decodes the CERTIFICATE blocks of a PEM encoded chain, skipping any other blocks.
+//
+// Returns the certificates in order or an error if a certificate can't be parsed
+func ParseChainPEM(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate %d: %w", len(certs)+1, err)
+		}
+		certs = append(certs, cert)
+	}
+}
@@ -0,0 +1,154 @@
+package ssl
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestCertificate creates a self-signed certificate for tests.
+func newTestCertificate(t *testing.T, commonName string, notAfter time.Time) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+// TestEncodeChainPEM - every certificate becomes one PEM block, in order.
+func TestEncodeChainPEM(t *testing.T) {
+	leaf := newTestCertificate(t, "example.com", time.Now().Add(24*time.Hour))
+	intermediate := newTestCertificate(t, "Test CA", time.Now().Add(48*time.Hour))
+
+	encoded := EncodeChainPEM([]*x509.Certificate{leaf, intermediate})
+
+	first, rest := pem.Decode([]byte(encoded))
+	require.NotNil(t, first)
+	assert.Equal(t, "CERTIFICATE", first.Type)
+	assert.Equal(t, leaf.Raw, first.Bytes)
+
+	second, rest := pem.Decode(rest)
+	require.NotNil(t, second)
+	assert.Equal(t, intermediate.Raw, second.Bytes)
+	assert.Empty(t, rest)
+}
+
+// TestEncodeChainPEM_Empty - no certificates means no output.
+func TestEncodeChainPEM_Empty(t *testing.T) {
+	assert.Equal(t, "", EncodeChainPEM(nil))
+}
+
+// TestParseChainPEM - round-trips an encoded chain and skips non-certificate blocks.
+func TestParseChainPEM(t *testing.T) {
+	leaf := newTestCertificate(t, "example.com", time.Now().Add(24*time.Hour))
+	intermediate := newTestCertificate(t, "Test CA", time.Now().Add(48*time.Hour))
+
+	encoded := EncodeChainPEM([]*x509.Certificate{leaf, intermediate})
+	encoded += string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("ignored")}))
+
+	certs, err := ParseChainPEM([]byte(encoded))
+	require.NoError(t, err)
+	require.Len(t, certs, 2)
+	assert.Equal(t, leaf.Raw, certs[0].Raw)
+	assert.Equal(t, intermediate.Raw, certs[1].Raw)
+
+	certs, err = ParseChainPEM(nil)
+	require.NoError(t, err)
+	assert.Empty(t, certs)
+
+	_, err = ParseChainPEM(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("garbage")}))
+	assert.Error(t, err)
+}
+
+// TestFetchCertificateChain_InvalidHostname - returns error for empty hostname.
+func TestFetchCertificateChain_InvalidHostname(t *testing.T) {
+	_, err := FetchCertificateChain(context.Background(), Hostname(""))
+	assert.ErrorIs(t, err, ErrInvalidHostname)
+}
+
+// TestFetchCertificateChain_Expired - a chain that fails verification is still returned as served.
+func TestFetchCertificateChain_Expired(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		DNSNames:     []string{"example.com"},
+		NotBefore:    time.Now().Add(-100 * 24 * time.Hour),
+		NotAfter:     time.Now().Add(-10 * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	})
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// The client rejects the certificate, failing the handshake on this side too
+		_ = conn.(*tls.Conn).Handshake()
+	}()
+
+	chain, err := fetchChain(context.Background(), Hostname("example.com"), listener.Addr().String())
+	require.NoError(t, err)
+	require.Len(t, chain, 1)
+	assert.Equal(t, der, chain[0].Raw)
+	assert.Contains(t, EncodeChainPEM(chain), "-----BEGIN CERTIFICATE-----")
+}
+
+// TestEncodeChainPKCS7 - the bundle is a SignedData holding every certificate, in order.
+func TestEncodeChainPKCS7(t *testing.T) {
+	leaf := newTestCertificate(t, "example.com", time.Now().Add(24*time.Hour))
+	intermediate := newTestCertificate(t, "Test CA", time.Now().Add(48*time.Hour))
+
+	encoded, err := EncodeChainPKCS7([]*x509.Certificate{leaf, intermediate})
+	require.NoError(t, err)
+
+	var outer contentInfo
+	_, err = asn1.Unmarshal(encoded, &outer)
+	require.NoError(t, err)
+	assert.True(t, outer.ContentType.Equal(oidSignedData))
+	var sd signedData
+	_, err = asn1.Unmarshal(outer.Content.Bytes, &sd)
+	require.NoError(t, err)
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	require.NoError(t, err)
+	require.Len(t, certs, 2)
+	assert.Equal(t, leaf.Raw, certs[0].Raw)
+	assert.Equal(t, intermediate.Raw, certs[1].Raw)
+
+	parsed, err := ParseChainPKCS7(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, certs, parsed)
+}
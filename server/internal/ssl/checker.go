@@ -0,0 +1,35 @@
+package ssl
+
+import "context"
+
+// Checker checks the certificate of a tracked name. The worker pool and the domain service check through one, so
+// tests and other backends, e.g. reading certificates from a secret store, plug into the same pipeline
+type Checker interface {
+	CheckSSLCertificate(ctx context.Context, name string) (*SSLCertificate, error)
+}
+
+// TargetValidator is implemented by checkers that decide themselves which names can be tracked, instead of
+// ValidateTarget resolving them
+type TargetValidator interface {
+	ValidateTarget(name string) error
+}
+
+// CheckerFunc lets a function be a Checker
+type CheckerFunc func(ctx context.Context, name string) (*SSLCertificate, error)
+
+func (f CheckerFunc) CheckSSLCertificate(ctx context.Context, name string) (*SSLCertificate, error) {
+	return f(ctx, name)
+}
+
+// DefaultChecker checks hostnames over TLS, files from disk, SSH hosts and names with a registered TargetChecker,
+// as CheckTarget does
+var DefaultChecker Checker = CheckerFunc(CheckTarget)
+
+// Validate checks a name that is about to be tracked with checker, through ValidateTarget unless the checker
+// validates names itself
+func Validate(checker Checker, name string) error {
+	if v, ok := checker.(TargetValidator); ok {
+		return v.ValidateTarget(name)
+	}
+	return ValidateTarget(name)
+}
@@ -0,0 +1,47 @@
+package ssl
+
+import (
+	"crypto/x509"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FieldComparison is one property of two certificates side by side
+type FieldComparison struct {
+	Field  string
+	Served string
+	Local  string
+}
+
+// Matches reports whether both certificates have the same value
+func (f FieldComparison) Matches() bool {
+	return f.Served == f.Local
+}
+
+// CompareCertificates lists the serial number, fingerprint, names and expiry of the certificate a server
+// presents next to those of a local copy, to tell whether a deploy shipped the certificate expected
+func CompareCertificates(served, local *x509.Certificate) []FieldComparison {
+	return []FieldComparison{
+		{"serial", serialOf(served), serialOf(local)},
+		{"fingerprint", CertFingerprint(served), CertFingerprint(local)},
+		{"sans", sansOf(served), sansOf(local)},
+		{"expiry_date", served.NotAfter.UTC().Format(time.RFC3339), local.NotAfter.UTC().Format(time.RFC3339)},
+	}
+}
+
+// serialOf is the serial number of a certificate in hex, the way browsers and OpenSSL show it
+func serialOf(cert *x509.Certificate) string {
+	return fmt.Sprintf("%X", cert.SerialNumber)
+}
+
+// sansOf is the sorted, comma separated DNS names and IP addresses a certificate is valid for
+func sansOf(cert *x509.Certificate) string {
+	names := append([]string(nil), cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	slices.Sort(names)
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,24 @@
+package ssl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCompareCertificates - the same certificate matches on every field, a renewed one on none but its names.
+func TestCompareCertificates(t *testing.T) {
+	served := newTestCertificate(t, "example.com", time.Now().Add(24*time.Hour))
+	renewed := newTestCertificate(t, "example.com", time.Now().Add(90*24*time.Hour))
+
+	for _, f := range CompareCertificates(served, served) {
+		assert.True(t, f.Matches(), f.Field)
+	}
+
+	mismatched := map[string]bool{}
+	for _, f := range CompareCertificates(served, renewed) {
+		mismatched[f.Field] = !f.Matches()
+	}
+	assert.Equal(t, map[string]bool{"serial": true, "fingerprint": true, "sans": false, "expiry_date": true}, mismatched)
+}
@@ -0,0 +1,202 @@
+package ssl
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/types"
+	"golang.org/x/crypto/pkcs12"
+)
+
+// FilePrefix marks a tracked name as a certificate file on this machine rather than a hostname,
+// e.g. file:///etc/haproxy/certs/site.pem
+const FilePrefix = "file://"
+
+// ErrNoCertificate occurs when a file holds no certificate
+var ErrNoCertificate = errors.New("no certificate found")
+
+var (
+	pkcs12Mu        sync.RWMutex
+	pkcs12Passwords []string
+)
+
+// SetPKCS12Passwords sets the passwords tried on PKCS#12 files after the empty password
+func SetPKCS12Passwords(passwords []string) {
+	pkcs12Mu.Lock()
+	defer pkcs12Mu.Unlock()
+	pkcs12Passwords = append([]string(nil), passwords...)
+}
+
+// IsFileTarget reports whether a tracked name refers to a certificate file
+func IsFileTarget(name string) bool {
+	return strings.HasPrefix(name, FilePrefix)
+}
+
+// FilePath returns the path of a file target
+func FilePath(name string) string {
+	return strings.TrimPrefix(name, FilePrefix)
+}
+
+// CheckCertificateFile reads the expiry and issuer of the certificate in a PEM, DER or PKCS#12 file.
+//
+// Bundles holding a chain or a private key as well are fine, the first certificate that isn't a CA is used
+//
+// Returns the certificate information or an error if the file can't be read or holds no certificate
+func CheckCertificateFile(path string) (*SSLCertificate, error) {
+	certs, err := LoadCertificateFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cert := leaf(certs)
+	return &SSLCertificate{
+		Hostname:        Hostname(FilePrefix + path),
+		ExpiryDate:      types.NewExpiryDate(cert.NotAfter),
+		TimeLeft:        TimeLeft(time.Until(cert.NotAfter).Hours() / 24),
+		Issuer:          IssuerName(cert),
+		CertFingerprint: CertFingerprint(cert),
+		SANs:            cert.DNSNames,
+		IssuedAt:        cert.NotBefore,
+		KeyType:         KeyType(cert),
+		Signatures:      ChainSignatures(certs),
+	}, nil
+}
+
+// LoadCertificateFile returns every certificate in a PEM, DER or PKCS#12 file, in the order they are stored
+func LoadCertificateFile(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read certificate file: %w", err)
+	}
+	certs, err := ParseCertificates(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return certs, nil
+}
+
+// ParseCertificates reads every certificate in PEM, DER or PKCS#12 data, trying them in that order
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	if certs, err := ParseChainPEM(data); err != nil || len(certs) > 0 {
+		return certs, err
+	}
+	if cert, err := x509.ParseCertificate(data); err == nil {
+		return []*x509.Certificate{cert}, nil
+	}
+
+	pkcs12Mu.RLock()
+	passwords := append([]string{""}, pkcs12Passwords...)
+	pkcs12Mu.RUnlock()
+	var lastErr error
+	for _, password := range passwords {
+		blocks, err := pkcs12.ToPEM(data, password)
+		if err != nil {
+			lastErr = err
+			if errors.Is(err, pkcs12.ErrIncorrectPassword) {
+				continue
+			}
+			break
+		}
+		var certs []*x509.Certificate
+		for _, block := range blocks {
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse certificate %d: %w", len(certs)+1, err)
+			}
+			certs = append(certs, cert)
+		}
+		if len(certs) == 0 {
+			return nil, ErrNoCertificate
+		}
+		return certs, nil
+	}
+	var unsupported pkcs12.NotImplementedError
+	switch {
+	case errors.Is(lastErr, pkcs12.ErrIncorrectPassword):
+		return nil, fmt.Errorf("PKCS#12 file needs a password: %w", lastErr)
+	case errors.As(lastErr, &unsupported):
+		return nil, fmt.Errorf("unsupported PKCS#12 file: %w", lastErr)
+	}
+	return nil, ErrNoCertificate
+}
+
+// leaf picks the end-entity certificate, the first one that isn't a CA or else the first one
+func leaf(certs []*x509.Certificate) *x509.Certificate {
+	for _, cert := range certs {
+		if !cert.IsCA {
+			return cert
+		}
+	}
+	return certs[0]
+}
+
+// pkcs12Extensions are scanned even though their contents can't be recognised without a password
+var pkcs12Extensions = map[string]bool{".p12": true, ".pfx": true}
+
+// ScanCertificateFiles finds the certificate files under root, or root itself when it is a file.
+//
+// Files without a certificate, such as private keys on their own, are skipped, and so are hidden directories.
+//
+// Returns the absolute paths in lexical order or an error if root can't be walked
+func ScanCertificateFiles(root string) ([]string, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			// Unreadable entries, e.g. a private directory, don't stop the rest of the scan
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() && d.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+		if isCertificateFile(path) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+// isCertificateFile reports whether a file holds a certificate
+func isCertificateFile(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			return true
+		}
+	}
+	if _, err := x509.ParseCertificate(data); err == nil {
+		return true
+	}
+	return pkcs12Extensions[strings.ToLower(filepath.Ext(path))]
+}
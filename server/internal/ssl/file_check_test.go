@@ -0,0 +1,102 @@
+package ssl
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFile writes data to name under dir, creating parent directories, and returns its path.
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+// TestCheckCertificateFile - PEM bundles and DER files give the expiry of the certificate in them.
+func TestCheckCertificateFile(t *testing.T) {
+	dir := t.TempDir()
+	expiry := time.Now().Add(45 * 24 * time.Hour).Truncate(time.Second)
+	cert := newTestCertificate(t, "internal.example.com", expiry)
+	ca := newTestCertificate(t, "Example CA", expiry.Add(365*24*time.Hour))
+
+	key := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("not a real key")})
+	bundle := append(key, EncodeChainPEM([]*x509.Certificate{cert, ca})...)
+	pemPath := writeFile(t, dir, "haproxy.pem", bundle)
+	derPath := writeFile(t, dir, "site.der", cert.Raw)
+
+	for _, path := range []string{pemPath, derPath} {
+		got, err := CheckCertificateFile(path)
+		require.NoError(t, err, path)
+		assert.True(t, got.ExpiryDate.Time().Equal(expiry), path)
+		assert.Equal(t, "internal.example.com", got.Issuer, "Self-signed, so the issuer is the subject")
+		assert.Equal(t, Hostname(FilePrefix+path), got.Hostname)
+	}
+
+	certs, err := LoadCertificateFile(pemPath)
+	require.NoError(t, err)
+	assert.Len(t, certs, 2)
+
+	_, err = CheckCertificateFile(writeFile(t, dir, "site.key", key))
+	assert.ErrorIs(t, err, ErrNoCertificate)
+	_, err = CheckCertificateFile(filepath.Join(dir, "missing.pem"))
+	assert.Error(t, err)
+}
+
+// TestCheckTarget - file targets are read from disk and can't be directories or relative paths.
+func TestCheckTarget(t *testing.T) {
+	dir := t.TempDir()
+	expiry := time.Now().Add(10 * 24 * time.Hour)
+	path := writeFile(t, dir, "site.crt", []byte(EncodeChainPEM([]*x509.Certificate{newTestCertificate(t, "a.example.com", expiry)})))
+
+	assert.True(t, IsFileTarget(FilePrefix+path))
+	assert.False(t, IsFileTarget("example.com"))
+	assert.Equal(t, path, FilePath(FilePrefix+path))
+
+	got, err := CheckTarget(context.Background(), FilePrefix+path)
+	require.NoError(t, err)
+	assert.Equal(t, TimeLeft(9), got.TimeLeft)
+
+	assert.NoError(t, ValidateTarget(FilePrefix+path))
+	assert.Error(t, ValidateTarget(FilePrefix+dir), "Directories are scanned, not tracked")
+	assert.Error(t, ValidateTarget(FilePrefix+"certs/site.crt"))
+	assert.Error(t, ValidateTarget(FilePrefix+filepath.Join(dir, "missing.crt")))
+}
+
+// TestScanCertificateFiles - certificates are found through subdirectories while keys, other files and hidden directories are skipped.
+func TestScanCertificateFiles(t *testing.T) {
+	dir := t.TempDir()
+	cert := newTestCertificate(t, "a.example.com", time.Now().Add(30*24*time.Hour))
+	certPEM := []byte(EncodeChainPEM([]*x509.Certificate{cert}))
+
+	want := []string{
+		writeFile(t, dir, "a.pem", certPEM),
+		writeFile(t, dir, "haproxy/b.crt", certPEM),
+		writeFile(t, dir, "haproxy/c.der", cert.Raw),
+		writeFile(t, dir, "private/d.pfx", []byte("locked")),
+	}
+	writeFile(t, dir, "private/a.key", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("key")}))
+	writeFile(t, dir, "README", []byte("certificates for the load balancer"))
+	writeFile(t, dir, ".git/cert.pem", certPEM)
+
+	paths, err := ScanCertificateFiles(dir)
+	require.NoError(t, err)
+	assert.Equal(t, want, paths)
+
+	paths, err = ScanCertificateFiles(want[0])
+	require.NoError(t, err)
+	assert.Equal(t, want[:1], paths, "A file is scanned on its own")
+
+	_, err = ScanCertificateFiles(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,81 @@
+package ssl
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// HandshakeLog connects to the hostname the way a check does and describes each step: the addresses it resolves
+// to, the connection, the TLS version and cipher suite negotiated and the certificates served with how they
+// verified.
+//
+// Returns the log, which ends with the step that failed and why if one did
+func HandshakeLog(ctx context.Context, hostname Hostname) []string {
+	start := time.Now()
+	var log []string
+	logf := func(format string, args ...any) {
+		elapsed := time.Since(start).Milliseconds()
+		log = append(log, fmt.Sprintf("[%5dms] ", elapsed)+fmt.Sprintf(format, args...))
+	}
+
+	if !hostname.IsValid() {
+		logf("%v", ErrInvalidHostname)
+		return log
+	}
+
+	logf("resolving %s", hostname)
+	addrs, err := net.DefaultResolver.LookupHost(ctx, hostname.String())
+	if err != nil {
+		logf("lookup failed: %v", err)
+		return log
+	}
+	logf("resolved to %s", strings.Join(addrs, ", "))
+
+	addr := net.JoinHostPort(hostname.String(), "443")
+	logf("connecting to %s", addr)
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		logf("connection failed: %v", err)
+		return log
+	}
+	defer conn.Close()
+	logf("connected to %s from %s", conn.RemoteAddr(), conn.LocalAddr())
+
+	// The chain is logged as served, before it is verified the way checks verify it
+	var warning string
+	config := verifyingConfig(ctx, hostname, &warning)
+	verify := config.VerifyConnection
+	config.VerifyConnection = func(cs tls.ConnectionState) error {
+		logf("negotiated %s with %s", tls.VersionName(cs.Version), tls.CipherSuiteName(cs.CipherSuite))
+		for i, cert := range cs.PeerCertificates {
+			logf("certificate %d: %s, issued by %s, valid %s to %s", i, cert.Subject, cert.Issuer,
+				cert.NotBefore.UTC().Format(time.RFC3339), cert.NotAfter.UTC().Format(time.RFC3339))
+		}
+		if err := verify(cs); err != nil {
+			logf("verification failed: %v", err)
+			return err
+		}
+		logf("chain verified")
+		return nil
+	}
+
+	logf("starting TLS handshake for server name %s", hostname)
+	client := tls.Client(conn, config)
+	if err := client.HandshakeContext(ctx); err != nil {
+		logf("handshake failed: %v", err)
+		return log
+	}
+	defer client.Close()
+	logf("handshake completed")
+	if warning != "" {
+		logf("warning: %s", warning)
+	}
+	return log
+}
@@ -0,0 +1,22 @@
+package ssl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHandshakeLog_StopsAtFailure - the log ends with the step that failed.
+func TestHandshakeLog_StopsAtFailure(t *testing.T) {
+	log := HandshakeLog(context.Background(), Hostname(""))
+	require.Len(t, log, 1)
+	assert.Contains(t, log[0], ErrInvalidHostname.Error())
+
+	// .invalid names never resolve
+	log = HandshakeLog(context.Background(), Hostname("example.invalid"))
+	require.Len(t, log, 2)
+	assert.Contains(t, log[0], "resolving example.invalid")
+	assert.Contains(t, log[1], "lookup failed")
+}
@@ -0,0 +1,198 @@
+package ssl
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// LintSeverity is how bad a lint finding is
+type LintSeverity string
+
+const (
+	LintOK      LintSeverity = "ok"
+	LintWarning LintSeverity = "warning"
+	LintError   LintSeverity = "error"
+)
+
+// LintFinding is the outcome of one check of a certificate bundle
+type LintFinding struct {
+	Severity LintSeverity
+	// Check names what was checked, e.g. chain_order
+	Check   string
+	Message string
+}
+
+// LintOptions are what a bundle is checked against besides itself
+type LintOptions struct {
+	// Hostnames the leaf certificate has to be valid for
+	Hostnames []string
+	// Key is the private key that has to belong to the leaf certificate, nil skips the check
+	Key crypto.Signer
+	// WarnDays warns about a leaf certificate expiring within this many days
+	WarnDays int
+	// Now is when validity is checked at
+	Now time.Time
+}
+
+// maxValidity is the longest lifetime browsers accept for a leaf certificate
+const maxValidity = 398 * 24 * time.Hour
+
+// LintBundle runs sanity checks on a certificate bundle, leaf first, so problems are caught before it is deployed.
+// A misordered bundle is still checked against its leaf, the first certificate that isn't a CA.
+//
+// Every check reports either its problems or a single ok finding
+func LintBundle(certs []*x509.Certificate, opts LintOptions) []LintFinding {
+	if len(certs) == 0 {
+		return []LintFinding{{LintError, "parse", "bundle holds no certificate"}}
+	}
+
+	var findings []LintFinding
+	check := func(name, ok string, problems []LintFinding) {
+		if len(problems) == 0 {
+			problems = []LintFinding{{LintOK, name, ok}}
+		}
+		findings = append(findings, problems...)
+	}
+
+	end := leaf(certs)
+	order := "single certificate"
+	if len(certs) > 1 {
+		order = fmt.Sprintf("leaf first, each of the %d certificates signed by the next", len(certs))
+	}
+	check("chain_order", order, lintChainOrder(certs))
+	check("expiry", fmt.Sprintf("valid until %s", end.NotAfter.UTC().Format(time.RFC3339)), lintExpiry(certs, end, opts))
+	if len(opts.Hostnames) > 0 {
+		check("hostnames", fmt.Sprintf("valid for %d hostnames", len(opts.Hostnames)), lintHostnames(end, opts.Hostnames))
+	}
+	if opts.Key != nil {
+		check("key_match", "private key belongs to the leaf certificate", lintKeyMatch(end, opts.Key))
+	}
+	check("parameters", "no weak keys or signatures", lintParameters(certs, end))
+	return findings
+}
+
+// lintChainOrder checks the leaf comes first and every certificate is issued by the one after it
+func lintChainOrder(certs []*x509.Certificate) []LintFinding {
+	var problems []LintFinding
+	if end := leaf(certs); end != certs[0] {
+		problems = append(problems, LintFinding{LintError, "chain_order", fmt.Sprintf("first certificate (%s) is a CA, the leaf (%s) has to come first", certs[0].Subject, end.Subject)})
+	}
+	for i := 0; i+1 < len(certs); i++ {
+		if err := certs[i].CheckSignatureFrom(certs[i+1]); err != nil {
+			problems = append(problems, LintFinding{LintError, "chain_order",
+				fmt.Sprintf("certificate %d (%s) is not issued by certificate %d (%s)", i+1, certs[i].Subject, i+2, certs[i+1].Subject)})
+		}
+	}
+	last := certs[len(certs)-1]
+	if len(certs) > 1 && bytes.Equal(last.RawIssuer, last.RawSubject) {
+		problems = append(problems, LintFinding{LintWarning, "chain_order", fmt.Sprintf("bundle includes the root %s, which clients already have", last.Subject)})
+	}
+	return problems
+}
+
+// lintExpiry checks every certificate is valid now and the leaf for more than opts.WarnDays
+func lintExpiry(certs []*x509.Certificate, leaf *x509.Certificate, opts LintOptions) []LintFinding {
+	var problems []LintFinding
+	for i, cert := range certs {
+		switch {
+		case opts.Now.After(cert.NotAfter):
+			problems = append(problems, LintFinding{LintError, "expiry", fmt.Sprintf("certificate %d (%s) expired on %s", i+1, cert.Subject, cert.NotAfter.UTC().Format(time.RFC3339))})
+		case opts.Now.Before(cert.NotBefore):
+			problems = append(problems, LintFinding{LintError, "expiry", fmt.Sprintf("certificate %d (%s) is not valid before %s", i+1, cert.Subject, cert.NotBefore.UTC().Format(time.RFC3339))})
+		}
+	}
+	daysLeft := int(leaf.NotAfter.Sub(opts.Now).Hours() / 24)
+	if len(problems) == 0 && daysLeft < opts.WarnDays {
+		problems = append(problems, LintFinding{LintWarning, "expiry", fmt.Sprintf("leaf certificate expires in %d days", daysLeft)})
+	}
+	return problems
+}
+
+// lintHostnames checks the leaf certificate is valid for every hostname
+func lintHostnames(leaf *x509.Certificate, hostnames []string) []LintFinding {
+	var problems []LintFinding
+	for _, hostname := range hostnames {
+		if err := leaf.VerifyHostname(hostname); err != nil {
+			problems = append(problems, LintFinding{LintError, "hostnames", fmt.Sprintf("not valid for %s", hostname)})
+		}
+	}
+	return problems
+}
+
+// lintKeyMatch checks the private key is the one of the leaf certificate
+func lintKeyMatch(leaf *x509.Certificate, key crypto.Signer) []LintFinding {
+	public, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !public.Equal(leaf.PublicKey) {
+		return []LintFinding{{LintError, "key_match", "private key does not belong to the leaf certificate"}}
+	}
+	return nil
+}
+
+// lintParameters checks for keys and signature algorithms clients reject or will soon
+func lintParameters(certs []*x509.Certificate, leaf *x509.Certificate) []LintFinding {
+	var problems []LintFinding
+	for i, cert := range certs {
+		switch key := cert.PublicKey.(type) {
+		case *rsa.PublicKey:
+			if key.N.BitLen() < 2048 {
+				problems = append(problems, LintFinding{LintError, "parameters", fmt.Sprintf("certificate %d has a %d-bit RSA key, 2048 is the minimum", i+1, key.N.BitLen())})
+			}
+		case *ecdsa.PublicKey:
+			if key.Curve.Params().BitSize < 256 {
+				problems = append(problems, LintFinding{LintError, "parameters", fmt.Sprintf("certificate %d uses the weak curve %s", i+1, key.Curve.Params().Name)})
+			}
+		}
+		// The signature on a self-signed root isn't relied on
+		if bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.IsCA {
+			continue
+		}
+		switch cert.SignatureAlgorithm {
+		case x509.MD2WithRSA, x509.MD5WithRSA, x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+			problems = append(problems, LintFinding{LintError, "parameters", fmt.Sprintf("certificate %d is signed with %s", i+1, cert.SignatureAlgorithm)})
+		}
+	}
+	if lifetime := leaf.NotAfter.Sub(leaf.NotBefore); lifetime > maxValidity {
+		problems = append(problems, LintFinding{LintWarning, "parameters", fmt.Sprintf("leaf certificate is valid for %d days, browsers reject more than %d", int(lifetime.Hours()/24), int(maxValidity.Hours()/24))})
+	}
+	return problems
+}
+
+// ParsePrivateKey reads a PEM encoded RSA, ECDSA or Ed25519 private key in PKCS#1, SEC 1 or PKCS#8 form,
+// skipping any certificates alongside it
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("no private key found")
+		}
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			return x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			return x509.ParseECPrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			switch key := key.(type) {
+			case *rsa.PrivateKey:
+				return key, nil
+			case *ecdsa.PrivateKey:
+				return key, nil
+			case ed25519.PrivateKey:
+				return key, nil
+			}
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package ssl
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// issue creates a certificate for template signed by parent's key, self-signed when parent is nil.
+func issue(t *testing.T, template, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, crypto.Signer) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+// severities maps each check of findings to its worst severity.
+func severities(findings []LintFinding) map[string]LintSeverity {
+	worst := map[string]LintSeverity{}
+	for _, f := range findings {
+		if worst[f.Check] != LintError {
+			if f.Severity != LintOK || worst[f.Check] == "" {
+				worst[f.Check] = f.Severity
+			}
+		}
+	}
+	return worst
+}
+
+// TestLintBundle - a good bundle passes every check, a reordered one with the wrong key and host doesn't.
+func TestLintBundle(t *testing.T) {
+	now := time.Now()
+	root, rootKey := issue(t, &x509.Certificate{
+		Subject: pkix.Name{CommonName: "Test Root"}, IsCA: true, BasicConstraintsValid: true,
+		KeyUsage: x509.KeyUsageCertSign, NotBefore: now.Add(-time.Hour), NotAfter: now.AddDate(10, 0, 0),
+	}, nil, nil)
+	intermediate, intermediateKey := issue(t, &x509.Certificate{
+		Subject: pkix.Name{CommonName: "Test Intermediate"}, IsCA: true, BasicConstraintsValid: true,
+		KeyUsage: x509.KeyUsageCertSign, NotBefore: now.Add(-time.Hour), NotAfter: now.AddDate(5, 0, 0),
+	}, root, rootKey)
+	leaf, leafKey := issue(t, &x509.Certificate{
+		Subject: pkix.Name{CommonName: "example.com"}, DNSNames: []string{"example.com", "*.example.com"},
+		NotBefore: now.Add(-time.Hour), NotAfter: now.AddDate(0, 0, 90),
+	}, intermediate, intermediateKey)
+
+	opts := LintOptions{Hostnames: []string{"example.com", "www.example.com"}, Key: leafKey, WarnDays: 30, Now: now}
+	assert.Equal(t, map[string]LintSeverity{
+		"chain_order": LintOK, "expiry": LintOK, "hostnames": LintOK, "key_match": LintOK, "parameters": LintOK,
+	}, severities(LintBundle([]*x509.Certificate{leaf, intermediate}, opts)))
+
+	opts = LintOptions{Hostnames: []string{"example.org"}, Key: intermediateKey, WarnDays: 120, Now: now}
+	assert.Equal(t, map[string]LintSeverity{
+		"chain_order": LintError, "expiry": LintWarning, "hostnames": LintError, "key_match": LintError, "parameters": LintOK,
+	}, severities(LintBundle([]*x509.Certificate{intermediate, leaf}, opts)))
+
+	withRoot := severities(LintBundle([]*x509.Certificate{leaf, intermediate, root}, LintOptions{Now: now}))
+	assert.Equal(t, LintWarning, withRoot["chain_order"])
+
+	expired := severities(LintBundle([]*x509.Certificate{leaf}, LintOptions{Now: now.AddDate(1, 0, 0)}))
+	assert.Equal(t, LintError, expired["expiry"])
+}
+
+// TestParsePrivateKey - keys are found next to certificates in any of the usual encodings.
+func TestParsePrivateKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	sec1, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	cert := newTestCertificate(t, "example.com", time.Now().Add(time.Hour))
+
+	for _, block := range []*pem.Block{{Type: "PRIVATE KEY", Bytes: pkcs8}, {Type: "EC PRIVATE KEY", Bytes: sec1}} {
+		data := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), pem.EncodeToMemory(block)...)
+		parsed, err := ParsePrivateKey(data)
+		require.NoError(t, err, block.Type)
+		assert.True(t, key.PublicKey.Equal(parsed.Public()), block.Type)
+	}
+
+	_, err = ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	assert.Error(t, err)
+}
@@ -0,0 +1,101 @@
+package ssl
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/x509roots/fallback/bundle"
+)
+
+// Root stores certificates can be verified against
+const (
+	// RootsSystem is the operating system's trust store
+	RootsSystem = "system"
+	// RootsMozilla is the snapshot of Mozilla's trust store built in, the one Firefox, and most Linux
+	// distributions, trust
+	RootsMozilla = "mozilla"
+)
+
+// RootStore is a set of trusted root certificates
+type RootStore struct {
+	// Name describes the store in warnings, e.g. system or the path of a custom bundle
+	Name string
+	// Pool holds the roots, nil for the operating system's
+	Pool *x509.CertPool
+}
+
+// String names the store in warnings
+func (s RootStore) String() string {
+	switch s.Name {
+	case RootsSystem:
+		return "the system root store"
+	case RootsMozilla:
+		return "Mozilla's root store"
+	}
+	return "the root store " + s.Name
+}
+
+var (
+	rootsMu sync.RWMutex
+	// trustRoots verifies every chain, compareRoots is checked alongside to report where they disagree
+	trustRoots   = RootStore{Name: RootsSystem}
+	compareRoots *RootStore
+)
+
+// mozillaRoots is parsed once, the first time it is used
+var mozillaRoots = sync.OnceValue(func() *x509.CertPool {
+	pool := x509.NewCertPool()
+	for root := range bundle.Roots() {
+		cert, err := x509.ParseCertificate(root.Certificate)
+		if err != nil {
+			continue
+		}
+		// Roots Mozilla distrusts from a date on only anchor chains issued before it
+		pool.AddCertWithConstraint(cert, root.Constraint)
+	}
+	return pool
+})
+
+// LoadRootStore opens the system store, the built in Mozilla snapshot or a PEM bundle of custom roots at a path
+func LoadRootStore(name string) (RootStore, error) {
+	switch name {
+	case "", RootsSystem:
+		return RootStore{Name: RootsSystem}, nil
+	case RootsMozilla:
+		return RootStore{Name: RootsMozilla, Pool: mozillaRoots()}, nil
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return RootStore{}, fmt.Errorf("failed to read root store: %w", err)
+	}
+	certs, err := ParseChainPEM(data)
+	if err != nil {
+		return RootStore{}, fmt.Errorf("failed to read root store %s: %w", name, err)
+	}
+	if len(certs) == 0 {
+		return RootStore{}, errors.New("no certificates in root store " + name)
+	}
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return RootStore{Name: name, Pool: pool}, nil
+}
+
+// SetRootStores verifies chains against roots and, unless compare is nil, warns about chains only one of the
+// two trusts
+func SetRootStores(roots RootStore, compare *RootStore) {
+	rootsMu.Lock()
+	defer rootsMu.Unlock()
+	trustRoots, compareRoots = roots, compare
+}
+
+// rootStores returns the stores chains are verified against
+func rootStores() (RootStore, *RootStore) {
+	rootsMu.RLock()
+	defer rootsMu.RUnlock()
+	return trustRoots, compareRoots
+}
@@ -0,0 +1,40 @@
+package ssl
+
+import (
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoadRootStore - the system store has no pool, Mozilla's is built in and custom stores are PEM bundles.
+func TestLoadRootStore(t *testing.T) {
+	system, err := LoadRootStore("")
+	require.NoError(t, err)
+	assert.Equal(t, RootsSystem, system.Name)
+	assert.Nil(t, system.Pool)
+
+	mozilla, err := LoadRootStore(RootsMozilla)
+	require.NoError(t, err)
+	require.NotNil(t, mozilla.Pool)
+	assert.Equal(t, "Mozilla's root store", mozilla.String())
+
+	path := filepath.Join(t.TempDir(), "roots.pem")
+	require.NoError(t, os.WriteFile(path, []byte(EncodeChainPEM([]*x509.Certificate{
+		newTestCertificate(t, "Private Root", time.Now().Add(time.Hour)),
+	})), 0600))
+	custom, err := LoadRootStore(path)
+	require.NoError(t, err)
+	assert.Equal(t, path, custom.Name)
+	assert.NotNil(t, custom.Pool)
+
+	_, err = LoadRootStore(filepath.Join(t.TempDir(), "missing.pem"))
+	assert.Error(t, err)
+	require.NoError(t, os.WriteFile(path, []byte("not a certificate"), 0600))
+	_, err = LoadRootStore(path)
+	assert.Error(t, err)
+}
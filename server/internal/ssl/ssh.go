@@ -0,0 +1,102 @@
+package ssl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/types"
+	"golang.org/x/crypto/ssh"
+)
+
+// SSHPrefix marks a tracked name as an SSH server, e.g. ssh://bastion.example.com or ssh://bastion.example.com:2222
+const SSHPrefix = "ssh://"
+
+// sshHostKeyAlgorithms asks for a host certificate before a plain key, servers pick the first one they have
+var sshHostKeyAlgorithms = []string{
+	ssh.CertAlgoED25519v01, ssh.CertAlgoECDSA256v01, ssh.CertAlgoECDSA384v01, ssh.CertAlgoECDSA521v01,
+	ssh.CertAlgoRSASHA512v01, ssh.CertAlgoRSASHA256v01, ssh.CertAlgoRSAv01,
+	ssh.KeyAlgoED25519, ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521,
+	ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA,
+}
+
+// IsSSHTarget reports whether a tracked name refers to an SSH server
+func IsSSHTarget(name string) bool {
+	return strings.HasPrefix(name, SSHPrefix)
+}
+
+// SSHAddress splits an SSH target into its hostname and the address to dial, port 22 unless it names another
+func SSHAddress(name string) (string, string, error) {
+	hostport := strings.TrimSuffix(strings.TrimPrefix(name, SSHPrefix), "/")
+	host, port := hostport, "22"
+	if h, p, err := net.SplitHostPort(hostport); err == nil {
+		host, port = h, p
+	}
+	host = NormalizeHostname(host)
+	if err := ValidateHostname(host); err != nil {
+		return "", "", err
+	}
+	return host, net.JoinHostPort(host, port), nil
+}
+
+// CheckSSHHost reads the host key of an SSH server, stopping the handshake before logging in.
+//
+// Servers with an OpenSSH host certificate report its expiry and the fingerprint of the CA that signed it as
+// the issuer. A plain host key has no expiry, leaving ExpiryDate zero. Either way Fingerprint is the SHA256
+// fingerprint of the host key, the one ssh-keygen -l shows
+func CheckSSHHost(ctx context.Context, name string) (*SSLCertificate, error) {
+	host, addr, err := SSHAddress(name)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	var hostKey ssh.PublicKey
+	config := &ssh.ClientConfig{
+		User:              "sslcerttop",
+		HostKeyAlgorithms: sshHostKeyAlgorithms,
+		HostKeyCallback: func(_ string, _ net.Addr, key ssh.PublicKey) error {
+			hostKey = key
+			// Everything needed has been seen, there is no reason to go on and try to log in
+			return errors.New("host key recorded")
+		},
+	}
+	if _, _, _, err := ssh.NewClientConn(conn, addr, config); hostKey == nil {
+		return nil, fmt.Errorf("SSH handshake failed for %s: %w", addr, err)
+	}
+
+	cert, ok := hostKey.(*ssh.Certificate)
+	if !ok {
+		return &SSLCertificate{Hostname: Hostname(name), Fingerprint: ssh.FingerprintSHA256(hostKey)}, nil
+	}
+	if cert.CertType != ssh.HostCert {
+		return nil, fmt.Errorf("%s presented a user certificate as its host certificate", addr)
+	}
+	if len(cert.ValidPrincipals) > 0 && !slices.Contains(cert.ValidPrincipals, host) {
+		return nil, fmt.Errorf("host certificate of %s is not valid for %s, only for %s", addr, host, strings.Join(cert.ValidPrincipals, ", "))
+	}
+	result := &SSLCertificate{
+		Hostname:    Hostname(name),
+		Issuer:      "SSH CA " + ssh.FingerprintSHA256(cert.SignatureKey),
+		Fingerprint: ssh.FingerprintSHA256(cert.Key),
+	}
+	if cert.ValidBefore != ssh.CertTimeInfinity {
+		notAfter := time.Unix(int64(cert.ValidBefore), 0)
+		result.ExpiryDate = types.NewExpiryDate(notAfter)
+		result.TimeLeft = TimeLeft(time.Until(notAfter).Hours() / 24)
+	}
+	return result, nil
+}
@@ -0,0 +1,112 @@
+package ssl
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// newTestSigner generates an ed25519 key.
+func newTestSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+	return signer
+}
+
+// serveSSH accepts SSH handshakes presenting hostKey until the test ends, returning the target to check.
+func serveSSH(t *testing.T, hostKey ssh.Signer) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(hostKey)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				ssh.NewServerConn(conn, config)
+			}()
+		}
+	}()
+	return SSHPrefix + listener.Addr().String()
+}
+
+// TestCheckSSHHost_PlainKey - a plain host key is fingerprinted and doesn't expire.
+func TestCheckSSHHost_PlainKey(t *testing.T) {
+	hostKey := newTestSigner(t)
+	target := serveSSH(t, hostKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	got, err := CheckTarget(ctx, target)
+	require.NoError(t, err)
+	assert.Equal(t, ssh.FingerprintSHA256(hostKey.PublicKey()), got.Fingerprint)
+	assert.True(t, got.ExpiryDate.Time().IsZero())
+	assert.Empty(t, got.Issuer)
+}
+
+// TestCheckSSHHost_Certificate - a host certificate gives its expiry and CA, and has to name the host.
+func TestCheckSSHHost_Certificate(t *testing.T) {
+	ca := newTestSigner(t)
+	hostKey := newTestSigner(t)
+	expiry := time.Now().Add(20 * 24 * time.Hour).Truncate(time.Second)
+
+	certSigner := func(principals ...string) ssh.Signer {
+		cert := &ssh.Certificate{
+			Key:             hostKey.PublicKey(),
+			CertType:        ssh.HostCert,
+			ValidPrincipals: principals,
+			ValidAfter:      uint64(time.Now().Add(-time.Hour).Unix()),
+			ValidBefore:     uint64(expiry.Unix()),
+		}
+		require.NoError(t, cert.SignCert(rand.Reader, ca))
+		signer, err := ssh.NewCertSigner(cert, hostKey)
+		require.NoError(t, err)
+		return signer
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	got, err := CheckSSHHost(ctx, serveSSH(t, certSigner("127.0.0.1")))
+	require.NoError(t, err)
+	assert.True(t, got.ExpiryDate.Time().Equal(expiry))
+	assert.Equal(t, 19, int(got.TimeLeft))
+	assert.Equal(t, "SSH CA "+ssh.FingerprintSHA256(ca.PublicKey()), got.Issuer)
+	assert.Equal(t, ssh.FingerprintSHA256(hostKey.PublicKey()), got.Fingerprint, "The fingerprint is the host key's, not the certificate's")
+
+	_, err = CheckSSHHost(ctx, serveSSH(t, certSigner("bastion.example.com")))
+	assert.ErrorContains(t, err, "not valid for 127.0.0.1")
+}
+
+// TestSSHAddress - the port defaults to 22 and the host has to be a valid hostname.
+func TestSSHAddress(t *testing.T) {
+	host, addr, err := SSHAddress("ssh://bastion.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "bastion.example.com", host)
+	assert.Equal(t, "bastion.example.com:22", addr)
+
+	_, addr, err = SSHAddress("ssh://bastion.example.com:2222")
+	require.NoError(t, err)
+	assert.Equal(t, "bastion.example.com:2222", addr)
+
+	_, _, err = SSHAddress("ssh://user@bastion.example.com")
+	assert.Error(t, err)
+}
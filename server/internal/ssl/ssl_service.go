@@ -1,35 +1,188 @@
 package ssl
 
 import (
+	"context"
+	"errors"
 	"log/slog"
 	"sync"
+	"time"
+)
+
+// DefaultWorkers is the number of checks a CertService runs concurrently
+const DefaultWorkers = 20
+
+const (
+	// resultBatchSize is the most results handed to a batch handler at once
+	resultBatchSize = 50
+	// resultFlushInterval is the longest a result waits for its batch to fill up
+	resultFlushInterval = 500 * time.Millisecond
 )
 
 type CertService struct {
-	pool    *WorkerPool
-	results func(Result)
-	started bool
-	mu      sync.Mutex
+	pool             *WorkerPool
+	results          func(Result)
+	batchResults     func([]Result)
+	subscribers      map[int]chan Result
+	nextSubscriberID int
+	// inFlight holds the channels waiting for the result of each domain ID with a check queued or running
+	inFlight map[int][]chan Result
+	started  bool
+	stopped  bool
+	mu       sync.Mutex
 }
 
 func NewCertService() *CertService {
+	return NewCertServiceWithPool(DefaultWorkers, DefaultCheckTimeout)
+}
+
+// NewCertServiceWithPool creates a CertService running the given number of workers, each check limited to checkTimeout
+func NewCertServiceWithPool(workers int, checkTimeout time.Duration) *CertService {
+	pool := NewWorkerPool(workers)
+	pool.SetCheckTimeout(checkTimeout)
 	return &CertService{
-		pool: NewWorkerPool(20),
+		pool:        pool,
+		subscribers: make(map[int]chan Result),
+		inFlight:    make(map[int][]chan Result),
 	}
 }
 
 func (cs *CertService) processResults() {
-	for result := range cs.pool.GetResults() {
-		cs.mu.Lock()
-		handler := cs.results
-		cs.mu.Unlock()
+	ticker := time.NewTicker(resultFlushInterval)
+	defer ticker.Stop()
 
-		if handler != nil {
+	var batch []Result
+	results := cs.pool.GetResults()
+	for results != nil {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				results = nil
+				break
+			}
+			batch = append(batch, result)
+			if len(batch) >= cs.batchSize() {
+				cs.handle(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				cs.handle(batch)
+				batch = nil
+			}
+		}
+	}
+	if len(batch) > 0 {
+		cs.handle(batch)
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	for id, ch := range cs.subscribers {
+		close(ch)
+		delete(cs.subscribers, id)
+	}
+	// Checks still queued won't run any more
+	for domainID, waiting := range cs.inFlight {
+		for _, ch := range waiting {
+			close(ch)
+		}
+		delete(cs.inFlight, domainID)
+	}
+}
+
+// batchSize is how many results are collected before they are handled, only batch handlers wait for more than one
+func (cs *CertService) batchSize() int {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.batchResults != nil {
+		return resultBatchSize
+	}
+	return 1
+}
+
+// handle passes results to the result handler and then to subscribers, so subscribers see handled results
+func (cs *CertService) handle(batch []Result) {
+	cs.mu.Lock()
+	handler := cs.results
+	batchHandler := cs.batchResults
+	cs.mu.Unlock()
+
+	switch {
+	case batchHandler != nil:
+		batchHandler(batch)
+	case handler != nil:
+		for _, result := range batch {
 			handler(result)
-		} else {
+		}
+	default:
+		for _, result := range batch {
 			cs.defaultHandler(result)
 		}
 	}
+
+	for _, result := range batch {
+		cs.settle(result)
+		cs.publish(result)
+	}
+}
+
+// join waits for the result of a domain's check, reporting whether one was already queued or running
+func (cs *CertService) join(domainID int) (chan Result, bool) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	ch := make(chan Result, 1)
+	waiting, running := cs.inFlight[domainID]
+	cs.inFlight[domainID] = append(waiting, ch)
+	return ch, running
+}
+
+// settle hands a handled result to everyone waiting for it, after which the domain can be checked again
+func (cs *CertService) settle(result Result) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	for _, ch := range cs.inFlight[result.Task.DomainID] {
+		ch <- result
+	}
+	delete(cs.inFlight, result.Task.DomainID)
+}
+
+// publish fans a handled result out to every subscriber, dropping it for subscribers that are full
+func (cs *CertService) publish(result Result) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	for _, ch := range cs.subscribers {
+		select {
+		case ch <- result:
+		default:
+		}
+	}
+}
+
+// Subscribe receives a copy of every result after the result handler has run.
+//
+// Results are dropped rather than blocking the pool when the buffer is full.
+// Returns the channel and a function that unsubscribes and closes it; the channel is also closed when the service stops
+func (cs *CertService) Subscribe(buffer int) (<-chan Result, func()) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	id := cs.nextSubscriberID
+	cs.nextSubscriberID++
+	ch := make(chan Result, buffer)
+	cs.subscribers[id] = ch
+
+	unsubscribe := func() {
+		cs.mu.Lock()
+		defer cs.mu.Unlock()
+		if sub, ok := cs.subscribers[id]; ok {
+			close(sub)
+			delete(cs.subscribers, id)
+		}
+	}
+	return ch, unsubscribe
 }
 
 func (cs *CertService) Start() {
@@ -46,22 +199,112 @@ func (cs *CertService) Start() {
 }
 
 func (cs *CertService) Stop() {
+	cs.mu.Lock()
+	cs.stopped = true
+	cs.mu.Unlock()
+
 	cs.pool.Stop()
 }
 
-func (cs *CertService) CheckDomain(domain string, domainID, userID int) {
+// Stopped reports whether the service has been stopped and can no longer check certificates.
+//
+// A service that was never started is not stopped, it starts on first use
+func (cs *CertService) Stopped() bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.stopped
+}
+
+// CheckDomain queues a check of a domain unless one is already queued or running, e.g. from a sweep, so a domain
+// is never checked twice at once.
+//
+// The returned channel receives the result of whichever check covers the request once it has been handled, or is
+// closed without one if the service stops first. A check the stopping pool no longer took gets a result failing with
+// ErrPoolStopped, so nobody waits for it forever
+func (cs *CertService) CheckDomain(domain string, domainID, userID int) <-chan Result {
+	if cs.Stopped() {
+		ch := make(chan Result)
+		close(ch)
+		return ch
+	}
+	ch, running := cs.join(domainID)
+	if !running {
+		task := Task{
+			Domain:   domain,
+			DomainID: domainID,
+			UserID:   userID,
+		}
+		if err := cs.pool.AddTask(task); err != nil {
+			cs.settle(Result{Task: task, Error: err, CheckedAt: time.Now()})
+		}
+	}
+	return ch
+}
+
+// CheckNow checks a domain right away in the calling goroutine rather than queueing it behind other checks, or
+// waits for the check of it that is already queued or running.
+//
+// The result goes through the result handler and to subscribers like any other
+func (cs *CertService) CheckNow(ctx context.Context, domain string, domainID, userID int) Result {
 	task := Task{
 		Domain:   domain,
 		DomainID: domainID,
 		UserID:   userID,
 	}
-	cs.pool.AddTask(task)
+	ch, running := cs.join(domainID)
+	if !running {
+		// The handler may note on the result that it couldn't store it
+		batch := []Result{cs.pool.check(ctx, task)}
+		cs.handle(batch)
+		return batch[0]
+	}
+	select {
+	case result, ok := <-ch:
+		if ok {
+			return result
+		}
+		return Result{Task: task, Error: errors.New("certificate service stopped"), CheckedAt: time.Now()}
+	case <-ctx.Done():
+		return Result{Task: task, Error: ctx.Err(), CheckedAt: time.Now()}
+	}
+}
+
+// PoolSize is how many checks run at once and how many more can be queued
+func (cs *CertService) PoolSize() PoolSize {
+	return cs.pool.Size()
+}
+
+// PoolStats is what the worker pool is doing and has done
+func (cs *CertService) PoolStats() PoolStats {
+	return cs.pool.Stats()
+}
+
+// ResizePool changes how many checks run at once and how many more can be queued, also while checks run
+func (cs *CertService) ResizePool(size PoolSize) error {
+	return cs.pool.Resize(size)
+}
+
+// SetChecker changes what checks domains, DefaultChecker unless set. It must be called before the service starts
+func (cs *CertService) SetChecker(checker Checker) {
+	cs.pool.SetChecker(checker)
 }
 
 func (cs *CertService) SetResultHandler(handler func(Result)) {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
 	cs.results = handler
+	cs.batchResults = nil
+}
+
+// SetBatchResultHandler replaces the result handler with one that receives results in batches.
+//
+// A batch is handed over once it holds 50 results and at least every 500ms otherwise, so bulk
+// checks can be stored together. Subscribers receive the results after the handler returns
+func (cs *CertService) SetBatchResultHandler(handler func([]Result)) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.batchResults = handler
+	cs.results = nil
 }
 
 func (cs *CertService) defaultHandler(result Result) {
@@ -1,11 +1,14 @@
 package ssl
 
 import (
+	"context"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/goleak"
 )
 
@@ -121,3 +124,197 @@ func TestCertService_HighLoad(t *testing.T) {
 
 	assert.Equal(t, int32(100), count.Load())
 }
+
+// TestCertService_Subscribe - subscribers receive results and are closed on stop.
+func TestCertService_Subscribe(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	cs := NewCertService()
+	results, _ := cs.Subscribe(10)
+
+	cs.Start()
+	cs.CheckDomain("invalid..domain", 7, 1)
+
+	select {
+	case r := <-results:
+		assert.Equal(t, 7, r.Task.DomainID)
+		assert.Error(t, r.Error)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Subscriber should have received the result")
+	}
+
+	cs.Stop()
+	_, open := <-results
+	assert.False(t, open, "Channel should be closed when the service stops")
+}
+
+// TestCertService_Unsubscribe - unsubscribing closes the channel and stops delivery.
+func TestCertService_Unsubscribe(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	cs := NewCertService()
+	results, unsubscribe := cs.Subscribe(10)
+	unsubscribe()
+	unsubscribe() // Safe to call twice
+
+	_, open := <-results
+	assert.False(t, open)
+
+	cs.Start()
+	cs.CheckDomain("invalid..domain", 1, 1)
+	time.Sleep(100 * time.Millisecond)
+	cs.Stop()
+}
+
+// TestCertService_Stopped - reports stopped only after Stop.
+func TestCertService_Stopped(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	cs := NewCertService()
+	assert.False(t, cs.Stopped())
+
+	cs.Start()
+	assert.False(t, cs.Stopped())
+
+	cs.Stop()
+	assert.True(t, cs.Stopped())
+}
+
+// TestCertService_BatchResultHandler - results arrive in batches and subscribers only see handled results.
+func TestCertService_BatchResultHandler(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	cs := NewCertService()
+
+	var handled atomic.Int32
+	var batches atomic.Int32
+	cs.SetBatchResultHandler(func(results []Result) {
+		batches.Add(1)
+		handled.Add(int32(len(results)))
+	})
+	results, unsubscribe := cs.Subscribe(10)
+	defer unsubscribe()
+
+	cs.Start()
+	for i := 1; i <= 5; i++ {
+		cs.CheckDomain("invalid..domain", i, 1) // invalid domain = quick error
+	}
+
+	for i := 0; i < 5; i++ {
+		select {
+		case <-results:
+			assert.Greater(t, handled.Load(), int32(i), "result published before it was handled")
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for results")
+		}
+	}
+	cs.Stop()
+
+	assert.Equal(t, int32(5), handled.Load())
+	assert.Less(t, batches.Load(), int32(5), "results should share batches")
+}
+
+// TestCertService_InFlightDedup - a domain already queued or running isn't checked again, every caller gets its result.
+func TestCertService_InFlightDedup(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	release := make(chan struct{})
+	var checks atomic.Int32
+	RegisterTargetChecker("dedup-test:", func(ctx context.Context, name string) (*SSLCertificate, error) {
+		checks.Add(1)
+		<-release
+		return &SSLCertificate{Fingerprint: "SHA256:test"}, nil
+	})
+
+	cs := NewCertService()
+	var handled atomic.Int32
+	cs.SetResultHandler(func(r Result) { handled.Add(1) })
+	cs.Start()
+
+	first := cs.CheckDomain("dedup-test:a", 1, 1)
+	second := cs.CheckDomain("dedup-test:a", 1, 1)
+	now := make(chan Result)
+	go func() { now <- cs.CheckNow(context.Background(), "dedup-test:a", 1, 1) }()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	for _, ch := range []<-chan Result{first, second, now} {
+		select {
+		case r := <-ch:
+			assert.NoError(t, r.Error)
+			assert.Equal(t, "SHA256:test", r.Certificate.Fingerprint)
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for the shared result")
+		}
+	}
+	assert.Equal(t, int32(1), checks.Load())
+	assert.Equal(t, int32(1), handled.Load(), "the shared result is stored once")
+
+	// Once settled the domain is checked again
+	r := cs.CheckNow(context.Background(), "dedup-test:a", 1, 1)
+	assert.NoError(t, r.Error)
+	assert.Equal(t, int32(2), checks.Load())
+	cs.Stop()
+}
+
+// TestCertService_CheckDuringStop - a check queued while the service stops is settled either way, so later checks
+// of the domain don't wait for it forever.
+func TestCertService_CheckDuringStop(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	for i := range 50 {
+		cs := NewCertServiceWithPool(1, time.Second)
+		cs.SetChecker(CheckerFunc(func(context.Context, string) (*SSLCertificate, error) {
+			return &SSLCertificate{}, nil
+		}))
+		cs.Start()
+
+		stopped := make(chan struct{})
+		go func() {
+			cs.Stop()
+			close(stopped)
+		}()
+		ch := cs.CheckDomain("example.com", i, 1)
+		<-stopped
+
+		select {
+		case <-ch:
+		case <-time.After(2 * time.Second):
+			t.Fatal("the check was never settled")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		r := cs.CheckNow(ctx, "example.com", i, 1)
+		cancel()
+		assert.NotErrorIs(t, r.Error, context.DeadlineExceeded, "Nothing is left in flight for the domain")
+	}
+}
+
+// TestCertService_SetChecker - queued and immediate checks go through the checker given instead of the network.
+func TestCertService_SetChecker(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var checked []string
+	var mu sync.Mutex
+	cs := NewCertServiceWithPool(2, time.Second)
+	cs.SetChecker(CheckerFunc(func(_ context.Context, name string) (*SSLCertificate, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		checked = append(checked, name)
+		return &SSLCertificate{Issuer: "Fake CA"}, nil
+	}))
+	cs.SetResultHandler(func(Result) {})
+	cs.Start()
+
+	select {
+	case r := <-cs.CheckDomain("queued.invalid", 1, 1):
+		require.NoError(t, r.Error)
+		assert.Equal(t, "Fake CA", r.Certificate.Issuer)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the queued check")
+	}
+	r := cs.CheckNow(context.Background(), "now.invalid", 2, 1)
+	require.NoError(t, r.Error)
+	cs.Stop()
+
+	assert.ElementsMatch(t, []string{"queued.invalid", "now.invalid"}, checked)
+}
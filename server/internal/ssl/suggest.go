@@ -0,0 +1,138 @@
+package ssl
+
+import (
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// commonTLDTypos maps frequently mistyped top level domains to the intended one. Only those that aren't top level
+// domains themselves are corrected, e.g. cm is Cameroon's, not a typo of com
+var commonTLDTypos = map[string]string{
+	"con":  "com",
+	"cmo":  "com",
+	"ocm":  "com",
+	"cm":   "com",
+	"comm": "com",
+	"vom":  "com",
+	"nte":  "net",
+	"ent":  "net",
+	"ner":  "net",
+	"ogr":  "org",
+	"rog":  "org",
+	"orgg": "org",
+	"oi":   "io",
+}
+
+// wellKnownDomains are popular registrable domains that typos are compared against
+var wellKnownDomains = []string{
+	"google.com",
+	"github.com",
+	"gitlab.com",
+	"amazon.com",
+	"apple.com",
+	"microsoft.com",
+	"facebook.com",
+	"youtube.com",
+	"twitter.com",
+	"linkedin.com",
+	"cloudflare.com",
+	"wikipedia.org",
+	"example.com",
+	"reddit.com",
+	"netflix.com",
+	"stackoverflow.com",
+}
+
+// SuggestHostname proposes a correction for a likely mistyped hostname.
+//
+// The suggestion fixes:
+//   - A leading URL scheme or trailing path
+//   - Common top level domain typos (e.g. .con -> .com), unless they are real top level domains too
+//   - Names one or two edits away from a well known domain with the same top level domain (e.g. gogle.com ->
+//     google.com)
+//
+// Returns the suggested hostname and true, or an empty string and false when nothing looks wrong
+func SuggestHostname(hostname string) (string, bool) {
+	original := strings.TrimSpace(hostname)
+	suggestion := strings.ToLower(original)
+
+	if i := strings.Index(suggestion, "://"); i >= 0 {
+		suggestion = suggestion[i+3:]
+	}
+	if i := strings.IndexAny(suggestion, "/?#"); i >= 0 {
+		suggestion = suggestion[:i]
+	}
+
+	labels := strings.Split(suggestion, ".")
+	if len(labels) > 1 {
+		tld := labels[len(labels)-1]
+		if fixed, ok := commonTLDTypos[tld]; ok && !isTLD(tld) {
+			labels[len(labels)-1] = fixed
+		}
+	}
+
+	// Compare the registrable part (last two labels) against well known domains, keeping a real top level domain
+	if len(labels) >= 2 {
+		registrable := strings.Join(labels[len(labels)-2:], ".")
+		tld := labels[len(labels)-1]
+		if closest, ok := closestKnownDomain(registrable); ok && (strings.HasSuffix(closest, "."+tld) || !isTLD(tld)) {
+			labels = append(labels[:len(labels)-2], strings.Split(closest, ".")...)
+		}
+	}
+	suggestion = strings.Join(labels, ".")
+
+	if suggestion == "" || suggestion == original {
+		return "", false
+	}
+	return suggestion, true
+}
+
+// isTLD reports whether label is a top level domain in the public suffix list
+func isTLD(label string) bool {
+	_, icann := publicsuffix.PublicSuffix(label)
+	return icann
+}
+
+// closestKnownDomain finds a well known domain within a small edit distance of name.
+//
+// Returns false when name is itself well known or nothing is close enough
+func closestKnownDomain(name string) (string, bool) {
+	maxDistance := 1
+	if len(name) >= 12 {
+		maxDistance = 2
+	}
+
+	best, bestDistance := "", maxDistance+1
+	for _, known := range wellKnownDomains {
+		d := editDistance(name, known)
+		if d == 0 {
+			return "", false
+		}
+		if d < bestDistance {
+			best, bestDistance = known, d
+		}
+	}
+	return best, best != ""
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
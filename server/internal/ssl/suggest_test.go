@@ -0,0 +1,70 @@
+package ssl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSuggestHostname - likely typos get a correction.
+func TestSuggestHostname(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"gogle.com", "google.com"},
+		{"www.githb.com", "www.github.com"},
+		{"example.con", "example.com"},
+		{"mysite.ogr", "mysite.org"},
+		{"https://example.com/path", "example.com"},
+		{"Example.com", "example.com"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			got, ok := SuggestHostname(tc.input)
+			assert.True(t, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+// TestSuggestHostname_NoSuggestion - correct hostnames are left alone.
+func TestSuggestHostname_NoSuggestion(t *testing.T) {
+	valid := []string{
+		"google.com",
+		"gitlab.com",
+		"my-company.net",
+		"sub.example.org",
+		"example.cm",
+		"",
+	}
+
+	for _, h := range valid {
+		t.Run(h, func(t *testing.T) {
+			_, ok := SuggestHostname(h)
+			assert.False(t, ok)
+		})
+	}
+}
+
+// TestEditDistance - spot checks of the Levenshtein distance.
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("abc", "abc"))
+	assert.Equal(t, 1, editDistance("gogle", "google"))
+	assert.Equal(t, 3, editDistance("", "abc"))
+	assert.Equal(t, 2, editDistance("gitlab", "github"))
+}
+
+// FuzzSuggestHostname - random input shouldn't crash the suggester.
+func FuzzSuggestHostname(f *testing.F) {
+	f.Add("gogle.com")
+	f.Add("")
+	f.Add("https://")
+	f.Add(strings.Repeat(".", 50))
+
+	f.Fuzz(func(t *testing.T, input string) {
+		_, _ = SuggestHostname(input)
+	})
+}
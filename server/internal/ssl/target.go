@@ -0,0 +1,138 @@
+package ssl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// TargetChecker checks tracked names that aren't hostnames, e.g. certificates held by a cloud provider
+type TargetChecker func(ctx context.Context, name string) (*SSLCertificate, error)
+
+// IssuerVerifier describes why the CA that issued a hostname's certificate shouldn't have, empty when it may
+type IssuerVerifier func(ctx context.Context, hostname Hostname, issuerOrganization string) string
+
+var (
+	checkersMu     sync.RWMutex
+	checkers       = map[string]TargetChecker{}
+	issuerVerifier IssuerVerifier
+)
+
+// RegisterTargetChecker checks names starting with prefix through check instead of connecting to them
+func RegisterTargetChecker(prefix string, check TargetChecker) {
+	checkersMu.Lock()
+	defer checkersMu.Unlock()
+	checkers[prefix] = check
+}
+
+// SetIssuerVerifier verifies the issuer of every certificate a hostname serves, its answer becoming the
+// certificate's warning. Nil stops verifying
+func SetIssuerVerifier(verify IssuerVerifier) {
+	checkersMu.Lock()
+	defer checkersMu.Unlock()
+	issuerVerifier = verify
+}
+
+// targetChecker returns the checker registered for a name, nil for hostnames and files
+func targetChecker(name string) TargetChecker {
+	checkersMu.RLock()
+	defer checkersMu.RUnlock()
+	for prefix, check := range checkers {
+		if strings.HasPrefix(name, prefix) {
+			return check
+		}
+	}
+	return nil
+}
+
+// NormalizeTarget returns the form a name is tracked under: hostnames, including those of SSH targets, are
+// normalized by NormalizeHostname while file paths, cloud identifiers and other names with a scheme are kept as
+// they are
+func NormalizeTarget(name string) string {
+	name = strings.TrimSpace(name)
+	if IsSSHTarget(name) {
+		hostport := strings.TrimSuffix(strings.TrimPrefix(name, SSHPrefix), "/")
+		if host, port, err := net.SplitHostPort(hostport); err == nil {
+			return SSHPrefix + net.JoinHostPort(NormalizeHostname(host), port)
+		}
+		return SSHPrefix + NormalizeHostname(hostport)
+	}
+	// Hostnames have no colons, anything with one is a path or an identifier that may be case-sensitive
+	if IsFileTarget(name) || targetChecker(name) != nil || strings.Contains(name, ":") {
+		return name
+	}
+	return NormalizeHostname(name)
+}
+
+// ValidateTarget checks a name that is about to be tracked.
+//
+// Hostnames, including those of SSH targets, have to resolve, file targets need an absolute path to a readable
+// file and names with a registered checker are left to it
+func ValidateTarget(name string) error {
+	if targetChecker(name) != nil {
+		return nil
+	}
+	if IsSSHTarget(name) {
+		host, _, err := SSHAddress(name)
+		if err != nil {
+			return err
+		}
+		return ValidateHostnameDNS(host)
+	}
+	if !IsFileTarget(name) {
+		return ValidateHostnameDNS(name)
+	}
+	path := FilePath(name)
+	if !filepath.IsAbs(path) {
+		return errors.New("certificate file path must be absolute: " + path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("could not read certificate file: %w", err)
+	}
+	if info.IsDir() {
+		return errors.New("certificate path is a directory, scan it to track the files inside: " + path)
+	}
+	return nil
+}
+
+// CheckTarget checks a tracked name, reading the file for file targets, the host key for SSH targets, using
+// the registered checker for its prefix if there is one and connecting to the host otherwise
+func CheckTarget(ctx context.Context, name string) (*SSLCertificate, error) {
+	if IsFileTarget(name) {
+		return CheckCertificateFile(FilePath(name))
+	}
+	if IsSSHTarget(name) {
+		return CheckSSHHost(ctx, name)
+	}
+	if check := targetChecker(name); check != nil {
+		return check(ctx, name)
+	}
+	hostname, err := NewHostname(name)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := CheckSSLCertificate(ctx, hostname)
+	if err != nil {
+		return nil, err
+	}
+	checkersMu.RLock()
+	verify := issuerVerifier
+	checkersMu.RUnlock()
+	if verify != nil {
+		// Keeps the warning about the chain the check may have found
+		switch warning := verify(ctx, hostname, cert.IssuerOrganization); {
+		case warning == "":
+		case cert.Warning == "":
+			cert.Warning = warning
+		default:
+			cert.Warning += "; " + warning
+		}
+	}
+	return cert, nil
+}
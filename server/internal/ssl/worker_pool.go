@@ -2,6 +2,8 @@ package ssl
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"sync"
 	"time"
@@ -18,84 +20,264 @@ type Result struct {
 	Certificate *SSLCertificate
 	Error       error
 	CheckedAt   time.Time
+	// Latency is how long the check took
+	Latency time.Duration
+	// StoreError is why the result handler couldn't store the result, set by the handler itself
+	StoreError error
+}
+
+// ErrPoolStopped occurs when a task is added to a worker pool that was stopped
+var ErrPoolStopped = errors.New("worker pool is stopped")
+
+// DefaultCheckTimeout is how long a single certificate check may take
+const DefaultCheckTimeout = 10 * time.Second
+
+// DefaultQueueSize is how many checks can wait for a worker before queueing another blocks
+const DefaultQueueSize = 100
+
+// PoolSize is how many checks a worker pool runs at once and how many more can wait for a worker
+type PoolSize struct {
+	Workers   int `json:"workers"`
+	QueueSize int `json:"queue_size"`
+}
+
+// Validate reports a pool without workers or queue
+func (p PoolSize) Validate() error {
+	if p.Workers < 1 {
+		return fmt.Errorf("workers must be at least 1, got %d", p.Workers)
+	}
+	if p.QueueSize < 1 {
+		return fmt.Errorf("queue size must be at least 1, got %d", p.QueueSize)
+	}
+	return nil
+}
+
+// PoolStats is what a worker pool is doing and has done since it was created
+type PoolStats struct {
+	Workers int `json:"workers"`
+	// Busy counts the workers running a check
+	Busy int `json:"busy"`
+	// Queued counts the checks waiting for a worker
+	Queued int `json:"queued"`
+	// InFlight counts the checks taken by a worker whose results haven't been handed over yet, more than Busy
+	// while results wait for the handler
+	InFlight int `json:"in_flight"`
+	// Completed counts the checks finished, also those run right away rather than queued, Failed those of them
+	// that failed
+	Completed int64 `json:"completed"`
+	Failed    int64 `json:"failed"`
+	// AvgLatency is how long a completed check took on average, 0 before any completed
+	AvgLatency time.Duration `json:"avg_latency_ns"`
 }
 
 type WorkerPool struct {
-	tasks   chan Task
-	results chan Result
-	workers int
-	wg      sync.WaitGroup
-	ctx     context.Context
-	cancel  context.CancelFunc
+	results      chan Result
+	checkTimeout time.Duration
+	checker      Checker
+	wg           sync.WaitGroup
+	ctx          context.Context
+	cancel       context.CancelFunc
+
+	mu sync.Mutex
+	// changed is broadcast whenever the queue, its size or the number of workers changes
+	changed *sync.Cond
+	queue   []Task
+	size    PoolSize
+	// running is the number of worker goroutines, more than size.Workers while a shrunk pool winds down
+	running int
+	started bool
+	closed  bool
+	// stats counts what the workers do, Stats fills in the rest
+	stats PoolStats
+	// latency is how long the completed checks took together
+	latency time.Duration
 }
 
 func NewWorkerPool(workers int) *WorkerPool {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &WorkerPool{
-		tasks:   make(chan Task, 100),
-		results: make(chan Result, 100),
-		workers: workers,
-		ctx:     ctx,
-		cancel:  cancel,
+	wp := &WorkerPool{
+		results:      make(chan Result, DefaultQueueSize),
+		checkTimeout: DefaultCheckTimeout,
+		checker:      DefaultChecker,
+		ctx:          ctx,
+		cancel:       cancel,
+		size:         PoolSize{Workers: workers, QueueSize: DefaultQueueSize},
+	}
+	wp.changed = sync.NewCond(&wp.mu)
+	return wp
+}
+
+// SetCheckTimeout changes how long each check may take, it must be called before Start
+func (wp *WorkerPool) SetCheckTimeout(timeout time.Duration) {
+	wp.checkTimeout = timeout
+}
+
+// SetChecker changes what checks each task, DefaultChecker unless set. It must be called before Start
+func (wp *WorkerPool) SetChecker(checker Checker) {
+	wp.checker = checker
+}
+
+// Size is how many workers the pool runs and how many tasks can wait for them
+func (wp *WorkerPool) Size() PoolSize {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	return wp.size
+}
+
+// Stats is what the pool is doing and has done
+func (wp *WorkerPool) Stats() PoolStats {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	stats := wp.stats
+	stats.Workers = wp.size.Workers
+	stats.Queued = len(wp.queue)
+	if stats.Completed > 0 {
+		stats.AvgLatency = wp.latency / time.Duration(stats.Completed)
+	}
+	return stats
+}
+
+// Resize changes the number of workers and the queue size, also while the pool runs. Workers that are no longer
+// needed finish their current check first, and tasks already queued beyond a smaller queue size still run
+func (wp *WorkerPool) Resize(size PoolSize) error {
+	if err := size.Validate(); err != nil {
+		return err
+	}
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.size = size
+	if wp.started && !wp.closed {
+		wp.spawn()
+	}
+	wp.changed.Broadcast()
+	slog.Info("Worker pool resized", "workers", size.Workers, "queue_size", size.QueueSize)
+	return nil
+}
+
+// spawn starts workers until there are enough, wp.mu must be held
+func (wp *WorkerPool) spawn() {
+	for wp.running < wp.size.Workers {
+		wp.running++
+		wp.wg.Add(1)
+		go wp.worker()
 	}
 }
 
 func (wp *WorkerPool) processTask(task Task) Result {
-	hostname, err := NewHostname(task.Domain)
+	return wp.check(wp.ctx, task)
+}
+
+// check runs a task within ctx and the check timeout
+func (wp *WorkerPool) check(ctx context.Context, task Task) Result {
+	ctx, cancel := context.WithTimeout(ctx, wp.checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	certificate, err := wp.checker.CheckSSLCertificate(ctx, task.Domain)
+	checkedAt := time.Now()
+
+	wp.mu.Lock()
+	wp.stats.Completed++
 	if err != nil {
-		return Result{
-			Task:      task,
-			Error:     err,
-			CheckedAt: time.Now(),
-		}
+		wp.stats.Failed++
 	}
-	ctx, cancel := context.WithTimeout(wp.ctx, 10*time.Second)
-	defer cancel()
+	wp.latency += checkedAt.Sub(start)
+	wp.mu.Unlock()
 
-	certificate, err := CheckSSLCertificate(ctx, hostname)
 	return Result{
 		Task:        task,
 		Certificate: certificate,
 		Error:       err,
-		CheckedAt:   time.Now(),
+		CheckedAt:   checkedAt,
+		Latency:     checkedAt.Sub(start),
 	}
 }
 
 func (wp *WorkerPool) Start() {
-	for i := 0; i < wp.workers; i++ {
-		wp.wg.Add(1)
-		go wp.worker(i)
-	}
-	slog.Info("Worker pool started", "workers", wp.workers)
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.started = true
+	wp.spawn()
+	slog.Info("Worker pool started", "workers", wp.size.Workers, "queue_size", wp.size.QueueSize)
 }
 
+// Stop waits for the queued tasks to be checked, then closes the results
 func (wp *WorkerPool) Stop() {
-	close(wp.tasks)
+	wp.mu.Lock()
+	wp.closed = true
+	wp.changed.Broadcast()
+	wp.mu.Unlock()
+
 	wp.wg.Wait()
 	close(wp.results)
 	wp.cancel()
 	slog.Info("Worker pool stopped")
 }
 
-func (wp *WorkerPool) AddTask(task Task) {
-	select {
-	case wp.tasks <- task:
-	case <-wp.ctx.Done():
+// AddTask queues a task, waiting while the queue is full. Tasks added after Stop are dropped with ErrPoolStopped,
+// they will never have a result
+func (wp *WorkerPool) AddTask(task Task) error {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	for len(wp.queue) >= wp.size.QueueSize && !wp.closed {
+		wp.changed.Wait()
 	}
+	if wp.closed {
+		return ErrPoolStopped
+	}
+	wp.queue = append(wp.queue, task)
+	wp.changed.Broadcast()
+	return nil
 }
 
-func (wp *WorkerPool) worker(id int) {
+func (wp *WorkerPool) worker() {
 	defer wp.wg.Done()
-	for task := range wp.tasks {
+	for {
+		task, ok := wp.next()
+		if !ok {
+			return
+		}
 		result := wp.processTask(task)
+		wp.mu.Lock()
+		wp.stats.Busy--
+		wp.mu.Unlock()
+
+		delivered := true
 		select {
 		case wp.results <- result:
 		case <-wp.ctx.Done():
+			delivered = false
+		}
+		wp.mu.Lock()
+		wp.stats.InFlight--
+		wp.mu.Unlock()
+		if !delivered {
 			return
 		}
 	}
 }
 
+// next waits for a task, or reports that the worker should exit because the pool shrank or stopped with nothing
+// left to check
+func (wp *WorkerPool) next() (Task, bool) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	for len(wp.queue) == 0 && !wp.closed && wp.running <= wp.size.Workers {
+		wp.changed.Wait()
+	}
+	if wp.running > wp.size.Workers || len(wp.queue) == 0 {
+		wp.running--
+		return Task{}, false
+	}
+	task := wp.queue[0]
+	wp.queue[0] = Task{}
+	wp.queue = wp.queue[1:]
+	wp.stats.Busy++
+	wp.stats.InFlight++
+	wp.changed.Broadcast()
+	return task, true
+}
+
 func (wp *WorkerPool) GetResults() <-chan Result {
 	return wp.results
 }
@@ -1,12 +1,15 @@
 package ssl
 
 import (
+	"context"
+	"errors"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/goleak"
 )
 
@@ -159,6 +162,17 @@ func TestWorkerPool_ResultHasTimestamp(t *testing.T) {
 	wp.Stop()
 }
 
+// TestWorkerPool_AddAfterStop - a stopped pool refuses new tasks instead of dropping them silently.
+func TestWorkerPool_AddAfterStop(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	wp := NewWorkerPool(1)
+	wp.Start()
+	wp.Stop()
+
+	assert.ErrorIs(t, wp.AddTask(Task{Domain: "example.com", DomainID: 1, UserID: 1}), ErrPoolStopped)
+}
+
 // TestWorkerPool_ZeroWorkers - edge case: pool with 0 workers should still stop.
 func TestWorkerPool_ZeroWorkers(t *testing.T) {
 	defer goleak.VerifyNone(t)
@@ -214,3 +228,83 @@ func TestWorkerPool_HighLoad(t *testing.T) {
 
 	assert.Equal(t, int32(500), count.Load())
 }
+
+// TestWorkerPool_Resize - a running pool grows and shrinks, and a bigger queue takes more tasks without blocking.
+func TestWorkerPool_Resize(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	release := make(chan struct{})
+	var running, peak atomic.Int32
+	wp := NewWorkerPool(1)
+	wp.SetChecker(CheckerFunc(func(context.Context, string) (*SSLCertificate, error) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		running.Add(-1)
+		return nil, errors.New("checked")
+	}))
+	assert.Error(t, wp.Resize(PoolSize{Workers: 0, QueueSize: 10}))
+	assert.NoError(t, wp.Resize(PoolSize{Workers: 1, QueueSize: 1}))
+	wp.Start()
+	done := drainResults(wp)
+
+	require.NoError(t, wp.Resize(PoolSize{Workers: 3, QueueSize: 5}))
+	for i := 0; i < 8; i++ {
+		wp.AddTask(Task{Domain: "example.com", DomainID: i})
+	}
+	assert.Eventually(t, func() bool { return running.Load() == 3 }, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, wp.Resize(PoolSize{Workers: 1, QueueSize: 5}))
+	close(release)
+	wp.Stop()
+	<-done
+
+	assert.Equal(t, int32(3), peak.Load())
+	assert.Equal(t, PoolSize{Workers: 1, QueueSize: 5}, wp.Size())
+}
+
+// TestWorkerPool_Stats - checks are counted as they are queued, run and finish.
+func TestWorkerPool_Stats(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	release := make(chan struct{})
+	wp := NewWorkerPool(2)
+	wp.SetChecker(CheckerFunc(func(_ context.Context, domain string) (*SSLCertificate, error) {
+		<-release
+		if domain == "broken.example.com" {
+			return nil, errors.New("handshake failed")
+		}
+		return &SSLCertificate{}, nil
+	}))
+	wp.Start()
+
+	for _, domain := range []string{"example.com", "broken.example.com", "example.org"} {
+		wp.AddTask(Task{Domain: domain})
+	}
+	assert.Eventually(t, func() bool { return wp.Stats().Busy == 2 }, time.Second, 5*time.Millisecond)
+	stats := wp.Stats()
+	assert.Equal(t, 2, stats.Workers)
+	assert.Equal(t, 1, stats.Queued)
+	assert.Equal(t, 2, stats.InFlight)
+	assert.Zero(t, stats.Completed)
+	assert.Zero(t, stats.AvgLatency)
+
+	close(release)
+	for range 3 {
+		<-wp.GetResults()
+	}
+	wp.Stop()
+
+	stats = wp.Stats()
+	assert.Zero(t, stats.Busy)
+	assert.Zero(t, stats.Queued)
+	assert.Zero(t, stats.InFlight)
+	assert.Equal(t, int64(3), stats.Completed)
+	assert.Equal(t, int64(1), stats.Failed)
+	assert.Positive(t, stats.AvgLatency)
+}
@@ -0,0 +1,140 @@
+// This package signs users in through an OpenID Connect provider such as Keycloak or Google Workspace
+//
+// It only talks to the provider, mapping the identities it returns to local users is left to the user service
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// discoveryTimeout bounds fetching the provider's configuration at startup
+const discoveryTimeout = 30 * time.Second
+
+// Config is the OIDC client registered with the provider
+type Config struct {
+	// Issuer is the provider's URL, e.g. https://keycloak.example.com/realms/ops or https://accounts.google.com
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is where the provider sends users back to, the server's /api/v1/auth/oidc/callback
+	RedirectURL string
+	// Scopes are requested on top of openid, email when empty
+	Scopes []string
+}
+
+// Identity is a user as vouched for by the provider
+type Identity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+// Provider runs the authorization code flow against one OpenID Connect provider
+type Provider struct {
+	oauth    oauth2.Config
+	verifier *oidc.IDTokenVerifier
+	client   *http.Client
+}
+
+// NewProvider fetches the provider's configuration from its discovery document.
+//
+// A nil httpClient uses http.DefaultClient
+func NewProvider(ctx context.Context, config Config, httpClient *http.Client) (*Provider, error) {
+	if config.Issuer == "" || config.ClientID == "" {
+		return nil, errors.New("OIDC needs an issuer and a client ID")
+	}
+	if config.RedirectURL == "" {
+		return nil, errors.New("OIDC needs a redirect URL")
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	ctx = oidc.ClientContext(ctx, httpClient)
+
+	discoveryCtx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	defer cancel()
+	provider, err := oidc.NewProvider(discoveryCtx, config.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC provider: %w", err)
+	}
+
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"email"}
+	}
+	return &Provider{
+		oauth: oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       append([]string{oidc.ScopeOpenID}, scopes...),
+		},
+		// Keys are fetched lazily with ctx's client, so it has to outlive discovery
+		verifier: provider.VerifierContext(ctx, &oidc.Config{ClientID: config.ClientID}),
+		client:   httpClient,
+	}, nil
+}
+
+// AuthCodeURL returns the provider's sign in page, which redirects back with a code for Exchange.
+//
+// state and nonce must be unguessable and kept by the caller, verifier is from oauth2.GenerateVerifier
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) string {
+	return p.oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+}
+
+// Exchange redeems a code from the redirect for the identity of the user who signed in
+func (p *Provider) Exchange(ctx context.Context, code, nonce, verifier string) (*Identity, error) {
+	ctx = oidc.ClientContext(ctx, p.client)
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem authorization code: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("provider returned no ID token")
+	}
+
+	identity, idToken, err := p.verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("ID token was issued for another sign in")
+	}
+	return identity, nil
+}
+
+// Verify checks an ID token issued to this client, so API callers can present one instead of a session token
+func (p *Provider) Verify(ctx context.Context, rawIDToken string) (*Identity, error) {
+	identity, _, err := p.verify(oidc.ClientContext(ctx, p.client), rawIDToken)
+	return identity, err
+}
+
+func (p *Provider) verify(ctx context.Context, rawIDToken string) (*Identity, *oidc.IDToken, error) {
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, nil, fmt.Errorf("invalid ID token claims: %w", err)
+	}
+	return &Identity{
+		Issuer:        idToken.Issuer,
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+	}, idToken, nil
+}
@@ -0,0 +1,128 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/coreos/go-oidc/v3/oidc/oidctest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIssuer is an OIDC provider whose token endpoint answers every code with an ID token carrying nonce.
+type fakeIssuer struct {
+	*httptest.Server
+	key   *rsa.PrivateKey
+	nonce string
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	discovery := &oidctest.Server{PublicKeys: []oidctest.PublicKey{{PublicKey: key.Public(), KeyID: "test", Algorithm: oidc.RS256}}}
+
+	f := &fakeIssuer{key: key}
+	mux := http.NewServeMux()
+	mux.Handle("/", discovery)
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "good-code" || r.FormValue("code_verifier") == "" {
+			http.Error(w, `{"error": "invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     f.idToken("alice", f.nonce),
+		})
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	discovery.SetIssuer(f.URL)
+	return f
+}
+
+func (f *fakeIssuer) idToken(subject, nonce string) string {
+	claims := fmt.Sprintf(`{"iss": %q, "aud": "sslcerttop", "sub": %q, "nonce": %q, "exp": %d, "email": "alice@example.com", "email_verified": true}`,
+		f.URL, subject, nonce, time.Now().Add(time.Hour).Unix())
+	return oidctest.SignIDToken(f.key, "test", oidc.RS256, claims)
+}
+
+func newTestProvider(t *testing.T, f *fakeIssuer) *Provider {
+	t.Helper()
+
+	p, err := NewProvider(context.Background(), Config{
+		Issuer:      f.URL,
+		ClientID:    "sslcerttop",
+		RedirectURL: "http://localhost:8080/api/v1/auth/oidc/callback",
+	}, f.Client())
+	require.NoError(t, err)
+	return p
+}
+
+// TestNewProvider_Invalid - the issuer, client and redirect are required.
+func TestNewProvider_Invalid(t *testing.T) {
+	_, err := NewProvider(context.Background(), Config{ClientID: "x", RedirectURL: "http://localhost"}, nil)
+	assert.Error(t, err)
+	_, err = NewProvider(context.Background(), Config{Issuer: "https://issuer.example.com", ClientID: "x"}, nil)
+	assert.Error(t, err)
+}
+
+// TestProvider_AuthCodeURL - the sign in page gets the state, nonce and a PKCE challenge.
+func TestProvider_AuthCodeURL(t *testing.T) {
+	p := newTestProvider(t, newFakeIssuer(t))
+
+	u, err := url.Parse(p.AuthCodeURL("state", "nonce", "verifier-verifier-verifier-verifier-verifier"))
+	require.NoError(t, err)
+	q := u.Query()
+	assert.Equal(t, "/auth", u.Path)
+	assert.Equal(t, "state", q.Get("state"))
+	assert.Equal(t, "nonce", q.Get("nonce"))
+	assert.Equal(t, "openid email", q.Get("scope"))
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+}
+
+// TestProvider_Exchange - a code is redeemed for the identity, provided the nonce matches.
+func TestProvider_Exchange(t *testing.T) {
+	f := newFakeIssuer(t)
+	p := newTestProvider(t, f)
+	ctx := context.Background()
+
+	f.nonce = "nonce"
+	identity, err := p.Exchange(ctx, "good-code", "nonce", "verifier")
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{Issuer: f.URL, Subject: "alice", Email: "alice@example.com", EmailVerified: true}, identity)
+
+	_, err = p.Exchange(ctx, "good-code", "other-nonce", "verifier")
+	assert.Error(t, err)
+
+	_, err = p.Exchange(ctx, "bad-code", "nonce", "verifier")
+	assert.Error(t, err)
+}
+
+// TestProvider_Verify - ID tokens from the provider are accepted, anything else isn't.
+func TestProvider_Verify(t *testing.T) {
+	f := newFakeIssuer(t)
+	p := newTestProvider(t, f)
+
+	identity, err := p.Verify(context.Background(), f.idToken("alice", ""))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", identity.Subject)
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	forged := oidctest.SignIDToken(other, "test", oidc.RS256, fmt.Sprintf(`{"iss": %q, "aud": "sslcerttop", "sub": "mallory", "exp": %d}`, f.URL, time.Now().Add(time.Hour).Unix()))
+	_, err = p.Verify(context.Background(), forged)
+	assert.Error(t, err)
+}
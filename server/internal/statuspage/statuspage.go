@@ -0,0 +1,254 @@
+// Package statuspage renders a static status page of tracked certificates, as HTML for people and JSON for
+// scripts, ready to publish on any static file host
+package statuspage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+)
+
+// untaggedGroup is the group of domains without tags
+const untaggedGroup = "untagged"
+
+// statusOrder sorts the statuses needing attention first
+var statusOrder = map[string]int{"error": 0, "expired": 1, "warning": 2, "soon": 3, "valid": 4, "unknown": 5}
+
+// Entry is a domain on the page
+type Entry struct {
+	Domain      string     `json:"domain"`
+	Status      string     `json:"status"`
+	ExpiryDate  *time.Time `json:"expiry_date"`
+	DaysLeft    *int       `json:"days_left"`
+	Issuer      string     `json:"issuer,omitempty"`
+	LastChecked *time.Time `json:"last_checked"`
+	// Error is only filled in when errors are published
+	Error string `json:"error,omitempty"`
+}
+
+// Group lists the domains carrying a tag, those needing attention first
+type Group struct {
+	Tag     string  `json:"tag"`
+	Domains []Entry `json:"domains"`
+}
+
+// Page is everything the status page shows
+type Page struct {
+	Title       string    `json:"title"`
+	GeneratedAt time.Time `json:"generated_at"`
+	// Counts is the number of distinct domains in each status
+	Counts map[string]int `json:"counts"`
+	Groups []Group        `json:"groups"`
+}
+
+// Options choose what a page shows
+type Options struct {
+	Title string
+	// Tags limits the page to domains with one of them, every domain when empty
+	Tags []string
+	// ShowErrors publishes the error of failing checks, which can reveal internal addresses
+	ShowErrors bool
+}
+
+// Build groups domains by tag as of now, a domain with several tags is in each of their groups
+func Build(domains []domain.Domain, opts Options, now time.Time) Page {
+	page := Page{Title: opts.Title, GeneratedAt: now.UTC(), Counts: map[string]int{}, Groups: []Group{}}
+	if page.Title == "" {
+		page.Title = "Certificate status"
+	}
+
+	groups := map[string][]Entry{}
+	for _, d := range domains {
+		if !d.IsActive || !hasAnyTag(d, opts.Tags) {
+			continue
+		}
+		e := Entry{
+			Domain:     d.DomainName.String(),
+			Status:     d.Status(),
+			ExpiryDate: d.ExpiryTime(),
+			Issuer:     d.Issuer,
+		}
+		if e.ExpiryDate != nil {
+			days := int(e.ExpiryDate.Sub(now).Hours() / 24)
+			e.DaysLeft = &days
+		}
+		if d.LastChecked != nil {
+			t := d.LastChecked.Time()
+			e.LastChecked = &t
+		}
+		if opts.ShowErrors && d.LastError != nil {
+			e.Error = d.LastError.String()
+		}
+		page.Counts[e.Status]++
+
+		tags := d.Tags
+		if len(tags) == 0 {
+			tags = []string{untaggedGroup}
+		}
+		for _, tag := range tags {
+			if len(opts.Tags) == 0 || contains(opts.Tags, tag) {
+				groups[tag] = append(groups[tag], e)
+			}
+		}
+	}
+
+	for tag, entries := range groups {
+		sort.SliceStable(entries, func(i, j int) bool { return less(entries[i], entries[j]) })
+		page.Groups = append(page.Groups, Group{Tag: tag, Domains: entries})
+	}
+	// Untagged domains go last
+	sort.Slice(page.Groups, func(i, j int) bool {
+		if (page.Groups[i].Tag == untaggedGroup) != (page.Groups[j].Tag == untaggedGroup) {
+			return page.Groups[j].Tag == untaggedGroup
+		}
+		return page.Groups[i].Tag < page.Groups[j].Tag
+	})
+	return page
+}
+
+// less orders entries by status, then soonest expiry, then name
+func less(a, b Entry) bool {
+	if statusOrder[a.Status] != statusOrder[b.Status] {
+		return statusOrder[a.Status] < statusOrder[b.Status]
+	}
+	if a.ExpiryDate != nil && b.ExpiryDate != nil && !a.ExpiryDate.Equal(*b.ExpiryDate) {
+		return a.ExpiryDate.Before(*b.ExpiryDate)
+	}
+	return a.Domain < b.Domain
+}
+
+func hasAnyTag(d domain.Domain, tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	for _, tag := range tags {
+		if d.HasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Attention is the number of domains that are failing, expired or expiring within a week
+func (p Page) Attention() int {
+	return p.Counts["error"] + p.Counts["expired"] + p.Counts["warning"]
+}
+
+// Total is the number of domains on the page
+func (p Page) Total() int {
+	total := 0
+	for _, n := range p.Counts {
+		total += n
+	}
+	return total
+}
+
+var pageHTML = template.Must(template.New("statuspage").Funcs(template.FuncMap{
+	"date": func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.UTC().Format(time.DateOnly)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem auto; max-width: 60rem; padding: 0 1rem; color: #1f2328; }
+.summary { padding: 1rem; border-radius: 6px; color: #fff; background: #1a7f37; }
+.summary.attention { background: #cf222e; }
+table { width: 100%; border-collapse: collapse; margin-bottom: 2rem; }
+th, td { text-align: left; padding: 0.4rem; border-bottom: 1px solid #d0d7de; }
+.status { font-weight: 600; text-transform: uppercase; font-size: 0.8rem; }
+.valid { color: #1a7f37; }
+.soon { color: #9a6700; }
+.warning { color: #bc4c00; }
+.expired, .error { color: #cf222e; }
+.unknown { color: #656d76; }
+footer { color: #656d76; font-size: 0.8rem; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{- if .Attention}}
+<p class="summary attention">{{.Attention}} of {{.Total}} certificates need attention</p>
+{{- else}}
+<p class="summary">All {{.Total}} certificates are fine</p>
+{{- end}}
+{{- range .Groups}}
+<h2>{{.Tag}}</h2>
+<table>
+<tr><th>Domain</th><th>Status</th><th>Expires</th><th>Days left</th><th>Issuer</th></tr>
+{{- range .Domains}}
+<tr><td>{{.Domain}}{{if .Error}}<br><small>{{.Error}}</small>{{end}}</td><td class="status {{.Status}}">{{.Status}}</td><td>{{date .ExpiryDate}}</td><td>{{if .DaysLeft}}{{.DaysLeft}}{{else}}-{{end}}</td><td>{{.Issuer}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>No certificates are tracked.</p>
+{{- end}}
+<footer>Generated {{.GeneratedAt.Format "2006-01-02 15:04 UTC"}} by sslcerttop. Also available as <a href="status.json">JSON</a>.</footer>
+</body>
+</html>
+`))
+
+// Write renders the page into dir as index.html and status.json, creating dir if needed. Each file is replaced
+// in one step, so a web server publishing dir never serves half a page
+func Write(dir string, page Page) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	var html bytes.Buffer
+	if err := pageHTML.Execute(&html, page); err != nil {
+		return fmt.Errorf("failed to render status page: %w", err)
+	}
+	data, err := json.MarshalIndent(page, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeFile(filepath.Join(dir, "index.html"), html.Bytes()); err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, "status.json"), append(data, '\n'))
+}
+
+// writeFile replaces path through a temporary file in the same directory
+func writeFile(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
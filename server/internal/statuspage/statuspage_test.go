@@ -0,0 +1,90 @@
+package statuspage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDomain is an active domain expiring in days, no expiry when days is nil.
+func testDomain(name string, days *int, tags ...string) domain.Domain {
+	d := domain.Domain{DomainName: domain.NewDomainName(name), IsActive: true, Tags: tags}
+	if days != nil {
+		expiry := types.NewExpiryDate(time.Now().Add(time.Duration(*days)*24*time.Hour + time.Hour))
+		d.ExpiryDate = &expiry
+	}
+	return d
+}
+
+func days(n int) *int { return &n }
+
+// TestBuild - domains are grouped by tag with the ones needing attention first and untagged ones last.
+func TestBuild(t *testing.T) {
+	failing := testDomain("down.example.com", nil, "web")
+	lastError := domain.NewLastError("connection refused by 10.0.0.5")
+	failing.LastError = &lastError
+	inactive := testDomain("old.example.com", days(1), "web")
+	inactive.IsActive = false
+
+	domains := []domain.Domain{
+		testDomain("www.example.com", days(90), "web", "public"),
+		testDomain("shop.example.com", days(3), "web"),
+		failing,
+		inactive,
+		testDomain("mail.example.com", days(20)),
+	}
+
+	page := Build(domains, Options{}, time.Now())
+	assert.Equal(t, "Certificate status", page.Title)
+	require.Len(t, page.Groups, 3)
+	assert.Equal(t, "public", page.Groups[0].Tag)
+	assert.Equal(t, "web", page.Groups[1].Tag)
+	assert.Equal(t, untaggedGroup, page.Groups[2].Tag)
+
+	web := page.Groups[1].Domains
+	require.Len(t, web, 3, "Inactive domains are left out")
+	assert.Equal(t, "down.example.com", web[0].Domain)
+	assert.Empty(t, web[0].Error, "Errors are private unless asked for")
+	assert.Equal(t, "shop.example.com", web[1].Domain)
+	assert.Equal(t, 3, *web[1].DaysLeft)
+	assert.Equal(t, "www.example.com", web[2].Domain)
+	assert.Equal(t, 4, page.Total(), "A domain in several groups is counted once")
+	assert.Equal(t, 2, page.Attention())
+
+	page = Build(domains, Options{Tags: []string{"public"}, ShowErrors: true}, time.Now())
+	require.Len(t, page.Groups, 1)
+	assert.Equal(t, "public", page.Groups[0].Tag)
+	assert.Equal(t, 1, page.Total())
+}
+
+// TestWrite - the page is written as HTML and JSON, with domain names escaped.
+func TestWrite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "public")
+	page := Build([]domain.Domain{testDomain("<b>www.example.com</b>", days(40))}, Options{Title: "Example"}, time.Now())
+	require.NoError(t, Write(dir, page))
+
+	html, err := os.ReadFile(filepath.Join(dir, "index.html"))
+	require.NoError(t, err)
+	assert.Contains(t, string(html), "<title>Example</title>")
+	assert.Contains(t, string(html), "&lt;b&gt;www.example.com&lt;/b&gt;")
+	assert.Contains(t, string(html), `class="status valid"`)
+	assert.Contains(t, string(html), "All 1 certificates are fine")
+
+	data, err := os.ReadFile(filepath.Join(dir, "status.json"))
+	require.NoError(t, err)
+	var decoded Page
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, 1, decoded.Counts["valid"])
+	assert.Equal(t, 40, *decoded.Groups[0].Domains[0].DaysLeft)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "No temporary files are left behind")
+}
@@ -0,0 +1,77 @@
+// This package groups users into teams that share a domain list
+//
+// Domains stay private to whoever added them until they are shared with a team, every member then sees them
+package team
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// Role decides what a member may do in a team
+type Role string
+
+const (
+	// RoleOwner manages the team and its members
+	RoleOwner Role = "owner"
+	// RoleMember sees and manages the team's domains
+	RoleMember Role = "member"
+)
+
+// ParseRole validates a role name
+func ParseRole(role string) (Role, error) {
+	switch Role(role) {
+	case RoleOwner, RoleMember:
+		return Role(role), nil
+	default:
+		return "", fmt.Errorf("unknown role %q, expected %q or %q", role, RoleOwner, RoleMember)
+	}
+}
+
+func (r Role) String() string {
+	return string(r)
+}
+
+var (
+	// ErrNotFound is returned for a team that doesn't exist or that the user isn't a member of
+	ErrNotFound = errors.New("team not found")
+	// ErrNameTaken is returned when creating a team with the name of another
+	ErrNameTaken = errors.New("a team with this name already exists")
+	// ErrNotOwner is returned when a member who isn't an owner tries to manage the team
+	ErrNotOwner = errors.New("only team owners can do this")
+	// ErrAlreadyMember is returned when adding a user to a team they are in
+	ErrAlreadyMember = errors.New("user is already a member of this team")
+	// ErrNotMember is returned when changing a user who isn't in the team
+	ErrNotMember = errors.New("user is not a member of this team")
+	// ErrLastOwner is returned when the change would leave a team without owners
+	ErrLastOwner = errors.New("a team needs at least one owner")
+)
+
+type Team struct {
+	TeamID    types.TeamID `db:"id"`
+	Name      string       `db:"name"`
+	CreatedAt time.Time    `db:"created_at"`
+}
+
+// Member is a user's place in a team
+type Member struct {
+	TeamID types.TeamID `db:"team_id"`
+	UserID types.UserID `db:"user_id"`
+	// Email is the member's account, empty for the default user
+	Email    string    `db:"email"`
+	Role     Role      `db:"role"`
+	JoinedAt time.Time `db:"created_at"`
+}
+
+// normalizeName trims a team name, rejecting empty ones
+func normalizeName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("team name cannot be empty")
+	}
+	return name, nil
+}
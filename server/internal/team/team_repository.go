@@ -0,0 +1,200 @@
+package team
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+type Repository struct {
+	db     *sql.DB
+	writer *database.Writer
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db:     db,
+		writer: database.NewWriter(db),
+	}
+}
+
+const selectTeams = `SELECT t.id, t.name, t.created_at FROM teams t`
+
+const selectMembers = `SELECT m.team_id, m.user_id, COALESCE(u.email, ''), m.role, m.created_at FROM team_members m JOIN users u ON u.id = m.user_id`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTeam(row scanner) (Team, error) {
+	var id uint
+	var t Team
+	if err := row.Scan(&id, &t.Name, &t.CreatedAt); err != nil {
+		return Team{}, err
+	}
+	t.TeamID = types.TeamID(id)
+	return t, nil
+}
+
+func scanMember(row scanner) (Member, error) {
+	var teamID, userID uint
+	var role string
+	var m Member
+	if err := row.Scan(&teamID, &userID, &m.Email, &role, &m.JoinedAt); err != nil {
+		return Member{}, err
+	}
+	m.TeamID = types.TeamID(teamID)
+	m.UserID = types.UserID(userID)
+	m.Role = Role(role)
+	return m, nil
+}
+
+// CreateTeam stores a new team with owner as its first owner
+func (r *Repository) CreateTeam(t *Team, owner types.UserID) error {
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	return r.writer.Transaction(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`INSERT INTO teams (name, created_at) VALUES (?, ?)`, t.Name, t.CreatedAt)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		t.TeamID = types.TeamID(id)
+
+		query := `INSERT INTO team_members (team_id, user_id, role, created_at) VALUES (?, ?, ?, ?)`
+		_, err = tx.Exec(query, t.TeamID.Uint(), owner.Uint(), RoleOwner.String(), t.CreatedAt)
+		return err
+	})
+}
+
+// GetTeamByName looks up a team by its name, returning nil if there is none
+func (r *Repository) GetTeamByName(name string) (*Team, error) {
+	t, err := scanTeam(r.db.QueryRow(selectTeams+` WHERE t.name = ?`, name))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetTeamsByUserID lists the teams a user belongs to by name
+func (r *Repository) GetTeamsByUserID(userID types.UserID) ([]Team, error) {
+	query := selectTeams + ` JOIN team_members m ON m.team_id = t.id WHERE m.user_id = ? ORDER BY t.name`
+	rows, err := r.db.Query(query, userID.Uint())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	teams := []Team{}
+	for rows.Next() {
+		t, err := scanTeam(rows)
+		if err != nil {
+			return nil, err
+		}
+		teams = append(teams, t)
+	}
+	return teams, rows.Err()
+}
+
+// GetTeamIDs lists the IDs of the teams a user belongs to
+func (r *Repository) GetTeamIDs(userID types.UserID) ([]types.TeamID, error) {
+	rows, err := r.db.Query(`SELECT team_id FROM team_members WHERE user_id = ? ORDER BY team_id`, userID.Uint())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []types.TeamID
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, types.TeamID(id))
+	}
+	return ids, rows.Err()
+}
+
+// GetMembers lists the members of a team, owners first
+func (r *Repository) GetMembers(teamID types.TeamID) ([]Member, error) {
+	query := selectMembers + ` WHERE m.team_id = ? ORDER BY m.role DESC, u.email`
+	rows, err := r.db.Query(query, teamID.Uint())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []Member{}
+	for rows.Next() {
+		m, err := scanMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// GetMember looks up a user's membership of a team, returning nil if they aren't in it
+func (r *Repository) GetMember(teamID types.TeamID, userID types.UserID) (*Member, error) {
+	m, err := scanMember(r.db.QueryRow(selectMembers+` WHERE m.team_id = ? AND m.user_id = ?`, teamID.Uint(), userID.Uint()))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// CountOwners returns how many owners a team has
+func (r *Repository) CountOwners(teamID types.TeamID) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM team_members WHERE team_id = ? AND role = ?`, teamID.Uint(), RoleOwner.String()).Scan(&count)
+	return count, err
+}
+
+// AddMember stores a user's membership of a team
+func (r *Repository) AddMember(m *Member) error {
+	if m.JoinedAt.IsZero() {
+		m.JoinedAt = time.Now()
+	}
+	query := `INSERT INTO team_members (team_id, user_id, role, created_at) VALUES (?, ?, ?, ?)`
+	_, err := r.writer.Exec(query, m.TeamID.Uint(), m.UserID.Uint(), m.Role.String(), m.JoinedAt)
+	return err
+}
+
+// UpdateRole changes the role of a member
+func (r *Repository) UpdateRole(teamID types.TeamID, userID types.UserID, role Role) error {
+	_, err := r.writer.Exec(`UPDATE team_members SET role = ? WHERE team_id = ? AND user_id = ?`, role.String(), teamID.Uint(), userID.Uint())
+	return err
+}
+
+// RemoveMember takes a user out of a team
+func (r *Repository) RemoveMember(teamID types.TeamID, userID types.UserID) error {
+	_, err := r.writer.Exec(`DELETE FROM team_members WHERE team_id = ? AND user_id = ?`, teamID.Uint(), userID.Uint())
+	return err
+}
+
+// DeleteTeam removes a team and its memberships, its domains go back to whoever added them
+func (r *Repository) DeleteTeam(teamID types.TeamID) error {
+	return r.writer.Transaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`UPDATE domains SET team_id = NULL WHERE team_id = ?`, teamID.Uint()); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM team_members WHERE team_id = ?`, teamID.Uint()); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM teams WHERE id = ?`, teamID.Uint())
+		return err
+	})
+}
@@ -0,0 +1,201 @@
+package team
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+type Service struct {
+	teamRepo *Repository
+}
+
+func NewService(teamRepo *Repository) *Service {
+	return &Service{
+		teamRepo: teamRepo,
+	}
+}
+
+// CreateTeam starts a team with the user as its owner
+func (s *Service) CreateTeam(userID types.UserID, name string) (*Team, error) {
+	name, err := normalizeName(name)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.teamRepo.GetTeamByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up team: %w", err)
+	}
+	if existing != nil {
+		return nil, ErrNameTaken
+	}
+
+	t := Team{Name: name}
+	if err := s.teamRepo.CreateTeam(&t, userID); err != nil {
+		return nil, fmt.Errorf("failed to store team: %w", err)
+	}
+	slog.Info("Team created", "team_id", t.TeamID.Uint(), "name", t.Name, "owner", userID.Uint())
+	return &t, nil
+}
+
+// GetUsersTeams lists the teams a user belongs to
+func (s *Service) GetUsersTeams(userID types.UserID) ([]Team, error) {
+	return s.teamRepo.GetTeamsByUserID(userID)
+}
+
+// GetTeamIDs lists the IDs of the teams a user belongs to, so domain.Service can show their shared domains
+func (s *Service) GetTeamIDs(userID types.UserID) ([]types.TeamID, error) {
+	return s.teamRepo.GetTeamIDs(userID)
+}
+
+// FindTeam looks up one of the user's teams by name, returning ErrNotFound for teams they aren't in
+func (s *Service) FindTeam(userID types.UserID, name string) (*Team, error) {
+	t, err := s.teamRepo.GetTeamByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up team: %w", err)
+	}
+	if t == nil {
+		return nil, ErrNotFound
+	}
+	if _, err := s.membership(userID, t.TeamID); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// GetMembers lists the members of one of the user's teams
+func (s *Service) GetMembers(userID types.UserID, teamID types.TeamID) ([]Member, error) {
+	if _, err := s.membership(userID, teamID); err != nil {
+		return nil, err
+	}
+	return s.teamRepo.GetMembers(teamID)
+}
+
+// AddMember lets an owner add a user to the team
+func (s *Service) AddMember(ownerID types.UserID, teamID types.TeamID, userID types.UserID, role Role) error {
+	if _, err := ParseRole(role.String()); err != nil {
+		return err
+	}
+	if err := s.requireOwner(ownerID, teamID); err != nil {
+		return err
+	}
+	existing, err := s.teamRepo.GetMember(teamID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to look up member: %w", err)
+	}
+	if existing != nil {
+		return ErrAlreadyMember
+	}
+
+	if err := s.teamRepo.AddMember(&Member{TeamID: teamID, UserID: userID, Role: role}); err != nil {
+		return fmt.Errorf("failed to add member: %w", err)
+	}
+	slog.Info("Team member added", "team_id", teamID.Uint(), "user_id", userID.Uint(), "role", role.String())
+	return nil
+}
+
+// SetRole lets an owner change the role of a member, as long as an owner remains
+func (s *Service) SetRole(ownerID types.UserID, teamID types.TeamID, userID types.UserID, role Role) error {
+	if _, err := ParseRole(role.String()); err != nil {
+		return err
+	}
+	if err := s.requireOwner(ownerID, teamID); err != nil {
+		return err
+	}
+	m, err := s.teamRepo.GetMember(teamID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to look up member: %w", err)
+	}
+	if m == nil {
+		return ErrNotMember
+	}
+	if m.Role == RoleOwner && role != RoleOwner {
+		if err := s.keepAnOwner(teamID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.teamRepo.UpdateRole(teamID, userID, role); err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+	return nil
+}
+
+// RemoveMember takes a user out of a team, owners may remove anyone and members themselves
+func (s *Service) RemoveMember(actorID types.UserID, teamID types.TeamID, userID types.UserID) error {
+	if actorID != userID {
+		if err := s.requireOwner(actorID, teamID); err != nil {
+			return err
+		}
+	}
+	m, err := s.teamRepo.GetMember(teamID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to look up member: %w", err)
+	}
+	if m == nil {
+		if actorID == userID {
+			return ErrNotFound
+		}
+		return ErrNotMember
+	}
+	if m.Role == RoleOwner {
+		if err := s.keepAnOwner(teamID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.teamRepo.RemoveMember(teamID, userID); err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+	slog.Info("Team member removed", "team_id", teamID.Uint(), "user_id", userID.Uint())
+	return nil
+}
+
+// DeleteTeam lets an owner remove a team, its domains go back to whoever added them
+func (s *Service) DeleteTeam(ownerID types.UserID, teamID types.TeamID) error {
+	if err := s.requireOwner(ownerID, teamID); err != nil {
+		return err
+	}
+	if err := s.teamRepo.DeleteTeam(teamID); err != nil {
+		return fmt.Errorf("failed to delete team: %w", err)
+	}
+	slog.Info("Team deleted", "team_id", teamID.Uint())
+	return nil
+}
+
+// membership returns the user's membership of a team, ErrNotFound if they aren't in it
+func (s *Service) membership(userID types.UserID, teamID types.TeamID) (*Member, error) {
+	m, err := s.teamRepo.GetMember(teamID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up member: %w", err)
+	}
+	if m == nil {
+		return nil, ErrNotFound
+	}
+	return m, nil
+}
+
+// requireOwner returns ErrNotOwner unless the user owns the team
+func (s *Service) requireOwner(userID types.UserID, teamID types.TeamID) error {
+	m, err := s.membership(userID, teamID)
+	if err != nil {
+		return err
+	}
+	if m.Role != RoleOwner {
+		return ErrNotOwner
+	}
+	return nil
+}
+
+// keepAnOwner returns ErrLastOwner when the team has only one owner left
+func (s *Service) keepAnOwner(teamID types.TeamID) error {
+	owners, err := s.teamRepo.CountOwners(teamID)
+	if err != nil {
+		return fmt.Errorf("failed to count owners: %w", err)
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
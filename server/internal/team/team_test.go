@@ -0,0 +1,134 @@
+package team
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDB creates a fresh SQLite database with the default user and two more.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`INSERT INTO users (id, username, email) VALUES (2, 'alice', 'alice@example.com'), (3, 'bob', 'bob@example.com')`)
+	require.NoError(t, err)
+	return db
+}
+
+// TestParseRole - only owner and member are roles.
+func TestParseRole(t *testing.T) {
+	role, err := ParseRole("owner")
+	require.NoError(t, err)
+	assert.Equal(t, RoleOwner, role)
+
+	_, err = ParseRole("admin")
+	assert.Error(t, err)
+}
+
+// TestService_Membership - owners manage members, members only see the team.
+func TestService_Membership(t *testing.T) {
+	s := NewService(NewRepository(newTestDB(t)))
+	alice, bob, carol := types.UserID(2), types.UserID(3), types.UserID(1)
+
+	sre, err := s.CreateTeam(alice, " SRE ")
+	require.NoError(t, err)
+	assert.Equal(t, "SRE", sre.Name)
+	_, err = s.CreateTeam(bob, "SRE")
+	assert.ErrorIs(t, err, ErrNameTaken)
+
+	_, err = s.GetMembers(bob, sre.TeamID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.FindTeam(bob, "SRE")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, s.AddMember(alice, sre.TeamID, bob, RoleMember))
+	assert.ErrorIs(t, s.AddMember(alice, sre.TeamID, bob, RoleMember), ErrAlreadyMember)
+	assert.ErrorIs(t, s.AddMember(bob, sre.TeamID, carol, RoleMember), ErrNotOwner)
+
+	found, err := s.FindTeam(bob, "SRE")
+	require.NoError(t, err)
+	assert.Equal(t, sre.TeamID, found.TeamID)
+
+	members, err := s.GetMembers(bob, sre.TeamID)
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	assert.Equal(t, "alice@example.com", members[0].Email)
+	assert.Equal(t, RoleOwner, members[0].Role)
+	assert.Equal(t, RoleMember, members[1].Role)
+
+	assert.ErrorIs(t, s.SetRole(alice, sre.TeamID, alice, RoleMember), ErrLastOwner)
+	assert.ErrorIs(t, s.RemoveMember(alice, sre.TeamID, alice), ErrLastOwner)
+	require.NoError(t, s.SetRole(alice, sre.TeamID, bob, RoleOwner))
+	require.NoError(t, s.RemoveMember(alice, sre.TeamID, alice), "Another owner remains")
+
+	teams, err := s.GetUsersTeams(alice)
+	require.NoError(t, err)
+	assert.Empty(t, teams)
+	ids, err := s.GetTeamIDs(bob)
+	require.NoError(t, err)
+	assert.Equal(t, []types.TeamID{sre.TeamID}, ids)
+}
+
+// TestService_SharedDomains - the whole team sees a shared domain, personal ones stay private.
+func TestService_SharedDomains(t *testing.T) {
+	db := newTestDB(t)
+	teams := NewService(NewRepository(db))
+	domainRepo := domain.NewRepository(db)
+	domains := domain.NewService(domainRepo, nil)
+	domains.SetTeams(teams)
+	alice, bob, carol := types.UserID(2), types.UserID(3), types.UserID(1)
+
+	sre, err := teams.CreateTeam(alice, "SRE")
+	require.NoError(t, err)
+	require.NoError(t, teams.AddMember(alice, sre.TeamID, bob, RoleMember))
+
+	prod := domain.Domain{UserID: alice, DomainName: domain.NewDomainName("prod.example.com"), CreatedAt: domain.NewCreatedAt(time.Now()), IsActive: true}
+	require.NoError(t, domainRepo.CreateDomain(&prod))
+	private := domain.Domain{UserID: alice, DomainName: domain.NewDomainName("blog.example.com"), CreatedAt: domain.NewCreatedAt(time.Now()), IsActive: true}
+	require.NoError(t, domainRepo.CreateDomain(&private))
+
+	assert.ErrorIs(t, domains.ShareDomain(bob, prod.DomainID, sre.TeamID), domain.ErrNotFound, "Only alice can share her domain")
+	assert.ErrorIs(t, domains.ShareDomain(carol, prod.DomainID, sre.TeamID), domain.ErrNotFound)
+	require.NoError(t, domains.ShareDomain(alice, prod.DomainID, sre.TeamID))
+
+	names := func(userID types.UserID) []string {
+		list, err := domains.GetUsersDomains(userID)
+		require.NoError(t, err)
+		var names []string
+		for _, d := range list {
+			names = append(names, d.DomainName.String())
+		}
+		return names
+	}
+	assert.Equal(t, []string{"blog.example.com", "prod.example.com"}, names(alice))
+	assert.Equal(t, []string{"prod.example.com"}, names(bob))
+	assert.Empty(t, names(carol))
+
+	shared, err := domains.GetDomain(prod.DomainID)
+	require.NoError(t, err)
+	ok, err := domains.CanAccess(bob, *shared)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = domains.CanAccess(carol, *shared)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	d, err := domains.FindDomainByName(bob, "prod.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, prod.DomainID, d.DomainID)
+
+	require.NoError(t, teams.DeleteTeam(alice, sre.TeamID))
+	assert.Equal(t, []string{"blog.example.com", "prod.example.com"}, names(alice), "Domains go back to whoever added them")
+	assert.Empty(t, names(bob))
+}
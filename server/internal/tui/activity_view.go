@@ -0,0 +1,240 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samokw/ssl_tracker/internal/activity"
+	"github.com/samokw/ssl_tracker/internal/domain"
+)
+
+// activityFeedSize is how many of the latest events the activity feed lists
+const activityFeedSize = 200
+
+// ActivityModel lists what happened to the user's domains lately, newest first, leading to the domain of each
+type ActivityModel struct {
+	table   table.Model
+	events  []activity.Event
+	loading bool
+	err     error
+	// notice says why the selected event's domain couldn't be opened
+	notice string
+	width  int
+	height int
+}
+
+func NewActivityModel() ActivityModel {
+	t := table.New(
+		table.WithColumns(activityColumns(80)),
+		table.WithFocused(true),
+		table.WithHeight(10),
+	)
+
+	s := table.DefaultStyles()
+	s.Header = s.Header.
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(theme.Border).
+		BorderBottom(true).
+		Bold(false)
+	s.Selected = s.Selected.
+		Foreground(theme.SelectedText).
+		Background(theme.SelectedBackground).
+		Bold(false)
+	t.SetStyles(s)
+
+	return ActivityModel{
+		table:   t,
+		loading: true,
+		width:   80,
+		height:  24,
+	}
+}
+
+func activityColumns(width int) []table.Column {
+	if width < 120 {
+		return []table.Column{
+			{Title: "When", Width: 11},
+			{Title: "Event", Width: 12},
+			{Title: "Domain", Width: max(20, width/3)},
+		}
+	}
+	return []table.Column{
+		{Title: "When", Width: 16},
+		{Title: "Event", Width: 12},
+		{Title: "Domain", Width: 35},
+		{Title: "Detail", Width: 45},
+	}
+}
+
+func (m ActivityModel) Update(msg tea.Msg) (ActivityModel, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			return m, func() tea.Msg { return "back_to_main" }
+		case "enter":
+			if e, ok := m.selected(); ok {
+				m.notice = ""
+				return m, func() tea.Msg { return OpenActivityDomainMsg{event: e} }
+			}
+		}
+	}
+
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+func (m ActivityModel) selected() (activity.Event, bool) {
+	if len(m.events) > 0 && m.table.Cursor() < len(m.events) {
+		return m.events[m.table.Cursor()], true
+	}
+	return activity.Event{}, false
+}
+
+func (m *ActivityModel) UpdateSize(width, height int) {
+	m.width = width
+	m.height = height
+
+	m.table.SetRows([]table.Row{})
+	m.table.SetColumns(activityColumns(width))
+	m.SetEvents(m.events)
+	m.table.SetHeight(max(5, height-10))
+}
+
+// SetEvents replaces the listed events
+func (m *ActivityModel) SetEvents(events []activity.Event) {
+	m.events = events
+	m.loading = false
+
+	wide := len(m.table.Columns()) > 3
+	rows := make([]table.Row, len(events))
+	for i, e := range events {
+		if wide {
+			rows[i] = table.Row{
+				formatTime(e.At, "2006-01-02 15:04"),
+				getEventDisplay(e.Kind),
+				e.Domain,
+				e.Detail,
+			}
+		} else {
+			rows[i] = table.Row{
+				formatTime(e.At, "01-02 15:04"),
+				getEventDisplay(e.Kind),
+				e.Domain,
+			}
+		}
+	}
+	m.table.SetRows(rows)
+}
+
+func getEventDisplay(kind string) string {
+	switch kind {
+	case domain.ActivityAdded:
+		return "➕ Added"
+	case domain.ActivityDeleted:
+		return "🗑 Deleted"
+	case domain.ActivityRenewed:
+		return "🔄 Renewed"
+	case domain.ActivityStatus:
+		return "🔀 Status"
+	case activity.KindNotified:
+		return "🔔 Notified"
+	default:
+		return kind
+	}
+}
+
+func (m ActivityModel) View() string {
+	var b strings.Builder
+
+	b.WriteString("\n\n")
+
+	headerStyle := lipgloss.NewStyle().
+		Foreground(theme.Accent).
+		Bold(true).
+		Width(m.width).
+		Align(lipgloss.Center)
+
+	b.WriteString(headerStyle.Render("sslcerttop 📜 Activity"))
+	b.WriteString("\n")
+
+	deleted := 0
+	for _, e := range m.events {
+		if e.Kind == domain.ActivityDeleted {
+			deleted++
+		}
+	}
+	statsStyle := lipgloss.NewStyle().
+		Foreground(theme.Subtle).
+		Width(m.width).
+		Align(lipgloss.Center)
+	b.WriteString(statsStyle.Render(fmt.Sprintf("[%d recent events, %d domains deleted]", len(m.events), deleted)))
+	b.WriteString("\n")
+	if m.notice != "" {
+		noticeStyle := lipgloss.NewStyle().
+			Foreground(theme.Highlight).
+			Width(m.width).
+			Align(lipgloss.Center)
+		b.WriteString(noticeStyle.Render(m.notice))
+		b.WriteString("\n")
+	}
+
+	separatorStyle := lipgloss.NewStyle().
+		Foreground(theme.Muted).
+		Width(m.width).
+		Align(lipgloss.Center)
+
+	if m.width < 84 {
+		b.WriteString(separatorStyle.Render("- - - - - - - - - - - - - - - -"))
+	} else {
+		b.WriteString(separatorStyle.Render(strings.Repeat("═", 80)))
+	}
+	b.WriteString("\n\n")
+
+	messageStyle := lipgloss.NewStyle().
+		Foreground(theme.Subtle).
+		Width(m.width).
+		Align(lipgloss.Center)
+	switch {
+	case m.loading:
+		b.WriteString(messageStyle.Foreground(theme.Highlight).Render("Loading activity..."))
+		b.WriteString("\n")
+	case m.err != nil:
+		b.WriteString(messageStyle.Foreground(theme.Error).Bold(true).Render(fmt.Sprintf("Error: %v", m.err)))
+		b.WriteString("\n")
+	case len(m.events) == 0:
+		b.WriteString(messageStyle.Render("Nothing has happened yet."))
+		b.WriteString("\n")
+	default:
+		tableStyle := lipgloss.NewStyle().
+			Width(m.width).
+			Align(lipgloss.Center)
+		b.WriteString(tableStyle.Render(m.table.View()))
+	}
+
+	b.WriteString("\n\n")
+
+	footerStyle := lipgloss.NewStyle().
+		Foreground(theme.Text).
+		Width(m.width).
+		Align(lipgloss.Center)
+	b.WriteString(footerStyle.Render("[Enter] Open Domain  [Esc] Back  [q] Quit"))
+
+	return b.String()
+}
+
+// ActivityLoadedMsg carries the latest events about the user's domains
+type ActivityLoadedMsg struct {
+	events []activity.Event
+	err    error
+}
+
+// OpenActivityDomainMsg opens the details of the domain an event is about
+type OpenActivityDomainMsg struct {
+	event activity.Event
+}
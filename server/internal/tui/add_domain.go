@@ -1,26 +1,38 @@
 package tui
 
 import (
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
 )
 
+// dnsCheckDelay is how long typing must pause before the DNS lookup runs
+const dnsCheckDelay = 500 * time.Millisecond
+
 type DomainModel struct {
-	textInput textinput.Model
-	err       error
-	adding    bool
-	width     int
-	height    int
+	textInput  textinput.Model
+	err        error
+	adding     bool
+	validation string
+	valid      bool
+	suggestion string
+	dnsSeq     int
+	dnsStatus  string
+	width      int
+	height     int
 }
 
 func NewDomainModel() DomainModel {
 	ti := textinput.New()
-	ti.Placeholder = "Enter domain name (e.g., example.com)"
+	ti.Placeholder = "Enter domain names (e.g., example.com, www.example.com)"
 	ti.Focus()
-	ti.CharLimit = 253
+	ti.CharLimit = 2048
 	ti.Width = 50
 
 	return DomainModel{
@@ -43,27 +55,154 @@ func (m DomainModel) Update(msg tea.Msg) (DomainModel, tea.Cmd) {
 		case tea.KeyEscape:
 			return m, func() tea.Msg { return "back_to_main" }
 		case tea.KeyEnter:
-			if m.textInput.Value() != "" && !m.adding {
+			domains := splitDomainInput(m.textInput.Value())
+			if len(domains) > 0 && m.valid && !m.adding {
 				m.adding = true
 				return m, func() tea.Msg {
-					return AddDomainMsg{domain: m.textInput.Value()}
+					return AddDomainMsg{domains: domains}
 				}
 			}
+			return m, nil
+		case tea.KeyTab:
+			if m.suggestion != "" {
+				m.textInput.SetValue(m.suggestion)
+				m.textInput.CursorEnd()
+				return m, m.validate()
+			}
+			return m, nil
 		}
 	case DomainAddedMsg:
 		if msg.err != nil {
+			// Only the names that failed are left to fix and submit again
 			m.err = msg.err
 			m.adding = false
-		} else {
-			return m, func() tea.Msg { return "back_to_main" }
+			m.textInput.SetValue(strings.Join(msg.failed, ", "))
+			m.textInput.CursorEnd()
+			return m, m.validate()
 		}
+		return m, func() tea.Msg { return "back_to_main" }
+	case dnsCheckTickMsg:
+		if msg.seq != m.dnsSeq {
+			return m, nil // Input changed since this check was scheduled
+		}
+		m.dnsStatus = "⏳ Resolving..."
+		return m, checkDomainsDNS(msg.seq, msg.domains)
+	case dnsCheckResultMsg:
+		if msg.seq == m.dnsSeq {
+			m.dnsStatus = msg.status
+		}
+		return m, nil
 	}
 
-	// Update text input
+	// Update text input and re-validate when the value changed
+	before := m.textInput.Value()
 	m.textInput, cmd = m.textInput.Update(msg)
+	if m.textInput.Value() != before {
+		m.err = nil
+		return m, tea.Batch(cmd, m.validate())
+	}
 	return m, cmd
 }
 
+// validate checks the format of the current input and schedules a DNS lookup
+func (m *DomainModel) validate() tea.Cmd {
+	m.dnsSeq++
+	m.dnsStatus = ""
+	m.suggestion = ""
+	m.valid = false
+
+	domains := splitDomainInput(m.textInput.Value())
+	if len(domains) == 0 {
+		m.validation = ""
+		return nil
+	}
+
+	var suggestions []string
+	var invalid []string
+	for _, d := range domains {
+		if ssl.IsFileTarget(d) {
+			suggestions = append(suggestions, d)
+			if err := ssl.ValidateTarget(d); err != nil {
+				invalid = append(invalid, fmt.Sprintf("%s (%v)", d, err))
+			}
+			continue
+		}
+		if ssl.IsSSHTarget(d) {
+			// The hostname is resolved along with the others below
+			suggestions = append(suggestions, d)
+			if _, _, err := ssl.SSHAddress(d); err != nil {
+				invalid = append(invalid, fmt.Sprintf("%s (%v)", d, err))
+			}
+			continue
+		}
+		s, ok := ssl.SuggestHostname(d)
+		if ok {
+			suggestions = append(suggestions, s)
+		} else {
+			suggestions = append(suggestions, d)
+		}
+		if err := ssl.ValidateHostname(ssl.NormalizeHostname(d)); err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s (%v)", d, err))
+		}
+	}
+	if suggested := strings.Join(suggestions, ", "); suggested != strings.Join(domains, ", ") {
+		m.suggestion = suggested
+	}
+
+	if len(invalid) > 0 {
+		m.validation = "✗ Invalid: " + strings.Join(invalid, ", ")
+		return nil
+	}
+
+	m.valid = true
+	if len(domains) == 1 {
+		m.validation = "✓ Valid hostname"
+	} else {
+		m.validation = fmt.Sprintf("✓ %d valid hostnames", len(domains))
+	}
+
+	seq := m.dnsSeq
+	return tea.Tick(dnsCheckDelay, func(time.Time) tea.Msg {
+		return dnsCheckTickMsg{seq: seq, domains: domains}
+	})
+}
+
+// checkDomainsDNS resolves every domain and reports which ones failed
+func checkDomainsDNS(seq int, domains []string) tea.Cmd {
+	return func() tea.Msg {
+		var unresolved []string
+		for _, d := range domains {
+			if ssl.IsFileTarget(d) {
+				continue
+			}
+			if err := ssl.ValidateTarget(d); err != nil {
+				unresolved = append(unresolved, d)
+			}
+		}
+		if len(unresolved) > 0 {
+			return dnsCheckResultMsg{seq: seq, status: "⚠️ Could not resolve: " + strings.Join(unresolved, ", ")}
+		}
+		return dnsCheckResultMsg{seq: seq, status: "✓ DNS resolves"}
+	}
+}
+
+// splitDomainInput splits comma or whitespace separated input into domain names
+func splitDomainInput(input string) []string {
+	fields := strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == ';'
+	})
+
+	seen := make(map[string]bool)
+	var domains []string
+	for _, f := range fields {
+		if !seen[f] {
+			seen[f] = true
+			domains = append(domains, f)
+		}
+	}
+	return domains
+}
+
 func (m *DomainModel) UpdateSize(width, height int) {
 	m.width = width
 	m.height = height
@@ -85,7 +224,7 @@ func (m DomainModel) View() string {
 	var b strings.Builder
 
 	headerStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#00ff88")).
+		Foreground(theme.Accent).
 		Bold(true).
 		Width(m.width).
 		Align(lipgloss.Center)
@@ -94,7 +233,7 @@ func (m DomainModel) View() string {
 	b.WriteString("\n")
 
 	separatorStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#666666")).
+		Foreground(theme.Muted).
 		Width(m.width).
 		Align(lipgloss.Center)
 
@@ -112,7 +251,10 @@ func (m DomainModel) View() string {
 	}
 	b.WriteString("\n\n")
 
-	formContentHeight := 4
+	formContentHeight := 6
+	if m.suggestion != "" {
+		formContentHeight++
+	}
 	if m.err != nil {
 		formContentHeight += 2
 	}
@@ -126,12 +268,12 @@ func (m DomainModel) View() string {
 	}
 
 	instructionStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#00bfff")).
+		Foreground(theme.Highlight).
 		Bold(true).
 		Width(m.width).
 		Align(lipgloss.Center)
 
-	instruction := "Enter a domain name to monitor its SSL certificate:"
+	instruction := "Enter one or more domain names to monitor (comma or space separated):"
 	if m.width < 60 {
 		instruction = "Enter domain name:"
 	}
@@ -149,10 +291,34 @@ func (m DomainModel) View() string {
 		inputSection = m.textInput.View()
 	}
 	b.WriteString(inputStyle.Render(inputSection))
+	b.WriteString("\n\n")
+
+	feedbackColor := theme.Accent
+	if !m.valid {
+		feedbackColor = theme.Error
+	}
+	feedbackStyle := lipgloss.NewStyle().
+		Foreground(feedbackColor).
+		Width(m.width).
+		Align(lipgloss.Center)
+	feedback := m.validation
+	if m.valid && m.dnsStatus != "" {
+		feedback += "  " + m.dnsStatus
+	}
+	b.WriteString(feedbackStyle.Render(feedback))
+
+	if m.suggestion != "" {
+		suggestionStyle := lipgloss.NewStyle().
+			Foreground(theme.Warning).
+			Width(m.width).
+			Align(lipgloss.Center)
+		b.WriteString("\n")
+		b.WriteString(suggestionStyle.Render("Did you mean " + m.suggestion + "? [Tab] to accept"))
+	}
 
 	if m.err != nil {
 		errorStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#ff4444")).
+			Foreground(theme.Error).
 			Bold(true).
 			Width(m.width).
 			Align(lipgloss.Center)
@@ -169,13 +335,13 @@ func (m DomainModel) View() string {
 	}
 
 	footerStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#ffffff")).
+		Foreground(theme.Text).
 		Width(m.width).
 		Align(lipgloss.Center)
 
-	footerText := "[Enter] Add Domain  [Esc] Back  [Alt+Enter] Toggle Screen  [q] Quit"
+	footerText := "[Enter] Add Domain  [Tab] Accept Suggestion  [Esc] Back  [Alt+Enter] Toggle Screen  [q] Quit"
 	if m.width < 80 {
-		footerText = "[Enter] Add  [Esc] Back  [q] Quit"
+		footerText = "[Enter] Add  [Tab] Fix  [Esc] Back  [q] Quit"
 	}
 	b.WriteString(footerStyle.Render(footerText))
 
@@ -184,9 +350,25 @@ func (m DomainModel) View() string {
 
 // Message types for domain operations
 type AddDomainMsg struct {
-	domain string
+	domains []string
 }
 
 type DomainAddedMsg struct {
-	err error
+	// added are the domains that were added, their first check still running
+	added []types.DomainID
+	// failed are the names that couldn't be added, as entered
+	failed []string
+	err    error
+}
+
+// dnsCheckTickMsg fires once typing pauses long enough to run a DNS lookup
+type dnsCheckTickMsg struct {
+	seq     int
+	domains []string
+}
+
+// dnsCheckResultMsg carries the outcome of an asynchronous DNS lookup
+type dnsCheckResultMsg struct {
+	seq    int
+	status string
 }
@@ -1,22 +1,65 @@
 package tui
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/notification"
+	"github.com/samokw/ssl_tracker/internal/ssl"
 	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/samokw/ssl_tracker/internal/user"
 )
 
 type App struct {
-	domainService *domain.Service
+	domainService       DomainService
+	notificationService NotificationService
+	channelTester       ChannelTester
+	simulator           AlertSimulator
+	users               UserService
+	settingsService     SettingsService
+	pool                PoolService
+	activityService     ActivityService
+	handshakes          HandshakeService
+	checkFeed           CheckFeed
+	sessions            SessionStore
+	// loginRequired is set once anyone registered, until then the default user needn't sign in
+	loginRequired bool
+	user          *user.User
+	token         string
+	userID        types.UserID
 	currentView   View
+	login         LoginModel
+	switcher      SwitcherModel
 	home          HomeModel
 	main          MainModel
 	domain        DomainModel
+	detail        DetailModel
+	notifications NotificationsModel
+	duplicates    DuplicatesModel
+	triage        TriageModel
+	activity      ActivityModel
+	errorDetail   ErrorModel
+	simulation    SimulationModel
+	settings      SettingsModel
 	altScreen     bool
-	width         int
-	height        int
+	// awaitingPoll is set while a reload for domains awaiting their first check is scheduled
+	awaitingPoll bool
+	// checks passes on what checkFeed delivers, feedReload is set while a reload for them is scheduled
+	checks     chan domain.Domain
+	feedReload bool
+	// sweep is the re-check of the domains running, nil when none is
+	sweep  *sweep
+	width  int
+	height int
 }
 
 type View int
@@ -25,21 +68,120 @@ const (
 	Home View = iota
 	Main
 	AddDomain
+	Detail
+	Notifications
+	Login
+	Settings
+	Duplicates
+	Triage
+	Switcher
+	Activity
+	ErrorDetail
+	Simulation
 )
 
-func NewApp(domainService *domain.Service) *App {
+func NewApp(domainService DomainService, notificationService NotificationService) *App {
 	return &App{
-		domainService: domainService,
-		currentView:   Home,
-		home:          NewHomeModel(),
-		main:          NewMainModel(),
-		domain:        NewDomainModel(),
-		altScreen:     true,
+		domainService:       domainService,
+		notificationService: notificationService,
+		currentView:         Home,
+		home:                NewHomeModel(),
+		main:                NewMainModel(),
+		domain:              NewDomainModel(),
+		notifications:       NewNotificationsModel(),
+		userID:              user.DefaultUserID,
+		altScreen:           true,
 	}
 }
 
+// SetUsers enables signing in to accounts on the local database, required once anyone registered
+func (a *App) SetUsers(users UserService, sessions SessionStore, required bool) {
+	a.users = users
+	a.sessions = sessions
+	a.loginRequired = required
+	a.main.accounts = true
+}
+
+// SetSettings enables per-user settings, loaded when the app starts and after signing in
+func (a *App) SetSettings(settings SettingsService) {
+	a.settingsService = settings
+	a.main.settings = true
+}
+
+// SetActivity enables the activity feed of what happened to the user's domains
+func (a *App) SetActivity(activity ActivityService) {
+	a.activityService = activity
+	a.main.activity = true
+}
+
+// SetHandshakes lets the error view log a fresh handshake with a domain next to its last error
+func (a *App) SetHandshakes(handshakes HandshakeService) {
+	a.handshakes = handshakes
+}
+
+// SetCheckFeed keeps the domains up to date with the checks completed outside the TUI, e.g. by a daemon
+func (a *App) SetCheckFeed(feed CheckFeed) {
+	a.checkFeed = feed
+	a.main.live = true
+}
+
+// SetPool lets the settings screen resize the worker pool checking certificates, and the status bar show what it
+// is doing
+func (a *App) SetPool(pool PoolService) {
+	a.pool = pool
+}
+
+// SetUser makes u the signed in user, e.g. from a session saved by an earlier run
+func (a *App) SetUser(u *user.User, token string) {
+	a.user = u
+	a.token = token
+	a.userID = u.UserID
+	a.main.account = u.Email.String()
+}
+
+// needsLogin reports whether someone has to sign in before seeing any domains
+func (a *App) needsLogin() bool {
+	return a.users != nil && a.loginRequired && a.user == nil
+}
+
+// showLogin switches to the sign in form, offering to register when nobody has yet
+func (a *App) showLogin() tea.Cmd {
+	a.currentView = Login
+	a.login = NewLoginModel(!a.loginRequired, !a.needsLogin())
+	a.login.UpdateSize(a.width, a.height)
+	return textinput.Blink
+}
+
+// SetChannelTester enables sending test notifications from the notification center
+func (a *App) SetChannelTester(t ChannelTester) {
+	a.channelTester = t
+}
+
+// SetSimulator enables previewing the notifications the rules would queue at a date
+func (a *App) SetSimulator(s AlertSimulator) {
+	a.simulator = s
+}
+
 func (a *App) Init() tea.Cmd {
-	return nil
+	cmds := []tea.Cmd{a.loadSettings()}
+	if a.checkFeed != nil {
+		cmds = append(cmds, a.followChecks())
+	}
+	if a.pool != nil {
+		cmds = append(cmds, a.loadPoolStats())
+	}
+	return tea.Batch(cmds...)
+}
+
+// resetMain starts the main view over, so the previous user's domains never show or keep being checked
+func (a *App) resetMain() {
+	a.cancelSweep()
+	a.main = NewMainModel()
+	a.main.accounts = true
+	a.main.settings = a.settingsService != nil
+	a.main.activity = a.activityService != nil
+	a.main.live = a.checkFeed != nil
+	a.main.UpdateSize(a.width, a.height)
 }
 
 func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -51,46 +193,167 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.home.UpdateSize(msg.Width, msg.Height)
 		a.main.UpdateSize(msg.Width, msg.Height)
 		a.domain.UpdateSize(msg.Width, msg.Height)
+		a.detail.UpdateSize(msg.Width, msg.Height)
+		a.duplicates.UpdateSize(msg.Width, msg.Height)
+		a.triage.UpdateSize(msg.Width, msg.Height)
+		a.activity.UpdateSize(msg.Width, msg.Height)
+		a.errorDetail.UpdateSize(msg.Width, msg.Height)
+		a.simulation.UpdateSize(msg.Width, msg.Height)
+		a.notifications.UpdateSize(msg.Width, msg.Height)
+		a.login.UpdateSize(msg.Width, msg.Height)
+		a.switcher.UpdateSize(msg.Width, msg.Height)
+		a.settings.UpdateSize(msg.Width, msg.Height)
 		return a, nil
+	case LoginMsg:
+		return a, a.signIn(msg)
+	case LoggedInMsg:
+		if msg.err != nil && a.currentView == Switcher {
+			a.switcher, _ = a.switcher.Update(msg)
+			return a, nil
+		}
+		if msg.err != nil {
+			var cmd tea.Cmd
+			a.login, cmd = a.login.Update(msg)
+			return a, cmd
+		}
+		a.resetMain()
+		a.loginRequired = true
+		a.SetUser(msg.user, msg.token)
+		a.currentView = Main
+		return a, tea.Batch(a.loadSettings(), a.loadDomains())
+	case LoggedOutMsg:
+		a.user = nil
+		a.token = ""
+		a.userID = user.DefaultUserID
+		if a.settingsService != nil {
+			applySettings(a.settingsService.DefaultSettings())
+		}
+		a.resetMain()
+		cmd := a.showLogin()
+		a.login.err = msg.err
+		if msg.err != nil {
+			return a, cmd
+		}
+		// Another user still signed in on this machine can be switched to instead
+		return a, tea.Batch(cmd, a.loadAccounts(false))
+	case AccountsLoadedMsg:
+		if msg.err == nil && !msg.asked && !a.offerSwitch(msg.accounts) {
+			if a.needsLogin() {
+				return a, a.showLogin()
+			}
+			a.currentView = Main
+			return a, a.loadDomains()
+		}
+		a.currentView = Switcher
+		a.switcher = NewSwitcherModel(msg.accounts, a.token, !a.needsLogin())
+		a.switcher.err = msg.err
+		a.switcher.UpdateSize(a.width, a.height)
+		return a, nil
+	case SwitchUserMsg:
+		return a, a.switchUser(msg.account)
+	case SettingsLoadedMsg:
+		if msg.err != nil {
+			a.main.err = msg.err
+			return a, nil
+		}
+		applySettings(*msg.settings)
+		a.main.applyTheme()
+		// The user's columns replace the layout, showing the domains with them
+		a.main.UpdateSize(a.main.width, a.main.height)
+		return a, nil
+	case SaveSettingsMsg:
+		return a, a.saveSettings(msg.settings, msg.pool)
+	case SettingsSavedMsg:
+		if msg.err != nil {
+			var cmd tea.Cmd
+			a.settings, cmd = a.settings.Update(msg)
+			return a, cmd
+		}
+		applySettings(msg.settings)
+		a.main.applyTheme()
+		a.main.UpdateSize(a.main.width, a.main.height)
+		a.currentView = Main
+		return a, a.loadDomains()
 	case DomainsLoadedMsg:
 		if msg.err != nil {
 			a.main.err = msg.err
 			a.main.loading = false
 		} else {
+			a.main.SetAcks(msg.acks)
 			a.main.SetDomains(msg.domains)
 		}
-		return a, nil
+		return a, a.pollFirstChecks()
+	case DomainsRefreshedMsg:
+		if msg.err != nil {
+			// The domain may be gone, or the service unreachable, the full list sorts out which
+			return a, a.loadDomains()
+		}
+		for _, d := range msg.domains {
+			if !a.main.UpdateDomain(d) {
+				return a, a.loadDomains()
+			}
+		}
+		return a, a.pollFirstChecks()
+	case PoolStatsMsg:
+		a.main.pool = nil
+		if msg.err == nil {
+			a.main.pool = &msg.stats
+		}
+		return a, tea.Tick(poolStatsInterval, func(time.Time) tea.Msg { return a.loadPoolStats()() })
+	case CheckStreamedMsg:
+		cmds := []tea.Cmd{a.nextCheck()}
+		if !a.main.UpdateDomain(msg.domain) && !a.feedReload {
+			// A domain added elsewhere takes the full list, reloaded once the burst it came in has passed
+			a.feedReload = true
+			cmds = append(cmds, tea.Tick(feedSettle, func(time.Time) tea.Msg { return FeedSettledMsg{} }))
+		}
+		return a, tea.Batch(cmds...)
+	case FeedSettledMsg:
+		a.feedReload = false
+		return a, a.loadDomains()
+	case FirstCheckTickMsg:
+		a.awaitingPoll = false
+		if len(a.main.awaiting) == 0 {
+			return a, nil
+		}
+		return a, a.refreshDomains(slices.Collect(maps.Keys(a.main.awaiting))...)
 	case SSLCheckStartedMsg:
 		// Start SSL checking progress
-		a.main.sslChecking = true
-		a.main.sslProgress = 0.0
-		return a, nil
+		a.main.startSweep(msg.total)
+		return a, a.sweep.next()
 	case SSLCheckCompletedMsg:
 		// SSL check completed, stop progress and reload domains
+		a.sweep = nil
 		a.main.sslChecking = false
 		a.main.sslProgress = 1.0
-		return a, a.loadDomains()
-	case SSLProgressMsg:
-		// Update progress with real data
-		a.main.sslProgress = msg.progress
-		if msg.progress >= 1.0 {
-			// Complete - transition to completion
-			a.main.sslChecking = false
+		a.main.sweepCancelling = false
+		var notices []string
+		if msg.cancelled {
+			notices = append(notices, fmt.Sprintf("Check cancelled after %d of %d domains", msg.completed, msg.total))
+		}
+		if msg.err != nil {
+			// The reloaded list may show results older than this sweep, say so rather than look up to date
+			notices = append(notices, "⚠ "+msg.err.Error())
+		}
+		a.main.notice = strings.Join(notices, "  ")
+		if msg.stale {
 			return a, a.loadDomains()
 		}
 		return a, nil
-	case ProgressTickMsg:
-		// Update progress incrementally while SSL checking
-		if a.main.sslChecking && a.main.sslProgress < 0.95 {
-			a.main.sslProgress += 0.05
-			return a, a.progressTicker()
+	case SSLProgressMsg:
+		// Update progress with real data
+		a.main.sslProgress = msg.progress
+		a.main.sweepDone, a.main.sweepETA, a.main.sweepLast = msg.completed, msg.eta, msg.domainName
+		if msg.domain != nil {
+			a.main.UpdateDomain(*msg.domain)
 		}
-		return a, nil
+		return a, a.sweep.next()
 	case AddDomainMsg:
 		// Add a new domain
-		return a, a.addDomain(msg.domain)
+		return a, a.addDomains(msg.domains)
 	case DomainAddedMsg:
 		// Domain addition completed, delegate to domain view
+		a.main.awaitFirstChecks(msg.added)
 		if a.currentView == AddDomain {
 			var cmd tea.Cmd
 			a.domain, cmd = a.domain.Update(msg)
@@ -101,37 +364,213 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Delete a domain
 		return a, a.deleteDomain(msg.domainID)
 	case DomainDeletedMsg:
-		// Domain deletion completed, reload domains
+		// Domain deletion completed, drop it from the list
 		if msg.err != nil {
 			a.main.err = msg.err
+			return a, a.loadDomains()
 		}
-		return a, a.loadDomains()
+		a.main.RemoveDomain(msg.domainID)
+		return a, nil
 	case CheckSingleDomainMsg:
 		// Check SSL for a single domain
 		return a, a.checkSingleDomain(msg.domainID)
 	case SingleDomainCheckCompletedMsg:
-		// Single domain SSL check completed, reload domains
+		// Single domain SSL check completed, refresh its row
 		if msg.err != nil {
 			a.main.err = msg.err
 		}
-		return a, a.loadDomains()
+		return a, a.refreshDomains(msg.domainID)
+	case ShowDetailMsg:
+		// Switch to the detail view for the selected domain
+		a.currentView = Detail
+		a.detail = NewDetailModel(msg.domain, msg.ack)
+		a.detail.UpdateSize(a.width, a.height)
+		return a, nil
+	case ShowErrorMsg:
+		// Switch to the domain's whole error and log a handshake with it, when that can be done from here
+		a.currentView = ErrorDetail
+		a.errorDetail = NewErrorModel(msg.domain, a.handshakes != nil)
+		a.errorDetail.UpdateSize(a.width, a.height)
+		if a.handshakes == nil {
+			return a, nil
+		}
+		return a, a.logHandshake(msg.domain.DomainID)
+	case LogHandshakeMsg:
+		return a, a.logHandshake(msg.domainID)
+	case HandshakeLoggedMsg:
+		a.errorDetail, _ = a.errorDetail.Update(msg)
+		return a, nil
+	case ShowDuplicatesMsg:
+		// Switch to the shared certificate report for the listed domains
+		a.currentView = Duplicates
+		a.duplicates = NewDuplicatesModel(msg.domains)
+		a.duplicates.UpdateSize(a.width, a.height)
+		return a, nil
+	case ShowTriageMsg:
+		// Switch to the error triage and read the failing domains' history
+		a.currentView = Triage
+		a.triage = NewTriageModel()
+		a.triage.UpdateSize(a.width, a.height)
+		return a, a.loadErrorTriage(msg.domains)
+	case ErrorTriageLoadedMsg:
+		a.triage, _ = a.triage.Update(msg)
+		return a, nil
+	case ActivityLoadedMsg:
+		if msg.err != nil {
+			a.activity.err = msg.err
+			a.activity.loading = false
+		} else {
+			a.activity.SetEvents(msg.events)
+		}
+		return a, nil
+	case OpenActivityDomainMsg:
+		// Deleted domains and those no longer shared with the user have no details to show
+		for _, d := range a.main.domains {
+			if d.DomainID == msg.event.DomainID {
+				a.currentView = Detail
+				a.detail = NewDetailModel(d, a.main.ackFor(d))
+				a.detail.UpdateSize(a.width, a.height)
+				return a, nil
+			}
+		}
+		a.activity.notice = msg.event.Domain + " is no longer tracked"
+		return a, nil
+	case CopyChainMsg:
+		// Fetch the served chain and copy it as PEM
+		return a, a.copyCertificateChain(msg.domainID)
+	case ExportChainMsg:
+		// Fetch the served chain and write it to a file as PEM
+		return a, a.exportCertificateChain(msg.domainID, msg.path)
+	case ChainExportedMsg:
+		var cmd tea.Cmd
+		a.detail, cmd = a.detail.Update(msg)
+		return a, cmd
+	case ClipboardCopiedMsg:
+		// Clipboard write completed, report it in the active view
+		var cmd tea.Cmd
+		if a.currentView == Detail {
+			a.detail, cmd = a.detail.Update(msg)
+		} else {
+			a.main, cmd = a.main.Update(msg)
+		}
+		return a, cmd
+	case NotificationsLoadedMsg:
+		if msg.err != nil {
+			a.notifications.err = msg.err
+			a.notifications.loading = false
+		} else {
+			a.notifications.err = nil
+			a.notifications.SetNotifications(msg.notifications)
+		}
+		return a, nil
+	case ResendNotificationMsg:
+		// Queue a notification for delivery again
+		return a, a.resendNotification(msg.notificationID)
+	case AcknowledgeNotificationMsg:
+		// Acknowledge a notification
+		return a, a.acknowledgeNotification(msg.notificationID)
+	case NotificationUpdatedMsg:
+		// Notification changed, reload the list
+		if msg.err != nil {
+			a.notifications.err = msg.err
+		}
+		return a, a.loadNotifications()
+	case TestChannelsMsg:
+		a.notifications.testResults = []string{"Sending test notifications..."}
+		return a, a.testChannels()
+	case ChannelsTestedMsg:
+		a.notifications.testResults = msg.results
+		return a, nil
+	case SimulateMsg:
+		return a, a.simulate(msg.at)
+	case SimulatedMsg:
+		a.simulation, _ = a.simulation.Update(msg)
+		return a, nil
 	case string:
 		switch msg {
 		case "refresh_domains":
 			// Trigger SSL check for all domains
-			return a, a.checkAllSSL()
+			return a, a.startSweep(false)
+		case "retry_failed":
+			// Check again only the domains whose last check failed
+			return a, a.startSweep(true)
+		case "cancel_sweep":
+			a.cancelSweep()
+			return a, nil
 		case "show_add_domain":
 			// Switch to add domain view
 			a.currentView = AddDomain
 			a.domain = NewDomainModel()            // Reset the form
 			a.domain.UpdateSize(a.width, a.height) // Apply current window size
 			return a, nil
+		case "show_notifications":
+			// Switch to the notification center
+			a.currentView = Notifications
+			a.notifications = NewNotificationsModel()
+			a.notifications.UpdateSize(a.width, a.height)
+			return a, a.loadNotifications()
+		case "show_simulation":
+			// Switch to previewing the notifications at a date
+			a.currentView = Simulation
+			a.simulation = NewSimulationModel()
+			a.simulation.UpdateSize(a.width, a.height)
+			return a, textinput.Blink
+		case "show_activity":
+			if a.activityService == nil {
+				return a, nil
+			}
+			a.currentView = Activity
+			a.activity = NewActivityModel()
+			a.activity.UpdateSize(a.width, a.height)
+			return a, a.loadActivity()
+		case "show_settings":
+			if a.settingsService == nil {
+				return a, nil
+			}
+			var channels []notification.NotificationType
+			if a.channelTester != nil {
+				channels = a.channelTester.Channels()
+			}
+			var pool *ssl.PoolSize
+			if a.pool != nil {
+				if size, err := a.pool.PoolSize(); err == nil {
+					pool = &size
+				}
+			}
+			a.currentView = Settings
+			a.settings = NewSettingsModel(userSettings, channels, pool)
+			a.settings.UpdateSize(a.width, a.height)
+			return a, textinput.Blink
+		case "show_login":
+			return a, a.showLogin()
+		case "switch_user":
+			return a, a.loadAccounts(true)
+		case "logout":
+			return a, a.signOut()
 		case "back_to_main":
 			// Switch back to main view and reload domains
 			a.currentView = Main
 			return a, a.loadDomains()
 		}
 	case tea.KeyMsg:
+		// The sign in form takes every key but ctrl+c, q included
+		if a.currentView == Login && msg.String() != "ctrl+c" {
+			var cmd tea.Cmd
+			a.login, cmd = a.login.Update(msg)
+			return a, cmd
+		}
+		// So does the settings form, timezones can have a q in them
+		if a.currentView == Settings && msg.String() != "ctrl+c" {
+			var cmd tea.Cmd
+			a.settings, cmd = a.settings.Update(msg)
+			return a, cmd
+		}
+		// And the notification preview, whose date is typed in
+		if a.currentView == Simulation && msg.String() != "ctrl+c" {
+			var cmd tea.Cmd
+			a.simulation, cmd = a.simulation.Update(msg)
+			return a, cmd
+		}
 		switch msg.String() {
 		case "ctrl+c", "q":
 			return a, tea.Quit
@@ -144,8 +583,15 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return a, tea.ExitAltScreen
 			}
 		default:
-			// If we're on home screen, any key moves to main
+			// If we're on home screen, any key moves to main, or to signing in first
 			if a.currentView == Home {
+				// Users signed in to several accounts on this machine pick which to use first
+				if a.users != nil {
+					return a, a.loadAccounts(false)
+				}
+				if a.needsLogin() {
+					return a, a.showLogin()
+				}
 				a.currentView = Main
 				// Load domains when transitioning to main view
 				return a, a.loadDomains()
@@ -159,6 +605,41 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				var cmd tea.Cmd
 				a.domain, cmd = a.domain.Update(msg)
 				return a, cmd
+			} else if a.currentView == Detail {
+				// Delegate to detail view
+				var cmd tea.Cmd
+				a.detail, cmd = a.detail.Update(msg)
+				return a, cmd
+			} else if a.currentView == Notifications {
+				// Delegate to notification center
+				var cmd tea.Cmd
+				a.notifications, cmd = a.notifications.Update(msg)
+				return a, cmd
+			} else if a.currentView == Duplicates {
+				// Delegate to the shared certificate report
+				var cmd tea.Cmd
+				a.duplicates, cmd = a.duplicates.Update(msg)
+				return a, cmd
+			} else if a.currentView == Triage {
+				// Delegate to the error triage
+				var cmd tea.Cmd
+				a.triage, cmd = a.triage.Update(msg)
+				return a, cmd
+			} else if a.currentView == Activity {
+				// Delegate to the activity feed
+				var cmd tea.Cmd
+				a.activity, cmd = a.activity.Update(msg)
+				return a, cmd
+			} else if a.currentView == ErrorDetail {
+				// Delegate to the error view
+				var cmd tea.Cmd
+				a.errorDetail, cmd = a.errorDetail.Update(msg)
+				return a, cmd
+			} else if a.currentView == Switcher {
+				// Delegate to the user switcher
+				var cmd tea.Cmd
+				a.switcher, cmd = a.switcher.Update(msg)
+				return a, cmd
 			}
 		}
 	}
@@ -188,6 +669,26 @@ func (a *App) View() string {
 		return a.renderMainView()
 	case AddDomain:
 		return a.renderAddDomainView()
+	case Detail:
+		return a.detail.View()
+	case Notifications:
+		return a.notifications.View()
+	case Duplicates:
+		return a.duplicates.View()
+	case Triage:
+		return a.triage.View()
+	case Activity:
+		return a.activity.View()
+	case ErrorDetail:
+		return a.errorDetail.View()
+	case Simulation:
+		return a.simulation.View()
+	case Login:
+		return a.login.View()
+	case Switcher:
+		return a.switcher.View()
+	case Settings:
+		return a.settings.View()
 	default:
 		return "Unknown view"
 	}
@@ -208,60 +709,108 @@ func (a *App) renderAddDomainView() string {
 
 // loadDomains loads domains from the service
 func (a *App) loadDomains() tea.Cmd {
+	userID := a.userID
 	return func() tea.Msg {
-		domains, err := a.domainService.GetUsersDomains(types.UserID(1)) // Use default user
+		domains, err := a.domainService.GetUsersDomains(userID)
 		if err != nil {
 			return DomainsLoadedMsg{err: err}
 		}
-		return DomainsLoadedMsg{domains: domains}
+		// Acks only decorate the list, so a server without them still shows its domains
+		acks, _ := a.notificationService.GetUsersAcks(userID)
+		return DomainsLoadedMsg{domains: domains, acks: acks}
 	}
 }
 
-// checkAllSSL performs SSL checks on all domains with progress reporting
-func (a *App) checkAllSSL() tea.Cmd {
-	return tea.Sequence(
-		func() tea.Msg { return SSLCheckStartedMsg{} },
-		a.progressTicker(),
-		a.checkDomainsWithProgress(),
-	)
+// refreshDomains fetches the domains again, to update their rows rather than reload every domain
+func (a *App) refreshDomains(domainIDs ...types.DomainID) tea.Cmd {
+	return func() tea.Msg {
+		domains := make([]domain.Domain, 0, len(domainIDs))
+		for _, id := range domainIDs {
+			d, err := a.domainService.GetDomain(id)
+			if err != nil {
+				return DomainsRefreshedMsg{err: err}
+			}
+			domains = append(domains, *d)
+		}
+		return DomainsRefreshedMsg{domains: domains}
+	}
 }
 
-// progressTicker creates a ticker to simulate progress updates
-func (a *App) progressTicker() tea.Cmd {
-	return tea.Tick(time.Millisecond*200, func(t time.Time) tea.Msg {
-		return ProgressTickMsg{}
-	})
+// pollFirstChecks schedules refreshing the domains awaiting their first check, unless it's scheduled already
+func (a *App) pollFirstChecks() tea.Cmd {
+	if len(a.main.awaiting) == 0 || a.awaitingPoll {
+		return nil
+	}
+	// Added domains are checked in the background, refresh them until their results are in
+	a.awaitingPoll = true
+	return tea.Tick(time.Second, func(time.Time) tea.Msg { return FirstCheckTickMsg{} })
 }
 
-// checkDomainsWithProgress checks domains concurrently using the worker pool
-func (a *App) checkDomainsWithProgress() tea.Cmd {
+// addDomains adds one or more domains to the system
+func (a *App) addDomains(domainNames []string) tea.Cmd {
+	userID := a.userID
 	return func() tea.Msg {
-		// Use the synchronous version that waits for completion
-		err := a.domainService.CheckAllDomainsSSLSync(types.UserID(1))
-		return SSLCheckCompletedMsg{err: err}
+		var errs []error
+		var added []types.DomainID
+		var failed []string
+		for _, domainName := range domainNames {
+			d, err := a.domainService.AddDomain(userID, domainName)
+			switch {
+			case err == nil:
+				added = append(added, d.DomainID)
+				continue
+			case errors.Is(err, domain.ErrDuplicate):
+				errs = append(errs, fmt.Errorf("%s is already being tracked", domainName))
+			default:
+				errs = append(errs, fmt.Errorf("%s: %w", domainName, err))
+			}
+			failed = append(failed, domainName)
+		}
+		return DomainAddedMsg{added: added, failed: failed, err: errors.Join(errs...)}
 	}
 }
 
-// addDomain adds a new domain to the system
-func (a *App) addDomain(domainName string) tea.Cmd {
+// copyCertificateChain fetches the served chain of a domain and copies it as PEM
+func (a *App) copyCertificateChain(domainID types.DomainID) tea.Cmd {
 	return func() tea.Msg {
-		_, err := a.domainService.AddDomain(types.UserID(1), domainName)
+		chain, err := a.domainService.GetCertificateChain(domainID)
 		if err != nil {
-			return DomainAddedMsg{err: err}
+			return ClipboardCopiedMsg{what: "certificate chain", err: err}
 		}
+		return copyToClipboard("certificate chain", ssl.EncodeChainPEM(chain))()
+	}
+}
 
-		// Also perform an initial SSL check
-		domains, err := a.domainService.GetUsersDomains(types.UserID(1))
-		if err == nil {
-			for _, d := range domains {
-				if d.DomainName.String() == domainName {
-					_ = a.domainService.CheckDomainSSL(d.DomainID)
-					break
-				}
+// loadErrorTriage groups the failing domains by error class, reading their recent checks to see how long each
+// has been failing
+func (a *App) loadErrorTriage(domains []domain.Domain) tea.Cmd {
+	return func() tea.Msg {
+		now := time.Now()
+		var failing []domain.DomainError
+		for _, d := range domains {
+			if _, ok := domain.DiagnoseError(d, nil, now); !ok {
+				continue
 			}
+			history, err := a.domainService.GetCheckHistory(d.DomainID, triageHistory)
+			if err != nil {
+				return ErrorTriageLoadedMsg{err: err}
+			}
+			diagnosis, _ := domain.DiagnoseError(d, history, now)
+			failing = append(failing, diagnosis)
 		}
+		return ErrorTriageLoadedMsg{groups: domain.GroupErrors(failing)}
+	}
+}
 
-		return DomainAddedMsg{err: nil}
+// exportCertificateChain fetches the served chain of a domain and writes it to path as PEM
+func (a *App) exportCertificateChain(domainID types.DomainID, path string) tea.Cmd {
+	return func() tea.Msg {
+		chain, err := a.domainService.GetCertificateChain(domainID)
+		if err != nil {
+			return ChainExportedMsg{path: path, err: err}
+		}
+		err = os.WriteFile(path, []byte(ssl.EncodeChainPEM(chain)), 0o644)
+		return ChainExportedMsg{path: path, err: err}
 	}
 }
 
@@ -269,7 +818,7 @@ func (a *App) addDomain(domainName string) tea.Cmd {
 func (a *App) deleteDomain(domainID types.DomainID) tea.Cmd {
 	return func() tea.Msg {
 		err := a.domainService.RemoveDomain(domainID)
-		return DomainDeletedMsg{err: err}
+		return DomainDeletedMsg{domainID: domainID, err: err}
 	}
 }
 
@@ -281,17 +830,224 @@ func (a *App) checkSingleDomain(domainID types.DomainID) tea.Cmd {
 	}
 }
 
+// loadNotifications loads notifications from the service
+func (a *App) loadNotifications() tea.Cmd {
+	userID := a.userID
+	return func() tea.Msg {
+		notifications, err := a.notificationService.GetUsersNotifications(userID)
+		return NotificationsLoadedMsg{notifications: notifications, err: err}
+	}
+}
+
+// loadActivity loads the latest events about the user's domains
+func (a *App) loadActivity() tea.Cmd {
+	userID := a.userID
+	return func() tea.Msg {
+		events, err := a.activityService.Feed(userID, activityFeedSize)
+		return ActivityLoadedMsg{events: events, err: err}
+	}
+}
+
+// logHandshake logs a fresh handshake with a domain
+func (a *App) logHandshake(domainID types.DomainID) tea.Cmd {
+	return func() tea.Msg {
+		log, err := a.handshakes.HandshakeLog(domainID)
+		return HandshakeLoggedMsg{domainID: domainID, log: log, err: err}
+	}
+}
+
+// resendNotification queues a notification for delivery again
+func (a *App) resendNotification(notificationID uint) tea.Cmd {
+	return func() tea.Msg {
+		err := a.notificationService.Resend(notificationID)
+		return NotificationUpdatedMsg{err: err}
+	}
+}
+
+// acknowledgeNotification marks a notification as acknowledged
+func (a *App) acknowledgeNotification(notificationID uint) tea.Cmd {
+	return func() tea.Msg {
+		err := a.notificationService.Acknowledge(notificationID)
+		return NotificationUpdatedMsg{err: err}
+	}
+}
+
+// loadSettings loads the signed in user's settings, nothing before someone signed in
+func (a *App) loadSettings() tea.Cmd {
+	if a.settingsService == nil || a.needsLogin() {
+		return nil
+	}
+	userID := a.userID
+	return func() tea.Msg {
+		settings, err := a.settingsService.GetSettings(userID)
+		return SettingsLoadedMsg{settings: settings, err: err}
+	}
+}
+
+// saveSettings stores the signed in user's settings and resizes the worker pool when asked to
+func (a *App) saveSettings(settings user.Settings, pool *ssl.PoolSize) tea.Cmd {
+	settings.UserID = a.userID
+	return func() tea.Msg {
+		if pool != nil && a.pool != nil {
+			if err := a.pool.ResizePool(*pool); err != nil {
+				return SettingsSavedMsg{settings: settings, err: err}
+			}
+		}
+		err := a.settingsService.SaveSettings(&settings)
+		return SettingsSavedMsg{settings: settings, err: err}
+	}
+}
+
+// signIn registers the account if asked to, signs in and saves the session for the next run
+func (a *App) signIn(msg LoginMsg) tea.Cmd {
+	return func() tea.Msg {
+		if a.users == nil {
+			return LoggedInMsg{err: errors.New("accounts need a local database")}
+		}
+		if msg.register {
+			if _, err := a.users.Register(msg.email, msg.password); err != nil {
+				return LoggedInMsg{err: err}
+			}
+		}
+		u, token, err := a.users.Login(msg.email, msg.password, 0)
+		if err != nil {
+			return LoggedInMsg{err: err}
+		}
+		if err := a.sessions.Save(token); err != nil {
+			return LoggedInMsg{err: err}
+		}
+		return LoggedInMsg{user: u, token: token}
+	}
+}
+
+// signOut ends the active user's session and forgets it, the other users signed in stay so
+func (a *App) signOut() tea.Cmd {
+	token := a.token
+	return func() tea.Msg {
+		err := a.users.Logout(token)
+		if removeErr := a.sessions.Remove(token); err == nil {
+			err = removeErr
+		}
+		return LoggedOutMsg{err: err}
+	}
+}
+
+// loadAccounts resumes the session of every user signed in on this machine, forgetting those that expired
+func (a *App) loadAccounts(asked bool) tea.Cmd {
+	return func() tea.Msg {
+		tokens, err := a.sessions.LoadAll()
+		if err != nil {
+			return AccountsLoadedMsg{asked: asked, err: err}
+		}
+		var accounts []account
+		for _, token := range tokens {
+			u, err := a.users.ResumeSession(token)
+			if errors.Is(err, user.ErrInvalidSession) {
+				if err := a.sessions.Remove(token); err != nil {
+					return AccountsLoadedMsg{accounts: accounts, asked: asked, err: err}
+				}
+				continue
+			}
+			if err != nil {
+				return AccountsLoadedMsg{accounts: accounts, asked: asked, err: err}
+			}
+			accounts = append(accounts, account{user: u, token: token})
+		}
+		return AccountsLoadedMsg{accounts: accounts, asked: asked}
+	}
+}
+
+// offerSwitch reports whether there is a choice of user to make: several are signed in on this machine, or
+// nobody is active while someone is signed in
+func (a *App) offerSwitch(accounts []account) bool {
+	return len(accounts) > 1 || len(accounts) == 1 && a.user == nil
+}
+
+// switchUser makes another user signed in on this machine the active one, for this run and the next
+func (a *App) switchUser(acc account) tea.Cmd {
+	return func() tea.Msg {
+		if err := a.sessions.Save(acc.token); err != nil {
+			return LoggedInMsg{err: err}
+		}
+		return LoggedInMsg{user: acc.user, token: acc.token}
+	}
+}
+
+// testChannels sends a test notification through every configured channel
+func (a *App) testChannels() tea.Cmd {
+	return func() tea.Msg {
+		if a.channelTester == nil {
+			return ChannelsTestedMsg{results: []string{"Test notifications need a local database"}}
+		}
+		channels := a.channelTester.Channels()
+		if len(channels) == 0 {
+			return ChannelsTestedMsg{results: []string{"No notification channels are configured"}}
+		}
+
+		results := make([]string, len(channels))
+		for i, c := range channels {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			err := a.channelTester.SendTest(ctx, c)
+			cancel()
+			if err != nil {
+				results[i] = fmt.Sprintf("❌ %s: %v", c.String(), err)
+			} else {
+				results[i] = fmt.Sprintf("✅ %s: sent", c.String())
+			}
+		}
+		return ChannelsTestedMsg{results: results}
+	}
+}
+
+// simulate previews the notifications the user's domains would queue at at
+func (a *App) simulate(at time.Time) tea.Cmd {
+	userID := a.userID
+	return func() tea.Msg {
+		if a.simulator == nil {
+			return SimulatedMsg{at: at, err: errors.New("previews need a local database")}
+		}
+		domains, err := a.domainService.GetUsersDomains(userID)
+		if err != nil {
+			return SimulatedMsg{at: at, err: err}
+		}
+		alerts, err := a.simulator.Simulate(domains, at)
+		return SimulatedMsg{at: at, alerts: alerts, err: err}
+	}
+}
+
+// LoggedOutMsg reports the session ended
+type LoggedOutMsg struct {
+	err error
+}
+
 // DomainsLoadedMsg represents the result of loading domains
 type DomainsLoadedMsg struct {
+	domains []domain.Domain
+	acks    []notification.DomainAck
+	err     error
+}
+
+// DomainsRefreshedMsg carries domains fetched again after they changed
+type DomainsRefreshedMsg struct {
 	domains []domain.Domain
 	err     error
 }
 
 // Add SSL checking message types
-type SSLCheckStartedMsg struct{}
+type SSLCheckStartedMsg struct {
+	total int
+}
+
+// FirstCheckTickMsg refreshes added domains while they wait for their first check
+type FirstCheckTickMsg struct{}
 
 type SSLCheckCompletedMsg struct {
 	err error
+	// completed of the total domains were checked, fewer when the sweep was cancelled
+	completed, total int
+	cancelled        bool
+	// stale is set when rows couldn't be updated after their checks, so the domains need reloading
+	stale bool
 }
 
 // Progress message types
@@ -300,17 +1056,20 @@ type SSLProgressMsg struct {
 	domainName   string
 	totalDomains int
 	completed    int
+	// eta is how much longer the sweep should take, going by how long its checks took so far
+	eta time.Duration
+	// domain is the checked domain as it is now, nil if it couldn't be fetched
+	domain *domain.Domain
 }
 
-type ProgressTickMsg struct{}
-
 // Domain management message types (defined in add_domain.go)
 type DeleteDomainMsg struct {
 	domainID types.DomainID
 }
 
 type DomainDeletedMsg struct {
-	err error
+	domainID types.DomainID
+	err      error
 }
 
 // Single domain SSL check message types
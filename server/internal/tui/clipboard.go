@@ -0,0 +1,14 @@
+package tui
+
+import (
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// copyToClipboard writes text to the system clipboard in the background
+func copyToClipboard(what, text string) tea.Cmd {
+	return func() tea.Msg {
+		err := clipboard.WriteAll(text)
+		return ClipboardCopiedMsg{what: what, err: err}
+	}
+}
@@ -0,0 +1,146 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/user"
+)
+
+// tableColumn is a column the domain table can show
+type tableColumn struct {
+	title string
+	// width is used unless the user chose another
+	width int
+	cell  func(m MainModel, d domain.Domain) string
+}
+
+// columnNames are the columns users can choose from, in the order they are listed to them
+var columnNames = []string{"domain", "status", "expires", "last_check", "registration", "compliance", "details",
+	"issuer", "tags", "latency", "last_error"}
+
+var tableColumns = map[string]tableColumn{
+	"domain": {"Domain", 25, func(m MainModel, d domain.Domain) string {
+		if m.grouped {
+			return "  " + d.DomainName.String()
+		}
+		return d.DomainName.String()
+	}},
+	"status": {"Status", 12, func(m MainModel, d domain.Domain) string {
+		status := getStatusDisplay(d)
+		if _, ok := m.awaiting[d.DomainID]; ok {
+			status = "⏳ Checking…"
+		}
+		if a := m.ackFor(d); a != nil && a.Active(d.ExpiryTime(), time.Now()) {
+			status += " 🔕"
+		}
+		return status
+	}},
+	"expires":      {"Expires", 12, cellOf(getExpiryDisplay)},
+	"last_check":   {"Last Check", 12, cellOf(getLastCheckDisplay)},
+	"registration": {"Registration", 14, cellOf(getRegistrationDisplay)},
+	"compliance":   {"Compliance", 11, cellOf(getComplianceDisplay)},
+	"details":      {"Details", 17, cellOf(getDetailsDisplay)},
+	"issuer":       {"Issuer", 20, cellOf(func(d domain.Domain) string { return d.Issuer })},
+	"tags":         {"Tags", 18, cellOf(func(d domain.Domain) string { return strings.Join(d.Tags, ", ") })},
+	"latency":      {"Latency", 9, cellOf(getLatencyDisplay)},
+	"last_error": {"Last Error", 30, cellOf(func(d domain.Domain) string {
+		if d.LastError == nil {
+			return ""
+		}
+		return d.LastError.String()
+	})},
+}
+
+// cellOf makes a cell of a display that only needs the domain
+func cellOf(display func(d domain.Domain) string) func(MainModel, domain.Domain) string {
+	return func(_ MainModel, d domain.Domain) string { return display(d) }
+}
+
+// columnLayout is the columns of the domain table, those the user chose or else the default layout fitting width.
+// The domain comes first unless the user placed it, and names the TUI doesn't know, e.g. from a newer version,
+// are left out
+func columnLayout(width int, chosen []user.Column) []user.Column {
+	if len(chosen) == 0 {
+		return defaultLayout(width)
+	}
+	var columns []user.Column
+	placed := false
+	for _, c := range chosen {
+		column, ok := tableColumns[c.Name]
+		if !ok {
+			continue
+		}
+		if c.Width == 0 {
+			c.Width = column.width
+		}
+		placed = placed || c.Name == "domain"
+		columns = append(columns, c)
+	}
+	if !placed {
+		columns = append([]user.Column{{Name: "domain", Width: tableColumns["domain"].width}}, columns...)
+	}
+	return columns
+}
+
+// defaultLayout fits the columns shown to the terminal's width
+func defaultLayout(width int) []user.Column {
+	switch {
+	case width >= twoPaneMinWidth:
+		// The table shares the screen with the details pane
+		return []user.Column{{Name: "domain", Width: 30}, {Name: "status", Width: 14}, {Name: "expires", Width: 12},
+			{Name: "last_check", Width: 12}}
+	case width < 80:
+		return []user.Column{{Name: "domain", Width: max(20, width/3)}, {Name: "status", Width: 8}, {Name: "expires", Width: 8}}
+	case width < 120:
+		return []user.Column{{Name: "domain", Width: 25}, {Name: "status", Width: 12}, {Name: "expires", Width: 15},
+			{Name: "last_check", Width: 12}}
+	default:
+		return []user.Column{{Name: "domain", Width: 26}, {Name: "status", Width: 14}, {Name: "expires", Width: 12},
+			{Name: "last_check", Width: 12}, {Name: "registration", Width: 14}, {Name: "compliance", Width: 11},
+			{Name: "details", Width: 17}}
+	}
+}
+
+// tableColumnsOf are the table's columns for a layout
+func tableColumnsOf(layout []user.Column) []table.Column {
+	columns := make([]table.Column, len(layout))
+	for i, c := range layout {
+		columns[i] = table.Column{Title: tableColumns[c.Name].title, Width: c.Width}
+	}
+	return columns
+}
+
+// tableWidth is how wide the table's columns are together, with the padding the table adds to each cell
+func tableWidth(columns []table.Column) int {
+	width := 0
+	for _, c := range columns {
+		width += c.Width + 2
+	}
+	return width
+}
+
+// unknownColumns lists the columns the table doesn't have, to tell a user choosing them
+func unknownColumns(columns []user.Column) []string {
+	var unknown []string
+	for _, c := range columns {
+		if _, ok := tableColumns[c.Name]; !ok {
+			unknown = append(unknown, c.Name)
+		}
+	}
+	return unknown
+}
+
+// getLatencyDisplay is how long the last check took
+func getLatencyDisplay(d domain.Domain) string {
+	if d.CheckLatency == nil {
+		return "-"
+	}
+	if *d.CheckLatency < time.Second {
+		return fmt.Sprintf("%dms", d.CheckLatency.Milliseconds())
+	}
+	return fmt.Sprintf("%.1fs", d.CheckLatency.Seconds())
+}
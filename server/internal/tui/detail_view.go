@@ -0,0 +1,339 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/notification"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+type DetailModel struct {
+	domain    domain.Domain
+	ack       *notification.DomainAck
+	notice    string
+	copying   bool
+	exporting bool
+	width     int
+	height    int
+}
+
+func NewDetailModel(d domain.Domain, ack *notification.DomainAck) DetailModel {
+	return DetailModel{
+		domain: d,
+		ack:    ack,
+		width:  80,
+		height: 24,
+	}
+}
+
+func (m DetailModel) Update(msg tea.Msg) (DetailModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			return m, func() tea.Msg { return "back_to_main" }
+		case "y":
+			if m.domain.ExpiryDate == nil {
+				m.notice = "No expiry date to copy"
+				return m, nil
+			}
+			return m, copyToClipboard("expiry date", m.domain.ExpiryDate.Time().In(displayLocation).Format("2006-01-02"))
+		case "p":
+			if !m.copying {
+				m.copying = true
+				m.notice = "⏳ Fetching certificate chain..."
+				return m, func() tea.Msg {
+					return CopyChainMsg{domainID: m.domain.DomainID}
+				}
+			}
+		case "e":
+			if !m.exporting {
+				m.exporting = true
+				m.notice = "⏳ Fetching certificate chain..."
+				path := chainFileName(m.domain)
+				return m, func() tea.Msg {
+					return ExportChainMsg{domainID: m.domain.DomainID, path: path}
+				}
+			}
+		}
+	case ClipboardCopiedMsg:
+		m.copying = false
+		m.notice = msg.Notice()
+	case ChainExportedMsg:
+		m.exporting = false
+		m.notice = msg.Notice()
+	}
+	return m, nil
+}
+
+func (m *DetailModel) UpdateSize(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+func (m DetailModel) View() string {
+	var b strings.Builder
+
+	b.WriteString("\n\n")
+
+	headerStyle := lipgloss.NewStyle().
+		Foreground(theme.Accent).
+		Bold(true).
+		Width(m.width).
+		Align(lipgloss.Center)
+
+	b.WriteString(headerStyle.Render("sslcerttop 🔒 " + m.domain.DomainName.String()))
+	b.WriteString("\n")
+
+	separatorStyle := lipgloss.NewStyle().
+		Foreground(theme.Muted).
+		Width(m.width).
+		Align(lipgloss.Center)
+
+	if m.width < 84 {
+		b.WriteString(separatorStyle.Render("- - - - - - - - - - - - - - - -"))
+	} else {
+		b.WriteString(separatorStyle.Render(strings.Repeat("═", 80)))
+	}
+	b.WriteString("\n\n")
+
+	b.WriteString(renderDomainDetails(m.domain, m.ack, m.width))
+	b.WriteString("\n\n")
+
+	if m.notice != "" {
+		noticeStyle := lipgloss.NewStyle().
+			Foreground(theme.Highlight).
+			Width(m.width).
+			Align(lipgloss.Center)
+		b.WriteString(noticeStyle.Render(m.notice))
+		b.WriteString("\n\n")
+	}
+
+	footerStyle := lipgloss.NewStyle().
+		Foreground(theme.Text).
+		Width(m.width).
+		Align(lipgloss.Center)
+
+	footerText := "[y] Copy Expiry  [p] Copy PEM Chain  [e] Export PEM Chain  [Esc] Back  [q] Quit"
+	if m.width < 80 {
+		footerText = "[y] Expiry  [p] PEM  [e] Export  [Esc] Back  [q] Quit"
+	}
+	b.WriteString(footerStyle.Render(footerText))
+
+	return b.String()
+}
+
+// renderDomainDetails renders the labelled fields of a single domain and its acknowledgement, if any
+func renderDomainDetails(d domain.Domain, ack *notification.DomainAck, width int) string {
+	labelStyle := lipgloss.NewStyle().
+		Foreground(theme.Highlight).
+		Bold(true).
+		Width(14)
+
+	valueStyle := lipgloss.NewStyle().
+		Foreground(theme.Text)
+
+	expiry := "Unknown"
+	if d.ExpiryDate != nil {
+		expiry = formatTime(d.ExpiryDate.Time(), "2006-01-02 15:04 MST")
+	}
+	lastError := "None"
+	if d.LastError != nil {
+		lastError = d.LastError.String()
+	}
+	// The error is shown on its own, other reasons say how long is left
+	status := getStatusDisplay(d)
+	if st := statusOf(d); st.Level != domain.StatusError {
+		status += " (" + st.Reason + ")"
+	}
+
+	fields := []struct {
+		label string
+		value string
+	}{
+		{"Domain", d.DomainName.String()},
+		{"Status", status},
+		{"Expires", expiry},
+		{"Days Left", getExpiryDisplay(d)},
+		{"Last Check", getLastCheckDisplay(d)},
+		{"Registration", getRegistrationDisplay(d)},
+		{"Added", formatTime(d.CreatedAt.Time(), "2006-01-02")},
+		{"Last Error", lastError},
+	}
+	if d.Warning != nil {
+		fields = append(fields, struct {
+			label string
+			value string
+		}{"Warning", "⚠️ " + *d.Warning})
+	}
+	if len(d.DNSExpectations) > 0 {
+		fields = append(fields, struct {
+			label string
+			value string
+		}{"DNS", getDNSDisplay(d)})
+	}
+	if len(d.ExpectedSANs) > 0 {
+		fields = append(fields, struct {
+			label string
+			value string
+		}{"Names", getExpectedSANsDisplay(d)})
+	}
+	if c := complianceOf(d); len(compliancePolicies) > 0 && c.Level != domain.ComplianceUnknown {
+		fields = append(fields, struct {
+			label string
+			value string
+		}{"Compliance", getComplianceDetail(c)})
+	}
+	if d.AutoRenewDays > 0 {
+		fields = append(fields, struct {
+			label string
+			value string
+		}{"Auto-Renewal", getAutoRenewDisplay(d)})
+	}
+	if ack != nil {
+		fields = append(fields, struct {
+			label string
+			value string
+		}{"Acknowledged", getAckDisplay(d, *ack)})
+	}
+
+	var lines []string
+	for _, f := range fields {
+		lines = append(lines, lipgloss.JoinHorizontal(lipgloss.Top,
+			labelStyle.Render(f.label),
+			valueStyle.Render(f.value),
+		))
+	}
+
+	blockStyle := lipgloss.NewStyle().
+		Width(width).
+		Align(lipgloss.Center)
+	return blockStyle.Render(lipgloss.NewStyle().Align(lipgloss.Left).Render(strings.Join(lines, "\n")))
+}
+
+// getDNSDisplay describes whether the domain's DNS records matched what is expected of them
+func getDNSDisplay(d domain.Domain) string {
+	switch {
+	case d.DNSError != nil:
+		return "❌ " + strings.ReplaceAll(*d.DNSError, "\n", "; ")
+	case d.DNSChecked == nil:
+		return "Not checked yet"
+	default:
+		return "✅ " + domain.FormatDNSExpectations(d.DNSExpectations)
+	}
+}
+
+// getExpectedSANsDisplay describes whether the certificate covered every name it is expected to
+func getExpectedSANsDisplay(d domain.Domain) string {
+	if d.LastError != nil {
+		if missing, ok := strings.CutPrefix(d.LastError.String(), domain.ErrMissingSANs.Error()+": "); ok {
+			return "❌ Missing " + missing
+		}
+	}
+	if d.LastChecked == nil || d.LastError != nil {
+		return strings.Join(d.ExpectedSANs, ", ")
+	}
+	return "✅ " + strings.Join(d.ExpectedSANs, ", ")
+}
+
+// getComplianceDetail lists the policies the certificate breaks, with when those not in effect yet take effect
+func getComplianceDetail(c domain.ComplianceStatus) string {
+	if len(c.Violations) == 0 {
+		return "✅ Meets every policy"
+	}
+	var broken []string
+	for _, v := range c.Violations {
+		if time.Now().Before(v.From) {
+			days := int(time.Until(v.From).Hours() / 24)
+			broken = append(broken, fmt.Sprintf("⏳ %s from %s, in %d days: %s", v.Policy, v.From.Format("2006-01-02"), days, v.Reason))
+			continue
+		}
+		broken = append(broken, fmt.Sprintf("❌ %s: %s", v.Policy, v.Reason))
+	}
+	return strings.Join(broken, "; ")
+}
+
+// getAutoRenewDisplay describes when the certificate renews automatically and whether that is overdue
+func getAutoRenewDisplay(d domain.Domain) string {
+	renews := fmt.Sprintf("%d days before expiry", d.AutoRenewDays)
+	if d.AutoRenewVia != "" {
+		renews += " via " + d.AutoRenewVia
+	}
+	if d.RenewalOverdue(time.Now()) {
+		return "❌ Overdue, renews " + renews
+	}
+	return renews
+}
+
+// getAckDisplay describes an acknowledgement and how long it silences the domain
+func getAckDisplay(d domain.Domain, a notification.DomainAck) string {
+	note := a.Note
+	if note == "" {
+		note = "No note"
+	}
+	switch {
+	case !a.Active(d.ExpiryTime(), time.Now()):
+		return note + " (ended)"
+	case a.ExpiresAt != nil:
+		return fmt.Sprintf("%s (until %s)", note, formatTime(*a.ExpiresAt, "2006-01-02 15:04"))
+	default:
+		return note + " (until the certificate changes)"
+	}
+}
+
+// ShowDetailMsg opens the detail view for a domain
+type ShowDetailMsg struct {
+	domain domain.Domain
+	ack    *notification.DomainAck
+}
+
+// CopyChainMsg requests the served PEM chain of a domain be copied
+type CopyChainMsg struct {
+	domainID types.DomainID
+}
+
+// ExportChainMsg requests the served PEM chain of a domain be written to path
+type ExportChainMsg struct {
+	domainID types.DomainID
+	path     string
+}
+
+// ChainExportedMsg reports the outcome of writing a chain to a file
+type ChainExportedMsg struct {
+	path string
+	err  error
+}
+
+// Notice returns the status line shown after an export attempt
+func (c ChainExportedMsg) Notice() string {
+	if c.err != nil {
+		return fmt.Sprintf("❌ Could not export certificate chain: %v", c.err)
+	}
+	return "💾 Wrote certificate chain to " + c.path
+}
+
+// chainFileName is the file in the working directory a domain's chain is exported to, named after the domain
+func chainFileName(d domain.Domain) string {
+	name := strings.TrimPrefix(d.DomainName.String(), "file://")
+	name = strings.NewReplacer("://", "_", "/", "_", ":", "_", "*", "_").Replace(name)
+	return strings.Trim(name, "_") + ".pem"
+}
+
+// ClipboardCopiedMsg reports the outcome of a clipboard write
+type ClipboardCopiedMsg struct {
+	what string
+	err  error
+}
+
+// Notice returns the status line shown after a copy attempt
+func (c ClipboardCopiedMsg) Notice() string {
+	if c.err != nil {
+		return fmt.Sprintf("❌ Could not copy %s: %v", c.what, c.err)
+	}
+	return fmt.Sprintf("📋 Copied %s to clipboard", c.what)
+}
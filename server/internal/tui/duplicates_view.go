@@ -0,0 +1,140 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samokw/ssl_tracker/internal/domain"
+)
+
+// DuplicatesModel reports certificates served by several domains and certificates covering other domains,
+// which could be renewed as one
+type DuplicatesModel struct {
+	shared   []domain.CertificateGroup
+	coverage []domain.SANCoverage
+	width    int
+	height   int
+}
+
+func NewDuplicatesModel(domains []domain.Domain) DuplicatesModel {
+	return DuplicatesModel{
+		shared:   domain.SharedCertificates(domains),
+		coverage: domain.CoveredBySANs(domains),
+		width:    80,
+		height:   24,
+	}
+}
+
+func (m DuplicatesModel) Update(msg tea.Msg) (DuplicatesModel, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok && msg.String() == "esc" {
+		return m, func() tea.Msg { return "back_to_main" }
+	}
+	return m, nil
+}
+
+func (m *DuplicatesModel) UpdateSize(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+func (m DuplicatesModel) View() string {
+	var b strings.Builder
+
+	b.WriteString("\n\n")
+
+	headerStyle := lipgloss.NewStyle().
+		Foreground(theme.Accent).
+		Bold(true).
+		Width(m.width).
+		Align(lipgloss.Center)
+
+	b.WriteString(headerStyle.Render("sslcerttop 🔗 Shared Certificates"))
+	b.WriteString("\n")
+
+	statsStyle := lipgloss.NewStyle().
+		Foreground(theme.Subtle).
+		Width(m.width).
+		Align(lipgloss.Center)
+	b.WriteString(statsStyle.Render(fmt.Sprintf("[%d shared, %d covering other domains]", len(m.shared), len(m.coverage))))
+	b.WriteString("\n")
+
+	separatorStyle := lipgloss.NewStyle().
+		Foreground(theme.Muted).
+		Width(m.width).
+		Align(lipgloss.Center)
+
+	if m.width < 84 {
+		b.WriteString(separatorStyle.Render("- - - - - - - - - - - - - - - -"))
+	} else {
+		b.WriteString(separatorStyle.Render(strings.Repeat("═", 80)))
+	}
+	b.WriteString("\n\n")
+
+	if len(m.shared) == 0 && len(m.coverage) == 0 {
+		emptyStyle := lipgloss.NewStyle().
+			Foreground(theme.Subtle).
+			Width(m.width).
+			Align(lipgloss.Center)
+		b.WriteString(emptyStyle.Render("Every domain serves a certificate of its own."))
+		b.WriteString("\n")
+	} else {
+		b.WriteString(m.renderReport())
+	}
+
+	b.WriteString("\n\n")
+
+	footerStyle := lipgloss.NewStyle().
+		Foreground(theme.Text).
+		Width(m.width).
+		Align(lipgloss.Center)
+	b.WriteString(footerStyle.Render("[Esc] Back  [q] Quit"))
+
+	return b.String()
+}
+
+// renderReport lists each shared certificate with the domains serving it, then each certificate with the other
+// domains its SANs cover
+func (m DuplicatesModel) renderReport() string {
+	titleStyle := lipgloss.NewStyle().
+		Foreground(theme.Highlight).
+		Bold(true)
+	nameStyle := lipgloss.NewStyle().
+		Foreground(theme.Text)
+	mutedStyle := lipgloss.NewStyle().
+		Foreground(theme.Subtle)
+
+	var lines []string
+	if len(m.shared) > 0 {
+		lines = append(lines, titleStyle.Render("Served by several domains"))
+		for _, g := range m.shared {
+			lines = append(lines, mutedStyle.Render(fmt.Sprintf("%s…, %s left", g.Fingerprint[:min(16, len(g.Fingerprint))], getExpiryDisplay(g.Domains[0]))))
+			for _, d := range g.Domains {
+				lines = append(lines, nameStyle.Render("  "+d.DomainName.String()))
+			}
+		}
+	}
+	if len(m.coverage) > 0 {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, titleStyle.Render("Also valid for domains with a certificate of their own"))
+		for _, c := range m.coverage {
+			lines = append(lines, nameStyle.Render(c.Domain.DomainName.String())+mutedStyle.Render(" covers"))
+			for _, d := range c.Covered {
+				lines = append(lines, nameStyle.Render("  "+d.DomainName.String())+mutedStyle.Render(", "+getExpiryDisplay(d)+" left"))
+			}
+		}
+	}
+
+	blockStyle := lipgloss.NewStyle().
+		Width(m.width).
+		Align(lipgloss.Center)
+	return blockStyle.Render(lipgloss.NewStyle().Align(lipgloss.Left).Render(strings.Join(lines, "\n")))
+}
+
+// ShowDuplicatesMsg opens the shared certificate report for the listed domains
+type ShowDuplicatesMsg struct {
+	domains []domain.Domain
+}
@@ -0,0 +1,185 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// ErrorModel shows the whole last error of a domain and the log of a fresh handshake with it, wrapped and
+// scrolled together
+type ErrorModel struct {
+	domain   domain.Domain
+	viewport viewport.Model
+	// logged is set when handshake logs can be taken, loading while one is
+	logged  bool
+	loading bool
+	log     []string
+	err     error
+	width   int
+	height  int
+}
+
+func NewErrorModel(d domain.Domain, logged bool) ErrorModel {
+	m := ErrorModel{
+		domain:   d,
+		viewport: viewport.New(76, 14),
+		logged:   logged,
+		loading:  logged,
+		width:    80,
+		height:   24,
+	}
+	m.render()
+	return m
+}
+
+func (m ErrorModel) Update(msg tea.Msg) (ErrorModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			return m, func() tea.Msg { return "back_to_main" }
+		case "r":
+			if m.logged && !m.loading {
+				m.loading = true
+				m.render()
+				id := m.domain.DomainID
+				return m, func() tea.Msg { return LogHandshakeMsg{domainID: id} }
+			}
+			return m, nil
+		}
+	case HandshakeLoggedMsg:
+		if msg.domainID != m.domain.DomainID {
+			return m, nil
+		}
+		m.loading = false
+		m.log, m.err = msg.log, msg.err
+		m.render()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+func (m *ErrorModel) UpdateSize(width, height int) {
+	m.width = width
+	m.height = height
+	m.viewport.Width = max(20, min(100, width-4))
+	m.viewport.Height = max(5, height-10)
+	m.render()
+}
+
+// render fills the viewport with the error and the handshake log, wrapped to its width
+func (m *ErrorModel) render() {
+	sectionStyle := lipgloss.NewStyle().
+		Foreground(theme.Highlight).
+		Bold(true)
+	textStyle := lipgloss.NewStyle().
+		Foreground(theme.Text).
+		Width(m.viewport.Width)
+	mutedStyle := textStyle.Foreground(theme.Subtle)
+
+	lines := []string{sectionStyle.Render("Last Error")}
+	if m.domain.LastError != nil {
+		lines = append(lines, textStyle.Foreground(theme.Error).Render(m.domain.LastError.String()))
+	} else {
+		lines = append(lines, mutedStyle.Render("None, the last check succeeded"))
+	}
+	lines = append(lines, "", sectionStyle.Render("Handshake Log"))
+	switch {
+	case !m.logged:
+		lines = append(lines, mutedStyle.Render("Handshakes can only be logged with a local database"))
+	case m.loading:
+		lines = append(lines, mutedStyle.Foreground(theme.Highlight).Render("⏳ Connecting..."))
+	case m.err != nil:
+		lines = append(lines, textStyle.Foreground(theme.Error).Render(fmt.Sprintf("Error: %v", m.err)))
+	default:
+		for _, line := range m.log {
+			lines = append(lines, textStyle.Render(line))
+		}
+	}
+	m.viewport.SetContent(strings.Join(lines, "\n"))
+}
+
+func (m ErrorModel) View() string {
+	var b strings.Builder
+
+	b.WriteString("\n\n")
+
+	headerStyle := lipgloss.NewStyle().
+		Foreground(theme.Accent).
+		Bold(true).
+		Width(m.width).
+		Align(lipgloss.Center)
+
+	b.WriteString(headerStyle.Render("sslcerttop 🩺 " + m.domain.DomainName.String()))
+	b.WriteString("\n")
+
+	summary := "last check succeeded"
+	if m.domain.LastError != nil {
+		summary = domain.ClassifyError(m.domain.LastError.String()).Label()
+	}
+	if m.domain.LastChecked != nil {
+		summary += " · checked " + formatTime(m.domain.LastChecked.Time(), "2006-01-02 15:04")
+	}
+	statsStyle := lipgloss.NewStyle().
+		Foreground(theme.Subtle).
+		Width(m.width).
+		Align(lipgloss.Center)
+	b.WriteString(statsStyle.Render("[" + summary + "]"))
+	b.WriteString("\n")
+
+	separatorStyle := lipgloss.NewStyle().
+		Foreground(theme.Muted).
+		Width(m.width).
+		Align(lipgloss.Center)
+
+	if m.width < 84 {
+		b.WriteString(separatorStyle.Render("- - - - - - - - - - - - - - - -"))
+	} else {
+		b.WriteString(separatorStyle.Render(strings.Repeat("═", 80)))
+	}
+	b.WriteString("\n\n")
+
+	contentStyle := lipgloss.NewStyle().
+		Width(m.width).
+		Align(lipgloss.Center)
+	b.WriteString(contentStyle.Render(lipgloss.NewStyle().Align(lipgloss.Left).Render(m.viewport.View())))
+	b.WriteString("\n\n")
+
+	footer := "[↑/↓] Scroll  [Esc] Back  [q] Quit"
+	if m.logged {
+		footer = "[↑/↓] Scroll  [r] Log Again  [Esc] Back  [q] Quit"
+	}
+	footerStyle := lipgloss.NewStyle().
+		Foreground(theme.Text).
+		Width(m.width).
+		Align(lipgloss.Center)
+	b.WriteString(footerStyle.Render(footer))
+
+	return b.String()
+}
+
+// ShowErrorMsg opens the last error and handshake log of a domain
+type ShowErrorMsg struct {
+	domain domain.Domain
+}
+
+// LogHandshakeMsg asks the app to log a fresh handshake with a domain
+type LogHandshakeMsg struct {
+	domainID types.DomainID
+}
+
+// HandshakeLoggedMsg carries the log of a handshake with a domain
+type HandshakeLoggedMsg struct {
+	domainID types.DomainID
+	log      []string
+	err      error
+}
@@ -0,0 +1,35 @@
+package tui
+
+import (
+	"context"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/samokw/ssl_tracker/internal/domain"
+)
+
+// feedSettle is how long streamed checks of domains the table doesn't list are gathered before the domains are
+// reloaded, checks come in bursts while a sweep runs
+const feedSettle = 500 * time.Millisecond
+
+// followChecks starts passing on the checks the feed delivers, for as long as the app runs
+func (a *App) followChecks() tea.Cmd {
+	a.checks = make(chan domain.Domain, 64)
+	go a.checkFeed.WatchChecks(context.Background(), func(d domain.Domain) { a.checks <- d })
+	return a.nextCheck()
+}
+
+// nextCheck waits for the feed's next check
+func (a *App) nextCheck() tea.Cmd {
+	return func() tea.Msg {
+		return CheckStreamedMsg{domain: <-a.checks}
+	}
+}
+
+// CheckStreamedMsg carries a domain as of a check completed outside the TUI
+type CheckStreamedMsg struct {
+	domain domain.Domain
+}
+
+// FeedSettledMsg reloads the domains after streamed checks of domains the table didn't list
+type FeedSettledMsg struct{}
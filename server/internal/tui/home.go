@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/samokw/ssl_tracker/internal/buildinfo"
 )
 
 type HomeModel struct {
@@ -58,18 +59,18 @@ func (h HomeModel) RenderSplash() string {
 	}
 
 	titleStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#00ff88")).
+		Foreground(theme.Accent).
 		Bold(true).
 		Width(h.width).
 		Align(lipgloss.Center)
 
 	subtitleStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#cccccc")).
+		Foreground(theme.Subtle).
 		Width(h.width).
 		Align(lipgloss.Center)
 
 	messageStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#ffffff")).
+		Foreground(theme.Text).
 		Bold(true).
 		Width(h.width).
 		Align(lipgloss.Center)
@@ -82,7 +83,7 @@ func (h HomeModel) RenderSplash() string {
 
 	if h.width < 84 {
 		bigTitleStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#00ff88")).
+			Foreground(theme.Accent).
 			Bold(true).
 			Width(h.width).
 			Align(lipgloss.Center).
@@ -93,9 +94,9 @@ func (h HomeModel) RenderSplash() string {
 	}
 	content.WriteString("\n\n")
 
-	subtitle := "🔒 SSL Certificate Monitor v1.0"
+	subtitle := "🔒 SSL Certificate Monitor " + buildinfo.Get().Short()
 	if h.width < 84 {
-		subtitle = "SSL Certificate Monitor"
+		subtitle = "SSL Certificate Monitor " + buildinfo.Get().Version
 	}
 	content.WriteString(subtitleStyle.Render(subtitle))
 	content.WriteString("\n\n")
@@ -112,7 +113,7 @@ func (h HomeModel) RenderSplash() string {
 			instructionText = "Press any key"
 		}
 		instructionStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#00bfff")).
+			Foreground(theme.Highlight).
 			Width(h.width).
 			Align(lipgloss.Center)
 		content.WriteString(instructionStyle.Render(instructionText))
@@ -0,0 +1,210 @@
+package tui
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samokw/ssl_tracker/internal/user"
+)
+
+var (
+	errMissingCredentials = errors.New("enter your email and password")
+	errPasswordMismatch   = errors.New("passwords don't match")
+)
+
+// LoginModel signs in to an account or registers a new one
+type LoginModel struct {
+	inputs []textinput.Model
+	focus  int
+	// register adds a password confirmation and creates the account before signing in
+	register bool
+	// optional lets the user go back without signing in, before anyone has registered
+	optional bool
+	busy     bool
+	err      error
+	width    int
+	height   int
+}
+
+const (
+	loginEmail = iota
+	loginPassword
+	loginConfirm
+)
+
+func NewLoginModel(register, optional bool) LoginModel {
+	email := textinput.New()
+	email.Placeholder = "you@example.com"
+	email.CharLimit = 254
+	email.Width = 40
+	email.Focus()
+
+	password := textinput.New()
+	password.Placeholder = "password"
+	password.EchoMode = textinput.EchoPassword
+	password.CharLimit = 72
+	password.Width = 40
+
+	confirm := password
+	confirm.Placeholder = "repeat password"
+
+	return LoginModel{
+		inputs:   []textinput.Model{email, password, confirm},
+		register: register,
+		optional: optional,
+		width:    80,
+		height:   24,
+	}
+}
+
+// fields is the number of inputs in use, registering also asks to repeat the password
+func (m LoginModel) fields() int {
+	if m.register {
+		return 3
+	}
+	return 2
+}
+
+func (m LoginModel) Update(msg tea.Msg) (LoginModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if m.busy {
+			return m, nil
+		}
+		switch msg.String() {
+		case "esc":
+			if m.optional {
+				return m, func() tea.Msg { return "back_to_main" }
+			}
+			return m, nil
+		case "ctrl+r":
+			m.register = !m.register
+			m.err = nil
+			if m.focus >= m.fields() {
+				m.setFocus(loginPassword)
+			}
+			return m, nil
+		case "tab", "down":
+			m.setFocus((m.focus + 1) % m.fields())
+			return m, nil
+		case "shift+tab", "up":
+			m.setFocus((m.focus + m.fields() - 1) % m.fields())
+			return m, nil
+		case "enter":
+			if m.focus < m.fields()-1 {
+				m.setFocus(m.focus + 1)
+				return m, nil
+			}
+			return m.submit()
+		}
+	case LoggedInMsg:
+		m.busy = false
+		m.err = msg.err
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.inputs[m.focus], cmd = m.inputs[m.focus].Update(msg)
+	return m, cmd
+}
+
+func (m *LoginModel) setFocus(i int) {
+	m.inputs[m.focus].Blur()
+	m.focus = i
+	m.inputs[m.focus].Focus()
+}
+
+// submit checks the form and asks the app to sign in
+func (m LoginModel) submit() (LoginModel, tea.Cmd) {
+	email := strings.TrimSpace(m.inputs[loginEmail].Value())
+	password := m.inputs[loginPassword].Value()
+	if email == "" || password == "" {
+		m.err = errMissingCredentials
+		return m, nil
+	}
+	if m.register && password != m.inputs[loginConfirm].Value() {
+		m.err = errPasswordMismatch
+		m.inputs[loginConfirm].SetValue("")
+		m.setFocus(loginConfirm)
+		return m, nil
+	}
+
+	m.busy = true
+	m.err = nil
+	register := m.register
+	return m, func() tea.Msg {
+		return LoginMsg{email: email, password: password, register: register}
+	}
+}
+
+func (m *LoginModel) UpdateSize(width, height int) {
+	m.width = width
+	m.height = height
+	for i := range m.inputs {
+		m.inputs[i].Width = min(40, max(20, width-20))
+	}
+}
+
+func (m LoginModel) View() string {
+	var b strings.Builder
+
+	center := lipgloss.NewStyle().Width(m.width).Align(lipgloss.Center)
+	headerStyle := center.Foreground(theme.Accent).Bold(true)
+	labelStyle := center.Foreground(theme.Highlight).Bold(true)
+
+	title := "sslcerttop 🔑 Sign In"
+	if m.register {
+		title = "sslcerttop 🔑 Create Account"
+	}
+	topPadding := max(1, (m.height-16)/2)
+	b.WriteString(strings.Repeat("\n", topPadding))
+	b.WriteString(headerStyle.Render(title))
+	b.WriteString("\n")
+	b.WriteString(center.Foreground(theme.Muted).Render(strings.Repeat("═", min(40, max(20, m.width-4)))))
+	b.WriteString("\n\n")
+
+	labels := []string{"Email", "Password", "Repeat password"}
+	for i := 0; i < m.fields(); i++ {
+		b.WriteString(labelStyle.Render(labels[i]))
+		b.WriteString("\n")
+		b.WriteString(center.Render(m.inputs[i].View()))
+		b.WriteString("\n\n")
+	}
+
+	switch {
+	case m.busy:
+		b.WriteString(center.Foreground(theme.Highlight).Render("⏳ Signing in..."))
+	case m.err != nil:
+		b.WriteString(center.Foreground(theme.Error).Bold(true).Render("✗ " + m.err.Error()))
+	}
+	b.WriteString("\n\n")
+
+	footer := "[Enter] Sign in  [Tab] Next field  [Ctrl+R] Create account"
+	if m.register {
+		footer = "[Enter] Create account  [Tab] Next field  [Ctrl+R] Sign in instead"
+	}
+	if m.optional {
+		footer += "  [Esc] Back"
+	}
+	footer += "  [Ctrl+C] Quit"
+	b.WriteString(center.Foreground(theme.Text).Render(footer))
+
+	return b.String()
+}
+
+// LoginMsg asks the app to sign in, registering the account first if asked to
+type LoginMsg struct {
+	email    string
+	password string
+	register bool
+}
+
+// LoggedInMsg reports the outcome of signing in
+type LoggedInMsg struct {
+	user  *user.User
+	token string
+	err   error
+}
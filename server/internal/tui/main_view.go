@@ -2,6 +2,7 @@ package tui
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -10,52 +11,71 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/notification"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/samokw/ssl_tracker/internal/user"
 )
 
 type MainModel struct {
-	table       table.Model
-	domains     []domain.Domain
+	table   table.Model
+	domains []domain.Domain
+	// rows are what each row of the table shows
+	rows        []mainRow
+	acks        map[types.DomainID]notification.DomainAck
 	loading     bool
 	err         error
+	notice      string
 	sslChecking bool
 	progress    progress.Model
 	sslProgress float64
-	width       int
-	height      int
+	// sweepDone of sweepTotal domains have been checked, the last being sweepLast, and sweepETA is about how
+	// long the rest take
+	sweepDone, sweepTotal int
+	sweepLast             string
+	sweepETA              time.Duration
+	// sweepCancelling is set once Esc stopped the sweep starting checks, until those running finish
+	sweepCancelling bool
+	// awaiting are the added domains whose first check hasn't arrived yet, by when to stop waiting for it
+	awaiting map[types.DomainID]time.Time
+	// accounts enables signing in and out, account is the signed in user's email
+	accounts bool
+	account  string
+	// live is set when checks completed elsewhere, e.g. by a daemon, show up as they come in
+	live bool
+	// pool is what the worker pool is doing, nil when the checks run elsewhere
+	pool *ssl.PoolStats
+	// settings enables the settings view
+	settings bool
+	// activity enables the activity feed
+	activity bool
+	// grouped shows the domains in a section per tag, leaving out those of the collapsed tags
+	grouped   bool
+	collapsed map[string]bool
+	// filter narrows the table to the domains expiring soonest
+	filter expiryFilter
+	// columns are what the table shows, in order
+	columns []user.Column
+	width   int
+	height  int
 }
 
 func NewMainModel() MainModel {
-	columns := []table.Column{
-		{Title: "Domain", Width: 25},
-		{Title: "Status", Width: 12},
-		{Title: "Expires", Width: 15},
-		{Title: "Last Check", Width: 12},
-	}
+	columns := columnLayout(80, userSettings.Columns)
 
 	t := table.New(
-		table.WithColumns(columns),
+		table.WithColumns(tableColumnsOf(columns)),
 		table.WithFocused(true),
 		table.WithHeight(10),
 	)
 
-	s := table.DefaultStyles()
-	s.Header = s.Header.
-		BorderStyle(lipgloss.NormalBorder()).
-		BorderForeground(lipgloss.Color("240")).
-		BorderBottom(true).
-		Bold(false)
-	s.Selected = s.Selected.
-		Foreground(lipgloss.Color("229")).
-		Background(lipgloss.Color("57")).
-		Bold(false)
-	t.SetStyles(s)
-
 	prog := progress.New(progress.WithDefaultGradient())
 	prog.ShowPercentage = true
 	prog.Width = 60
 
-	return MainModel{
+	m := MainModel{
 		table:       t,
+		columns:     columns,
 		domains:     []domain.Domain{},
 		loading:     true,
 		sslChecking: false,
@@ -64,6 +84,23 @@ func NewMainModel() MainModel {
 		width:       80,
 		height:      24,
 	}
+	m.applyTheme()
+	return m
+}
+
+// applyTheme styles the table with the active theme, the rest of the view picks it up when rendered
+func (m *MainModel) applyTheme() {
+	s := table.DefaultStyles()
+	s.Header = s.Header.
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(theme.Border).
+		BorderBottom(true).
+		Bold(false)
+	s.Selected = s.Selected.
+		Foreground(theme.SelectedText).
+		Background(theme.SelectedBackground).
+		Bold(false)
+	m.table.SetStyles(s)
 }
 
 func (m MainModel) Update(msg tea.Msg) (MainModel, tea.Cmd) {
@@ -73,24 +110,89 @@ func (m MainModel) Update(msg tea.Msg) (MainModel, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "enter":
-			if len(m.domains) > 0 && m.table.Cursor() < len(m.domains) {
-				selectedDomain := m.domains[m.table.Cursor()]
+			if g, ok := m.selectedGroup(); ok {
+				m.toggleGroup(g.tag)
+				return m, nil
+			}
+			if selectedDomain, ok := m.selected(); ok {
 				return m, func() tea.Msg {
 					return CheckSingleDomainMsg{domainID: selectedDomain.DomainID}
 				}
 			}
+		case "g":
+			m.toggleGrouping()
+			return m, nil
+		case "0", "1", "2", "3":
+			m.setFilter(expiryFilter(msg.String()[0] - '0'))
+			return m, nil
 		case "a":
 			return m, func() tea.Msg { return "show_add_domain" }
 		case "d":
-			if len(m.domains) > 0 && m.table.Cursor() < len(m.domains) {
-				selectedDomain := m.domains[m.table.Cursor()]
+			if selectedDomain, ok := m.selected(); ok {
 				return m, func() tea.Msg {
 					return DeleteDomainMsg{domainID: selectedDomain.DomainID}
 				}
 			}
 		case "r":
+			if m.sslChecking {
+				return m, nil
+			}
 			return m, func() tea.Msg { return "refresh_domains" }
+		case "R":
+			if m.sslChecking {
+				return m, nil
+			}
+			return m, func() tea.Msg { return "retry_failed" }
+		case "esc":
+			if m.sslChecking && !m.sweepCancelling {
+				return m, func() tea.Msg { return "cancel_sweep" }
+			}
+		case "n":
+			return m, func() tea.Msg { return "show_notifications" }
+		case "c":
+			domains := m.domains
+			return m, func() tea.Msg { return ShowDuplicatesMsg{domains: domains} }
+		case "e":
+			domains := m.domains
+			return m, func() tea.Msg { return ShowTriageMsg{domains: domains} }
+		case "A":
+			if m.activity {
+				return m, func() tea.Msg { return "show_activity" }
+			}
+		case "s":
+			if m.settings {
+				return m, func() tea.Msg { return "show_settings" }
+			}
+		case "u":
+			if m.accounts {
+				return m, func() tea.Msg { return "switch_user" }
+			}
+		case "L":
+			if m.account != "" {
+				return m, func() tea.Msg { return "logout" }
+			}
+			if m.accounts {
+				return m, func() tea.Msg { return "show_login" }
+			}
+		case "i":
+			if selectedDomain, ok := m.selected(); ok {
+				ack := m.ackFor(selectedDomain)
+				return m, func() tea.Msg {
+					return ShowDetailMsg{domain: selectedDomain, ack: ack}
+				}
+			}
+		case "x":
+			if selectedDomain, ok := m.selected(); ok {
+				return m, func() tea.Msg { return ShowErrorMsg{domain: selectedDomain} }
+			}
+		case "y":
+			if selectedDomain, ok := m.selected(); ok {
+				return m, copyToClipboard(selectedDomain.DomainName.String(), selectedDomain.DomainName.String())
+			}
 		}
+	case ClipboardCopiedMsg:
+		m.notice = msg.Notice()
+		return m, nil
 	}
 
 	// Update table
@@ -113,7 +215,7 @@ func (m MainModel) View() string {
 	b.WriteString("\n\n")
 
 	headerStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#00ff88")).
+		Foreground(theme.Accent).
 		Bold(true).
 		Width(m.width).
 		Align(lipgloss.Center)
@@ -122,16 +224,42 @@ func (m MainModel) View() string {
 	b.WriteString("\n")
 
 	statsStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#cccccc")).
+		Foreground(theme.Subtle).
 		Width(m.width).
 		Align(lipgloss.Center)
 
 	domainCount := len(m.domains)
-	b.WriteString(statsStyle.Render(fmt.Sprintf("[%d domains tracked]", domainCount)))
+	stats := fmt.Sprintf("%d domains tracked", domainCount)
+	if m.filter != filterAll {
+		stats = fmt.Sprintf("%d of %d domains · %s", len(m.filtered(m.domains)), domainCount, m.filter)
+	}
+	if m.grouped {
+		stats += " · grouped by tag"
+	}
+	if m.live {
+		stats += " · live"
+	}
+	if m.pool != nil {
+		stats += " · " + poolStatus(*m.pool)
+	}
+	if m.account != "" {
+		stats += " · " + m.account
+	}
+	stats = "[" + stats + "]"
+	b.WriteString(statsStyle.Render(stats))
 	b.WriteString("\n")
 
+	if m.notice != "" {
+		noticeStyle := lipgloss.NewStyle().
+			Foreground(theme.Highlight).
+			Width(m.width).
+			Align(lipgloss.Center)
+		b.WriteString(noticeStyle.Render(m.notice))
+		b.WriteString("\n")
+	}
+
 	separatorStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#666666")).
+		Foreground(theme.Muted).
 		Width(m.width).
 		Align(lipgloss.Center)
 
@@ -146,17 +274,25 @@ func (m MainModel) View() string {
 		statusStyle := lipgloss.NewStyle().
 			Width(m.width).
 			Align(lipgloss.Center)
-		b.WriteString(statusStyle.Render("🔍 Checking SSL certificates..."))
+		b.WriteString(statusStyle.Render(m.sweepStatus()))
 		b.WriteString("\n\n")
 
 		progressStyle := lipgloss.NewStyle().
 			Width(m.width).
 			Align(lipgloss.Center)
 		b.WriteString(progressStyle.Render(m.progress.ViewAs(m.sslProgress)))
+		b.WriteString("\n")
+		if m.sweepLast != "" {
+			lastStyle := lipgloss.NewStyle().
+				Foreground(theme.Muted).
+				Width(m.width).
+				Align(lipgloss.Center)
+			b.WriteString(lastStyle.Render("Checked " + m.sweepLast))
+		}
 		b.WriteString("\n\n")
 	} else if m.loading {
 		loadingStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#00bfff")).
+			Foreground(theme.Highlight).
 			Width(m.width).
 			Align(lipgloss.Center)
 		b.WriteString(loadingStyle.Render("Loading domains..."))
@@ -165,7 +301,7 @@ func (m MainModel) View() string {
 		b.WriteString("\n")
 	} else if m.err != nil {
 		errorStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#ff4444")).
+			Foreground(theme.Error).
 			Bold(true).
 			Width(m.width).
 			Align(lipgloss.Center)
@@ -173,14 +309,21 @@ func (m MainModel) View() string {
 		b.WriteString("\n")
 	} else if len(m.domains) == 0 {
 		emptyStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#cccccc")).
+			Foreground(theme.Subtle).
 			Width(m.width).
 			Align(lipgloss.Center)
 		b.WriteString(emptyStyle.Render("No domains found. Press 'a' to add your first domain."))
 		b.WriteString("\n")
+	} else if len(m.rows) == 0 {
+		emptyStyle := lipgloss.NewStyle().
+			Foreground(theme.Subtle).
+			Width(m.width).
+			Align(lipgloss.Center)
+		b.WriteString(emptyStyle.Render("No domains are " + m.filter.String() + ". Press '0' to show them all."))
+		b.WriteString("\n")
 	} else {
 		listHeaderStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#00bfff")).
+			Foreground(theme.Highlight).
 			Bold(true).
 			Width(m.width).
 			Align(lipgloss.Center)
@@ -190,56 +333,70 @@ func (m MainModel) View() string {
 		tableStyle := lipgloss.NewStyle().
 			Width(m.width).
 			Align(lipgloss.Center)
-		b.WriteString(tableStyle.Render(m.table.View()))
+		if m.isTwoPane() {
+			b.WriteString(tableStyle.Render(m.renderTwoPane()))
+		} else {
+			b.WriteString(tableStyle.Render(m.table.View()))
+		}
 	}
 
 	b.WriteString("\n\n")
 
 	footerStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#ffffff")).
+		Foreground(theme.Text).
 		Width(m.width).
 		Align(lipgloss.Center)
 
-	footerText := "[Enter] Check SSL  [a] Add Domain  [d] Delete  [r] Refresh  [Alt+Enter] Toggle Screen  [q] Quit"
+	footerText := "[Enter] Check SSL  [i] Details  [x] Error  [y] Copy  [a] Add Domain  [d] Delete  [r] Re-check All  [R] Retry Failed  [n] Notifications  [c] Shared Certs  [e] Errors  [g] Group by Tag  [1/2/3/0] Expiring  [Alt+Enter] Toggle Screen  [q] Quit"
 	if m.width < 80 {
-		footerText = "[Enter] Check  [a] Add  [d] Del  [r] Refresh  [q] Quit"
+		footerText = "[Enter] Check  [i] Info  [x] Error  [y] Copy  [a] Add  [d] Del  [r] Refresh  [R] Retry  [n] Notifs  [c] Shared  [e] Errors  [g] Group  [1/2/3/0] Filter  [q] Quit"
+	}
+	if m.activity {
+		footerText = strings.Replace(footerText, "  [q] Quit", "  [A] Activity  [q] Quit", 1)
+	}
+	if m.settings {
+		footerText = strings.Replace(footerText, "  [q] Quit", "  [s] Settings  [q] Quit", 1)
+	}
+	switch {
+	case m.account != "":
+		footerText = strings.Replace(footerText, "  [q] Quit", "  [u] Switch User  [L] Log out  [q] Quit", 1)
+	case m.accounts:
+		footerText = strings.Replace(footerText, "  [q] Quit", "  [L] Sign in  [q] Quit", 1)
 	}
 	b.WriteString(footerStyle.Render(footerText))
 
 	return b.String()
 }
 
+// startSweep shows the progress of a sweep of total domains from the start
+func (m *MainModel) startSweep(total int) {
+	m.sslChecking = true
+	m.sslProgress = 0.0
+	m.sweepDone, m.sweepTotal, m.sweepLast, m.sweepETA = 0, total, "", 0
+	m.sweepCancelling = false
+	m.notice = ""
+}
+
+// sweepStatus says how far the sweep got and how long it should take, or that it is being cancelled
+func (m MainModel) sweepStatus() string {
+	if m.sweepCancelling {
+		return fmt.Sprintf("⏹ Cancelling after %d of %d domains, waiting for the checks already running...", m.sweepDone, m.sweepTotal)
+	}
+	eta := "estimating time left"
+	if m.sweepDone > 0 {
+		eta = fmt.Sprintf("about %s left", m.sweepETA.Round(time.Second))
+	}
+	return fmt.Sprintf("🔍 Checking SSL certificates... %d of %d, %s  [Esc] Cancel", m.sweepDone, m.sweepTotal, eta)
+}
+
 // UpdateSize adjusts the model for new terminal dimensions
 func (m *MainModel) UpdateSize(width, height int) {
 	m.width = width
 	m.height = height
 
-	var columns []table.Column
-	if width < 80 {
-		columns = []table.Column{
-			{Title: "Domain", Width: max(20, width/3)},
-			{Title: "Status", Width: 8},
-			{Title: "Expires", Width: 8},
-		}
-	} else if width < 120 {
-		columns = []table.Column{
-			{Title: "Domain", Width: 25},
-			{Title: "Status", Width: 12},
-			{Title: "Expires", Width: 15},
-			{Title: "Last Check", Width: 12},
-		}
-	} else {
-		columns = []table.Column{
-			{Title: "Domain", Width: 35},
-			{Title: "Status", Width: 15},
-			{Title: "Expires", Width: 20},
-			{Title: "Last Check", Width: 18},
-			{Title: "Details", Width: 25},
-		}
-	}
-
+	m.columns = columnLayout(width, userSettings.Columns)
 	m.table.SetRows([]table.Row{})
-	m.table.SetColumns(columns)
+	m.table.SetColumns(tableColumnsOf(m.columns))
 
 	if len(m.domains) > 0 {
 		m.SetDomains(m.domains)
@@ -252,6 +409,41 @@ func (m *MainModel) UpdateSize(width, height int) {
 	m.progress.Width = progressWidth
 }
 
+// twoPaneMinWidth is the terminal width from which details are shown beside the table, as long as the columns
+// leave the pane paneMinWidth
+const (
+	twoPaneMinWidth = 140
+	paneMinWidth    = 40
+)
+
+// isTwoPane reports whether the table and the details pane are shown side by side
+func (m MainModel) isTwoPane() bool {
+	return m.width >= twoPaneMinWidth && m.width-tableWidth(m.table.Columns())-8 >= paneMinWidth
+}
+
+// renderTwoPane renders the domain table with the selected domain's details next to it
+func (m MainModel) renderTwoPane() string {
+	tableView := m.table.View()
+	paneWidth := min(70, m.width-lipgloss.Width(tableView)-8)
+
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(theme.Border).
+		Padding(0, 1).
+		Width(paneWidth).
+		Height(max(5, m.table.Height()))
+
+	var details string
+	if d, ok := m.selected(); ok {
+		// A zero width keeps the fields left aligned inside the pane
+		details = renderDomainDetails(d, m.ackFor(d), 0)
+	} else if g, ok := m.selectedGroup(); ok {
+		details = renderGroupDetails(g)
+	}
+
+	return lipgloss.JoinHorizontal(lipgloss.Top, tableView, "  ", paneStyle.Render(details))
+}
+
 func max(a, b int) int {
 	if a > b {
 		return a
@@ -266,93 +458,362 @@ func min(a, b int) int {
 	return b
 }
 
+// SetAcks replaces the domain acknowledgements, SetDomains shows them
+func (m *MainModel) SetAcks(acks []notification.DomainAck) {
+	m.acks = make(map[types.DomainID]notification.DomainAck, len(acks))
+	for _, a := range acks {
+		m.acks[a.DomainID] = a
+	}
+}
+
+// ackFor returns the acknowledgement of a domain, nil without one
+func (m MainModel) ackFor(d domain.Domain) *notification.DomainAck {
+	a, ok := m.acks[d.DomainID]
+	if !ok {
+		return nil
+	}
+	return &a
+}
+
+// firstCheckWait is how long an added domain shows as being checked before its first check is given up on
+const firstCheckWait = time.Minute
+
+// awaitFirstChecks shows added domains as being checked until the first check of each arrives
+func (m *MainModel) awaitFirstChecks(ids []types.DomainID) {
+	if m.awaiting == nil {
+		m.awaiting = map[types.DomainID]time.Time{}
+	}
+	until := time.Now().Add(firstCheckWait)
+	for _, id := range ids {
+		m.awaiting[id] = until
+	}
+}
+
+// settleFirstChecks stops waiting for the domains that have been checked, were removed or took too long
+func (m *MainModel) settleFirstChecks() {
+	now := time.Now()
+	listed := map[types.DomainID]domain.Domain{}
+	for _, d := range m.domains {
+		listed[d.DomainID] = d
+	}
+	for id, until := range m.awaiting {
+		d, ok := listed[id]
+		if !ok || d.LastChecked != nil || now.After(until) {
+			delete(m.awaiting, id)
+		}
+	}
+}
+
 // Helper function to update table data
 func (m *MainModel) SetDomains(domains []domain.Domain) {
 	m.domains = domains
 	m.loading = false
-
-	// Convert domains to table rows based on current column layout
-	rows := make([]table.Row, len(domains))
-	columns := m.table.Columns()
-
-	for i, d := range domains {
-		status := m.getStatusDisplay(d)
-		expires := m.getExpiryDisplay(d)
-		lastCheck := m.getLastCheckDisplay(d)
-
-		switch len(columns) {
-		case 3: // Narrow layout
-			rows[i] = table.Row{
-				d.DomainName.String(),
-				status,
-				expires,
-			}
-		case 4: // Standard layout
-			rows[i] = table.Row{
-				d.DomainName.String(),
-				status,
-				expires,
-				lastCheck,
-			}
-		case 5: // Wide layout
-			details := m.getDetailsDisplay(d)
-			rows[i] = table.Row{
-				d.DomainName.String(),
-				status,
-				expires,
-				lastCheck,
-				details,
+	m.settleFirstChecks()
+
+	m.rows = nil
+	shown := m.filtered(domains)
+	if m.grouped {
+		for _, g := range groupByTag(shown) {
+			m.rows = append(m.rows, mainRow{group: g})
+			if m.collapsed[g.tag] {
+				continue
 			}
-		default: // Fallback to standard
-			rows[i] = table.Row{
-				d.DomainName.String(),
-				status,
-				expires,
-				lastCheck,
+			for i := range g.domains {
+				m.rows = append(m.rows, mainRow{domain: &g.domains[i]})
 			}
 		}
+	} else {
+		for i := range shown {
+			m.rows = append(m.rows, mainRow{domain: &shown[i]})
+		}
+	}
+
+	rows := make([]table.Row, len(m.rows))
+	for i, r := range m.rows {
+		if r.group == nil {
+			rows[i] = m.domainRow(*r.domain)
+			continue
+		}
+		// The header shows the group's worst status under the status, if the table has one
+		rows[i] = make(table.Row, len(m.columns))
+		rows[i][0] = groupHeader(r.group, m.collapsed[r.group.tag])
+		if j := slices.IndexFunc(m.columns, func(c user.Column) bool { return c.Name == "status" }); j > 0 {
+			rows[i][j] = getLevelDisplay(r.group.worst)
+		}
 	}
 
 	m.table.SetRows(rows)
 }
 
-func (m MainModel) getStatusDisplay(d domain.Domain) string {
-	if d.LastError != nil {
-		return "❌ Error"
+// poolStatus sums up what the worker pool is doing for the status bar
+func poolStatus(s ssl.PoolStats) string {
+	status := fmt.Sprintf("%d/%d workers busy", s.Busy, s.Workers)
+	if s.Queued > 0 {
+		status += fmt.Sprintf(", %d queued", s.Queued)
+	}
+	if s.Completed > 0 {
+		status += fmt.Sprintf(", avg %s", s.AvgLatency.Round(time.Millisecond))
 	}
+	if s.Failed > 0 {
+		status += fmt.Sprintf(", %d failed", s.Failed)
+	}
+	return status
+}
 
-	if d.ExpiryDate == nil {
-		return "❓ Unknown"
+// domainRow is what a domain's row shows
+func (m MainModel) domainRow(d domain.Domain) table.Row {
+	row := make(table.Row, len(m.columns))
+	for j, c := range m.columns {
+		row[j] = tableColumns[c.Name].cell(m, d)
+	}
+	return row
+}
+
+// UpdateDomain shows a domain as it is now, e.g. after a check, in place of how it was. Only its own row is
+// rebuilt, unless the table is grouped by tag or the domain moved in or out of the filter, which take every row.
+//
+// Reports false for a domain the table doesn't list, e.g. one added elsewhere, which takes reloading the domains
+func (m *MainModel) UpdateDomain(d domain.Domain) bool {
+	i := slices.IndexFunc(m.domains, func(listed domain.Domain) bool { return listed.DomainID == d.DomainID })
+	if i < 0 {
+		return false
+	}
+	previous := m.domains[i]
+	m.domains[i] = d
+	if until, ok := m.awaiting[d.DomainID]; ok && (d.LastChecked != nil || time.Now().After(until)) {
+		delete(m.awaiting, d.DomainID)
+	}
+	if m.grouped || m.filter.matches(previous) != m.filter.matches(d) {
+		// Group headers sum up their domains, and the filter decides which rows there are
+		m.SetDomains(m.domains)
+		return true
+	}
+
+	rows := m.table.Rows()
+	for k, r := range m.rows {
+		if r.domain != nil && r.domain.DomainID == d.DomainID {
+			*r.domain = d
+			rows[k] = m.domainRow(d)
+		}
+	}
+	m.table.SetRows(rows)
+	return true
+}
+
+// RemoveDomain stops listing a domain
+func (m *MainModel) RemoveDomain(domainID types.DomainID) {
+	domains := slices.DeleteFunc(slices.Clone(m.domains), func(d domain.Domain) bool { return d.DomainID == domainID })
+	m.SetDomains(domains)
+	if c := m.table.Cursor(); c >= len(m.rows) && len(m.rows) > 0 {
+		m.table.SetCursor(len(m.rows) - 1)
+	}
+}
+
+// selected is the domain of the selected row, none when it is the header of a tag group
+func (m MainModel) selected() (domain.Domain, bool) {
+	if c := m.table.Cursor(); c >= 0 && c < len(m.rows) && m.rows[c].domain != nil {
+		return *m.rows[c].domain, true
+	}
+	return domain.Domain{}, false
+}
+
+// selectedGroup is the tag group whose header is the selected row
+func (m MainModel) selectedGroup() (*tagGroup, bool) {
+	if c := m.table.Cursor(); c >= 0 && c < len(m.rows) && m.rows[c].group != nil {
+		return m.rows[c].group, true
+	}
+	return nil, false
+}
+
+// toggleGrouping switches between a row per domain and a section per tag, keeping the selected domain selected
+func (m *MainModel) toggleGrouping() {
+	selected, ok := m.selected()
+	m.grouped = !m.grouped
+	m.SetDomains(m.domains)
+	if len(m.rows) == 0 {
+		return
+	}
+	cursor := 0
+	for i, r := range m.rows {
+		if ok && r.domain != nil && r.domain.DomainID == selected.DomainID {
+			cursor = i
+			break
+		}
+	}
+	m.table.SetCursor(cursor)
+}
+
+// setFilter narrows the table to the domains the filter matches, selecting the first of them
+func (m *MainModel) setFilter(f expiryFilter) {
+	m.filter = f
+	m.SetDomains(m.domains)
+	if len(m.rows) > 0 {
+		m.table.SetCursor(0)
+	}
+}
+
+// filtered are the domains the filter shows
+func (m MainModel) filtered(domains []domain.Domain) []domain.Domain {
+	if m.filter == filterAll {
+		return domains
+	}
+	var shown []domain.Domain
+	for _, d := range domains {
+		if m.filter.matches(d) {
+			shown = append(shown, d)
+		}
+	}
+	return shown
+}
+
+// toggleGroup collapses the tag's group, or expands it when it is collapsed
+func (m *MainModel) toggleGroup(tag string) {
+	if m.collapsed == nil {
+		m.collapsed = map[string]bool{}
+	}
+	m.collapsed[tag] = !m.collapsed[tag]
+	m.SetDomains(m.domains)
+}
+
+// expiryFilter narrows the table to the domains whose certificates expire soonest, by their status so the
+// thresholds are the signed in user's
+type expiryFilter int
+
+const (
+	filterAll expiryFilter = iota
+	// filterExpired shows the expired certificates
+	filterExpired
+	// filterCritical shows those expiring within the critical threshold, and the expired
+	filterCritical
+	// filterSoon shows those expiring within the warning threshold, and the critical and expired
+	filterSoon
+)
+
+// matches reports whether the filter shows a domain
+func (f expiryFilter) matches(d domain.Domain) bool {
+	switch level := statusOf(d).Level; f {
+	case filterExpired:
+		return level == domain.StatusExpired
+	case filterCritical:
+		return level == domain.StatusExpired || level == domain.StatusCritical
+	case filterSoon:
+		return level == domain.StatusExpired || level == domain.StatusCritical || level == domain.StatusExpiringSoon
+	default:
+		return true
+	}
+}
+
+func (f expiryFilter) String() string {
+	switch f {
+	case filterExpired:
+		return "expired"
+	case filterCritical:
+		return fmt.Sprintf("expiring within %d days", userSettings.CriticalDays)
+	case filterSoon:
+		return fmt.Sprintf("expiring within %d days", userSettings.WarningDays)
+	default:
+		return "all"
 	}
+}
 
-	daysLeft := time.Until(d.ExpiryDate.Time()).Hours() / 24
+// statusOf is the status of a domain with the signed in user's thresholds
+func statusOf(d domain.Domain) domain.DomainStatus {
+	return d.StatusWith(domain.StatusThresholds{Warning: userSettings.WarningDays, Critical: userSettings.CriticalDays}, time.Now())
+}
 
-	if daysLeft < 0 {
+func getStatusDisplay(d domain.Domain) string {
+	return getLevelDisplay(statusOf(d).Level)
+}
+
+// getLevelDisplay shows a status level the way the table does
+func getLevelDisplay(level domain.StatusLevel) string {
+	switch level {
+	case domain.StatusError:
+		return "❌ Error"
+	case domain.StatusUnknown:
+		return "❓ Unknown"
+	case domain.StatusExpired:
 		return "❌ Expired"
-	} else if daysLeft < 7 {
+	case domain.StatusCritical:
 		return "⚠️ Warning"
-	} else if daysLeft < 30 {
+	case domain.StatusExpiringSoon:
 		return "🟡 Soon"
-	} else {
+	default:
 		return "✅ Valid"
 	}
 }
 
-func (m MainModel) getExpiryDisplay(d domain.Domain) string {
-	if d.ExpiryDate == nil {
+func getExpiryDisplay(d domain.Domain) string {
+	daysLeft := statusOf(d).DaysLeft
+	if daysLeft == nil {
 		return "Unknown"
 	}
+	return fmt.Sprintf("%d days", *daysLeft)
+}
 
-	daysLeft := time.Until(d.ExpiryDate.Time()).Hours() / 24
+// registrationWarning and registrationCritical are the days before a registration expires shown as a warning
+// and as critical
+var registrationWarning, registrationCritical = 60, 14
 
-	if daysLeft < 0 {
-		return fmt.Sprintf("-%d days", int(-daysLeft))
-	} else {
-		return fmt.Sprintf("%d days", int(daysLeft))
+// SetRegistrationThresholds sets the days before a registration expires shown as a warning and as critical
+func SetRegistrationThresholds(warning, critical int) {
+	registrationWarning, registrationCritical = warning, critical
+}
+
+// getRegistrationDisplay shows how long the domain's registration has left, marked when it is close
+func getRegistrationDisplay(d domain.Domain) string {
+	if d.RegistrationExpiry == nil {
+		return "Unknown"
+	}
+	daysLeft := int(time.Until(*d.RegistrationExpiry).Hours() / 24)
+	switch d.RegistrationStatus(registrationWarning, registrationCritical, time.Now()) {
+	case domain.RegistrationExpired:
+		return "❌ Expired"
+	case domain.RegistrationCritical:
+		return fmt.Sprintf("⚠️ %d days", daysLeft)
+	case domain.RegistrationWarning:
+		return fmt.Sprintf("🟡 %d days", daysLeft)
+	default:
+		return fmt.Sprintf("%d days", daysLeft)
+	}
+}
+
+// compliancePolicies are the org-wide policies certificates are checked against
+var compliancePolicies []domain.Policy
+
+// SetCompliancePolicies sets the org-wide policies certificates are checked against
+func SetCompliancePolicies(policies []domain.Policy) {
+	compliancePolicies = policies
+}
+
+// complianceOf is how the domain's certificate measures up to the compliance policies
+func complianceOf(d domain.Domain) domain.ComplianceStatus {
+	return d.ComplianceWith(compliancePolicies, time.Now())
+}
+
+// getComplianceDisplay shows how many policies the certificate breaks, or the days until it breaks one that
+// isn't in effect yet
+func getComplianceDisplay(d domain.Domain) string {
+	c := complianceOf(d)
+	switch c.Level {
+	case domain.NonCompliant:
+		broken := 0
+		for _, v := range c.Violations {
+			if !time.Now().Before(v.From) {
+				broken++
+			}
+		}
+		return fmt.Sprintf("❌ %d broken", broken)
+	case domain.ComplianceDue:
+		return fmt.Sprintf("⏳ %d days", *c.DaysLeft)
+	case domain.Compliant:
+		return "✅ OK"
+	default:
+		return "Unknown"
 	}
 }
 
-func (m MainModel) getLastCheckDisplay(d domain.Domain) string {
+func getLastCheckDisplay(d domain.Domain) string {
 	if d.LastChecked == nil {
 		return "Never"
 	}
@@ -368,24 +829,46 @@ func (m MainModel) getLastCheckDisplay(d domain.Domain) string {
 	}
 }
 
-func (m MainModel) getDetailsDisplay(d domain.Domain) string {
+func getDetailsDisplay(d domain.Domain) string {
 	if d.LastError != nil {
-		return "Check failed"
+		return errorReason(d.LastError.String())
 	}
 
-	if d.ExpiryDate == nil {
-		return "No cert data"
+	if d.DNSError != nil {
+		return "DNS mismatch"
+	}
+
+	if d.Warning != nil {
+		return "Certificate warning"
 	}
 
-	daysLeft := time.Until(d.ExpiryDate.Time()).Hours() / 24
+	if d.RenewalOverdue(time.Now()) {
+		return "Auto-renewal overdue"
+	}
 
-	if daysLeft < 0 {
+	switch statusOf(d).Level {
+	case domain.StatusUnknown:
+		return "No cert data"
+	case domain.StatusExpired:
 		return "Certificate expired"
-	} else if daysLeft < 7 {
+	case domain.StatusCritical:
 		return "Expires very soon!"
-	} else if daysLeft < 30 {
+	case domain.StatusExpiringSoon:
 		return "Renewal recommended"
-	} else {
+	default:
 		return "Certificate healthy"
 	}
 }
+
+// errorReason shortens a check error to its cause, the part after the context each layer wrapped it in and
+// before any explanation in parentheses, e.g. "no such host" of
+// "failed to connect to example.com: dial tcp: lookup example.com: no such host"
+func errorReason(message string) string {
+	if i := strings.Index(message, " ("); i > 0 {
+		message = message[:i]
+	}
+	if i := strings.LastIndex(message, ": "); i >= 0 && i+2 < len(message) {
+		return message[i+2:]
+	}
+	return message
+}
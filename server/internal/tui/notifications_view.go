@@ -0,0 +1,314 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samokw/ssl_tracker/internal/notification"
+)
+
+type NotificationsModel struct {
+	table         table.Model
+	notifications []notification.Notification
+	loading       bool
+	err           error
+	// testResults is the outcome of the last test send, one line per channel
+	testResults []string
+	width       int
+	height      int
+}
+
+func NewNotificationsModel() NotificationsModel {
+	t := table.New(
+		table.WithColumns(notificationColumns(80)),
+		table.WithFocused(true),
+		table.WithHeight(10),
+	)
+
+	s := table.DefaultStyles()
+	s.Header = s.Header.
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(theme.Border).
+		BorderBottom(true).
+		Bold(false)
+	s.Selected = s.Selected.
+		Foreground(theme.SelectedText).
+		Background(theme.SelectedBackground).
+		Bold(false)
+	t.SetStyles(s)
+
+	return NotificationsModel{
+		table:   t,
+		loading: true,
+		width:   80,
+		height:  24,
+	}
+}
+
+func notificationColumns(width int) []table.Column {
+	if width < 120 {
+		return []table.Column{
+			{Title: "Domain", Width: max(20, width/4)},
+			{Title: "Channel", Width: 9},
+			{Title: "Days", Width: 5},
+			{Title: "Status", Width: 16},
+		}
+	}
+	return []table.Column{
+		{Title: "Domain", Width: 35},
+		{Title: "Channel", Width: 10},
+		{Title: "Threshold", Width: 10},
+		{Title: "Sent At", Width: 18},
+		{Title: "Status", Width: 16},
+		{Title: "Error", Width: 25},
+	}
+}
+
+func (m NotificationsModel) Update(msg tea.Msg) (NotificationsModel, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			return m, func() tea.Msg { return "back_to_main" }
+		case "r":
+			if n, ok := m.selected(); ok {
+				return m, func() tea.Msg {
+					return ResendNotificationMsg{notificationID: n.NotificationID}
+				}
+			}
+		case "a":
+			if n, ok := m.selected(); ok {
+				return m, func() tea.Msg {
+					return AcknowledgeNotificationMsg{notificationID: n.NotificationID}
+				}
+			}
+		case "t":
+			return m, func() tea.Msg { return TestChannelsMsg{} }
+		case "p":
+			return m, func() tea.Msg { return "show_simulation" }
+		}
+	}
+
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+func (m NotificationsModel) selected() (notification.Notification, bool) {
+	if len(m.notifications) > 0 && m.table.Cursor() < len(m.notifications) {
+		return m.notifications[m.table.Cursor()], true
+	}
+	return notification.Notification{}, false
+}
+
+func (m *NotificationsModel) UpdateSize(width, height int) {
+	m.width = width
+	m.height = height
+
+	m.table.SetRows([]table.Row{})
+	m.table.SetColumns(notificationColumns(width))
+	m.SetNotifications(m.notifications)
+	m.table.SetHeight(max(5, height-10))
+}
+
+// SetNotifications replaces the listed notifications
+func (m *NotificationsModel) SetNotifications(notifications []notification.Notification) {
+	m.notifications = notifications
+	m.loading = false
+
+	wide := len(m.table.Columns()) > 4
+	rows := make([]table.Row, len(notifications))
+	for i, n := range notifications {
+		status := getNotificationStatusDisplay(n)
+		if wide {
+			sentAt := "-"
+			if n.SentAt != nil {
+				sentAt = formatTime(*n.SentAt, "2006-01-02 15:04")
+			} else if n.Status == notification.StatusRetrying && n.NextAttemptAt != nil {
+				sentAt = "retry " + formatTime(*n.NextAttemptAt, "01-02 15:04")
+			}
+			lastError := ""
+			if n.LastError != nil {
+				lastError = *n.LastError
+				if n.LastStatusCode != nil {
+					lastError = fmt.Sprintf("[%d] %s", *n.LastStatusCode, lastError)
+				}
+			}
+			rows[i] = table.Row{
+				n.DomainName,
+				n.NotificationType.String(),
+				fmt.Sprintf("%d days", n.DaysBefore),
+				sentAt,
+				status,
+				lastError,
+			}
+		} else {
+			rows[i] = table.Row{
+				n.DomainName,
+				n.NotificationType.String(),
+				fmt.Sprintf("%d", n.DaysBefore),
+				status,
+			}
+		}
+	}
+	m.table.SetRows(rows)
+}
+
+func getNotificationStatusDisplay(n notification.Notification) string {
+	switch n.Status {
+	case notification.StatusPending:
+		return "⏳ Pending"
+	case notification.StatusSent:
+		return "✅ Sent"
+	case notification.StatusRetrying:
+		return fmt.Sprintf("🔁 Retrying (%d)", n.Attempts)
+	case notification.StatusFailed:
+		// Failed for good, either rejected outright or out of retries
+		if n.Attempts > 1 {
+			return fmt.Sprintf("❌ Failed ×%d", n.Attempts)
+		}
+		return "❌ Failed"
+	case notification.StatusAcknowledged:
+		return "👍 Acknowledged"
+	default:
+		return "❓ " + n.Status.String()
+	}
+}
+
+func (m NotificationsModel) View() string {
+	var b strings.Builder
+
+	b.WriteString("\n\n")
+
+	headerStyle := lipgloss.NewStyle().
+		Foreground(theme.Accent).
+		Bold(true).
+		Width(m.width).
+		Align(lipgloss.Center)
+
+	b.WriteString(headerStyle.Render("sslcerttop 🔔 Notification Center"))
+	b.WriteString("\n")
+
+	pending, failed := 0, 0
+	for _, n := range m.notifications {
+		switch n.Status {
+		case notification.StatusPending, notification.StatusRetrying:
+			pending++
+		case notification.StatusFailed:
+			failed++
+		}
+	}
+	statsStyle := lipgloss.NewStyle().
+		Foreground(theme.Subtle).
+		Width(m.width).
+		Align(lipgloss.Center)
+	b.WriteString(statsStyle.Render(fmt.Sprintf("[%d notifications, %d need attention]", len(m.notifications), pending+failed)))
+	b.WriteString("\n")
+	if failed > 0 {
+		failedStyle := lipgloss.NewStyle().
+			Foreground(theme.Error).
+			Bold(true).
+			Width(m.width).
+			Align(lipgloss.Center)
+		b.WriteString(failedStyle.Render(fmt.Sprintf("%d failed permanently, [r] re-sends the selected one", failed)))
+		b.WriteString("\n")
+	}
+
+	separatorStyle := lipgloss.NewStyle().
+		Foreground(theme.Muted).
+		Width(m.width).
+		Align(lipgloss.Center)
+
+	if m.width < 84 {
+		b.WriteString(separatorStyle.Render("- - - - - - - - - - - - - - - -"))
+	} else {
+		b.WriteString(separatorStyle.Render(strings.Repeat("═", 80)))
+	}
+	b.WriteString("\n\n")
+
+	if m.loading {
+		loadingStyle := lipgloss.NewStyle().
+			Foreground(theme.Highlight).
+			Width(m.width).
+			Align(lipgloss.Center)
+		b.WriteString(loadingStyle.Render("Loading notifications..."))
+		b.WriteString("\n")
+	} else if m.err != nil {
+		errorStyle := lipgloss.NewStyle().
+			Foreground(theme.Error).
+			Bold(true).
+			Width(m.width).
+			Align(lipgloss.Center)
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", m.err)))
+		b.WriteString("\n")
+	} else if len(m.notifications) == 0 {
+		emptyStyle := lipgloss.NewStyle().
+			Foreground(theme.Subtle).
+			Width(m.width).
+			Align(lipgloss.Center)
+		b.WriteString(emptyStyle.Render("No notifications yet."))
+		b.WriteString("\n")
+	} else {
+		tableStyle := lipgloss.NewStyle().
+			Width(m.width).
+			Align(lipgloss.Center)
+		b.WriteString(tableStyle.Render(m.table.View()))
+	}
+
+	if len(m.testResults) > 0 {
+		resultStyle := lipgloss.NewStyle().
+			Foreground(theme.Subtle).
+			Width(m.width).
+			Align(lipgloss.Center)
+		b.WriteString("\n")
+		b.WriteString(resultStyle.Render(strings.Join(m.testResults, "\n")))
+	}
+
+	b.WriteString("\n\n")
+
+	footerStyle := lipgloss.NewStyle().
+		Foreground(theme.Text).
+		Width(m.width).
+		Align(lipgloss.Center)
+
+	footerText := "[r] Re-send  [a] Acknowledge  [t] Test channels  [p] Preview  [Esc] Back  [q] Quit"
+	if m.width < 80 {
+		footerText = "[r] Resend  [a] Ack  [t] Test  [p] Preview  [Esc] Back  [q] Quit"
+	}
+	b.WriteString(footerStyle.Render(footerText))
+
+	return b.String()
+}
+
+// NotificationsLoadedMsg represents the result of loading notifications
+type NotificationsLoadedMsg struct {
+	notifications []notification.Notification
+	err           error
+}
+
+// ResendNotificationMsg requests a notification be queued for delivery again
+type ResendNotificationMsg struct {
+	notificationID uint
+}
+
+// AcknowledgeNotificationMsg requests a notification be marked as acknowledged
+type AcknowledgeNotificationMsg struct {
+	notificationID uint
+}
+
+// TestChannelsMsg requests a test notification through every configured channel
+type TestChannelsMsg struct{}
+
+// ChannelsTestedMsg reports the outcome of a test send per channel
+type ChannelsTestedMsg struct {
+	results []string
+}
+
+// NotificationUpdatedMsg reports the outcome of a re-send or acknowledgement
+type NotificationUpdatedMsg struct {
+	err error
+}
@@ -0,0 +1,25 @@
+package tui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+)
+
+// poolStatsInterval is how often the worker pool statistics in the status bar are refreshed
+const poolStatsInterval = 2 * time.Second
+
+// loadPoolStats fetches what the worker pool is doing
+func (a *App) loadPoolStats() tea.Cmd {
+	return func() tea.Msg {
+		stats, err := a.pool.PoolStats()
+		return PoolStatsMsg{stats: stats, err: err}
+	}
+}
+
+// PoolStatsMsg carries what the worker pool is doing
+type PoolStatsMsg struct {
+	stats ssl.PoolStats
+	err   error
+}
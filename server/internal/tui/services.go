@@ -0,0 +1,121 @@
+package tui
+
+import (
+	"context"
+	"crypto/x509"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/activity"
+	"github.com/samokw/ssl_tracker/internal/demo"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/notification"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/samokw/ssl_tracker/internal/user"
+)
+
+// DomainService is what the TUI needs to manage domains.
+//
+// domain.Service works against the local database and remote.DomainService against a server
+type DomainService interface {
+	GetUsersDomains(userID types.UserID) ([]domain.Domain, error)
+	GetDomain(domainID types.DomainID) (*domain.Domain, error)
+	AddDomain(userID types.UserID, domainName string) (*domain.Domain, error)
+	RemoveDomain(domainID types.DomainID) error
+	CheckDomainSSL(domainID types.DomainID) error
+	GetCertificateChain(domainID types.DomainID) ([]*x509.Certificate, error)
+	GetCheckHistory(domainID types.DomainID, limit int) ([]domain.CheckRecord, error)
+}
+
+// CheckFeed delivers the checks completed outside the TUI, e.g. by the daemon it is attached to.
+//
+// remote.DomainService does this from the server's event stream, the local database doesn't offer it
+type CheckFeed interface {
+	WatchChecks(ctx context.Context, checked func(domain.Domain)) error
+}
+
+// NotificationService is what the TUI needs to manage notifications
+type NotificationService interface {
+	GetUsersNotifications(userID types.UserID) ([]notification.Notification, error)
+	Resend(id uint) error
+	Acknowledge(id uint) error
+	GetUsersAcks(userID types.UserID) ([]notification.DomainAck, error)
+}
+
+// ChannelTester sends test notifications through the configured channels.
+//
+// notification.Dispatcher does this for the local config, remote servers don't offer it
+type ChannelTester interface {
+	Channels() []notification.NotificationType
+	SendTest(ctx context.Context, channel notification.NotificationType) error
+}
+
+// AlertSimulator works out the notifications the rules would queue for domains at a date, queueing none.
+//
+// notification.Dispatcher does this for the local config, remote servers don't offer it
+type AlertSimulator interface {
+	Simulate(domains []domain.Domain, at time.Time) ([]notification.SimulatedAlert, error)
+}
+
+// UserService signs users in to the local database.
+//
+// user.Service does this, remote servers know the user from the API key instead
+type UserService interface {
+	Register(email, password string) (*user.User, error)
+	Login(email, password string, lifetime time.Duration) (*user.User, string, error)
+	Logout(token string) error
+	ResumeSession(token string) (*user.User, error)
+}
+
+// SettingsService keeps each user's preferences in the local database.
+//
+// user.Service does this, remote servers don't offer it
+type SettingsService interface {
+	DefaultSettings() user.Settings
+	GetSettings(userID types.UserID) (*user.Settings, error)
+	SaveSettings(settings *user.Settings) error
+}
+
+// PoolService sizes the worker pool checking certificates and reports what it is doing.
+//
+// domain.Service does this for its own pool, remote servers are resized through their API instead
+type PoolService interface {
+	PoolSize() (ssl.PoolSize, error)
+	ResizePool(size ssl.PoolSize) error
+	PoolStats() (ssl.PoolStats, error)
+}
+
+// ActivityService lists what happened to the domains a user can see.
+//
+// activity.Service does this for the local database, remote servers don't offer it
+type ActivityService interface {
+	Feed(userID types.UserID, limit int) ([]activity.Event, error)
+}
+
+// HandshakeService logs fresh handshakes with domains, to see where their checks fail.
+//
+// domain.Service does this from this machine, remote servers don't offer it
+type HandshakeService interface {
+	HandshakeLog(domainID types.DomainID) ([]string, error)
+}
+
+// SessionStore keeps the tokens of the users signed in between runs, the active user's first
+type SessionStore interface {
+	LoadAll() ([]string, error)
+	Save(token string) error
+	Remove(token string) error
+}
+
+var (
+	_ DomainService       = (*domain.Service)(nil)
+	_ NotificationService = (*notification.Service)(nil)
+	_ NotificationService = (*demo.Notifications)(nil)
+	_ ChannelTester       = (*notification.Dispatcher)(nil)
+	_ AlertSimulator      = (*notification.Dispatcher)(nil)
+	_ UserService         = (*user.Service)(nil)
+	_ SettingsService     = (*user.Service)(nil)
+	_ PoolService         = (*domain.Service)(nil)
+	_ ActivityService     = (*activity.Service)(nil)
+	_ HandshakeService    = (*domain.Service)(nil)
+	_ SessionStore        = user.SessionFile("")
+)
@@ -0,0 +1,365 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samokw/ssl_tracker/internal/notification"
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"github.com/samokw/ssl_tracker/internal/user"
+)
+
+// userSettings are the signed in user's settings, changed with applySettings
+var userSettings = user.DefaultSettings
+
+// displayLocation is the timezone of userSettings that times are shown in
+var displayLocation = time.Local
+
+// applySettings makes a user's theme, thresholds and timezone the active ones
+func applySettings(s user.Settings) {
+	userSettings = s
+	useTheme(s.Theme)
+	loc, err := s.Location()
+	if err != nil {
+		loc = time.Local
+	}
+	displayLocation = loc
+}
+
+// formatTime shows a time the way the signed in user chose, as a date in their timezone or relative to now
+func formatTime(t time.Time, layout string) string {
+	if userSettings.TimeDisplay == user.TimeRelative {
+		return user.RelativeTime(t, time.Now())
+	}
+	return t.In(displayLocation).Format(layout)
+}
+
+// timeDisplays are the choices of the times row in the order they cycle
+var timeDisplays = []user.TimeDisplay{user.TimeAbsolute, user.TimeRelative}
+
+// SettingsModel edits the signed in user's settings
+type SettingsModel struct {
+	settings user.Settings
+	theme    int
+	times    int
+	// inputs are the warning days, critical days and timezone, then the workers and queue size and last the columns
+	inputs []textinput.Model
+	// channels are the configured ones, selected those notified without rules
+	channels []notification.NotificationType
+	selected map[notification.NotificationType]bool
+	// pool is the worker pool's size, nil when it can't be resized from here
+	pool   *ssl.PoolSize
+	focus  int
+	busy   bool
+	err    error
+	width  int
+	height int
+}
+
+const (
+	settingsTheme = iota
+	settingsWarning
+	settingsCritical
+	settingsTimezone
+	settingsTimes
+	settingsColumns
+	// settingsChannels is the first channel, one row each
+	settingsChannels
+)
+
+func NewSettingsModel(s user.Settings, channels []notification.NotificationType, pool *ssl.PoolSize) SettingsModel {
+	days := textinput.New()
+	days.CharLimit = 4
+	days.Width = 10
+
+	warning := days
+	warning.SetValue(strconv.Itoa(s.WarningDays))
+	critical := days
+	critical.SetValue(strconv.Itoa(s.CriticalDays))
+
+	timezone := textinput.New()
+	timezone.Placeholder = "local time"
+	timezone.CharLimit = 64
+	timezone.Width = 30
+	timezone.SetValue(s.Timezone)
+
+	columns := textinput.New()
+	columns.Placeholder = "fit to the terminal"
+	columns.CharLimit = 255
+	columns.Width = 40
+	columns.SetValue(strings.ReplaceAll(user.FormatColumns(s.Columns), ",", ", "))
+
+	workers := days
+	queue := days
+	if pool != nil {
+		workers.SetValue(strconv.Itoa(pool.Workers))
+		queue.SetValue(strconv.Itoa(pool.QueueSize))
+	}
+
+	selected := make(map[notification.NotificationType]bool)
+	for _, c := range s.NotificationChannels {
+		selected[notification.NewNotificationType(c)] = true
+	}
+
+	return SettingsModel{
+		settings: s,
+		theme:    max(0, slices.Index(themeNames, s.Theme)),
+		times:    max(0, slices.Index(timeDisplays, s.TimeDisplay)),
+		inputs:   []textinput.Model{warning, critical, timezone, workers, queue, columns},
+		channels: channels,
+		selected: selected,
+		pool:     pool,
+		width:    80,
+		height:   24,
+	}
+}
+
+// rows is the number of focusable rows, one per channel after the fixed ones and then the pool's two
+func (m SettingsModel) rows() int {
+	if m.pool != nil {
+		return m.poolRow() + 2
+	}
+	return m.poolRow()
+}
+
+// poolRow is the workers row, followed by the queue size row, when the pool can be resized
+func (m SettingsModel) poolRow() int {
+	return settingsChannels + len(m.channels)
+}
+
+// input returns the text input on a row, if it has one
+func (m *SettingsModel) input(row int) *textinput.Model {
+	switch {
+	case row >= settingsWarning && row <= settingsTimezone:
+		return &m.inputs[row-settingsWarning]
+	case row == settingsColumns:
+		return &m.inputs[5]
+	case m.pool != nil && row >= m.poolRow():
+		return &m.inputs[3+row-m.poolRow()]
+	}
+	return nil
+}
+
+func (m SettingsModel) Update(msg tea.Msg) (SettingsModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if m.busy {
+			return m, nil
+		}
+		switch msg.String() {
+		case "esc":
+			return m, func() tea.Msg { return "back_to_main" }
+		case "tab", "down":
+			m.setFocus((m.focus + 1) % m.rows())
+			return m, nil
+		case "shift+tab", "up":
+			m.setFocus((m.focus + m.rows() - 1) % m.rows())
+			return m, nil
+		case "left", "right":
+			if m.focus == settingsTheme {
+				step := 1
+				if msg.String() == "left" {
+					step = len(themeNames) - 1
+				}
+				m.theme = (m.theme + step) % len(themeNames)
+				return m, nil
+			}
+			if m.focus == settingsTimes {
+				m.times = (m.times + 1) % len(timeDisplays)
+				return m, nil
+			}
+		case " ":
+			if m.focus >= settingsChannels && m.focus < m.poolRow() {
+				c := m.channels[m.focus-settingsChannels]
+				m.selected[c] = !m.selected[c]
+				return m, nil
+			}
+		case "enter":
+			return m.submit()
+		}
+	case SettingsSavedMsg:
+		m.busy = false
+		m.err = msg.err
+		return m, nil
+	}
+
+	if in := m.input(m.focus); in != nil {
+		var cmd tea.Cmd
+		*in, cmd = in.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+func (m *SettingsModel) setFocus(row int) {
+	if in := m.input(m.focus); in != nil {
+		in.Blur()
+	}
+	m.focus = row
+	if in := m.input(m.focus); in != nil {
+		in.Focus()
+	}
+}
+
+// submit checks the form and asks the app to save the settings
+func (m SettingsModel) submit() (SettingsModel, tea.Cmd) {
+	s := m.settings
+	s.Theme = themeNames[m.theme]
+	s.TimeDisplay = timeDisplays[m.times]
+	var err error
+	if s.WarningDays, err = strconv.Atoi(strings.TrimSpace(m.input(settingsWarning).Value())); err != nil {
+		m.err = errors.New("warning days must be a number")
+		return m, nil
+	}
+	if s.CriticalDays, err = strconv.Atoi(strings.TrimSpace(m.input(settingsCritical).Value())); err != nil {
+		m.err = errors.New("critical days must be a number")
+		return m, nil
+	}
+	s.Timezone = strings.TrimSpace(m.input(settingsTimezone).Value())
+	if s.Columns, err = user.ParseColumns(m.input(settingsColumns).Value()); err != nil {
+		m.err = err
+		return m, nil
+	}
+	if unknown := unknownColumns(s.Columns); len(unknown) > 0 {
+		m.err = fmt.Errorf("unknown columns %s", strings.Join(unknown, ", "))
+		return m, nil
+	}
+	s.NotificationChannels = nil
+	for _, c := range m.channels {
+		if m.selected[c] {
+			s.NotificationChannels = append(s.NotificationChannels, c.String())
+		}
+	}
+	if err := s.Validate(); err != nil {
+		m.err = err
+		return m, nil
+	}
+	var pool *ssl.PoolSize
+	if m.pool != nil {
+		size := *m.pool
+		if size.Workers, err = strconv.Atoi(strings.TrimSpace(m.input(m.poolRow()).Value())); err != nil {
+			m.err = errors.New("workers must be a number")
+			return m, nil
+		}
+		if size.QueueSize, err = strconv.Atoi(strings.TrimSpace(m.input(m.poolRow() + 1).Value())); err != nil {
+			m.err = errors.New("queue size must be a number")
+			return m, nil
+		}
+		if err := size.Validate(); err != nil {
+			m.err = err
+			return m, nil
+		}
+		if size != *m.pool {
+			pool = &size
+		}
+	}
+
+	m.busy = true
+	m.err = nil
+	return m, func() tea.Msg { return SaveSettingsMsg{settings: s, pool: pool} }
+}
+
+func (m *SettingsModel) UpdateSize(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+func (m SettingsModel) View() string {
+	var b strings.Builder
+
+	center := lipgloss.NewStyle().Width(m.width).Align(lipgloss.Center)
+	labelStyle := lipgloss.NewStyle().Foreground(theme.Highlight).Bold(true).Width(18)
+	valueStyle := lipgloss.NewStyle().Foreground(theme.Text)
+	focusStyle := lipgloss.NewStyle().Foreground(theme.Accent).Bold(true)
+
+	topPadding := max(1, (m.height-m.rows()-15)/2)
+	b.WriteString(strings.Repeat("\n", topPadding))
+	b.WriteString(center.Foreground(theme.Accent).Bold(true).Render("sslcerttop ⚙️ Settings"))
+	b.WriteString("\n")
+	b.WriteString(center.Foreground(theme.Muted).Render(strings.Repeat("═", min(40, max(20, m.width-4)))))
+	b.WriteString("\n\n")
+
+	themeName := themeNames[m.theme]
+	if themeName == "" {
+		themeName = "configured"
+	}
+	lines := []string{
+		m.row(settingsTheme, labelStyle, focusStyle, "Theme", valueStyle.Render("◀ "+themeName+" ▶")),
+		m.row(settingsWarning, labelStyle, focusStyle, "Warning days", m.inputs[0].View()),
+		m.row(settingsCritical, labelStyle, focusStyle, "Critical days", m.inputs[1].View()),
+		m.row(settingsTimezone, labelStyle, focusStyle, "Timezone", m.inputs[2].View()),
+		m.row(settingsTimes, labelStyle, focusStyle, "Times", valueStyle.Render("◀ "+string(timeDisplays[m.times])+" ▶")),
+		m.row(settingsColumns, labelStyle, focusStyle, "Columns", m.inputs[5].View()),
+		lipgloss.NewStyle().Foreground(theme.Muted).Width(min(60, max(20, m.width-4))).PaddingLeft(2).
+			Render("name or name:width of " + strings.Join(columnNames, ", ")),
+		"",
+	}
+	if len(m.channels) == 0 {
+		lines = append(lines, lipgloss.NewStyle().Foreground(theme.Muted).Render("No notification channels are configured"))
+	} else {
+		lines = append(lines, lipgloss.NewStyle().Foreground(theme.Subtle).Render("Notify without rules through (none selected notifies all):"))
+		for i, c := range m.channels {
+			check := "[ ]"
+			if m.selected[c] {
+				check = "[x]"
+			}
+			lines = append(lines, m.row(settingsChannels+i, labelStyle, focusStyle, "", valueStyle.Render(check+" "+c.String())))
+		}
+	}
+	if m.pool != nil {
+		lines = append(lines, "",
+			lipgloss.NewStyle().Foreground(theme.Subtle).Render("Certificate checks, until sslcerttop exits:"),
+			m.row(m.poolRow(), labelStyle, focusStyle, "Workers", m.inputs[3].View()),
+			m.row(m.poolRow()+1, labelStyle, focusStyle, "Queue size", m.inputs[4].View()))
+	}
+	b.WriteString(center.Render(lipgloss.NewStyle().Align(lipgloss.Left).Render(strings.Join(lines, "\n"))))
+	b.WriteString("\n\n")
+
+	switch {
+	case m.busy:
+		b.WriteString(center.Foreground(theme.Highlight).Render("⏳ Saving..."))
+	case m.err != nil:
+		b.WriteString(center.Foreground(theme.Error).Bold(true).Render("✗ " + m.err.Error()))
+	}
+	b.WriteString("\n\n")
+
+	footer := "[Enter] Save  [Tab] Next  [←/→] Change  [Space] Toggle channel  [Esc] Back  [Ctrl+C] Quit"
+	b.WriteString(center.Foreground(theme.Text).Render(footer))
+
+	return b.String()
+}
+
+// row renders a label and value, marking the focused row
+func (m SettingsModel) row(i int, labelStyle, focusStyle lipgloss.Style, label, value string) string {
+	cursor := "  "
+	if m.focus == i {
+		cursor = focusStyle.Render("▸ ")
+	}
+	return cursor + labelStyle.Render(label) + value
+}
+
+// SettingsLoadedMsg carries the signed in user's settings
+type SettingsLoadedMsg struct {
+	settings *user.Settings
+	err      error
+}
+
+// SaveSettingsMsg asks the app to save the edited settings
+type SaveSettingsMsg struct {
+	settings user.Settings
+	// pool is the new worker pool size, nil when it didn't change
+	pool *ssl.PoolSize
+}
+
+// SettingsSavedMsg reports the outcome of saving settings
+type SettingsSavedMsg struct {
+	settings user.Settings
+	err      error
+}
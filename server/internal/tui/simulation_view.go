@@ -0,0 +1,241 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samokw/ssl_tracker/internal/notification"
+)
+
+// simulationLead is how far ahead the simulated date starts out
+const simulationLead = 30 * 24 * time.Hour
+
+// SimulationModel previews the notifications the rules would queue for the domains at a date, queueing none
+type SimulationModel struct {
+	date    textinput.Model
+	table   table.Model
+	alerts  []notification.SimulatedAlert
+	at      time.Time
+	loading bool
+	// ran is set once a simulation finished
+	ran    bool
+	err    error
+	width  int
+	height int
+}
+
+func NewSimulationModel() SimulationModel {
+	date := textinput.New()
+	date.Placeholder = "2006-01-02"
+	date.CharLimit = 16
+	date.Width = 16
+	date.SetValue(time.Now().Add(simulationLead).In(displayLocation).Format(time.DateOnly))
+	date.Focus()
+
+	t := table.New(
+		table.WithColumns(simulationColumns(80)),
+		table.WithFocused(true),
+		table.WithHeight(10),
+	)
+
+	s := table.DefaultStyles()
+	s.Header = s.Header.
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(theme.Border).
+		BorderBottom(true).
+		Bold(false)
+	s.Selected = s.Selected.
+		Foreground(theme.SelectedText).
+		Background(theme.SelectedBackground).
+		Bold(false)
+	t.SetStyles(s)
+
+	return SimulationModel{
+		date:   date,
+		table:  t,
+		width:  80,
+		height: 24,
+	}
+}
+
+func simulationColumns(width int) []table.Column {
+	if width < 120 {
+		return []table.Column{
+			{Title: "Domain", Width: max(20, width/4)},
+			{Title: "Channel", Width: 9},
+			{Title: "Alert", Width: 16},
+			{Title: "Result", Width: 14},
+		}
+	}
+	return []table.Column{
+		{Title: "Domain", Width: 35},
+		{Title: "Channel", Width: 10},
+		{Title: "Alert", Width: 22},
+		{Title: "Rule", Width: 20},
+		{Title: "Result", Width: 16},
+	}
+}
+
+func (m SimulationModel) Update(msg tea.Msg) (SimulationModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			return m, func() tea.Msg { return "show_notifications" }
+		case "enter":
+			if m.loading {
+				return m, nil
+			}
+			at, err := time.ParseInLocation(time.DateOnly, strings.TrimSpace(m.date.Value()), displayLocation)
+			if err != nil {
+				m.err = fmt.Errorf("enter the date as %s", time.DateOnly)
+				return m, nil
+			}
+			m.loading = true
+			m.err = nil
+			return m, func() tea.Msg { return SimulateMsg{at: at} }
+		case "up", "down", "pgup", "pgdown":
+			var cmd tea.Cmd
+			m.table, cmd = m.table.Update(msg)
+			return m, cmd
+		}
+	case SimulatedMsg:
+		m.loading = false
+		m.err = msg.err
+		if msg.err == nil {
+			m.ran = true
+			m.at = msg.at
+			m.SetAlerts(msg.alerts)
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.date, cmd = m.date.Update(msg)
+	return m, cmd
+}
+
+func (m *SimulationModel) UpdateSize(width, height int) {
+	m.width = width
+	m.height = height
+
+	m.table.SetRows([]table.Row{})
+	m.table.SetColumns(simulationColumns(width))
+	m.SetAlerts(m.alerts)
+	m.table.SetHeight(max(5, height-14))
+}
+
+// SetAlerts replaces the listed alerts
+func (m *SimulationModel) SetAlerts(alerts []notification.SimulatedAlert) {
+	m.alerts = alerts
+
+	wide := len(m.table.Columns()) > 4
+	rows := make([]table.Row, len(alerts))
+	for i, a := range alerts {
+		result := "🔔 Would notify"
+		if a.Covered {
+			result = "✓ Queued already"
+		}
+		if wide {
+			rule := a.Rule
+			if rule == "" {
+				rule = "-"
+			}
+			rows[i] = table.Row{a.Domain.DomainName.String(), a.Channel.String(), a.Describe(), rule, result}
+		} else {
+			rows[i] = table.Row{a.Domain.DomainName.String(), a.Channel.String(), a.Describe(), result}
+		}
+	}
+	m.table.SetRows(rows)
+}
+
+func (m SimulationModel) View() string {
+	var b strings.Builder
+
+	b.WriteString("\n\n")
+
+	headerStyle := lipgloss.NewStyle().
+		Foreground(theme.Accent).
+		Bold(true).
+		Width(m.width).
+		Align(lipgloss.Center)
+
+	b.WriteString(headerStyle.Render("sslcerttop 🧪 Notification Preview"))
+	b.WriteString("\n")
+
+	statsStyle := lipgloss.NewStyle().
+		Foreground(theme.Subtle).
+		Width(m.width).
+		Align(lipgloss.Center)
+	b.WriteString(statsStyle.Render("[the notifications your domains would queue at a date, as they are now; nothing is sent]"))
+	b.WriteString("\n")
+
+	separatorStyle := lipgloss.NewStyle().
+		Foreground(theme.Muted).
+		Width(m.width).
+		Align(lipgloss.Center)
+
+	if m.width < 84 {
+		b.WriteString(separatorStyle.Render("- - - - - - - - - - - - - - - -"))
+	} else {
+		b.WriteString(separatorStyle.Render(strings.Repeat("═", 80)))
+	}
+	b.WriteString("\n\n")
+
+	center := lipgloss.NewStyle().
+		Width(m.width).
+		Align(lipgloss.Center)
+	labelStyle := lipgloss.NewStyle().
+		Foreground(theme.Highlight).
+		Bold(true)
+	b.WriteString(center.Render(labelStyle.Render("Date ") + m.date.View()))
+	b.WriteString("\n\n")
+
+	messageStyle := lipgloss.NewStyle().
+		Foreground(theme.Subtle).
+		Width(m.width).
+		Align(lipgloss.Center)
+	switch {
+	case m.loading:
+		b.WriteString(messageStyle.Foreground(theme.Highlight).Render("⏳ Simulating..."))
+		b.WriteString("\n")
+	case m.err != nil:
+		b.WriteString(messageStyle.Foreground(theme.Error).Bold(true).Render(fmt.Sprintf("Error: %v", m.err)))
+		b.WriteString("\n")
+	case !m.ran:
+		b.WriteString(messageStyle.Render("Press Enter to simulate the date."))
+		b.WriteString("\n")
+	case len(m.alerts) == 0:
+		b.WriteString(messageStyle.Render("Nothing would notify on " + m.at.Format(time.DateOnly) + "."))
+		b.WriteString("\n")
+	default:
+		b.WriteString(center.Render(m.table.View()))
+	}
+
+	b.WriteString("\n\n")
+
+	footerStyle := lipgloss.NewStyle().
+		Foreground(theme.Text).
+		Width(m.width).
+		Align(lipgloss.Center)
+	b.WriteString(footerStyle.Render("[Enter] Simulate  [↑/↓] Scroll  [Esc] Back  [Ctrl+C] Quit"))
+
+	return b.String()
+}
+
+// SimulateMsg asks the app to simulate the notifications at a date
+type SimulateMsg struct {
+	at time.Time
+}
+
+// SimulatedMsg carries the alerts a simulation found
+type SimulatedMsg struct {
+	at     time.Time
+	alerts []notification.SimulatedAlert
+	err    error
+}
@@ -0,0 +1,154 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// defaultSweepWorkers is how many checks a sweep runs at once when the worker pool size isn't known, e.g. when
+// the domains are on a server
+const defaultSweepWorkers = 5
+
+// sweep is a re-check of many domains the TUI runs itself a few checks at a time, so it can show how far it got
+// and stop between checks when cancelled
+type sweep struct {
+	cancel context.CancelFunc
+	// updates carries the sweep's SSLCheckStartedMsg, an SSLProgressMsg per check and finally its
+	// SSLCheckCompletedMsg
+	updates chan tea.Msg
+}
+
+// sweepCheck is the outcome of checking one domain of a sweep
+type sweepCheck struct {
+	name    string
+	latency time.Duration
+	err     error
+	// domain is the domain as of the check, nil if it couldn't be fetched
+	domain *domain.Domain
+}
+
+// startSweep re-checks every domain of the user, or only those whose last check failed
+func (a *App) startSweep(failedOnly bool) tea.Cmd {
+	if a.sweep != nil {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &sweep{cancel: cancel, updates: make(chan tea.Msg)}
+	a.sweep = s
+	go s.run(ctx, a.domainService, a.userID, failedOnly, a.sweepWorkers())
+	return s.next()
+}
+
+// cancelSweep stops the running sweep from starting any more checks, those already running still finish
+func (a *App) cancelSweep() {
+	if a.sweep != nil {
+		a.sweep.cancel()
+		a.main.sweepCancelling = true
+	}
+}
+
+// sweepWorkers is how many checks a sweep runs at once, as many as the worker pool runs
+func (a *App) sweepWorkers() int {
+	if a.pool != nil {
+		if size, err := a.pool.PoolSize(); err == nil {
+			return size.Workers
+		}
+	}
+	return defaultSweepWorkers
+}
+
+// next waits for the sweep's next update
+func (s *sweep) next() tea.Cmd {
+	return func() tea.Msg {
+		return <-s.updates
+	}
+}
+
+// run checks the domains with workers checks at once until they are all checked or ctx is cancelled
+func (s *sweep) run(ctx context.Context, svc DomainService, userID types.UserID, failedOnly bool, workers int) {
+	domains, err := svc.GetUsersDomains(userID)
+	if err != nil {
+		s.updates <- SSLCheckCompletedMsg{err: fmt.Errorf("failed to get domains: %w", err)}
+		return
+	}
+	if failedOnly {
+		domains = domain.FailedDomains(domains)
+	}
+	s.updates <- SSLCheckStartedMsg{total: len(domains)}
+
+	queue := make(chan domain.Domain)
+	checks := make(chan sweepCheck)
+	var wg sync.WaitGroup
+	for range min(workers, len(domains)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d := range queue {
+				start := time.Now()
+				err := svc.CheckDomainSSL(d.DomainID)
+				c := sweepCheck{name: d.DomainName.String(), latency: time.Since(start), err: err}
+				// A failed check is recorded too, so the row is updated either way
+				c.domain, _ = svc.GetDomain(d.DomainID)
+				checks <- c
+			}
+		}()
+	}
+	go func() {
+		defer close(queue)
+		for _, d := range domains {
+			// Checking ctx first keeps a cancelled sweep from starting one more check
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case queue <- d:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(checks)
+	}()
+
+	var latency time.Duration
+	var failed []error
+	done := 0
+	stale := false
+	for c := range checks {
+		done++
+		stale = stale || c.domain == nil
+		latency += c.latency
+		if c.err != nil {
+			failed = append(failed, fmt.Errorf("%s: %w", c.name, c.err))
+		}
+		// The checks left take about as long as those so far did, a batch of workers at a time
+		left := len(domains) - done
+		eta := latency / time.Duration(done) * time.Duration((left+workers-1)/workers)
+		s.updates <- SSLProgressMsg{
+			progress:     float64(done) / float64(len(domains)),
+			domainName:   c.name,
+			totalDomains: len(domains),
+			completed:    done,
+			eta:          eta,
+			domain:       c.domain,
+		}
+	}
+
+	completed := SSLCheckCompletedMsg{completed: done, total: len(domains), cancelled: done < len(domains), stale: stale}
+	switch len(failed) {
+	case 0:
+	case 1:
+		completed.err = failed[0]
+	default:
+		completed.err = fmt.Errorf("%d checks couldn't be completed, e.g. %w", len(failed), failed[0])
+	}
+	s.updates <- completed
+}
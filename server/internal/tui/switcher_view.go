@@ -0,0 +1,146 @@
+package tui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samokw/ssl_tracker/internal/user"
+)
+
+// account is a user signed in on this machine and their session
+type account struct {
+	user  *user.User
+	token string
+}
+
+// SwitcherModel picks which of the users signed in on this machine is the active one, or signs in another
+type SwitcherModel struct {
+	accounts []account
+	// active is the session of the active user, empty when nobody is signed in
+	active string
+	// cursor is the row selected, the one past the accounts signing in to another
+	cursor int
+	// optional lets the user go back without picking, when someone is signed in or nobody needs to be
+	optional bool
+	err      error
+	width    int
+	height   int
+}
+
+func NewSwitcherModel(accounts []account, active string, optional bool) SwitcherModel {
+	m := SwitcherModel{
+		accounts: accounts,
+		active:   active,
+		optional: optional,
+		width:    80,
+		height:   24,
+	}
+	for i, a := range accounts {
+		if a.token == active {
+			m.cursor = i
+		}
+	}
+	return m
+}
+
+func (m SwitcherModel) Update(msg tea.Msg) (SwitcherModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			if m.optional {
+				return m, func() tea.Msg { return "back_to_main" }
+			}
+		case "up", "k":
+			m.cursor = (m.cursor + len(m.accounts)) % (len(m.accounts) + 1)
+		case "down", "j":
+			m.cursor = (m.cursor + 1) % (len(m.accounts) + 1)
+		case "enter":
+			if m.cursor == len(m.accounts) {
+				return m, func() tea.Msg { return "show_login" }
+			}
+			selected := m.accounts[m.cursor]
+			if selected.token == m.active {
+				return m, func() tea.Msg { return "back_to_main" }
+			}
+			m.err = nil
+			return m, func() tea.Msg { return SwitchUserMsg{account: selected} }
+		}
+	case LoggedInMsg:
+		m.err = msg.err
+	}
+	return m, nil
+}
+
+func (m *SwitcherModel) UpdateSize(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+func (m SwitcherModel) View() string {
+	var b strings.Builder
+
+	center := lipgloss.NewStyle().Width(m.width).Align(lipgloss.Center)
+	headerStyle := center.Foreground(theme.Accent).Bold(true)
+
+	topPadding := max(1, (m.height-len(m.accounts)-10)/2)
+	b.WriteString(strings.Repeat("\n", topPadding))
+	b.WriteString(headerStyle.Render("sslcerttop 👥 Switch User"))
+	b.WriteString("\n")
+	b.WriteString(center.Foreground(theme.Muted).Render(strings.Repeat("═", min(40, max(20, m.width-4)))))
+	b.WriteString("\n\n")
+
+	rows := make([]string, 0, len(m.accounts)+1)
+	for _, a := range m.accounts {
+		row := a.user.Email.String()
+		if a.token == m.active {
+			row += " (active)"
+		}
+		rows = append(rows, row)
+	}
+	rows = append(rows, "Sign in to another account")
+
+	// Padding the rows to the same width keeps their cursors lined up once centered
+	width := 0
+	for _, row := range rows {
+		width = max(width, lipgloss.Width(row))
+	}
+	for i, row := range rows {
+		row += strings.Repeat(" ", width-lipgloss.Width(row))
+		if i == m.cursor {
+			b.WriteString(center.Foreground(theme.Highlight).Bold(true).Render("▶ " + row))
+		} else {
+			b.WriteString(center.Foreground(theme.Text).Render("  " + row))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+
+	if m.err != nil {
+		b.WriteString(center.Foreground(theme.Error).Bold(true).Render("✗ " + m.err.Error()))
+	}
+	b.WriteString("\n\n")
+
+	footer := "[↑/↓] Select  [Enter] Switch"
+	if m.optional {
+		footer += "  [Esc] Back"
+	}
+	footer += "  [q] Quit"
+	b.WriteString(center.Foreground(theme.Text).Render(footer))
+
+	return b.String()
+}
+
+// SwitchUserMsg asks the app to make another user signed in on this machine the active one
+type SwitchUserMsg struct {
+	account account
+}
+
+// AccountsLoadedMsg carries the users signed in on this machine whose sessions are still valid
+type AccountsLoadedMsg struct {
+	accounts []account
+	// asked is set when the user asked to switch, otherwise the switcher only shows when there is a choice to make
+	asked bool
+	err   error
+}
@@ -0,0 +1,122 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samokw/ssl_tracker/internal/domain"
+)
+
+// untaggedGroup is the group of the domains without tags, shown after the tags
+const untaggedGroup = "(untagged)"
+
+// tagGroup is the domains sharing a tag, shown as a section of the table when grouping by tag
+type tagGroup struct {
+	tag     string
+	domains []domain.Domain
+	// worst is the status of the group's domain needing attention most
+	worst domain.StatusLevel
+}
+
+// mainRow is what a row of the domain table shows, a domain or the header of a tag group
+type mainRow struct {
+	domain *domain.Domain
+	group  *tagGroup
+}
+
+// statusRank orders status levels from the one needing attention least to the one needing it most
+var statusRank = map[domain.StatusLevel]int{
+	domain.StatusValid:        0,
+	domain.StatusExpiringSoon: 1,
+	domain.StatusUnknown:      2,
+	domain.StatusCritical:     3,
+	domain.StatusError:        4,
+	domain.StatusExpired:      5,
+}
+
+// groupByTag sorts domains into a group per tag, in tag order with the untagged last. A domain with several tags
+// is in the group of each
+func groupByTag(domains []domain.Domain) []*tagGroup {
+	byTag := map[string]*tagGroup{}
+	var groups []*tagGroup
+	add := func(tag string, d domain.Domain) {
+		g, ok := byTag[tag]
+		if !ok {
+			g = &tagGroup{tag: tag, worst: domain.StatusValid}
+			byTag[tag] = g
+			groups = append(groups, g)
+		}
+		g.domains = append(g.domains, d)
+		if level := statusOf(d).Level; statusRank[level] > statusRank[g.worst] {
+			g.worst = level
+		}
+	}
+	for _, d := range domains {
+		if len(d.Tags) == 0 {
+			add(untaggedGroup, d)
+		}
+		for _, tag := range d.Tags {
+			add(tag, d)
+		}
+	}
+	slices.SortFunc(groups, func(a, b *tagGroup) int {
+		switch {
+		case a.tag == untaggedGroup:
+			return 1
+		case b.tag == untaggedGroup:
+			return -1
+		case a.tag < b.tag:
+			return -1
+		case a.tag > b.tag:
+			return 1
+		}
+		return 0
+	})
+	return groups
+}
+
+// groupHeader is the label of a group's header row, marked with whether it is collapsed
+func groupHeader(g *tagGroup, collapsed bool) string {
+	marker := "▼"
+	if collapsed {
+		marker = "▶"
+	}
+	return fmt.Sprintf("%s %s (%d)", marker, g.tag, len(g.domains))
+}
+
+// renderGroupDetails shows how many of a group's domains are in each status, for the details pane
+func renderGroupDetails(g *tagGroup) string {
+	labelStyle := lipgloss.NewStyle().
+		Foreground(theme.Highlight).
+		Bold(true).
+		Width(14)
+
+	valueStyle := lipgloss.NewStyle().
+		Foreground(theme.Text)
+
+	counts := map[domain.StatusLevel]int{}
+	for _, d := range g.domains {
+		counts[statusOf(d).Level]++
+	}
+	lines := []string{
+		lipgloss.JoinHorizontal(lipgloss.Top, labelStyle.Render("Tag"), valueStyle.Render(g.tag)),
+		lipgloss.JoinHorizontal(lipgloss.Top, labelStyle.Render("Domains"), valueStyle.Render(fmt.Sprint(len(g.domains)))),
+		lipgloss.JoinHorizontal(lipgloss.Top, labelStyle.Render("Worst"), valueStyle.Render(getLevelDisplay(g.worst))),
+		"",
+	}
+	// Those needing attention most come first
+	levels := make([]domain.StatusLevel, 0, len(counts))
+	for level := range counts {
+		levels = append(levels, level)
+	}
+	slices.SortFunc(levels, func(a, b domain.StatusLevel) int { return statusRank[b] - statusRank[a] })
+	for _, level := range levels {
+		lines = append(lines, lipgloss.JoinHorizontal(lipgloss.Top,
+			labelStyle.Render(getLevelDisplay(level)),
+			valueStyle.Render(fmt.Sprint(counts[level])),
+		))
+	}
+	return strings.Join(lines, "\n")
+}
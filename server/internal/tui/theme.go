@@ -0,0 +1,75 @@
+package tui
+
+import "github.com/charmbracelet/lipgloss"
+
+// Theme is the set of colours the views are drawn with
+type Theme struct {
+	Accent             lipgloss.Color
+	Highlight          lipgloss.Color
+	Text               lipgloss.Color
+	Subtle             lipgloss.Color
+	Muted              lipgloss.Color
+	Error              lipgloss.Color
+	Warning            lipgloss.Color
+	Border             lipgloss.Color
+	SelectedText       lipgloss.Color
+	SelectedBackground lipgloss.Color
+}
+
+// DefaultTheme is the built in dark theme
+var DefaultTheme = Theme{
+	Accent:             lipgloss.Color("#00ff88"),
+	Highlight:          lipgloss.Color("#00bfff"),
+	Text:               lipgloss.Color("#ffffff"),
+	Subtle:             lipgloss.Color("#cccccc"),
+	Muted:              lipgloss.Color("#666666"),
+	Error:              lipgloss.Color("#ff4444"),
+	Warning:            lipgloss.Color("#ffcc00"),
+	Border:             lipgloss.Color("240"),
+	SelectedText:       lipgloss.Color("229"),
+	SelectedBackground: lipgloss.Color("57"),
+}
+
+// LightTheme is the built in theme for terminals with a light background
+var LightTheme = Theme{
+	Accent:             lipgloss.Color("#007a4d"),
+	Highlight:          lipgloss.Color("#005f9e"),
+	Text:               lipgloss.Color("#1a1a1a"),
+	Subtle:             lipgloss.Color("#444444"),
+	Muted:              lipgloss.Color("#8a8a8a"),
+	Error:              lipgloss.Color("#c41e1e"),
+	Warning:            lipgloss.Color("#a86b00"),
+	Border:             lipgloss.Color("250"),
+	SelectedText:       lipgloss.Color("#ffffff"),
+	SelectedBackground: lipgloss.Color("25"),
+}
+
+// Themes are the built in themes users can pick in their settings, by name
+var Themes = map[string]Theme{
+	"dark":  DefaultTheme,
+	"light": LightTheme,
+}
+
+// themeNames lists the choices in the settings view, the empty name being the configured theme
+var themeNames = []string{"", "dark", "light"}
+
+// theme is the active theme, changed with SetTheme before the app is created
+var theme = DefaultTheme
+
+// configuredTheme is the theme set with SetTheme, used by users who didn't pick one
+var configuredTheme = DefaultTheme
+
+// SetTheme changes the colours used by views created afterwards
+func SetTheme(t Theme) {
+	theme = t
+	configuredTheme = t
+}
+
+// useTheme switches to a built in theme by name, or back to the configured one for an unknown name
+func useTheme(name string) {
+	if t, ok := Themes[name]; ok {
+		theme = t
+	} else {
+		theme = configuredTheme
+	}
+}
@@ -0,0 +1,160 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samokw/ssl_tracker/internal/domain"
+)
+
+// triageHistory is how many recent checks of each failing domain are read to see how long it has been failing
+const triageHistory = 100
+
+// TriageModel lists only the domains that are failing, grouped by what is likely broken, so a broken sweep can be
+// triaged at a glance
+type TriageModel struct {
+	groups  []domain.ErrorGroup
+	loading bool
+	err     error
+	width   int
+	height  int
+}
+
+func NewTriageModel() TriageModel {
+	return TriageModel{
+		loading: true,
+		width:   80,
+		height:  24,
+	}
+}
+
+func (m TriageModel) Update(msg tea.Msg) (TriageModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "esc" {
+			return m, func() tea.Msg { return "back_to_main" }
+		}
+	case ErrorTriageLoadedMsg:
+		m.loading = false
+		m.groups, m.err = msg.groups, msg.err
+	}
+	return m, nil
+}
+
+func (m *TriageModel) UpdateSize(width, height int) {
+	m.width = width
+	m.height = height
+}
+
+func (m TriageModel) View() string {
+	var b strings.Builder
+
+	b.WriteString("\n\n")
+
+	headerStyle := lipgloss.NewStyle().
+		Foreground(theme.Accent).
+		Bold(true).
+		Width(m.width).
+		Align(lipgloss.Center)
+
+	b.WriteString(headerStyle.Render("sslcerttop 🚨 Error Triage"))
+	b.WriteString("\n")
+
+	failing := 0
+	for _, g := range m.groups {
+		failing += len(g.Domains)
+	}
+	statsStyle := lipgloss.NewStyle().
+		Foreground(theme.Subtle).
+		Width(m.width).
+		Align(lipgloss.Center)
+	b.WriteString(statsStyle.Render(fmt.Sprintf("[%d failing domains in %d classes]", failing, len(m.groups))))
+	b.WriteString("\n")
+
+	separatorStyle := lipgloss.NewStyle().
+		Foreground(theme.Muted).
+		Width(m.width).
+		Align(lipgloss.Center)
+
+	if m.width < 84 {
+		b.WriteString(separatorStyle.Render("- - - - - - - - - - - - - - - -"))
+	} else {
+		b.WriteString(separatorStyle.Render(strings.Repeat("═", 80)))
+	}
+	b.WriteString("\n\n")
+
+	messageStyle := lipgloss.NewStyle().
+		Foreground(theme.Subtle).
+		Width(m.width).
+		Align(lipgloss.Center)
+	switch {
+	case m.loading:
+		b.WriteString(messageStyle.Render("⏳ Reading check history..."))
+		b.WriteString("\n")
+	case m.err != nil:
+		b.WriteString(messageStyle.Foreground(theme.Error).Render("Error: " + m.err.Error()))
+		b.WriteString("\n")
+	case len(m.groups) == 0:
+		b.WriteString(messageStyle.Render("No domain is failing."))
+		b.WriteString("\n")
+	default:
+		b.WriteString(m.renderGroups())
+	}
+
+	b.WriteString("\n\n")
+
+	footerStyle := lipgloss.NewStyle().
+		Foreground(theme.Text).
+		Width(m.width).
+		Align(lipgloss.Center)
+	b.WriteString(footerStyle.Render("[Esc] Back  [q] Quit"))
+
+	return b.String()
+}
+
+// renderGroups lists each error class with its failing domains, how often and since when each has failed
+func (m TriageModel) renderGroups() string {
+	titleStyle := lipgloss.NewStyle().
+		Foreground(theme.Highlight).
+		Bold(true)
+	nameStyle := lipgloss.NewStyle().
+		Foreground(theme.Text)
+	mutedStyle := lipgloss.NewStyle().
+		Foreground(theme.Subtle)
+
+	messageWidth := max(20, min(m.width, 100)-8)
+	var lines []string
+	for i, g := range m.groups {
+		if i > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, titleStyle.Render(fmt.Sprintf("%s (%d domains, %d failed checks)", g.Class.Label(), len(g.Domains), g.Occurrences())))
+		for _, d := range g.Domains {
+			lines = append(lines, nameStyle.Render("  "+d.Domain.DomainName.String())+mutedStyle.Render(fmt.Sprintf("  %d×, first %s, last %s",
+				d.Occurrences, formatTime(d.FirstSeen, "2006-01-02 15:04"), formatTime(d.LastSeen, "2006-01-02 15:04"))))
+			message := d.Message
+			if len(message) > messageWidth {
+				message = message[:messageWidth-1] + "…"
+			}
+			lines = append(lines, mutedStyle.Render("    "+message))
+		}
+	}
+
+	blockStyle := lipgloss.NewStyle().
+		Width(m.width).
+		Align(lipgloss.Center)
+	return blockStyle.Render(lipgloss.NewStyle().Align(lipgloss.Left).Render(strings.Join(lines, "\n")))
+}
+
+// ShowTriageMsg opens the error triage of the listed domains
+type ShowTriageMsg struct {
+	domains []domain.Domain
+}
+
+// ErrorTriageLoadedMsg carries the failing domains grouped by error class, once their history was read
+type ErrorTriageLoadedMsg struct {
+	groups []domain.ErrorGroup
+	err    error
+}
@@ -11,6 +11,8 @@ type ExpiryDate time.Time
 
 type DomainID uint
 
+type TeamID uint
+
 // UserID helper functions
 func NewUserID(id uint) UserID {
 	return UserID(id)
@@ -43,6 +45,11 @@ func ValidateDomainID(domainID DomainID) error {
 	return nil
 }
 
+// TeamID helper functions
+func (t TeamID) Uint() uint {
+	return uint(t)
+}
+
 func NewExpiryDate(t time.Time) ExpiryDate {
 	return ExpiryDate(t)
 }
@@ -0,0 +1,129 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// tokenPrefix marks a string as an sslcerttop session token
+const tokenPrefix = "scs_"
+
+// DefaultSessionLifetime is how long a sign-in lasts
+const DefaultSessionLifetime = 30 * 24 * time.Hour
+
+// ErrInvalidSession is returned when a session token is unknown or expired
+var ErrInvalidSession = errors.New("invalid or expired session")
+
+type Session struct {
+	SessionID uint         `db:"id"`
+	UserID    types.UserID `db:"user_id"`
+	CreatedAt time.Time    `db:"created_at"`
+	ExpiresAt time.Time    `db:"expires_at"`
+}
+
+// Expired reports whether the session can no longer be used at now
+func (s Session) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+// generateToken creates a new random session token
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate session token: %w", err)
+	}
+	return tokenPrefix + strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf)), nil
+}
+
+// hashToken returns the stored form of a token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// SessionFile keeps the tokens of the users signed in on this machine between runs, one per line with the
+// active user's first, readable only by its owner
+type SessionFile string
+
+// DefaultSessionFile returns the session file in the data directory
+func DefaultSessionFile() (SessionFile, error) {
+	dir, err := database.GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return SessionFile(filepath.Join(dir, "session")), nil
+}
+
+// Load returns the active user's token, empty when nobody is signed in
+func (f SessionFile) Load() (string, error) {
+	tokens, err := f.LoadAll()
+	if err != nil || len(tokens) == 0 {
+		return "", err
+	}
+	return tokens[0], nil
+}
+
+// LoadAll returns the token of every user signed in, the active user's first
+func (f SessionFile) LoadAll() ([]string, error) {
+	data, err := os.ReadFile(string(f))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+	return strings.Fields(string(data)), nil
+}
+
+// Save stores a token as the active user's, keeping the other users signed in
+func (f SessionFile) Save(token string) error {
+	tokens, err := f.LoadAll()
+	if err != nil {
+		return err
+	}
+	return f.write(append([]string{token}, slices.DeleteFunc(tokens, func(t string) bool { return t == token })...))
+}
+
+// Remove forgets a token, the next user's becoming the active one when it was the active user's
+func (f SessionFile) Remove(token string) error {
+	tokens, err := f.LoadAll()
+	if err != nil {
+		return err
+	}
+	tokens = slices.DeleteFunc(tokens, func(t string) bool { return t == token })
+	if len(tokens) == 0 {
+		return f.Clear()
+	}
+	return f.write(tokens)
+}
+
+// Clear forgets every saved token
+func (f SessionFile) Clear() error {
+	if err := os.Remove(string(f)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove session: %w", err)
+	}
+	return nil
+}
+
+// write replaces the saved tokens
+func (f SessionFile) write(tokens []string) error {
+	if err := os.MkdirAll(filepath.Dir(string(f)), 0o700); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+	if err := os.WriteFile(string(f), []byte(strings.Join(tokens, "\n")+"\n"), 0o600); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
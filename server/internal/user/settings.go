@@ -0,0 +1,210 @@
+package user
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+// DefaultSettings apply to users who haven't saved any, matching the default config
+var DefaultSettings = Settings{
+	WarningDays:  30,
+	CriticalDays: 7,
+}
+
+// Settings are a user's preferences for the TUI and notifications
+type Settings struct {
+	UserID types.UserID `db:"user_id"`
+	// Theme names a built in TUI theme, empty uses the configured colours
+	Theme string `db:"theme"`
+	// WarningDays and CriticalDays are the days before expiry a certificate shows as expiring soon and as critical
+	WarningDays  int `db:"warning_days"`
+	CriticalDays int `db:"critical_days"`
+	// NotificationChannels limit notifications without rules to these channels, empty notifies every channel
+	NotificationChannels []string `db:"notification_channels"`
+	// Timezone is the IANA name times are shown in, empty uses the local timezone
+	Timezone string `db:"timezone"`
+	// TimeDisplay chooses between dates and how long ago or until, absolute when empty
+	TimeDisplay TimeDisplay `db:"time_display"`
+	// Columns are the columns of the TUI's domain table in order, none uses the layout fitting the terminal
+	Columns   []Column  `db:"columns"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// Column is a column of the TUI's domain table and how wide it is
+type Column struct {
+	Name string
+	// Width in characters, zero uses the column's default width
+	Width int
+}
+
+// maxColumnWidth keeps a column from taking more than any terminal could show
+const maxColumnWidth = 200
+
+// ParseColumns reads a comma separated list of columns, each a name optionally followed by a colon and a width,
+// e.g. "status, expires:20, issuer"
+func ParseColumns(s string) ([]Column, error) {
+	var columns []Column
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, width, hasWidth := strings.Cut(field, ":")
+		c := Column{Name: strings.TrimSpace(name)}
+		if hasWidth {
+			w, err := strconv.Atoi(strings.TrimSpace(width))
+			if err != nil {
+				return nil, fmt.Errorf("invalid width %q for column %q", width, c.Name)
+			}
+			c.Width = w
+		}
+		columns = append(columns, c)
+	}
+	return columns, validateColumns(columns)
+}
+
+// FormatColumns writes columns the way ParseColumns reads them
+func FormatColumns(columns []Column) string {
+	fields := make([]string, len(columns))
+	for i, c := range columns {
+		fields[i] = c.Name
+		if c.Width > 0 {
+			fields[i] += ":" + strconv.Itoa(c.Width)
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+// validateColumns checks columns can be stored, which names exist is up to the TUI
+func validateColumns(columns []Column) error {
+	seen := map[string]bool{}
+	for _, c := range columns {
+		if c.Name == "" || strings.ContainsAny(c.Name, ",: ") {
+			return fmt.Errorf("invalid column %q", c.Name)
+		}
+		if c.Width < 0 || c.Width > maxColumnWidth {
+			return fmt.Errorf("width of column %q must be between 0 and %d, got %d", c.Name, maxColumnWidth, c.Width)
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("column %q is listed twice", c.Name)
+		}
+		seen[c.Name] = true
+	}
+	return nil
+}
+
+// TimeDisplay is how times are shown to a user
+type TimeDisplay string
+
+const (
+	TimeAbsolute TimeDisplay = "absolute"
+	TimeRelative TimeDisplay = "relative"
+)
+
+// ParseTimeDisplay reads a time display by name, empty meaning absolute
+func ParseTimeDisplay(s string) (TimeDisplay, error) {
+	switch TimeDisplay(s) {
+	case "", TimeAbsolute:
+		return TimeAbsolute, nil
+	case TimeRelative:
+		return TimeRelative, nil
+	default:
+		return "", fmt.Errorf("invalid time display %q, must be absolute or relative", s)
+	}
+}
+
+// Validate reports settings that can't be applied
+func (s Settings) Validate() error {
+	if s.WarningDays < 0 {
+		return fmt.Errorf("warning days must not be negative, got %d", s.WarningDays)
+	}
+	if s.CriticalDays < 0 || s.CriticalDays > s.WarningDays {
+		return fmt.Errorf("critical days (%d) must be between 0 and warning days (%d)", s.CriticalDays, s.WarningDays)
+	}
+	for _, c := range s.NotificationChannels {
+		if c == "" || strings.Contains(c, ",") {
+			return fmt.Errorf("invalid notification channel %q", c)
+		}
+	}
+	if _, err := s.Location(); err != nil {
+		return err
+	}
+	if _, err := ParseTimeDisplay(string(s.TimeDisplay)); err != nil {
+		return err
+	}
+	return validateColumns(s.Columns)
+}
+
+// Location is the timezone times are shown in
+func (s Settings) Location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", s.Timezone)
+	}
+	return loc, nil
+}
+
+// FormatTime shows t the way the user chose, in their timezone with layout or relative to now
+func (s Settings) FormatTime(t, now time.Time, layout string) string {
+	if s.TimeDisplay == TimeRelative {
+		return RelativeTime(t, now)
+	}
+	loc, err := s.Location()
+	if err != nil {
+		loc = time.Local
+	}
+	return t.In(loc).Format(layout)
+}
+
+// RelativeTime describes how long before or after now t is, such as "in 3 days" or "2 hours ago"
+func RelativeTime(t, now time.Time) string {
+	d := t.Sub(now)
+	future := d >= 0
+	if !future {
+		d = -d
+	}
+
+	var amount string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		amount = plural(int(d/time.Minute), "minute")
+	case d < 48*time.Hour:
+		amount = plural(int(d/time.Hour), "hour")
+	default:
+		amount = plural(int(d/(24*time.Hour)), "day")
+	}
+	if future {
+		return "in " + amount
+	}
+	return amount + " ago"
+}
+
+// plural counts n of a unit, adding an s unless there is one
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// joinChannels stores channels as a comma separated list
+func joinChannels(channels []string) string {
+	return strings.Join(channels, ",")
+}
+
+// splitChannels reads a list stored by joinChannels
+func splitChannels(channels string) []string {
+	if channels == "" {
+		return nil
+	}
+	return strings.Split(channels, ",")
+}
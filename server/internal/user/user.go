@@ -1,20 +1,75 @@
+// This package keeps the accounts that own domains, and the sessions they sign in with
+//
+// Until the first account registers everything belongs to the default user and nobody has to sign in
 package user
 
 import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
 	"github.com/samokw/ssl_tracker/internal/types"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// DefaultUserID owns the domains of installations without accounts, the first account to register takes it over
+const DefaultUserID = types.UserID(1)
+
+// MinPasswordLength is the shortest password an account can have
+const MinPasswordLength = 8
+
+var (
+	// ErrEmailTaken is returned when registering an email that already has an account
+	ErrEmailTaken = errors.New("an account with this email already exists")
+	// ErrInvalidCredentials is returned for an unknown email or a wrong password
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrUserNotFound is returned when looking up a user that doesn't exist
+	ErrUserNotFound = errors.New("user not found")
+	// ErrNoEmail is returned when an identity provider doesn't share an email to create an account with
+	ErrNoEmail = errors.New("identity provider shared no email address")
+)
+
 type Email string
 
+// ParseEmail normalises an email address, rejecting anything but a bare address
+func ParseEmail(email string) (Email, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", fmt.Errorf("invalid email address %q", email)
+	}
+	return Email(email), nil
+}
+
+func (e Email) String() string {
+	return string(e)
+}
+
 type Password string
 
 type WebHookUrl string
 
 type User struct {
 	UserID   types.UserID
+	Username string
 	Email    Email
-	Password Password
+	// Password is the bcrypt hash, empty for the default user before anyone registers and for single sign-on accounts
+	Password  Password
+	CreatedAt time.Time
+	// DeactivatedAt is set once the account is switched off, it can no longer sign in but keeps its data
+	DeactivatedAt *time.Time
+}
+
+// HasPassword reports whether the user can sign in
+func (u User) HasPassword() bool {
+	return u.Password != ""
+}
+
+// Active reports whether the account hasn't been deactivated
+func (u User) Active() bool {
+	return u.DeactivatedAt == nil
 }
 
 func HashPassword(password string) (string, error) {
@@ -29,3 +84,14 @@ func (u *User) ValidatePassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 	return err == nil
 }
+
+// validatePassword checks a new password is long enough and fits bcrypt's 72 byte limit
+func validatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	if len(password) > 72 {
+		return errors.New("password must be at most 72 bytes")
+	}
+	return nil
+}
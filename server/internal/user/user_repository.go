@@ -0,0 +1,278 @@
+package user
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/types"
+)
+
+type Repository struct {
+	db     *sql.DB
+	writer *database.Writer
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db:     db,
+		writer: database.NewWriter(db),
+	}
+}
+
+const selectUsers = `SELECT id, username, email, password_hash, created_at, deactivated_at FROM users`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func (r *Repository) scanUser(row scanner) (User, error) {
+	var id uint
+	var username, passwordHash string
+	var email sql.NullString
+	var createdAt time.Time
+	var deactivatedAt sql.NullTime
+
+	if err := row.Scan(&id, &username, &email, &passwordHash, &createdAt, &deactivatedAt); err != nil {
+		return User{}, err
+	}
+	u := User{
+		UserID:    types.UserID(id),
+		Username:  username,
+		Email:     Email(email.String),
+		Password:  Password(passwordHash),
+		CreatedAt: createdAt,
+	}
+	if deactivatedAt.Valid {
+		u.DeactivatedAt = &deactivatedAt.Time
+	}
+	return u, nil
+}
+
+// CreateUser stores a new account, its username defaulting to its email
+func (r *Repository) CreateUser(u *User) error {
+	if u.Username == "" {
+		u.Username = u.Email.String()
+	}
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now()
+	}
+
+	query := `INSERT INTO users (username, email, password_hash, created_at) VALUES (?, ?, ?, ?)`
+	result, err := r.writer.Exec(query, u.Username, u.Email.String(), string(u.Password), u.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	u.UserID = types.UserID(id)
+	return nil
+}
+
+// ClaimUser turns a user without a password or email, i.e. the default user, into u's account.
+//
+// Returns false when the user has been claimed by now
+func (r *Repository) ClaimUser(id types.UserID, u *User) (bool, error) {
+	if u.Username == "" {
+		u.Username = u.Email.String()
+	}
+	query := `UPDATE users SET username = ?, email = ?, password_hash = ? WHERE id = ? AND password_hash = '' AND email IS NULL`
+	result, err := r.writer.Exec(query, u.Username, u.Email.String(), string(u.Password), id.Uint())
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	claimed, err := r.GetUserByID(id)
+	if err != nil {
+		return false, err
+	}
+	*u = *claimed
+	return true, nil
+}
+
+// GetUserByID looks up a user, returning nil if there is none
+func (r *Repository) GetUserByID(id types.UserID) (*User, error) {
+	u, err := r.scanUser(r.db.QueryRow(selectUsers+` WHERE id = ?`, id.Uint()))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// GetUserByEmail looks up the account with an email, returning nil if there is none
+func (r *Repository) GetUserByEmail(email Email) (*User, error) {
+	u, err := r.scanUser(r.db.QueryRow(selectUsers+` WHERE email = ?`, email.String()))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// UpdatePassword replaces the password hash of a user
+func (r *Repository) UpdatePassword(id types.UserID, hash Password) error {
+	result, err := r.writer.Exec(`UPDATE users SET password_hash = ? WHERE id = ?`, string(hash), id.Uint())
+	if err != nil {
+		return err
+	}
+	return requireRow(result)
+}
+
+// Deactivate marks a user as deactivated and ends all of its sessions, keeping the time of an earlier deactivation
+func (r *Repository) Deactivate(id types.UserID, at time.Time) error {
+	return r.writer.Transaction(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`UPDATE users SET deactivated_at = COALESCE(deactivated_at, ?) WHERE id = ?`, at, id.Uint())
+		if err != nil {
+			return err
+		}
+		if err := requireRow(result); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`DELETE FROM user_sessions WHERE user_id = ?`, id.Uint())
+		return err
+	})
+}
+
+// requireRow returns ErrUserNotFound when a statement changed nothing
+func requireRow(result sql.Result) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// CountAccounts returns how many users have registered or signed in through single sign-on, deactivated ones included
+func (r *Repository) CountAccounts() (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM users WHERE password_hash <> '' OR email IS NOT NULL`).Scan(&count)
+	return count, err
+}
+
+// GetUserByIdentity looks up the user an identity provider's subject is linked to, returning nil if there is none
+func (r *Repository) GetUserByIdentity(issuer, subject string) (*User, error) {
+	query := selectUsers + ` WHERE id = (SELECT user_id FROM user_identities WHERE issuer = ? AND subject = ?)`
+	u, err := r.scanUser(r.db.QueryRow(query, issuer, subject))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// LinkIdentity makes an identity provider's subject sign in as a user
+func (r *Repository) LinkIdentity(id types.UserID, issuer, subject string) error {
+	query := `INSERT INTO user_identities (user_id, issuer, subject, created_at) VALUES (?, ?, ?, ?)`
+	_, err := r.writer.Exec(query, id.Uint(), issuer, subject, time.Now())
+	return err
+}
+
+// CreateSession stores a session by the hash of its token
+func (r *Repository) CreateSession(s *Session, tokenHash string) error {
+	if err := types.ValidateUserID(s.UserID); err != nil {
+		return fmt.Errorf("invalid user ID: %w", err)
+	}
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = time.Now()
+	}
+
+	query := `INSERT INTO user_sessions (user_id, token_hash, created_at, expires_at) VALUES (?, ?, ?, ?)`
+	result, err := r.writer.Exec(query, s.UserID.Uint(), tokenHash, s.CreatedAt, s.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	s.SessionID = uint(id)
+	return nil
+}
+
+// GetSessionByHash looks up a session by the hash of its token, returning nil if there is none
+func (r *Repository) GetSessionByHash(tokenHash string) (*Session, error) {
+	query := `SELECT id, user_id, created_at, expires_at FROM user_sessions WHERE token_hash = ?`
+	var id, userID uint
+	var s Session
+	err := r.db.QueryRow(query, tokenHash).Scan(&id, &userID, &s.CreatedAt, &s.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.SessionID = id
+	s.UserID = types.UserID(userID)
+	return &s, nil
+}
+
+// DeleteSession ends a session by the hash of its token
+func (r *Repository) DeleteSession(tokenHash string) error {
+	_, err := r.writer.Exec(`DELETE FROM user_sessions WHERE token_hash = ?`, tokenHash)
+	return err
+}
+
+// DeleteExpiredSessions removes sessions that ended before now
+func (r *Repository) DeleteExpiredSessions(now time.Time) (int64, error) {
+	// expires_at is written in local time and compared as text
+	result, err := r.writer.Exec(`DELETE FROM user_sessions WHERE expires_at <= ?`, now.Local())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetSettings looks up the settings a user saved, returning nil if there are none
+func (r *Repository) GetSettings(id types.UserID) (*Settings, error) {
+	query := `SELECT theme, warning_days, critical_days, notification_channels, timezone, time_display, columns, updated_at FROM user_settings WHERE user_id = ?`
+	var channels, display, columns string
+	s := Settings{UserID: id}
+	err := r.db.QueryRow(query, id.Uint()).Scan(&s.Theme, &s.WarningDays, &s.CriticalDays, &channels, &s.Timezone, &display, &columns, &s.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.NotificationChannels = splitChannels(channels)
+	s.TimeDisplay = TimeDisplay(display)
+	if s.Columns, err = ParseColumns(columns); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// SaveSettings replaces the settings of a user
+func (r *Repository) SaveSettings(s *Settings) error {
+	if s.UpdatedAt.IsZero() {
+		s.UpdatedAt = time.Now()
+	}
+	return r.writer.Transaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM user_settings WHERE user_id = ?`, s.UserID.Uint()); err != nil {
+			return err
+		}
+		query := `INSERT INTO user_settings (user_id, theme, warning_days, critical_days, notification_channels, timezone, time_display, columns, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		_, err := tx.Exec(query, s.UserID.Uint(), s.Theme, s.WarningDays, s.CriticalDays, joinChannels(s.NotificationChannels), s.Timezone, string(s.TimeDisplay), FormatColumns(s.Columns), s.UpdatedAt)
+		return err
+	})
+}
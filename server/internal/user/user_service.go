@@ -0,0 +1,332 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/types"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Service struct {
+	userRepo *Repository
+	defaults Settings
+	now      func() time.Time
+}
+
+func NewService(userRepo *Repository) *Service {
+	return &Service{
+		userRepo: userRepo,
+		defaults: DefaultSettings,
+		now:      time.Now,
+	}
+}
+
+// SetDefaultSettings changes the settings of users who haven't saved any, DefaultSettings unless set
+func (s *Service) SetDefaultSettings(defaults Settings) {
+	s.defaults = defaults
+}
+
+// DefaultSettings returns the settings of users who haven't saved any
+func (s *Service) DefaultSettings() Settings {
+	return s.defaults
+}
+
+// GetSettings returns a user's settings, the defaults until the user saves some
+func (s *Service) GetSettings(id types.UserID) (*Settings, error) {
+	settings, err := s.userRepo.GetSettings(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+	if settings == nil {
+		defaults := s.defaults
+		defaults.UserID = id
+		return &defaults, nil
+	}
+	return settings, nil
+}
+
+// SaveSettings validates and stores a user's settings
+func (s *Service) SaveSettings(settings *Settings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	settings.UpdatedAt = s.now()
+	if err := s.userRepo.SaveSettings(settings); err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+	return nil
+}
+
+// RequiresLogin reports whether anyone registered, until then everything belongs to the default user
+func (s *Service) RequiresLogin() (bool, error) {
+	count, err := s.userRepo.CountAccounts()
+	if err != nil {
+		return false, fmt.Errorf("failed to count accounts: %w", err)
+	}
+	return count > 0, nil
+}
+
+// GetUser looks up a user by ID
+func (s *Service) GetUser(id types.UserID) (*User, error) {
+	u, err := s.userRepo.GetUserByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if u == nil {
+		return nil, fmt.Errorf("user with ID %d: %w", id, ErrUserNotFound)
+	}
+	return u, nil
+}
+
+// GetUserByEmail looks up the account with an email, returning ErrUserNotFound if there is none
+func (s *Service) GetUserByEmail(email string) (*User, error) {
+	addr, err := ParseEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.userRepo.GetUserByEmail(addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if u == nil {
+		return nil, ErrUserNotFound
+	}
+	return u, nil
+}
+
+// Register creates an account.
+//
+// The first account takes over the default user, keeping the domains tracked before anyone registered
+func (s *Service) Register(email, password string) (*User, error) {
+	addr, err := ParseEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePassword(password); err != nil {
+		return nil, err
+	}
+	existing, err := s.userRepo.GetUserByEmail(addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up account: %w", err)
+	}
+	if existing != nil {
+		return nil, ErrEmailTaken
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	u := User{Email: addr, Password: Password(hash)}
+
+	claimed, err := s.userRepo.ClaimUser(DefaultUserID, &u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store account: %w", err)
+	}
+	if !claimed {
+		if err := s.userRepo.CreateUser(&u); err != nil {
+			return nil, fmt.Errorf("failed to store account: %w", err)
+		}
+	}
+	slog.Info("Account registered", "user_id", u.UserID.Uint(), "email", u.Email.String())
+	return &u, nil
+}
+
+// Authenticate checks an email and password, returning ErrInvalidCredentials if they don't match an account
+func (s *Service) Authenticate(email, password string) (*User, error) {
+	addr, err := ParseEmail(email)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	u, err := s.userRepo.GetUserByEmail(addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up account: %w", err)
+	}
+	if u == nil || !u.HasPassword() || !u.Active() {
+		// Take as long as a wrong password so response times don't reveal which emails have accounts
+		bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if !u.ValidatePassword(password) {
+		return nil, ErrInvalidCredentials
+	}
+	return u, nil
+}
+
+// ResolveIdentity returns the user an identity provider vouched for, creating one the first time a subject signs in.
+//
+// A new subject is linked to the account with the same email when the provider verified it,
+// otherwise it gets an account of its own, the first of which takes over the default user like Register does
+func (s *Service) ResolveIdentity(issuer, subject, email string, emailVerified bool) (*User, error) {
+	u, err := s.userRepo.GetUserByIdentity(issuer, subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up identity: %w", err)
+	}
+	if u != nil {
+		if !u.Active() {
+			return nil, ErrInvalidCredentials
+		}
+		return u, nil
+	}
+
+	addr, err := ParseEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNoEmail, err)
+	}
+	existing, err := s.userRepo.GetUserByEmail(addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up account: %w", err)
+	}
+	switch {
+	case existing != nil && !emailVerified:
+		return nil, ErrEmailTaken
+	case existing != nil:
+		if !existing.Active() {
+			return nil, ErrInvalidCredentials
+		}
+		u = existing
+	default:
+		u = &User{Email: addr}
+		claimed, err := s.userRepo.ClaimUser(DefaultUserID, u)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store account: %w", err)
+		}
+		if !claimed {
+			if err := s.userRepo.CreateUser(u); err != nil {
+				return nil, fmt.Errorf("failed to store account: %w", err)
+			}
+		}
+	}
+
+	if err := s.userRepo.LinkIdentity(u.UserID, issuer, subject); err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+	slog.Info("Identity linked", "user_id", u.UserID.Uint(), "issuer", issuer, "email", u.Email.String())
+	return u, nil
+}
+
+// Login authenticates a user and starts a session lasting lifetime, DefaultSessionLifetime when zero.
+//
+// Returns the session token, which can't be recovered later
+func (s *Service) Login(email, password string, lifetime time.Duration) (*User, string, error) {
+	u, err := s.Authenticate(email, password)
+	if err != nil {
+		return nil, "", err
+	}
+	token, _, err := s.StartSession(u.UserID, lifetime)
+	if err != nil {
+		return nil, "", err
+	}
+	return u, token, nil
+}
+
+// StartSession signs a user in for lifetime, DefaultSessionLifetime when zero.
+//
+// Returns the session token, which can't be recovered later, along with the stored session
+func (s *Service) StartSession(userID types.UserID, lifetime time.Duration) (string, *Session, error) {
+	if lifetime <= 0 {
+		lifetime = DefaultSessionLifetime
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return "", nil, err
+	}
+	now := s.now()
+	session := Session{UserID: userID, CreatedAt: now, ExpiresAt: now.Add(lifetime)}
+	if err := s.userRepo.CreateSession(&session, hashToken(token)); err != nil {
+		return "", nil, fmt.Errorf("failed to store session: %w", err)
+	}
+	if _, err := s.userRepo.DeleteExpiredSessions(now); err != nil {
+		slog.Warn("Failed to remove expired sessions", "error", err)
+	}
+	return token, &session, nil
+}
+
+// RefreshSession swaps a valid session token for a new one lasting lifetime, ending the old session.
+//
+// Returns ErrInvalidSession if the token can't be used anymore
+func (s *Service) RefreshSession(token string, lifetime time.Duration) (string, *Session, error) {
+	u, err := s.ResumeSession(token)
+	if err != nil {
+		return "", nil, err
+	}
+	newToken, session, err := s.StartSession(u.UserID, lifetime)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := s.Logout(token); err != nil {
+		return "", nil, fmt.Errorf("failed to end old session: %w", err)
+	}
+	return newToken, session, nil
+}
+
+// ResumeSession returns the user a session token belongs to, or ErrInvalidSession
+func (s *Service) ResumeSession(token string) (*User, error) {
+	session, err := s.userRepo.GetSessionByHash(hashToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up session: %w", err)
+	}
+	if session == nil || session.Expired(s.now()) {
+		return nil, ErrInvalidSession
+	}
+	u, err := s.userRepo.GetUserByID(session.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if u == nil || !u.Active() {
+		return nil, ErrInvalidSession
+	}
+	return u, nil
+}
+
+// ChangePassword replaces the password of an account after checking its current one
+func (s *Service) ChangePassword(id types.UserID, current, password string) error {
+	u, err := s.GetUser(id)
+	if err != nil {
+		return err
+	}
+	if !u.HasPassword() || !u.ValidatePassword(current) {
+		return ErrInvalidCredentials
+	}
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	if err := s.userRepo.UpdatePassword(id, Password(hash)); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	slog.Info("Password changed", "user_id", id.Uint())
+	return nil
+}
+
+// Deactivate switches off an account and signs it out everywhere, its domains and history are kept
+func (s *Service) Deactivate(id types.UserID) error {
+	if err := s.userRepo.Deactivate(id, s.now()); err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to deactivate user: %w", err)
+	}
+	slog.Info("Account deactivated", "user_id", id.Uint())
+	return nil
+}
+
+// Logout ends a session, unknown tokens are ignored
+func (s *Service) Logout(token string) error {
+	return s.userRepo.DeleteSession(hashToken(token))
+}
+
+// dummyHash is compared against when there is no account to check a password against
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("sslcerttop"), bcrypt.DefaultCost)
+	return hash
+})
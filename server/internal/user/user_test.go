@@ -0,0 +1,353 @@
+package user
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestService creates a user service backed by a fresh SQLite database.
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return NewService(NewRepository(db))
+}
+
+// TestParseEmail - addresses are normalised and display names rejected.
+func TestParseEmail(t *testing.T) {
+	email, err := ParseEmail("  Alice@Example.com ")
+	require.NoError(t, err)
+	assert.Equal(t, Email("alice@example.com"), email)
+
+	for _, bad := range []string{"", "alice", "Alice <alice@example.com>", "alice@"} {
+		_, err := ParseEmail(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+// TestService_Register - the first account takes over the default user, later ones get their own.
+func TestService_Register(t *testing.T) {
+	s := newTestService(t)
+
+	required, err := s.RequiresLogin()
+	require.NoError(t, err)
+	assert.False(t, required)
+
+	first, err := s.Register("alice@example.com", "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultUserID, first.UserID)
+	assert.Equal(t, "alice@example.com", first.Username)
+	assert.NotEqual(t, Password("correct horse"), first.Password)
+
+	second, err := s.Register("Bob@example.com", "battery staple")
+	require.NoError(t, err)
+	assert.NotEqual(t, DefaultUserID, second.UserID)
+	assert.Equal(t, Email("bob@example.com"), second.Email)
+
+	required, err = s.RequiresLogin()
+	require.NoError(t, err)
+	assert.True(t, required)
+
+	_, err = s.Register("BOB@example.com", "another password")
+	assert.ErrorIs(t, err, ErrEmailTaken)
+	_, err = s.Register("carol@example.com", "short")
+	assert.Error(t, err)
+	_, err = s.Register("not an email", "long enough")
+	assert.Error(t, err)
+}
+
+// TestService_Authenticate - only the right password for a known email signs in.
+func TestService_Authenticate(t *testing.T) {
+	s := newTestService(t)
+	_, err := s.Register("alice@example.com", "correct horse")
+	require.NoError(t, err)
+
+	u, err := s.Authenticate("ALICE@example.com", "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultUserID, u.UserID)
+
+	_, err = s.Authenticate("alice@example.com", "wrong horse")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = s.Authenticate("nobody@example.com", "correct horse")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+// TestService_Sessions - a login token resumes the session until it expires or the user logs out.
+func TestService_Sessions(t *testing.T) {
+	s := newTestService(t)
+	_, err := s.Register("alice@example.com", "correct horse")
+	require.NoError(t, err)
+	bob, err := s.Register("bob@example.com", "battery staple")
+	require.NoError(t, err)
+
+	u, token, err := s.Login("bob@example.com", "battery staple", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, bob.UserID, u.UserID)
+	assert.True(t, strings.HasPrefix(token, tokenPrefix))
+
+	resumed, err := s.ResumeSession(token)
+	require.NoError(t, err)
+	assert.Equal(t, bob.UserID, resumed.UserID)
+
+	_, err = s.ResumeSession("scs_unknown")
+	assert.ErrorIs(t, err, ErrInvalidSession)
+
+	now := time.Now()
+	s.now = func() time.Time { return now.Add(2 * time.Hour) }
+	_, err = s.ResumeSession(token)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+	s.now = time.Now
+
+	_, token, err = s.Login("bob@example.com", "battery staple", 0)
+	require.NoError(t, err)
+	require.NoError(t, s.Logout(token))
+	_, err = s.ResumeSession(token)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+
+	_, _, err = s.Login("bob@example.com", "wrong", 0)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+// TestService_RefreshSession - refreshing issues a new token and ends the old one.
+func TestService_RefreshSession(t *testing.T) {
+	s := newTestService(t)
+	alice, err := s.Register("alice@example.com", "correct horse")
+	require.NoError(t, err)
+	_, token, err := s.Login("alice@example.com", "correct horse", time.Hour)
+	require.NoError(t, err)
+
+	refreshed, session, err := s.RefreshSession(token, 2*time.Hour)
+	require.NoError(t, err)
+	assert.NotEqual(t, token, refreshed)
+	assert.Equal(t, alice.UserID, session.UserID)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), session.ExpiresAt, time.Minute)
+
+	_, err = s.ResumeSession(token)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+	_, _, err = s.RefreshSession(token, time.Hour)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+
+	u, err := s.ResumeSession(refreshed)
+	require.NoError(t, err)
+	assert.Equal(t, alice.UserID, u.UserID)
+}
+
+// TestService_ResolveIdentity - subjects get an account on first sign in and keep it, verified emails join existing ones.
+func TestService_ResolveIdentity(t *testing.T) {
+	s := newTestService(t)
+	const issuer = "https://issuer.example.com"
+
+	first, err := s.ResolveIdentity(issuer, "sub-1", "Carol@example.com", false)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultUserID, first.UserID, "the first account takes over the default user")
+	assert.Equal(t, Email("carol@example.com"), first.Email)
+	required, err := s.RequiresLogin()
+	require.NoError(t, err)
+	assert.True(t, required)
+
+	again, err := s.ResolveIdentity(issuer, "sub-1", "changed@example.com", true)
+	require.NoError(t, err)
+	assert.Equal(t, first.UserID, again.UserID)
+
+	alice, err := s.Register("alice@example.com", "correct horse")
+	require.NoError(t, err)
+	_, err = s.ResolveIdentity(issuer, "sub-2", "alice@example.com", false)
+	assert.ErrorIs(t, err, ErrEmailTaken)
+	linked, err := s.ResolveIdentity(issuer, "sub-2", "alice@example.com", true)
+	require.NoError(t, err)
+	assert.Equal(t, alice.UserID, linked.UserID)
+
+	_, err = s.ResolveIdentity(issuer, "sub-3", "", true)
+	assert.ErrorIs(t, err, ErrNoEmail)
+
+	require.NoError(t, s.Deactivate(alice.UserID))
+	_, err = s.ResolveIdentity(issuer, "sub-2", "alice@example.com", true)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+// TestService_GetUserByEmail - lookups ignore case and report unknown emails.
+func TestService_GetUserByEmail(t *testing.T) {
+	s := newTestService(t)
+	alice, err := s.Register("alice@example.com", "correct horse")
+	require.NoError(t, err)
+
+	u, err := s.GetUserByEmail(" Alice@Example.com")
+	require.NoError(t, err)
+	assert.Equal(t, alice.UserID, u.UserID)
+
+	_, err = s.GetUserByEmail("nobody@example.com")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	_, err = s.GetUserByEmail("not an email")
+	assert.Error(t, err)
+	_, err = s.GetUser(42)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+// TestService_ChangePassword - the current password is required and the old one stops working.
+func TestService_ChangePassword(t *testing.T) {
+	s := newTestService(t)
+	alice, err := s.Register("alice@example.com", "correct horse")
+	require.NoError(t, err)
+
+	err = s.ChangePassword(alice.UserID, "wrong horse", "battery staple")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	err = s.ChangePassword(alice.UserID, "correct horse", "short")
+	assert.Error(t, err)
+
+	require.NoError(t, s.ChangePassword(alice.UserID, "correct horse", "battery staple"))
+	_, err = s.Authenticate("alice@example.com", "correct horse")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = s.Authenticate("alice@example.com", "battery staple")
+	assert.NoError(t, err)
+
+	err = s.ChangePassword(42, "correct horse", "battery staple")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+// TestService_Deactivate - deactivated accounts are signed out and can't sign in again.
+func TestService_Deactivate(t *testing.T) {
+	s := newTestService(t)
+	alice, err := s.Register("alice@example.com", "correct horse")
+	require.NoError(t, err)
+	_, token, err := s.Login("alice@example.com", "correct horse", 0)
+	require.NoError(t, err)
+
+	require.NoError(t, s.Deactivate(alice.UserID))
+	require.NoError(t, s.Deactivate(alice.UserID))
+
+	u, err := s.GetUser(alice.UserID)
+	require.NoError(t, err)
+	assert.False(t, u.Active())
+
+	_, err = s.ResumeSession(token)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+	_, err = s.Authenticate("alice@example.com", "correct horse")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	required, err := s.RequiresLogin()
+	require.NoError(t, err)
+	assert.True(t, required)
+
+	assert.ErrorIs(t, s.Deactivate(42), ErrUserNotFound)
+}
+
+// TestService_Settings - users see the defaults until they save settings of their own.
+func TestService_Settings(t *testing.T) {
+	s := newTestService(t)
+	s.SetDefaultSettings(Settings{WarningDays: 21, CriticalDays: 5})
+
+	settings, err := s.GetSettings(DefaultUserID)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultUserID, settings.UserID)
+	assert.Equal(t, 21, settings.WarningDays)
+	assert.Equal(t, 5, settings.CriticalDays)
+
+	settings.Theme = "light"
+	settings.WarningDays = 45
+	settings.NotificationChannels = []string{"slack", "email"}
+	settings.Timezone = "Europe/Berlin"
+	settings.TimeDisplay = TimeRelative
+	settings.Columns, err = ParseColumns("status, expires:20, issuer")
+	require.NoError(t, err)
+	require.NoError(t, s.SaveSettings(settings))
+
+	saved, err := s.GetSettings(DefaultUserID)
+	require.NoError(t, err)
+	assert.Equal(t, "light", saved.Theme)
+	assert.Equal(t, 45, saved.WarningDays)
+	assert.Equal(t, 5, saved.CriticalDays)
+	assert.Equal(t, []string{"slack", "email"}, saved.NotificationChannels)
+	loc, err := saved.Location()
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/Berlin", loc.String())
+	assert.Equal(t, TimeRelative, saved.TimeDisplay)
+	assert.Equal(t, []Column{{Name: "status"}, {Name: "expires", Width: 20}, {Name: "issuer"}}, saved.Columns)
+
+	saved.NotificationChannels = nil
+	require.NoError(t, s.SaveSettings(saved))
+	saved, err = s.GetSettings(DefaultUserID)
+	require.NoError(t, err)
+	assert.Empty(t, saved.NotificationChannels)
+
+	for _, bad := range []Settings{
+		{UserID: DefaultUserID, WarningDays: 7, CriticalDays: 30},
+		{UserID: DefaultUserID, WarningDays: 30, CriticalDays: -1},
+		{UserID: DefaultUserID, WarningDays: 30, CriticalDays: 7, Timezone: "Mars/Olympus_Mons"},
+		{UserID: DefaultUserID, WarningDays: 30, CriticalDays: 7, NotificationChannels: []string{""}},
+		{UserID: DefaultUserID, WarningDays: 30, CriticalDays: 7, TimeDisplay: "sometimes"},
+		{UserID: DefaultUserID, WarningDays: 30, CriticalDays: 7, Columns: []Column{{Name: "status"}, {Name: "status"}}},
+		{UserID: DefaultUserID, WarningDays: 30, CriticalDays: 7, Columns: []Column{{Name: "issuer", Width: -1}}},
+	} {
+		assert.Error(t, s.SaveSettings(&bad), "%+v", bad)
+	}
+}
+
+// TestSettings_FormatTime - times show in the user's timezone or relative to now.
+func TestSettings_FormatTime(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	expiry := now.Add(72*time.Hour + 30*time.Minute)
+
+	absolute := Settings{Timezone: "Asia/Tokyo"}
+	assert.Equal(t, "2026-03-04 21:30 JST", absolute.FormatTime(expiry, now, "2006-01-02 15:04 MST"))
+
+	relative := Settings{Timezone: "Asia/Tokyo", TimeDisplay: TimeRelative}
+	assert.Equal(t, "in 3 days", relative.FormatTime(expiry, now, "2006-01-02"))
+	assert.Equal(t, "1 hour ago", relative.FormatTime(now.Add(-time.Hour), now, "2006-01-02"))
+	assert.Equal(t, "in 5 minutes", relative.FormatTime(now.Add(5*time.Minute), now, "2006-01-02"))
+	assert.Equal(t, "just now", relative.FormatTime(now, now, "2006-01-02"))
+}
+
+// TestSessionFile - the token survives a restart and is only readable by its owner.
+func TestSessionFile(t *testing.T) {
+	f := SessionFile(filepath.Join(t.TempDir(), "sslcerttop", "session"))
+
+	token, err := f.Load()
+	require.NoError(t, err)
+	assert.Empty(t, token)
+
+	require.NoError(t, f.Save("scs_abc"))
+	info, err := os.Stat(string(f))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	token, err = f.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "scs_abc", token)
+
+	require.NoError(t, f.Clear())
+	require.NoError(t, f.Clear())
+	token, err = f.Load()
+	require.NoError(t, err)
+	assert.Empty(t, token)
+}
+
+// TestSessionFile_SeveralUsers - signing in keeps the others signed in, the latest active.
+func TestSessionFile_SeveralUsers(t *testing.T) {
+	f := SessionFile(filepath.Join(t.TempDir(), "session"))
+
+	require.NoError(t, f.Save("scs_a"))
+	require.NoError(t, f.Save("scs_b"))
+	require.NoError(t, f.Save("scs_a"))
+	tokens, err := f.LoadAll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"scs_a", "scs_b"}, tokens)
+
+	require.NoError(t, f.Remove("scs_a"))
+	token, err := f.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "scs_b", token)
+
+	require.NoError(t, f.Remove("scs_b"))
+	_, err = os.Stat(string(f))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,147 @@
+package webconfig
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// apacheParser walks an Apache configuration and the files it includes
+type apacheParser struct {
+	root  string
+	sites []Site
+	// vhost is the VirtualHost being read, nil outside of one
+	vhost *apacheVHost
+}
+
+type apacheVHost struct {
+	site  Site
+	tls   bool
+	certs []string
+}
+
+// parseApache reads the virtual hosts with SSLEngine on, relative paths are resolved against ServerRoot, or the
+// directory of the main configuration until it is set
+func parseApache(path string) ([]Site, error) {
+	p := &apacheParser{root: filepath.Dir(path)}
+	if err := p.read(path, 0); err != nil {
+		return nil, err
+	}
+	return p.sites, nil
+}
+
+func (p *apacheParser) read(path string, depth int) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("%s: includes nested too deep", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		start := lineNo
+		// A trailing backslash continues the directive on the next line
+		for strings.HasSuffix(line, "\\") && scanner.Scan() {
+			lineNo++
+			line = strings.TrimSuffix(line, "\\") + " " + strings.TrimSpace(scanner.Text())
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := splitApache(line)
+		name := strings.ToLower(fields[0])
+		args := fields[1:]
+		switch {
+		case name == "<virtualhost":
+			p.vhost = &apacheVHost{site: Site{Source: source(path, start)}}
+		case name == "</virtualhost>":
+			if v := p.vhost; v != nil && v.tls {
+				for _, cert := range v.certs {
+					v.site.addCertFile(p.root, cert)
+				}
+				p.sites = append(p.sites, v.site)
+			}
+			p.vhost = nil
+		case name == "serverroot" && len(args) == 1:
+			p.root = strings.Trim(args[0], `"'`)
+		case (name == "include" || name == "includeoptional") && len(args) == 1:
+			files, err := includeFiles(p.root, args[0])
+			if err != nil {
+				if name == "includeoptional" {
+					continue
+				}
+				return err
+			}
+			if len(files) == 0 && name == "include" && !strings.ContainsAny(args[0], "*?[") {
+				return fmt.Errorf("%s:%d: included file %s not found", path, start, args[0])
+			}
+			for _, file := range files {
+				if err := p.read(file, depth+1); err != nil {
+					return err
+				}
+			}
+		case p.vhost == nil:
+		case name == "servername" && len(args) > 0:
+			p.vhost.site.addHostname(stripScheme(args[0]))
+		case name == "serveralias":
+			for _, alias := range args {
+				p.vhost.site.addHostname(alias)
+			}
+		case name == "sslengine" && len(args) == 1:
+			p.vhost.tls = strings.EqualFold(args[0], "on")
+		case name == "sslcertificatefile" && len(args) == 1:
+			p.vhost.certs = append(p.vhost.certs, args[0])
+		}
+	}
+	return scanner.Err()
+}
+
+// splitApache splits a directive into its name and arguments, keeping quoted arguments together
+func splitApache(line string) []string {
+	line = strings.TrimSuffix(line, ">")
+	if strings.HasPrefix(line, "</") {
+		return []string{strings.ToLower(line) + ">"}
+	}
+
+	var fields []string
+	var b strings.Builder
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			b.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ' ' || r == '\t':
+			if b.Len() > 0 {
+				fields = append(fields, b.String())
+				b.Reset()
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() > 0 {
+		fields = append(fields, b.String())
+	}
+	return fields
+}
+
+// stripScheme removes the scheme ServerName allows in front of the hostname
+func stripScheme(name string) string {
+	if i := strings.Index(name, "://"); i >= 0 {
+		return name[i+3:]
+	}
+	return name
+}
@@ -0,0 +1,154 @@
+package webconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// caddyLine is a line of a Caddyfile split into tokens
+type caddyLine struct {
+	tokens []string
+	line   int
+}
+
+// parseCaddy reads the site blocks of a Caddyfile. Every site address not starting with http:// is served over
+// HTTPS, and a site's certificate file is only known when tls names one
+func parseCaddy(path string) ([]Site, error) {
+	var sites []Site
+	if err := readCaddy(path, 0, &sites); err != nil {
+		return nil, err
+	}
+	return sites, nil
+}
+
+func readCaddy(path string, depth int, sites *[]Site) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("%s: imports nested too deep", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	lines := tokenizeCaddy(string(data))
+	dir := filepath.Dir(path)
+
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		first, last := l.tokens[0], l.tokens[len(l.tokens)-1]
+		switch {
+		case first == "{" || strings.HasPrefix(first, "("):
+			// The global options block and snippets hold no sites
+			if last == "{" {
+				i = caddyBlockEnd(lines, i)
+			}
+			continue
+		case first == "import" && len(l.tokens) > 1:
+			files, err := includeFiles(dir, l.tokens[1])
+			if err != nil {
+				return err
+			}
+			for _, f := range files {
+				if err := readCaddy(f, depth+1, sites); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		site := Site{Source: source(path, l.line)}
+		addresses := l.tokens
+		var body []caddyLine
+		if last == "{" {
+			end := caddyBlockEnd(lines, i)
+			addresses = addresses[:len(addresses)-1]
+			body = lines[i+1 : end]
+			i = end
+		} else {
+			// A Caddyfile with a single site may leave out the braces
+			body = lines[i+1:]
+			i = len(lines)
+		}
+
+		for _, address := range addresses {
+			for _, a := range strings.Split(address, ",") {
+				if a == "" || strings.HasPrefix(a, "http://") {
+					continue
+				}
+				a = strings.TrimPrefix(a, "https://")
+				a, _, _ = strings.Cut(a, "/")
+				site.addHostname(a)
+			}
+		}
+		for _, b := range body {
+			// tls <cert_file> <key_file>, the one argument forms name an email address or internal
+			if b.tokens[0] == "tls" && len(b.tokens) >= 3 && b.tokens[1] != "{" && b.tokens[2] != "{" {
+				site.addCertFile(dir, b.tokens[1])
+			}
+		}
+		if len(site.Hostnames) > 0 || len(site.CertFiles) > 0 {
+			*sites = append(*sites, site)
+		}
+	}
+	return nil
+}
+
+// caddyBlockEnd returns the index of the line closing the block opened on line start
+func caddyBlockEnd(lines []caddyLine, start int) int {
+	depth := 0
+	for i := start; i < len(lines); i++ {
+		for _, t := range lines[i].tokens {
+			switch t {
+			case "{":
+				depth++
+			case "}":
+				depth--
+			}
+		}
+		if depth <= 0 {
+			return i
+		}
+	}
+	return len(lines) - 1
+}
+
+// tokenizeCaddy splits a Caddyfile into lines of tokens, dropping comments and blank lines
+func tokenizeCaddy(data string) []caddyLine {
+	var lines []caddyLine
+	for n, text := range strings.Split(data, "\n") {
+		var tokens []string
+		var b strings.Builder
+		var quote rune
+		inToken := false
+	scan:
+		for _, r := range text {
+			switch {
+			case quote != 0 && r == quote:
+				quote = 0
+			case quote != 0:
+				b.WriteRune(r)
+			case r == '"' || r == '`':
+				quote, inToken = r, true
+			case r == '#' && !inToken:
+				break scan
+			case r == ' ' || r == '\t' || r == '\r':
+				if inToken {
+					tokens = append(tokens, b.String())
+					b.Reset()
+					inToken = false
+				}
+			default:
+				b.WriteRune(r)
+				inToken = true
+			}
+		}
+		if inToken {
+			tokens = append(tokens, b.String())
+		}
+		if len(tokens) > 0 {
+			lines = append(lines, caddyLine{tokens: tokens, line: n + 1})
+		}
+	}
+	return lines
+}
@@ -0,0 +1,157 @@
+package webconfig
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// haproxyHostFetches are the sample fetches that match on the requested hostname
+var haproxyHostFetches = map[string]bool{
+	"hdr(host)":     true,
+	"hdr_dom(host)": true,
+	"hdr_end(host)": true,
+	"req.hdr(host)": true,
+	"ssl_fc_sni":    true,
+	"req.ssl_sni":   true,
+	"req_ssl_sni":   true,
+}
+
+// haproxySections start a new section of the configuration
+var haproxySections = map[string]bool{
+	"global": true, "defaults": true, "frontend": true, "backend": true, "listen": true, "peers": true,
+	"resolvers": true, "userlist": true, "cache": true, "program": true, "http-errors": true, "ring": true,
+	"mailers": true,
+}
+
+// parseHAProxy reads the frontends and listen sections that bind with ssl. The certificates are those given to
+// crt, which may be directories, and the files named in crt-list files. Hostnames come from ACLs matching the
+// Host header or SNI
+func parseHAProxy(path string) ([]Site, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dir := filepath.Dir(path)
+	crtBase := dir
+	var sites []Site
+	var current *Site
+	tls := false
+	flush := func() {
+		if current != nil && tls {
+			sites = append(sites, *current)
+		}
+		current, tls = nil, false
+	}
+
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if haproxySections[fields[0]] {
+			flush()
+			if fields[0] == "frontend" || fields[0] == "listen" {
+				current = &Site{Source: source(path, lineNo)}
+			}
+			continue
+		}
+		if fields[0] == "crt-base" && len(fields) == 2 {
+			crtBase = fields[1]
+			if !filepath.IsAbs(crtBase) {
+				crtBase = filepath.Join(dir, crtBase)
+			}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		if fields[0] == "bind" {
+			for i, field := range fields {
+				switch {
+				case field == "ssl":
+					tls = true
+				case field == "crt" && i+1 < len(fields):
+					current.addCertFile(crtBase, fields[i+1])
+				case field == "crt-list" && i+1 < len(fields):
+					for _, cert := range readCrtList(dir, fields[i+1]) {
+						current.addCertFile(crtBase, cert)
+					}
+				}
+			}
+			continue
+		}
+		for _, host := range haproxyHostnames(fields) {
+			current.addHostname(host)
+		}
+	}
+	flush()
+	return sites, scanner.Err()
+}
+
+// haproxyHostnames returns the values matched against a hostname fetch anywhere in a line, in named ACLs as well
+// as anonymous ones between braces
+func haproxyHostnames(fields []string) []string {
+	var hosts []string
+	for i := 0; i < len(fields); i++ {
+		// Converters such as ,lower don't change what is matched
+		fetch, _, _ := strings.Cut(strings.ToLower(fields[i]), ",")
+		if !haproxyHostFetches[fetch] {
+			continue
+		}
+		exact := true
+		for i++; i < len(fields); i++ {
+			field := fields[i]
+			if field == "}" || field == "||" || field == "or" || field == "!" || field == "if" || field == "unless" {
+				break
+			}
+			if strings.HasPrefix(field, "-") {
+				// -m and -f take a value, -f naming a file of patterns that isn't read
+				if (field == "-m" || field == "-f") && i+1 < len(fields) {
+					i++
+					if field == "-m" {
+						// Patterns matched on part of the name aren't hostnames
+						exact = fields[i] == "str" || fields[i] == "dom" || fields[i] == "end"
+					}
+				}
+				continue
+			}
+			if exact {
+				hosts = append(hosts, field)
+			}
+		}
+	}
+	return hosts
+}
+
+// readCrtList returns the certificate of each line of a crt-list file, unreadable files give none
+func readCrtList(dir, path string) []string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var certs []string
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		certs = append(certs, fields[0])
+	}
+	return certs
+}
@@ -0,0 +1,237 @@
+package webconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// directive is a statement of an nginx configuration, with the statements of its block if it has one
+type directive struct {
+	name  string
+	args  []string
+	block []directive
+	file  string
+	line  int
+}
+
+// parseNginx reads the server blocks that listen with TLS, relative paths are resolved against the directory
+// of the main configuration like nginx's own prefix
+func parseNginx(path string) ([]Site, error) {
+	dir := filepath.Dir(path)
+	directives, err := readNginx(path, dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	var sites []Site
+	nginxSites(directives, dir, nil, &sites)
+	return sites, nil
+}
+
+// nginxSites collects the TLS servers in a block, certificates set around them apply unless they set their own
+func nginxSites(directives []directive, dir string, inherited []string, sites *[]Site) {
+	certs := inherited
+	if own := nginxArgs(directives, "ssl_certificate"); len(own) > 0 {
+		certs = own
+	}
+
+	for _, d := range directives {
+		if d.block == nil {
+			continue
+		}
+		if d.name != "server" {
+			nginxSites(d.block, dir, certs, sites)
+			continue
+		}
+
+		tls := false
+		for _, sd := range d.block {
+			switch {
+			case sd.name == "listen":
+				for _, arg := range sd.args {
+					if arg == "ssl" || arg == "quic" {
+						tls = true
+					}
+				}
+			case sd.name == "ssl" && len(sd.args) == 1 && sd.args[0] == "on":
+				tls = true
+			}
+		}
+		if !tls {
+			continue
+		}
+
+		site := Site{Source: source(d.file, d.line)}
+		for _, name := range nginxArgs(d.block, "server_name") {
+			site.addHostname(name)
+		}
+		serverCerts := certs
+		if own := nginxArgs(d.block, "ssl_certificate"); len(own) > 0 {
+			serverCerts = own
+		}
+		for _, cert := range serverCerts {
+			site.addCertFile(dir, cert)
+		}
+		*sites = append(*sites, site)
+	}
+}
+
+// nginxArgs returns the arguments of every simple directive called name in a block
+func nginxArgs(directives []directive, name string) []string {
+	var args []string
+	for _, d := range directives {
+		if d.name == name && d.block == nil {
+			args = append(args, d.args...)
+		}
+	}
+	return args
+}
+
+// readNginx parses a file, replacing include directives with what they include
+func readNginx(path, dir string, depth int) ([]directive, error) {
+	if depth > maxIncludeDepth {
+		return nil, fmt.Errorf("%s: includes nested too deep", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &nginxParser{tokens: tokenizeNginx(string(data)), file: path}
+	directives, err := p.block(false)
+	if err != nil {
+		return nil, err
+	}
+	return expandNginxIncludes(directives, dir, depth)
+}
+
+func expandNginxIncludes(directives []directive, dir string, depth int) ([]directive, error) {
+	var out []directive
+	for _, d := range directives {
+		if d.name == "include" && d.block == nil && len(d.args) == 1 {
+			files, err := includeFiles(dir, d.args[0])
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range files {
+				included, err := readNginx(f, dir, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, included...)
+			}
+			continue
+		}
+		if d.block != nil {
+			block, err := expandNginxIncludes(d.block, dir, depth)
+			if err != nil {
+				return nil, err
+			}
+			d.block = block
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// token is a word or one of { } ; with the line it starts on
+type token struct {
+	text   string
+	line   int
+	quoted bool
+}
+
+// tokenizeNginx splits a configuration into words and punctuation, dropping comments
+func tokenizeNginx(data string) []token {
+	var tokens []token
+	line := 1
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+		case c == '{' || c == '}' || c == ';':
+			tokens = append(tokens, token{text: string(c), line: line})
+			i++
+		case c == '"' || c == '\'':
+			start := line
+			var b strings.Builder
+			for i++; i < len(data) && data[i] != c; i++ {
+				if data[i] == '\\' && i+1 < len(data) {
+					i++
+				}
+				if data[i] == '\n' {
+					line++
+				}
+				b.WriteByte(data[i])
+			}
+			i++
+			tokens = append(tokens, token{text: b.String(), line: start, quoted: true})
+		default:
+			start := i
+			for i < len(data) && !strings.ContainsRune(" \t\r\n{};", rune(data[i])) {
+				i++
+			}
+			tokens = append(tokens, token{text: data[start:i], line: line})
+		}
+	}
+	return tokens
+}
+
+type nginxParser struct {
+	tokens []token
+	pos    int
+	file   string
+}
+
+// block reads directives up to the end of the file, or the closing brace when nested
+func (p *nginxParser) block(nested bool) ([]directive, error) {
+	directives := []directive{}
+	for p.pos < len(p.tokens) {
+		t := p.tokens[p.pos]
+		if t.text == "}" && !t.quoted {
+			if !nested {
+				return nil, fmt.Errorf("%s: unexpected } on line %d", p.file, t.line)
+			}
+			p.pos++
+			return directives, nil
+		}
+
+		d := directive{name: t.text, file: p.file, line: t.line}
+		for p.pos++; ; p.pos++ {
+			if p.pos >= len(p.tokens) {
+				return nil, fmt.Errorf("%s: %q on line %d is missing a ; or {", p.file, d.name, d.line)
+			}
+			arg := p.tokens[p.pos]
+			if arg.quoted || (arg.text != ";" && arg.text != "{" && arg.text != "}") {
+				d.args = append(d.args, arg.text)
+				continue
+			}
+			if arg.text == "}" {
+				return nil, fmt.Errorf("%s: %q on line %d is missing a ;", p.file, d.name, d.line)
+			}
+			p.pos++
+			if arg.text == "{" {
+				block, err := p.block(true)
+				if err != nil {
+					return nil, err
+				}
+				d.block = block
+			}
+			break
+		}
+		directives = append(directives, d)
+	}
+	if nested {
+		return nil, errors.New(p.file + ": missing }")
+	}
+	return directives, nil
+}
@@ -0,0 +1,185 @@
+// Package webconfig reads web server configurations for the HTTPS sites they serve, so their hostnames and
+// certificate files can be tracked in bulk
+package webconfig
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Format is the kind of configuration a file holds
+type Format string
+
+const (
+	Nginx   Format = "nginx"
+	Apache  Format = "apache"
+	HAProxy Format = "haproxy"
+	Caddy   Format = "caddy"
+)
+
+// Formats lists every supported format
+var Formats = []Format{Nginx, Apache, HAProxy, Caddy}
+
+// maxIncludeDepth stops include loops
+const maxIncludeDepth = 16
+
+// Site is an HTTPS server found in a configuration
+type Site struct {
+	// Source is where the server is defined, as file:line
+	Source string
+	// Hostnames the server answers to, without wildcards, regular expressions or ports
+	Hostnames []string
+	// CertFiles are the absolute paths of its certificates, a path can be a directory of certificates for HAProxy
+	CertFiles []string
+}
+
+// ParseFormat checks a format name given by the user
+func ParseFormat(name string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(name, string(f)) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown config format %q, expected nginx, apache, haproxy or caddy", name)
+}
+
+// ParseFile reads the HTTPS sites of a configuration file and the files it includes.
+//
+// An empty format is detected from the file's name and content
+func ParseFile(path string, format Format) ([]Site, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		format = Detect(path, data)
+	}
+
+	switch format {
+	case Nginx:
+		return parseNginx(path)
+	case Apache:
+		return parseApache(path)
+	case HAProxy:
+		return parseHAProxy(path)
+	case Caddy:
+		return parseCaddy(path)
+	default:
+		return nil, fmt.Errorf("unknown config format %q", format)
+	}
+}
+
+var (
+	apacheHint  = regexp.MustCompile(`(?im)^\s*(<VirtualHost\b|ServerName\s|SSLCertificateFile\s)`)
+	haproxyHint = regexp.MustCompile(`(?m)^\s*(frontend|backend|listen)\s+\S+`)
+	nginxHint   = regexp.MustCompile(`(?m)(^|[\s{])(server_name|ssl_certificate|listen)\s[^;]*;`)
+)
+
+// Detect guesses the format of a configuration from its file name, then its content, falling back to nginx
+func Detect(path string, data []byte) Format {
+	name := strings.ToLower(filepath.Base(path))
+	switch {
+	case strings.Contains(name, "caddyfile"):
+		return Caddy
+	case strings.Contains(name, "haproxy"):
+		return HAProxy
+	case strings.Contains(name, "httpd") || strings.Contains(name, "apache"):
+		return Apache
+	case strings.Contains(name, "nginx"):
+		return Nginx
+	}
+
+	switch {
+	case apacheHint.Match(data):
+		return Apache
+	case nginxHint.Match(data):
+		return Nginx
+	case haproxyHint.Match(data) && bytes.Contains(data, []byte("bind")):
+		return HAProxy
+	}
+	return Nginx
+}
+
+// hostname cleans up a server name, reporting false for names that can't be checked over the network such as
+// wildcards, regular expressions, variables and catch-alls
+func hostname(name string) (string, bool) {
+	name = strings.ToLower(strings.Trim(name, `"'`))
+	if host, _, err := net.SplitHostPort(name); err == nil {
+		name = host
+	}
+	name = strings.TrimSuffix(name, ".")
+	if name == "" || name == "_" || strings.HasPrefix(name, ".") || name == "localhost" || strings.ContainsAny(name, "*~$^(){}[]\\/ ") {
+		return "", false
+	}
+	return name, true
+}
+
+// addHostname appends a usable hostname once
+func (s *Site) addHostname(name string) {
+	host, ok := hostname(name)
+	if !ok {
+		return
+	}
+	for _, h := range s.Hostnames {
+		if h == host {
+			return
+		}
+	}
+	s.Hostnames = append(s.Hostnames, host)
+}
+
+// addCertFile appends a certificate path once, resolving it against dir.
+// Paths built from variables or held by an engine rather than a file are skipped
+func (s *Site) addCertFile(dir, path string) {
+	path = strings.Trim(path, `"'`)
+	if path == "" || strings.Contains(path, "$") || strings.Contains(path, "{") ||
+		strings.HasPrefix(path, "data:") || strings.HasPrefix(path, "engine:") || strings.HasPrefix(path, "store:") {
+		return
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	path = filepath.Clean(path)
+	for _, p := range s.CertFiles {
+		if p == path {
+			return
+		}
+	}
+	s.CertFiles = append(s.CertFiles, path)
+}
+
+// includeFiles expands an include pattern relative to dir, a directory includes every file in it
+func includeFiles(dir, pattern string) ([]string, error) {
+	pattern = strings.Trim(pattern, `"'`)
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+	if info, err := os.Stat(pattern); err == nil && info.IsDir() {
+		pattern = filepath.Join(pattern, "*")
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include %q: %w", pattern, err)
+	}
+	var files []string
+	for _, m := range matches {
+		if info, err := os.Stat(m); err == nil && !info.IsDir() {
+			files = append(files, m)
+		}
+	}
+	return files, nil
+}
+
+// source formats where something is defined
+func source(path string, line int) string {
+	return fmt.Sprintf("%s:%d", path, line)
+}
@@ -0,0 +1,179 @@
+package webconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles creates files under a temporary directory, returning the directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return dir
+}
+
+// TestParseFile_Nginx - TLS servers are read through includes, inheriting certificates from the http block.
+func TestParseFile_Nginx(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"nginx.conf": `
+http {
+    ssl_certificate /etc/ssl/default.pem; # used unless a server sets its own
+    include sites-enabled/*;
+
+    server {
+        listen 80 default_server;
+        server_name _;
+    }
+}`,
+		"sites-enabled/shop": `
+server {
+    listen 443 ssl http2;
+    server_name shop.example.com www.shop.example.com *.shop.example.com;
+    ssl_certificate "certs/shop.pem";
+    location / { proxy_pass http://127.0.0.1:8080; }
+}
+server {
+    listen [::]:443 ssl;
+    server_name ~^(?<app>.+)\.example\.com$ api.example.com.;
+}`,
+	})
+
+	sites, err := ParseFile(filepath.Join(dir, "nginx.conf"), "")
+	require.NoError(t, err)
+	require.Len(t, sites, 2, "The plain HTTP server is skipped")
+
+	assert.Equal(t, filepath.Join(dir, "sites-enabled/shop")+":2", sites[0].Source)
+	assert.Equal(t, []string{"shop.example.com", "www.shop.example.com"}, sites[0].Hostnames)
+	assert.Equal(t, []string{filepath.Join(dir, "certs/shop.pem")}, sites[0].CertFiles)
+	assert.Equal(t, []string{"api.example.com"}, sites[1].Hostnames)
+	assert.Equal(t, []string{"/etc/ssl/default.pem"}, sites[1].CertFiles)
+
+	_, err = ParseFile(writeFiles(t, map[string]string{"bad.conf": "server { listen 443 ssl"})+"/bad.conf", Nginx)
+	assert.Error(t, err)
+}
+
+// TestParseFile_Apache - virtual hosts with SSLEngine on are read with their aliases and certificate.
+func TestParseFile_Apache(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"conf.d/ssl.conf": `
+<IfModule mod_ssl.c>
+<VirtualHost *:443>
+    ServerName https://www.example.com:443
+    ServerAlias example.com \
+        *.example.com
+    SSLEngine on
+    SSLCertificateFile "certs/www.pem"
+</VirtualHost>
+</IfModule>`,
+	})
+	conf := filepath.Join(dir, "httpd.conf")
+	require.NoError(t, os.WriteFile(conf, []byte(`
+ServerRoot "`+dir+`"
+IncludeOptional conf.d/*.conf
+<VirtualHost *:80>
+    ServerName www.example.com
+</VirtualHost>`), 0o644))
+
+	sites, err := ParseFile(conf, "")
+	require.NoError(t, err)
+	require.Len(t, sites, 1)
+	assert.Equal(t, filepath.Join(dir, "conf.d/ssl.conf")+":3", sites[0].Source)
+	assert.Equal(t, []string{"www.example.com", "example.com"}, sites[0].Hostnames)
+	assert.Equal(t, []string{filepath.Join(dir, "certs/www.pem")}, sites[0].CertFiles)
+}
+
+// TestParseFile_HAProxy - frontends binding with ssl give their crt paths, crt-list entries and Host or SNI ACLs.
+func TestParseFile_HAProxy(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"haproxy.cfg": `
+global
+    crt-base /etc/haproxy/certs
+
+frontend http
+    bind :80
+    acl is_old hdr(host) -i old.example.com
+
+frontend https
+    bind :443 ssl crt site.pem crt-list crt-list.txt alpn h2,http/1.1
+    acl is_api hdr(host),lower -i api.example.com api.example.com:443
+    acl is_static hdr_beg(host) -i static.
+    acl is_sub hdr(host) -m sub example
+    use_backend shop if { ssl_fc_sni -i shop.example.com } || { req.hdr(host) -m reg ^a }
+
+backend shop
+    server s1 10.0.0.1:80
+`,
+		"crt-list.txt": "# certificates with SNI filters\n/etc/haproxy/other.pem [alpn h2] other.example.com\n",
+	})
+
+	sites, err := ParseFile(filepath.Join(dir, "haproxy.cfg"), "")
+	require.NoError(t, err)
+	require.Len(t, sites, 1, "The frontend without ssl is skipped")
+	assert.Equal(t, filepath.Join(dir, "haproxy.cfg")+":9", sites[0].Source)
+	assert.Equal(t, []string{"api.example.com", "shop.example.com"}, sites[0].Hostnames)
+	assert.Equal(t, []string{"/etc/haproxy/certs/site.pem", "/etc/haproxy/other.pem"}, sites[0].CertFiles)
+}
+
+// TestParseFile_Caddy - site addresses other than http:// ones are HTTPS, tls names the certificate file.
+func TestParseFile_Caddy(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"Caddyfile": `
+{
+    email ops@example.com
+}
+
+(common) {
+    encode gzip
+}
+
+example.com, www.example.com {
+    import common
+    reverse_proxy localhost:8080
+}
+
+https://api.example.com:8443/v1 {
+    tls /etc/caddy/api.pem /etc/caddy/api.key
+}
+
+http://plain.example.com {
+    respond "hello # not a comment"
+}
+
+import sites/*`,
+		"sites/intranet": `intranet.example.com {
+    tls internal
+}`,
+	})
+
+	sites, err := ParseFile(filepath.Join(dir, "Caddyfile"), "")
+	require.NoError(t, err)
+	require.Len(t, sites, 3, "The plain HTTP site is skipped")
+	assert.Equal(t, []string{"example.com", "www.example.com"}, sites[0].Hostnames)
+	assert.Empty(t, sites[0].CertFiles)
+	assert.Equal(t, []string{"api.example.com"}, sites[1].Hostnames)
+	assert.Equal(t, []string{"/etc/caddy/api.pem"}, sites[1].CertFiles)
+	assert.Equal(t, []string{"intranet.example.com"}, sites[2].Hostnames)
+	assert.Empty(t, sites[2].CertFiles, "Certificates Caddy issues itself have no file to track")
+}
+
+// TestDetect - the format comes from the file name, then the content.
+func TestDetect(t *testing.T) {
+	assert.Equal(t, Caddy, Detect("/etc/caddy/Caddyfile", nil))
+	assert.Equal(t, HAProxy, Detect("/etc/haproxy/haproxy.cfg", nil))
+	assert.Equal(t, Apache, Detect("/etc/site.conf", []byte("<VirtualHost *:443>\n")))
+	assert.Equal(t, Nginx, Detect("/etc/site.conf", []byte("server {\n  listen 443 ssl;\n}\n")))
+	assert.Equal(t, HAProxy, Detect("/etc/lb.cfg", []byte("frontend web\n  bind :443 ssl crt /x.pem\n")))
+
+	_, err := ParseFormat("iis")
+	assert.Error(t, err)
+}
@@ -0,0 +1,249 @@
+// Package whois looks up when the registration of each tracked domain expires.
+//
+// Lookups go to the registry's RDAP server, the JSON successor to WHOIS, found from the IANA bootstrap file
+package whois
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/ssl"
+	"golang.org/x/net/publicsuffix"
+)
+
+// BootstrapURL lists the RDAP server of every top level domain that has one
+const BootstrapURL = "https://data.iana.org/rdap/dns.json"
+
+const (
+	// lookupTimeout bounds each request made by the default HTTP client
+	lookupTimeout = 30 * time.Second
+	// bootstrapLifetime is how long the bootstrap file is used before it is fetched again
+	bootstrapLifetime = 24 * time.Hour
+)
+
+var (
+	// ErrNotRegistrable occurs for names that aren't under a public domain, e.g. IP addresses, files and
+	// hosts under shared suffixes like github.io
+	ErrNotRegistrable = errors.New("not part of a registrable domain")
+	// ErrNoRDAP occurs when the registry of the top level domain has no RDAP server
+	ErrNoRDAP = errors.New("registry has no RDAP server")
+	// ErrNotRegistered occurs when the registry doesn't know the domain
+	ErrNotRegistered = errors.New("domain is not registered")
+)
+
+// Registration is what the registry knows about a domain
+type Registration struct {
+	// Domain is the registered domain, e.g. example.com for www.example.com
+	Domain string
+	// Expiry is when the registration ends, nil when the registry doesn't say
+	Expiry    *time.Time
+	Registrar string
+}
+
+// RegisteredDomain is the domain a tracked name belongs to, the one a registrar sells, e.g. example.co.uk
+// for www.example.co.uk. SSH targets belong to the domain of their host
+func RegisteredDomain(name string) (string, error) {
+	host := name
+	if ssl.IsSSHTarget(name) {
+		var err error
+		if host, _, err = ssl.SSHAddress(name); err != nil {
+			return "", err
+		}
+	} else if strings.Contains(name, "://") {
+		return "", ErrNotRegistrable
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if net.ParseIP(host) != nil || ssl.ValidateHostname(host) != nil {
+		return "", ErrNotRegistrable
+	}
+	if _, icann := publicsuffix.PublicSuffix(host); !icann {
+		return "", ErrNotRegistrable
+	}
+	registered, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return "", ErrNotRegistrable
+	}
+	return registered, nil
+}
+
+// Client asks RDAP servers about domains
+type Client struct {
+	httpClient *http.Client
+	// serverURL is asked about every domain, empty finds the server from the bootstrap file
+	serverURL    string
+	bootstrapURL string
+	now          func() time.Time
+
+	mu             sync.Mutex
+	servers        map[string]string
+	bootstrappedAt time.Time
+}
+
+// NewClient asks serverURL about every domain, or each registry's own server when it is empty.
+// A nil httpClient uses one with a 30 second timeout
+func NewClient(serverURL string, httpClient *http.Client) (*Client, error) {
+	if serverURL != "" {
+		u, err := url.Parse(serverURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid RDAP server URL %q, expected an http or https URL", serverURL)
+		}
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: lookupTimeout}
+	}
+	return &Client{
+		httpClient:   httpClient,
+		serverURL:    serverURL,
+		bootstrapURL: BootstrapURL,
+		now:          time.Now,
+	}, nil
+}
+
+// rdapDomain is the part of an RDAP domain response a lookup reads
+type rdapDomain struct {
+	Events []struct {
+		Action string `json:"eventAction"`
+		Date   string `json:"eventDate"`
+	} `json:"events"`
+	Entities []struct {
+		Roles []string          `json:"roles"`
+		VCard []json.RawMessage `json:"vcardArray"`
+	} `json:"entities"`
+}
+
+// Lookup asks the registry when the domain a tracked name belongs to expires
+func (c *Client) Lookup(ctx context.Context, name string) (*Registration, error) {
+	registered, err := RegisteredDomain(name)
+	if err != nil {
+		return nil, err
+	}
+	server, err := c.server(ctx, registered)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp rdapDomain
+	status, err := c.getJSON(ctx, strings.TrimSuffix(server, "/")+"/domain/"+registered, &resp)
+	if status == http.StatusNotFound {
+		return nil, fmt.Errorf("%s: %w", registered, ErrNotRegistered)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("RDAP lookup of %s failed: %w", registered, err)
+	}
+
+	reg := &Registration{Domain: registered}
+	for _, e := range resp.Events {
+		if e.Action != "expiration" {
+			continue
+		}
+		if expiry, err := time.Parse(time.RFC3339, e.Date); err == nil {
+			reg.Expiry = &expiry
+		}
+	}
+	for _, e := range resp.Entities {
+		for _, role := range e.Roles {
+			if role == "registrar" {
+				reg.Registrar = vcardName(e.VCard)
+			}
+		}
+	}
+	return reg, nil
+}
+
+// vcardName reads the formatted name from a jCard, ["vcard", [["fn", {}, "text", "Example Registrar, Inc."], ...]]
+func vcardName(vcard []json.RawMessage) string {
+	if len(vcard) < 2 {
+		return ""
+	}
+	var properties [][]any
+	if err := json.Unmarshal(vcard[1], &properties); err != nil {
+		return ""
+	}
+	for _, p := range properties {
+		if len(p) >= 4 && p[0] == "fn" {
+			if name, ok := p[3].(string); ok {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// server returns the RDAP server for a registered domain, from the bootstrap file unless one was configured
+func (c *Client) server(ctx context.Context, registered string) (string, error) {
+	if c.serverURL != "" {
+		return c.serverURL, nil
+	}
+	tld := registered[strings.LastIndex(registered, ".")+1:]
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.servers == nil || c.now().Sub(c.bootstrappedAt) > bootstrapLifetime {
+		servers, err := c.bootstrap(ctx)
+		if err != nil {
+			return "", err
+		}
+		c.servers, c.bootstrappedAt = servers, c.now()
+	}
+	server, ok := c.servers[tld]
+	if !ok {
+		return "", fmt.Errorf(".%s: %w", tld, ErrNoRDAP)
+	}
+	return server, nil
+}
+
+// bootstrap fetches the RDAP server of each top level domain,
+// {"services": [[["com", "net"], ["https://rdap.verisign.com/com/v1/"]], ...]}
+func (c *Client) bootstrap(ctx context.Context) (map[string]string, error) {
+	var file struct {
+		Services [][][]string `json:"services"`
+	}
+	if _, err := c.getJSON(ctx, c.bootstrapURL, &file); err != nil {
+		return nil, fmt.Errorf("failed to fetch the RDAP bootstrap file: %w", err)
+	}
+
+	servers := make(map[string]string)
+	for _, service := range file.Services {
+		if len(service) < 2 || len(service[1]) == 0 {
+			continue
+		}
+		// Prefer an https server when several are listed
+		server := service[1][0]
+		for _, s := range service[1] {
+			if strings.HasPrefix(s, "https://") {
+				server = s
+				break
+			}
+		}
+		for _, tld := range service[0] {
+			servers[strings.ToLower(tld)] = server
+		}
+	}
+	return servers, nil
+}
+
+// getJSON decodes the response to a GET into v, returning the status code along with any error
+func (c *Client) getJSON(ctx context.Context, target string, v any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/rdap+json, application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,199 @@
+package whois
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/notification"
+)
+
+// trackerTick is how often the tracker looks for registrations that are due
+const trackerTick = time.Hour
+
+// Tracker keeps the registration expiry of every tracked domain up to date, and notifies as registrations
+// come close to expiring
+type Tracker struct {
+	domains    *domain.Service
+	client     *Client
+	repo       *Repository
+	interval   time.Duration
+	thresholds []int
+	senders    []notification.Sender
+	now        func() time.Time
+}
+
+// NewTracker looks each registration up again once interval has passed since the last lookup
+func NewTracker(domains *domain.Service, client *Client, repo *Repository, interval time.Duration) *Tracker {
+	return &Tracker{
+		domains:  domains,
+		client:   client,
+		repo:     repo,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// SetNotifications notifies every sender once a registration is within one of thresholds days of expiring,
+// zero meaning expired. Each threshold is notified once per registration period
+func (t *Tracker) SetNotifications(thresholds []int, senders ...notification.Sender) {
+	t.thresholds = thresholds
+	t.senders = senders
+}
+
+// Run updates registrations until the context is cancelled
+func (t *Tracker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(trackerTick)
+	defer ticker.Stop()
+
+	for {
+		if err := t.Update(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Failed to update domain registrations", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Update looks up the registrations not looked up within the interval and notifies about those that crossed
+// a threshold.
+//
+// Names belonging to the same registered domain, e.g. example.com and www.example.com, share one lookup.
+// A failed lookup keeps the expiry found before and is tried again after the interval
+func (t *Tracker) Update(ctx context.Context) error {
+	domains, err := t.domains.GetActiveDomains()
+	if err != nil {
+		return fmt.Errorf("failed to get domains: %w", err)
+	}
+	return t.update(ctx, domains, false)
+}
+
+// LookUp looks up the registrations of domains straight away, without notifying
+func (t *Tracker) LookUp(ctx context.Context, domains []domain.Domain) error {
+	return t.update(ctx, domains, true)
+}
+
+// update looks up the registrations of domains that are due, or all of them when forced, and notifies
+// about those that crossed a threshold unless forced
+func (t *Tracker) update(ctx context.Context, domains []domain.Domain, force bool) error {
+	groups := make(map[string][]domain.Domain)
+	for _, d := range domains {
+		registered, err := RegisteredDomain(d.DomainName.String())
+		if err != nil {
+			continue
+		}
+		groups[registered] = append(groups[registered], d)
+	}
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	now := t.now()
+	var errs []error
+	for _, name := range names {
+		group := groups[name]
+		expiry := lastExpiry(group)
+		if force || t.due(group, now) {
+			reg, err := t.client.Lookup(ctx, name)
+			switch {
+			case ctx.Err() != nil:
+				return ctx.Err()
+			case err != nil && force:
+				errs = append(errs, err)
+			case err != nil:
+				slog.Warn("Registration lookup failed", "domain", name, "error", err)
+			default:
+				expiry = reg.Expiry
+			}
+			for _, d := range group {
+				if err := t.domains.SetRegistration(d.DomainID, expiry, now); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+		if expiry != nil && !force {
+			if err := t.notify(ctx, name, *expiry, now); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// due reports whether any of the names of a registered domain hasn't been looked up within the interval
+func (t *Tracker) due(group []domain.Domain, now time.Time) bool {
+	for _, d := range group {
+		if d.RegistrationChecked == nil || now.Sub(*d.RegistrationChecked) >= t.interval {
+			return true
+		}
+	}
+	return false
+}
+
+// lastExpiry is the expiry the most recent lookup of a registered domain found, nil if none found one
+func lastExpiry(group []domain.Domain) *time.Time {
+	var latest *domain.Domain
+	for i, d := range group {
+		if d.RegistrationExpiry == nil || d.RegistrationChecked == nil {
+			continue
+		}
+		if latest == nil || d.RegistrationChecked.After(*latest.RegistrationChecked) {
+			latest = &group[i]
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+	return latest.RegistrationExpiry
+}
+
+// notify sends a notification through every sender when the registration has crossed a threshold it
+// hasn't been notified for
+func (t *Tracker) notify(ctx context.Context, registered string, expiry, now time.Time) error {
+	if len(t.senders) == 0 {
+		return nil
+	}
+	threshold, crossed := notification.CrossedThreshold(t.thresholds, expiry, now)
+	if !crossed {
+		return nil
+	}
+	sent, err := t.repo.AlertSent(registered, expiry, threshold)
+	if err != nil || sent {
+		return err
+	}
+
+	var errs []error
+	delivered := false
+	for _, s := range t.senders {
+		n := notification.Notification{
+			DomainName:       registered,
+			ExpiryDate:       &expiry,
+			DaysBefore:       threshold,
+			NotificationType: s.Type(),
+			Registration:     true,
+		}
+		if err := s.Send(ctx, n); err != nil {
+			errs = append(errs, fmt.Errorf("failed to notify %s about the registration of %s: %w", s.Type(), registered, err))
+			continue
+		}
+		delivered = true
+	}
+	// Channels that failed aren't tried again once one got through, so the others don't repeat themselves
+	if delivered {
+		slog.Info("Registration expiry notified", "domain", registered, "expiry", expiry, "threshold", threshold)
+		if err := t.repo.RecordAlert(registered, expiry, threshold, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,38 @@
+package whois
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+)
+
+// Repository remembers which registration thresholds have been notified
+type Repository struct {
+	db     *sql.DB
+	writer *database.Writer
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db:     db,
+		writer: database.NewWriter(db),
+	}
+}
+
+// AlertSent reports whether a threshold was already notified for the registration of a domain ending at expiry
+func (r *Repository) AlertSent(registered string, expiry time.Time, threshold int) (bool, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM registration_alerts WHERE registered_domain = ? AND expiry_date = ? AND days_before = ?`,
+		registered, expiry.UTC(), threshold).Scan(&count)
+	return count > 0, err
+}
+
+// RecordAlert remembers that a threshold was notified for the registration ending at expiry.
+//
+// A renewed registration ends later, so its thresholds are notified again
+func (r *Repository) RecordAlert(registered string, expiry time.Time, threshold int, sentAt time.Time) error {
+	_, err := r.writer.Exec(`INSERT INTO registration_alerts (registered_domain, expiry_date, days_before, sent_at) VALUES (?, ?, ?, ?)`,
+		registered, expiry.UTC(), threshold, sentAt)
+	return err
+}
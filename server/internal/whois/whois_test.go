@@ -0,0 +1,162 @@
+package whois
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/samokw/ssl_tracker/internal/database"
+	"github.com/samokw/ssl_tracker/internal/domain"
+	"github.com/samokw/ssl_tracker/internal/notification"
+	"github.com/samokw/ssl_tracker/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSender records what it was asked to send.
+type fakeSender struct {
+	sent []notification.Notification
+}
+
+func (f *fakeSender) Type() notification.NotificationType {
+	return notification.NotificationTypeWebhook
+}
+
+func (f *fakeSender) Send(ctx context.Context, n notification.Notification) error {
+	f.sent = append(f.sent, n)
+	return nil
+}
+
+// serveRDAP answers the bootstrap file and domain lookups, with registrations expiring at expiries.
+// The returned counter counts domain lookups
+func serveRDAP(t *testing.T, expiries map[string]time.Time) (*Client, *atomic.Int32) {
+	t.Helper()
+
+	lookups := &atomic.Int32{}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bootstrap" {
+			fmt.Fprintf(w, `{"services": [[["com", "uk"], ["%s/rdap/"]]]}`, server.URL)
+			return
+		}
+		lookups.Add(1)
+		expiry, ok := expiries[strings.TrimPrefix(r.URL.Path, "/rdap/domain/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"events": [{"eventAction": "registration", "eventDate": "2001-01-01T00:00:00Z"},
+			{"eventAction": "expiration", "eventDate": "%s"}],
+			"entities": [{"roles": ["registrar"], "vcardArray": ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "Example Registrar, Inc."]]]}]}`,
+			expiry.Format(time.RFC3339))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient("", server.Client())
+	require.NoError(t, err)
+	client.bootstrapURL = server.URL + "/bootstrap"
+	return client, lookups
+}
+
+// TestRegisteredDomain - names belong to the domain a registrar sells, names outside public domains to none.
+func TestRegisteredDomain(t *testing.T) {
+	for name, want := range map[string]string{
+		"example.com":                 "example.com",
+		"www.Example.com.":            "example.com",
+		"api.eu.example.co.uk":        "example.co.uk",
+		"ssh://bastion.example.com:2": "example.com",
+	} {
+		got, err := RegisteredDomain(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, got, name)
+	}
+
+	for _, name := range []string{"192.0.2.1", "file:///etc/ssl/cert.pem", "me.github.io", "printer.internal", "com"} {
+		_, err := RegisteredDomain(name)
+		assert.ErrorIs(t, err, ErrNotRegistrable, name)
+	}
+}
+
+// TestClient_Lookup - the registry's server is found from the bootstrap file and its answer read.
+func TestClient_Lookup(t *testing.T) {
+	expiry := time.Date(2027, 8, 13, 4, 0, 0, 0, time.UTC)
+	client, _ := serveRDAP(t, map[string]time.Time{"example.com": expiry})
+
+	reg, err := client.Lookup(context.Background(), "www.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", reg.Domain)
+	require.NotNil(t, reg.Expiry)
+	assert.True(t, reg.Expiry.Equal(expiry))
+	assert.Equal(t, "Example Registrar, Inc.", reg.Registrar)
+
+	_, err = client.Lookup(context.Background(), "missing.com")
+	assert.ErrorIs(t, err, ErrNotRegistered)
+	_, err = client.Lookup(context.Background(), "example.org")
+	assert.ErrorIs(t, err, ErrNoRDAP)
+}
+
+// TestTracker_Update - names of one registration share a lookup, and each threshold is notified once.
+func TestTracker_Update(t *testing.T) {
+	db, err := database.InitSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	domainRepo := domain.NewRepository(db)
+	domains := domain.NewService(domainRepo, nil)
+
+	now := time.Now().Truncate(time.Second)
+	expiry := now.Add(20 * 24 * time.Hour).UTC()
+	client, lookups := serveRDAP(t, map[string]time.Time{"example.com": expiry})
+
+	var ids []types.DomainID
+	for _, name := range []string{"example.com", "www.example.com", "192.0.2.1"} {
+		d := &domain.Domain{UserID: types.UserID(1), DomainName: domain.NewDomainName(name), CreatedAt: domain.NewCreatedAt(now), IsActive: true}
+		require.NoError(t, domainRepo.CreateDomain(d))
+		ids = append(ids, d.DomainID)
+	}
+
+	sender := &fakeSender{}
+	tracker := NewTracker(domains, client, NewRepository(db), 24*time.Hour)
+	tracker.SetNotifications([]int{60, 30, 7, 0}, sender)
+	tracker.now = func() time.Time { return now }
+
+	require.NoError(t, tracker.Update(context.Background()))
+	assert.Equal(t, int32(1), lookups.Load())
+	for _, id := range ids[:2] {
+		d, err := domains.GetDomain(id)
+		require.NoError(t, err)
+		require.NotNil(t, d.RegistrationExpiry)
+		assert.True(t, d.RegistrationExpiry.Equal(expiry))
+	}
+	d, err := domains.GetDomain(ids[2])
+	require.NoError(t, err)
+	assert.Nil(t, d.RegistrationChecked, "IP addresses have no registration")
+
+	require.Len(t, sender.sent, 1)
+	assert.Equal(t, "example.com", sender.sent[0].DomainName)
+	assert.Equal(t, 30, sender.sent[0].DaysBefore)
+	assert.True(t, sender.sent[0].Registration)
+
+	// Nothing is due again yet, and the threshold was already notified
+	require.NoError(t, tracker.Update(context.Background()))
+	assert.Equal(t, int32(1), lookups.Load())
+	assert.Len(t, sender.sent, 1)
+
+	// A forced lookup goes out straight away without notifying
+	all, err := domains.GetActiveDomains()
+	require.NoError(t, err)
+	require.NoError(t, tracker.LookUp(context.Background(), all))
+	assert.Equal(t, int32(2), lookups.Load())
+	assert.Len(t, sender.sent, 1)
+
+	// The next threshold notifies again
+	tracker.now = func() time.Time { return now.Add(14 * 24 * time.Hour) }
+	require.NoError(t, tracker.Update(context.Background()))
+	require.Len(t, sender.sent, 2)
+	assert.Equal(t, 7, sender.sent[1].DaysBefore)
+}
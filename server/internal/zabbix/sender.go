@@ -0,0 +1,149 @@
+// This package sends values to a Zabbix server or proxy the way zabbix_sender does, into trapper items
+package zabbix
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPort is where Zabbix servers and proxies listen for trapper data
+const DefaultPort = "10051"
+
+// header starts every message of the Zabbix protocol
+var header = []byte("ZBXD\x01")
+
+// maxResponse bounds how much of a reply is read
+const maxResponse = 1 << 20
+
+// Item is a value for a trapper item of a host
+type Item struct {
+	Host  string `json:"host"`
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Clock int64  `json:"clock,omitempty"`
+}
+
+// Response is how many of the sent values the server accepted
+type Response struct {
+	Processed int
+	Failed    int
+	Total     int
+}
+
+var infoPattern = regexp.MustCompile(`processed: (\d+); failed: (\d+); total: (\d+)`)
+
+// Send delivers items to a server or proxy at addr, host:port with the port defaulting to 10051.
+//
+// Values for items the server doesn't know, or that aren't trapper items, are counted as failed rather than
+// returned as an error
+func Send(ctx context.Context, addr string, items []Item) (*Response, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, DefaultPort)
+	}
+	payload, err := json.Marshal(struct {
+		Request string `json:"request"`
+		Data    []Item `json:"data"`
+		Clock   int64  `json:"clock"`
+	}{Request: "sender data", Data: items, Clock: time.Now().Unix()})
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Zabbix at %s: %w", addr, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	msg := make([]byte, 0, len(header)+8+len(payload))
+	msg = append(msg, header...)
+	msg = binary.LittleEndian.AppendUint64(msg, uint64(len(payload)))
+	msg = append(msg, payload...)
+	if _, err := conn.Write(msg); err != nil {
+		return nil, fmt.Errorf("failed to send to Zabbix: %w", err)
+	}
+
+	reply, err := readMessage(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the Zabbix response: %w", err)
+	}
+	var out struct {
+		Response string `json:"response"`
+		Info     string `json:"info"`
+	}
+	if err := json.Unmarshal(reply, &out); err != nil {
+		return nil, fmt.Errorf("invalid Zabbix response: %w", err)
+	}
+	if out.Response != "success" {
+		return nil, fmt.Errorf("Zabbix refused the data: %s", out.Info)
+	}
+
+	var resp Response
+	if m := infoPattern.FindStringSubmatch(out.Info); m != nil {
+		resp.Processed, _ = strconv.Atoi(m[1])
+		resp.Failed, _ = strconv.Atoi(m[2])
+		resp.Total, _ = strconv.Atoi(m[3])
+	}
+	return &resp, nil
+}
+
+// readMessage reads one message and returns its payload
+func readMessage(r io.Reader) ([]byte, error) {
+	head := make([]byte, len(header)+8)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, err
+	}
+	if string(head[:4]) != "ZBXD" {
+		return nil, errors.New("not a Zabbix protocol message")
+	}
+	size := binary.LittleEndian.Uint64(head[len(header):])
+	if size > maxResponse {
+		return nil, fmt.Errorf("message of %d bytes is too large", size)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// Key builds an item key with parameters, quoting those Zabbix would otherwise split or cut short,
+// e.g. Key("sslcerttop.days_left", "example.com") is sslcerttop.days_left[example.com]
+func Key(name string, params ...string) string {
+	if len(params) == 0 {
+		return name
+	}
+	quoted := make([]string, len(params))
+	for i, p := range params {
+		if strings.ContainsAny(p, `,]["`) || strings.HasPrefix(p, " ") {
+			p = `"` + strings.ReplaceAll(p, `"`, `\"`) + `"`
+		}
+		quoted[i] = p
+	}
+	return name + "[" + strings.Join(quoted, ",") + "]"
+}
+
+// SenderLine formats an item as a line of a zabbix_sender input file, a host of - standing for the sender's
+// configured host
+func SenderLine(item Item) string {
+	quote := func(s string) string {
+		if s != "" && !strings.ContainsAny(s, " \t\n\"\\") {
+			return s
+		}
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ").Replace(s) + `"`
+	}
+	return quote(item.Host) + " " + quote(item.Key) + " " + quote(item.Value)
+}
@@ -0,0 +1,71 @@
+package zabbix
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSend - items arrive as a sender data request and the counts of the reply are returned.
+func TestSend(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan []Item, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		payload, err := readMessage(conn)
+		if err != nil {
+			return
+		}
+		var req struct {
+			Request string `json:"request"`
+			Data    []Item `json:"data"`
+		}
+		json.Unmarshal(payload, &req)
+		if req.Request == "sender data" {
+			received <- req.Data
+		}
+
+		reply := []byte(`{"response":"success","info":"processed: 1; failed: 1; total: 2; seconds spent: 0.000055"}`)
+		msg := append([]byte("ZBXD\x01"), binary.LittleEndian.AppendUint64(nil, uint64(len(reply)))...)
+		conn.Write(append(msg, reply...))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	items := []Item{
+		{Host: "web01", Key: "sslcerttop.days_left[example.com]", Value: "20"},
+		{Host: "web01", Key: "unknown", Value: "1"},
+	}
+	resp, err := Send(ctx, ln.Addr().String(), items)
+	require.NoError(t, err)
+	assert.Equal(t, &Response{Processed: 1, Failed: 1, Total: 2}, resp)
+	assert.Equal(t, items, <-received)
+}
+
+// TestKey - parameters Zabbix would split are quoted.
+func TestKey(t *testing.T) {
+	assert.Equal(t, "sslcerttop.discovery", Key("sslcerttop.discovery"))
+	assert.Equal(t, "sslcerttop.days_left[example.com]", Key("sslcerttop.days_left", "example.com"))
+	assert.Equal(t, `sslcerttop.status["file:///etc/ssl/a,b.pem"]`, Key("sslcerttop.status", "file:///etc/ssl/a,b.pem"))
+}
+
+// TestSenderLine - fields with spaces or quotes are quoted for zabbix_sender.
+func TestSenderLine(t *testing.T) {
+	assert.Equal(t, "- sslcerttop.status[example.com] 0", SenderLine(Item{Host: "-", Key: "sslcerttop.status[example.com]", Value: "0"}))
+	assert.Equal(t, `web01 sslcerttop.error[example.com] "dial tcp: \"x\" refused"`,
+		SenderLine(Item{Host: "web01", Key: "sslcerttop.error[example.com]", Value: `dial tcp: "x" refused`}))
+	assert.Equal(t, `web01 sslcerttop.error[example.com] ""`, SenderLine(Item{Host: "web01", Key: "sslcerttop.error[example.com]"}))
+}
@@ -0,0 +1,411 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v28.3.0
+// source: tracker/v1/tracker.proto
+
+package trackerv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	TrackerService_ListDomains_FullMethodName        = "/tracker.v1.TrackerService/ListDomains"
+	TrackerService_GetDomain_FullMethodName          = "/tracker.v1.TrackerService/GetDomain"
+	TrackerService_AddDomain_FullMethodName          = "/tracker.v1.TrackerService/AddDomain"
+	TrackerService_DeleteDomain_FullMethodName       = "/tracker.v1.TrackerService/DeleteDomain"
+	TrackerService_CheckDomain_FullMethodName        = "/tracker.v1.TrackerService/CheckDomain"
+	TrackerService_CheckAllDomains_FullMethodName    = "/tracker.v1.TrackerService/CheckAllDomains"
+	TrackerService_GetDomainHistory_FullMethodName   = "/tracker.v1.TrackerService/GetDomainHistory"
+	TrackerService_StreamCheckResults_FullMethodName = "/tracker.v1.TrackerService/StreamCheckResults"
+)
+
+// TrackerServiceClient is the client API for TrackerService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// TrackerService manages tracked domains and their SSL certificate checks.
+type TrackerServiceClient interface {
+	// ListDomains lists every tracked domain.
+	ListDomains(ctx context.Context, in *ListDomainsRequest, opts ...grpc.CallOption) (*ListDomainsResponse, error)
+	// GetDomain looks up a single domain.
+	GetDomain(ctx context.Context, in *GetDomainRequest, opts ...grpc.CallOption) (*Domain, error)
+	// AddDomain tracks a new domain and checks its certificate.
+	AddDomain(ctx context.Context, in *AddDomainRequest, opts ...grpc.CallOption) (*Domain, error)
+	// DeleteDomain stops tracking a domain.
+	DeleteDomain(ctx context.Context, in *DeleteDomainRequest, opts ...grpc.CallOption) (*DeleteDomainResponse, error)
+	// CheckDomain checks a domain's certificate now.
+	CheckDomain(ctx context.Context, in *CheckDomainRequest, opts ...grpc.CallOption) (*Domain, error)
+	// CheckAllDomains checks every tracked domain.
+	CheckAllDomains(ctx context.Context, in *CheckAllDomainsRequest, opts ...grpc.CallOption) (*ListDomainsResponse, error)
+	// GetDomainHistory lists past checks of a domain, newest first.
+	GetDomainHistory(ctx context.Context, in *GetDomainHistoryRequest, opts ...grpc.CallOption) (*GetDomainHistoryResponse, error)
+	// StreamCheckResults sends every completed check as it happens.
+	StreamCheckResults(ctx context.Context, in *StreamCheckResultsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CheckResult], error)
+}
+
+type trackerServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTrackerServiceClient(cc grpc.ClientConnInterface) TrackerServiceClient {
+	return &trackerServiceClient{cc}
+}
+
+func (c *trackerServiceClient) ListDomains(ctx context.Context, in *ListDomainsRequest, opts ...grpc.CallOption) (*ListDomainsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDomainsResponse)
+	err := c.cc.Invoke(ctx, TrackerService_ListDomains_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trackerServiceClient) GetDomain(ctx context.Context, in *GetDomainRequest, opts ...grpc.CallOption) (*Domain, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Domain)
+	err := c.cc.Invoke(ctx, TrackerService_GetDomain_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trackerServiceClient) AddDomain(ctx context.Context, in *AddDomainRequest, opts ...grpc.CallOption) (*Domain, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Domain)
+	err := c.cc.Invoke(ctx, TrackerService_AddDomain_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trackerServiceClient) DeleteDomain(ctx context.Context, in *DeleteDomainRequest, opts ...grpc.CallOption) (*DeleteDomainResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteDomainResponse)
+	err := c.cc.Invoke(ctx, TrackerService_DeleteDomain_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trackerServiceClient) CheckDomain(ctx context.Context, in *CheckDomainRequest, opts ...grpc.CallOption) (*Domain, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Domain)
+	err := c.cc.Invoke(ctx, TrackerService_CheckDomain_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trackerServiceClient) CheckAllDomains(ctx context.Context, in *CheckAllDomainsRequest, opts ...grpc.CallOption) (*ListDomainsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDomainsResponse)
+	err := c.cc.Invoke(ctx, TrackerService_CheckAllDomains_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trackerServiceClient) GetDomainHistory(ctx context.Context, in *GetDomainHistoryRequest, opts ...grpc.CallOption) (*GetDomainHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetDomainHistoryResponse)
+	err := c.cc.Invoke(ctx, TrackerService_GetDomainHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trackerServiceClient) StreamCheckResults(ctx context.Context, in *StreamCheckResultsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CheckResult], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &TrackerService_ServiceDesc.Streams[0], TrackerService_StreamCheckResults_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamCheckResultsRequest, CheckResult]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TrackerService_StreamCheckResultsClient = grpc.ServerStreamingClient[CheckResult]
+
+// TrackerServiceServer is the server API for TrackerService service.
+// All implementations must embed UnimplementedTrackerServiceServer
+// for forward compatibility.
+//
+// TrackerService manages tracked domains and their SSL certificate checks.
+type TrackerServiceServer interface {
+	// ListDomains lists every tracked domain.
+	ListDomains(context.Context, *ListDomainsRequest) (*ListDomainsResponse, error)
+	// GetDomain looks up a single domain.
+	GetDomain(context.Context, *GetDomainRequest) (*Domain, error)
+	// AddDomain tracks a new domain and checks its certificate.
+	AddDomain(context.Context, *AddDomainRequest) (*Domain, error)
+	// DeleteDomain stops tracking a domain.
+	DeleteDomain(context.Context, *DeleteDomainRequest) (*DeleteDomainResponse, error)
+	// CheckDomain checks a domain's certificate now.
+	CheckDomain(context.Context, *CheckDomainRequest) (*Domain, error)
+	// CheckAllDomains checks every tracked domain.
+	CheckAllDomains(context.Context, *CheckAllDomainsRequest) (*ListDomainsResponse, error)
+	// GetDomainHistory lists past checks of a domain, newest first.
+	GetDomainHistory(context.Context, *GetDomainHistoryRequest) (*GetDomainHistoryResponse, error)
+	// StreamCheckResults sends every completed check as it happens.
+	StreamCheckResults(*StreamCheckResultsRequest, grpc.ServerStreamingServer[CheckResult]) error
+	mustEmbedUnimplementedTrackerServiceServer()
+}
+
+// UnimplementedTrackerServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTrackerServiceServer struct{}
+
+func (UnimplementedTrackerServiceServer) ListDomains(context.Context, *ListDomainsRequest) (*ListDomainsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDomains not implemented")
+}
+func (UnimplementedTrackerServiceServer) GetDomain(context.Context, *GetDomainRequest) (*Domain, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDomain not implemented")
+}
+func (UnimplementedTrackerServiceServer) AddDomain(context.Context, *AddDomainRequest) (*Domain, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddDomain not implemented")
+}
+func (UnimplementedTrackerServiceServer) DeleteDomain(context.Context, *DeleteDomainRequest) (*DeleteDomainResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteDomain not implemented")
+}
+func (UnimplementedTrackerServiceServer) CheckDomain(context.Context, *CheckDomainRequest) (*Domain, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckDomain not implemented")
+}
+func (UnimplementedTrackerServiceServer) CheckAllDomains(context.Context, *CheckAllDomainsRequest) (*ListDomainsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckAllDomains not implemented")
+}
+func (UnimplementedTrackerServiceServer) GetDomainHistory(context.Context, *GetDomainHistoryRequest) (*GetDomainHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDomainHistory not implemented")
+}
+func (UnimplementedTrackerServiceServer) StreamCheckResults(*StreamCheckResultsRequest, grpc.ServerStreamingServer[CheckResult]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamCheckResults not implemented")
+}
+func (UnimplementedTrackerServiceServer) mustEmbedUnimplementedTrackerServiceServer() {}
+func (UnimplementedTrackerServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeTrackerServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TrackerServiceServer will
+// result in compilation errors.
+type UnsafeTrackerServiceServer interface {
+	mustEmbedUnimplementedTrackerServiceServer()
+}
+
+func RegisterTrackerServiceServer(s grpc.ServiceRegistrar, srv TrackerServiceServer) {
+	// If the following call pancis, it indicates UnimplementedTrackerServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&TrackerService_ServiceDesc, srv)
+}
+
+func _TrackerService_ListDomains_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDomainsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrackerServiceServer).ListDomains(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TrackerService_ListDomains_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrackerServiceServer).ListDomains(ctx, req.(*ListDomainsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrackerService_GetDomain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDomainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrackerServiceServer).GetDomain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TrackerService_GetDomain_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrackerServiceServer).GetDomain(ctx, req.(*GetDomainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrackerService_AddDomain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddDomainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrackerServiceServer).AddDomain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TrackerService_AddDomain_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrackerServiceServer).AddDomain(ctx, req.(*AddDomainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrackerService_DeleteDomain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteDomainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrackerServiceServer).DeleteDomain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TrackerService_DeleteDomain_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrackerServiceServer).DeleteDomain(ctx, req.(*DeleteDomainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrackerService_CheckDomain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckDomainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrackerServiceServer).CheckDomain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TrackerService_CheckDomain_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrackerServiceServer).CheckDomain(ctx, req.(*CheckDomainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrackerService_CheckAllDomains_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckAllDomainsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrackerServiceServer).CheckAllDomains(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TrackerService_CheckAllDomains_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrackerServiceServer).CheckAllDomains(ctx, req.(*CheckAllDomainsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrackerService_GetDomainHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDomainHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrackerServiceServer).GetDomainHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TrackerService_GetDomainHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrackerServiceServer).GetDomainHistory(ctx, req.(*GetDomainHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrackerService_StreamCheckResults_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamCheckResultsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TrackerServiceServer).StreamCheckResults(m, &grpc.GenericServerStream[StreamCheckResultsRequest, CheckResult]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TrackerService_StreamCheckResultsServer = grpc.ServerStreamingServer[CheckResult]
+
+// TrackerService_ServiceDesc is the grpc.ServiceDesc for TrackerService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TrackerService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "tracker.v1.TrackerService",
+	HandlerType: (*TrackerServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListDomains",
+			Handler:    _TrackerService_ListDomains_Handler,
+		},
+		{
+			MethodName: "GetDomain",
+			Handler:    _TrackerService_GetDomain_Handler,
+		},
+		{
+			MethodName: "AddDomain",
+			Handler:    _TrackerService_AddDomain_Handler,
+		},
+		{
+			MethodName: "DeleteDomain",
+			Handler:    _TrackerService_DeleteDomain_Handler,
+		},
+		{
+			MethodName: "CheckDomain",
+			Handler:    _TrackerService_CheckDomain_Handler,
+		},
+		{
+			MethodName: "CheckAllDomains",
+			Handler:    _TrackerService_CheckAllDomains_Handler,
+		},
+		{
+			MethodName: "GetDomainHistory",
+			Handler:    _TrackerService_GetDomainHistory_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamCheckResults",
+			Handler:       _TrackerService_StreamCheckResults_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "tracker/v1/tracker.proto",
+}